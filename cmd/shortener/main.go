@@ -9,19 +9,33 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 
 	cfg "github.com/PrahaTurbo/url-shortener/config"
+	"github.com/PrahaTurbo/url-shortener/internal/account"
+	"github.com/PrahaTurbo/url-shortener/internal/admin"
+	"github.com/PrahaTurbo/url-shortener/internal/apikey"
+	"github.com/PrahaTurbo/url-shortener/internal/audit"
 	"github.com/PrahaTurbo/url-shortener/internal/auth"
+	"github.com/PrahaTurbo/url-shortener/internal/clientip"
 	"github.com/PrahaTurbo/url-shortener/internal/grpcapp"
 	"github.com/PrahaTurbo/url-shortener/internal/httpapp"
 	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	"github.com/PrahaTurbo/url-shortener/internal/preview"
+	"github.com/PrahaTurbo/url-shortener/internal/ratelimit"
 	"github.com/PrahaTurbo/url-shortener/internal/service"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/memory"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/pg"
 	"github.com/PrahaTurbo/url-shortener/internal/storage/provider"
+	"github.com/PrahaTurbo/url-shortener/internal/webhook"
+	"github.com/PrahaTurbo/url-shortener/internal/workspace"
 	pb "github.com/PrahaTurbo/url-shortener/proto"
 )
 
@@ -32,6 +46,18 @@ var (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate-data" {
+		os.Exit(runMigrateData(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "rebalance" {
+		os.Exit(runRebalance(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	fmt.Printf("Build version: %s\n", buildVersion)
 	fmt.Printf("Build date: %s\n", buildDate)
 	fmt.Printf("Build commit: %s\n", buildCommit)
@@ -42,15 +68,103 @@ func main() {
 		log.Fatal(err)
 	}
 
-	store, err := provider.NewStorage(c.DatabaseDSN, c.StorageFilePath, lgr)
+	storeCfg, err := loadStorageConfig(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	store, err := provider.NewStorage(storeCfg, lgr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	audits := audit.NewService(store.Audit, lgr)
+	webhooks := webhook.NewService(store.Webhooks, lgr, c.WebhookAllowPrivate, webhook.WithAuditLog(audits))
+
+	quota := models.Quota{
+		MaxLinks:     c.MaxLinksPerUser,
+		MaxBatchSize: c.MaxBatchSize,
+		MaxURLLength: c.MaxURLLength,
+	}
+
+	opts := []service.Option{
+		service.WithEventPublisher(webhooks),
+		service.WithQuotas(quota, store.Quotas),
+		service.WithAuditLog(audits),
+	}
+	if c.EnablePreviews {
+		opts = append(opts, service.WithPreviewFetcher(preview.NewFetcher(preview.DefaultTimeout, preview.DefaultMaxBytes)))
+	}
+
+	srvc := service.NewService(c.BaseURL, store.URLs, lgr, opts...)
+	apiKeys := apikey.NewService(store.APIKeys, apikey.WithAuditLog(audits))
+	accounts := account.NewService(store.Users, store.URLs, lgr, account.WithAuditLog(audits))
+	admins := admin.NewService(c.BaseURL, store.URLs, store.Users, audits, admin.WithEventPublisher(webhooks))
+	workspaces := workspace.NewService(store.Workspaces, store.URLs,
+		workspace.WithAuditLog(audits), workspace.WithEventPublisher(webhooks, c.BaseURL))
+	authOpts, err := loadAuthOptions(c)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	srvc := service.NewService(c.BaseURL, store, lgr)
-	auth := auth.NewAuth(c.JWTSecret, c.TrustedSubnet)
+	auth := auth.NewAuth(c.JWTSecret, c.TrustedSubnet, append(authOpts,
+		auth.WithRefreshTokens(store.Tokens),
+		auth.WithAPIKeys(apiKeys),
+		auth.WithRoles(admins),
+		auth.WithLogger(lgr),
+		auth.WithAuditLog(audits),
+		auth.WithPublicMethods(
+			pb.Auth_IssueAnonymousToken_FullMethodName,
+			pb.Auth_Register_FullMethodName,
+			pb.Auth_Login_FullMethodName,
+			pb.Auth_Refresh_FullMethodName,
+		),
+		auth.WithAdminMethods(pb.URLShortener_GetStats_FullMethodName, "/shortener.Admin/"),
+		auth.WithMethodRoles(map[string]string{
+			pb.Admin_ListUserLinks_FullMethodName:   auth.RoleModerator,
+			pb.Admin_SetLinkDisabled_FullMethodName: auth.RoleModerator,
+			"/shortener.Admin/":                     auth.RoleAdmin,
+		}),
+		auth.WithMethodScopes(map[string]string{
+			pb.URLShortener_MakeURL_FullMethodName:        auth.ScopeWrite,
+			pb.URLShortener_GetOriginalURL_FullMethodName: auth.ScopeRead,
+			pb.URLShortener_GetUserURLs_FullMethodName:    auth.ScopeRead,
+			pb.URLShortener_DeleteURLs_FullMethodName:     auth.ScopeDelete,
+		}),
+	)...)
 
-	httpApp := httpapp.NewHTTPApp(srvc, lgr, auth)
+	proxies, err := clientip.ParseCIDRs(c.TrustedProxies)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	resolver := clientip.NewResolver(proxies)
+
+	limits, err := loadRateLimits(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	go auth.PurgeRefreshTokens(purgeCtx)
+
+	linksCtx, stopLinks := context.WithCancel(context.Background())
+	linksDone := make(chan struct{})
+	go func() {
+		srvc.MaintainLinks(linksCtx)
+		close(linksDone)
+	}()
+
+	httpApp := httpapp.NewHTTPApp(srvc, lgr, auth,
+		httpapp.WithWebhooks(webhooks),
+		httpapp.WithAPIKeys(apiKeys),
+		httpapp.WithAccounts(accounts),
+		httpapp.WithAdmin(admins),
+		httpapp.WithWorkspaces(workspaces),
+		httpapp.WithAuditLog(audits),
+		httpapp.WithRateLimits(limits),
+		httpapp.WithClientIP(resolver),
+	)
 	httpServer := http.Server{
 		Addr:    c.Addr,
 		Handler: httpApp.Router(),
@@ -62,8 +176,18 @@ func main() {
 		log.Fatal(err)
 	}
 
-	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(auth.UnaryServerInterceptor))
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		resolver.UnaryServerInterceptor,
+		audit.UnaryServerInterceptor,
+		auth.UnaryServerInterceptor,
+		ratelimit.UnaryServerInterceptor(map[string]*ratelimit.Limiter{
+			pb.URLShortener_MakeURL_FullMethodName:        limits.Create,
+			pb.URLShortener_GetOriginalURL_FullMethodName: limits.Redirect,
+		}),
+	))
 	pb.RegisterURLShortenerServer(grpcServer, grpcApp)
+	pb.RegisterAuthServer(grpcServer, grpcapp.NewAuthServer(accounts, auth, lgr))
+	pb.RegisterAdminServer(grpcServer, grpcapp.NewAdminServer(admins, lgr))
 
 	idleConnsClosed := make(chan struct{})
 	go func() {
@@ -77,6 +201,16 @@ func main() {
 			lgr.Error("HTTP server shutdown error", zap.Error(err))
 		}
 
+		stopPurge()
+
+		// The clicks counted before the shutdown are stored before the storage is closed.
+		stopLinks()
+		<-linksDone
+
+		if err := store.Close(); err != nil {
+			lgr.Error("storage close error", zap.Error(err))
+		}
+
 		close(idleConnsClosed)
 	}()
 
@@ -105,3 +239,149 @@ func main() {
 	<-idleConnsClosed
 	lgr.Info("HTTP and gRPC servers shutdown gracefully")
 }
+
+func loadStorageConfig(c cfg.Config) (provider.Config, error) {
+	queryTimeout, err := time.ParseDuration(c.DatabaseQueryTimeout)
+	if err != nil {
+		return provider.Config{}, fmt.Errorf("database query timeout: %w", err)
+	}
+
+	batchTimeout, err := time.ParseDuration(c.DatabaseBatchTimeout)
+	if err != nil {
+		return provider.Config{}, fmt.Errorf("database batch timeout: %w", err)
+	}
+
+	cacheTTL, err := time.ParseDuration(c.CacheTTL)
+	if err != nil {
+		return provider.Config{}, fmt.Errorf("cache ttl: %w", err)
+	}
+
+	cacheNegativeTTL, err := time.ParseDuration(c.CacheNegativeTTL)
+	if err != nil {
+		return provider.Config{}, fmt.Errorf("cache negative ttl: %w", err)
+	}
+
+	replicaCheck, err := time.ParseDuration(c.DatabaseReplicaCheck)
+	if err != nil {
+		return provider.Config{}, fmt.Errorf("database replica check interval: %w", err)
+	}
+
+	replicaLag, err := time.ParseDuration(c.DatabaseReplicaLag)
+	if err != nil {
+		return provider.Config{}, fmt.Errorf("database replica max lag: %w", err)
+	}
+
+	fileCompact, err := time.ParseDuration(c.StorageFileCompact)
+	if err != nil {
+		return provider.Config{}, fmt.Errorf("storage file compaction interval: %w", err)
+	}
+
+	fileSync := memory.SyncPolicy(c.StorageFileSync)
+	switch fileSync {
+	case memory.SyncAlways, memory.SyncInterval, memory.SyncNever:
+	default:
+		return provider.Config{}, fmt.Errorf("unknown storage file sync policy %q", c.StorageFileSync)
+	}
+
+	storeCfg := provider.Config{
+		Backend:       c.StorageBackend,
+		DSN:           c.DatabaseDSN,
+		FilePath:      c.StorageFilePath,
+		FileSync:      fileSync,
+		FileCompact:   fileCompact,
+		AuditFilePath: c.AuditFilePath,
+		BoltFilePath:  c.BoltFilePath,
+		ReplicaDSNs:   splitDSNs(c.DatabaseReplicaDSNs),
+		ReplicaCheck:  replicaCheck,
+		ReplicaMaxLag: replicaLag,
+		ShardDSNs:     splitDSNs(c.DatabaseShardDSNs),
+		Pool: pg.PoolConfig{
+			MaxConns:     int32(c.DatabaseMaxConns),
+			MinConns:     int32(c.DatabaseMinConns),
+			QueryTimeout: queryTimeout,
+			BatchTimeout: batchTimeout,
+		},
+		Cache: provider.CacheConfig{
+			Size:        c.CacheSize,
+			TTL:         cacheTTL,
+			NegativeTTL: cacheNegativeTTL,
+		},
+	}
+
+	return storeCfg, nil
+}
+
+// splitDSNs splits a comma separated list of DSNs, skipping the empty ones.
+func splitDSNs(list string) []string {
+	var dsns []string
+	for _, dsn := range strings.Split(list, ",") {
+		if dsn = strings.TrimSpace(dsn); dsn != "" {
+			dsns = append(dsns, dsn)
+		}
+	}
+
+	return dsns
+}
+
+func loadAuthOptions(c cfg.Config) ([]auth.Option, error) {
+	accessTTL, err := time.ParseDuration(c.JWTAccessTTL)
+	if err != nil {
+		return nil, fmt.Errorf("jwt access ttl: %w", err)
+	}
+
+	refreshTTL, err := time.ParseDuration(c.JWTRefreshTTL)
+	if err != nil {
+		return nil, fmt.Errorf("jwt refresh ttl: %w", err)
+	}
+
+	opts := []auth.Option{auth.WithTokenTTL(accessTTL, refreshTTL)}
+
+	if c.JWTKeys != "" {
+		ring, err := auth.ParseKeyRing(c.JWTKeys)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, auth.WithKeyRing(ring))
+	}
+
+	if c.JWTLegacyUntil != "" {
+		cutoff, err := time.Parse(time.RFC3339, c.JWTLegacyUntil)
+		if err != nil {
+			return nil, fmt.Errorf("jwt legacy until: %w", err)
+		}
+
+		opts = append(opts, auth.WithLegacyTokensUntil(cutoff))
+	}
+
+	if c.TrustedIssuer != "" {
+		opts = append(opts, auth.WithIssuers(auth.NewIssuer(c.TrustedIssuer, c.TrustedAudience)))
+	}
+
+	return opts, nil
+}
+
+func loadRateLimits(c cfg.Config) (httpapp.RateLimits, error) {
+	create, err := ratelimit.ParseLimit(c.RateLimitCreate)
+	if err != nil {
+		return httpapp.RateLimits{}, err
+	}
+
+	batch, err := ratelimit.ParseLimit(c.RateLimitBatch)
+	if err != nil {
+		return httpapp.RateLimits{}, err
+	}
+
+	redirect, err := ratelimit.ParseLimit(c.RateLimitRedirect)
+	if err != nil {
+		return httpapp.RateLimits{}, err
+	}
+
+	limits := httpapp.RateLimits{
+		Create:   ratelimit.NewLimiter(create),
+		Batch:    ratelimit.NewLimiter(batch),
+		Redirect: ratelimit.NewLimiter(redirect),
+	}
+
+	return limits, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/migrate"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/pg"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/sqlite"
+)
+
+const migrateUsage = `Usage: shortener migrate [-d dsn] [-l level] <command>
+
+Commands:
+  status      show the applied and pending migrations
+  up          apply all pending migrations
+  down [N]    revert the N latest applied migrations (1 by default)
+  to VERSION  apply or revert migrations until the schema is at VERSION (0 reverts all)
+
+The DSN defaults to the DATABASE_DSN environment variable. A sqlite:// DSN migrates a SQLite database.
+`
+
+// runMigrate runs the migrate subcommand with the given arguments and returns the exit code.
+func runMigrate(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprint(stderr, migrateUsage) }
+
+	dsn := fs.String("d", os.Getenv("DATABASE_DSN"), "sql database dsn")
+	logLevel := fs.String("l", "info", "log level")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *dsn == "" || fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	lgr, err := logger.Initialize(*logLevel)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	openDB, newMigrator := pg.OpenDB, pg.NewMigrator
+	if sqlite.IsDSN(*dsn) {
+		openDB, newMigrator = sqlite.OpenDB, sqlite.NewMigrator
+	}
+
+	db, err := openDB(*dsn)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer db.Close()
+
+	m, err := newMigrator(db, lgr)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer stop()
+
+	if err := runMigration(ctx, m, fs.Args(), stdout); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	return 0
+}
+
+func runMigration(ctx context.Context, m *migrate.Migrator, args []string, stdout io.Writer) error {
+	switch cmd := args[0]; {
+	case cmd == "status" && len(args) == 1:
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.Applied() {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+
+		return w.Flush()
+	case cmd == "up" && len(args) == 1:
+		n, err := m.Up(ctx)
+		fmt.Fprintf(stdout, "applied %d migration(s)\n", n)
+
+		return err
+	case cmd == "down" && len(args) <= 2:
+		steps := 1
+		if len(args) == 2 {
+			var err error
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of migrations %q", args[1])
+			}
+		}
+
+		n, err := m.Down(ctx, steps)
+		fmt.Fprintf(stdout, "reverted %d migration(s)\n", n)
+
+		return err
+	case cmd == "to" && len(args) == 2:
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || version < 0 {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+
+		n, err := m.To(ctx, version)
+		fmt.Fprintf(stdout, "applied or reverted %d migration(s)\n", n)
+
+		return err
+	default:
+		return fmt.Errorf("invalid migrate command; run 'shortener migrate -h' for usage")
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"go.uber.org/zap"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/provider"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/transfer"
+)
+
+const migrateDataUsage = `Usage: shortener migrate-data -from location -to location [-checkpoint path] [-b size] [-l level]
+
+Copies all the URLs from one storage backend to another, with their UUIDs, owners and deletion state,
+and verifies that the target has as many URLs as the source at the end. The locations are:
+
+  file://path          the file of the memory backend, e.g. file:///tmp/short-url-db.json
+  bolt://path          the database file of the bolt backend
+  sqlite://path        a SQLite database
+  postgres://...       a PostgreSQL database
+
+The progress is saved to the checkpoint file after every batch, so an interrupted migration resumes where it
+stopped when run again. The checkpoint is removed once all the URLs are copied, before they are verified.
+The server should not write to the source meanwhile. The file of a file:// source is only read.
+`
+
+// runMigrateData runs the migrate-data subcommand with the given arguments and returns the exit code.
+func runMigrateData(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("migrate-data", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprint(stderr, migrateDataUsage) }
+
+	from := fs.String("from", "", "location of the source urls")
+	to := fs.String("to", "", "location of the target urls")
+	checkpointPath := fs.String("checkpoint", "migrate-data.checkpoint", "path to checkpoint file")
+	batchSize := fs.Int("b", transfer.DefaultBatchSize, "number of urls copied at once")
+	logLevel := fs.String("l", "info", "log level")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *from == "" || *to == "" || fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+
+	if *from == *to {
+		fmt.Fprintln(stderr, "the source and the target are the same")
+		return 2
+	}
+
+	lgr, err := logger.Initialize(*logLevel)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer stop()
+
+	if err := migrateData(ctx, *from, *to, *checkpointPath, *batchSize, lgr, stdout); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	return 0
+}
+
+func migrateData(ctx context.Context, from, to, checkpointPath string, batchSize int, lgr *logger.Logger, stdout io.Writer) error {
+	for _, scheme := range []string{provider.FileScheme, provider.BoltScheme} {
+		if path := strings.TrimPrefix(from, scheme); path != from {
+			if _, err := os.Stat(path); err != nil {
+				return fmt.Errorf("source: %w", err)
+			}
+		}
+	}
+
+	source, closeSource, err := openURLStore(from, provider.OpenSourceURLs, lgr)
+	if err != nil {
+		return fmt.Errorf("source: %w", err)
+	}
+	defer closeSource.Close()
+
+	target, closeTarget, err := openURLStore(to, provider.OpenURLs, lgr)
+	if err != nil {
+		return fmt.Errorf("target: %w", err)
+	}
+
+	err = copyData(ctx, source, target, from, to, checkpointPath, batchSize, lgr, stdout)
+
+	// The target syncs its pending writes on close, e.g. the file of the memory backend.
+	if closeErr := closeTarget.Close(); closeErr != nil && err == nil {
+		err = fmt.Errorf("target: %w", closeErr)
+	}
+
+	return err
+}
+
+func copyData(ctx context.Context, source, target storage.URLStore, from, to, checkpointPath string, batchSize int,
+	lgr *logger.Logger, stdout io.Writer) error {
+	cp, ok, err := transfer.LoadCheckpoint(checkpointPath)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case !ok:
+		cp = transfer.Checkpoint{Source: locationID(from), Target: locationID(to)}
+	case cp.Source != locationID(from) || cp.Target != locationID(to):
+		return fmt.Errorf("checkpoint %s belongs to the migration from %s to %s; remove it to start over",
+			checkpointPath, cp.Source, cp.Target)
+	default:
+		fmt.Fprintf(stdout, "resuming after %d url(s)\n", cp.Copied)
+	}
+
+	cp, err = transfer.Copy(ctx, source, target, batchSize, cp, func(cp transfer.Checkpoint) error {
+		lgr.Info("copied urls", zap.Int("urls", cp.Copied))
+
+		return transfer.SaveCheckpoint(checkpointPath, cp)
+	})
+	if err != nil {
+		return fmt.Errorf("copied %d url(s), run again to resume: %w", cp.Copied, err)
+	}
+
+	fmt.Fprintf(stdout, "copied %d url(s)\n", cp.Copied)
+
+	// The copy is complete, so running it again copies every url again, e.g. after a failed verification.
+	if err := os.Remove(checkpointPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	want, err := transfer.Count(ctx, source, batchSize)
+	if err != nil {
+		return fmt.Errorf("count source: %w", err)
+	}
+
+	got, err := transfer.Count(ctx, target, batchSize)
+	if err != nil {
+		return fmt.Errorf("count target: %w", err)
+	}
+
+	if got != want {
+		return fmt.Errorf("verification failed: the source has %s, the target %s; "+
+			"the source may have been written to, or the target had urls before", formatCounts(want), formatCounts(got))
+	}
+
+	fmt.Fprintf(stdout, "verified %s\n", formatCounts(got))
+
+	return nil
+}
+
+// openURLStore opens the URL repository at the location with open, which must implement storage.URLStore.
+func openURLStore(location string, open func(string, provider.Config, *logger.Logger) (storage.Repository, io.Closer, error),
+	lgr *logger.Logger) (storage.URLStore, io.Closer, error) {
+	urls, closer, err := open(location, provider.Config{}, lgr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	store, ok := urls.(storage.URLStore)
+	if !ok {
+		closer.Close()
+		return nil, nil, fmt.Errorf("the urls of %s can't be copied", locationID(location))
+	}
+
+	return store, closer, nil
+}
+
+// locationID identifies the location in the checkpoint without its credentials. The DSNs that aren't URLs,
+// like the key=value ones of PostgreSQL, are identified by their hash.
+func locationID(location string) string {
+	if u, err := url.Parse(location); err == nil && u.Scheme != "" {
+		return u.Redacted()
+	}
+
+	return fmt.Sprintf("dsn %x", sha256.Sum256([]byte(location)))[:20]
+}
+
+func formatCounts(c transfer.Counts) string {
+	return fmt.Sprintf("%d url(s), %d deleted, of %d user(s)", c.Records, c.Deleted, c.Users)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/provider"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/shard"
+)
+
+const rebalanceUsage = `Usage: shortener rebalance [-d dsn] [-ds dsns] [-b size] [-l level]
+
+Moves the URLs to the shards their short codes belong to, e.g. after shards were appended to -ds.
+The shards are the -d database followed by the -ds databases, in the order the server is configured with.
+An interrupted rebalance is completed by running it again.
+
+The DSNs default to the DATABASE_DSN and DATABASE_SHARD_DSNS environment variables.
+`
+
+// runRebalance runs the rebalance subcommand with the given arguments and returns the exit code.
+func runRebalance(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("rebalance", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprint(stderr, rebalanceUsage) }
+
+	dsn := fs.String("d", os.Getenv("DATABASE_DSN"), "sql database dsn")
+	shardDSNs := fs.String("ds", os.Getenv("DATABASE_SHARD_DSNS"), "comma separated dsns of databases to shard urls over besides the database")
+	batchSize := fs.Int("b", shard.DefaultBatchSize, "number of urls read from a shard at once")
+	logLevel := fs.String("l", "info", "log level")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *dsn == "" || fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+
+	lgr, err := logger.Initialize(*logLevel)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	shards, closer, err := provider.OpenShards(provider.Config{DSN: *dsn, ShardDSNs: splitDSNs(*shardDSNs)}, lgr)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer closer.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer stop()
+
+	result, err := shard.Rebalance(ctx, shards, *batchSize, lgr)
+	fmt.Fprintf(stdout, "scanned %d url(s) on %d shard(s), moved %d, left %d conflicting\n",
+		result.Scanned, len(shards), result.Moved, result.Conflicts)
+
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	return 0
+}
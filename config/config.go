@@ -10,15 +10,46 @@ import (
 
 // Config represents the configuration of the application.
 type Config struct {
-	Addr            string `json:"server_address"` // The server address, in the form host:port.
-	GRPCAddr        string `json:"grc_server_address"`
-	BaseURL         string `json:"base_url"`          // The base URL to which the server responds.
-	LogLevel        string `json:"log_level"`         // The level of logs that should be displayed. Options include "info", "error", and "debug".
-	StorageFilePath string `json:"file_storage_path"` // The path to the file where the server will store short URL data.
-	DatabaseDSN     string `json:"database_dsn"`      // The SQL database DSN (Data Source Name) to connect to the database.
-	JWTSecret       string // The secret key used in JWT for authentication.
-	TrustedSubnet   string `json:"trusted_subnet"` // Trusted subnet
-	EnableHTTPS     bool   `json:"enable_https"`   // Enable HTTPS on server
+	Addr                 string `json:"server_address"` // The server address, in the form host:port.
+	GRPCAddr             string `json:"grc_server_address"`
+	BaseURL              string `json:"base_url"`               // The base URL to which the server responds.
+	LogLevel             string `json:"log_level"`              // The level of logs that should be displayed. Options include "info", "error", and "debug".
+	StorageFilePath      string `json:"file_storage_path"`      // The path to the file where the server will store short URL data, and with the .users, .workspaces, .webhooks, .quotas, .keys and .tokens suffixes the accounts, workspaces, webhooks, quota overrides, API keys and refresh tokens.
+	StorageFileSync      string `json:"file_storage_sync"`      // When changes to the storage file are synced to the disk: "always", "interval" (every second) or "never".
+	StorageFileCompact   string `json:"file_storage_compact"`   // Time between the compactions of the storage file into a snapshot, e.g. "10m". "0" disables compaction.
+	AuditFilePath        string `json:"audit_file_path"`        // The path to the append-only file of the audit log, used without a database. Empty keeps the log in memory.
+	StorageBackend       string `json:"storage_backend"`        // The storage backend: "memory", "postgres", "sqlite" or "bolt". Empty selects it by DatabaseDSN, and memory if it is empty.
+	BoltFilePath         string `json:"bolt_file_path"`         // The path to the database file of the bolt storage backend.
+	DatabaseDSN          string `json:"database_dsn"`           // The SQL database DSN (Data Source Name) to connect to the database. A sqlite:// DSN selects a SQLite database file.
+	DatabaseMaxConns     int    `json:"database_max_conns"`     // Maximum number of connections in the database pool. Zero uses the default of pgxpool.
+	DatabaseMinConns     int    `json:"database_min_conns"`     // Minimum number of connections kept open in the database pool.
+	DatabaseQueryTimeout string `json:"database_query_timeout"` // Time a single query of the Postgresql pool is allowed to run for, e.g. "3s".
+	DatabaseBatchTimeout string `json:"database_batch_timeout"` // Time a batch of URLs is allowed to be stored in, e.g. "1m".
+	DatabaseReplicaDSNs  string `json:"database_replica_dsns"`  // Comma separated DSNs of the read replicas of the Postgresql database, which serve redirects, listings and stats.
+	DatabaseReplicaCheck string `json:"database_replica_check"` // Time between the health checks of the read replicas, e.g. "5s".
+	DatabaseReplicaLag   string `json:"database_replica_lag"`   // Replication lag past which a read replica stops serving reads, e.g. "10s". Zero serves reads of any staleness.
+	DatabaseShardDSNs    string `json:"database_shard_dsns"`    // Comma separated DSNs of the databases the URLs are sharded over besides the DatabaseDSN one. New shards must be appended.
+	CacheSize            int    `json:"cache_size"`             // Maximum number of codes in the redirect cache in front of the database. Zero disables the cache.
+	CacheTTL             string `json:"cache_ttl"`              // Time the original URL of a code is cached for, e.g. "1m".
+	CacheNegativeTTL     string `json:"cache_negative_ttl"`     // Time an unknown, deleted or disabled code is cached for, e.g. "10s".
+	JWTSecret            string // The secret key used in JWT for authentication.
+	JWTKeys              string `json:"jwt_keys"`              // Keys JWT tokens are signed with, in the form "kid:secret,kid:secret". The first one signs new tokens. Empty uses JWTSecret.
+	JWTAccessTTL         string `json:"jwt_access_ttl"`        // Lifetime of access tokens, e.g. "15m".
+	JWTRefreshTTL        string `json:"jwt_refresh_ttl"`       // Lifetime of refresh tokens, e.g. "720h".
+	JWTLegacyUntil       string `json:"jwt_legacy_until"`      // RFC 3339 time until which the cookies of tokens issued before JWTKeys are exchanged for sessions. Empty ignores them.
+	TrustedSubnet        string `json:"trusted_subnet"`        // Comma separated IPv4 and IPv6 CIDRs admin access is allowed from.
+	TrustedProxies       string `json:"trusted_proxies"`       // Comma separated CIDRs of the proxies whose X-Forwarded-For, Forwarded and X-Real-IP headers are trusted.
+	TrustedIssuer        string `json:"trusted_issuer"`        // URL of an external identity service whose tokens are accepted. Its keys are fetched from /.well-known/jwks.json.
+	TrustedAudience      string `json:"trusted_audience"`      // Audience the tokens of the TrustedIssuer must be issued for.
+	EnableHTTPS          bool   `json:"enable_https"`          // Enable HTTPS on server
+	EnablePreviews       bool   `json:"enable_previews"`       // Fetch Open Graph previews of destinations for new short URLs.
+	WebhookAllowPrivate  bool   `json:"webhook_allow_private"` // Allow webhooks to deliver events to loopback and private network addresses.
+	RateLimitCreate      string `json:"rate_limit_create"`     // Token bucket limit for creating short URLs, in the form "rate:burst". Empty disables it.
+	RateLimitBatch       string `json:"rate_limit_batch"`      // Token bucket limit for batch creation of short URLs, in the form "rate:burst".
+	RateLimitRedirect    string `json:"rate_limit_redirect"`   // Token bucket limit for redirects, in the form "rate:burst".
+	MaxLinksPerUser      int    `json:"max_links_per_user"`    // Default maximum number of active short URLs per user. Zero disables the quota.
+	MaxBatchSize         int    `json:"max_batch_size"`        // Default maximum number of URLs in a batch. Zero disables the quota.
+	MaxURLLength         int    `json:"max_url_length"`        // Default maximum length of an original URL. Zero disables the quota.
 }
 
 // Load reads command-line flags and environment variables to populate a Config object.
@@ -29,11 +60,41 @@ func Load() Config {
 	baseURL := flag.String("b", "http://localhost:8080", "base address for short url")
 	logLevel := flag.String("l", "info", "log lever")
 	storageFilePath := flag.String("f", "/tmp/short-url-db.json", "path to storage file")
-	databaseDSN := flag.String("d", "", "sql database dsn")
+	storageFileSync := flag.String("fs", "interval", "sync policy of storage file: always, interval or never")
+	storageFileCompact := flag.String("fc", "10m", "time between compactions of storage file, 0 disables them")
+	auditFilePath := flag.String("af", "/tmp/short-url-audit.json", "path to audit log file")
+	storageBackend := flag.String("sb", "", "storage backend: memory, postgres, sqlite or bolt")
+	boltFilePath := flag.String("bf", "/tmp/short-url.bolt", "path to bolt database file")
+	databaseDSN := flag.String("d", "", "sql database dsn, or sqlite://path for sqlite")
+	databaseMaxConns := flag.Int("dmc", 0, "max connections in the database pool")
+	databaseMinConns := flag.Int("dnc", 0, "min connections in the database pool")
+	databaseQueryTimeout := flag.String("dqt", "3s", "timeout of a single query to the database")
+	databaseBatchTimeout := flag.String("dbt", "1m", "timeout of storing a batch of urls in the database")
+	databaseReplicaDSNs := flag.String("dr", "", "comma separated dsns of read replicas of the database")
+	databaseReplicaCheck := flag.String("drc", "5s", "time between health checks of read replicas")
+	databaseReplicaLag := flag.String("drl", "10s", "max replication lag of read replicas serving reads, 0 disables the check")
+	databaseShardDSNs := flag.String("ds", "", "comma separated dsns of databases to shard urls over besides the database")
+	cacheSize := flag.Int("cs", 10000, "max codes in the redirect cache, 0 disables it")
+	cacheTTL := flag.String("ct", "1m", "lifetime of cached redirects")
+	cacheNegativeTTL := flag.String("cnt", "10s", "lifetime of cached unknown, deleted or disabled codes")
 	enableHTTPS := flag.Bool("s", false, "enable HTTPS on server")
 	configPath := flag.String("c", "", "path to config file")
-	trustedSubnet := flag.String("t", "", "trusted subnet")
+	trustedSubnet := flag.String("t", "", "comma separated cidrs of trusted subnets")
+	trustedProxies := flag.String("tp", "", "comma separated cidrs of trusted proxies")
+	trustedIssuer := flag.String("ti", "", "url of trusted external token issuer")
+	trustedAudience := flag.String("ta", "url-shortener", "audience of trusted issuer tokens")
 	grpcAddr := flag.String("ga", "localhost:3200", "grpc server address in a from host:port")
+	enablePreviews := flag.Bool("p", true, "fetch open graph previews of destinations")
+	webhookAllowPrivate := flag.Bool("wp", false, "allow webhooks to private network addresses")
+	rateLimitCreate := flag.String("rlc", "", "rate limit for url creation in a form rate:burst")
+	rateLimitBatch := flag.String("rlb", "", "rate limit for batch url creation in a form rate:burst")
+	rateLimitRedirect := flag.String("rlr", "", "rate limit for redirects in a form rate:burst")
+	maxLinksPerUser := flag.Int("ml", 0, "max active short urls per user, 0 for no limit")
+	maxBatchSize := flag.Int("mb", 0, "max urls in a batch, 0 for no limit")
+	maxURLLength := flag.Int("mu", 2048, "max length of an original url")
+	jwtAccessTTL := flag.String("jat", "15m", "lifetime of jwt access tokens")
+	jwtRefreshTTL := flag.String("jrt", "720h", "lifetime of refresh tokens")
+	jwtLegacyUntil := flag.String("jlu", "", "rfc 3339 time until which tokens issued before key rotation are accepted")
 	flag.Parse()
 
 	if err := c.loadJSON(*configPath); err != nil {
@@ -44,10 +105,40 @@ func Load() Config {
 	c.BaseURL = *baseURL
 	c.LogLevel = *logLevel
 	c.StorageFilePath = *storageFilePath
+	c.StorageFileSync = *storageFileSync
+	c.StorageFileCompact = *storageFileCompact
+	c.AuditFilePath = *auditFilePath
+	c.StorageBackend = *storageBackend
+	c.BoltFilePath = *boltFilePath
 	c.DatabaseDSN = *databaseDSN
+	c.DatabaseMaxConns = *databaseMaxConns
+	c.DatabaseMinConns = *databaseMinConns
+	c.DatabaseQueryTimeout = *databaseQueryTimeout
+	c.DatabaseBatchTimeout = *databaseBatchTimeout
+	c.DatabaseReplicaDSNs = *databaseReplicaDSNs
+	c.DatabaseReplicaCheck = *databaseReplicaCheck
+	c.DatabaseReplicaLag = *databaseReplicaLag
+	c.DatabaseShardDSNs = *databaseShardDSNs
+	c.CacheSize = *cacheSize
+	c.CacheTTL = *cacheTTL
+	c.CacheNegativeTTL = *cacheNegativeTTL
 	c.EnableHTTPS = *enableHTTPS
 	c.TrustedSubnet = *trustedSubnet
+	c.TrustedProxies = *trustedProxies
+	c.TrustedIssuer = *trustedIssuer
+	c.TrustedAudience = *trustedAudience
 	c.GRPCAddr = *grpcAddr
+	c.EnablePreviews = *enablePreviews
+	c.WebhookAllowPrivate = *webhookAllowPrivate
+	c.RateLimitCreate = *rateLimitCreate
+	c.RateLimitBatch = *rateLimitBatch
+	c.RateLimitRedirect = *rateLimitRedirect
+	c.MaxLinksPerUser = *maxLinksPerUser
+	c.MaxBatchSize = *maxBatchSize
+	c.MaxURLLength = *maxURLLength
+	c.JWTAccessTTL = *jwtAccessTTL
+	c.JWTRefreshTTL = *jwtRefreshTTL
+	c.JWTLegacyUntil = *jwtLegacyUntil
 
 	c.loadEnvVars()
 
@@ -88,10 +179,89 @@ func (c *Config) loadEnvVars() {
 		c.StorageFilePath = envStorageFilePath
 	}
 
+	if envStorageFileSync := os.Getenv("FILE_STORAGE_SYNC"); envStorageFileSync != "" {
+		c.StorageFileSync = envStorageFileSync
+	}
+
+	if envStorageFileCompact := os.Getenv("FILE_STORAGE_COMPACT"); envStorageFileCompact != "" {
+		c.StorageFileCompact = envStorageFileCompact
+	}
+
+	if envAuditFilePath := os.Getenv("AUDIT_FILE_PATH"); envAuditFilePath != "" {
+		c.AuditFilePath = envAuditFilePath
+	}
+
+	if envStorageBackend := os.Getenv("STORAGE_BACKEND"); envStorageBackend != "" {
+		c.StorageBackend = envStorageBackend
+	}
+
+	if envBoltFilePath := os.Getenv("BOLT_FILE_PATH"); envBoltFilePath != "" {
+		c.BoltFilePath = envBoltFilePath
+	}
+
 	if envDatabaseDSN := os.Getenv("DATABASE_DSN"); envDatabaseDSN != "" {
 		c.DatabaseDSN = envDatabaseDSN
 	}
 
+	if envDatabaseMaxConns := os.Getenv("DATABASE_MAX_CONNS"); envDatabaseMaxConns != "" {
+		val, err := strconv.Atoi(envDatabaseMaxConns)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		c.DatabaseMaxConns = val
+	}
+
+	if envDatabaseMinConns := os.Getenv("DATABASE_MIN_CONNS"); envDatabaseMinConns != "" {
+		val, err := strconv.Atoi(envDatabaseMinConns)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		c.DatabaseMinConns = val
+	}
+
+	if envDatabaseQueryTimeout := os.Getenv("DATABASE_QUERY_TIMEOUT"); envDatabaseQueryTimeout != "" {
+		c.DatabaseQueryTimeout = envDatabaseQueryTimeout
+	}
+
+	if envDatabaseBatchTimeout := os.Getenv("DATABASE_BATCH_TIMEOUT"); envDatabaseBatchTimeout != "" {
+		c.DatabaseBatchTimeout = envDatabaseBatchTimeout
+	}
+
+	if envDatabaseReplicaDSNs := os.Getenv("DATABASE_REPLICA_DSNS"); envDatabaseReplicaDSNs != "" {
+		c.DatabaseReplicaDSNs = envDatabaseReplicaDSNs
+	}
+
+	if envDatabaseReplicaCheck := os.Getenv("DATABASE_REPLICA_CHECK"); envDatabaseReplicaCheck != "" {
+		c.DatabaseReplicaCheck = envDatabaseReplicaCheck
+	}
+
+	if envDatabaseReplicaLag := os.Getenv("DATABASE_REPLICA_LAG"); envDatabaseReplicaLag != "" {
+		c.DatabaseReplicaLag = envDatabaseReplicaLag
+	}
+
+	if envDatabaseShardDSNs := os.Getenv("DATABASE_SHARD_DSNS"); envDatabaseShardDSNs != "" {
+		c.DatabaseShardDSNs = envDatabaseShardDSNs
+	}
+
+	if envCacheSize := os.Getenv("CACHE_SIZE"); envCacheSize != "" {
+		val, err := strconv.Atoi(envCacheSize)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		c.CacheSize = val
+	}
+
+	if envCacheTTL := os.Getenv("CACHE_TTL"); envCacheTTL != "" {
+		c.CacheTTL = envCacheTTL
+	}
+
+	if envCacheNegativeTTL := os.Getenv("CACHE_NEGATIVE_TTL"); envCacheNegativeTTL != "" {
+		c.CacheNegativeTTL = envCacheNegativeTTL
+	}
+
 	if envEnableHTTPS := os.Getenv("ENABLE_HTTPS"); envEnableHTTPS != "" {
 		val, err := strconv.ParseBool(envEnableHTTPS)
 		if err != nil {
@@ -101,13 +271,98 @@ func (c *Config) loadEnvVars() {
 		c.EnableHTTPS = val
 	}
 
+	if envEnablePreviews := os.Getenv("ENABLE_PREVIEWS"); envEnablePreviews != "" {
+		val, err := strconv.ParseBool(envEnablePreviews)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		c.EnablePreviews = val
+	}
+
+	if envWebhookAllowPrivate := os.Getenv("WEBHOOK_ALLOW_PRIVATE"); envWebhookAllowPrivate != "" {
+		val, err := strconv.ParseBool(envWebhookAllowPrivate)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		c.WebhookAllowPrivate = val
+	}
+
+	if envRateLimitCreate := os.Getenv("RATE_LIMIT_CREATE"); envRateLimitCreate != "" {
+		c.RateLimitCreate = envRateLimitCreate
+	}
+
+	if envRateLimitBatch := os.Getenv("RATE_LIMIT_BATCH"); envRateLimitBatch != "" {
+		c.RateLimitBatch = envRateLimitBatch
+	}
+
+	if envRateLimitRedirect := os.Getenv("RATE_LIMIT_REDIRECT"); envRateLimitRedirect != "" {
+		c.RateLimitRedirect = envRateLimitRedirect
+	}
+
+	if envMaxLinksPerUser := os.Getenv("MAX_LINKS_PER_USER"); envMaxLinksPerUser != "" {
+		val, err := strconv.Atoi(envMaxLinksPerUser)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		c.MaxLinksPerUser = val
+	}
+
+	if envMaxBatchSize := os.Getenv("MAX_BATCH_SIZE"); envMaxBatchSize != "" {
+		val, err := strconv.Atoi(envMaxBatchSize)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		c.MaxBatchSize = val
+	}
+
+	if envMaxURLLength := os.Getenv("MAX_URL_LENGTH"); envMaxURLLength != "" {
+		val, err := strconv.Atoi(envMaxURLLength)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		c.MaxURLLength = val
+	}
+
 	if envJWTSecret := os.Getenv("JWT_SECRET_KEY"); envJWTSecret != "" {
 		c.JWTSecret = envJWTSecret
 	} else {
 		c.JWTSecret = "key_for_studying"
 	}
 
+	if envJWTKeys := os.Getenv("JWT_KEYS"); envJWTKeys != "" {
+		c.JWTKeys = envJWTKeys
+	}
+
+	if envJWTAccessTTL := os.Getenv("JWT_ACCESS_TTL"); envJWTAccessTTL != "" {
+		c.JWTAccessTTL = envJWTAccessTTL
+	}
+
+	if envJWTRefreshTTL := os.Getenv("JWT_REFRESH_TTL"); envJWTRefreshTTL != "" {
+		c.JWTRefreshTTL = envJWTRefreshTTL
+	}
+
+	if envJWTLegacyUntil := os.Getenv("JWT_LEGACY_UNTIL"); envJWTLegacyUntil != "" {
+		c.JWTLegacyUntil = envJWTLegacyUntil
+	}
+
 	if envTrustedSubnet := os.Getenv("TRUSTED_SUBNET"); envTrustedSubnet != "" {
 		c.TrustedSubnet = envTrustedSubnet
 	}
+
+	if envTrustedProxies := os.Getenv("TRUSTED_PROXIES"); envTrustedProxies != "" {
+		c.TrustedProxies = envTrustedProxies
+	}
+
+	if envTrustedIssuer := os.Getenv("TRUSTED_ISSUER"); envTrustedIssuer != "" {
+		c.TrustedIssuer = envTrustedIssuer
+	}
+
+	if envTrustedAudience := os.Getenv("TRUSTED_AUDIENCE"); envTrustedAudience != "" {
+		c.TrustedAudience = envTrustedAudience
+	}
 }
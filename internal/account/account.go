@@ -0,0 +1,214 @@
+// Package account lets users register an account with a username and password, so their links
+// don't depend on a single browser cookie. Logging in merges the links of the anonymous user
+// of the session into the account.
+package account
+
+import (
+	"context"
+	"errors"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/PrahaTurbo/url-shortener/internal/audit"
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+const (
+	minUsernameLength = 3
+	maxUsernameLength = 64
+	minPasswordLength = 8
+	// bcrypt ignores everything past the first 72 bytes of a password.
+	maxPasswordLength = 72
+)
+
+// Error variables, used in the account service.
+var (
+	ErrInvalidUsername    = errors.New("username must be from 3 to 64 characters long")
+	ErrInvalidPassword    = errors.New("password must be from 8 to 72 bytes long")
+	ErrUsernameTaken      = errors.New("username is already taken")
+	ErrInvalidCredentials = errors.New("invalid username or password")
+)
+
+// dummyHash is compared against when the username is unknown, so a failed login
+// takes as long for unknown usernames as for wrong passwords.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+// Service is an interface for registering and logging in to accounts.
+type Service interface {
+	Register(ctx context.Context, sessionUserID string, req models.AccountRequest) (*models.AccountResponse, error)
+	Login(ctx context.Context, sessionUserID string, req models.AccountRequest) (*models.AccountResponse, error)
+}
+
+type service struct {
+	users  storage.UserRepository
+	urls   storage.Repository
+	logger *logger.Logger
+	audit  audit.Recorder
+}
+
+// Option configures optional dependencies of the account service.
+type Option func(s *service)
+
+// WithAuditLog enables recording of registrations, logins and failed logins in the audit log.
+func WithAuditLog(r audit.Recorder) Option {
+	return func(s *service) {
+		s.audit = r
+	}
+}
+
+// NewService creates a new instance of the account service.
+func NewService(users storage.UserRepository, urls storage.Repository, logger *logger.Logger, opts ...Option) Service {
+	s := &service{
+		users:  users,
+		urls:   urls,
+		logger: logger,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Register creates an account and merges the links of the anonymous user of the session into it.
+func (s *service) Register(ctx context.Context, sessionUserID string, req models.AccountRequest) (*models.AccountResponse, error) {
+	if n := utf8.RuneCountInString(req.Username); n < minUsernameLength || n > maxUsernameLength {
+		return nil, ErrInvalidUsername
+	}
+
+	if n := len(req.Password); n < minPasswordLength || n > maxPasswordLength {
+		return nil, ErrInvalidPassword
+	}
+
+	existing, err := s.users.GetUserByUsername(ctx, req.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		return nil, ErrUsernameTaken
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	u := entity.User{
+		ID:           uuid.New().String(),
+		Username:     req.Username,
+		PasswordHash: string(hash),
+		CreatedAt:    time.Now().UTC(),
+	}
+
+	// Another registration may have taken the username since it was checked.
+	err = s.users.SaveUser(ctx, u)
+	if errors.Is(err, storage.ErrUsernameTaken) {
+		return nil, ErrUsernameTaken
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	merged := s.mergeSession(ctx, sessionUserID, u.ID)
+	s.record(ctx, audit.Entry{
+		ActorID: sessionUserID,
+		Action:  audit.ActionUserRegister,
+		Target:  u.ID,
+		After:   loginValue{UserID: u.ID, Username: u.Username, MergedUserID: merged},
+	})
+
+	return &models.AccountResponse{UserID: u.ID, Username: u.Username}, nil
+}
+
+// Login checks the credentials and merges the links of the anonymous user of the session into the account.
+func (s *service) Login(ctx context.Context, sessionUserID string, req models.AccountRequest) (*models.AccountResponse, error) {
+	u, err := s.users.GetUserByUsername(ctx, req.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	if u == nil {
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(req.Password))
+		s.recordFailedLogin(ctx, sessionUserID, req.Username)
+
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(req.Password)); err != nil {
+		s.recordFailedLogin(ctx, sessionUserID, req.Username)
+
+		return nil, ErrInvalidCredentials
+	}
+
+	merged := s.mergeSession(ctx, sessionUserID, u.ID)
+	s.record(ctx, audit.Entry{
+		ActorID: sessionUserID,
+		Action:  audit.ActionUserLogin,
+		Target:  u.ID,
+		After:   loginValue{UserID: u.ID, Username: u.Username, MergedUserID: merged},
+	})
+
+	return &models.AccountResponse{UserID: u.ID, Username: u.Username}, nil
+}
+
+// mergeSession moves the links of the anonymous user of the session into the account and returns
+// the ID of the anonymous user if they were moved. Sessions of other accounts are never merged.
+// A failed merge doesn't fail the login, since the links stay with the anonymous user and can be
+// merged by logging in again.
+func (s *service) mergeSession(ctx context.Context, sessionUserID, accountID string) string {
+	if sessionUserID == "" || sessionUserID == accountID {
+		return ""
+	}
+
+	other, err := s.users.GetUserByID(ctx, sessionUserID)
+	if err != nil {
+		s.logger.Error("cannot get session user", zap.Error(err), zap.String("user id", sessionUserID))
+		return ""
+	}
+
+	if other != nil {
+		return ""
+	}
+
+	if err := s.urls.ReassignURLs(ctx, sessionUserID, accountID); err != nil {
+		s.logger.Error("cannot merge session urls", zap.Error(err),
+			zap.String("user id", sessionUserID), zap.String("account id", accountID))
+		return ""
+	}
+
+	return sessionUserID
+}
+
+// loginValue is the value recorded in the audit log for registrations and logins.
+// MergedUserID is the anonymous user whose links were moved into the account.
+type loginValue struct {
+	UserID       string `json:"user_id"`
+	Username     string `json:"username"`
+	MergedUserID string `json:"merged_user_id,omitempty"`
+}
+
+func (s *service) record(ctx context.Context, e audit.Entry) {
+	if s.audit == nil {
+		return
+	}
+
+	s.audit.Record(ctx, e)
+}
+
+func (s *service) recordFailedLogin(ctx context.Context, sessionUserID, username string) {
+	s.record(ctx, audit.Entry{
+		ActorID: sessionUserID,
+		Action:  audit.ActionUserLoginFailed,
+		Target:  username,
+	})
+}
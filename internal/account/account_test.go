@@ -0,0 +1,159 @@
+package account
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/memory"
+)
+
+func setupService(t *testing.T) (*service, storage.Repository) {
+	log, _ := logger.Initialize("debug")
+	urls, err := memory.NewInMemStorage(filepath.Join(t.TempDir(), "db.json"), log)
+	require.NoError(t, err)
+
+	return NewService(memory.NewUserStorage(), urls, log).(*service), urls
+}
+
+func saveURL(t *testing.T, urls storage.Repository, userID, shortURL string) {
+	err := urls.SaveURL(context.Background(), entity.URLRecord{
+		UUID:        userID + shortURL,
+		ShortURL:    shortURL,
+		OriginalURL: "https://" + shortURL + ".example",
+		UserID:      userID,
+	})
+	require.NoError(t, err)
+}
+
+func TestService_Register(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     models.AccountRequest
+		wantErr error
+	}{
+		{
+			name: "should register account successfully",
+			req:  models.AccountRequest{Username: "alice", Password: "correct horse"},
+		},
+		{
+			name:    "should reject short username",
+			req:     models.AccountRequest{Username: "al", Password: "correct horse"},
+			wantErr: ErrInvalidUsername,
+		},
+		{
+			name:    "should reject short password",
+			req:     models.AccountRequest{Username: "alice", Password: "horse"},
+			wantErr: ErrInvalidPassword,
+		},
+		{
+			name:    "should reject password bcrypt would truncate",
+			req:     models.AccountRequest{Username: "alice", Password: strings.Repeat("a", 73)},
+			wantErr: ErrInvalidPassword,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := setupService(t)
+
+			resp, err := s.Register(context.Background(), "", tt.req)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.req.Username, resp.Username)
+			assert.NotEmpty(t, resp.UserID)
+
+			_, err = s.Register(context.Background(), "", tt.req)
+			assert.ErrorIs(t, err, ErrUsernameTaken)
+		})
+	}
+}
+
+// racingUsers misses the accounts on the check of the username, like a registration racing another one.
+type racingUsers struct {
+	storage.UserRepository
+}
+
+func (racingUsers) GetUserByUsername(context.Context, string) (*entity.User, error) {
+	return nil, nil
+}
+
+func TestService_Register_race(t *testing.T) {
+	s, _ := setupService(t)
+	ctx := context.Background()
+	req := models.AccountRequest{Username: "alice", Password: "correct horse"}
+
+	_, err := s.Register(ctx, "", req)
+	require.NoError(t, err)
+
+	s.users = racingUsers{s.users}
+
+	_, err = s.Register(ctx, "", req)
+	assert.ErrorIs(t, err, ErrUsernameTaken)
+}
+
+func TestService_Login(t *testing.T) {
+	s, _ := setupService(t)
+	ctx := context.Background()
+
+	registered, err := s.Register(ctx, "", models.AccountRequest{Username: "alice", Password: "correct horse"})
+	require.NoError(t, err)
+
+	resp, err := s.Login(ctx, "", models.AccountRequest{Username: "alice", Password: "correct horse"})
+	require.NoError(t, err)
+	assert.Equal(t, registered.UserID, resp.UserID)
+
+	_, err = s.Login(ctx, "", models.AccountRequest{Username: "alice", Password: "battery staple"})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	_, err = s.Login(ctx, "", models.AccountRequest{Username: "bob", Password: "correct horse"})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestService_mergeSession(t *testing.T) {
+	s, urls := setupService(t)
+	ctx := context.Background()
+
+	saveURL(t, urls, "anon-1", "aaaaaa")
+	saveURL(t, urls, "anon-1", "bbbbbb")
+
+	alice, err := s.Register(ctx, "anon-1", models.AccountRequest{Username: "alice", Password: "correct horse"})
+	require.NoError(t, err)
+
+	saveURL(t, urls, "anon-2", "bbbbbb")
+	saveURL(t, urls, "anon-2", "cccccc")
+
+	_, err = s.Login(ctx, "anon-2", models.AccountRequest{Username: "alice", Password: "correct horse"})
+	require.NoError(t, err)
+
+	records, err := urls.GetURLsByUserID(ctx, alice.UserID)
+	require.NoError(t, err)
+
+	var shortURLs []string
+	for _, r := range records {
+		shortURLs = append(shortURLs, r.ShortURL)
+	}
+	assert.Equal(t, []string{"aaaaaa", "bbbbbb", "cccccc"}, shortURLs)
+
+	bob, err := s.Register(ctx, "", models.AccountRequest{Username: "bob", Password: "correct horse"})
+	require.NoError(t, err)
+
+	_, err = s.Login(ctx, bob.UserID, models.AccountRequest{Username: "alice", Password: "correct horse"})
+	require.NoError(t, err)
+
+	count, err := urls.CountURLsByUserID(ctx, alice.UserID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count, "links of another account should never be merged")
+}
@@ -0,0 +1,246 @@
+// Package admin lets moderators and administrators act on the links and accounts of other users,
+// e.g. to handle abuse reports. Every action is recorded in the audit log, including the views
+// of the links of other users.
+package admin
+
+import (
+	"context"
+	"errors"
+
+	"github.com/PrahaTurbo/url-shortener/internal/audit"
+	"github.com/PrahaTurbo/url-shortener/internal/auth"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/webhook"
+)
+
+// Error variables, used in the admin service.
+var (
+	ErrNotFound       = errors.New("link not found")
+	ErrUserNotFound   = errors.New("user not found")
+	ErrAlreadyOwned   = errors.New("link is already owned by the user")
+	ErrInvalidRequest = errors.New("from_user_id and to_user_id must be different non-empty user ids")
+	ErrInvalidRole    = errors.New("unknown role")
+)
+
+// Service is an interface for the actions of moderators and administrators and for looking up the roles of users.
+type Service interface {
+	ListUserLinks(ctx context.Context, actorID, userID string) ([]models.AdminLinkResponse, error)
+	SetLinkDisabled(ctx context.Context, actorID, shortURL string, disabled bool) error
+	ReassignLink(ctx context.Context, actorID, shortURL string, req models.ReassignRequest) error
+	SetUserRole(ctx context.Context, actorID, userID, role string) error
+	UserRole(ctx context.Context, userID string) (string, error)
+}
+
+type service struct {
+	baseURL   string
+	urls      storage.Repository
+	users     storage.UserRepository
+	audit     audit.Recorder
+	publisher webhook.Publisher
+}
+
+// Option configures optional dependencies of the admin service.
+type Option func(s *service)
+
+// WithEventPublisher enables publishing of the links disabled, enabled and reassigned by moderators
+// and administrators to their owners.
+func WithEventPublisher(p webhook.Publisher) Option {
+	return func(s *service) {
+		s.publisher = p
+	}
+}
+
+// NewService creates a new instance of the admin service recording the actions in the audit log.
+// The baseURL is the prefix of the listed short URLs.
+func NewService(baseURL string, urls storage.Repository, users storage.UserRepository, recorder audit.Recorder, opts ...Option) Service {
+	s := &service{
+		baseURL: baseURL,
+		urls:    urls,
+		users:   users,
+		audit:   recorder,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// ListUserLinks returns all the links of the user, including the deleted and disabled ones.
+// It returns no links for a user without any.
+func (s *service) ListUserLinks(ctx context.Context, actorID, userID string) ([]models.AdminLinkResponse, error) {
+	records, err := s.urls.GetURLsByUserID(ctx, userID)
+	if err != nil && !errors.Is(err, storage.ErrURLNotFound) {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, audit.Entry{
+		ActorID: actorID,
+		Action:  audit.ActionUserLinksView,
+		Target:  userID,
+	})
+
+	resp := make([]models.AdminLinkResponse, 0, len(records))
+	for _, r := range records {
+		resp = append(resp, models.AdminLinkResponse{
+			ShortURL:    s.baseURL + "/" + r.ShortURL,
+			OriginalURL: r.OriginalURL,
+			UserID:      r.UserID,
+			Deleted:     r.DeletedFlag,
+			Disabled:    r.Disabled,
+		})
+	}
+
+	return resp, nil
+}
+
+// SetLinkDisabled disables the link for all its owners, so it no longer redirects, or enables it again.
+func (s *service) SetLinkDisabled(ctx context.Context, actorID, shortURL string, disabled bool) error {
+	before := s.linkDisabled(ctx, shortURL)
+
+	records, err := s.urls.SetURLDisabled(ctx, shortURL, disabled)
+	if errors.Is(err, storage.ErrURLNotFound) {
+		return ErrNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		if r.DeletedFlag {
+			continue
+		}
+
+		s.publishUpdated(r.UserID, models.LinkEvent{
+			ShortURL:    s.baseURL + "/" + r.ShortURL,
+			OriginalURL: r.OriginalURL,
+			Disabled:    &disabled,
+		})
+	}
+
+	auditAction := audit.ActionLinkEnable
+	if disabled {
+		auditAction = audit.ActionLinkDisable
+	}
+
+	s.audit.Record(ctx, audit.Entry{
+		ActorID: actorID,
+		Action:  auditAction,
+		Target:  shortURL,
+		Before:  before,
+		After:   map[string]bool{"disabled": disabled},
+	})
+
+	return nil
+}
+
+// ReassignLink moves the link from one user to another. It fails if the other user already has the link.
+func (s *service) ReassignLink(ctx context.Context, actorID, shortURL string, req models.ReassignRequest) error {
+	if req.FromUserID == "" || req.ToUserID == "" || req.FromUserID == req.ToUserID {
+		return ErrInvalidRequest
+	}
+
+	if err := s.urls.CheckExistence(ctx, shortURL, req.ToUserID); err == nil {
+		return ErrAlreadyOwned
+	}
+
+	err := s.urls.ReassignURL(ctx, shortURL, req.FromUserID, req.ToUserID)
+	if errors.Is(err, storage.ErrURLNotFound) {
+		return ErrNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// Both users are notified, the link is gone from the links of the one and added to the ones of the other.
+	link := models.LinkEvent{ShortURL: s.baseURL + "/" + shortURL, UserID: req.ToUserID}
+	s.publishUpdated(req.FromUserID, link)
+	s.publishUpdated(req.ToUserID, link)
+
+	s.audit.Record(ctx, audit.Entry{
+		ActorID: actorID,
+		Action:  audit.ActionLinkReassign,
+		Target:  shortURL,
+		Before:  map[string]string{"user_id": req.FromUserID},
+		After:   map[string]string{"user_id": req.ToUserID},
+	})
+
+	return nil
+}
+
+// SetUserRole grants the role to the registered user. Anonymous users always have the auth.RoleUser.
+func (s *service) SetUserRole(ctx context.Context, actorID, userID, role string) error {
+	if !auth.ValidRole(role) {
+		return ErrInvalidRole
+	}
+
+	u, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if u == nil {
+		return ErrUserNotFound
+	}
+
+	if err := s.users.SetUserRole(ctx, userID, role); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, audit.Entry{
+		ActorID: actorID,
+		Action:  audit.ActionUserRole,
+		Target:  userID,
+		Before:  map[string]string{"role": roleOrDefault(u.Role)},
+		After:   map[string]string{"role": role},
+	})
+
+	return nil
+}
+
+// UserRole returns the role of the user, which is auth.RoleUser for anonymous users.
+func (s *service) UserRole(ctx context.Context, userID string) (string, error) {
+	u, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	if u == nil {
+		return auth.RoleUser, nil
+	}
+
+	return roleOrDefault(u.Role), nil
+}
+
+func (s *service) publishUpdated(userID string, link models.LinkEvent) {
+	if s.publisher == nil {
+		return
+	}
+
+	s.publisher.Publish(userID, webhook.EventLinkUpdated, link)
+}
+
+// linkDisabled returns whether the link is disabled before a change. It returns nil if the link is not found.
+func (s *service) linkDisabled(ctx context.Context, shortURL string) map[string]bool {
+	_, err := s.urls.GetURLRecord(ctx, shortURL)
+	switch {
+	case errors.Is(err, storage.ErrURLDisabled):
+		return map[string]bool{"disabled": true}
+	case err != nil:
+		return nil
+	}
+
+	return map[string]bool{"disabled": false}
+}
+
+func roleOrDefault(role string) string {
+	if role == "" {
+		return auth.RoleUser
+	}
+
+	return role
+}
@@ -0,0 +1,224 @@
+package admin
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/PrahaTurbo/url-shortener/internal/audit"
+	"github.com/PrahaTurbo/url-shortener/internal/auth"
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/mocks"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/memory"
+	"github.com/PrahaTurbo/url-shortener/internal/webhook"
+)
+
+func setupService(t *testing.T) (*service, storage.Repository, storage.UserRepository) {
+	log, _ := logger.Initialize("debug")
+	urls, err := memory.NewInMemStorage(filepath.Join(t.TempDir(), "db.json"), log)
+	require.NoError(t, err)
+	users := memory.NewUserStorage()
+	audits := audit.NewService(memory.NewAuditStorage("", log), log)
+
+	return NewService("http://localhost:8080", urls, users, audits).(*service), urls, users
+}
+
+// auditEntries returns the entries of the audit log of the service, newest first.
+func auditEntries(t *testing.T, s *service) []models.AuditEntryResponse {
+	page, err := s.audit.(audit.Service).Query(context.Background(), models.AuditQuery{})
+	require.NoError(t, err)
+
+	return page.Entries
+}
+
+func saveURL(t *testing.T, urls storage.Repository, userID, shortURL string) {
+	err := urls.SaveURL(context.Background(), entity.URLRecord{
+		UUID:        userID + shortURL,
+		ShortURL:    shortURL,
+		OriginalURL: "https://" + shortURL + ".example",
+		UserID:      userID,
+	})
+	require.NoError(t, err)
+}
+
+func TestService_ListUserLinks(t *testing.T) {
+	s, urls, _ := setupService(t)
+	ctx := context.Background()
+
+	saveURL(t, urls, "user", "abc")
+	_, err := urls.SetURLDisabled(ctx, "abc", true)
+	require.NoError(t, err)
+
+	links, err := s.ListUserLinks(ctx, "moderator", "user")
+	require.NoError(t, err)
+
+	assert.Equal(t, []models.AdminLinkResponse{{
+		ShortURL:    "http://localhost:8080/abc",
+		OriginalURL: "https://abc.example",
+		UserID:      "user",
+		Disabled:    true,
+	}}, links)
+
+	links, err = s.ListUserLinks(ctx, "moderator", "nobody")
+	require.NoError(t, err)
+	assert.Empty(t, links)
+
+	entries := auditEntries(t, s)
+	require.Len(t, entries, 2)
+	assert.Equal(t, audit.ActionUserLinksView, entries[1].Action)
+	assert.Equal(t, "moderator", entries[1].ActorID)
+	assert.Equal(t, "user", entries[1].Target)
+}
+
+func TestService_SetLinkDisabled(t *testing.T) {
+	s, urls, _ := setupService(t)
+	ctx := context.Background()
+
+	saveURL(t, urls, "user", "abc")
+
+	require.NoError(t, s.SetLinkDisabled(ctx, "moderator", "abc", true))
+
+	_, err := urls.GetURL(ctx, "abc")
+	assert.ErrorIs(t, err, storage.ErrURLDisabled)
+
+	require.NoError(t, s.SetLinkDisabled(ctx, "moderator", "abc", false))
+
+	originalURL, err := urls.GetURL(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://abc.example", originalURL)
+
+	assert.ErrorIs(t, s.SetLinkDisabled(ctx, "moderator", "unknown", true), ErrNotFound)
+
+	entries := auditEntries(t, s)
+	require.Len(t, entries, 2)
+	assert.Equal(t, audit.ActionLinkEnable, entries[0].Action)
+	assert.Equal(t, audit.ActionLinkDisable, entries[1].Action)
+	assert.Equal(t, "abc", entries[1].Target)
+}
+
+func TestService_publishesUpdatedLinks(t *testing.T) {
+	s, urls, _ := setupService(t)
+	ctx := context.Background()
+
+	publisher := mocks.NewMockWebhookPublisher(gomock.NewController(t))
+	s.publisher = publisher
+
+	saveURL(t, urls, "alice", "abc")
+	saveURL(t, urls, "bob", "abc")
+
+	// Every owner of the disabled link is notified.
+	disabled := true
+	for _, owner := range []string{"alice", "bob"} {
+		publisher.EXPECT().Publish(owner, webhook.EventLinkUpdated, models.LinkEvent{
+			ShortURL:    "http://localhost:8080/abc",
+			OriginalURL: "https://abc.example",
+			Disabled:    &disabled,
+		})
+	}
+
+	require.NoError(t, s.SetLinkDisabled(ctx, "moderator", "abc", true))
+
+	saveURL(t, urls, "alice", "def")
+
+	reassigned := models.LinkEvent{ShortURL: "http://localhost:8080/def", UserID: "carol"}
+	publisher.EXPECT().Publish("alice", webhook.EventLinkUpdated, reassigned)
+	publisher.EXPECT().Publish("carol", webhook.EventLinkUpdated, reassigned)
+
+	require.NoError(t, s.ReassignLink(ctx, "admin", "def", models.ReassignRequest{FromUserID: "alice", ToUserID: "carol"}))
+}
+
+func TestService_ReassignLink(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     models.ReassignRequest
+		short   string
+		wantErr error
+	}{
+		{
+			name:  "should reassign link successfully",
+			req:   models.ReassignRequest{FromUserID: "alice", ToUserID: "bob"},
+			short: "abc",
+		},
+		{
+			name:    "should reject missing user",
+			req:     models.ReassignRequest{FromUserID: "alice"},
+			short:   "abc",
+			wantErr: ErrInvalidRequest,
+		},
+		{
+			name:    "should reject link the user doesn't have",
+			req:     models.ReassignRequest{FromUserID: "bob", ToUserID: "carol"},
+			short:   "abc",
+			wantErr: ErrNotFound,
+		},
+		{
+			name:    "should reject link the other user already has",
+			req:     models.ReassignRequest{FromUserID: "alice", ToUserID: "bob"},
+			short:   "shared",
+			wantErr: ErrAlreadyOwned,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, urls, _ := setupService(t)
+			ctx := context.Background()
+
+			saveURL(t, urls, "alice", "abc")
+			saveURL(t, urls, "alice", "shared")
+			saveURL(t, urls, "bob", "shared")
+
+			err := s.ReassignLink(ctx, "admin", tt.short, tt.req)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.NoError(t, urls.CheckExistence(ctx, "abc", "bob"))
+			assert.Error(t, urls.CheckExistence(ctx, "abc", "alice"))
+
+			entries := auditEntries(t, s)
+			require.Len(t, entries, 1)
+			assert.Equal(t, audit.ActionLinkReassign, entries[0].Action)
+			assert.JSONEq(t, `{"user_id":"bob"}`, string(entries[0].After))
+		})
+	}
+}
+
+func TestService_SetUserRole(t *testing.T) {
+	s, _, users := setupService(t)
+	ctx := context.Background()
+
+	err := users.SaveUser(ctx, entity.User{ID: "alice", Username: "alice", CreatedAt: time.Now()})
+	require.NoError(t, err)
+
+	role, err := s.UserRole(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, auth.RoleUser, role)
+
+	assert.ErrorIs(t, s.SetUserRole(ctx, "admin", "alice", "root"), ErrInvalidRole)
+	assert.ErrorIs(t, s.SetUserRole(ctx, "admin", "anonymous", auth.RoleModerator), ErrUserNotFound)
+	require.NoError(t, s.SetUserRole(ctx, "admin", "alice", auth.RoleModerator))
+
+	role, err = s.UserRole(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, auth.RoleModerator, role)
+
+	role, err = s.UserRole(ctx, "anonymous")
+	require.NoError(t, err)
+	assert.Equal(t, auth.RoleUser, role)
+
+	entries := auditEntries(t, s)
+	require.Len(t, entries, 1)
+	assert.Equal(t, audit.ActionUserRole, entries[0].Action)
+	assert.JSONEq(t, `{"role":"moderator"}`, string(entries[0].After))
+}
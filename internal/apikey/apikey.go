@@ -0,0 +1,244 @@
+// Package apikey lets users issue API keys for programmatic clients such as servers and CI jobs.
+// Only the SHA-256 hash of a key is stored, so a key cannot be recovered once it's created.
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/PrahaTurbo/url-shortener/internal/audit"
+	"github.com/PrahaTurbo/url-shortener/internal/auth"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// Error variables, used in the API key service.
+var (
+	ErrInvalidScopes = errors.New("unknown api key scope")
+	ErrInvalidExpiry = errors.New("api key expiry must be in the future")
+	ErrNotFound      = errors.New("api key not found")
+)
+
+var allScopes = []string{auth.ScopeRead, auth.ScopeWrite, auth.ScopeDelete}
+
+// Service is an interface for managing the API keys of users and verifying the keys presented by clients.
+type Service interface {
+	Create(ctx context.Context, userID string, req models.APIKeyRequest) (*models.APIKeyResponse, error)
+	List(ctx context.Context, userID string) ([]models.APIKeyResponse, error)
+	Revoke(ctx context.Context, userID, id string) error
+	VerifyKey(ctx context.Context, key string) (*auth.APIKey, error)
+}
+
+type service struct {
+	repo  storage.APIKeyRepository
+	audit audit.Recorder
+	now   func() time.Time
+}
+
+// Option configures optional dependencies of the API key service.
+type Option func(s *service)
+
+// WithAuditLog enables recording of created and revoked API keys in the audit log.
+func WithAuditLog(r audit.Recorder) Option {
+	return func(s *service) {
+		s.audit = r
+	}
+}
+
+// NewService creates a new instance of the API key service.
+func NewService(repo storage.APIKeyRepository, opts ...Option) Service {
+	s := &service{
+		repo: repo,
+		now:  time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Create validates and stores a new API key for the user. The response includes
+// the generated key, which is never returned again.
+func (s *service) Create(ctx context.Context, userID string, req models.APIKeyRequest) (*models.APIKeyResponse, error) {
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	var expiresAt time.Time
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(s.now()) {
+			return nil, ErrInvalidExpiry
+		}
+
+		expiresAt = req.ExpiresAt.UTC()
+	}
+
+	key, err := generateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	k := entity.APIKey{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Name:      req.Name,
+		Hash:      hashKey(key),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+		CreatedAt: s.now().UTC(),
+	}
+
+	if err := s.repo.SaveAPIKey(ctx, k); err != nil {
+		return nil, err
+	}
+
+	resp := toResponse(k)
+	s.record(ctx, audit.Entry{ActorID: userID, Action: audit.ActionAPIKeyCreate, Target: k.ID, After: resp})
+
+	resp.Key = key
+
+	return &resp, nil
+}
+
+// List retrieves all the API keys of the user, including the revoked ones.
+func (s *service) List(ctx context.Context, userID string) ([]models.APIKeyResponse, error) {
+	keys, err := s.repo.GetAPIKeysByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]models.APIKeyResponse, 0, len(keys))
+	for _, k := range keys {
+		resp = append(resp, toResponse(k))
+	}
+
+	return resp, nil
+}
+
+// Revoke revokes the API key of the user, so it's no longer accepted.
+func (s *service) Revoke(ctx context.Context, userID, id string) error {
+	keys, err := s.repo.GetAPIKeysByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		if k.ID != id {
+			continue
+		}
+
+		if err := s.repo.RevokeAPIKey(ctx, id, userID); err != nil {
+			return err
+		}
+
+		before := toResponse(k)
+		after := before
+		after.Revoked = true
+		s.record(ctx, audit.Entry{ActorID: userID, Action: audit.ActionAPIKeyRevoke, Target: id, Before: before, After: after})
+
+		return nil
+	}
+
+	return ErrNotFound
+}
+
+// VerifyKey looks up the key presented by a client. It returns auth.ErrInvalidAPIKey
+// if the key is unknown, revoked or expired.
+func (s *service) VerifyKey(ctx context.Context, key string) (*auth.APIKey, error) {
+	k, err := s.repo.GetAPIKeyByHash(ctx, hashKey(key))
+	if err != nil {
+		return nil, err
+	}
+
+	if k == nil || k.Revoked {
+		return nil, auth.ErrInvalidAPIKey
+	}
+
+	if !k.ExpiresAt.IsZero() && !k.ExpiresAt.After(s.now()) {
+		return nil, auth.ErrInvalidAPIKey
+	}
+
+	apiKey := &auth.APIKey{
+		ID:     k.ID,
+		UserID: k.UserID,
+		Scopes: k.Scopes,
+	}
+
+	return apiKey, nil
+}
+
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return allScopes, nil
+	}
+
+	requested := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		requested[scope] = true
+	}
+
+	normalized := make([]string, 0, len(requested))
+	for _, scope := range allScopes {
+		if requested[scope] {
+			normalized = append(normalized, scope)
+			delete(requested, scope)
+		}
+	}
+
+	if len(requested) > 0 {
+		return nil, ErrInvalidScopes
+	}
+
+	return normalized, nil
+}
+
+func toResponse(k entity.APIKey) models.APIKeyResponse {
+	resp := models.APIKeyResponse{
+		ID:        k.ID,
+		Name:      k.Name,
+		Scopes:    k.Scopes,
+		Revoked:   k.Revoked,
+		CreatedAt: k.CreatedAt,
+	}
+
+	if !k.ExpiresAt.IsZero() {
+		expiresAt := k.ExpiresAt
+		resp.ExpiresAt = &expiresAt
+	}
+
+	return resp
+}
+
+func (s *service) record(ctx context.Context, e audit.Entry) {
+	if s.audit == nil {
+		return
+	}
+
+	s.audit.Record(ctx, e)
+}
+
+func generateKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return auth.APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(sum[:])
+}
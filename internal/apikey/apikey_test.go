@@ -0,0 +1,117 @@
+package apikey
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PrahaTurbo/url-shortener/internal/auth"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/memory"
+)
+
+var now = time.Date(2023, time.November, 1, 12, 0, 0, 0, time.UTC)
+
+func setupService() *service {
+	s := NewService(memory.NewAPIKeyStorage()).(*service)
+	s.now = func() time.Time { return now }
+
+	return s
+}
+
+func TestService_Create(t *testing.T) {
+	future := now.Add(time.Hour)
+	past := now.Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		req     models.APIKeyRequest
+		scopes  []string
+		wantErr error
+	}{
+		{
+			name:   "should grant all scopes if none requested",
+			req:    models.APIKeyRequest{Name: "ci"},
+			scopes: []string{auth.ScopeRead, auth.ScopeWrite, auth.ScopeDelete},
+		},
+		{
+			name:   "should keep requested scopes only once",
+			req:    models.APIKeyRequest{Name: "ci", Scopes: []string{auth.ScopeWrite, auth.ScopeRead, auth.ScopeWrite}, ExpiresAt: &future},
+			scopes: []string{auth.ScopeRead, auth.ScopeWrite},
+		},
+		{
+			name:    "should reject unknown scope",
+			req:     models.APIKeyRequest{Name: "ci", Scopes: []string{"admin"}},
+			wantErr: ErrInvalidScopes,
+		},
+		{
+			name:    "should reject expiry in the past",
+			req:     models.APIKeyRequest{Name: "ci", ExpiresAt: &past},
+			wantErr: ErrInvalidExpiry,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := setupService()
+
+			resp, err := s.Create(context.Background(), "1", tt.req)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(resp.Key, auth.APIKeyPrefix))
+			assert.Equal(t, tt.scopes, resp.Scopes)
+
+			keys, err := s.List(context.Background(), "1")
+			require.NoError(t, err)
+			require.Len(t, keys, 1)
+			assert.Empty(t, keys[0].Key, "the key should never be returned again")
+		})
+	}
+}
+
+func TestService_VerifyKey(t *testing.T) {
+	s := setupService()
+	ctx := context.Background()
+
+	expiresAt := now.Add(time.Hour)
+	resp, err := s.Create(ctx, "1", models.APIKeyRequest{Name: "ci", Scopes: []string{auth.ScopeRead}, ExpiresAt: &expiresAt})
+	require.NoError(t, err)
+
+	key, err := s.VerifyKey(ctx, resp.Key)
+	require.NoError(t, err)
+	assert.Equal(t, &auth.APIKey{ID: resp.ID, UserID: "1", Scopes: []string{auth.ScopeRead}}, key)
+
+	_, err = s.VerifyKey(ctx, resp.Key+"x")
+	assert.ErrorIs(t, err, auth.ErrInvalidAPIKey, "unknown key should be rejected")
+
+	s.now = func() time.Time { return expiresAt }
+	_, err = s.VerifyKey(ctx, resp.Key)
+	assert.ErrorIs(t, err, auth.ErrInvalidAPIKey, "expired key should be rejected")
+}
+
+func TestService_Revoke(t *testing.T) {
+	s := setupService()
+	ctx := context.Background()
+
+	resp, err := s.Create(ctx, "1", models.APIKeyRequest{Name: "ci"})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, s.Revoke(ctx, "2", resp.ID), ErrNotFound, "other users cannot revoke the key")
+	require.NoError(t, s.Revoke(ctx, "1", resp.ID))
+
+	_, err = s.VerifyKey(ctx, resp.Key)
+	assert.ErrorIs(t, err, auth.ErrInvalidAPIKey)
+
+	keys, err := s.List(ctx, "1")
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.True(t, keys[0].Revoked)
+}
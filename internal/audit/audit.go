@@ -0,0 +1,216 @@
+// Package audit records an immutable trail of the changes made to the links, accounts and settings,
+// and of the links of users viewed by moderators and administrators: who acted, from which client IP,
+// through which transport and in which request, and the values before and after a change.
+// Entries are only ever appended to the log.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// Transports the changes are requested through.
+const (
+	TransportHTTP = "http"
+	TransportGRPC = "grpc"
+)
+
+// Actions recorded in the audit log.
+const (
+	ActionLinkCreate            = "link.create"
+	ActionLinkDelete            = "link.delete"
+	ActionLinkDisable           = "link.disable"
+	ActionLinkEnable            = "link.enable"
+	ActionLinkReassign          = "link.reassign"
+	ActionLinkMove              = "link.move"
+	ActionLinkExpire            = "link.expire"
+	ActionUserRegister          = "user.register"
+	ActionUserLogin             = "user.login"
+	ActionUserLoginFailed       = "user.login_failed"
+	ActionUserRole              = "user.role"
+	ActionUserLinksView         = "user.links_view"
+	ActionSessionRefresh        = "session.refresh"
+	ActionSessionRevoke         = "session.revoke"
+	ActionAPIKeyCreate          = "api_key.create"
+	ActionAPIKeyRevoke          = "api_key.revoke"
+	ActionWebhookCreate         = "webhook.create"
+	ActionWebhookDelete         = "webhook.delete"
+	ActionQuotaSet              = "quota.set"
+	ActionQuotaReset            = "quota.reset"
+	ActionWorkspaceCreate       = "workspace.create"
+	ActionWorkspaceMemberSet    = "workspace.member_set"
+	ActionWorkspaceMemberRemove = "workspace.member_remove"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+)
+
+// ErrInvalidQuery is returned by the Query for a negative offset or a limit above 500.
+var ErrInvalidQuery = errors.New("limit must be from 0 to 500 and offset must not be negative")
+
+// Entry describes a change to record. Before and After are the changed values and are encoded as JSON;
+// nil means there was nothing before or after the change. The Target identifies the changed object,
+// e.g. a short URL or a user ID.
+type Entry struct {
+	ActorID string
+	Action  string
+	Target  string
+	Before  interface{}
+	After   interface{}
+}
+
+// Recorder is an interface for recording changes in the audit log.
+// RecordBatch records several changes made by one request at once.
+type Recorder interface {
+	Record(ctx context.Context, e Entry)
+	RecordBatch(ctx context.Context, entries []Entry)
+}
+
+// Service is an interface for recording changes in the audit log and querying it.
+type Service interface {
+	Recorder
+	Query(ctx context.Context, q models.AuditQuery) (*models.AuditPage, error)
+}
+
+type service struct {
+	repo   storage.AuditRepository
+	logger *logger.Logger
+	now    func() time.Time
+}
+
+// NewService creates a new instance of the audit service.
+func NewService(repo storage.AuditRepository, logger *logger.Logger) Service {
+	return &service{
+		repo:   repo,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Record appends the change to the audit log along with the source of the request from the context.
+// Changes are recorded after they're made, so a failure to record one is logged rather than returned.
+func (s *service) Record(ctx context.Context, e Entry) {
+	s.RecordBatch(ctx, []Entry{e})
+}
+
+// RecordBatch appends the changes to the audit log in a single write, along with the source of the request
+// from the context. A failure to record them is logged rather than returned, like in the Record.
+func (s *service) RecordBatch(ctx context.Context, entries []Entry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	src := SourceFromContext(ctx)
+	now := s.now().UTC()
+
+	records := make([]entity.AuditEntry, 0, len(entries))
+	for _, e := range entries {
+		records = append(records, entity.AuditEntry{
+			ID:        uuid.New().String(),
+			ActorID:   e.ActorID,
+			Action:    e.Action,
+			Target:    e.Target,
+			Transport: src.Transport,
+			ClientIP:  src.ClientIP,
+			RequestID: src.RequestID,
+			Before:    s.encode(e.Before),
+			After:     s.encode(e.After),
+			CreatedAt: now,
+		})
+	}
+
+	if err := s.repo.SaveAuditEntries(ctx, records); err != nil {
+		s.logger.Error("cannot record audit entries", zap.Error(err), zap.String("action", entries[0].Action),
+			zap.Int("count", len(entries)), zap.String("request id", src.RequestID))
+	}
+}
+
+// Query returns a page of the entries matching the query, newest first. A zero limit means 50 entries.
+func (s *service) Query(ctx context.Context, q models.AuditQuery) (*models.AuditPage, error) {
+	if q.Limit < 0 || q.Limit > maxLimit || q.Offset < 0 {
+		return nil, ErrInvalidQuery
+	}
+
+	limit := q.Limit
+	if limit == 0 {
+		limit = defaultLimit
+	}
+
+	// One more entry than requested tells whether there is a next page.
+	entries, err := s.repo.GetAuditEntries(ctx, entity.AuditFilter{
+		ActorID:   q.ActorID,
+		Action:    q.Action,
+		Target:    q.Target,
+		Transport: q.Transport,
+		Since:     q.Since,
+		Until:     q.Until,
+		Limit:     limit + 1,
+		Offset:    q.Offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.AuditPage{Entries: make([]models.AuditEntryResponse, 0, len(entries))}
+
+	if len(entries) > limit {
+		entries = entries[:limit]
+		page.NextOffset = q.Offset + limit
+	}
+
+	for _, e := range entries {
+		page.Entries = append(page.Entries, models.AuditEntryResponse{
+			ID:        e.ID,
+			ActorID:   e.ActorID,
+			Action:    e.Action,
+			Target:    e.Target,
+			Transport: e.Transport,
+			ClientIP:  e.ClientIP,
+			RequestID: e.RequestID,
+			Before:    rawJSON(e.Before),
+			After:     rawJSON(e.After),
+			CreatedAt: e.CreatedAt,
+		})
+	}
+
+	return page, nil
+}
+
+func (s *service) encode(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		s.logger.Error("cannot encode audit value", zap.Error(err))
+		return ""
+	}
+
+	// Typed nils, e.g. nil pointers and maps, mean there was no value as well.
+	if string(data) == "null" {
+		return ""
+	}
+
+	return string(data)
+}
+
+func rawJSON(s string) json.RawMessage {
+	if s == "" {
+		return nil
+	}
+
+	return json.RawMessage(s)
+}
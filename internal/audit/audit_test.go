@@ -0,0 +1,216 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/memory"
+)
+
+func setupService(t *testing.T, filePath string) *service {
+	log, _ := logger.Initialize("debug")
+	s := NewService(memory.NewAuditStorage(filePath, log), log).(*service)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	}
+
+	return s
+}
+
+func TestService_Record(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "audit.json")
+	s := setupService(t, filePath)
+
+	ctx := WithSource(context.Background(), models.RequestSource{
+		Transport: TransportHTTP,
+		ClientIP:  "192.0.2.1",
+		RequestID: "req-1",
+	})
+
+	s.Record(ctx, Entry{
+		ActorID: "alice",
+		Action:  ActionLinkDelete,
+		Target:  "abc",
+		Before:  map[string]bool{"deleted": false},
+		After:   map[string]bool{"deleted": true},
+	})
+
+	// The entries are restored from the file.
+	s = setupService(t, filePath)
+
+	page, err := s.Query(context.Background(), models.AuditQuery{})
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 1)
+
+	e := page.Entries[0]
+	assert.Equal(t, "alice", e.ActorID)
+	assert.Equal(t, ActionLinkDelete, e.Action)
+	assert.Equal(t, "abc", e.Target)
+	assert.Equal(t, TransportHTTP, e.Transport)
+	assert.Equal(t, "192.0.2.1", e.ClientIP)
+	assert.Equal(t, "req-1", e.RequestID)
+	assert.JSONEq(t, `{"deleted": false}`, string(e.Before))
+	assert.JSONEq(t, `{"deleted": true}`, string(e.After))
+	assert.Zero(t, page.NextOffset)
+}
+
+func TestService_RecordBatch(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "audit.json")
+	s := setupService(t, filePath)
+
+	ctx := WithSource(context.Background(), models.RequestSource{Transport: TransportGRPC, RequestID: "req-2"})
+
+	s.RecordBatch(ctx, nil)
+	s.RecordBatch(ctx, []Entry{
+		{ActorID: "alice", Action: ActionLinkCreate, Target: "abc"},
+		{ActorID: "alice", Action: ActionLinkCreate, Target: "def"},
+	})
+
+	s = setupService(t, filePath)
+
+	page, err := s.Query(context.Background(), models.AuditQuery{})
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 2)
+
+	for _, e := range page.Entries {
+		assert.Equal(t, TransportGRPC, e.Transport)
+		assert.Equal(t, "req-2", e.RequestID)
+	}
+	assert.ElementsMatch(t, []string{"abc", "def"}, []string{page.Entries[0].Target, page.Entries[1].Target})
+	assert.NotEqual(t, page.Entries[0].ID, page.Entries[1].ID)
+}
+
+func TestService_Query(t *testing.T) {
+	s := setupService(t, "")
+	ctx := context.Background()
+
+	for _, target := range []string{"a", "b", "c", "d", "e"} {
+		s.Record(ctx, Entry{ActorID: "alice", Action: ActionLinkCreate, Target: target})
+	}
+	s.Record(ctx, Entry{ActorID: "bob", Action: ActionLinkDelete, Target: "a"})
+
+	tests := []struct {
+		name       string
+		query      models.AuditQuery
+		targets    []string
+		nextOffset int
+		wantErr    error
+	}{
+		{
+			name:       "should return first page newest first",
+			query:      models.AuditQuery{ActorID: "alice", Limit: 2},
+			targets:    []string{"e", "d"},
+			nextOffset: 2,
+		},
+		{
+			name:    "should return last page without next offset",
+			query:   models.AuditQuery{ActorID: "alice", Limit: 2, Offset: 4},
+			targets: []string{"a"},
+		},
+		{
+			name:    "should filter by action and target",
+			query:   models.AuditQuery{Action: ActionLinkDelete, Target: "a"},
+			targets: []string{"a"},
+		},
+		{
+			name: "should filter by time",
+			query: models.AuditQuery{
+				Since: time.Date(2024, 1, 1, 0, 2, 0, 0, time.UTC),
+				Until: time.Date(2024, 1, 1, 0, 4, 0, 0, time.UTC),
+			},
+			targets: []string{"c", "b"},
+		},
+		{
+			name:    "should reject too big limit",
+			query:   models.AuditQuery{Limit: 501},
+			wantErr: ErrInvalidQuery,
+		},
+		{
+			name:    "should reject negative offset",
+			query:   models.AuditQuery{Offset: -1},
+			wantErr: ErrInvalidQuery,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := s.Query(ctx, tt.query)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+
+			targets := make([]string, 0, len(page.Entries))
+			for _, e := range page.Entries {
+				targets = append(targets, e.Target)
+			}
+
+			assert.Equal(t, tt.targets, targets)
+			assert.Equal(t, tt.nextOffset, page.NextOffset)
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name      string
+		requestID string
+		want      string
+	}{
+		{
+			name:      "should keep request id of the client",
+			requestID: "req-1",
+			want:      "req-1",
+		},
+		{
+			name:      "should replace request id with spaces",
+			requestID: "req 1",
+		},
+		{
+			name: "should generate missing request id",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var src models.RequestSource
+			h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				src = SourceFromContext(r.Context())
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = "192.0.2.1:1234"
+			if tt.requestID != "" {
+				r.Header.Set(RequestIDHeader, tt.requestID)
+			}
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, r)
+
+			assert.Equal(t, TransportHTTP, src.Transport)
+			assert.Equal(t, "192.0.2.1", src.ClientIP)
+			assert.NotEmpty(t, src.RequestID)
+			assert.Equal(t, src.RequestID, w.Header().Get(RequestIDHeader))
+
+			if tt.want != "" {
+				assert.Equal(t, tt.want, src.RequestID)
+			} else {
+				assert.NotEqual(t, tt.requestID, src.RequestID)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package audit
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/PrahaTurbo/url-shortener/internal/clientip"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+)
+
+// RequestIDHeader is the header carrying the ID of the request. The ID is taken from the request
+// if the client sets it and is echoed in the response. gRPC uses its lowercase name as the metadata key.
+const RequestIDHeader = "X-Request-Id"
+
+const maxRequestIDLength = 128
+
+type contextKey struct{}
+
+// WithSource returns a copy of the context carrying the source of the request.
+func WithSource(ctx context.Context, src models.RequestSource) context.Context {
+	return context.WithValue(ctx, contextKey{}, src)
+}
+
+// SourceFromContext returns the source of the request set in the context, or a zero source.
+func SourceFromContext(ctx context.Context) models.RequestSource {
+	src, _ := ctx.Value(contextKey{}).(models.RequestSource)
+
+	return src
+}
+
+// Middleware sets the source of the HTTP request in the request context and the request ID in the response.
+// It must run after the client IP is resolved.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		src := models.RequestSource{
+			Transport: TransportHTTP,
+			ClientIP:  ipString(clientip.FromRequest(r)),
+			RequestID: requestID(r.Header.Get(RequestIDHeader)),
+		}
+
+		w.Header().Set(RequestIDHeader, src.RequestID)
+
+		next.ServeHTTP(w, r.WithContext(WithSource(r.Context(), src)))
+	})
+}
+
+// UnaryServerInterceptor sets the source of the gRPC call in the context and the request ID in the response header.
+// It must run after the client IP is resolved.
+func UnaryServerInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(RequestIDHeader); len(values) > 0 {
+			id = values[0]
+		}
+	}
+
+	src := models.RequestSource{
+		Transport: TransportGRPC,
+		ClientIP:  ipString(clientip.FromGRPC(ctx)),
+		RequestID: requestID(id),
+	}
+
+	// The header can only fail to be set if the handler already sent it, which it hasn't yet.
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, src.RequestID))
+
+	return handler(WithSource(ctx, src), req)
+}
+
+// requestID returns the ID set by the client, or a new one if the client didn't set a usable ID.
+func requestID(id string) string {
+	if id == "" || len(id) > maxRequestIDLength {
+		return uuid.New().String()
+	}
+
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return uuid.New().String()
+		}
+	}
+
+	return id
+}
+
+func ipString(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+
+	return ip.String()
+}
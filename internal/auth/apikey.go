@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+)
+
+// Scopes an API key can be restricted to.
+const (
+	ScopeRead   = "read"
+	ScopeWrite  = "write"
+	ScopeDelete = "delete"
+)
+
+// APIKeyPrefix is the prefix of every API key, which tells the keys apart from JWT tokens.
+const APIKeyPrefix = "sk_"
+
+const (
+	apiKeyHeader   = "X-API-Key"
+	apiKeyMetadata = "x-api-key"
+)
+
+// ErrInvalidAPIKey is returned by a KeyVerifier if the key is unknown, revoked or expired.
+var ErrInvalidAPIKey = errors.New("api key is invalid")
+
+// APIKey describes the API key a request was authenticated with.
+type APIKey struct {
+	ID     string
+	UserID string
+	Scopes []string
+}
+
+// KeyVerifier is an interface for verifying the API keys presented by clients.
+type KeyVerifier interface {
+	VerifyKey(ctx context.Context, key string) (*APIKey, error)
+}
+
+type apiKeyCtxKey struct{}
+
+// APIKeyFromContext extracts the API key the request was authenticated with from the context.
+// It reports false if the request was authenticated with a JWT token.
+func APIKeyFromContext(ctx context.Context) (*APIKey, bool) {
+	key, ok := ctx.Value(apiKeyCtxKey{}).(*APIKey)
+
+	return key, ok
+}
+
+// HasScope reports whether the request is allowed to perform actions of the scope.
+// Requests authenticated with a JWT token have all the scopes.
+func HasScope(ctx context.Context, scope string) bool {
+	key, ok := APIKeyFromContext(ctx)
+	if !ok {
+		return true
+	}
+
+	for _, s := range key.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RequireScope returns a middleware that responds with 403 to the requests authenticated
+// with an API key lacking the scope.
+func (a *Auth) RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !HasScope(r.Context(), scope) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RejectAPIKeysHTTP is a middleware that responds with 403 to the requests authenticated with an API key,
+// so the keys cannot be used to manage the keys themselves.
+func (a *Auth) RejectAPIKeysHTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := APIKeyFromContext(r.Context()); ok {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *Auth) verifyKey(ctx context.Context, key string) (*APIKey, error) {
+	if a.keys == nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	apiKey, err := a.keys.VerifyKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return apiKey, nil
+}
+
+func (a *Auth) logKeyUsage(key *APIKey, fields ...zap.Field) {
+	if a.logger == nil {
+		return
+	}
+
+	fields = append(fields, zap.String("key id", key.ID), zap.String("user id", key.UserID))
+	a.logger.Info("api key used", fields...)
+}
+
+func withAPIKey(ctx context.Context, key *APIKey) context.Context {
+	ctx = context.WithValue(ctx, UserIDKey, key.UserID)
+
+	return context.WithValue(ctx, apiKeyCtxKey{}, key)
+}
+
+func apiKeyFromRequest(r *http.Request) (string, bool) {
+	if key := r.Header.Get(apiKeyHeader); key != "" {
+		return key, true
+	}
+
+	return apiKeyFromBearer(r.Header.Get("Authorization"))
+}
+
+func apiKeyFromMetadata(md metadata.MD) (string, bool) {
+	if values := md.Get(apiKeyMetadata); len(values) > 0 {
+		return values[0], true
+	}
+
+	if values := md.Get(authentication); len(values) > 0 {
+		return apiKeyFromBearer(values[0])
+	}
+
+	return "", false
+}
+
+// apiKeyFromBearer extracts the API key from the value of an authorization header.
+// Bearer tokens without APIKeyPrefix are JWT tokens and are not reported.
+func apiKeyFromBearer(header string) (string, bool) {
+	splits := strings.SplitN(header, " ", 2)
+	if len(splits) < 2 || strings.ToLower(splits[0]) != bearerSchema {
+		return "", false
+	}
+
+	if !strings.HasPrefix(splits[1], APIKeyPrefix) {
+		return "", false
+	}
+
+	return splits[1], true
+}
@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type fakeVerifier map[string]*APIKey
+
+func (f fakeVerifier) VerifyKey(_ context.Context, key string) (*APIKey, error) {
+	if key == "sk_broken" {
+		return nil, errors.New("storage is down")
+	}
+
+	k, ok := f[key]
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+
+	return k, nil
+}
+
+var testKeys = fakeVerifier{
+	"sk_full": {ID: "k1", UserID: "u1", Scopes: []string{ScopeRead, ScopeWrite, ScopeDelete}},
+	"sk_read": {ID: "k2", UserID: "u2", Scopes: []string{ScopeRead}},
+}
+
+func TestAuth_BasicMiddlewareHTTP_apiKeys(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		scope  string
+		status int
+		userID string
+	}{
+		{
+			name:   "key in X-API-Key header",
+			header: "X-API-Key",
+			value:  "sk_full",
+			scope:  ScopeWrite,
+			status: http.StatusOK,
+			userID: "u1",
+		},
+		{
+			name:   "key as Bearer token",
+			header: "Authorization",
+			value:  "Bearer sk_read",
+			scope:  ScopeRead,
+			status: http.StatusOK,
+			userID: "u2",
+		},
+		{
+			name:   "key lacking the scope",
+			header: "X-API-Key",
+			value:  "sk_read",
+			scope:  ScopeDelete,
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "unknown key",
+			header: "X-API-Key",
+			value:  "sk_unknown",
+			scope:  ScopeRead,
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "verifier failure",
+			header: "X-API-Key",
+			value:  "sk_broken",
+			scope:  ScopeRead,
+			status: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/user/urls", nil)
+			req.Header.Set(tt.header, tt.value)
+
+			rr := httptest.NewRecorder()
+
+			var userID string
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				userID, _ = UserIDFromContext(r.Context())
+			})
+
+			a := NewAuth(testJWTsecret, "", WithAPIKeys(testKeys))
+			a.BasicMiddlewareHTTP(a.RequireScope(tt.scope)(handler)).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.status, rr.Code)
+			assert.Equal(t, tt.userID, userID)
+			assert.Empty(t, rr.Header().Get("Set-Cookie"), "no cookie should be created for api keys")
+		})
+	}
+}
+
+func TestAuth_RejectAPIKeysHTTP(t *testing.T) {
+	a := NewAuth(testJWTsecret, "", WithAPIKeys(testKeys))
+	handler := a.BasicMiddlewareHTTP(a.RejectAPIKeysHTTP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/user/keys", nil)
+	req.Header.Set("X-API-Key", "sk_full")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/user/keys", nil)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code, "cookie sessions can manage keys")
+}
+
+func TestAuth_UnaryServerInterceptor_apiKeys(t *testing.T) {
+	const method = "/shortener.URLShortener/DeleteURLs"
+
+	tests := []struct {
+		name string
+		md   map[string]string
+		want codes.Code
+	}{
+		{
+			name: "key in x-api-key metadata",
+			md:   map[string]string{"x-api-key": "sk_full"},
+			want: codes.OK,
+		},
+		{
+			name: "key as bearer token",
+			md:   map[string]string{"authorization": "bearer sk_full"},
+			want: codes.OK,
+		},
+		{
+			name: "key lacking the scope",
+			md:   map[string]string{"x-api-key": "sk_read"},
+			want: codes.PermissionDenied,
+		},
+		{
+			name: "unknown key",
+			md:   map[string]string{"x-api-key": "sk_unknown"},
+			want: codes.Unauthenticated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAuth(testJWTsecret, "", WithAPIKeys(testKeys), WithMethodScopes(map[string]string{method: ScopeDelete}))
+
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.New(tt.md))
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				userID, ok := UserIDFromContext(ctx)
+				assert.True(t, ok)
+				assert.Equal(t, "u1", userID)
+
+				return nil, nil
+			}
+
+			_, err := a.UnaryServerInterceptor(ctx, "request", &grpc.UnaryServerInfo{FullMethod: method}, handler)
+			assert.Equal(t, tt.want, status.Code(err))
+		})
+	}
+}
@@ -2,17 +2,23 @@ package auth
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"net"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/google/uuid"
+	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+
+	"github.com/PrahaTurbo/url-shortener/internal/audit"
+	"github.com/PrahaTurbo/url-shortener/internal/clientip"
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
 )
 
 // UserIDKeyType is a custom string type that will be used as a key in request context.
@@ -28,29 +34,193 @@ const (
 )
 
 // Claims represents the custom claims we're using in JWT tokens.
+// The Role is omitted for ordinary users.
 type Claims struct {
 	jwt.RegisteredClaims
 	UserID string
+	Role   string `json:"role,omitempty"`
 }
 
 type Auth struct {
-	secret        string
-	trustedSubnet string
+	secret         string
+	trustedSubnet  string
+	trustedNets    []*net.IPNet
+	trustedNetsErr error
+	ring           *KeyRing
+	accessTTL      time.Duration
+	refreshTTL     time.Duration
+	tokens         storage.RefreshTokenRepository
+	issuers        map[string]*Issuer
+	keys           KeyVerifier
+	methodScopes   map[string]string
+	methodRoles    map[string]string
+	roles          RoleResolver
+	publicMethods  map[string]bool
+	adminMethods   []string
+	logger         *logger.Logger
+	audit          audit.Recorder
+	legacyUntil    time.Time
+	purgeInterval  time.Duration
+	now            func() time.Time
+}
+
+// Option configures optional features of the Auth.
+type Option func(a *Auth)
+
+// WithKeyRing sets the keys JWT tokens are signed and verified with.
+// Without it, tokens are signed with the JWT secret under the DefaultKeyID.
+func WithKeyRing(ring *KeyRing) Option {
+	return func(a *Auth) {
+		a.ring = ring
+	}
+}
+
+// WithIssuers enables authentication with the tokens of external issuers. The user id of
+// such a token is a UUID derived from its issuer and its subject.
+func WithIssuers(issuers ...*Issuer) Option {
+	return func(a *Auth) {
+		for _, issuer := range issuers {
+			a.issuers[issuer.name] = issuer
+		}
+	}
+}
+
+// WithTokenTTL sets the lifetime of access and refresh tokens.
+func WithTokenTTL(access, refresh time.Duration) Option {
+	return func(a *Auth) {
+		a.accessTTL = access
+		a.refreshTTL = refresh
+	}
+}
+
+// WithRefreshTokens enables refresh tokens, stored in the repo so they can be revoked.
+func WithRefreshTokens(repo storage.RefreshTokenRepository) Option {
+	return func(a *Auth) {
+		a.tokens = repo
+	}
 }
 
-func NewAuth(secret string, subnet string) *Auth {
-	return &Auth{
+// WithAPIKeys enables authentication with API keys, verified by the verifier.
+func WithAPIKeys(v KeyVerifier) Option {
+	return func(a *Auth) {
+		a.keys = v
+	}
+}
+
+// WithMethodScopes sets the scopes an API key needs to call the gRPC methods, keyed by full method name.
+func WithMethodScopes(scopes map[string]string) Option {
+	return func(a *Auth) {
+		a.methodScopes = scopes
+	}
+}
+
+// WithRoles enables the roles of users, looked up by the resolver whenever a session is issued.
+// A role change takes effect once the access token of the user is renewed.
+func WithRoles(r RoleResolver) Option {
+	return func(a *Auth) {
+		a.roles = r
+	}
+}
+
+// WithMethodRoles sets the roles needed to call the gRPC methods, keyed by full method name.
+// A method ending with "/" stands for all the methods of a service.
+func WithMethodRoles(roles map[string]string) Option {
+	return func(a *Auth) {
+		a.methodRoles = roles
+	}
+}
+
+// WithPublicMethods allows the gRPC methods, keyed by full method name, to be called without a token.
+// A valid token presented to such a method still sets the user id in the context.
+func WithPublicMethods(methods ...string) Option {
+	return func(a *Auth) {
+		for _, method := range methods {
+			a.publicMethods[method] = true
+		}
+	}
+}
+
+// WithAdminMethods restricts the gRPC methods to the clients of the trusted subnets, as AdminMiddlewareHTTP
+// does for HTTP. A method ending with "/" stands for all the methods of a service, e.g. "/shortener.URLShortener/".
+// The admin methods don't need a token, unless WithMethodRoles requires a role for them too.
+func WithAdminMethods(methods ...string) Option {
+	return func(a *Auth) {
+		a.adminMethods = append(a.adminMethods, methods...)
+	}
+}
+
+// WithLogger enables logging of the API key usage.
+func WithLogger(l *logger.Logger) Option {
+	return func(a *Auth) {
+		a.logger = l
+	}
+}
+
+// WithAuditLog enables recording of session refreshes and revocations in the audit log.
+func WithAuditLog(r audit.Recorder) Option {
+	return func(a *Auth) {
+		a.audit = r
+	}
+}
+
+// WithLegacyTokensUntil accepts the cookies of the tokens issued before the key ring was introduced
+// until the cutoff, exchanging each of them for a new session of its user. Such tokens have no issue
+// or expiration time, so they can't expire by themselves: the cutoff ends the migration window for
+// all of them at once. Without it, they are ignored like expired tokens.
+func WithLegacyTokensUntil(cutoff time.Time) Option {
+	return func(a *Auth) {
+		a.legacyUntil = cutoff
+	}
+}
+
+// NewAuth creates an Auth signing tokens with the secret. The subnet is a comma separated list of
+// the IPv4 and IPv6 CIDRs admin access is allowed from.
+func NewAuth(secret string, subnet string, opts ...Option) *Auth {
+	a := &Auth{
 		secret:        secret,
 		trustedSubnet: subnet,
+		ring:          NewKeyRing(DefaultKeyID, []byte(secret)),
+		accessTTL:     DefaultAccessTokenTTL,
+		refreshTTL:    DefaultRefreshTokenTTL,
+		issuers:       make(map[string]*Issuer),
+		publicMethods: make(map[string]bool),
+		purgeInterval: refreshTokenPurgeInterval,
+		now:           time.Now,
+	}
+
+	a.trustedNets, a.trustedNetsErr = clientip.ParseCIDRs(subnet)
+
+	for _, opt := range opts {
+		opt(a)
 	}
+
+	return a
 }
 
 func (a *Auth) UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if info != nil && a.isAdminMethod(info.FullMethod) {
+		if err := a.checkTrustedSubnet(ctx); err != nil {
+			return nil, err
+		}
+
+		if _, ok := a.methodRole(info.FullMethod); !ok {
+			return handler(a.optionalUserID(ctx), req)
+		}
+	}
+
+	if info != nil && a.publicMethods[info.FullMethod] {
+		return handler(a.optionalUserID(ctx), req)
+	}
+
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
 		return nil, status.Errorf(codes.Unauthenticated, "metadata is not provided")
 	}
 
+	if key, ok := apiKeyFromMetadata(md); ok {
+		return a.interceptWithAPIKey(ctx, key, req, info, handler)
+	}
+
 	authHeader, ok := md[authentication]
 	if !ok {
 		return nil, status.Errorf(codes.Unauthenticated, "authorization token is not provided")
@@ -65,18 +235,12 @@ func (a *Auth) UnaryServerInterceptor(ctx context.Context, req interface{}, info
 		return nil, status.Errorf(codes.Unauthenticated, "the token is not a Bearer token")
 	}
 
-	tokenString := splits[1]
-
-	claims := &Claims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-		}
-
-		return []byte(a.secret), nil
-	})
+	claims, err := a.parseAccessToken(splits[1])
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, status.Errorf(codes.Unauthenticated, "the token is expired")
+	}
 
-	if err != nil || !token.Valid {
+	if err != nil {
 		return nil, status.Errorf(codes.Unauthenticated, "the token is invalid")
 	}
 
@@ -84,66 +248,245 @@ func (a *Auth) UnaryServerInterceptor(ctx context.Context, req interface{}, info
 		return nil, status.Errorf(codes.Unauthenticated, "user_id is empty")
 	}
 
-	newCtx := context.WithValue(ctx, UserIDKey, claims.UserID)
+	ctx = withClaims(ctx, claims)
+
+	if info != nil {
+		if role, ok := a.methodRole(info.FullMethod); ok && !HasRole(ctx, role) {
+			return nil, status.Errorf(codes.PermissionDenied, "the %s role is required", role)
+		}
+	}
+
+	return handler(ctx, req)
+}
+
+func (a *Auth) isAdminMethod(method string) bool {
+	for _, m := range a.adminMethods {
+		if m == method || strings.HasSuffix(m, "/") && strings.HasPrefix(method, m) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkTrustedSubnet checks that the gRPC client is in one of the trusted subnets.
+func (a *Auth) checkTrustedSubnet(ctx context.Context) error {
+	if a.trustedSubnet == "" {
+		return status.Errorf(codes.PermissionDenied, "trusted subnet is not configured")
+	}
+
+	if a.trustedNetsErr != nil {
+		return status.Errorf(codes.Internal, "internal server error")
+	}
+
+	if !clientip.Contains(a.trustedNets, clientip.FromGRPC(ctx)) {
+		return status.Errorf(codes.PermissionDenied, "the client is not in a trusted subnet")
+	}
+
+	return nil
+}
+
+// optionalUserID sets the user id of a valid Bearer token in the context, if there is one.
+// API keys are ignored, as they don't identify a session.
+func (a *Auth) optionalUserID(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	authHeader := md.Get(authentication)
+	if len(authHeader) == 0 {
+		return ctx
+	}
+
+	scheme, token, ok := strings.Cut(authHeader[0], " ")
+	if !ok || strings.ToLower(scheme) != bearerSchema || strings.HasPrefix(token, APIKeyPrefix) {
+		return ctx
+	}
+
+	claims, err := a.parseAccessToken(token)
+	if err != nil || claims.UserID == "" {
+		return ctx
+	}
 
-	return handler(newCtx, req)
+	return withClaims(ctx, claims)
+}
+
+func (a *Auth) interceptWithAPIKey(ctx context.Context, key string, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	apiKey, err := a.verifyKey(ctx, key)
+	if errors.Is(err, ErrInvalidAPIKey) {
+		return nil, status.Errorf(codes.Unauthenticated, "the api key is invalid")
+	}
+
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "internal server error")
+	}
+
+	var method string
+	if info != nil {
+		method = info.FullMethod
+	}
+
+	a.logKeyUsage(apiKey, zap.String("method", method))
+
+	ctx = withAPIKey(ctx, apiKey)
+
+	if scope, ok := a.methodScopes[method]; ok && !HasScope(ctx, scope) {
+		return nil, status.Errorf(codes.PermissionDenied, "the api key lacks the %s scope", scope)
+	}
+
+	if role, ok := a.methodRole(method); ok && !HasRole(ctx, role) {
+		return nil, status.Errorf(codes.PermissionDenied, "the %s role is required", role)
+	}
+
+	return handler(ctx, req)
 }
 
 // BasicMiddlewareHTTP is a middleware for JWT authentication.
-// The middleware checks if JWT Token is present in a cookie and valid.
-// If the cookie is not present or JWT Token is not valid, a new cookie with JWT Token is created.
+// The middleware checks if a JWT access token is present in the Authorization header or in a cookie and valid.
+// If the cookie is not present or its token is expired, the session is renewed with the refresh cookie,
+// and failing that the request goes on without a user: SessionMiddlewareHTTP starts a new session on the
+// routes that need one. A token in the Authorization header is never renewed: an expired or invalid one
+// is rejected with 401.
+//
+// Tokens issued before the key ring was introduced are accepted from the cookie until the cutoff
+// set with WithLegacyTokensUntil and replaced with a new session of the same user. Past the cutoff,
+// they are ignored like an expired token.
+//
+// Requests presenting an API key in the X-API-Key header or as a Bearer token are authenticated
+// with the key instead, and an invalid key is rejected with 401 without creating a cookie.
 //
-// The middleware sets the user id (from the JWT claims) in the request context.
+// The middleware sets the user id (from the JWT claims or the API key) and the role of the user
+// (from the JWT claims) in the request context.
 func (a *Auth) BasicMiddlewareHTTP(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cookie, err := r.Cookie(jwtTokenCookieName)
-		if err != nil {
-			cookie, err = createJWTAuthCookie(a.secret)
-			if err != nil {
+		if key, ok := apiKeyFromRequest(r); ok {
+			apiKey, err := a.verifyKey(r.Context(), key)
+			switch {
+			case errors.Is(err, ErrInvalidAPIKey):
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			case err != nil:
 				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
 
-			http.SetCookie(w, cookie)
-		}
+			a.logKeyUsage(apiKey, zap.String("method", r.Method), zap.String("uri", r.RequestURI))
 
-		claims := &Claims{}
+			next.ServeHTTP(w, r.WithContext(withAPIKey(r.Context(), apiKey)))
+			return
+		}
 
-		token, err := jwt.ParseWithClaims(cookie.Value, claims, func(t *jwt.Token) (interface{}, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		if tokenString, ok := bearerTokenFromRequest(r); ok {
+			claims, err := a.parseAccessToken(tokenString)
+			if err != nil || claims.UserID == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
 			}
 
-			return []byte(a.secret), nil
-		})
-
-		if err != nil {
-			w.WriteHeader(http.StatusUnauthorized)
+			next.ServeHTTP(w, r.WithContext(withClaims(r.Context(), claims)))
 			return
 		}
 
-		if !token.Valid {
-			newCookie, err := createJWTAuthCookie(a.secret)
+		var userID, role string
+
+		cookie, err := r.Cookie(jwtTokenCookieName)
+		if err == nil {
+			claims, err := a.parseAccessToken(cookie.Value)
+			switch {
+			case err == nil && claims.UserID != "":
+				userID, role = claims.UserID, claims.Role
+			case errors.Is(err, jwt.ErrTokenExpired):
+			case errors.Is(err, ErrUnknownKey):
+				legacy, err := a.parseLegacyToken(cookie.Value)
+				if errors.Is(err, errLegacyTokensClosed) {
+					// Without a user id, the session is renewed below.
+					break
+				}
+
+				if err != nil || legacy.UserID == "" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+
+				session, err := a.IssueSession(r.Context(), legacy.UserID)
+				if err != nil {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+
+				a.SetSessionCookies(w, session)
+				userID, role = session.UserID, session.Role
+			default:
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+
+		if userID == "" {
+			session, err := a.renewSession(r)
 			if err != nil {
 				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
 
-			cookie = newCookie
-			http.SetCookie(w, cookie)
+			if session == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			a.SetSessionCookies(w, session)
+			userID, role = session.UserID, session.Role
 		}
 
-		if claims.UserID == "" {
-			w.WriteHeader(http.StatusUnauthorized)
+		ctx := withClaims(r.Context(), &Claims{UserID: userID, Role: role})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// SessionMiddlewareHTTP is a middleware that starts a session for a new anonymous user and sets it
+// in the cookies when the request has no user. It must run after BasicMiddlewareHTTP, and is meant
+// for the routes saving something for the user: a session is stored for every new anonymous user,
+// so the read-only routes, like the redirect, go on without one.
+func (a *Auth) SessionMiddlewareHTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := UserIDFromContext(r.Context()); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		session, err := a.IssueAnonymousSession(r.Context())
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
+		a.SetSessionCookies(w, session)
+
+		ctx := withClaims(r.Context(), &Claims{UserID: session.UserID, Role: session.Role})
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-// AdminMiddlewareHTTP is a middleware function that checks if the incoming request is from a trusted subnet.
+// renewSession renews the session with the refresh cookie of the request.
+// It returns nil if there is no valid refresh cookie.
+func (a *Auth) renewSession(r *http.Request) (*Session, error) {
+	refreshToken, ok := RefreshTokenFromRequest(r)
+	if !ok {
+		return nil, nil
+	}
+
+	session, err := a.Refresh(r.Context(), refreshToken)
+	if errors.Is(err, ErrInvalidRefreshToken) {
+		return nil, nil
+	}
+
+	return session, err
+}
+
+// AdminMiddlewareHTTP is a middleware function that checks if the incoming request is from one of the trusted subnets.
+// The client IP is the one resolved by the clientip middleware, so forwarding headers are only honored
+// when the request comes through a trusted proxy.
 func (a *Auth) AdminMiddlewareHTTP(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if a.trustedSubnet == "" {
@@ -151,16 +494,12 @@ func (a *Auth) AdminMiddlewareHTTP(next http.Handler) http.Handler {
 			return
 		}
 
-		_, trustNet, err := net.ParseCIDR(a.trustedSubnet)
-		if err != nil {
+		if a.trustedNetsErr != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
-		ipStr := r.Header.Get("X-Real-IP")
-		ip := net.ParseIP(ipStr)
-
-		if !trustNet.Contains(ip) {
+		if !clientip.Contains(a.trustedNets, clientip.FromRequest(r)) {
 			w.WriteHeader(http.StatusForbidden)
 			return
 		}
@@ -169,22 +508,23 @@ func (a *Auth) AdminMiddlewareHTTP(next http.Handler) http.Handler {
 	})
 }
 
-func createJWTAuthCookie(jwtSecret string) (*http.Cookie, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
-		UserID: uuid.New().String(),
-	})
+// UserIDFromContext extracts the user ID set by the authentication middleware from the context.
+func UserIDFromContext(ctx context.Context) (string, bool) {
+	userID, ok := ctx.Value(UserIDKey).(string)
 
-	tokenString, err := token.SignedString([]byte(jwtSecret))
-	if err != nil {
-		return nil, err
-	}
+	return userID, ok && userID != ""
+}
+
+// JWKS returns the public keys the tokens issued by the shortener can be verified with.
+func (a *Auth) JWKS() JWKS {
+	return a.ring.JWKS()
+}
 
-	cookie := &http.Cookie{
-		Name:     jwtTokenCookieName,
-		Value:    tokenString,
-		HttpOnly: true,
-		Path:     "/",
+func bearerTokenFromRequest(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || strings.ToLower(scheme) != bearerSchema || token == "" {
+		return "", false
 	}
 
-	return cookie, nil
+	return token, true
 }
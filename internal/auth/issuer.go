@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// jwksPath is where the JWKS of an issuer is served, relative to the issuer URL.
+	jwksPath = "/.well-known/jwks.json"
+
+	jwksTimeout    = 5 * time.Second
+	jwksMaxBytes   = 1 << 20
+	jwksMinRefresh = time.Minute
+)
+
+// Error variables, returned for the tokens of external issuers.
+var (
+	ErrInvalidAudience     = errors.New("token is not issued for this audience")
+	ErrTokenWithoutSubject = errors.New("token has no subject")
+)
+
+// Issuer is an external identity service whose tokens are accepted, verified with the keys
+// it publishes at /.well-known/jwks.json under the issuer URL. The keys are fetched on
+// first use and again when a token refers to an unknown kid, at most once a minute.
+// Concurrent lookups share a single fetch, and the keys stay readable while it's in flight.
+type Issuer struct {
+	name      string
+	audience  string
+	namespace uuid.UUID
+	jwksURL   string
+	client    *http.Client
+	fetches   singleflight.Group
+
+	mu        sync.Mutex
+	keys      map[string]signingKey
+	fetchedAt time.Time
+}
+
+// NewIssuer creates an Issuer whose tokens must have the iss claim set to the issuer URL
+// and the aud claim include the audience.
+func NewIssuer(issuer, audience string) *Issuer {
+	return &Issuer{
+		name:      issuer,
+		audience:  audience,
+		namespace: uuid.NewSHA1(uuid.NameSpaceURL, []byte(issuer)),
+		jwksURL:   strings.TrimSuffix(issuer, "/") + jwksPath,
+		client:    &http.Client{Timeout: jwksTimeout},
+	}
+}
+
+func (i *Issuer) keyFunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	key, err := i.key(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+	}
+
+	return key.public, nil
+}
+
+func (i *Issuer) key(kid string) (signingKey, error) {
+	i.mu.Lock()
+	key, ok := i.keys[kid]
+	fresh := time.Since(i.fetchedAt) < jwksMinRefresh
+	i.mu.Unlock()
+
+	if ok {
+		return key, nil
+	}
+
+	if fresh {
+		return signingKey{}, ErrUnknownKey
+	}
+
+	// The keys are fetched without holding the mutex, so a slow issuer doesn't block the lookups of known keys.
+	_, err, _ := i.fetches.Do(i.jwksURL, func() (interface{}, error) {
+		i.mu.Lock()
+		fresh := time.Since(i.fetchedAt) < jwksMinRefresh
+		i.mu.Unlock()
+
+		// The keys may have been fetched since they were looked up.
+		if fresh {
+			return nil, nil
+		}
+
+		keys, err := i.fetchKeys()
+
+		i.mu.Lock()
+		defer i.mu.Unlock()
+
+		// A failed fetch isn't retried sooner than a successful one, so a down issuer isn't flooded.
+		i.fetchedAt = time.Now()
+		if err != nil {
+			return nil, err
+		}
+
+		i.keys = keys
+
+		return nil, nil
+	})
+	if err != nil {
+		return signingKey{}, fmt.Errorf("cannot fetch keys of %s: %w", i.name, err)
+	}
+
+	i.mu.Lock()
+	key, ok = i.keys[kid]
+	i.mu.Unlock()
+
+	if ok {
+		return key, nil
+	}
+
+	return signingKey{}, ErrUnknownKey
+}
+
+func (i *Issuer) fetchKeys() (map[string]signingKey, error) {
+	resp, err := i.client.Get(i.jwksURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var set JWKS
+	if err := json.NewDecoder(io.LimitReader(resp.Body, jwksMaxBytes)).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]signingKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		key, err := jwk.signingKey()
+		if err != nil {
+			continue
+		}
+
+		keys[jwk.KeyID] = key
+	}
+
+	return keys, nil
+}
+
+func (i *Issuer) validate(claims *Claims) error {
+	if claims.Subject == "" {
+		return ErrTokenWithoutSubject
+	}
+
+	for _, aud := range claims.Audience {
+		if aud == i.audience {
+			return nil
+		}
+	}
+
+	return ErrInvalidAudience
+}
+
+// userID maps the subject of a token to the id of its user. The subjects of the issuers aren't UUIDs
+// in general, and two issuers may use the same subject for different users, so the id is a UUID
+// derived from both the issuer and the subject. It's the same for every token of the user.
+func (i *Issuer) userID(subject string) string {
+	return uuid.NewSHA1(i.namespace, []byte(subject)).String()
+}
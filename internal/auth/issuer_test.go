@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuth_ExternalIssuer(t *testing.T) {
+	pub, private, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	jwk, _ := newJWK("idp-1", signingKey{method: jwt.SigningMethodEdDSA, public: pub})
+
+	var fetches int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		assert.Equal(t, "/.well-known/jwks.json", r.URL.Path)
+		_ = json.NewEncoder(w).Encode(JWKS{Keys: []JWK{jwk}})
+	}))
+	defer srv.Close()
+
+	sign := func(kid string, claims jwt.RegisteredClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, Claims{RegisteredClaims: claims})
+		token.Header["kid"] = kid
+
+		s, err := token.SignedString(private)
+		assert.NoError(t, err)
+
+		return s
+	}
+
+	valid := jwt.RegisteredClaims{
+		Issuer:    srv.URL,
+		Subject:   "user-1",
+		Audience:  jwt.ClaimStrings{"url-shortener"},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}
+
+	tests := []struct {
+		name    string
+		token   func() string
+		wantErr bool
+	}{
+		{
+			name:  "valid token",
+			token: func() string { return sign("idp-1", valid) },
+		},
+		{
+			name: "wrong audience",
+			token: func() string {
+				c := valid
+				c.Audience = jwt.ClaimStrings{"billing"}
+
+				return sign("idp-1", c)
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown issuer",
+			token: func() string {
+				c := valid
+				c.Issuer = "https://evil.example.com"
+
+				return sign("idp-1", c)
+			},
+			wantErr: true,
+		},
+		{
+			name: "no subject",
+			token: func() string {
+				c := valid
+				c.Subject = ""
+
+				return sign("idp-1", c)
+			},
+			wantErr: true,
+		},
+		{
+			name:    "unknown kid",
+			token:   func() string { return sign("idp-2", valid) },
+			wantErr: true,
+		},
+		{
+			name: "expired token",
+			token: func() string {
+				c := valid
+				c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+
+				return sign("idp-1", c)
+			},
+			wantErr: true,
+		},
+	}
+
+	issuer := NewIssuer(srv.URL, "url-shortener")
+	a := NewAuth(testJWTsecret, "", WithIssuers(issuer))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := a.parseAccessToken(tt.token())
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, issuer.userID("user-1"), claims.UserID)
+
+			_, err = uuid.Parse(claims.UserID)
+			assert.NoError(t, err)
+		})
+	}
+
+	assert.Equal(t, 1, fetches, "keys must be fetched at most once a minute")
+}
+
+func TestIssuer_key_fetchesOutsideLock(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	known := signingKey{method: jwt.SigningMethodEdDSA, public: pub}
+	jwk, _ := newJWK("idp-2", known)
+
+	var fetches int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&fetches, 1) == 1 {
+			close(started)
+		}
+
+		<-release
+		_ = json.NewEncoder(w).Encode(JWKS{Keys: []JWK{jwk}})
+	}))
+	defer srv.Close()
+
+	i := NewIssuer(srv.URL, "url-shortener")
+	i.keys = map[string]signingKey{"idp-1": known}
+
+	var wg sync.WaitGroup
+	for n := 0; n < 5; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := i.key("idp-2")
+			assert.NoError(t, err)
+		}()
+	}
+
+	<-started
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		_, err := i.key("idp-1")
+		assert.NoError(t, err)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("a known key should be found while the keys are fetched")
+	}
+
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches), "concurrent lookups should share a fetch")
+}
+
+func TestIssuer_userID(t *testing.T) {
+	first := NewIssuer("https://idp-1.example.com", "url-shortener")
+	second := NewIssuer("https://idp-2.example.com", "url-shortener")
+
+	assert.Equal(t, first.userID("user-1"), NewIssuer("https://idp-1.example.com", "billing").userID("user-1"))
+	assert.NotEqual(t, first.userID("user-1"), first.userID("user-2"))
+	assert.NotEqual(t, first.userID("user-1"), second.userID("user-1"))
+}
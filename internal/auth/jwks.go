@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	keyTypeRSA = "RSA"
+	keyTypeOKP = "OKP"
+	curveEd    = "Ed25519"
+	keyUseSig  = "sig"
+)
+
+// ErrUnsupportedJWK is returned for JSON Web Keys of types and algorithms tokens can't be verified with.
+var ErrUnsupportedJWK = errors.New("unsupported json web key")
+
+// JWK is a public key in the JSON Web Key format (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+// JWKS is a set of JSON Web Keys, as served at /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func newJWK(kid string, key signingKey) (JWK, bool) {
+	switch pub := key.public.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			KeyType:   keyTypeRSA,
+			KeyID:     kid,
+			Use:       keyUseSig,
+			Algorithm: key.method.Alg(),
+			N:         base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JWK{
+			KeyType:   keyTypeOKP,
+			KeyID:     kid,
+			Use:       keyUseSig,
+			Algorithm: key.method.Alg(),
+			Curve:     curveEd,
+			X:         base64.RawURLEncoding.EncodeToString(pub),
+		}, true
+	default:
+		return JWK{}, false
+	}
+}
+
+// signingKey converts the JWK to a key tokens can be verified with.
+func (k JWK) signingKey() (signingKey, error) {
+	if k.Use != "" && k.Use != keyUseSig {
+		return signingKey{}, ErrUnsupportedJWK
+	}
+
+	switch {
+	case k.KeyType == keyTypeRSA && (k.Algorithm == "" || k.Algorithm == jwt.SigningMethodRS256.Alg()):
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return signingKey{}, err
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return signingKey{}, err
+		}
+
+		exp := new(big.Int).SetBytes(e)
+		if !exp.IsInt64() || exp.Int64() > 1<<31-1 || exp.Int64() < 3 {
+			return signingKey{}, ErrUnsupportedJWK
+		}
+
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}
+
+		return signingKey{method: jwt.SigningMethodRS256, public: pub}, nil
+	case k.KeyType == keyTypeOKP && k.Curve == curveEd && (k.Algorithm == "" || k.Algorithm == jwt.SigningMethodEdDSA.Alg()):
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return signingKey{}, err
+		}
+
+		if len(x) != ed25519.PublicKeySize {
+			return signingKey{}, ErrUnsupportedJWK
+		}
+
+		return signingKey{method: jwt.SigningMethodEdDSA, public: ed25519.PublicKey(x)}, nil
+	default:
+		return signingKey{}, ErrUnsupportedJWK
+	}
+}
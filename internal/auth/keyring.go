@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultKeyID is the ID of the key made from the JWT secret when no key ring is configured.
+const DefaultKeyID = "default"
+
+// Error variables, used in the key ring.
+var (
+	ErrInvalidKeyRing = errors.New("key ring must be a comma separated list of kid:secret or kid:alg:pem-file entries")
+	ErrUnknownKey     = errors.New("token is signed with an unknown key")
+	ErrInvalidKey     = errors.New("key doesn't match its algorithm")
+)
+
+// signingMethods are the algorithms tokens can be signed with.
+var signingMethods = []string{
+	jwt.SigningMethodHS256.Alg(),
+	jwt.SigningMethodRS256.Alg(),
+	jwt.SigningMethodEdDSA.Alg(),
+}
+
+// signingKey is a key of the ring. For HMAC both private and public hold the secret.
+type signingKey struct {
+	method  jwt.SigningMethod
+	private interface{}
+	public  interface{}
+}
+
+// KeyRing holds the keys JWT tokens are signed and verified with. Tokens are signed with
+// the current key and carry its ID in the kid header, and are verified with the key
+// their kid refers to, so the secrets can be rotated without invalidating issued tokens.
+//
+// The public keys of RS256 and EdDSA keys are published in the JWKS of the ring,
+// so other services can verify the tokens without holding a shared secret.
+type KeyRing struct {
+	current string
+	order   []string
+	keys    map[string]signingKey
+}
+
+// NewKeyRing creates a key ring with a single HMAC key used to both sign and verify tokens.
+func NewKeyRing(kid string, secret []byte) *KeyRing {
+	r := &KeyRing{keys: make(map[string]signingKey)}
+	r.add(kid, signingKey{method: jwt.SigningMethodHS256, private: secret, public: secret})
+
+	return r
+}
+
+// ParseKeyRing parses a key ring in the form "kid:secret,kid:alg:pem-file".
+// A kid:secret entry is an HS256 key, and a kid:alg:pem-file entry is an RS256 or EdDSA key
+// read from the PEM encoded private key in the file.
+// The first key is the current one, the rest are only used to verify the tokens issued before rotation.
+func ParseKeyRing(value string) (*KeyRing, error) {
+	r := &KeyRing{keys: make(map[string]signingKey)}
+
+	for _, entry := range strings.Split(value, ",") {
+		kid, rest, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || kid == "" || rest == "" {
+			return nil, ErrInvalidKeyRing
+		}
+
+		if _, ok := r.keys[kid]; ok {
+			return nil, fmt.Errorf("%w: duplicate kid %q", ErrInvalidKeyRing, kid)
+		}
+
+		key, err := parseSigningKey(rest)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", kid, err)
+		}
+
+		r.add(kid, key)
+	}
+
+	return r, nil
+}
+
+func parseSigningKey(value string) (signingKey, error) {
+	alg, path, ok := strings.Cut(value, ":")
+	if !ok {
+		return signingKey{method: jwt.SigningMethodHS256, private: []byte(value), public: []byte(value)}, nil
+	}
+
+	var method jwt.SigningMethod
+
+	switch {
+	case strings.EqualFold(alg, jwt.SigningMethodRS256.Alg()):
+		method = jwt.SigningMethodRS256
+	case strings.EqualFold(alg, jwt.SigningMethodEdDSA.Alg()):
+		method = jwt.SigningMethodEdDSA
+	default:
+		// A secret containing a colon.
+		return signingKey{method: jwt.SigningMethodHS256, private: []byte(value), public: []byte(value)}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return signingKey{}, err
+	}
+
+	return parsePrivateKey(method, data)
+}
+
+func parsePrivateKey(method jwt.SigningMethod, data []byte) (signingKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return signingKey{}, fmt.Errorf("%w: no PEM data", ErrInvalidKey)
+	}
+
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if private, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return signingKey{}, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+		}
+	}
+
+	switch k := private.(type) {
+	case *rsa.PrivateKey:
+		if method != jwt.SigningMethodRS256 {
+			return signingKey{}, ErrInvalidKey
+		}
+
+		return signingKey{method: method, private: k, public: &k.PublicKey}, nil
+	case ed25519.PrivateKey:
+		if method != jwt.SigningMethodEdDSA {
+			return signingKey{}, ErrInvalidKey
+		}
+
+		return signingKey{method: method, private: k, public: k.Public()}, nil
+	default:
+		return signingKey{}, ErrInvalidKey
+	}
+}
+
+// JWKS returns the public keys of the RS256 and EdDSA keys of the ring. HMAC keys are never published.
+func (r *KeyRing) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+
+	for _, kid := range r.order {
+		if jwk, ok := newJWK(kid, r.keys[kid]); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+
+	return set
+}
+
+func (r *KeyRing) add(kid string, key signingKey) {
+	if r.current == "" {
+		r.current = kid
+	}
+
+	r.order = append(r.order, kid)
+	r.keys[kid] = key
+}
+
+func (r *KeyRing) sign(claims jwt.Claims) (string, error) {
+	key := r.keys[r.current]
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = r.current
+
+	return token.SignedString(key.private)
+}
+
+func (r *KeyRing) keyFunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	key, ok := r.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	if t.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+	}
+
+	return key.public, nil
+}
@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKeyRing(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		wantCurrent string
+		wantKeys    int
+		wantErr     bool
+	}{
+		{
+			name:        "single key",
+			value:       "k1:secret",
+			wantCurrent: "k1",
+			wantKeys:    1,
+		},
+		{
+			name:        "first key is current",
+			value:       "k2:new-secret, k1:old-secret",
+			wantCurrent: "k2",
+			wantKeys:    2,
+		},
+		{
+			name:    "missing secret",
+			value:   "k1:",
+			wantErr: true,
+		},
+		{
+			name:    "missing kid",
+			value:   "secret",
+			wantErr: true,
+		},
+		{
+			name:    "duplicate kid",
+			value:   "k1:a,k1:b",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ring, err := ParseKeyRing(tt.value)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidKeyRing)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCurrent, ring.current)
+			assert.Len(t, ring.keys, tt.wantKeys)
+		})
+	}
+}
+
+func TestKeyRing_Rotation(t *testing.T) {
+	oldRing, _ := ParseKeyRing("k1:old-secret")
+	newRing, _ := ParseKeyRing("k2:new-secret,k1:old-secret")
+	unrelatedRing, _ := ParseKeyRing("k2:new-secret")
+
+	session, err := NewAuth(testJWTsecret, "", WithKeyRing(oldRing)).IssueSession(context.Background(), "user-1")
+	assert.NoError(t, err)
+
+	claims, err := NewAuth(testJWTsecret, "", WithKeyRing(newRing)).parseAccessToken(session.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", claims.UserID)
+
+	_, err = NewAuth(testJWTsecret, "", WithKeyRing(unrelatedRing)).parseAccessToken(session.AccessToken)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func writeKey(t *testing.T, key interface{}) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestKeyRing_Asymmetric(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	rsaPath := writeKey(t, rsaKey)
+	edPath := writeKey(t, edKey)
+
+	tests := []struct {
+		name    string
+		value   string
+		wantAlg string
+		wantKty string
+		wantErr bool
+	}{
+		{
+			name:    "RS256 key",
+			value:   "rs:RS256:" + rsaPath,
+			wantAlg: "RS256",
+			wantKty: "RSA",
+		},
+		{
+			name:    "EdDSA key",
+			value:   "ed:EdDSA:" + edPath,
+			wantAlg: "EdDSA",
+			wantKty: "OKP",
+		},
+		{
+			name:    "key doesn't match algorithm",
+			value:   "ed:EdDSA:" + rsaPath,
+			wantErr: true,
+		},
+		{
+			name:    "missing file",
+			value:   "rs:RS256:" + filepath.Join(t.TempDir(), "missing.pem"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ring, err := ParseKeyRing(tt.value + ",hs:secret")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+
+			a := NewAuth(testJWTsecret, "", WithKeyRing(ring))
+
+			session, err := a.IssueSession(context.Background(), "user-1")
+			assert.NoError(t, err)
+
+			claims, err := a.parseAccessToken(session.AccessToken)
+			assert.NoError(t, err)
+			assert.Equal(t, "user-1", claims.UserID)
+
+			set := a.JWKS()
+			assert.Len(t, set.Keys, 1, "HMAC keys must not be published")
+			assert.Equal(t, tt.wantAlg, set.Keys[0].Algorithm)
+			assert.Equal(t, tt.wantKty, set.Keys[0].KeyType)
+
+			key, err := set.Keys[0].signingKey()
+			assert.NoError(t, err)
+
+			_, err = jwt.Parse(session.AccessToken, func(*jwt.Token) (interface{}, error) { return key.public, nil })
+			assert.NoError(t, err, "token must verify with the published key")
+		})
+	}
+}
+
+func TestKeyRing_RejectsAlgorithmConfusion(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	ring, err := ParseKeyRing("rs:RS256:" + writeKey(t, rsaKey))
+	assert.NoError(t, err)
+
+	// A token signed with HS256 using the public key as the secret must not verify.
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))},
+		UserID:           "user-1",
+	})
+	token.Header["kid"] = "rs"
+
+	tokenString, err := token.SignedString(x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey))
+	assert.NoError(t, err)
+
+	_, err = NewAuth(testJWTsecret, "", WithKeyRing(ring)).parseAccessToken(tokenString)
+	assert.Error(t, err)
+}
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Roles of the users. Every role has the permissions of the roles below it.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var roleRanks = map[string]int{
+	RoleUser:      0,
+	RoleModerator: 1,
+	RoleAdmin:     2,
+}
+
+// RoleResolver is an interface for looking up the role of a user when a session is issued.
+type RoleResolver interface {
+	UserRole(ctx context.Context, userID string) (string, error)
+}
+
+type roleCtxKey struct{}
+
+// ValidRole reports whether the role is one of the known roles.
+func ValidRole(role string) bool {
+	_, ok := roleRanks[role]
+
+	return ok
+}
+
+// RoleFromContext extracts the role of the user set by the authentication middleware from the context.
+// Requests without a role, including the ones authenticated with an API key, have the RoleUser.
+func RoleFromContext(ctx context.Context) string {
+	role, ok := ctx.Value(roleCtxKey{}).(string)
+	if !ok || !ValidRole(role) {
+		return RoleUser
+	}
+
+	return role
+}
+
+// HasRole reports whether the user of the request has the role or a role above it.
+func HasRole(ctx context.Context, role string) bool {
+	return roleRanks[RoleFromContext(ctx)] >= roleRanks[role]
+}
+
+// RequireRole returns a middleware that responds with 403 to the requests of users lacking the role.
+func (a *Auth) RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !HasRole(r.Context(), role) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// methodRole returns the role needed to call the gRPC method, if any.
+func (a *Auth) methodRole(method string) (string, bool) {
+	if role, ok := a.methodRoles[method]; ok {
+		return role, true
+	}
+
+	for m, role := range a.methodRoles {
+		if strings.HasSuffix(m, "/") && strings.HasPrefix(method, m) {
+			return role, true
+		}
+	}
+
+	return "", false
+}
+
+// withClaims sets the user id and the role of the claims in the context.
+func withClaims(ctx context.Context, claims *Claims) context.Context {
+	ctx = context.WithValue(ctx, UserIDKey, claims.UserID)
+
+	if claims.Role != "" {
+		ctx = context.WithValue(ctx, roleCtxKey{}, claims.Role)
+	}
+
+	return ctx
+}
@@ -0,0 +1,270 @@
+package auth
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type roleResolver map[string]string
+
+func (r roleResolver) UserRole(_ context.Context, userID string) (string, error) {
+	if role, ok := r[userID]; ok {
+		return role, nil
+	}
+
+	return RoleUser, nil
+}
+
+func TestAuth_IssueSession_Roles(t *testing.T) {
+	a := NewAuth(testJWTsecret, "", WithRoles(roleResolver{"admin-1": RoleAdmin}))
+
+	session, err := a.IssueSession(context.Background(), "admin-1")
+	require.NoError(t, err)
+	assert.Equal(t, RoleAdmin, session.Role)
+
+	claims, err := a.parseAccessToken(session.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, RoleAdmin, claims.Role)
+
+	session, err = a.IssueSession(context.Background(), "user-1")
+	require.NoError(t, err)
+
+	claims, err = a.parseAccessToken(session.AccessToken)
+	require.NoError(t, err)
+	assert.Empty(t, claims.Role)
+}
+
+func TestHasRole(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		role string
+		want bool
+	}{
+		{
+			name: "user without role",
+			ctx:  context.Background(),
+			role: RoleUser,
+			want: true,
+		},
+		{
+			name: "user lacks moderator role",
+			ctx:  context.Background(),
+			role: RoleModerator,
+			want: false,
+		},
+		{
+			name: "admin has moderator role",
+			ctx:  withClaims(context.Background(), &Claims{UserID: "1", Role: RoleAdmin}),
+			role: RoleModerator,
+			want: true,
+		},
+		{
+			name: "moderator lacks admin role",
+			ctx:  withClaims(context.Background(), &Claims{UserID: "1", Role: RoleModerator}),
+			role: RoleAdmin,
+			want: false,
+		},
+		{
+			name: "unknown role is user",
+			ctx:  withClaims(context.Background(), &Claims{UserID: "1", Role: "root"}),
+			role: RoleModerator,
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, HasRole(tt.ctx, tt.role))
+		})
+	}
+}
+
+func TestAuth_RequireRole(t *testing.T) {
+	a := NewAuth(testJWTsecret, "", WithRoles(roleResolver{"mod-1": RoleModerator}))
+
+	moderator, err := a.IssueSession(context.Background(), "mod-1")
+	require.NoError(t, err)
+
+	user, err := a.IssueSession(context.Background(), "user-1")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		token      string
+		statusCode int
+	}{
+		{
+			name:       "moderator is allowed",
+			token:      moderator.AccessToken,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "user is forbidden",
+			token:      user.AccessToken,
+			statusCode: http.StatusForbidden,
+		},
+	}
+
+	handler := a.BasicMiddlewareHTTP(a.RequireRole(RoleModerator)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/admin/actions", nil)
+			r.AddCookie(&http.Cookie{Name: jwtTokenCookieName, Value: tt.token})
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+		})
+	}
+}
+
+func TestAuth_UnaryServerInterceptor_MethodRoles(t *testing.T) {
+	const (
+		listMethod = "/shortener.Admin/ListUserLinks"
+		roleMethod = "/shortener.Admin/SetUserRole"
+	)
+
+	a := NewAuth(testJWTsecret, "",
+		WithRoles(roleResolver{"mod-1": RoleModerator, "admin-1": RoleAdmin}),
+		WithMethodRoles(map[string]string{
+			listMethod:          RoleModerator,
+			"/shortener.Admin/": RoleAdmin,
+		}),
+	)
+
+	tokens := make(map[string]string)
+	for _, userID := range []string{"user-1", "mod-1", "admin-1"} {
+		session, err := a.IssueSession(context.Background(), userID)
+		require.NoError(t, err)
+
+		tokens[userID] = session.AccessToken
+	}
+
+	tests := []struct {
+		name        string
+		userID      string
+		method      string
+		expectedErr codes.Code
+	}{
+		{
+			name:        "moderator calls moderator method",
+			userID:      "mod-1",
+			method:      listMethod,
+			expectedErr: codes.OK,
+		},
+		{
+			name:        "user calls moderator method",
+			userID:      "user-1",
+			method:      listMethod,
+			expectedErr: codes.PermissionDenied,
+		},
+		{
+			name:        "moderator calls admin method",
+			userID:      "mod-1",
+			method:      roleMethod,
+			expectedErr: codes.PermissionDenied,
+		},
+		{
+			name:        "admin calls admin method",
+			userID:      "admin-1",
+			method:      roleMethod,
+			expectedErr: codes.OK,
+		},
+		{
+			name:        "user calls method without role",
+			userID:      "user-1",
+			method:      "/shortener.URLShortener/MakeURL",
+			expectedErr: codes.OK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := mockHandler(func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, nil
+			})
+
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.New(map[string]string{
+				"authorization": "bearer " + tokens[tt.userID],
+			}))
+
+			_, err := a.UnaryServerInterceptor(ctx, "request", &grpc.UnaryServerInfo{FullMethod: tt.method}, handler.Handle)
+
+			assert.Equal(t, tt.expectedErr.String(), status.Code(err).String())
+		})
+	}
+}
+
+func TestAuth_UnaryServerInterceptor_AdminMethodRoles(t *testing.T) {
+	const method = "/shortener.Admin/SetUserRole"
+
+	a := NewAuth(testJWTsecret, "192.0.2.0/24",
+		WithRoles(roleResolver{"admin-1": RoleAdmin}),
+		WithAdminMethods("/shortener.Admin/"),
+		WithMethodRoles(map[string]string{"/shortener.Admin/": RoleAdmin}),
+	)
+
+	session, err := a.IssueSession(context.Background(), "admin-1")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		addr        string
+		token       string
+		expectedErr codes.Code
+	}{
+		{
+			name:        "admin in trusted subnet",
+			addr:        "192.0.2.1",
+			token:       session.AccessToken,
+			expectedErr: codes.OK,
+		},
+		{
+			name:        "admin outside trusted subnet",
+			addr:        "198.51.100.1",
+			token:       session.AccessToken,
+			expectedErr: codes.PermissionDenied,
+		},
+		{
+			name:        "no token in trusted subnet",
+			addr:        "192.0.2.1",
+			expectedErr: codes.Unauthenticated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := mockHandler(func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, nil
+			})
+
+			md := map[string]string{}
+			if tt.token != "" {
+				md["authorization"] = "bearer " + tt.token
+			}
+
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(tt.addr), Port: 5000}})
+			ctx = metadata.NewIncomingContext(ctx, metadata.New(md))
+
+			_, err := a.UnaryServerInterceptor(ctx, "request", &grpc.UnaryServerInfo{FullMethod: method}, handler.Handle)
+
+			assert.Equal(t, tt.expectedErr.String(), status.Code(err).String())
+		})
+	}
+}
@@ -0,0 +1,405 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/PrahaTurbo/url-shortener/internal/audit"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+const (
+	// RefreshTokenPrefix is the prefix of every refresh token.
+	RefreshTokenPrefix = "rt_"
+
+	// DefaultAccessTokenTTL is the lifetime of access tokens when no other is configured.
+	DefaultAccessTokenTTL = 15 * time.Minute
+	// DefaultRefreshTokenTTL is the lifetime of refresh tokens when no other is configured.
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+	// rotationGrace is how long a rotated refresh token is still accepted, so the concurrent
+	// requests of a browser holding the same refresh cookie don't end the session.
+	rotationGrace = 30 * time.Second
+
+	// revokedTokenRetention is how long revoked refresh tokens are kept before they are purged, so presenting
+	// a rotated token is still treated as theft for that long.
+	revokedTokenRetention = 24 * time.Hour
+	// refreshTokenPurgeInterval is how often the expired and revoked refresh tokens are purged.
+	refreshTokenPurgeInterval = time.Hour
+
+	refreshTokenCookieName = "refresh_token"
+	refreshTokenBytes      = 32
+)
+
+// Error variables, used in the sessions.
+var (
+	ErrInvalidRefreshToken = errors.New("refresh token is invalid")
+	ErrTokenWithoutExpiry  = errors.New("token has no expiration time")
+)
+
+// Session is a pair of a short-lived access token and the refresh token it can be renewed with.
+// The RefreshToken is empty when refresh tokens are not enabled.
+type Session struct {
+	UserID       string
+	Role         string
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
+
+// IssueSession creates an access token for the user and, if refresh tokens are enabled,
+// a refresh token stored for later revocation. If roles are enabled, the access token carries
+// the current role of the user.
+func (a *Auth) IssueSession(ctx context.Context, userID string) (*Session, error) {
+	var role string
+
+	if a.roles != nil {
+		var err error
+		if role, err = a.roles.UserRole(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	return a.issueSession(ctx, userID, role)
+}
+
+// IssueAnonymousSession creates a session for a new anonymous user.
+func (a *Auth) IssueAnonymousSession(ctx context.Context) (*Session, error) {
+	return a.issueSession(ctx, uuid.New().String(), "")
+}
+
+func (a *Auth) issueSession(ctx context.Context, userID, role string) (*Session, error) {
+	if role == RoleUser {
+		role = ""
+	}
+
+	now := a.now()
+
+	accessToken, err := a.ring.sign(Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(a.accessTTL)),
+		},
+		UserID: userID,
+		Role:   role,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	session := &Session{
+		UserID:      userID,
+		Role:        role,
+		AccessToken: accessToken,
+		ExpiresAt:   now.Add(a.accessTTL),
+	}
+
+	if a.tokens == nil {
+		return session, nil
+	}
+
+	raw := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+
+	session.RefreshToken = RefreshTokenPrefix + base64.RawURLEncoding.EncodeToString(raw)
+
+	err = a.tokens.SaveRefreshToken(ctx, entity.RefreshToken{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Hash:      hashRefreshToken(session.RefreshToken),
+		ExpiresAt: now.Add(a.refreshTTL),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// Refresh exchanges the refresh token for a new session of the same user. The refresh token
+// is rotated: it can't be used again, and presenting it after the rotation grace period is
+// treated as theft, revoking every refresh token of the user.
+func (a *Auth) Refresh(ctx context.Context, refreshToken string) (*Session, error) {
+	token, err := a.lookupRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	now := a.now()
+
+	if !token.RevokedAt.IsZero() {
+		if !token.Rotated || now.Sub(token.RevokedAt) > rotationGrace {
+			if token.Rotated {
+				a.revokeReusedToken(ctx, token)
+			}
+
+			return nil, ErrInvalidRefreshToken
+		}
+
+		return a.refreshedSession(ctx, token)
+	}
+
+	if err := a.tokens.RevokeRefreshToken(ctx, token.ID, true); err != nil {
+		return nil, err
+	}
+
+	return a.refreshedSession(ctx, token)
+}
+
+func (a *Auth) refreshedSession(ctx context.Context, token *entity.RefreshToken) (*Session, error) {
+	session, err := a.IssueSession(ctx, token.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	a.record(ctx, audit.Entry{
+		ActorID: token.UserID,
+		Action:  audit.ActionSessionRefresh,
+		Target:  token.ID,
+		Before:  map[string]string{"refresh_token_id": token.ID},
+	})
+
+	return session, nil
+}
+
+// RevokeSession revokes the refresh token, so the session can't be renewed once its access token expires.
+// Unknown refresh tokens are ignored.
+func (a *Auth) RevokeSession(ctx context.Context, refreshToken string) error {
+	token, err := a.lookupRefreshToken(ctx, refreshToken)
+	if errors.Is(err, ErrInvalidRefreshToken) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := a.tokens.RevokeRefreshToken(ctx, token.ID, false); err != nil {
+		return err
+	}
+
+	a.record(ctx, audit.Entry{
+		ActorID: token.UserID,
+		Action:  audit.ActionSessionRevoke,
+		Target:  token.ID,
+		Before:  map[string]string{"refresh_token_id": token.ID},
+	})
+
+	return nil
+}
+
+// SetSessionCookies sets the access and refresh tokens of the session as the auth cookies of the response.
+func (a *Auth) SetSessionCookies(w http.ResponseWriter, s *Session) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     jwtTokenCookieName,
+		Value:    s.AccessToken,
+		HttpOnly: true,
+		Path:     "/",
+	})
+
+	if s.RefreshToken == "" {
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshTokenCookieName,
+		Value:    s.RefreshToken,
+		HttpOnly: true,
+		Path:     "/",
+		MaxAge:   int(a.refreshTTL.Seconds()),
+	})
+}
+
+// ClearSessionCookies removes the auth cookies from the client.
+func (a *Auth) ClearSessionCookies(w http.ResponseWriter) {
+	for _, name := range []string{jwtTokenCookieName, refreshTokenCookieName} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			HttpOnly: true,
+			Path:     "/",
+			MaxAge:   -1,
+		})
+	}
+}
+
+// RefreshTokenFromRequest extracts the refresh token from the refresh cookie of the request.
+func RefreshTokenFromRequest(r *http.Request) (string, bool) {
+	cookie, err := r.Cookie(refreshTokenCookieName)
+	if err != nil || cookie.Value == "" {
+		return "", false
+	}
+
+	return cookie.Value, true
+}
+
+// parseAccessToken verifies a token issued by the shortener or by one of the external issuers.
+// The roles claimed by the tokens of external issuers are ignored, since roles are granted by the shortener.
+func (a *Auth) parseAccessToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+
+	_, err := jwt.ParseWithClaims(tokenString, claims, a.keyFunc,
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithTimeFunc(a.now),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.ExpiresAt == nil {
+		return nil, ErrTokenWithoutExpiry
+	}
+
+	if issuer, ok := a.issuers[claims.Issuer]; ok {
+		if err := issuer.validate(claims); err != nil {
+			return nil, err
+		}
+
+		claims.UserID = issuer.userID(claims.Subject)
+		claims.Role = ""
+	}
+
+	return claims, nil
+}
+
+func (a *Auth) keyFunc(t *jwt.Token) (interface{}, error) {
+	if claims, ok := t.Claims.(*Claims); ok && claims.Issuer != "" {
+		if issuer, ok := a.issuers[claims.Issuer]; ok {
+			return issuer.keyFunc(t)
+		}
+	}
+
+	return a.ring.keyFunc(t)
+}
+
+// errLegacyTokensClosed is returned by parseLegacyToken past the cutoff of the legacy tokens.
+var errLegacyTokensClosed = errors.New("legacy tokens are no longer accepted")
+
+// parseLegacyToken verifies a token issued before the key ring was introduced,
+// which has no kid header and no expiration time and is signed with the JWT secret.
+// It returns errLegacyTokensClosed past the cutoff set with WithLegacyTokensUntil.
+func (a *Auth) parseLegacyToken(tokenString string) (*Claims, error) {
+	if !a.now().Before(a.legacyUntil) {
+		return nil, errLegacyTokensClosed
+	}
+
+	claims := &Claims{}
+
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Header["kid"]; ok {
+			return nil, ErrUnknownKey
+		}
+
+		return []byte(a.secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (a *Auth) lookupRefreshToken(ctx context.Context, refreshToken string) (*entity.RefreshToken, error) {
+	if a.tokens == nil || !strings.HasPrefix(refreshToken, RefreshTokenPrefix) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	token, err := a.tokens.GetRefreshTokenByHash(ctx, hashRefreshToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+
+	if token == nil || !a.now().Before(token.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return token, nil
+}
+
+// PurgeRefreshTokens deletes the refresh tokens that expired or were revoked more than a day ago every hour,
+// until the context is done. It returns right away if refresh tokens are not enabled.
+func (a *Auth) PurgeRefreshTokens(ctx context.Context) {
+	if a.tokens == nil {
+		return
+	}
+
+	t := time.NewTicker(a.purgeInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			a.purgeRefreshTokens(ctx)
+		}
+	}
+}
+
+func (a *Auth) purgeRefreshTokens(ctx context.Context) {
+	deleted, err := a.tokens.DeleteRefreshTokens(ctx, a.now().Add(-revokedTokenRetention))
+
+	if a.logger == nil {
+		return
+	}
+
+	if err != nil {
+		a.logger.Error("cannot purge refresh tokens", zap.Error(err))
+		return
+	}
+
+	a.logger.Debug("refresh tokens purged", zap.Int("deleted", deleted))
+}
+
+func (a *Auth) revokeReusedToken(ctx context.Context, token *entity.RefreshToken) {
+	err := a.tokens.RevokeUserRefreshTokens(ctx, token.UserID)
+	if err == nil {
+		a.record(ctx, audit.Entry{
+			ActorID: token.UserID,
+			Action:  audit.ActionSessionRevoke,
+			Target:  token.UserID,
+			After:   map[string]string{"reason": "rotated refresh token reused", "refresh_token_id": token.ID},
+		})
+	}
+
+	if a.logger == nil {
+		return
+	}
+
+	if err != nil {
+		a.logger.Error("cannot revoke refresh tokens", zap.String("user_id", token.UserID), zap.Error(err))
+		return
+	}
+
+	a.logger.Warn("rotated refresh token reused, all sessions revoked",
+		zap.String("token_id", token.ID),
+		zap.String("user_id", token.UserID),
+	)
+}
+
+func (a *Auth) record(ctx context.Context, e audit.Entry) {
+	if a.audit == nil {
+		return
+	}
+
+	a.audit.Record(ctx, e)
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage/memory"
+)
+
+func TestAuth_Refresh(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(a *Auth, session *Session) string
+		wantErr error
+	}{
+		{
+			name: "should rotate refresh token",
+			prepare: func(a *Auth, session *Session) string {
+				return session.RefreshToken
+			},
+		},
+		{
+			name: "should accept rotated token within grace period",
+			prepare: func(a *Auth, session *Session) string {
+				_, _ = a.Refresh(context.Background(), session.RefreshToken)
+
+				return session.RefreshToken
+			},
+		},
+		{
+			name: "should reject rotated token after grace period",
+			prepare: func(a *Auth, session *Session) string {
+				_, _ = a.Refresh(context.Background(), session.RefreshToken)
+				a.now = func() time.Time { return time.Now().Add(time.Minute) }
+
+				return session.RefreshToken
+			},
+			wantErr: ErrInvalidRefreshToken,
+		},
+		{
+			name: "should reject revoked token",
+			prepare: func(a *Auth, session *Session) string {
+				_ = a.RevokeSession(context.Background(), session.RefreshToken)
+
+				return session.RefreshToken
+			},
+			wantErr: ErrInvalidRefreshToken,
+		},
+		{
+			name: "should reject expired token",
+			prepare: func(a *Auth, session *Session) string {
+				a.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+
+				return session.RefreshToken
+			},
+			wantErr: ErrInvalidRefreshToken,
+		},
+		{
+			name: "should reject unknown token",
+			prepare: func(a *Auth, session *Session) string {
+				return RefreshTokenPrefix + "unknown"
+			},
+			wantErr: ErrInvalidRefreshToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAuth(testJWTsecret, "",
+				WithRefreshTokens(memory.NewRefreshTokenStorage()),
+				WithTokenTTL(time.Minute, time.Hour),
+			)
+
+			session, err := a.IssueSession(context.Background(), "user-1")
+			assert.NoError(t, err)
+
+			got, err := a.Refresh(context.Background(), tt.prepare(a, session))
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, "user-1", got.UserID)
+			assert.NotEqual(t, session.RefreshToken, got.RefreshToken)
+		})
+	}
+}
+
+func TestAuth_Refresh_ReuseRevokesAllSessions(t *testing.T) {
+	a := NewAuth(testJWTsecret, "", WithRefreshTokens(memory.NewRefreshTokenStorage()))
+
+	stolen, err := a.IssueSession(context.Background(), "user-1")
+	assert.NoError(t, err)
+
+	current, err := a.Refresh(context.Background(), stolen.RefreshToken)
+	assert.NoError(t, err)
+
+	a.now = func() time.Time { return time.Now().Add(time.Minute) }
+
+	_, err = a.Refresh(context.Background(), stolen.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	_, err = a.Refresh(context.Background(), current.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestAuth_IssueSession_WithoutRefreshTokens(t *testing.T) {
+	a := NewAuth(testJWTsecret, "")
+
+	session, err := a.IssueSession(context.Background(), "user-1")
+	assert.NoError(t, err)
+	assert.Empty(t, session.RefreshToken)
+
+	_, err = a.Refresh(context.Background(), RefreshTokenPrefix+"any")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestAuth_PurgeRefreshTokens(t *testing.T) {
+	ctx := context.Background()
+	tokens := memory.NewRefreshTokenStorage()
+	a := NewAuth(testJWTsecret, "", WithRefreshTokens(tokens))
+
+	revoked, err := a.IssueSession(ctx, "user-1")
+	assert.NoError(t, err)
+	assert.NoError(t, a.RevokeSession(ctx, revoked.RefreshToken))
+
+	active, err := a.IssueSession(ctx, "user-1")
+	assert.NoError(t, err)
+
+	stored := func(refreshToken string) bool {
+		token, err := tokens.GetRefreshTokenByHash(ctx, hashRefreshToken(refreshToken))
+		assert.NoError(t, err)
+
+		return token != nil
+	}
+
+	a.now = func() time.Time { return time.Now().Add(time.Hour) }
+	a.purgeRefreshTokens(ctx)
+	assert.True(t, stored(revoked.RefreshToken), "a token revoked within the retention should be kept to detect its reuse")
+
+	a.now = func() time.Time { return time.Now().Add(revokedTokenRetention + time.Hour) }
+	a.purgeInterval = time.Millisecond
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		a.PurgeRefreshTokens(ctx)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return !stored(revoked.RefreshToken)
+	}, time.Second, time.Millisecond, "the revoked token should be purged")
+
+	cancel()
+	<-done
+
+	assert.True(t, stored(active.RefreshToken), "the active token should be kept")
+}
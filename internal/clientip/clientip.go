@@ -0,0 +1,241 @@
+// Package clientip resolves the IP address of the client behind trusted proxies and shares it through the context.
+package clientip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+type contextKey struct{}
+
+// Resolver resolves the IP address of the client. The forwarding headers are only trusted
+// when the immediate peer is one of the trusted proxies, otherwise the peer is the client.
+type Resolver struct {
+	proxies []*net.IPNet
+}
+
+// NewResolver creates a Resolver trusting the forwarding headers set by the proxies.
+func NewResolver(proxies []*net.IPNet) *Resolver {
+	return &Resolver{proxies: proxies}
+}
+
+// Middleware resolves the client IP of the request and sets it in the request context.
+func (res *Resolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := res.resolve(hostIP(r.RemoteAddr), r.Header.Values)
+		if ip == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, ip)))
+	})
+}
+
+// UnaryServerInterceptor resolves the client IP of the gRPC call and sets it in the context.
+// The forwarding headers are read from the metadata under their lowercase names.
+func (res *Resolver) UnaryServerInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	ip := res.resolve(peerIP(ctx), func(key string) []string {
+		return md.Get(key)
+	})
+	if ip == nil {
+		return handler(ctx, req)
+	}
+
+	return handler(context.WithValue(ctx, contextKey{}, ip), req)
+}
+
+// FromContext returns the client IP set in the context by the Resolver.
+func FromContext(ctx context.Context) (net.IP, bool) {
+	ip, ok := ctx.Value(contextKey{}).(net.IP)
+
+	return ip, ok
+}
+
+// FromRequest returns the client IP resolved by the Resolver middleware, or the address of the peer
+// if the request didn't pass through it.
+func FromRequest(r *http.Request) net.IP {
+	if ip, ok := FromContext(r.Context()); ok {
+		return ip
+	}
+
+	return hostIP(r.RemoteAddr)
+}
+
+// FromGRPC returns the client IP resolved by the Resolver interceptor, or the address of the peer
+// if the call didn't pass through it.
+func FromGRPC(ctx context.Context) net.IP {
+	if ip, ok := FromContext(ctx); ok {
+		return ip
+	}
+
+	return peerIP(ctx)
+}
+
+// Contains reports whether the ip is in any of the networks.
+func Contains(nets []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseCIDRs parses a comma separated list of IPv4 and IPv6 CIDRs.
+// A bare IP address is taken as a single address network.
+func ParseCIDRs(value string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+
+	for _, s := range strings.Split(value, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid ip address %q", s)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+
+		nets = append(nets, n)
+	}
+
+	return nets, nil
+}
+
+// resolve walks the forwarding chain from the closest hop and returns the first address
+// that is not a trusted proxy. X-Forwarded-For is preferred over Forwarded, and X-Real-IP
+// is only used when neither is present.
+func (res *Resolver) resolve(peer net.IP, header func(key string) []string) net.IP {
+	if !Contains(res.proxies, peer) {
+		return peer
+	}
+
+	chain := forwardedFor(header("x-forwarded-for"))
+	if len(chain) == 0 {
+		chain = forwarded(header("forwarded"))
+	}
+
+	if len(chain) == 0 {
+		if values := header("x-real-ip"); len(values) > 0 {
+			chain = []net.IP{net.ParseIP(strings.TrimSpace(values[0]))}
+		}
+	}
+
+	ip := peer
+	for i := len(chain) - 1; i >= 0; i-- {
+		if chain[i] == nil {
+			break
+		}
+
+		ip = chain[i]
+		if !Contains(res.proxies, ip) {
+			break
+		}
+	}
+
+	return ip
+}
+
+// forwardedFor parses the X-Forwarded-For header values. An unparsable hop is kept as nil,
+// so the chain stops there.
+func forwardedFor(values []string) []net.IP {
+	var chain []net.IP
+
+	for _, v := range values {
+		for _, hop := range strings.Split(v, ",") {
+			chain = append(chain, net.ParseIP(strings.TrimSpace(hop)))
+		}
+	}
+
+	return chain
+}
+
+// forwarded parses the for parameters of the Forwarded header values (RFC 7239).
+func forwarded(values []string) []net.IP {
+	var chain []net.IP
+
+	for _, v := range values {
+		for _, element := range strings.Split(v, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok || !strings.EqualFold(key, "for") {
+					continue
+				}
+
+				chain = append(chain, forwardedNode(value))
+			}
+		}
+	}
+
+	return chain
+}
+
+// forwardedNode parses a node of the Forwarded header: an IPv4 address or a quoted,
+// bracketed IPv6 address, optionally with a port. Obfuscated and unknown nodes are nil.
+func forwardedNode(value string) net.IP {
+	value = strings.Trim(value, `"`)
+
+	if strings.HasPrefix(value, "[") {
+		end := strings.Index(value, "]")
+		if end < 0 {
+			return nil
+		}
+
+		return net.ParseIP(value[1:end])
+	}
+
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+
+	return net.ParseIP(value)
+}
+
+func peerIP(ctx context.Context) net.IP {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return nil
+	}
+
+	return hostIP(p.Addr.String())
+}
+
+func hostIP(addr string) net.IP {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+
+	return net.ParseIP(host)
+}
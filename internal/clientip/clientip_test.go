@@ -0,0 +1,119 @@
+package clientip
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestResolver_Middleware(t *testing.T) {
+	proxies, err := ParseCIDRs("10.0.0.0/8, fd00::/8")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{
+			name:       "direct client",
+			remoteAddr: "203.0.113.7:5000",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "headers of untrusted peer are ignored",
+			remoteAddr: "203.0.113.7:5000",
+			headers:    map[string]string{"X-Forwarded-For": "192.0.2.1", "X-Real-IP": "192.0.2.1"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "x-forwarded-for from trusted proxy",
+			remoteAddr: "10.0.0.1:5000",
+			headers:    map[string]string{"X-Forwarded-For": "192.0.2.1"},
+			want:       "192.0.2.1",
+		},
+		{
+			name:       "spoofed hops before the closest untrusted hop are ignored",
+			remoteAddr: "10.0.0.1:5000",
+			headers:    map[string]string{"X-Forwarded-For": "1.1.1.1, 192.0.2.1, 10.0.0.2"},
+			want:       "192.0.2.1",
+		},
+		{
+			name:       "forwarded header with ipv6",
+			remoteAddr: "[fd00::1]:5000",
+			headers:    map[string]string{"Forwarded": `for=192.0.2.60;proto=http, for="[2001:db8::17]:4711"`},
+			want:       "2001:db8::17",
+		},
+		{
+			name:       "x-real-ip from trusted proxy",
+			remoteAddr: "10.0.0.1:5000",
+			headers:    map[string]string{"X-Real-IP": "192.0.2.1"},
+			want:       "192.0.2.1",
+		},
+		{
+			name:       "unparsable hop stops the chain at the proxy",
+			remoteAddr: "10.0.0.1:5000",
+			headers:    map[string]string{"X-Forwarded-For": "192.0.2.1, garbage"},
+			want:       "10.0.0.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+
+			var got net.IP
+			NewResolver(proxies).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = FromRequest(r)
+			})).ServeHTTP(httptest.NewRecorder(), r)
+
+			assert.Equal(t, tt.want, got.String())
+		})
+	}
+}
+
+func TestResolver_UnaryServerInterceptor(t *testing.T) {
+	proxies, err := ParseCIDRs("10.0.0.1")
+	assert.NoError(t, err)
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5000}})
+	ctx = metadata.NewIncomingContext(ctx, metadata.New(map[string]string{"x-forwarded-for": "192.0.2.1"}))
+
+	var got net.IP
+	_, err = NewResolver(proxies).UnaryServerInterceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		got = FromGRPC(ctx)
+		return nil, nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "192.0.2.1", got.String())
+}
+
+func TestParseCIDRs(t *testing.T) {
+	nets, err := ParseCIDRs("10.0.0.0/8, 192.0.2.1,2001:db8::/32,::1")
+	assert.NoError(t, err)
+	assert.Len(t, nets, 4)
+	assert.True(t, Contains(nets, net.ParseIP("192.0.2.1")))
+	assert.False(t, Contains(nets, net.ParseIP("192.0.2.2")))
+	assert.True(t, Contains(nets, net.ParseIP("2001:db8::5")))
+	assert.True(t, Contains(nets, net.ParseIP("::1")))
+	assert.False(t, Contains(nets, nil))
+
+	_, err = ParseCIDRs("10.0.0.0/33")
+	assert.Error(t, err)
+
+	_, err = ParseCIDRs("not-an-ip")
+	assert.Error(t, err)
+}
@@ -0,0 +1,113 @@
+package grpcapp
+
+import (
+	"context"
+	"errors"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/PrahaTurbo/url-shortener/internal/admin"
+	"github.com/PrahaTurbo/url-shortener/internal/auth"
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	pb "github.com/PrahaTurbo/url-shortener/proto"
+)
+
+// AdminServer implements the Admin service of moderators and administrators.
+// The roles its methods need are meant to be checked by the auth interceptor.
+type AdminServer struct {
+	pb.UnimplementedAdminServer
+
+	admin admin.Service
+	log   *logger.Logger
+}
+
+func NewAdminServer(admin admin.Service, logger *logger.Logger) *AdminServer {
+	return &AdminServer{
+		admin: admin,
+		log:   logger,
+	}
+}
+
+func (a *AdminServer) ListUserLinks(ctx context.Context, in *pb.AdminUserLinksRequest) (*pb.AdminUserLinksResponse, error) {
+	actorID, _ := auth.UserIDFromContext(ctx)
+
+	links, err := a.admin.ListUserLinks(ctx, actorID, in.UserId)
+	if err != nil {
+		a.log.Error("error listing user links", zap.Error(err))
+
+		return nil, status.Errorf(codes.Internal, "internal server error")
+	}
+
+	pbLinks := make([]*pb.AdminUserLinksResponse_Link, len(links))
+
+	for i := range links {
+		pbLinks[i] = &pb.AdminUserLinksResponse_Link{
+			ShortUrl:    links[i].ShortURL,
+			OriginalUrl: links[i].OriginalURL,
+			UserId:      links[i].UserID,
+			Deleted:     links[i].Deleted,
+			Disabled:    links[i].Disabled,
+		}
+	}
+
+	return &pb.AdminUserLinksResponse{Links: pbLinks}, nil
+}
+
+func (a *AdminServer) SetLinkDisabled(ctx context.Context, in *pb.SetLinkDisabledRequest) (*pb.SetLinkDisabledResponse, error) {
+	actorID, _ := auth.UserIDFromContext(ctx)
+
+	err := a.admin.SetLinkDisabled(ctx, actorID, in.ShortUrl, in.Disabled)
+	switch {
+	case errors.Is(err, admin.ErrNotFound):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case err != nil:
+		a.log.Error("error changing link state", zap.Error(err))
+
+		return nil, status.Errorf(codes.Internal, "internal server error")
+	}
+
+	return &pb.SetLinkDisabledResponse{}, nil
+}
+
+func (a *AdminServer) ReassignLink(ctx context.Context, in *pb.ReassignLinkRequest) (*pb.ReassignLinkResponse, error) {
+	actorID, _ := auth.UserIDFromContext(ctx)
+
+	req := models.ReassignRequest{FromUserID: in.FromUserId, ToUserID: in.ToUserId}
+
+	err := a.admin.ReassignLink(ctx, actorID, in.ShortUrl, req)
+	switch {
+	case errors.Is(err, admin.ErrInvalidRequest):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, admin.ErrNotFound):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, admin.ErrAlreadyOwned):
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	case err != nil:
+		a.log.Error("error reassigning link", zap.Error(err))
+
+		return nil, status.Errorf(codes.Internal, "internal server error")
+	}
+
+	return &pb.ReassignLinkResponse{}, nil
+}
+
+func (a *AdminServer) SetUserRole(ctx context.Context, in *pb.SetUserRoleRequest) (*pb.SetUserRoleResponse, error) {
+	actorID, _ := auth.UserIDFromContext(ctx)
+
+	err := a.admin.SetUserRole(ctx, actorID, in.UserId, in.Role)
+	switch {
+	case errors.Is(err, admin.ErrInvalidRole):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, admin.ErrUserNotFound):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case err != nil:
+		a.log.Error("error setting user role", zap.Error(err))
+
+		return nil, status.Errorf(codes.Internal, "internal server error")
+	}
+
+	return &pb.SetUserRoleResponse{}, nil
+}
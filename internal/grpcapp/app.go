@@ -2,13 +2,16 @@ package grpcapp
 
 import (
 	"context"
+	"errors"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
 	"github.com/PrahaTurbo/url-shortener/internal/service"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
 	pb "github.com/PrahaTurbo/url-shortener/proto"
 )
 
@@ -27,7 +30,11 @@ func NewGRPCApp(srvc service.Service, logger *logger.Logger) *Application {
 }
 
 func (a *Application) MakeURL(ctx context.Context, in *pb.MakeURLRequest) (*pb.MakeURLResponse, error) {
-	url, err := a.srvc.SaveURL(ctx, in.Url)
+	url, err := a.srvc.SaveURL(ctx, in.Url, models.LinkOptions{})
+	if errors.Is(err, service.ErrQuotaExceeded) {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+
 	if err != nil {
 		a.log.Error("error while saving url", zap.Error(err))
 
@@ -41,6 +48,14 @@ func (a *Application) MakeURL(ctx context.Context, in *pb.MakeURLRequest) (*pb.M
 
 func (a *Application) GetOriginalURL(ctx context.Context, in *pb.GetURLRequest) (*pb.GetURLResponse, error) {
 	originalURL, err := a.srvc.GetURL(ctx, in.ShortUrl)
+	if errors.Is(err, storage.ErrURLDisabled) {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	if errors.Is(err, storage.ErrURLDeleted) || errors.Is(err, storage.ErrURLNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+
 	if err != nil {
 		a.log.Error("error while getting original url", zap.Error(err))
 
@@ -66,6 +81,9 @@ func (a *Application) GetUserURLs(ctx context.Context, in *pb.UserURLsRequest) (
 		url := &pb.UserURLsResponse_UserURLs{
 			ShortUrl:    urls[i].ShortURL,
 			OriginalUrl: urls[i].OriginalURL,
+			Title:       urls[i].Title,
+			Description: urls[i].Description,
+			Image:       urls[i].Image,
 		}
 
 		pbURLs[i] = url
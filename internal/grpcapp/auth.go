@@ -0,0 +1,130 @@
+package grpcapp
+
+import (
+	"context"
+	"errors"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/PrahaTurbo/url-shortener/internal/account"
+	"github.com/PrahaTurbo/url-shortener/internal/auth"
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	pb "github.com/PrahaTurbo/url-shortener/proto"
+)
+
+// AuthServer implements the Auth service, which issues the tokens the rest of the gRPC API is called with.
+// Its methods are meant to be allowed without a token; a valid token, if present, identifies the
+// session whose links are merged into the account on Register and Login.
+type AuthServer struct {
+	pb.UnimplementedAuthServer
+
+	accounts account.Service
+	auth     *auth.Auth
+	log      *logger.Logger
+}
+
+func NewAuthServer(accounts account.Service, auth *auth.Auth, logger *logger.Logger) *AuthServer {
+	return &AuthServer{
+		accounts: accounts,
+		auth:     auth,
+		log:      logger,
+	}
+}
+
+func (a *AuthServer) IssueAnonymousToken(ctx context.Context, _ *pb.AnonymousTokenRequest) (*pb.TokenResponse, error) {
+	session, err := a.auth.IssueAnonymousSession(ctx)
+	if err != nil {
+		a.log.Error("error issuing session", zap.Error(err))
+
+		return nil, status.Errorf(codes.Internal, "internal server error")
+	}
+
+	return tokenResponse(session), nil
+}
+
+func (a *AuthServer) Register(ctx context.Context, in *pb.AccountRequest) (*pb.AccountResponse, error) {
+	if a.accounts == nil {
+		return nil, status.Errorf(codes.Unimplemented, "accounts are not enabled")
+	}
+
+	sessionUserID, _ := auth.UserIDFromContext(ctx)
+
+	resp, err := a.accounts.Register(ctx, sessionUserID, models.AccountRequest{Username: in.Username, Password: in.Password})
+	switch {
+	case errors.Is(err, account.ErrInvalidUsername), errors.Is(err, account.ErrInvalidPassword):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, account.ErrUsernameTaken):
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	case err != nil:
+		a.log.Error("error registering account", zap.Error(err))
+
+		return nil, status.Errorf(codes.Internal, "internal server error")
+	}
+
+	return a.accountResponse(ctx, resp)
+}
+
+func (a *AuthServer) Login(ctx context.Context, in *pb.AccountRequest) (*pb.AccountResponse, error) {
+	if a.accounts == nil {
+		return nil, status.Errorf(codes.Unimplemented, "accounts are not enabled")
+	}
+
+	sessionUserID, _ := auth.UserIDFromContext(ctx)
+
+	resp, err := a.accounts.Login(ctx, sessionUserID, models.AccountRequest{Username: in.Username, Password: in.Password})
+	switch {
+	case errors.Is(err, account.ErrInvalidCredentials):
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	case err != nil:
+		a.log.Error("error logging in", zap.Error(err))
+
+		return nil, status.Errorf(codes.Internal, "internal server error")
+	}
+
+	return a.accountResponse(ctx, resp)
+}
+
+func (a *AuthServer) Refresh(ctx context.Context, in *pb.RefreshRequest) (*pb.TokenResponse, error) {
+	session, err := a.auth.Refresh(ctx, in.RefreshToken)
+	switch {
+	case errors.Is(err, auth.ErrInvalidRefreshToken):
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	case err != nil:
+		a.log.Error("error refreshing session", zap.Error(err))
+
+		return nil, status.Errorf(codes.Internal, "internal server error")
+	}
+
+	return tokenResponse(session), nil
+}
+
+func (a *AuthServer) accountResponse(ctx context.Context, resp *models.AccountResponse) (*pb.AccountResponse, error) {
+	session, err := a.auth.IssueSession(ctx, resp.UserID)
+	if err != nil {
+		a.log.Error("error issuing session", zap.Error(err))
+
+		return nil, status.Errorf(codes.Internal, "internal server error")
+	}
+
+	response := pb.AccountResponse{
+		UserId:       resp.UserID,
+		Username:     resp.Username,
+		Token:        session.AccessToken,
+		RefreshToken: session.RefreshToken,
+		ExpiresAt:    session.ExpiresAt.Unix(),
+	}
+
+	return &response, nil
+}
+
+func tokenResponse(session *auth.Session) *pb.TokenResponse {
+	return &pb.TokenResponse{
+		UserId:       session.UserID,
+		Token:        session.AccessToken,
+		RefreshToken: session.RefreshToken,
+		ExpiresAt:    session.ExpiresAt.Unix(),
+	}
+}
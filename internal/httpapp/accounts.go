@@ -0,0 +1,101 @@
+package httpapp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/PrahaTurbo/url-shortener/internal/account"
+	"github.com/PrahaTurbo/url-shortener/internal/auth"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+)
+
+// RegisterHandler is an HTTP handler that registers an account with the username and password
+// from the JSON in request body, and logs the user in to it.
+// It responds with status codes to indicate success (201), an invalid username or password (400),
+// a taken username (409), or server errors (500).
+//
+// On success, it sets the auth cookie of the account and returns the account with its token in the JSON response.
+// The links of the anonymous user of the session are merged into the account.
+func (a *Application) RegisterHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.AccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.logger.Debug("cannot unmarshal request", zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	sessionUserID, _ := auth.UserIDFromContext(r.Context())
+
+	resp, err := a.accounts.Register(r.Context(), sessionUserID, req)
+	switch {
+	case errors.Is(err, account.ErrInvalidUsername), errors.Is(err, account.ErrInvalidPassword):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, account.ErrUsernameTaken):
+		w.WriteHeader(http.StatusConflict)
+		return
+	case err != nil:
+		a.logger.Error("cannot register account", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	a.startSession(w, r, http.StatusCreated, resp)
+}
+
+// LoginHandler is an HTTP handler that logs the user in to the account with the username and password
+// from the JSON in request body.
+// It responds with status codes to indicate success (200), invalid credentials (401),
+// or server errors (500).
+//
+// On success, it sets the auth cookie of the account and returns the account with its token in the JSON response.
+// The links of the anonymous user of the session are merged into the account.
+func (a *Application) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.AccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.logger.Debug("cannot unmarshal request", zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	sessionUserID, _ := auth.UserIDFromContext(r.Context())
+
+	resp, err := a.accounts.Login(r.Context(), sessionUserID, req)
+	switch {
+	case errors.Is(err, account.ErrInvalidCredentials):
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	case err != nil:
+		a.logger.Error("cannot log in", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	a.startSession(w, r, http.StatusOK, resp)
+}
+
+// startSession replaces the session of the request with a session of the account.
+func (a *Application) startSession(w http.ResponseWriter, r *http.Request, statusCode int, resp *models.AccountResponse) {
+	if refreshToken, ok := auth.RefreshTokenFromRequest(r); ok {
+		if err := a.auth.RevokeSession(r.Context(), refreshToken); err != nil {
+			a.logger.Error("cannot revoke previous session", zap.Error(err))
+		}
+	}
+
+	session, err := a.auth.IssueSession(r.Context(), resp.UserID)
+	if err != nil {
+		a.logger.Error("cannot issue session", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	a.auth.SetSessionCookies(w, session)
+
+	resp.Token = session.AccessToken
+	resp.RefreshToken = session.RefreshToken
+	resp.ExpiresAt = &session.ExpiresAt
+	a.writeJSON(w, statusCode, resp)
+}
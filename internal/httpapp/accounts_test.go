@@ -0,0 +1,163 @@
+package httpapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/PrahaTurbo/url-shortener/internal/account"
+	"github.com/PrahaTurbo/url-shortener/internal/auth"
+	"github.com/PrahaTurbo/url-shortener/internal/mocks"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+)
+
+func TestRegisterHandler(t *testing.T) {
+	app := setupTestApp()
+	app.auth = auth.NewAuth("secret", "")
+
+	req := models.AccountRequest{Username: "alice", Password: "correct horse"}
+
+	tests := []struct {
+		name        string
+		requestBody string
+		prepare     func(s *mocks.MockAccountService)
+		want        int
+	}{
+		{
+			name:        "should register account successfully",
+			requestBody: `{"username": "alice", "password": "correct horse"}`,
+			prepare: func(s *mocks.MockAccountService) {
+				s.EXPECT().
+					Register(gomock.Any(), "anon", req).
+					Return(&models.AccountResponse{UserID: "1", Username: "alice"}, nil)
+			},
+			want: http.StatusCreated,
+		},
+		{
+			name:        "should return 400 if password is invalid",
+			requestBody: `{"username": "alice", "password": "horse"}`,
+			prepare: func(s *mocks.MockAccountService) {
+				s.EXPECT().
+					Register(gomock.Any(), "anon", gomock.Any()).
+					Return(nil, account.ErrInvalidPassword)
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name:        "should return 409 if username is taken",
+			requestBody: `{"username": "alice", "password": "correct horse"}`,
+			prepare: func(s *mocks.MockAccountService) {
+				s.EXPECT().
+					Register(gomock.Any(), "anon", req).
+					Return(nil, account.ErrUsernameTaken)
+			},
+			want: http.StatusConflict,
+		},
+		{
+			name:        "should return 400 if cannot unmarshal",
+			requestBody: `"alice"`,
+			prepare:     func(s *mocks.MockAccountService) {},
+			want:        http.StatusBadRequest,
+		},
+		{
+			name:        "should return 500 if register fails",
+			requestBody: `{"username": "alice", "password": "correct horse"}`,
+			prepare: func(s *mocks.MockAccountService) {
+				s.EXPECT().
+					Register(gomock.Any(), "anon", req).
+					Return(nil, errors.New("internal error"))
+			},
+			want: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			accounts := mocks.NewMockAccountService(ctrl)
+
+			tt.prepare(accounts)
+			app.accounts = accounts
+
+			r := httptest.NewRequest(http.MethodPost, "/api/user/register", strings.NewReader(tt.requestBody))
+			r = r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, "anon"))
+			w := httptest.NewRecorder()
+
+			app.RegisterHandler(w, r)
+
+			assert.Equal(t, tt.want, w.Code)
+
+			if tt.want == http.StatusCreated {
+				assert.Contains(t, w.Header().Get("Set-Cookie"), "token=")
+				assert.Contains(t, w.Body.String(), `"token"`)
+			}
+		})
+	}
+}
+
+func TestLoginHandler(t *testing.T) {
+	app := setupTestApp()
+	app.auth = auth.NewAuth("secret", "")
+
+	tests := []struct {
+		name        string
+		requestBody string
+		prepare     func(s *mocks.MockAccountService)
+		want        int
+	}{
+		{
+			name:        "should log in successfully",
+			requestBody: `{"username": "alice", "password": "correct horse"}`,
+			prepare: func(s *mocks.MockAccountService) {
+				s.EXPECT().
+					Login(gomock.Any(), "anon", models.AccountRequest{Username: "alice", Password: "correct horse"}).
+					Return(&models.AccountResponse{UserID: "1", Username: "alice"}, nil)
+			},
+			want: http.StatusOK,
+		},
+		{
+			name:        "should return 401 on invalid credentials",
+			requestBody: `{"username": "alice", "password": "battery staple"}`,
+			prepare: func(s *mocks.MockAccountService) {
+				s.EXPECT().
+					Login(gomock.Any(), "anon", gomock.Any()).
+					Return(nil, account.ErrInvalidCredentials)
+			},
+			want: http.StatusUnauthorized,
+		},
+		{
+			name:        "should return 500 if login fails",
+			requestBody: `{"username": "alice", "password": "correct horse"}`,
+			prepare: func(s *mocks.MockAccountService) {
+				s.EXPECT().
+					Login(gomock.Any(), "anon", gomock.Any()).
+					Return(nil, errors.New("internal error"))
+			},
+			want: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			accounts := mocks.NewMockAccountService(ctrl)
+
+			tt.prepare(accounts)
+			app.accounts = accounts
+
+			r := httptest.NewRequest(http.MethodPost, "/api/user/login", strings.NewReader(tt.requestBody))
+			r = r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, "anon"))
+			w := httptest.NewRecorder()
+
+			app.LoginHandler(w, r)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package httpapp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/PrahaTurbo/url-shortener/internal/admin"
+	"github.com/PrahaTurbo/url-shortener/internal/auth"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+)
+
+// AdminUserURLsHandler is an HTTP handler that retrieves all the links of the user from the request parameters,
+// including the deleted and disabled ones.
+// It responds with status codes to indicate success (200), if no URLs are found (204),
+// or server errors (500).
+//
+// On success, it returns the links in the JSON response.
+func (a *Application) AdminUserURLsHandler(w http.ResponseWriter, r *http.Request) {
+	actorID, _ := auth.UserIDFromContext(r.Context())
+
+	resp, err := a.admin.ListUserLinks(r.Context(), actorID, chi.URLParam(r, "userID"))
+	if err != nil {
+		a.logger.Error("cannot list user links", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if len(resp) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	a.writeJSON(w, http.StatusOK, resp)
+}
+
+// DisableURLHandler is an HTTP handler that disables the link with the id from the request parameters
+// for all its owners, so it responds with 403 instead of redirecting.
+// It responds with status codes to indicate success (204), an unknown link (404),
+// or server errors (500).
+func (a *Application) DisableURLHandler(w http.ResponseWriter, r *http.Request) {
+	a.setURLDisabled(w, r, true)
+}
+
+// EnableURLHandler is an HTTP handler that enables the disabled link with the id from the request parameters.
+// It responds with status codes to indicate success (204), an unknown link (404),
+// or server errors (500).
+func (a *Application) EnableURLHandler(w http.ResponseWriter, r *http.Request) {
+	a.setURLDisabled(w, r, false)
+}
+
+func (a *Application) setURLDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	actorID, _ := auth.UserIDFromContext(r.Context())
+
+	err := a.admin.SetLinkDisabled(r.Context(), actorID, chi.URLParam(r, "id"), disabled)
+	switch {
+	case errors.Is(err, admin.ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		return
+	case err != nil:
+		a.logger.Error("cannot change link state", zap.Error(err), zap.Bool("disabled", disabled))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReassignURLHandler is an HTTP handler that moves the link with the id from the request parameters
+// between the users from the JSON in request body.
+// It responds with status codes to indicate success (204), an invalid request (400), a link the first user
+// doesn't have (404), a link the other user already has (409), or server errors (500).
+func (a *Application) ReassignURLHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.ReassignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.logger.Debug("cannot unmarshal request", zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	actorID, _ := auth.UserIDFromContext(r.Context())
+
+	err := a.admin.ReassignLink(r.Context(), actorID, chi.URLParam(r, "id"), req)
+	switch {
+	case errors.Is(err, admin.ErrInvalidRequest):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, admin.ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		return
+	case errors.Is(err, admin.ErrAlreadyOwned):
+		w.WriteHeader(http.StatusConflict)
+		return
+	case err != nil:
+		a.logger.Error("cannot reassign link", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetUserRoleHandler is an HTTP handler that grants the role from the JSON in request body
+// to the registered user from the request parameters. The role takes effect once the access token
+// of the user is renewed.
+// It responds with status codes to indicate success (204), an unknown role (400), an unknown user (404),
+// or server errors (500).
+//
+// Requests from the trusted subnet have no actor, which lets the first administrator be appointed.
+func (a *Application) SetUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.logger.Debug("cannot unmarshal request", zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	actorID, _ := auth.UserIDFromContext(r.Context())
+
+	err := a.admin.SetUserRole(r.Context(), actorID, chi.URLParam(r, "userID"), req.Role)
+	switch {
+	case errors.Is(err, admin.ErrInvalidRole):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, admin.ErrUserNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		return
+	case err != nil:
+		a.logger.Error("cannot set user role", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,277 @@
+package httpapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/PrahaTurbo/url-shortener/internal/admin"
+	"github.com/PrahaTurbo/url-shortener/internal/auth"
+	"github.com/PrahaTurbo/url-shortener/internal/mocks"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+)
+
+func TestAdminUserURLsHandler(t *testing.T) {
+	app := setupTestApp()
+
+	tests := []struct {
+		name     string
+		prepare  func(s *mocks.MockAdminService)
+		want     int
+		response string
+	}{
+		{
+			name: "should return links successfully",
+			prepare: func(s *mocks.MockAdminService) {
+				s.EXPECT().
+					ListUserLinks(gomock.Any(), "mod", "1").
+					Return([]models.AdminLinkResponse{{ShortURL: "http://localhost:8080/abc", OriginalURL: "https://example.com", UserID: "1", Disabled: true}}, nil)
+			},
+			want:     http.StatusOK,
+			response: `[{"short_url": "http://localhost:8080/abc", "original_url": "https://example.com", "user_id": "1", "deleted": false, "disabled": true}]`,
+		},
+		{
+			name: "should return 204 if no links are found",
+			prepare: func(s *mocks.MockAdminService) {
+				s.EXPECT().ListUserLinks(gomock.Any(), "mod", "1").Return([]models.AdminLinkResponse{}, nil)
+			},
+			want: http.StatusNoContent,
+		},
+		{
+			name: "should return 500 on storage error",
+			prepare: func(s *mocks.MockAdminService) {
+				s.EXPECT().ListUserLinks(gomock.Any(), "mod", "1").Return(nil, errors.New("connection refused"))
+			},
+			want: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			admins := mocks.NewMockAdminService(ctrl)
+
+			tt.prepare(admins)
+			app.admin = admins
+
+			r := withUserIDParam(httptest.NewRequest(http.MethodGet, "/api/admin/users/1/urls", nil), "1")
+			r = r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, "mod"))
+			w := httptest.NewRecorder()
+
+			app.AdminUserURLsHandler(w, r)
+
+			assert.Equal(t, tt.want, w.Code)
+
+			if tt.response != "" {
+				assert.JSONEq(t, tt.response, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestDisableURLHandler(t *testing.T) {
+	app := setupTestApp()
+
+	tests := []struct {
+		name    string
+		prepare func(s *mocks.MockAdminService)
+		want    int
+	}{
+		{
+			name: "should disable link successfully",
+			prepare: func(s *mocks.MockAdminService) {
+				s.EXPECT().SetLinkDisabled(gomock.Any(), "mod", "abc", true).Return(nil)
+			},
+			want: http.StatusNoContent,
+		},
+		{
+			name: "should return 404 for unknown link",
+			prepare: func(s *mocks.MockAdminService) {
+				s.EXPECT().SetLinkDisabled(gomock.Any(), "mod", "abc", true).Return(admin.ErrNotFound)
+			},
+			want: http.StatusNotFound,
+		},
+		{
+			name: "should return 500 if disabling fails",
+			prepare: func(s *mocks.MockAdminService) {
+				s.EXPECT().SetLinkDisabled(gomock.Any(), "mod", "abc", true).Return(errors.New("internal error"))
+			},
+			want: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			admins := mocks.NewMockAdminService(ctrl)
+
+			tt.prepare(admins)
+			app.admin = admins
+
+			r := httptest.NewRequest(http.MethodPost, "/api/admin/urls/abc/disable", nil)
+			chiCtx := chi.NewRouteContext()
+			chiCtx.URLParams.Add("id", "abc")
+			ctx := context.WithValue(r.Context(), chi.RouteCtxKey, chiCtx)
+			r = r.WithContext(context.WithValue(ctx, auth.UserIDKey, "mod"))
+			w := httptest.NewRecorder()
+
+			app.DisableURLHandler(w, r)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestReassignURLHandler(t *testing.T) {
+	app := setupTestApp()
+
+	tests := []struct {
+		name        string
+		requestBody string
+		prepare     func(s *mocks.MockAdminService)
+		want        int
+	}{
+		{
+			name:        "should reassign link successfully",
+			requestBody: `{"from_user_id": "1", "to_user_id": "2"}`,
+			prepare: func(s *mocks.MockAdminService) {
+				s.EXPECT().
+					ReassignLink(gomock.Any(), "admin", "abc", models.ReassignRequest{FromUserID: "1", ToUserID: "2"}).
+					Return(nil)
+			},
+			want: http.StatusNoContent,
+		},
+		{
+			name:        "should return 400 if cannot unmarshal",
+			requestBody: `"1"`,
+			prepare:     func(s *mocks.MockAdminService) {},
+			want:        http.StatusBadRequest,
+		},
+		{
+			name:        "should return 400 for invalid request",
+			requestBody: `{"from_user_id": "1"}`,
+			prepare: func(s *mocks.MockAdminService) {
+				s.EXPECT().ReassignLink(gomock.Any(), "admin", "abc", gomock.Any()).Return(admin.ErrInvalidRequest)
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name:        "should return 404 for unknown link",
+			requestBody: `{"from_user_id": "1", "to_user_id": "2"}`,
+			prepare: func(s *mocks.MockAdminService) {
+				s.EXPECT().ReassignLink(gomock.Any(), "admin", "abc", gomock.Any()).Return(admin.ErrNotFound)
+			},
+			want: http.StatusNotFound,
+		},
+		{
+			name:        "should return 409 if the user already has the link",
+			requestBody: `{"from_user_id": "1", "to_user_id": "2"}`,
+			prepare: func(s *mocks.MockAdminService) {
+				s.EXPECT().ReassignLink(gomock.Any(), "admin", "abc", gomock.Any()).Return(admin.ErrAlreadyOwned)
+			},
+			want: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			admins := mocks.NewMockAdminService(ctrl)
+
+			tt.prepare(admins)
+			app.admin = admins
+
+			r := httptest.NewRequest(http.MethodPost, "/api/admin/urls/abc/owner", strings.NewReader(tt.requestBody))
+			chiCtx := chi.NewRouteContext()
+			chiCtx.URLParams.Add("id", "abc")
+			ctx := context.WithValue(r.Context(), chi.RouteCtxKey, chiCtx)
+			r = r.WithContext(context.WithValue(ctx, auth.UserIDKey, "admin"))
+			w := httptest.NewRecorder()
+
+			app.ReassignURLHandler(w, r)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestSetUserRoleHandler(t *testing.T) {
+	app := setupTestApp()
+
+	tests := []struct {
+		name        string
+		requestBody string
+		prepare     func(s *mocks.MockAdminService)
+		want        int
+	}{
+		{
+			name:        "should set role successfully",
+			requestBody: `{"role": "moderator"}`,
+			prepare: func(s *mocks.MockAdminService) {
+				s.EXPECT().SetUserRole(gomock.Any(), "", "1", auth.RoleModerator).Return(nil)
+			},
+			want: http.StatusNoContent,
+		},
+		{
+			name:        "should return 400 for unknown role",
+			requestBody: `{"role": "root"}`,
+			prepare: func(s *mocks.MockAdminService) {
+				s.EXPECT().SetUserRole(gomock.Any(), "", "1", "root").Return(admin.ErrInvalidRole)
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name:        "should return 404 for unknown user",
+			requestBody: `{"role": "admin"}`,
+			prepare: func(s *mocks.MockAdminService) {
+				s.EXPECT().SetUserRole(gomock.Any(), "", "1", auth.RoleAdmin).Return(admin.ErrUserNotFound)
+			},
+			want: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			admins := mocks.NewMockAdminService(ctrl)
+
+			tt.prepare(admins)
+			app.admin = admins
+
+			r := withUserIDParam(httptest.NewRequest(http.MethodPut, "/api/internal/users/1/role", strings.NewReader(tt.requestBody)), "1")
+			w := httptest.NewRecorder()
+
+			app.SetUserRoleHandler(w, r)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestGetOriginHandler_Disabled(t *testing.T) {
+	app := setupTestApp()
+
+	ctrl := gomock.NewController(t)
+	srv := mocks.NewMockService(ctrl)
+	srv.EXPECT().GetURL(gomock.Any(), "abc").Return("", storage.ErrURLDisabled)
+	app.srv = srv
+
+	r := httptest.NewRequest(http.MethodGet, "/abc", nil)
+	chiCtx := chi.NewRouteContext()
+	chiCtx.URLParams.Add("id", "abc")
+	r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, chiCtx))
+	w := httptest.NewRecorder()
+
+	app.GetOriginHandler(w, r)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, w.Header().Get("Location"))
+}
@@ -0,0 +1,94 @@
+package httpapp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/PrahaTurbo/url-shortener/internal/apikey"
+	"github.com/PrahaTurbo/url-shortener/internal/auth"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+)
+
+// CreateAPIKeyHandler is an HTTP handler that creates an API key for the user.
+// It responds with status codes to indicate success (201), unknown scopes or an expiry in the past (400),
+// or server errors (500).
+//
+// On success, it returns the API key in the JSON response. The key itself is never returned again.
+func (a *Application) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var req models.APIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.logger.Debug("cannot unmarshal request", zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	resp, err := a.apiKeys.Create(r.Context(), userID, req)
+	switch {
+	case errors.Is(err, apikey.ErrInvalidScopes), errors.Is(err, apikey.ErrInvalidExpiry):
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	case err != nil:
+		a.logger.Error("cannot create api key", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	a.writeJSON(w, http.StatusCreated, resp)
+}
+
+// GetAPIKeysHandler is an HTTP handler that retrieves all the API keys of the user.
+// It responds with status codes to indicate success (200), if no keys are found (204),
+// or server errors (500).
+func (a *Application) GetAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	resp, err := a.apiKeys.List(r.Context(), userID)
+	if err != nil {
+		a.logger.Error("cannot get api keys", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if len(resp) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	a.writeJSON(w, http.StatusOK, resp)
+}
+
+// RevokeAPIKeyHandler is an HTTP handler that revokes the API key of the user.
+// It responds with status codes to indicate success (204), an unknown key (404),
+// or server errors (500).
+func (a *Application) RevokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	err := a.apiKeys.Revoke(r.Context(), userID, chi.URLParam(r, "id"))
+	switch {
+	case errors.Is(err, apikey.ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case err != nil:
+		a.logger.Error("cannot revoke api key", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,202 @@
+package httpapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/PrahaTurbo/url-shortener/internal/apikey"
+	"github.com/PrahaTurbo/url-shortener/internal/auth"
+	"github.com/PrahaTurbo/url-shortener/internal/mocks"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+)
+
+func TestCreateAPIKeyHandler(t *testing.T) {
+	app := setupTestApp()
+
+	tests := []struct {
+		name        string
+		requestBody string
+		userID      string
+		prepare     func(s *mocks.MockAPIKeyService)
+		want        int
+	}{
+		{
+			name:        "should create api key successfully",
+			requestBody: `{"name": "ci", "scopes": ["read"]}`,
+			userID:      "1",
+			prepare: func(s *mocks.MockAPIKeyService) {
+				s.EXPECT().
+					Create(gomock.Any(), "1", models.APIKeyRequest{Name: "ci", Scopes: []string{"read"}}).
+					Return(&models.APIKeyResponse{ID: "abc", Key: "sk_1", CreatedAt: time.Now()}, nil)
+			},
+			want: http.StatusCreated,
+		},
+		{
+			name:        "should return 400 if scopes are invalid",
+			requestBody: `{"name": "ci", "scopes": ["admin"]}`,
+			userID:      "1",
+			prepare: func(s *mocks.MockAPIKeyService) {
+				s.EXPECT().
+					Create(gomock.Any(), "1", gomock.Any()).
+					Return(nil, apikey.ErrInvalidScopes)
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name:        "should return 400 if cannot unmarshal",
+			requestBody: `"name"`,
+			userID:      "1",
+			prepare:     func(s *mocks.MockAPIKeyService) {},
+			want:        http.StatusBadRequest,
+		},
+		{
+			name:        "should return 500 if create fails",
+			requestBody: `{"name": "ci"}`,
+			userID:      "1",
+			prepare: func(s *mocks.MockAPIKeyService) {
+				s.EXPECT().
+					Create(gomock.Any(), "1", gomock.Any()).
+					Return(nil, errors.New("internal error"))
+			},
+			want: http.StatusInternalServerError,
+		},
+		{
+			name:        "should return 401 without user",
+			requestBody: `{"name": "ci"}`,
+			prepare:     func(s *mocks.MockAPIKeyService) {},
+			want:        http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			apiKeys := mocks.NewMockAPIKeyService(ctrl)
+
+			tt.prepare(apiKeys)
+			app.apiKeys = apiKeys
+
+			r := httptest.NewRequest(http.MethodPost, "/api/user/keys", strings.NewReader(tt.requestBody))
+			if tt.userID != "" {
+				r = r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, tt.userID))
+			}
+			w := httptest.NewRecorder()
+
+			app.CreateAPIKeyHandler(w, r)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestGetAPIKeysHandler(t *testing.T) {
+	app := setupTestApp()
+
+	tests := []struct {
+		name    string
+		prepare func(s *mocks.MockAPIKeyService)
+		want    int
+	}{
+		{
+			name: "should list api keys successfully",
+			prepare: func(s *mocks.MockAPIKeyService) {
+				s.EXPECT().List(gomock.Any(), "1").Return([]models.APIKeyResponse{{ID: "abc"}}, nil)
+			},
+			want: http.StatusOK,
+		},
+		{
+			name: "should return 204 if there are no api keys",
+			prepare: func(s *mocks.MockAPIKeyService) {
+				s.EXPECT().List(gomock.Any(), "1").Return(nil, nil)
+			},
+			want: http.StatusNoContent,
+		},
+		{
+			name: "should return 500 if list fails",
+			prepare: func(s *mocks.MockAPIKeyService) {
+				s.EXPECT().List(gomock.Any(), "1").Return(nil, errors.New("internal error"))
+			},
+			want: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			apiKeys := mocks.NewMockAPIKeyService(ctrl)
+
+			tt.prepare(apiKeys)
+			app.apiKeys = apiKeys
+
+			r := httptest.NewRequest(http.MethodGet, "/api/user/keys", nil)
+			r = r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, "1"))
+			w := httptest.NewRecorder()
+
+			app.GetAPIKeysHandler(w, r)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestRevokeAPIKeyHandler(t *testing.T) {
+	app := setupTestApp()
+
+	tests := []struct {
+		name    string
+		prepare func(s *mocks.MockAPIKeyService)
+		want    int
+	}{
+		{
+			name: "should revoke api key successfully",
+			prepare: func(s *mocks.MockAPIKeyService) {
+				s.EXPECT().Revoke(gomock.Any(), "1", "abc").Return(nil)
+			},
+			want: http.StatusNoContent,
+		},
+		{
+			name: "should return 404 if api key not found",
+			prepare: func(s *mocks.MockAPIKeyService) {
+				s.EXPECT().Revoke(gomock.Any(), "1", "abc").Return(apikey.ErrNotFound)
+			},
+			want: http.StatusNotFound,
+		},
+		{
+			name: "should return 500 if revoke fails",
+			prepare: func(s *mocks.MockAPIKeyService) {
+				s.EXPECT().Revoke(gomock.Any(), "1", "abc").Return(errors.New("internal error"))
+			},
+			want: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			apiKeys := mocks.NewMockAPIKeyService(ctrl)
+
+			tt.prepare(apiKeys)
+			app.apiKeys = apiKeys
+
+			r := httptest.NewRequest(http.MethodDelete, "/api/user/keys/abc", nil)
+			chiCtx := chi.NewRouteContext()
+			chiCtx.URLParams.Add("id", "abc")
+			ctx := context.WithValue(r.Context(), chi.RouteCtxKey, chiCtx)
+			r = r.WithContext(context.WithValue(ctx, auth.UserIDKey, "1"))
+			w := httptest.NewRecorder()
+
+			app.RevokeAPIKeyHandler(w, r)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
@@ -1,24 +1,113 @@
 package httpapp
 
 import (
+	"github.com/PrahaTurbo/url-shortener/internal/account"
+	"github.com/PrahaTurbo/url-shortener/internal/admin"
+	"github.com/PrahaTurbo/url-shortener/internal/apikey"
+	"github.com/PrahaTurbo/url-shortener/internal/audit"
 	"github.com/PrahaTurbo/url-shortener/internal/auth"
+	"github.com/PrahaTurbo/url-shortener/internal/clientip"
 	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/ratelimit"
 	"github.com/PrahaTurbo/url-shortener/internal/service"
+	"github.com/PrahaTurbo/url-shortener/internal/webhook"
+	"github.com/PrahaTurbo/url-shortener/internal/workspace"
 )
 
 // Application is an implementation of the App interface.
 type Application struct {
-	srv    service.Service
-	logger *logger.Logger
-	auth   *auth.Auth
+	srv        service.Service
+	logger     *logger.Logger
+	auth       *auth.Auth
+	webhooks   webhook.Service
+	apiKeys    apikey.Service
+	accounts   account.Service
+	admin      admin.Service
+	workspaces workspace.Service
+	audit      audit.Service
+	limits     RateLimits
+	clientIP   *clientip.Resolver
+}
+
+// RateLimits holds the limiters applied to the routes. A nil limiter disables limiting of its routes.
+type RateLimits struct {
+	Create   *ratelimit.Limiter
+	Batch    *ratelimit.Limiter
+	Redirect *ratelimit.Limiter
+}
+
+// Option configures optional features of the Application.
+type Option func(a *Application)
+
+// WithWebhooks enables the webhook management API.
+func WithWebhooks(ws webhook.Service) Option {
+	return func(a *Application) {
+		a.webhooks = ws
+	}
+}
+
+// WithAPIKeys enables the API key management API.
+func WithAPIKeys(ks apikey.Service) Option {
+	return func(a *Application) {
+		a.apiKeys = ks
+	}
+}
+
+// WithAccounts enables the account registration and login API.
+func WithAccounts(as account.Service) Option {
+	return func(a *Application) {
+		a.accounts = as
+	}
+}
+
+// WithAdmin enables the API of moderators and administrators.
+func WithAdmin(as admin.Service) Option {
+	return func(a *Application) {
+		a.admin = as
+	}
+}
+
+// WithWorkspaces enables the workspace management API.
+func WithWorkspaces(ws workspace.Service) Option {
+	return func(a *Application) {
+		a.workspaces = ws
+	}
+}
+
+// WithAuditLog enables the audit log query API for administrators.
+func WithAuditLog(as audit.Service) Option {
+	return func(a *Application) {
+		a.audit = as
+	}
+}
+
+// WithRateLimits enables rate limiting of the creation, batch and redirect routes.
+func WithRateLimits(limits RateLimits) Option {
+	return func(a *Application) {
+		a.limits = limits
+	}
+}
+
+// WithClientIP resolves the client IP of every request behind the trusted proxies of the resolver,
+// for admin access, rate limiting and logging. Without it, the peer address is the client IP.
+func WithClientIP(r *clientip.Resolver) Option {
+	return func(a *Application) {
+		a.clientIP = r
+	}
 }
 
 // NewHTTPApp initializes a new Application struct with the provided service, logger, server address and JWT Secret,
 // and returns it as an App interface.
-func NewHTTPApp(srv service.Service, logger *logger.Logger, auth *auth.Auth) *Application {
-	return &Application{
+func NewHTTPApp(srv service.Service, logger *logger.Logger, auth *auth.Auth, opts ...Option) *Application {
+	a := &Application{
 		srv:    srv,
 		logger: logger,
 		auth:   auth,
 	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
 }
@@ -0,0 +1,100 @@
+package httpapp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/PrahaTurbo/url-shortener/internal/audit"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+)
+
+// AuditLogHandler is an HTTP handler that retrieves a page of the audit log. The entries can be filtered
+// with the actor_id, action, target and transport query parameters and the since and until RFC 3339 times,
+// and paginated with the limit and offset query parameters.
+// It responds with status codes to indicate success (200), invalid query parameters (400),
+// or server errors (500).
+//
+// On success, it returns the entries, newest first, and the offset of the next page in the JSON response.
+func (a *Application) AuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	q, err := parseAuditQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := a.audit.Query(r.Context(), q)
+	switch {
+	case errors.Is(err, audit.ErrInvalidQuery):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		a.logger.Error("cannot query audit log", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	a.writeJSON(w, http.StatusOK, resp)
+}
+
+func parseAuditQuery(values url.Values) (models.AuditQuery, error) {
+	q := models.AuditQuery{
+		ActorID:   values.Get("actor_id"),
+		Action:    values.Get("action"),
+		Target:    values.Get("target"),
+		Transport: values.Get("transport"),
+	}
+
+	var err error
+
+	if q.Since, err = parseTimeParam(values, "since"); err != nil {
+		return q, err
+	}
+
+	if q.Until, err = parseTimeParam(values, "until"); err != nil {
+		return q, err
+	}
+
+	if q.Limit, err = parseIntParam(values, "limit"); err != nil {
+		return q, err
+	}
+
+	if q.Offset, err = parseIntParam(values, "offset"); err != nil {
+		return q, err
+	}
+
+	return q, nil
+}
+
+func parseTimeParam(values url.Values, name string) (time.Time, error) {
+	v := values.Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 time", name)
+	}
+
+	return t, nil
+}
+
+func parseIntParam(values url.Values, name string) (int, error) {
+	v := values.Get(name)
+	if v == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer", name)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,111 @@
+package httpapp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/PrahaTurbo/url-shortener/internal/audit"
+	"github.com/PrahaTurbo/url-shortener/internal/mocks"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+)
+
+func TestAuditLogHandler(t *testing.T) {
+	app := setupTestApp()
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		query    string
+		prepare  func(s *mocks.MockAuditService)
+		want     int
+		response string
+	}{
+		{
+			name:  "should return page successfully",
+			query: "?actor_id=1&action=link.delete&since=2024-01-01T00:00:00Z&limit=1&offset=2",
+			prepare: func(s *mocks.MockAuditService) {
+				s.EXPECT().
+					Query(gomock.Any(), models.AuditQuery{
+						ActorID: "1",
+						Action:  audit.ActionLinkDelete,
+						Since:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+						Limit:   1,
+						Offset:  2,
+					}).
+					Return(&models.AuditPage{
+						Entries: []models.AuditEntryResponse{{
+							ID:        "e1",
+							ActorID:   "1",
+							Action:    audit.ActionLinkDelete,
+							Target:    "abc",
+							Transport: audit.TransportHTTP,
+							ClientIP:  "192.0.2.1",
+							RequestID: "req-1",
+							Before:    json.RawMessage(`{"is_deleted":false}`),
+							CreatedAt: createdAt,
+						}},
+						NextOffset: 3,
+					}, nil)
+			},
+			want: http.StatusOK,
+			response: `{"entries": [{"id": "e1", "actor_id": "1", "action": "link.delete", "target": "abc",
+				"transport": "http", "client_ip": "192.0.2.1", "request_id": "req-1",
+				"before": {"is_deleted": false}, "created_at": "2024-01-02T03:04:05Z"}], "next_offset": 3}`,
+		},
+		{
+			name:    "should return 400 for invalid time",
+			query:   "?until=yesterday",
+			prepare: func(s *mocks.MockAuditService) {},
+			want:    http.StatusBadRequest,
+		},
+		{
+			name:    "should return 400 for invalid limit",
+			query:   "?limit=ten",
+			prepare: func(s *mocks.MockAuditService) {},
+			want:    http.StatusBadRequest,
+		},
+		{
+			name:  "should return 400 for limit out of range",
+			query: "?limit=1000",
+			prepare: func(s *mocks.MockAuditService) {
+				s.EXPECT().Query(gomock.Any(), models.AuditQuery{Limit: 1000}).Return(nil, audit.ErrInvalidQuery)
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "should return 500 if query fails",
+			prepare: func(s *mocks.MockAuditService) {
+				s.EXPECT().Query(gomock.Any(), models.AuditQuery{}).Return(nil, errors.New("internal error"))
+			},
+			want: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			audits := mocks.NewMockAuditService(ctrl)
+
+			tt.prepare(audits)
+			app.audit = audits
+
+			r := httptest.NewRequest(http.MethodGet, "/api/admin/audit"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			app.AuditLogHandler(w, r)
+
+			assert.Equal(t, tt.want, w.Code)
+
+			if tt.response != "" {
+				assert.JSONEq(t, tt.response, w.Body.String())
+			}
+		})
+	}
+}
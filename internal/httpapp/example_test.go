@@ -76,6 +76,17 @@ func ExampleApplication_BatchHandler() {
 	fmt.Println(r)
 }
 
+func ExampleApplication_ExpandHandler() {
+	url := "http://127.0.0.1:8080/api/expand/abc-123"
+
+	r, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(r)
+}
+
 func ExampleApplication_GetUserURLsHandler() {
 	url := "http://127.0.0.1:8080/api/user/urls"
 
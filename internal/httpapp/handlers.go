@@ -2,6 +2,7 @@ package httpapp
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 
@@ -10,12 +11,12 @@ import (
 
 	"github.com/PrahaTurbo/url-shortener/internal/models"
 	"github.com/PrahaTurbo/url-shortener/internal/service"
-	"github.com/PrahaTurbo/url-shortener/internal/storage/pg"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
 )
 
 // MakeURLHandler is an HTTP handler that saves URL from the request body and creates a short URL version.
 // It responds with status codes to indicate success (201), duplicate URL (409),
-// invalid path (400), or server errors (500).
+// invalid path (400), exceeded quota (403), or server errors (500).
 //
 // On successful URL creation, it returns the short URL in the response.
 func (a *Application) MakeURLHandler(w http.ResponseWriter, r *http.Request) {
@@ -31,11 +32,14 @@ func (a *Application) MakeURLHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var statusCode int
-	shortURL, err := a.srv.SaveURL(r.Context(), string(body))
-	switch err {
-	case service.ErrAlready:
+	shortURL, err := a.srv.SaveURL(r.Context(), string(body), models.LinkOptions{})
+	switch {
+	case errors.Is(err, service.ErrAlready):
 		statusCode = http.StatusConflict
-	case nil:
+	case errors.Is(err, service.ErrQuotaExceeded):
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	case err == nil:
 		statusCode = http.StatusCreated
 	default:
 		w.WriteHeader(http.StatusInternalServerError)
@@ -49,9 +53,11 @@ func (a *Application) MakeURLHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// JSONHandler is an HTTP handler that saves URL from the JSON in request body and creates a short URL version.
+// JSONHandler is an HTTP handler that saves URL from the JSON in request body and creates a short URL version,
+// which expires at the expires_at and notifies its owner once it's clicked click_threshold times, if they're set.
 // It responds with status codes to indicate success (201), a URL already saved (409),
-// a bad request i.e., a request without a URL (400), or server errors (500).
+// a bad request i.e., a request without a URL or with an expiry in the past or a negative threshold (400),
+// exceeded quota (403), or server errors (500).
 //
 // On successful URL creation, it returns the short URL in the JSON response.
 func (a *Application) JSONHandler(w http.ResponseWriter, r *http.Request) {
@@ -70,11 +76,17 @@ func (a *Application) JSONHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var statusCode int
-	shortURL, err := a.srv.SaveURL(r.Context(), req.URL)
-	switch err {
-	case service.ErrAlready:
+	shortURL, err := a.srv.SaveURL(r.Context(), req.URL, req.LinkOptions)
+	switch {
+	case errors.Is(err, service.ErrAlready):
 		statusCode = http.StatusConflict
-	case nil:
+	case errors.Is(err, service.ErrInvalidExpiry), errors.Is(err, service.ErrInvalidClickThreshold):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, service.ErrQuotaExceeded):
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	case err == nil:
 		statusCode = http.StatusCreated
 	default:
 		w.WriteHeader(http.StatusInternalServerError)
@@ -97,15 +109,22 @@ func (a *Application) JSONHandler(w http.ResponseWriter, r *http.Request) {
 // GetOriginHandler is an HTTP handler function that retrieves the original URL
 // for a given id from the request parameters.
 // It responds with status codes to indicate success (307) alongside the original URL located in the header,
-// a URL was deleted (410) or a bad request (400) for any other error.
+// a URL was deleted (410), a URL was disabled by a moderator (403), a URL not found (404)
+// or a bad request (400) for any other error.
 func (a *Application) GetOriginHandler(w http.ResponseWriter, r *http.Request) {
 	url, err := a.srv.GetURL(r.Context(), chi.URLParam(r, "id"))
 
-	switch err {
-	case pg.ErrURLDeleted:
+	switch {
+	case errors.Is(err, storage.ErrURLDeleted):
 		w.WriteHeader(http.StatusGone)
 		return
-	case nil:
+	case errors.Is(err, storage.ErrURLDisabled):
+		w.WriteHeader(http.StatusForbidden)
+		return
+	case errors.Is(err, storage.ErrURLNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		return
+	case err == nil:
 		w.Header().Set("Location", url)
 		w.WriteHeader(http.StatusTemporaryRedirect)
 		return
@@ -115,6 +134,41 @@ func (a *Application) GetOriginHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// ExpandHandler is an HTTP handler function that retrieves the original URL and the preview
+// of its destination for a given id from the request parameters.
+// It responds with status codes to indicate success (200), a URL was deleted (410),
+// a URL was disabled by a moderator (403), a URL not found (404) or server errors (500).
+//
+// On success, it returns the original URL with its title, description and image in the JSON response.
+func (a *Application) ExpandHandler(w http.ResponseWriter, r *http.Request) {
+	resp, err := a.srv.Expand(r.Context(), chi.URLParam(r, "id"))
+
+	switch {
+	case errors.Is(err, storage.ErrURLDeleted):
+		w.WriteHeader(http.StatusGone)
+		return
+	case errors.Is(err, storage.ErrURLDisabled):
+		w.WriteHeader(http.StatusForbidden)
+		return
+	case errors.Is(err, storage.ErrURLNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		return
+	case err == nil:
+	default:
+		a.logger.Error("failed to expand url", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("content-type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		a.logger.Debug("error encoding response", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
 // PingHandler is an HTTP handler function that checks the connection to the database.
 // It responds with status code 500 to indicate if the database is unreachable,
 // or 200 if the connection is healthy.
@@ -128,8 +182,9 @@ func (a *Application) PingHandler(w http.ResponseWriter, _ *http.Request) {
 }
 
 // BatchHandler is an HTTP handler that saves multiple URLs from the JSON in request body
-// and creates a short URL version for each.
-// It responds with status codes to indicate success (201), or server errors (500).
+// and creates a short URL version for each, with the expiry and the click threshold of its request.
+// It responds with status codes to indicate success (201), an expiry in the past or a negative threshold (400),
+// exceeded quota (403), or server errors (500).
 //
 // On successful URLs creation, it returns the short URLs in the JSON response.
 func (a *Application) BatchHandler(w http.ResponseWriter, r *http.Request) {
@@ -142,6 +197,16 @@ func (a *Application) BatchHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	resp, err := a.srv.SaveBatch(r.Context(), req)
+	if errors.Is(err, service.ErrQuotaExceeded) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	if errors.Is(err, service.ErrInvalidExpiry) || errors.Is(err, service.ErrInvalidClickThreshold) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
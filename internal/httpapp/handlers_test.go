@@ -17,7 +17,7 @@ import (
 	"github.com/PrahaTurbo/url-shortener/internal/mocks"
 	"github.com/PrahaTurbo/url-shortener/internal/models"
 	"github.com/PrahaTurbo/url-shortener/internal/service"
-	"github.com/PrahaTurbo/url-shortener/internal/storage/pg"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
 )
 
 var (
@@ -55,7 +55,7 @@ func Test_application_makeURL(t *testing.T) {
 			requestBody: "https://ya.ru",
 			prepare: func(s *mocks.MockService) {
 				s.EXPECT().
-					SaveURL(gomock.Any(), "https://ya.ru").
+					SaveURL(gomock.Any(), "https://ya.ru", models.LinkOptions{}).
 					Return(baseURL+"/fpCk-c", service.ErrAlready)
 			},
 			want: want{
@@ -70,7 +70,7 @@ func Test_application_makeURL(t *testing.T) {
 			requestBody: "https://ya.ru",
 			prepare: func(s *mocks.MockService) {
 				s.EXPECT().
-					SaveURL(gomock.Any(), "https://ya.ru").
+					SaveURL(gomock.Any(), "https://ya.ru", models.LinkOptions{}).
 					Return(baseURL+"/fpCk-c", nil)
 			},
 			want: want{
@@ -79,6 +79,20 @@ func Test_application_makeURL(t *testing.T) {
 				response:    baseURL + "/fpCk-c",
 			},
 		},
+		{
+			name:        "should return forbidden if quota is exceeded",
+			request:     "/",
+			requestBody: "https://ya.ru",
+			prepare: func(s *mocks.MockService) {
+				s.EXPECT().
+					SaveURL(gomock.Any(), "https://ya.ru", models.LinkOptions{}).
+					Return("", &service.QuotaError{Quota: service.QuotaLinks, Limit: 10, Requested: 11})
+			},
+			want: want{
+				contentType: "text/plain",
+				statusCode:  http.StatusForbidden,
+			},
+		},
 		{
 			name:        "should return error if unsupported request path",
 			request:     "/make-url",
@@ -95,7 +109,7 @@ func Test_application_makeURL(t *testing.T) {
 			requestBody: "https://ya.ru",
 			prepare: func(s *mocks.MockService) {
 				s.EXPECT().
-					SaveURL(gomock.Any(), "https://ya.ru").
+					SaveURL(gomock.Any(), "https://ya.ru", models.LinkOptions{}).
 					Return("", errors.New("internal error"))
 			},
 			want: want{
@@ -176,13 +190,39 @@ func Test_application_getOrigin(t *testing.T) {
 			prepare: func(s *mocks.MockService) {
 				s.EXPECT().
 					GetURL(gomock.Any(), "fpCk-c").
-					Return("", pg.ErrURLDeleted)
+					Return("", storage.ErrURLDeleted)
 			},
 			want: want{
 				location:   "",
 				statusCode: http.StatusGone,
 			},
 		},
+		{
+			name:    "should return 410 if url was deleted with a wrapped error",
+			request: "/fpCk-c",
+			prepare: func(s *mocks.MockService) {
+				s.EXPECT().
+					GetURL(gomock.Any(), "fpCk-c").
+					Return("", fmt.Errorf("get url: %w", storage.ErrURLDeleted))
+			},
+			want: want{
+				location:   "",
+				statusCode: http.StatusGone,
+			},
+		},
+		{
+			name:    "should return 404 if url not found",
+			request: "/azcxc",
+			prepare: func(s *mocks.MockService) {
+				s.EXPECT().
+					GetURL(gomock.Any(), "azcxc").
+					Return("", fmt.Errorf("get url: %w", storage.ErrURLNotFound))
+			},
+			want: want{
+				location:   "",
+				statusCode: http.StatusNotFound,
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -212,6 +252,113 @@ func Test_application_getOrigin(t *testing.T) {
 	}
 }
 
+func Test_application_expandHandler(t *testing.T) {
+	app := setupTestApp()
+
+	type want struct {
+		statusCode int
+		response   string
+	}
+
+	tests := []struct {
+		name    string
+		id      string
+		prepare func(s *mocks.MockService)
+		want    want
+	}{
+		{
+			name: "should expand url successfully",
+			id:   "fpCk-c",
+			prepare: func(s *mocks.MockService) {
+				s.EXPECT().
+					Expand(gomock.Any(), "fpCk-c").
+					Return(&models.ExpandResponse{
+						ShortURL:    baseURL + "/fpCk-c",
+						OriginalURL: "https://ya.ru",
+						Title:       "Яндекс",
+					}, nil)
+			},
+			want: want{
+				statusCode: http.StatusOK,
+				response:   fmt.Sprintf(`{"short_url": "%s/fpCk-c", "original_url": "https://ya.ru", "title": "Яндекс"}`, baseURL),
+			},
+		},
+		{
+			name: "should return 404 if url not found",
+			id:   "azcxc",
+			prepare: func(s *mocks.MockService) {
+				s.EXPECT().
+					Expand(gomock.Any(), "azcxc").
+					Return(nil, fmt.Errorf("%w: azcxc", storage.ErrURLNotFound))
+			},
+			want: want{
+				statusCode: http.StatusNotFound,
+			},
+		},
+		{
+			name: "should return 500 if storage fails",
+			id:   "fpCk-c",
+			prepare: func(s *mocks.MockService) {
+				s.EXPECT().
+					Expand(gomock.Any(), "fpCk-c").
+					Return(nil, errors.New("connection refused"))
+			},
+			want: want{
+				statusCode: http.StatusInternalServerError,
+			},
+		},
+		{
+			name: "should return 410 if url was deleted",
+			id:   "fpCk-c",
+			prepare: func(s *mocks.MockService) {
+				s.EXPECT().
+					Expand(gomock.Any(), "fpCk-c").
+					Return(nil, storage.ErrURLDeleted)
+			},
+			want: want{
+				statusCode: http.StatusGone,
+			},
+		},
+		{
+			name: "should return 403 if url was disabled with a wrapped error",
+			id:   "fpCk-c",
+			prepare: func(s *mocks.MockService) {
+				s.EXPECT().
+					Expand(gomock.Any(), "fpCk-c").
+					Return(nil, fmt.Errorf("expand: %w", storage.ErrURLDisabled))
+			},
+			want: want{
+				statusCode: http.StatusForbidden,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			service := mocks.NewMockService(ctrl)
+
+			tt.prepare(service)
+			app.srv = service
+
+			r := httptest.NewRequest(http.MethodGet, "/api/expand/"+tt.id, nil)
+			w := httptest.NewRecorder()
+
+			chiCtx := chi.NewRouteContext()
+			r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, chiCtx))
+			chiCtx.URLParams.Add("id", tt.id)
+
+			app.ExpandHandler(w, r)
+
+			assert.Equal(t, tt.want.statusCode, w.Code)
+
+			if tt.want.response != "" {
+				assert.JSONEq(t, tt.want.response, w.Body.String())
+			}
+		})
+	}
+}
+
 func Test_application_jsonHandler(t *testing.T) {
 	app := setupTestApp()
 
@@ -233,7 +380,7 @@ func Test_application_jsonHandler(t *testing.T) {
 			requestBody: `{"url": "https://ya.ru"}`,
 			prepare: func(s *mocks.MockService) {
 				s.EXPECT().
-					SaveURL(gomock.Any(), "https://ya.ru").
+					SaveURL(gomock.Any(), "https://ya.ru", models.LinkOptions{}).
 					Return(baseURL+"/fpCk-c", service.ErrAlready)
 			},
 			want: want{
@@ -247,7 +394,7 @@ func Test_application_jsonHandler(t *testing.T) {
 			requestBody: `{"url": "https://yandex.ru"}`,
 			prepare: func(s *mocks.MockService) {
 				s.EXPECT().
-					SaveURL(gomock.Any(), "https://yandex.ru").
+					SaveURL(gomock.Any(), "https://yandex.ru", models.LinkOptions{}).
 					Return(baseURL+"/FgAJzm", nil)
 			},
 			want: want{
@@ -270,7 +417,7 @@ func Test_application_jsonHandler(t *testing.T) {
 			requestBody: `{"url": "https://ya.ru"}`,
 			prepare: func(s *mocks.MockService) {
 				s.EXPECT().
-					SaveURL(gomock.Any(), "https://ya.ru").
+					SaveURL(gomock.Any(), "https://ya.ru", models.LinkOptions{}).
 					Return("", errors.New("internal error"))
 			},
 			want: want{
@@ -416,6 +563,19 @@ func Test_application_batchHandler(t *testing.T) {
 				statusCode: http.StatusInternalServerError,
 			},
 		},
+		{
+			name:        "should return forbidden if batch is too large",
+			request:     "/api/shorten/batch",
+			requestBody: `[{"correlation_id": "1", "original_url": "https://ya.ru"}]`,
+			prepare: func(s *mocks.MockService) {
+				s.EXPECT().
+					SaveBatch(gomock.Any(), gomock.Any()).
+					Return(nil, &service.QuotaError{Quota: service.QuotaBatchSize, Limit: 0, Requested: 1})
+			},
+			want: want{
+				statusCode: http.StatusForbidden,
+			},
+		},
 		{
 			name:        "should return unmarshal error",
 			request:     "/api/shorten/batch",
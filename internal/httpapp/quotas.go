@@ -0,0 +1,66 @@
+package httpapp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	"github.com/PrahaTurbo/url-shortener/internal/service"
+)
+
+// GetQuotaHandler is an HTTP handler that retrieves the quota applied to the user from the request parameters.
+// It responds with status codes to indicate success (200), or server errors (500).
+//
+// On success, it returns the quota in the JSON response.
+func (a *Application) GetQuotaHandler(w http.ResponseWriter, r *http.Request) {
+	resp, err := a.srv.GetUserQuota(r.Context(), chi.URLParam(r, "userID"))
+	if err != nil {
+		a.logger.Error("cannot get quota", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	a.writeJSON(w, http.StatusOK, resp)
+}
+
+// SetQuotaHandler is an HTTP handler that overrides the default quota for the user from the request parameters.
+// It responds with status codes to indicate success (204), invalid limits (400),
+// or server errors (500).
+func (a *Application) SetQuotaHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.Quota
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.logger.Debug("cannot unmarshal request", zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err := a.srv.SetUserQuota(r.Context(), chi.URLParam(r, "userID"), req)
+	switch {
+	case errors.Is(err, service.ErrInvalidQuota):
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	case err != nil:
+		a.logger.Error("cannot set quota", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResetQuotaHandler is an HTTP handler that removes the override of the quota for the user
+// from the request parameters, so the default quota applies again.
+// It responds with status codes to indicate success (204), or server errors (500).
+func (a *Application) ResetQuotaHandler(w http.ResponseWriter, r *http.Request) {
+	if err := a.srv.ResetUserQuota(r.Context(), chi.URLParam(r, "userID")); err != nil {
+		a.logger.Error("cannot reset quota", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,195 @@
+package httpapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/PrahaTurbo/url-shortener/internal/mocks"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	"github.com/PrahaTurbo/url-shortener/internal/service"
+)
+
+func withUserIDParam(r *http.Request, userID string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("userID", userID)
+
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestGetQuotaHandler(t *testing.T) {
+	type want struct {
+		statusCode int
+		response   string
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(s *mocks.MockService)
+		want    want
+	}{
+		{
+			name: "should get quota successfully",
+			prepare: func(s *mocks.MockService) {
+				s.EXPECT().
+					GetUserQuota(gomock.Any(), "1").
+					Return(&models.Quota{MaxLinks: 10, MaxBatchSize: 5, MaxURLLength: 100}, nil)
+			},
+			want: want{
+				statusCode: http.StatusOK,
+				response:   `{"max_links": 10, "max_batch_size": 5, "max_url_length": 100}`,
+			},
+		},
+		{
+			name: "should return error if GetUserQuota fails",
+			prepare: func(s *mocks.MockService) {
+				s.EXPECT().
+					GetUserQuota(gomock.Any(), "1").
+					Return(nil, errors.New("internal error"))
+			},
+			want: want{
+				statusCode: http.StatusInternalServerError,
+			},
+		},
+	}
+
+	app := setupTestApp()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			service := mocks.NewMockService(ctrl)
+
+			tt.prepare(service)
+			app.srv = service
+
+			request := withUserIDParam(httptest.NewRequest(http.MethodGet, "/api/admin/users/1/quota", nil), "1")
+			w := httptest.NewRecorder()
+
+			app.GetQuotaHandler(w, request)
+
+			assert.Equal(t, tt.want.statusCode, w.Code)
+
+			if tt.want.response != "" {
+				assert.JSONEq(t, tt.want.response, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestSetQuotaHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		requestBody string
+		prepare     func(s *mocks.MockService)
+		want        int
+	}{
+		{
+			name:        "should set quota successfully",
+			requestBody: `{"max_links": 10, "max_batch_size": 5, "max_url_length": 100}`,
+			prepare: func(s *mocks.MockService) {
+				s.EXPECT().
+					SetUserQuota(gomock.Any(), "1", models.Quota{MaxLinks: 10, MaxBatchSize: 5, MaxURLLength: 100}).
+					Return(nil)
+			},
+			want: http.StatusNoContent,
+		},
+		{
+			name:        "should return bad request on negative limits",
+			requestBody: `{"max_links": -1}`,
+			prepare: func(s *mocks.MockService) {
+				s.EXPECT().
+					SetUserQuota(gomock.Any(), "1", models.Quota{MaxLinks: -1}).
+					Return(service.ErrInvalidQuota)
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name:        "should return bad request on invalid json",
+			requestBody: `{"max_links": "a lot"}`,
+			prepare:     func(s *mocks.MockService) {},
+			want:        http.StatusBadRequest,
+		},
+		{
+			name:        "should return error if SetUserQuota fails",
+			requestBody: `{"max_links": 10}`,
+			prepare: func(s *mocks.MockService) {
+				s.EXPECT().
+					SetUserQuota(gomock.Any(), "1", gomock.Any()).
+					Return(errors.New("internal error"))
+			},
+			want: http.StatusInternalServerError,
+		},
+	}
+
+	app := setupTestApp()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			service := mocks.NewMockService(ctrl)
+
+			tt.prepare(service)
+			app.srv = service
+
+			reader := strings.NewReader(tt.requestBody)
+			request := withUserIDParam(httptest.NewRequest(http.MethodPut, "/api/admin/users/1/quota", reader), "1")
+			w := httptest.NewRecorder()
+
+			app.SetQuotaHandler(w, request)
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestResetQuotaHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(s *mocks.MockService)
+		want    int
+	}{
+		{
+			name: "should reset quota successfully",
+			prepare: func(s *mocks.MockService) {
+				s.EXPECT().
+					ResetUserQuota(gomock.Any(), "1").
+					Return(nil)
+			},
+			want: http.StatusNoContent,
+		},
+		{
+			name: "should return error if ResetUserQuota fails",
+			prepare: func(s *mocks.MockService) {
+				s.EXPECT().
+					ResetUserQuota(gomock.Any(), "1").
+					Return(errors.New("internal error"))
+			},
+			want: http.StatusInternalServerError,
+		},
+	}
+
+	app := setupTestApp()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			service := mocks.NewMockService(ctrl)
+
+			tt.prepare(service)
+			app.srv = service
+
+			request := withUserIDParam(httptest.NewRequest(http.MethodDelete, "/api/admin/users/1/quota", nil), "1")
+			w := httptest.NewRecorder()
+
+			app.ResetQuotaHandler(w, request)
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
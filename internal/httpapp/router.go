@@ -4,15 +4,24 @@ import (
 	"github.com/go-chi/chi/v5"
 	libmiddleware "github.com/go-chi/chi/v5/middleware"
 
+	"github.com/PrahaTurbo/url-shortener/internal/audit"
+	"github.com/PrahaTurbo/url-shortener/internal/auth"
 	appmiddleware "github.com/PrahaTurbo/url-shortener/internal/middleware"
 )
 
 // Router is a receiver method on the Application struct that initializes and returns a new chi Router.
-// It sets up middleware functions for logging, authentication, compression and decompression.
+// It sets up middleware functions for client IP resolution, request IDs and the audit log, logging, authentication,
+// API key scopes, user roles, rate limiting, compression and decompression. Anonymous sessions are only started
+// on the routes creating something for the user, so the redirects don't store a session each.
 // It also maps HTTP methods (GET, POST, DELETE) and routes to the appropriate handler functions.
 func (a *Application) Router() chi.Router {
 	r := chi.NewRouter()
 
+	if a.clientIP != nil {
+		r.Use(a.clientIP.Middleware)
+	}
+
+	r.Use(audit.Middleware)
 	r.Use(a.logger.RequestLogger)
 	r.Use(libmiddleware.Compress(5, "application/json", "text/html"))
 	r.Use(appmiddleware.Decompress)
@@ -20,19 +29,99 @@ func (a *Application) Router() chi.Router {
 	r.Group(func(r chi.Router) {
 		r.Use(a.auth.BasicMiddlewareHTTP)
 
-		r.Post("/", a.MakeURLHandler)
-		r.Get("/{id}", a.GetOriginHandler)
-		r.Post("/api/shorten", a.JSONHandler)
-		r.Post("/api/shorten/batch", a.BatchHandler)
-		r.Get("/api/user/urls", a.GetUserURLsHandler)
-		r.Delete("/api/user/urls", a.DeleteURLsHandler)
+		read := a.auth.RequireScope(auth.ScopeRead)
+		write := a.auth.RequireScope(auth.ScopeWrite)
+		remove := a.auth.RequireScope(auth.ScopeDelete)
+		session := a.auth.SessionMiddlewareHTTP
+
+		r.With(write, a.limits.Create.Middleware, session).Post("/", a.MakeURLHandler)
+		r.With(a.limits.Redirect.Middleware).Get("/{id}", a.GetOriginHandler)
+		r.With(write, a.limits.Create.Middleware, session).Post("/api/shorten", a.JSONHandler)
+		r.With(write, a.limits.Batch.Middleware, session).Post("/api/shorten/batch", a.BatchHandler)
+		r.With(read).Get("/api/expand/{id}", a.ExpandHandler)
+		r.With(read).Get("/api/user/urls", a.GetUserURLsHandler)
+		r.With(remove).Delete("/api/user/urls", a.DeleteURLsHandler)
 		r.Get("/ping", a.PingHandler)
+
+		if a.webhooks != nil {
+			r.With(write, session).Post("/api/user/webhooks", a.CreateWebhookHandler)
+			r.With(read).Get("/api/user/webhooks", a.GetWebhooksHandler)
+			r.With(remove).Delete("/api/user/webhooks/{id}", a.DeleteWebhookHandler)
+			r.With(read).Get("/api/user/webhooks/{id}/deliveries", a.GetWebhookDeliveriesHandler)
+		}
+
+		if a.accounts != nil {
+			r.Group(func(r chi.Router) {
+				r.Use(a.auth.RejectAPIKeysHTTP)
+
+				r.Post("/api/user/register", a.RegisterHandler)
+				r.Post("/api/user/login", a.LoginHandler)
+			})
+		}
+
+		if a.apiKeys != nil {
+			r.Group(func(r chi.Router) {
+				r.Use(a.auth.RejectAPIKeysHTTP)
+
+				r.With(session).Post("/api/user/keys", a.CreateAPIKeyHandler)
+				r.Get("/api/user/keys", a.GetAPIKeysHandler)
+				r.Delete("/api/user/keys/{id}", a.RevokeAPIKeyHandler)
+			})
+		}
+
+		if a.workspaces != nil {
+			r.Group(func(r chi.Router) {
+				r.Use(a.auth.RejectAPIKeysHTTP)
+
+				r.With(session).Post("/api/user/workspaces", a.CreateWorkspaceHandler)
+				r.Get("/api/user/workspaces", a.GetWorkspacesHandler)
+				r.Get("/api/user/workspaces/{id}/members", a.GetWorkspaceMembersHandler)
+				r.Put("/api/user/workspaces/{id}/members/{userID}", a.SetWorkspaceMemberHandler)
+				r.Delete("/api/user/workspaces/{id}/members/{userID}", a.RemoveWorkspaceMemberHandler)
+				r.Post("/api/user/workspaces/{id}/urls", a.AddWorkspaceURLsHandler)
+			})
+		}
+
+		if a.admin != nil {
+			r.Group(func(r chi.Router) {
+				r.Use(a.auth.RejectAPIKeysHTTP)
+
+				moderator := a.auth.RequireRole(auth.RoleModerator)
+				admin := a.auth.RequireRole(auth.RoleAdmin)
+
+				r.With(moderator).Get("/api/admin/users/{userID}/urls", a.AdminUserURLsHandler)
+				r.With(moderator).Post("/api/admin/urls/{id}/disable", a.DisableURLHandler)
+				r.With(moderator).Post("/api/admin/urls/{id}/enable", a.EnableURLHandler)
+				r.With(admin).Post("/api/admin/urls/{id}/owner", a.ReassignURLHandler)
+				r.With(admin).Put("/api/admin/users/{userID}/role", a.SetUserRoleHandler)
+			})
+		}
+
+		r.Group(func(r chi.Router) {
+			r.Use(a.auth.RejectAPIKeysHTTP, a.auth.RequireRole(auth.RoleAdmin))
+
+			r.Get("/api/admin/users/{userID}/quota", a.GetQuotaHandler)
+			r.Put("/api/admin/users/{userID}/quota", a.SetQuotaHandler)
+			r.Delete("/api/admin/users/{userID}/quota", a.ResetQuotaHandler)
+
+			if a.audit != nil {
+				r.Get("/api/admin/audit", a.AuditLogHandler)
+			}
+		})
 	})
 
+	r.Get("/.well-known/jwks.json", a.JWKSHandler)
+	r.Post("/api/user/token/refresh", a.RefreshTokenHandler)
+	r.Post("/api/user/logout", a.LogoutHandler)
+
 	r.Group(func(r chi.Router) {
 		r.Use(a.auth.AdminMiddlewareHTTP)
 
 		r.Get("/api/internal/stats", a.StatsHandler)
+
+		if a.admin != nil {
+			r.Put("/api/internal/users/{userID}/role", a.SetUserRoleHandler)
+		}
 	})
 
 	return r
@@ -0,0 +1,97 @@
+package httpapp
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/PrahaTurbo/url-shortener/internal/auth"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+)
+
+// RefreshTokenHandler is an HTTP handler that renews the session with the refresh token from the JSON
+// in request body, or from the refresh cookie if the body is empty.
+// It responds with status codes to indicate success (200), a malformed request (400),
+// an invalid, expired or revoked refresh token (401), or server errors (500).
+//
+// On success, it sets the auth cookies of the new session and returns its tokens in the JSON response.
+// The refresh token is rotated and can't be used again.
+func (a *Application) RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	refreshToken, ok := a.refreshTokenFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	session, err := a.auth.Refresh(r.Context(), refreshToken)
+	switch {
+	case errors.Is(err, auth.ErrInvalidRefreshToken):
+		a.auth.ClearSessionCookies(w)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	case err != nil:
+		a.logger.Error("cannot refresh session", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	a.auth.SetSessionCookies(w, session)
+
+	a.writeJSON(w, http.StatusOK, models.TokenResponse{
+		UserID:       session.UserID,
+		Token:        session.AccessToken,
+		RefreshToken: session.RefreshToken,
+		ExpiresAt:    session.ExpiresAt,
+	})
+}
+
+// LogoutHandler is an HTTP handler that revokes the refresh token from the JSON in request body,
+// or from the refresh cookie if the body is empty, and removes the auth cookies.
+// It responds with status codes to indicate success (204), a malformed request (400),
+// or server errors (500).
+//
+// The access token of the session stays valid until it expires.
+func (a *Application) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	refreshToken, ok := a.refreshTokenFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := a.auth.RevokeSession(r.Context(), refreshToken); err != nil {
+		a.logger.Error("cannot revoke session", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	a.auth.ClearSessionCookies(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *Application) refreshTokenFromRequest(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var req models.RefreshRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		a.logger.Debug("cannot unmarshal request", zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return "", false
+	}
+
+	if req.RefreshToken != "" {
+		return req.RefreshToken, true
+	}
+
+	refreshToken, _ := auth.RefreshTokenFromRequest(r)
+
+	return refreshToken, true
+}
+
+// JWKSHandler is an HTTP handler that serves the public keys the tokens issued by the shortener
+// can be verified with, in the JWKS format. It always responds with status code 200, and
+// an empty key set if tokens are signed with HMAC keys only.
+func (a *Application) JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	a.writeJSON(w, http.StatusOK, a.auth.JWKS())
+}
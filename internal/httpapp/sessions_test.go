@@ -0,0 +1,137 @@
+package httpapp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/PrahaTurbo/url-shortener/internal/auth"
+	"github.com/PrahaTurbo/url-shortener/internal/mocks"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/memory"
+)
+
+func TestRefreshTokenHandler(t *testing.T) {
+	app := setupTestApp()
+	app.auth = auth.NewAuth("secret", "", auth.WithRefreshTokens(memory.NewRefreshTokenStorage()))
+
+	tests := []struct {
+		name        string
+		requestBody func(refreshToken string) string
+		cookie      bool
+		want        int
+	}{
+		{
+			name:        "should refresh with token from body",
+			requestBody: func(refreshToken string) string { return `{"refresh_token": "` + refreshToken + `"}` },
+			want:        http.StatusOK,
+		},
+		{
+			name:        "should refresh with token from cookie",
+			requestBody: func(string) string { return "" },
+			cookie:      true,
+			want:        http.StatusOK,
+		},
+		{
+			name:        "should return 401 if token is unknown",
+			requestBody: func(string) string { return `{"refresh_token": "rt_unknown"}` },
+			want:        http.StatusUnauthorized,
+		},
+		{
+			name:        "should return 401 without token",
+			requestBody: func(string) string { return "" },
+			want:        http.StatusUnauthorized,
+		},
+		{
+			name:        "should return 400 if cannot unmarshal",
+			requestBody: func(string) string { return `"refresh_token"` },
+			want:        http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session, err := app.auth.IssueSession(context.Background(), "user-1")
+			assert.NoError(t, err)
+
+			r := httptest.NewRequest(http.MethodPost, "/api/user/token/refresh", strings.NewReader(tt.requestBody(session.RefreshToken)))
+			if tt.cookie {
+				r.AddCookie(&http.Cookie{Name: "refresh_token", Value: session.RefreshToken})
+			}
+			w := httptest.NewRecorder()
+
+			app.RefreshTokenHandler(w, r)
+
+			assert.Equal(t, tt.want, w.Code)
+
+			if tt.want == http.StatusOK {
+				assert.Contains(t, w.Body.String(), `"user_id":"user-1"`)
+				assert.NotContains(t, w.Body.String(), session.RefreshToken)
+			}
+		})
+	}
+}
+
+func TestLogoutHandler(t *testing.T) {
+	app := setupTestApp()
+	app.auth = auth.NewAuth("secret", "", auth.WithRefreshTokens(memory.NewRefreshTokenStorage()))
+
+	session, err := app.auth.IssueSession(context.Background(), "user-1")
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/user/logout", nil)
+	r.AddCookie(&http.Cookie{Name: "refresh_token", Value: session.RefreshToken})
+	w := httptest.NewRecorder()
+
+	app.LogoutHandler(w, r)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Len(t, w.Result().Cookies(), 2)
+
+	_, err = app.auth.Refresh(context.Background(), session.RefreshToken)
+	assert.ErrorIs(t, err, auth.ErrInvalidRefreshToken)
+}
+
+func TestJWKSHandler(t *testing.T) {
+	app := setupTestApp()
+	app.auth = auth.NewAuth("secret", "")
+
+	r := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	w := httptest.NewRecorder()
+
+	app.JWKSHandler(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"keys": []}`, w.Body.String())
+}
+
+func TestRouter_anonymousSessions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	srv := mocks.NewMockService(ctrl)
+	srv.EXPECT().GetURL(gomock.Any(), "fpCk-c").Return("https://ya.ru", nil)
+	srv.EXPECT().SaveURL(gomock.Any(), "https://ya.ru", models.LinkOptions{}).Return("http://localhost/fpCk-c", nil)
+
+	app := setupTestApp()
+	app.srv = srv
+	app.auth = auth.NewAuth("secret", "", auth.WithRefreshTokens(memory.NewRefreshTokenStorage()))
+	router := app.Router()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fpCk-c", nil))
+
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	assert.Empty(t, w.Result().Cookies(), "a redirect should not start a session")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://ya.ru")))
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NotEmpty(t, w.Result().Cookies(), "saving a link should start a session")
+}
@@ -0,0 +1,127 @@
+package httpapp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/PrahaTurbo/url-shortener/internal/auth"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	"github.com/PrahaTurbo/url-shortener/internal/webhook"
+)
+
+// CreateWebhookHandler is an HTTP handler that registers a webhook endpoint for the user.
+// It responds with status codes to indicate success (201), an invalid url or events (400),
+// or server errors (500).
+//
+// On success, it returns the webhook together with its signing secret in the JSON response.
+func (a *Application) CreateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var req models.WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.logger.Debug("cannot unmarshal request", zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	resp, err := a.webhooks.Register(r.Context(), userID, req)
+	switch {
+	case errors.Is(err, webhook.ErrInvalidURL), errors.Is(err, webhook.ErrInvalidEvents):
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	case err != nil:
+		a.logger.Error("cannot register webhook", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	a.writeJSON(w, http.StatusCreated, resp)
+}
+
+// GetWebhooksHandler is an HTTP handler that retrieves all the webhooks of the user.
+// It responds with status codes to indicate success (200), if no webhooks are found (204),
+// or server errors (500).
+func (a *Application) GetWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	resp, err := a.webhooks.List(r.Context(), userID)
+	if err != nil {
+		a.logger.Error("cannot get webhooks", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if len(resp) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	a.writeJSON(w, http.StatusOK, resp)
+}
+
+// DeleteWebhookHandler is an HTTP handler that removes the webhook of the user.
+// It responds with status codes to indicate success (204), an unknown webhook (404),
+// or server errors (500).
+func (a *Application) DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	err := a.webhooks.Delete(r.Context(), userID, chi.URLParam(r, "id"))
+	switch {
+	case errors.Is(err, webhook.ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case err != nil:
+		a.logger.Error("cannot delete webhook", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetWebhookDeliveriesHandler is an HTTP handler that retrieves the delivery log of the user's webhook.
+// It responds with status codes to indicate success (200), an unknown webhook (404),
+// or server errors (500).
+func (a *Application) GetWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	resp, err := a.webhooks.Deliveries(r.Context(), userID, chi.URLParam(r, "id"))
+	switch {
+	case errors.Is(err, webhook.ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		return
+	case err != nil:
+		a.logger.Error("cannot get webhook deliveries", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	a.writeJSON(w, http.StatusOK, resp)
+}
+
+func (a *Application) writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		a.logger.Debug("error encoding response", zap.Error(err))
+	}
+}
@@ -0,0 +1,258 @@
+package httpapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/PrahaTurbo/url-shortener/internal/auth"
+	"github.com/PrahaTurbo/url-shortener/internal/mocks"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	"github.com/PrahaTurbo/url-shortener/internal/webhook"
+)
+
+func TestCreateWebhookHandler(t *testing.T) {
+	app := setupTestApp()
+
+	tests := []struct {
+		name        string
+		requestBody string
+		userID      string
+		prepare     func(s *mocks.MockWebhookService)
+		want        int
+	}{
+		{
+			name:        "should register webhook successfully",
+			requestBody: `{"url": "https://cms.example.com", "events": ["link.created"]}`,
+			userID:      "1",
+			prepare: func(s *mocks.MockWebhookService) {
+				s.EXPECT().
+					Register(gomock.Any(), "1", models.WebhookRequest{URL: "https://cms.example.com", Events: []string{"link.created"}}).
+					Return(&models.WebhookResponse{ID: "abc", Secret: "whsec_1", CreatedAt: time.Now()}, nil)
+			},
+			want: http.StatusCreated,
+		},
+		{
+			name:        "should return 400 if events are invalid",
+			requestBody: `{"url": "https://cms.example.com", "events": ["link.visited"]}`,
+			userID:      "1",
+			prepare: func(s *mocks.MockWebhookService) {
+				s.EXPECT().
+					Register(gomock.Any(), "1", gomock.Any()).
+					Return(nil, webhook.ErrInvalidEvents)
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name:        "should return 400 if cannot unmarshal",
+			requestBody: `"url"`,
+			userID:      "1",
+			prepare:     func(s *mocks.MockWebhookService) {},
+			want:        http.StatusBadRequest,
+		},
+		{
+			name:        "should return 500 if register fails",
+			requestBody: `{"url": "https://cms.example.com", "events": ["link.created"]}`,
+			userID:      "1",
+			prepare: func(s *mocks.MockWebhookService) {
+				s.EXPECT().
+					Register(gomock.Any(), "1", gomock.Any()).
+					Return(nil, errors.New("internal error"))
+			},
+			want: http.StatusInternalServerError,
+		},
+		{
+			name:        "should return 401 without user",
+			requestBody: `{"url": "https://cms.example.com", "events": ["link.created"]}`,
+			prepare:     func(s *mocks.MockWebhookService) {},
+			want:        http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			webhooks := mocks.NewMockWebhookService(ctrl)
+
+			tt.prepare(webhooks)
+			app.webhooks = webhooks
+
+			r := httptest.NewRequest(http.MethodPost, "/api/user/webhooks", strings.NewReader(tt.requestBody))
+			if tt.userID != "" {
+				r = r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, tt.userID))
+			}
+			w := httptest.NewRecorder()
+
+			app.CreateWebhookHandler(w, r)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestGetWebhooksHandler(t *testing.T) {
+	app := setupTestApp()
+
+	tests := []struct {
+		name    string
+		prepare func(s *mocks.MockWebhookService)
+		want    int
+	}{
+		{
+			name: "should return webhooks successfully",
+			prepare: func(s *mocks.MockWebhookService) {
+				s.EXPECT().
+					List(gomock.Any(), "1").
+					Return([]models.WebhookResponse{{ID: "abc"}}, nil)
+			},
+			want: http.StatusOK,
+		},
+		{
+			name: "should return 204 if user doesn't have webhooks",
+			prepare: func(s *mocks.MockWebhookService) {
+				s.EXPECT().
+					List(gomock.Any(), "1").
+					Return(nil, nil)
+			},
+			want: http.StatusNoContent,
+		},
+		{
+			name: "should return 500 if list fails",
+			prepare: func(s *mocks.MockWebhookService) {
+				s.EXPECT().
+					List(gomock.Any(), "1").
+					Return(nil, errors.New("internal error"))
+			},
+			want: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			webhooks := mocks.NewMockWebhookService(ctrl)
+
+			tt.prepare(webhooks)
+			app.webhooks = webhooks
+
+			r := httptest.NewRequest(http.MethodGet, "/api/user/webhooks", nil)
+			r = r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, "1"))
+			w := httptest.NewRecorder()
+
+			app.GetWebhooksHandler(w, r)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestDeleteWebhookHandler(t *testing.T) {
+	app := setupTestApp()
+
+	tests := []struct {
+		name    string
+		prepare func(s *mocks.MockWebhookService)
+		want    int
+	}{
+		{
+			name: "should delete webhook successfully",
+			prepare: func(s *mocks.MockWebhookService) {
+				s.EXPECT().Delete(gomock.Any(), "1", "abc").Return(nil)
+			},
+			want: http.StatusNoContent,
+		},
+		{
+			name: "should return 404 if webhook not found",
+			prepare: func(s *mocks.MockWebhookService) {
+				s.EXPECT().Delete(gomock.Any(), "1", "abc").Return(webhook.ErrNotFound)
+			},
+			want: http.StatusNotFound,
+		},
+		{
+			name: "should return 500 if delete fails",
+			prepare: func(s *mocks.MockWebhookService) {
+				s.EXPECT().Delete(gomock.Any(), "1", "abc").Return(errors.New("internal error"))
+			},
+			want: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			webhooks := mocks.NewMockWebhookService(ctrl)
+
+			tt.prepare(webhooks)
+			app.webhooks = webhooks
+
+			r := httptest.NewRequest(http.MethodDelete, "/api/user/webhooks/abc", nil)
+			chiCtx := chi.NewRouteContext()
+			chiCtx.URLParams.Add("id", "abc")
+			ctx := context.WithValue(r.Context(), chi.RouteCtxKey, chiCtx)
+			r = r.WithContext(context.WithValue(ctx, auth.UserIDKey, "1"))
+			w := httptest.NewRecorder()
+
+			app.DeleteWebhookHandler(w, r)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestGetWebhookDeliveriesHandler(t *testing.T) {
+	app := setupTestApp()
+
+	tests := []struct {
+		name    string
+		prepare func(s *mocks.MockWebhookService)
+		want    int
+	}{
+		{
+			name: "should return deliveries successfully",
+			prepare: func(s *mocks.MockWebhookService) {
+				s.EXPECT().
+					Deliveries(gomock.Any(), "1", "abc").
+					Return([]models.WebhookDeliveryResponse{{ID: "d1", Delivered: true}}, nil)
+			},
+			want: http.StatusOK,
+		},
+		{
+			name: "should return 404 if webhook not found",
+			prepare: func(s *mocks.MockWebhookService) {
+				s.EXPECT().
+					Deliveries(gomock.Any(), "1", "abc").
+					Return(nil, webhook.ErrNotFound)
+			},
+			want: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			webhooks := mocks.NewMockWebhookService(ctrl)
+
+			tt.prepare(webhooks)
+			app.webhooks = webhooks
+
+			r := httptest.NewRequest(http.MethodGet, "/api/user/webhooks/abc/deliveries", nil)
+			chiCtx := chi.NewRouteContext()
+			chiCtx.URLParams.Add("id", "abc")
+			ctx := context.WithValue(r.Context(), chi.RouteCtxKey, chiCtx)
+			r = r.WithContext(context.WithValue(ctx, auth.UserIDKey, "1"))
+			w := httptest.NewRecorder()
+
+			app.GetWebhookDeliveriesHandler(w, r)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package httpapp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/PrahaTurbo/url-shortener/internal/auth"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	"github.com/PrahaTurbo/url-shortener/internal/workspace"
+)
+
+// CreateWorkspaceHandler is an HTTP handler that creates a workspace with the name from the JSON in request body.
+// The user becomes its member with all the permissions.
+// It responds with status codes to indicate success (201), an invalid name (400),
+// or server errors (500).
+//
+// On success, it returns the workspace in the JSON response.
+func (a *Application) CreateWorkspaceHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var req models.WorkspaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.logger.Debug("cannot unmarshal request", zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	resp, err := a.workspaces.Create(r.Context(), userID, req)
+	switch {
+	case errors.Is(err, workspace.ErrInvalidName):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		a.logger.Error("cannot create workspace", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	a.writeJSON(w, http.StatusCreated, resp)
+}
+
+// GetWorkspacesHandler is an HTTP handler that retrieves the workspaces the user is a member of.
+// It responds with status codes to indicate success (200), or server errors (500).
+//
+// On success, it returns the workspaces with the permissions of the user in the JSON response.
+func (a *Application) GetWorkspacesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	resp, err := a.workspaces.List(r.Context(), userID)
+	if err != nil {
+		a.logger.Error("cannot list workspaces", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	a.writeJSON(w, http.StatusOK, resp)
+}
+
+// GetWorkspaceMembersHandler is an HTTP handler that retrieves the members of the workspace.
+// It responds with status codes to indicate success (200), a missing view permission (403),
+// a workspace the user is not a member of (404), or server errors (500).
+func (a *Application) GetWorkspaceMembersHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	resp, err := a.workspaces.Members(r.Context(), userID, chi.URLParam(r, "id"))
+	if err != nil {
+		a.writeWorkspaceError(w, err, "cannot list workspace members")
+		return
+	}
+
+	a.writeJSON(w, http.StatusOK, resp)
+}
+
+// SetWorkspaceMemberHandler is an HTTP handler that adds the user from the request parameters to the workspace
+// with the permissions from the JSON in request body, or changes the permissions of the member.
+// It responds with status codes to indicate success (204), invalid permissions (400), a user without
+// all the permissions (403), a workspace the user is not a member of (404), a change that would leave
+// the workspace without a member with all the permissions (409), or server errors (500).
+func (a *Application) SetWorkspaceMemberHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var req models.WorkspaceMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.logger.Debug("cannot unmarshal request", zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err := a.workspaces.SetMember(r.Context(), userID, chi.URLParam(r, "id"), chi.URLParam(r, "userID"), req)
+	if err != nil {
+		a.writeWorkspaceError(w, err, "cannot set workspace member")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveWorkspaceMemberHandler is an HTTP handler that removes the user from the request parameters
+// from the workspace. Users can always leave a workspace themselves.
+// It responds with status codes to indicate success (204), a user without all the permissions (403),
+// a workspace the user is not a member of (404), a removal that would leave the workspace without
+// a member with all the permissions (409), or server errors (500).
+func (a *Application) RemoveWorkspaceMemberHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	err := a.workspaces.RemoveMember(r.Context(), userID, chi.URLParam(r, "id"), chi.URLParam(r, "userID"))
+	if err != nil {
+		a.writeWorkspaceError(w, err, "cannot remove workspace member")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddWorkspaceURLsHandler is an HTTP handler that moves the short URLs from the JSON in request body,
+// which the user created, to the workspace.
+// It responds with status codes to indicate success (204), an invalid body or a URL the user
+// didn't create (400), a missing edit permission (403), a workspace the user is not a member of (404),
+// or server errors (500).
+func (a *Application) AddWorkspaceURLsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var shortURLs []string
+	if err := json.NewDecoder(r.Body).Decode(&shortURLs); err != nil {
+		a.logger.Debug("cannot unmarshal request", zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err := a.workspaces.AddURLs(r.Context(), userID, chi.URLParam(r, "id"), shortURLs)
+	if err != nil {
+		a.writeWorkspaceError(w, err, "cannot add urls to workspace")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *Application) writeWorkspaceError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, workspace.ErrInvalidPermissions), errors.Is(err, workspace.ErrURLNotFound):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, workspace.ErrForbidden):
+		w.WriteHeader(http.StatusForbidden)
+	case errors.Is(err, workspace.ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, workspace.ErrLastManager):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		a.logger.Error(msg, zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
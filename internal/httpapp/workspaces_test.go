@@ -0,0 +1,236 @@
+package httpapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/PrahaTurbo/url-shortener/internal/auth"
+	"github.com/PrahaTurbo/url-shortener/internal/mocks"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	"github.com/PrahaTurbo/url-shortener/internal/workspace"
+)
+
+func withWorkspaceParams(r *http.Request, id, userID string) *http.Request {
+	chiCtx := chi.NewRouteContext()
+	chiCtx.URLParams.Add("id", id)
+	chiCtx.URLParams.Add("userID", userID)
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, chiCtx)
+
+	return r.WithContext(context.WithValue(ctx, auth.UserIDKey, "alice"))
+}
+
+func TestCreateWorkspaceHandler(t *testing.T) {
+	app := setupTestApp()
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		requestBody string
+		prepare     func(s *mocks.MockWorkspaceService)
+		want        int
+		response    string
+	}{
+		{
+			name:        "should create workspace successfully",
+			requestBody: `{"name": "team"}`,
+			prepare: func(s *mocks.MockWorkspaceService) {
+				s.EXPECT().
+					Create(gomock.Any(), "alice", models.WorkspaceRequest{Name: "team"}).
+					Return(&models.WorkspaceResponse{ID: "w1", Name: "team", Permissions: []string{"view", "edit", "delete"}, CreatedAt: createdAt}, nil)
+			},
+			want:     http.StatusCreated,
+			response: `{"id": "w1", "name": "team", "permissions": ["view", "edit", "delete"], "created_at": "2024-01-02T03:04:05Z"}`,
+		},
+		{
+			name:        "should return 400 if cannot unmarshal",
+			requestBody: `"team"`,
+			prepare:     func(s *mocks.MockWorkspaceService) {},
+			want:        http.StatusBadRequest,
+		},
+		{
+			name:        "should return 400 for invalid name",
+			requestBody: `{"name": ""}`,
+			prepare: func(s *mocks.MockWorkspaceService) {
+				s.EXPECT().Create(gomock.Any(), "alice", gomock.Any()).Return(nil, workspace.ErrInvalidName)
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name:        "should return 500 if creation fails",
+			requestBody: `{"name": "team"}`,
+			prepare: func(s *mocks.MockWorkspaceService) {
+				s.EXPECT().Create(gomock.Any(), "alice", gomock.Any()).Return(nil, errors.New("internal error"))
+			},
+			want: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			workspaces := mocks.NewMockWorkspaceService(ctrl)
+
+			tt.prepare(workspaces)
+			app.workspaces = workspaces
+
+			r := httptest.NewRequest(http.MethodPost, "/api/user/workspaces", strings.NewReader(tt.requestBody))
+			r = r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, "alice"))
+			w := httptest.NewRecorder()
+
+			app.CreateWorkspaceHandler(w, r)
+
+			assert.Equal(t, tt.want, w.Code)
+
+			if tt.response != "" {
+				assert.JSONEq(t, tt.response, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestSetWorkspaceMemberHandler(t *testing.T) {
+	app := setupTestApp()
+
+	tests := []struct {
+		name        string
+		requestBody string
+		prepare     func(s *mocks.MockWorkspaceService)
+		want        int
+	}{
+		{
+			name:        "should set member successfully",
+			requestBody: `{"permissions": ["view", "delete"]}`,
+			prepare: func(s *mocks.MockWorkspaceService) {
+				s.EXPECT().
+					SetMember(gomock.Any(), "alice", "w1", "bob", models.WorkspaceMemberRequest{Permissions: []string{"view", "delete"}}).
+					Return(nil)
+			},
+			want: http.StatusNoContent,
+		},
+		{
+			name:        "should return 400 for invalid permissions",
+			requestBody: `{"permissions": ["own"]}`,
+			prepare: func(s *mocks.MockWorkspaceService) {
+				s.EXPECT().SetMember(gomock.Any(), "alice", "w1", "bob", gomock.Any()).Return(workspace.ErrInvalidPermissions)
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name:        "should return 403 for member without all the permissions",
+			requestBody: `{"permissions": ["view"]}`,
+			prepare: func(s *mocks.MockWorkspaceService) {
+				s.EXPECT().SetMember(gomock.Any(), "alice", "w1", "bob", gomock.Any()).Return(workspace.ErrForbidden)
+			},
+			want: http.StatusForbidden,
+		},
+		{
+			name:        "should return 404 for unknown workspace",
+			requestBody: `{"permissions": ["view"]}`,
+			prepare: func(s *mocks.MockWorkspaceService) {
+				s.EXPECT().SetMember(gomock.Any(), "alice", "w1", "bob", gomock.Any()).Return(workspace.ErrNotFound)
+			},
+			want: http.StatusNotFound,
+		},
+		{
+			name:        "should return 409 for the last member with all the permissions",
+			requestBody: `{"permissions": ["view"]}`,
+			prepare: func(s *mocks.MockWorkspaceService) {
+				s.EXPECT().SetMember(gomock.Any(), "alice", "w1", "bob", gomock.Any()).Return(workspace.ErrLastManager)
+			},
+			want: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			workspaces := mocks.NewMockWorkspaceService(ctrl)
+
+			tt.prepare(workspaces)
+			app.workspaces = workspaces
+
+			r := httptest.NewRequest(http.MethodPut, "/api/user/workspaces/w1/members/bob", strings.NewReader(tt.requestBody))
+			w := httptest.NewRecorder()
+
+			app.SetWorkspaceMemberHandler(w, withWorkspaceParams(r, "w1", "bob"))
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestAddWorkspaceURLsHandler(t *testing.T) {
+	app := setupTestApp()
+
+	tests := []struct {
+		name        string
+		requestBody string
+		prepare     func(s *mocks.MockWorkspaceService)
+		want        int
+	}{
+		{
+			name:        "should add urls successfully",
+			requestBody: `["abc", "def"]`,
+			prepare: func(s *mocks.MockWorkspaceService) {
+				s.EXPECT().AddURLs(gomock.Any(), "alice", "w1", []string{"abc", "def"}).Return(nil)
+			},
+			want: http.StatusNoContent,
+		},
+		{
+			name:        "should return 400 if cannot unmarshal",
+			requestBody: `"abc"`,
+			prepare:     func(s *mocks.MockWorkspaceService) {},
+			want:        http.StatusBadRequest,
+		},
+		{
+			name:        "should return 400 for url of another user",
+			requestBody: `["abc"]`,
+			prepare: func(s *mocks.MockWorkspaceService) {
+				s.EXPECT().AddURLs(gomock.Any(), "alice", "w1", gomock.Any()).Return(workspace.ErrURLNotFound)
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name:        "should return 403 for member without the edit permission",
+			requestBody: `["abc"]`,
+			prepare: func(s *mocks.MockWorkspaceService) {
+				s.EXPECT().AddURLs(gomock.Any(), "alice", "w1", gomock.Any()).Return(workspace.ErrForbidden)
+			},
+			want: http.StatusForbidden,
+		},
+		{
+			name:        "should return 500 if adding fails",
+			requestBody: `["abc"]`,
+			prepare: func(s *mocks.MockWorkspaceService) {
+				s.EXPECT().AddURLs(gomock.Any(), "alice", "w1", gomock.Any()).Return(errors.New("internal error"))
+			},
+			want: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			workspaces := mocks.NewMockWorkspaceService(ctrl)
+
+			tt.prepare(workspaces)
+			app.workspaces = workspaces
+
+			r := httptest.NewRequest(http.MethodPost, "/api/user/workspaces/w1/urls", strings.NewReader(tt.requestBody))
+			w := httptest.NewRecorder()
+
+			app.AddWorkspaceURLsHandler(w, withWorkspaceParams(r, "w1", ""))
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"go.uber.org/zap"
+
+	"github.com/PrahaTurbo/url-shortener/internal/clientip"
 )
 
 type responseData struct {
@@ -53,7 +55,7 @@ func Initialize(level string) (*Logger, error) {
 }
 
 // RequestLogger is a middleware to log the message about request handling.
-// This includes: the URI of the request, the HTTP method, the duration of handling, the status and size of the response.
+// This includes: the URI of the request, the HTTP method, the client IP, the duration of handling, the status and size of the response.
 func (logger *Logger) RequestLogger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
@@ -72,6 +74,7 @@ func (logger *Logger) RequestLogger(next http.Handler) http.Handler {
 		logger.Info("HTTP request handled",
 			zap.String("uri", r.RequestURI),
 			zap.String("method", r.Method),
+			zap.Stringer("client ip", clientip.FromRequest(r)),
 			zap.Duration("duration", duration),
 			zap.Int("response status", responseData.status),
 			zap.Int("response size", responseData.size),
@@ -49,6 +49,21 @@ func (mr *MockServiceMockRecorder) DeleteURLs(ctx, urls interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteURLs", reflect.TypeOf((*MockService)(nil).DeleteURLs), ctx, urls)
 }
 
+// Expand mocks base method.
+func (m *MockService) Expand(ctx context.Context, shortURL string) (*models.ExpandResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Expand", ctx, shortURL)
+	ret0, _ := ret[0].(*models.ExpandResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Expand indicates an expected call of Expand.
+func (mr *MockServiceMockRecorder) Expand(ctx, shortURL interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Expand", reflect.TypeOf((*MockService)(nil).Expand), ctx, shortURL)
+}
+
 // GetStats mocks base method.
 func (m *MockService) GetStats(ctx context.Context) (*models.StatsResponse, error) {
 	m.ctrl.T.Helper()
@@ -94,6 +109,33 @@ func (mr *MockServiceMockRecorder) GetURLsByUserID(ctx interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetURLsByUserID", reflect.TypeOf((*MockService)(nil).GetURLsByUserID), ctx)
 }
 
+// GetUserQuota mocks base method.
+func (m *MockService) GetUserQuota(ctx context.Context, userID string) (*models.Quota, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserQuota", ctx, userID)
+	ret0, _ := ret[0].(*models.Quota)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserQuota indicates an expected call of GetUserQuota.
+func (mr *MockServiceMockRecorder) GetUserQuota(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserQuota", reflect.TypeOf((*MockService)(nil).GetUserQuota), ctx, userID)
+}
+
+// MaintainLinks mocks base method.
+func (m *MockService) MaintainLinks(ctx context.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "MaintainLinks", ctx)
+}
+
+// MaintainLinks indicates an expected call of MaintainLinks.
+func (mr *MockServiceMockRecorder) MaintainLinks(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MaintainLinks", reflect.TypeOf((*MockService)(nil).MaintainLinks), ctx)
+}
+
 // PingDB mocks base method.
 func (m *MockService) PingDB() error {
 	m.ctrl.T.Helper()
@@ -108,6 +150,20 @@ func (mr *MockServiceMockRecorder) PingDB() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PingDB", reflect.TypeOf((*MockService)(nil).PingDB))
 }
 
+// ResetUserQuota mocks base method.
+func (m *MockService) ResetUserQuota(ctx context.Context, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetUserQuota", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetUserQuota indicates an expected call of ResetUserQuota.
+func (mr *MockServiceMockRecorder) ResetUserQuota(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetUserQuota", reflect.TypeOf((*MockService)(nil).ResetUserQuota), ctx, userID)
+}
+
 // SaveBatch mocks base method.
 func (m *MockService) SaveBatch(ctx context.Context, batch []models.BatchRequest) ([]models.BatchResponse, error) {
 	m.ctrl.T.Helper()
@@ -124,16 +180,30 @@ func (mr *MockServiceMockRecorder) SaveBatch(ctx, batch interface{}) *gomock.Cal
 }
 
 // SaveURL mocks base method.
-func (m *MockService) SaveURL(ctx context.Context, originalURL string) (string, error) {
+func (m *MockService) SaveURL(ctx context.Context, originalURL string, opts models.LinkOptions) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveURL", ctx, originalURL)
+	ret := m.ctrl.Call(m, "SaveURL", ctx, originalURL, opts)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SaveURL indicates an expected call of SaveURL.
-func (mr *MockServiceMockRecorder) SaveURL(ctx, originalURL interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) SaveURL(ctx, originalURL, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveURL", reflect.TypeOf((*MockService)(nil).SaveURL), ctx, originalURL, opts)
+}
+
+// SetUserQuota mocks base method.
+func (m *MockService) SetUserQuota(ctx context.Context, userID string, quota models.Quota) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserQuota", ctx, userID, quota)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetUserQuota indicates an expected call of SetUserQuota.
+func (mr *MockServiceMockRecorder) SetUserQuota(ctx, userID, quota interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveURL", reflect.TypeOf((*MockService)(nil).SaveURL), ctx, originalURL)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserQuota", reflect.TypeOf((*MockService)(nil).SetUserQuota), ctx, userID, quota)
 }
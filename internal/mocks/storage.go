@@ -7,6 +7,7 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	entity "github.com/PrahaTurbo/url-shortener/internal/storage/entity"
 	gomock "go.uber.org/mock/gomock"
@@ -35,6 +36,21 @@ func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
 	return m.recorder
 }
 
+// AddClicks mocks base method.
+func (m *MockRepository) AddClicks(ctx context.Context, clicks map[string]int64) ([]entity.URLRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddClicks", ctx, clicks)
+	ret0, _ := ret[0].([]entity.URLRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddClicks indicates an expected call of AddClicks.
+func (mr *MockRepositoryMockRecorder) AddClicks(ctx, clicks interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddClicks", reflect.TypeOf((*MockRepository)(nil).AddClicks), ctx, clicks)
+}
+
 // CheckExistence mocks base method.
 func (m *MockRepository) CheckExistence(ctx context.Context, shortURL, userID string) error {
 	m.ctrl.T.Helper()
@@ -49,12 +65,28 @@ func (mr *MockRepositoryMockRecorder) CheckExistence(ctx, shortURL, userID inter
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckExistence", reflect.TypeOf((*MockRepository)(nil).CheckExistence), ctx, shortURL, userID)
 }
 
+// CountURLsByUserID mocks base method.
+func (m *MockRepository) CountURLsByUserID(ctx context.Context, userID string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountURLsByUserID", ctx, userID)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountURLsByUserID indicates an expected call of CountURLsByUserID.
+func (mr *MockRepositoryMockRecorder) CountURLsByUserID(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountURLsByUserID", reflect.TypeOf((*MockRepository)(nil).CountURLsByUserID), ctx, userID)
+}
+
 // DeleteURLBatch mocks base method.
-func (m *MockRepository) DeleteURLBatch(urls []string, user string) error {
+func (m *MockRepository) DeleteURLBatch(urls []string, user string) ([]entity.URLRecord, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "DeleteURLBatch", urls, user)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].([]entity.URLRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // DeleteURLBatch indicates an expected call of DeleteURLBatch.
@@ -63,6 +95,36 @@ func (mr *MockRepositoryMockRecorder) DeleteURLBatch(urls, user interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteURLBatch", reflect.TypeOf((*MockRepository)(nil).DeleteURLBatch), urls, user)
 }
 
+// ExpireURLs mocks base method.
+func (m *MockRepository) ExpireURLs(ctx context.Context, now time.Time) ([]entity.URLRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpireURLs", ctx, now)
+	ret0, _ := ret[0].([]entity.URLRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExpireURLs indicates an expected call of ExpireURLs.
+func (mr *MockRepositoryMockRecorder) ExpireURLs(ctx, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireURLs", reflect.TypeOf((*MockRepository)(nil).ExpireURLs), ctx, now)
+}
+
+// GetExistingURLs mocks base method.
+func (m *MockRepository) GetExistingURLs(ctx context.Context, shortURLs []string, userID string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExistingURLs", ctx, shortURLs, userID)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExistingURLs indicates an expected call of GetExistingURLs.
+func (mr *MockRepositoryMockRecorder) GetExistingURLs(ctx, shortURLs, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExistingURLs", reflect.TypeOf((*MockRepository)(nil).GetExistingURLs), ctx, shortURLs, userID)
+}
+
 // GetStats mocks base method.
 func (m *MockRepository) GetStats(ctx context.Context) (*entity.Stats, error) {
 	m.ctrl.T.Helper()
@@ -93,6 +155,21 @@ func (mr *MockRepositoryMockRecorder) GetURL(ctx, shortURL interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetURL", reflect.TypeOf((*MockRepository)(nil).GetURL), ctx, shortURL)
 }
 
+// GetURLRecord mocks base method.
+func (m *MockRepository) GetURLRecord(ctx context.Context, shortURL string) (*entity.URLRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetURLRecord", ctx, shortURL)
+	ret0, _ := ret[0].(*entity.URLRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetURLRecord indicates an expected call of GetURLRecord.
+func (mr *MockRepositoryMockRecorder) GetURLRecord(ctx, shortURL interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetURLRecord", reflect.TypeOf((*MockRepository)(nil).GetURLRecord), ctx, shortURL)
+}
+
 // GetURLsByUserID mocks base method.
 func (m *MockRepository) GetURLsByUserID(ctx context.Context, userID string) ([]entity.URLRecord, error) {
 	m.ctrl.T.Helper()
@@ -122,6 +199,48 @@ func (mr *MockRepositoryMockRecorder) Ping() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockRepository)(nil).Ping))
 }
 
+// ReassignURL mocks base method.
+func (m *MockRepository) ReassignURL(ctx context.Context, shortURL, fromUserID, toUserID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReassignURL", ctx, shortURL, fromUserID, toUserID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReassignURL indicates an expected call of ReassignURL.
+func (mr *MockRepositoryMockRecorder) ReassignURL(ctx, shortURL, fromUserID, toUserID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReassignURL", reflect.TypeOf((*MockRepository)(nil).ReassignURL), ctx, shortURL, fromUserID, toUserID)
+}
+
+// ReassignURLs mocks base method.
+func (m *MockRepository) ReassignURLs(ctx context.Context, fromUserID, toUserID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReassignURLs", ctx, fromUserID, toUserID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReassignURLs indicates an expected call of ReassignURLs.
+func (mr *MockRepositoryMockRecorder) ReassignURLs(ctx, fromUserID, toUserID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReassignURLs", reflect.TypeOf((*MockRepository)(nil).ReassignURLs), ctx, fromUserID, toUserID)
+}
+
+// SavePreview mocks base method.
+func (m *MockRepository) SavePreview(ctx context.Context, shortURL string, preview entity.Preview) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SavePreview", ctx, shortURL, preview)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SavePreview indicates an expected call of SavePreview.
+func (mr *MockRepositoryMockRecorder) SavePreview(ctx, shortURL, preview interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SavePreview", reflect.TypeOf((*MockRepository)(nil).SavePreview), ctx, shortURL, preview)
+}
+
 // SaveURL mocks base method.
 func (m *MockRepository) SaveURL(ctx context.Context, url entity.URLRecord) error {
 	m.ctrl.T.Helper()
@@ -149,3 +268,731 @@ func (mr *MockRepositoryMockRecorder) SaveURLBatch(ctx, urls interface{}) *gomoc
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveURLBatch", reflect.TypeOf((*MockRepository)(nil).SaveURLBatch), ctx, urls)
 }
+
+// SetURLDisabled mocks base method.
+func (m *MockRepository) SetURLDisabled(ctx context.Context, shortURL string, disabled bool) ([]entity.URLRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetURLDisabled", ctx, shortURL, disabled)
+	ret0, _ := ret[0].([]entity.URLRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetURLDisabled indicates an expected call of SetURLDisabled.
+func (mr *MockRepositoryMockRecorder) SetURLDisabled(ctx, shortURL, disabled interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetURLDisabled", reflect.TypeOf((*MockRepository)(nil).SetURLDisabled), ctx, shortURL, disabled)
+}
+
+// SetURLWorkspace mocks base method.
+func (m *MockRepository) SetURLWorkspace(ctx context.Context, shortURL, userID, workspaceID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetURLWorkspace", ctx, shortURL, userID, workspaceID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetURLWorkspace indicates an expected call of SetURLWorkspace.
+func (mr *MockRepositoryMockRecorder) SetURLWorkspace(ctx, shortURL, userID, workspaceID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetURLWorkspace", reflect.TypeOf((*MockRepository)(nil).SetURLWorkspace), ctx, shortURL, userID, workspaceID)
+}
+
+// MockURLStore is a mock of URLStore interface.
+type MockURLStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockURLStoreMockRecorder
+}
+
+// MockURLStoreMockRecorder is the mock recorder for MockURLStore.
+type MockURLStoreMockRecorder struct {
+	mock *MockURLStore
+}
+
+// NewMockURLStore creates a new mock instance.
+func NewMockURLStore(ctrl *gomock.Controller) *MockURLStore {
+	mock := &MockURLStore{ctrl: ctrl}
+	mock.recorder = &MockURLStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockURLStore) EXPECT() *MockURLStoreMockRecorder {
+	return m.recorder
+}
+
+// ImportURLs mocks base method.
+func (m *MockURLStore) ImportURLs(ctx context.Context, records []entity.URLRecord) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportURLs", ctx, records)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ImportURLs indicates an expected call of ImportURLs.
+func (mr *MockURLStoreMockRecorder) ImportURLs(ctx, records interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportURLs", reflect.TypeOf((*MockURLStore)(nil).ImportURLs), ctx, records)
+}
+
+// RemoveURLs mocks base method.
+func (m *MockURLStore) RemoveURLs(ctx context.Context, uuids []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveURLs", ctx, uuids)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveURLs indicates an expected call of RemoveURLs.
+func (mr *MockURLStoreMockRecorder) RemoveURLs(ctx, uuids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveURLs", reflect.TypeOf((*MockURLStore)(nil).RemoveURLs), ctx, uuids)
+}
+
+// ScanURLs mocks base method.
+func (m *MockURLStore) ScanURLs(ctx context.Context, afterUUID string, limit int) ([]entity.URLRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ScanURLs", ctx, afterUUID, limit)
+	ret0, _ := ret[0].([]entity.URLRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ScanURLs indicates an expected call of ScanURLs.
+func (mr *MockURLStoreMockRecorder) ScanURLs(ctx, afterUUID, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScanURLs", reflect.TypeOf((*MockURLStore)(nil).ScanURLs), ctx, afterUUID, limit)
+}
+
+// MockUserLister is a mock of UserLister interface.
+type MockUserLister struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserListerMockRecorder
+}
+
+// MockUserListerMockRecorder is the mock recorder for MockUserLister.
+type MockUserListerMockRecorder struct {
+	mock *MockUserLister
+}
+
+// NewMockUserLister creates a new mock instance.
+func NewMockUserLister(ctrl *gomock.Controller) *MockUserLister {
+	mock := &MockUserLister{ctrl: ctrl}
+	mock.recorder = &MockUserListerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserLister) EXPECT() *MockUserListerMockRecorder {
+	return m.recorder
+}
+
+// GetUserIDs mocks base method.
+func (m *MockUserLister) GetUserIDs(ctx context.Context) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserIDs", ctx)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserIDs indicates an expected call of GetUserIDs.
+func (mr *MockUserListerMockRecorder) GetUserIDs(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserIDs", reflect.TypeOf((*MockUserLister)(nil).GetUserIDs), ctx)
+}
+
+// MockWebhookRepository is a mock of WebhookRepository interface.
+type MockWebhookRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockWebhookRepositoryMockRecorder
+}
+
+// MockWebhookRepositoryMockRecorder is the mock recorder for MockWebhookRepository.
+type MockWebhookRepositoryMockRecorder struct {
+	mock *MockWebhookRepository
+}
+
+// NewMockWebhookRepository creates a new mock instance.
+func NewMockWebhookRepository(ctrl *gomock.Controller) *MockWebhookRepository {
+	mock := &MockWebhookRepository{ctrl: ctrl}
+	mock.recorder = &MockWebhookRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWebhookRepository) EXPECT() *MockWebhookRepositoryMockRecorder {
+	return m.recorder
+}
+
+// DeleteWebhook mocks base method.
+func (m *MockWebhookRepository) DeleteWebhook(ctx context.Context, id, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWebhook", ctx, id, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteWebhook indicates an expected call of DeleteWebhook.
+func (mr *MockWebhookRepositoryMockRecorder) DeleteWebhook(ctx, id, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebhook", reflect.TypeOf((*MockWebhookRepository)(nil).DeleteWebhook), ctx, id, userID)
+}
+
+// GetDeliveries mocks base method.
+func (m *MockWebhookRepository) GetDeliveries(ctx context.Context, webhookID string, limit int) ([]entity.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeliveries", ctx, webhookID, limit)
+	ret0, _ := ret[0].([]entity.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeliveries indicates an expected call of GetDeliveries.
+func (mr *MockWebhookRepositoryMockRecorder) GetDeliveries(ctx, webhookID, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeliveries", reflect.TypeOf((*MockWebhookRepository)(nil).GetDeliveries), ctx, webhookID, limit)
+}
+
+// GetWebhooksByUserID mocks base method.
+func (m *MockWebhookRepository) GetWebhooksByUserID(ctx context.Context, userID string) ([]entity.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWebhooksByUserID", ctx, userID)
+	ret0, _ := ret[0].([]entity.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWebhooksByUserID indicates an expected call of GetWebhooksByUserID.
+func (mr *MockWebhookRepositoryMockRecorder) GetWebhooksByUserID(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhooksByUserID", reflect.TypeOf((*MockWebhookRepository)(nil).GetWebhooksByUserID), ctx, userID)
+}
+
+// SaveDelivery mocks base method.
+func (m *MockWebhookRepository) SaveDelivery(ctx context.Context, delivery entity.WebhookDelivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDelivery", ctx, delivery)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveDelivery indicates an expected call of SaveDelivery.
+func (mr *MockWebhookRepositoryMockRecorder) SaveDelivery(ctx, delivery interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDelivery", reflect.TypeOf((*MockWebhookRepository)(nil).SaveDelivery), ctx, delivery)
+}
+
+// SaveWebhook mocks base method.
+func (m *MockWebhookRepository) SaveWebhook(ctx context.Context, webhook entity.Webhook) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveWebhook", ctx, webhook)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveWebhook indicates an expected call of SaveWebhook.
+func (mr *MockWebhookRepositoryMockRecorder) SaveWebhook(ctx, webhook interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveWebhook", reflect.TypeOf((*MockWebhookRepository)(nil).SaveWebhook), ctx, webhook)
+}
+
+// MockQuotaRepository is a mock of QuotaRepository interface.
+type MockQuotaRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockQuotaRepositoryMockRecorder
+}
+
+// MockQuotaRepositoryMockRecorder is the mock recorder for MockQuotaRepository.
+type MockQuotaRepositoryMockRecorder struct {
+	mock *MockQuotaRepository
+}
+
+// NewMockQuotaRepository creates a new mock instance.
+func NewMockQuotaRepository(ctrl *gomock.Controller) *MockQuotaRepository {
+	mock := &MockQuotaRepository{ctrl: ctrl}
+	mock.recorder = &MockQuotaRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockQuotaRepository) EXPECT() *MockQuotaRepositoryMockRecorder {
+	return m.recorder
+}
+
+// DeleteQuota mocks base method.
+func (m *MockQuotaRepository) DeleteQuota(ctx context.Context, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteQuota", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteQuota indicates an expected call of DeleteQuota.
+func (mr *MockQuotaRepositoryMockRecorder) DeleteQuota(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteQuota", reflect.TypeOf((*MockQuotaRepository)(nil).DeleteQuota), ctx, userID)
+}
+
+// GetQuota mocks base method.
+func (m *MockQuotaRepository) GetQuota(ctx context.Context, userID string) (*entity.Quota, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetQuota", ctx, userID)
+	ret0, _ := ret[0].(*entity.Quota)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetQuota indicates an expected call of GetQuota.
+func (mr *MockQuotaRepositoryMockRecorder) GetQuota(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetQuota", reflect.TypeOf((*MockQuotaRepository)(nil).GetQuota), ctx, userID)
+}
+
+// SaveQuota mocks base method.
+func (m *MockQuotaRepository) SaveQuota(ctx context.Context, quota entity.Quota) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveQuota", ctx, quota)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveQuota indicates an expected call of SaveQuota.
+func (mr *MockQuotaRepositoryMockRecorder) SaveQuota(ctx, quota interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveQuota", reflect.TypeOf((*MockQuotaRepository)(nil).SaveQuota), ctx, quota)
+}
+
+// MockAPIKeyRepository is a mock of APIKeyRepository interface.
+type MockAPIKeyRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAPIKeyRepositoryMockRecorder
+}
+
+// MockAPIKeyRepositoryMockRecorder is the mock recorder for MockAPIKeyRepository.
+type MockAPIKeyRepositoryMockRecorder struct {
+	mock *MockAPIKeyRepository
+}
+
+// NewMockAPIKeyRepository creates a new mock instance.
+func NewMockAPIKeyRepository(ctrl *gomock.Controller) *MockAPIKeyRepository {
+	mock := &MockAPIKeyRepository{ctrl: ctrl}
+	mock.recorder = &MockAPIKeyRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAPIKeyRepository) EXPECT() *MockAPIKeyRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetAPIKeyByHash mocks base method.
+func (m *MockAPIKeyRepository) GetAPIKeyByHash(ctx context.Context, hash string) (*entity.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAPIKeyByHash", ctx, hash)
+	ret0, _ := ret[0].(*entity.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAPIKeyByHash indicates an expected call of GetAPIKeyByHash.
+func (mr *MockAPIKeyRepositoryMockRecorder) GetAPIKeyByHash(ctx, hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIKeyByHash", reflect.TypeOf((*MockAPIKeyRepository)(nil).GetAPIKeyByHash), ctx, hash)
+}
+
+// GetAPIKeysByUserID mocks base method.
+func (m *MockAPIKeyRepository) GetAPIKeysByUserID(ctx context.Context, userID string) ([]entity.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAPIKeysByUserID", ctx, userID)
+	ret0, _ := ret[0].([]entity.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAPIKeysByUserID indicates an expected call of GetAPIKeysByUserID.
+func (mr *MockAPIKeyRepositoryMockRecorder) GetAPIKeysByUserID(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIKeysByUserID", reflect.TypeOf((*MockAPIKeyRepository)(nil).GetAPIKeysByUserID), ctx, userID)
+}
+
+// RevokeAPIKey mocks base method.
+func (m *MockAPIKeyRepository) RevokeAPIKey(ctx context.Context, id, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAPIKey", ctx, id, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAPIKey indicates an expected call of RevokeAPIKey.
+func (mr *MockAPIKeyRepositoryMockRecorder) RevokeAPIKey(ctx, id, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAPIKey", reflect.TypeOf((*MockAPIKeyRepository)(nil).RevokeAPIKey), ctx, id, userID)
+}
+
+// SaveAPIKey mocks base method.
+func (m *MockAPIKeyRepository) SaveAPIKey(ctx context.Context, key entity.APIKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveAPIKey", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveAPIKey indicates an expected call of SaveAPIKey.
+func (mr *MockAPIKeyRepositoryMockRecorder) SaveAPIKey(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAPIKey", reflect.TypeOf((*MockAPIKeyRepository)(nil).SaveAPIKey), ctx, key)
+}
+
+// MockUserRepository is a mock of UserRepository interface.
+type MockUserRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserRepositoryMockRecorder
+}
+
+// MockUserRepositoryMockRecorder is the mock recorder for MockUserRepository.
+type MockUserRepositoryMockRecorder struct {
+	mock *MockUserRepository
+}
+
+// NewMockUserRepository creates a new mock instance.
+func NewMockUserRepository(ctrl *gomock.Controller) *MockUserRepository {
+	mock := &MockUserRepository{ctrl: ctrl}
+	mock.recorder = &MockUserRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserRepository) EXPECT() *MockUserRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetUserByID mocks base method.
+func (m *MockUserRepository) GetUserByID(ctx context.Context, id string) (*entity.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByID", ctx, id)
+	ret0, _ := ret[0].(*entity.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByID indicates an expected call of GetUserByID.
+func (mr *MockUserRepositoryMockRecorder) GetUserByID(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByID", reflect.TypeOf((*MockUserRepository)(nil).GetUserByID), ctx, id)
+}
+
+// GetUserByUsername mocks base method.
+func (m *MockUserRepository) GetUserByUsername(ctx context.Context, username string) (*entity.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByUsername", ctx, username)
+	ret0, _ := ret[0].(*entity.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByUsername indicates an expected call of GetUserByUsername.
+func (mr *MockUserRepositoryMockRecorder) GetUserByUsername(ctx, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByUsername", reflect.TypeOf((*MockUserRepository)(nil).GetUserByUsername), ctx, username)
+}
+
+// SaveUser mocks base method.
+func (m *MockUserRepository) SaveUser(ctx context.Context, user entity.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveUser", ctx, user)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveUser indicates an expected call of SaveUser.
+func (mr *MockUserRepositoryMockRecorder) SaveUser(ctx, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveUser", reflect.TypeOf((*MockUserRepository)(nil).SaveUser), ctx, user)
+}
+
+// SetUserRole mocks base method.
+func (m *MockUserRepository) SetUserRole(ctx context.Context, id, role string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserRole", ctx, id, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetUserRole indicates an expected call of SetUserRole.
+func (mr *MockUserRepositoryMockRecorder) SetUserRole(ctx, id, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserRole", reflect.TypeOf((*MockUserRepository)(nil).SetUserRole), ctx, id, role)
+}
+
+// MockRefreshTokenRepository is a mock of RefreshTokenRepository interface.
+type MockRefreshTokenRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRefreshTokenRepositoryMockRecorder
+}
+
+// MockRefreshTokenRepositoryMockRecorder is the mock recorder for MockRefreshTokenRepository.
+type MockRefreshTokenRepositoryMockRecorder struct {
+	mock *MockRefreshTokenRepository
+}
+
+// NewMockRefreshTokenRepository creates a new mock instance.
+func NewMockRefreshTokenRepository(ctrl *gomock.Controller) *MockRefreshTokenRepository {
+	mock := &MockRefreshTokenRepository{ctrl: ctrl}
+	mock.recorder = &MockRefreshTokenRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRefreshTokenRepository) EXPECT() *MockRefreshTokenRepositoryMockRecorder {
+	return m.recorder
+}
+
+// DeleteRefreshTokens mocks base method.
+func (m *MockRefreshTokenRepository) DeleteRefreshTokens(ctx context.Context, before time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRefreshTokens", ctx, before)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteRefreshTokens indicates an expected call of DeleteRefreshTokens.
+func (mr *MockRefreshTokenRepositoryMockRecorder) DeleteRefreshTokens(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRefreshTokens", reflect.TypeOf((*MockRefreshTokenRepository)(nil).DeleteRefreshTokens), ctx, before)
+}
+
+// GetRefreshTokenByHash mocks base method.
+func (m *MockRefreshTokenRepository) GetRefreshTokenByHash(ctx context.Context, hash string) (*entity.RefreshToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRefreshTokenByHash", ctx, hash)
+	ret0, _ := ret[0].(*entity.RefreshToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRefreshTokenByHash indicates an expected call of GetRefreshTokenByHash.
+func (mr *MockRefreshTokenRepositoryMockRecorder) GetRefreshTokenByHash(ctx, hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRefreshTokenByHash", reflect.TypeOf((*MockRefreshTokenRepository)(nil).GetRefreshTokenByHash), ctx, hash)
+}
+
+// RevokeRefreshToken mocks base method.
+func (m *MockRefreshTokenRepository) RevokeRefreshToken(ctx context.Context, id string, rotated bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeRefreshToken", ctx, id, rotated)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeRefreshToken indicates an expected call of RevokeRefreshToken.
+func (mr *MockRefreshTokenRepositoryMockRecorder) RevokeRefreshToken(ctx, id, rotated interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeRefreshToken", reflect.TypeOf((*MockRefreshTokenRepository)(nil).RevokeRefreshToken), ctx, id, rotated)
+}
+
+// RevokeUserRefreshTokens mocks base method.
+func (m *MockRefreshTokenRepository) RevokeUserRefreshTokens(ctx context.Context, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeUserRefreshTokens", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeUserRefreshTokens indicates an expected call of RevokeUserRefreshTokens.
+func (mr *MockRefreshTokenRepositoryMockRecorder) RevokeUserRefreshTokens(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeUserRefreshTokens", reflect.TypeOf((*MockRefreshTokenRepository)(nil).RevokeUserRefreshTokens), ctx, userID)
+}
+
+// SaveRefreshToken mocks base method.
+func (m *MockRefreshTokenRepository) SaveRefreshToken(ctx context.Context, token entity.RefreshToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveRefreshToken", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveRefreshToken indicates an expected call of SaveRefreshToken.
+func (mr *MockRefreshTokenRepositoryMockRecorder) SaveRefreshToken(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveRefreshToken", reflect.TypeOf((*MockRefreshTokenRepository)(nil).SaveRefreshToken), ctx, token)
+}
+
+// MockAuditRepository is a mock of AuditRepository interface.
+type MockAuditRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditRepositoryMockRecorder
+}
+
+// MockAuditRepositoryMockRecorder is the mock recorder for MockAuditRepository.
+type MockAuditRepositoryMockRecorder struct {
+	mock *MockAuditRepository
+}
+
+// NewMockAuditRepository creates a new mock instance.
+func NewMockAuditRepository(ctrl *gomock.Controller) *MockAuditRepository {
+	mock := &MockAuditRepository{ctrl: ctrl}
+	mock.recorder = &MockAuditRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuditRepository) EXPECT() *MockAuditRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetAuditEntries mocks base method.
+func (m *MockAuditRepository) GetAuditEntries(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuditEntries", ctx, filter)
+	ret0, _ := ret[0].([]entity.AuditEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuditEntries indicates an expected call of GetAuditEntries.
+func (mr *MockAuditRepositoryMockRecorder) GetAuditEntries(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuditEntries", reflect.TypeOf((*MockAuditRepository)(nil).GetAuditEntries), ctx, filter)
+}
+
+// SaveAuditEntries mocks base method.
+func (m *MockAuditRepository) SaveAuditEntries(ctx context.Context, entries []entity.AuditEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveAuditEntries", ctx, entries)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveAuditEntries indicates an expected call of SaveAuditEntries.
+func (mr *MockAuditRepositoryMockRecorder) SaveAuditEntries(ctx, entries interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAuditEntries", reflect.TypeOf((*MockAuditRepository)(nil).SaveAuditEntries), ctx, entries)
+}
+
+// MockWorkspaceRepository is a mock of WorkspaceRepository interface.
+type MockWorkspaceRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockWorkspaceRepositoryMockRecorder
+}
+
+// MockWorkspaceRepositoryMockRecorder is the mock recorder for MockWorkspaceRepository.
+type MockWorkspaceRepositoryMockRecorder struct {
+	mock *MockWorkspaceRepository
+}
+
+// NewMockWorkspaceRepository creates a new mock instance.
+func NewMockWorkspaceRepository(ctrl *gomock.Controller) *MockWorkspaceRepository {
+	mock := &MockWorkspaceRepository{ctrl: ctrl}
+	mock.recorder = &MockWorkspaceRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWorkspaceRepository) EXPECT() *MockWorkspaceRepositoryMockRecorder {
+	return m.recorder
+}
+
+// DeleteMember mocks base method.
+func (m *MockWorkspaceRepository) DeleteMember(ctx context.Context, workspaceID, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteMember", ctx, workspaceID, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteMember indicates an expected call of DeleteMember.
+func (mr *MockWorkspaceRepositoryMockRecorder) DeleteMember(ctx, workspaceID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMember", reflect.TypeOf((*MockWorkspaceRepository)(nil).DeleteMember), ctx, workspaceID, userID)
+}
+
+// GetMember mocks base method.
+func (m *MockWorkspaceRepository) GetMember(ctx context.Context, workspaceID, userID string) (*entity.WorkspaceMember, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMember", ctx, workspaceID, userID)
+	ret0, _ := ret[0].(*entity.WorkspaceMember)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMember indicates an expected call of GetMember.
+func (mr *MockWorkspaceRepositoryMockRecorder) GetMember(ctx, workspaceID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMember", reflect.TypeOf((*MockWorkspaceRepository)(nil).GetMember), ctx, workspaceID, userID)
+}
+
+// GetMembers mocks base method.
+func (m *MockWorkspaceRepository) GetMembers(ctx context.Context, workspaceID string) ([]entity.WorkspaceMember, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMembers", ctx, workspaceID)
+	ret0, _ := ret[0].([]entity.WorkspaceMember)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMembers indicates an expected call of GetMembers.
+func (mr *MockWorkspaceRepositoryMockRecorder) GetMembers(ctx, workspaceID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMembers", reflect.TypeOf((*MockWorkspaceRepository)(nil).GetMembers), ctx, workspaceID)
+}
+
+// GetMembershipsByUserID mocks base method.
+func (m *MockWorkspaceRepository) GetMembershipsByUserID(ctx context.Context, userID string) ([]entity.WorkspaceMember, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMembershipsByUserID", ctx, userID)
+	ret0, _ := ret[0].([]entity.WorkspaceMember)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMembershipsByUserID indicates an expected call of GetMembershipsByUserID.
+func (mr *MockWorkspaceRepositoryMockRecorder) GetMembershipsByUserID(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMembershipsByUserID", reflect.TypeOf((*MockWorkspaceRepository)(nil).GetMembershipsByUserID), ctx, userID)
+}
+
+// GetWorkspace mocks base method.
+func (m *MockWorkspaceRepository) GetWorkspace(ctx context.Context, id string) (*entity.Workspace, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWorkspace", ctx, id)
+	ret0, _ := ret[0].(*entity.Workspace)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWorkspace indicates an expected call of GetWorkspace.
+func (mr *MockWorkspaceRepositoryMockRecorder) GetWorkspace(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWorkspace", reflect.TypeOf((*MockWorkspaceRepository)(nil).GetWorkspace), ctx, id)
+}
+
+// SaveMember mocks base method.
+func (m *MockWorkspaceRepository) SaveMember(ctx context.Context, member entity.WorkspaceMember) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveMember", ctx, member)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveMember indicates an expected call of SaveMember.
+func (mr *MockWorkspaceRepositoryMockRecorder) SaveMember(ctx, member interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveMember", reflect.TypeOf((*MockWorkspaceRepository)(nil).SaveMember), ctx, member)
+}
+
+// SaveWorkspace mocks base method.
+func (m *MockWorkspaceRepository) SaveWorkspace(ctx context.Context, workspace entity.Workspace, owner entity.WorkspaceMember) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveWorkspace", ctx, workspace, owner)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveWorkspace indicates an expected call of SaveWorkspace.
+func (mr *MockWorkspaceRepositoryMockRecorder) SaveWorkspace(ctx, workspace, owner interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveWorkspace", reflect.TypeOf((*MockWorkspaceRepository)(nil).SaveWorkspace), ctx, workspace, owner)
+}
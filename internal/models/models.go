@@ -1,8 +1,21 @@
 package models
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // Request represents a URL shortening request.
 type Request struct {
 	URL string `json:"url"`
+	LinkOptions
+}
+
+// LinkOptions are the optional settings of a link being shortened. A link with an ExpiresAt is deleted
+// once it expires, and the owner of a link with a ClickThreshold is notified once it's clicked that many times.
+type LinkOptions struct {
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	ClickThreshold int64      `json:"click_threshold,omitempty"`
 }
 
 // Response is the structure of a response from a URL shortening request.
@@ -15,6 +28,7 @@ type Response struct {
 type BatchRequest struct {
 	CorrelationID string `json:"correlation_id"`
 	OriginalURL   string `json:"original_url"`
+	LinkOptions
 }
 
 // BatchResponse is the structure of a response from a batch URL shortening request.
@@ -24,20 +38,238 @@ type BatchResponse struct {
 }
 
 // UserURLsResponse is the structure of a response containing a user's URLs.
+// Title, Description and Image are filled in once the preview of the destination is fetched.
+// Disabled reports that the URL was disabled by a moderator and no longer redirects.
+// WorkspaceID is set for the URLs shared in a workspace. ExpiresAt and ClickThreshold are set
+// for the URLs created with them, and Clicks counts the redirects of the URL.
 type UserURLsResponse struct {
+	ShortURL       string     `json:"short_url"`
+	OriginalURL    string     `json:"original_url"`
+	Title          string     `json:"title,omitempty"`
+	Description    string     `json:"description,omitempty"`
+	Image          string     `json:"image,omitempty"`
+	Disabled       bool       `json:"disabled,omitempty"`
+	WorkspaceID    string     `json:"workspace_id,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	Clicks         int64      `json:"clicks,omitempty"`
+	ClickThreshold int64      `json:"click_threshold,omitempty"`
+}
+
+// ExpandResponse is the structure of a response from the ExpandHandler.
+// It includes the original URL alongside the Open Graph preview of its destination.
+type ExpandResponse struct {
 	ShortURL    string `json:"short_url"`
 	OriginalURL string `json:"original_url"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
 }
 
 // URLDeletionTask represents a task to delete URLs for deletion worker.
+// The Source is where the deletion was requested from, for the audit log.
 type URLDeletionTask struct {
 	UserID string
 	URLs   []string
+	Source RequestSource
+}
+
+// RequestSource describes where a request came from.
+type RequestSource struct {
+	Transport string
+	ClientIP  string
+	RequestID string
 }
 
 // StatsResponse is the structure of a response from the StatsHandler.
-// It includes the total count of URLs and Users in the system.
+// It includes the total count of URLs and Users in the system and the stats of the redirect cache, if enabled.
 type StatsResponse struct {
-	URLs  int `json:"urls"`
-	Users int `json:"users"`
+	URLs  int                 `json:"urls"`
+	Users int                 `json:"users"`
+	Cache *CacheStatsResponse `json:"cache,omitempty"`
+}
+
+// CacheStatsResponse is the structure of the redirect cache stats in a StatsResponse.
+type CacheStatsResponse struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+	Size   int   `json:"size"`
+}
+
+// LinkEvent is the payload of a link lifecycle event sent to webhooks. The events of updated links
+// carry the changed state of the link, the events of expired links their expiry and the events
+// of links that reached their click threshold the clicks and the threshold.
+type LinkEvent struct {
+	ShortURL       string     `json:"short_url"`
+	OriginalURL    string     `json:"original_url,omitempty"`
+	UserID         string     `json:"user_id,omitempty"`
+	WorkspaceID    string     `json:"workspace_id,omitempty"`
+	Disabled       *bool      `json:"disabled,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	Clicks         int64      `json:"clicks,omitempty"`
+	ClickThreshold int64      `json:"click_threshold,omitempty"`
+}
+
+// WebhookRequest represents a request to register a webhook endpoint.
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+// WebhookResponse is the structure of a response describing a registered webhook.
+// The Secret is only returned once, when the webhook is registered.
+type WebhookResponse struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookDeliveryResponse is the structure of a response describing a single delivery attempt.
+type WebhookDeliveryResponse struct {
+	ID         string    `json:"id"`
+	EventID    string    `json:"event_id"`
+	EventType  string    `json:"event_type"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Delivered  bool      `json:"delivered"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Quota represents the limits applied to a user. A zero limit means no limit.
+type Quota struct {
+	MaxLinks     int `json:"max_links"`
+	MaxBatchSize int `json:"max_batch_size"`
+	MaxURLLength int `json:"max_url_length"`
+}
+
+// APIKeyRequest represents a request to create an API key. Omitted scopes grant all the scopes,
+// and an omitted expiry creates a key that never expires.
+type APIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// APIKeyResponse is the structure of a response describing an API key.
+// The Key is only returned once, when the key is created.
+type APIKeyResponse struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Key       string     `json:"key,omitempty"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Revoked   bool       `json:"revoked"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// AccountRequest represents a request to register or log in to an account.
+type AccountRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// AccountResponse is the structure of a response describing the account the user is logged in to.
+// The Token is a short-lived access token that authenticates the account in the gRPC API and
+// in the Authorization header until ExpiresAt, and is renewed with the RefreshToken.
+type AccountResponse struct {
+	UserID       string     `json:"user_id"`
+	Username     string     `json:"username"`
+	Token        string     `json:"token,omitempty"`
+	RefreshToken string     `json:"refresh_token,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+}
+
+// RefreshRequest represents a request to renew a session or to log out of it.
+// An empty RefreshToken refers to the refresh cookie of the request.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// TokenResponse is the structure of a response describing a renewed session.
+type TokenResponse struct {
+	UserID       string    `json:"user_id"`
+	Token        string    `json:"token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// AdminLinkResponse is the structure of a response describing a link of any user to a moderator.
+type AdminLinkResponse struct {
+	ShortURL    string `json:"short_url"`
+	OriginalURL string `json:"original_url"`
+	UserID      string `json:"user_id"`
+	Deleted     bool   `json:"deleted"`
+	Disabled    bool   `json:"disabled"`
+}
+
+// ReassignRequest represents a request to move a link from one user to another.
+type ReassignRequest struct {
+	FromUserID string `json:"from_user_id"`
+	ToUserID   string `json:"to_user_id"`
+}
+
+// RoleRequest represents a request to change the role of a user.
+type RoleRequest struct {
+	Role string `json:"role"`
+}
+
+// WorkspaceRequest represents a request to create a workspace.
+type WorkspaceRequest struct {
+	Name string `json:"name"`
+}
+
+// WorkspaceResponse is the structure of a response describing a workspace and the permissions
+// the user has in it.
+type WorkspaceResponse struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// WorkspaceMemberRequest represents a request to add a member to a workspace or to change the permissions of the member.
+type WorkspaceMemberRequest struct {
+	Permissions []string `json:"permissions"`
+}
+
+// WorkspaceMemberResponse is the structure of a response describing a member of a workspace.
+type WorkspaceMemberResponse struct {
+	UserID      string    `json:"user_id"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// AuditQuery represents a query of the audit log. Empty fields match any entry.
+type AuditQuery struct {
+	ActorID   string
+	Action    string
+	Target    string
+	Transport string
+	Since     time.Time
+	Until     time.Time
+	Limit     int
+	Offset    int
+}
+
+// AuditEntryResponse is the structure of a response describing a change recorded in the audit log.
+type AuditEntryResponse struct {
+	ID        string          `json:"id"`
+	ActorID   string          `json:"actor_id"`
+	Action    string          `json:"action"`
+	Target    string          `json:"target,omitempty"`
+	Transport string          `json:"transport"`
+	ClientIP  string          `json:"client_ip,omitempty"`
+	RequestID string          `json:"request_id"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// AuditPage is the structure of a response from the AuditLogHandler. NextOffset is the offset
+// of the next page and is omitted on the last page.
+type AuditPage struct {
+	Entries    []AuditEntryResponse `json:"entries"`
+	NextOffset int                  `json:"next_offset,omitempty"`
 }
@@ -0,0 +1,218 @@
+// Package preview fetches destination pages of short URLs and extracts
+// Open Graph metadata from them.
+package preview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"github.com/PrahaTurbo/url-shortener/internal/netguard"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// Default limits applied by the Fetcher.
+const (
+	DefaultTimeout  = time.Second * 5
+	DefaultMaxBytes = 1 << 20
+
+	maxRedirects   = 3
+	maxTitleLen    = 300
+	maxDescription = 1000
+)
+
+// Error variables, used by the Fetcher.
+var (
+	ErrUnsupportedURL = errors.New("only http and https urls are supported")
+	ErrNotHTML        = errors.New("destination is not an html page")
+)
+
+// Fetcher downloads html pages and extracts previews from them.
+// It refuses to connect to loopback, private and otherwise non-public addresses, see netguard.
+type Fetcher struct {
+	client   *http.Client
+	maxBytes int64
+	allowIP  func(ip net.IP) bool
+}
+
+// NewFetcher creates a new Fetcher that gives up after timeout and reads at most maxBytes of a page.
+func NewFetcher(timeout time.Duration, maxBytes int64) *Fetcher {
+	f := &Fetcher{
+		maxBytes: maxBytes,
+		allowIP:  netguard.IsPublicIP,
+	}
+
+	f.client = &http.Client{
+		Timeout: timeout,
+		Transport: netguard.NewTransport(timeout, func(ip net.IP) bool {
+			return f.allowIP(ip)
+		}),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("too many redirects")
+			}
+
+			return checkScheme(req.URL)
+		},
+	}
+
+	return f
+}
+
+// Fetch downloads the page at rawURL and extracts its preview.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*entity.Preview, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkScheme(u); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType != "text/html" {
+		return nil, ErrNotHTML
+	}
+
+	p := Extract(io.LimitReader(resp.Body, f.maxBytes))
+	p.Image = resolveReference(resp.Request.URL, p.Image)
+
+	return p, nil
+}
+
+// Extract parses the html document from r and returns its preview.
+// Open Graph properties take precedence over the <title> element and the description meta tag.
+func Extract(r io.Reader) *entity.Preview {
+	var p entity.Preview
+	var title, description string
+
+	z := html.NewTokenizer(r)
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return finalize(p, title, description)
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+
+			switch t.Data {
+			case "title":
+				if title == "" && z.Next() == html.TextToken {
+					title = string(z.Text())
+				}
+			case "meta":
+				key, content := metaAttrs(t)
+				switch key {
+				case "og:title":
+					p.Title = content
+				case "og:description":
+					p.Description = content
+				case "og:image", "og:image:url":
+					if p.Image == "" {
+						p.Image = content
+					}
+				case "description":
+					description = content
+				}
+			case "body":
+				return finalize(p, title, description)
+			}
+		}
+	}
+}
+
+func checkScheme(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ErrUnsupportedURL
+	}
+
+	return nil
+}
+
+func metaAttrs(t html.Token) (string, string) {
+	var key, content string
+
+	for _, a := range t.Attr {
+		switch strings.ToLower(a.Key) {
+		case "property", "name":
+			if key == "" {
+				key = strings.ToLower(strings.TrimSpace(a.Val))
+			}
+		case "content":
+			content = a.Val
+		}
+	}
+
+	return key, content
+}
+
+func finalize(p entity.Preview, title, description string) *entity.Preview {
+	if p.Title == "" {
+		p.Title = title
+	}
+
+	if p.Description == "" {
+		p.Description = description
+	}
+
+	p.Title = truncate(strings.TrimSpace(p.Title), maxTitleLen)
+	p.Description = truncate(strings.TrimSpace(p.Description), maxDescription)
+	p.Image = strings.TrimSpace(p.Image)
+
+	return &p
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+
+	return string(r[:n])
+}
+
+func resolveReference(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+
+	resolved := base.ResolveReference(u)
+	if checkScheme(resolved) != nil {
+		return ""
+	}
+
+	return resolved.String()
+}
@@ -0,0 +1,120 @@
+package preview
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PrahaTurbo/url-shortener/internal/netguard"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		want *entity.Preview
+	}{
+		{
+			name: "should prefer open graph properties",
+			page: `<html><head>
+				<title>Plain title</title>
+				<meta name="description" content="Plain description">
+				<meta property="og:title" content="OG title">
+				<meta property="og:description" content="OG description">
+				<meta property="og:image" content="https://example.com/image.png">
+				</head><body></body></html>`,
+			want: &entity.Preview{
+				Title:       "OG title",
+				Description: "OG description",
+				Image:       "https://example.com/image.png",
+			},
+		},
+		{
+			name: "should fall back to title and description meta tag",
+			page: `<html><head>
+				<title> Plain title </title>
+				<meta name="description" content="Plain description">
+				</head></html>`,
+			want: &entity.Preview{
+				Title:       "Plain title",
+				Description: "Plain description",
+			},
+		},
+		{
+			name: "should ignore tags in body",
+			page: `<html><head></head><body><meta property="og:title" content="Body title"></body></html>`,
+			want: &entity.Preview{},
+		},
+		{
+			name: "should return empty preview for non html input",
+			page: `just some text`,
+			want: &entity.Preview{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Extract(strings.NewReader(tt.page))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFetcher_Fetch(t *testing.T) {
+	page := `<html><head><meta property="og:title" content="Test"><meta property="og:image" content="/img.png"></head></html>`
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{}`))
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write([]byte(page))
+		}
+	}))
+	defer srv.Close()
+
+	t.Run("should refuse loopback destination", func(t *testing.T) {
+		f := NewFetcher(DefaultTimeout, DefaultMaxBytes)
+
+		_, err := f.Fetch(context.Background(), srv.URL)
+		assert.ErrorIs(t, err, netguard.ErrForbiddenAddress)
+	})
+
+	t.Run("should refuse unsupported scheme", func(t *testing.T) {
+		f := NewFetcher(DefaultTimeout, DefaultMaxBytes)
+
+		_, err := f.Fetch(context.Background(), "file:///etc/passwd")
+		assert.ErrorIs(t, err, ErrUnsupportedURL)
+	})
+
+	f := NewFetcher(DefaultTimeout, DefaultMaxBytes)
+	f.allowIP = func(ip net.IP) bool { return true }
+
+	t.Run("should fetch preview", func(t *testing.T) {
+		got, err := f.Fetch(context.Background(), srv.URL+"/page")
+		require.NoError(t, err)
+
+		assert.Equal(t, &entity.Preview{Title: "Test", Image: srv.URL + "/img.png"}, got)
+	})
+
+	t.Run("should refuse non html destination", func(t *testing.T) {
+		_, err := f.Fetch(context.Background(), srv.URL+"/json")
+		assert.ErrorIs(t, err, ErrNotHTML)
+	})
+
+	t.Run("should fail on unexpected status code", func(t *testing.T) {
+		_, err := f.Fetch(context.Background(), srv.URL+"/missing")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,217 @@
+// Package ratelimit implements token bucket rate limiting keyed by user ID and client IP.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/PrahaTurbo/url-shortener/internal/auth"
+	"github.com/PrahaTurbo/url-shortener/internal/clientip"
+)
+
+// ErrInvalidLimit is returned when a limit cannot be parsed.
+var ErrInvalidLimit = errors.New(`rate limit must be in the form "rate:burst"`)
+
+// Limit describes a token bucket: Rate tokens are added every second, up to Burst tokens.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// ParseLimit parses a limit in the form "rate:burst", e.g. "10:20".
+// An empty string results in a zero Limit, which disables rate limiting.
+func ParseLimit(s string) (Limit, error) {
+	if s == "" {
+		return Limit{}, nil
+	}
+
+	rate, burst, ok := strings.Cut(s, ":")
+	if !ok {
+		return Limit{}, ErrInvalidLimit
+	}
+
+	r, err := strconv.ParseFloat(rate, 64)
+	if err != nil || r <= 0 {
+		return Limit{}, ErrInvalidLimit
+	}
+
+	b, err := strconv.Atoi(burst)
+	if err != nil || b < 1 {
+		return Limit{}, ErrInvalidLimit
+	}
+
+	return Limit{Rate: r, Burst: b}, nil
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter keeps a token bucket per key.
+// A nil Limiter allows everything, so disabled limits need no special handling.
+type Limiter struct {
+	limit     Limit
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+	mu        sync.Mutex
+}
+
+// NewLimiter creates a new Limiter for the limit. It returns nil if the limit is zero.
+func NewLimiter(limit Limit) *Limiter {
+	if limit.Rate <= 0 || limit.Burst < 1 {
+		return nil
+	}
+
+	return &Limiter{
+		limit:   limit,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from the bucket of the key. If the bucket is empty,
+// it reports false along with the time until the next token is available.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+
+	return l.allowAll(key)
+}
+
+// allowAll takes a token from the bucket of every key, or from none of them if any bucket is empty,
+// so that a request denied by one bucket doesn't spend the others. It reports false along with the time
+// until every bucket has a token.
+func (l *Limiter) allowAll(keys ...string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	var wait time.Duration
+	buckets := make([]*bucket, 0, len(keys))
+	for _, key := range keys {
+		b, ok := l.buckets[key]
+		if !ok {
+			b = &bucket{tokens: float64(l.limit.Burst), last: now}
+			l.buckets[key] = b
+		}
+
+		b.tokens = math.Min(float64(l.limit.Burst), b.tokens+now.Sub(b.last).Seconds()*l.limit.Rate)
+		b.last = now
+
+		if b.tokens < 1 {
+			if w := time.Duration((1 - b.tokens) / l.limit.Rate * float64(time.Second)); w > wait {
+				wait = w
+			}
+		}
+
+		buckets = append(buckets, b)
+	}
+
+	if wait > 0 {
+		return false, wait
+	}
+
+	for _, b := range buckets {
+		b.tokens--
+	}
+
+	return true, 0
+}
+
+// Middleware is an HTTP middleware that limits requests by the user ID from the request context
+// and by the client IP resolved by the clientip middleware. It responds with status code 429 and a Retry-After header when a limit is exceeded.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := auth.UserIDFromContext(r.Context())
+
+		if ok, wait := l.allowRequest(userID, ipKey(clientip.FromRequest(r))); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(wait)))
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// UnaryServerInterceptor returns a gRPC interceptor that applies the limiter of the called method,
+// keyed by the user ID from the context and the client IP resolved by the clientip interceptor. Methods without a limiter are not limited.
+// It must run after the authentication interceptor, so the user ID is already in the context.
+func UnaryServerInterceptor(limiters map[string]*Limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		l := limiters[info.FullMethod]
+		if l == nil {
+			return handler(ctx, req)
+		}
+
+		userID, _ := auth.UserIDFromContext(ctx)
+
+		if ok, wait := l.allowRequest(userID, ipKey(clientip.FromGRPC(ctx))); !ok {
+			return nil, status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry in %d seconds", retryAfterSeconds(wait))
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// allowRequest takes a token from the buckets of both the client IP and the user, or from neither.
+func (l *Limiter) allowRequest(userID, ip string) (bool, time.Duration) {
+	keys := make([]string, 0, 2)
+	if ip != "" {
+		keys = append(keys, "ip:"+ip)
+	}
+
+	if userID != "" {
+		keys = append(keys, "user:"+userID)
+	}
+
+	return l.allowAll(keys...)
+}
+
+// sweep drops the buckets that have been idle long enough to be full again.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+
+	l.lastSweep = now
+	refill := time.Duration(float64(l.limit.Burst) / l.limit.Rate * float64(time.Second))
+
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > refill {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func ipKey(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+
+	return ip.String()
+}
+
+func retryAfterSeconds(wait time.Duration) int {
+	return int(math.Ceil(wait.Seconds()))
+}
@@ -0,0 +1,140 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/PrahaTurbo/url-shortener/internal/auth"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    Limit
+		wantErr bool
+	}{
+		{name: "should parse limit", value: "0.5:10", want: Limit{Rate: 0.5, Burst: 10}},
+		{name: "should return zero limit if empty", value: ""},
+		{name: "should fail without burst", value: "10", wantErr: true},
+		{name: "should fail on negative rate", value: "-1:10", wantErr: true},
+		{name: "should fail on zero burst", value: "1:0", wantErr: true},
+		{name: "should fail on garbage", value: "a:b", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLimit(tt.value)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidLimit)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	l := NewLimiter(Limit{Rate: 2, Burst: 3})
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		ok, _ := l.Allow("a")
+		assert.True(t, ok, "request %d should be allowed", i)
+	}
+
+	ok, wait := l.Allow("a")
+	assert.False(t, ok)
+	assert.Equal(t, time.Millisecond*500, wait)
+
+	ok, _ = l.Allow("b")
+	assert.True(t, ok, "other keys have their own bucket")
+
+	now = now.Add(time.Millisecond * 500)
+	ok, _ = l.Allow("a")
+	assert.True(t, ok, "bucket should be refilled")
+
+	now = now.Add(time.Hour)
+	l.Allow("c")
+	assert.Len(t, l.buckets, 1, "idle buckets should be swept")
+}
+
+func TestLimiter_Nil(t *testing.T) {
+	l := NewLimiter(Limit{})
+	require.Nil(t, l)
+
+	ok, _ := l.Allow("a")
+	assert.True(t, ok)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	assert.NotNil(t, l.Middleware(handler))
+}
+
+func TestLimiter_Middleware(t *testing.T) {
+	l := NewLimiter(Limit{Rate: 1, Burst: 1})
+
+	handler := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	request := func(userID, addr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/shorten/batch", nil)
+		r.RemoteAddr = addr
+		r = r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, userID))
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		return w
+	}
+
+	assert.Equal(t, http.StatusCreated, request("1", "192.0.2.1:1234").Code)
+
+	w := request("1", "192.0.2.2:1234")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "same user from another ip should be limited")
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusCreated, request("4", "192.0.2.2:4321").Code, "a denied request should not spend the budget of its ip")
+
+	assert.Equal(t, http.StatusTooManyRequests, request("2", "192.0.2.1:4321").Code, "another user from the same ip should be limited")
+	assert.Equal(t, http.StatusCreated, request("3", "192.0.2.3:1234").Code)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor(map[string]*Limiter{
+		"/shortener.URLShortener/MakeURL": NewLimiter(Limit{Rate: 1, Burst: 1}),
+	})
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1234}})
+	ctx = context.WithValue(ctx, auth.UserIDKey, "1")
+
+	limited := &grpc.UnaryServerInfo{FullMethod: "/shortener.URLShortener/MakeURL"}
+	unlimited := &grpc.UnaryServerInfo{FullMethod: "/shortener.URLShortener/PingDB"}
+
+	_, err := interceptor(ctx, nil, limited, handler)
+	assert.NoError(t, err)
+
+	_, err = interceptor(ctx, nil, limited, handler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	_, err = interceptor(ctx, nil, unlimited, handler)
+	assert.NoError(t, err)
+}
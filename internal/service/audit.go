@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+
+	"github.com/PrahaTurbo/url-shortener/internal/audit"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// WithAuditLog enables recording of the created and deleted links and of the quota changes in the audit log.
+func WithAuditLog(r audit.Recorder) Option {
+	return func(s *service) {
+		s.audit = r
+	}
+}
+
+func (s *service) record(ctx context.Context, e audit.Entry) {
+	if s.audit == nil {
+		return
+	}
+
+	s.audit.Record(ctx, e)
+}
+
+// recordBatch records the changes in the audit log at once, if it's enabled.
+func (s *service) recordBatch(ctx context.Context, entries []audit.Entry) {
+	if s.audit == nil {
+		return
+	}
+
+	s.audit.RecordBatch(ctx, entries)
+}
+
+// auditedQuota returns the quota of the user before a change. It skips the lookup if the audit log is disabled.
+func (s *service) auditedQuota(ctx context.Context, userID string) (*models.Quota, error) {
+	if s.audit == nil {
+		return nil, nil
+	}
+
+	quota, err := s.quotaFor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &quota, nil
+}
+
+// recordDeleted records the deletion of the records DeleteURLBatch marked as deleted, at once. It marks only
+// the records that weren't deleted yet, so they differ from the records before the deletion by the flag only.
+// The records are taken as DeleteURLBatch returned them rather than read again, since a read replica
+// may not have caught up with the deletion.
+func (s *service) recordDeleted(ctx context.Context, userID string, deleted []entity.URLRecord) {
+	if len(deleted) == 0 {
+		return
+	}
+
+	entries := make([]audit.Entry, 0, len(deleted))
+	for _, after := range deleted {
+		before := after
+		before.DeletedFlag = false
+
+		entries = append(entries, audit.Entry{
+			ActorID: userID,
+			Action:  audit.ActionLinkDelete,
+			Target:  after.ShortURL,
+			Before:  before,
+			After:   after,
+		})
+	}
+
+	s.recordBatch(ctx, entries)
+}
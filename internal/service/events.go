@@ -0,0 +1,41 @@
+package service
+
+import (
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+	"github.com/PrahaTurbo/url-shortener/internal/webhook"
+)
+
+// EventPublisher is an interface for publishing link lifecycle events, e.g. to webhooks.
+type EventPublisher interface {
+	Publish(userID, eventType string, link models.LinkEvent)
+}
+
+// WithEventPublisher enables publishing of link lifecycle events.
+func WithEventPublisher(p EventPublisher) Option {
+	return func(s *service) {
+		s.publisher = p
+	}
+}
+
+func (s *service) publish(userID, eventType string, shortURL, originalURL string) {
+	s.publishLink(userID, eventType, shortURL, models.LinkEvent{OriginalURL: originalURL})
+}
+
+// publishLink publishes the event with the payload, setting its short URL to the full one of the shortened URL.
+func (s *service) publishLink(userID, eventType string, shortURL string, link models.LinkEvent) {
+	if s.publisher == nil {
+		return
+	}
+
+	link.ShortURL = formURL(s.baseURL, shortURL)
+	s.publisher.Publish(userID, eventType, link)
+}
+
+// publishDeleted publishes the deletion of every deleted record to its owner, who isn't the user
+// that deleted it if it was deleted from a workspace.
+func (s *service) publishDeleted(records []entity.URLRecord) {
+	for _, r := range records {
+		s.publish(r.UserID, webhook.EventLinkDeleted, r.ShortURL, r.OriginalURL)
+	}
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/PrahaTurbo/url-shortener/internal/audit"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+	"github.com/PrahaTurbo/url-shortener/internal/webhook"
+)
+
+// Intervals of the link maintenance. Links expire within the expiry interval, and the clicks
+// are stored within the flush interval after the redirects.
+const (
+	linkExpiryInterval = time.Minute
+	clickFlushInterval = time.Second * 10
+)
+
+// clickCounter counts the redirects of the links until they're stored, so that a redirect doesn't wait on a write.
+type clickCounter struct {
+	mu     sync.Mutex
+	clicks map[string]int64
+}
+
+// add counts a redirect of the shortened URL.
+func (c *clickCounter) add(shortURL string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.clicks == nil {
+		c.clicks = make(map[string]int64)
+	}
+
+	c.clicks[shortURL]++
+}
+
+// take returns the counted redirects and starts counting anew.
+func (c *clickCounter) take() map[string]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clicks := c.clicks
+	c.clicks = nil
+
+	return clicks
+}
+
+// restore counts the redirects again, e.g. after they failed to be stored.
+func (c *clickCounter) restore(clicks map[string]int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.clicks == nil {
+		c.clicks = make(map[string]int64, len(clicks))
+	}
+
+	for shortURL, n := range clicks {
+		c.clicks[shortURL] += n
+	}
+}
+
+// checkLinkOptions returns an error if the link would expire by the time it's created,
+// or if its click threshold is negative.
+func checkLinkOptions(opts models.LinkOptions, now time.Time) error {
+	if opts.ExpiresAt != nil && !opts.ExpiresAt.After(now) {
+		return ErrInvalidExpiry
+	}
+
+	if opts.ClickThreshold < 0 {
+		return ErrInvalidClickThreshold
+	}
+
+	return nil
+}
+
+// newRecord returns a new URL record of the user with the options of the link.
+func newRecord(shortURL, originalURL, userID string, opts models.LinkOptions) entity.URLRecord {
+	r := entity.URLRecord{
+		ShortURL:       shortURL,
+		OriginalURL:    originalURL,
+		UserID:         userID,
+		ClickThreshold: opts.ClickThreshold,
+	}
+
+	if opts.ExpiresAt != nil {
+		expiresAt := opts.ExpiresAt.UTC()
+		r.ExpiresAt = &expiresAt
+	}
+
+	return r
+}
+
+// MaintainLinks deletes the links as they expire and stores the redirects counted by GetURL until the context
+// is done, storing the redirects counted so far before it returns. It publishes link.expired to the owners
+// of the expired links and link.click_threshold_reached to the owners of the links whose clicks reached
+// their threshold. An expired link stops redirecting at its expiry, before it is marked as deleted here.
+func (s *service) MaintainLinks(ctx context.Context) {
+	expiry := time.NewTicker(s.expiryInterval)
+	defer expiry.Stop()
+
+	flush := time.NewTicker(s.flushInterval)
+	defer flush.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+			s.flushClicks(flushCtx)
+			cancel()
+
+			return
+		case <-expiry.C:
+			s.expireLinks(ctx)
+		case <-flush.C:
+			s.flushClicks(ctx)
+		}
+	}
+}
+
+// expireLinks deletes the expired links, publishes their expiry to their owners and records it in the audit log.
+func (s *service) expireLinks(ctx context.Context) {
+	expired, err := s.Storage.ExpireURLs(ctx, time.Now())
+	if err != nil {
+		s.logger.Error("cannot expire urls", zap.Error(err))
+		return
+	}
+
+	if len(expired) == 0 {
+		return
+	}
+
+	entries := make([]audit.Entry, 0, len(expired))
+	for _, after := range expired {
+		s.publishLink(after.UserID, webhook.EventLinkExpired, after.ShortURL, models.LinkEvent{
+			OriginalURL: after.OriginalURL,
+			ExpiresAt:   after.ExpiresAt,
+		})
+
+		before := after
+		before.DeletedFlag = false
+
+		entries = append(entries, audit.Entry{
+			Action: audit.ActionLinkExpire,
+			Target: after.ShortURL,
+			Before: before,
+			After:  after,
+		})
+	}
+
+	s.recordBatch(ctx, entries)
+}
+
+// flushClicks stores the counted redirects and publishes the links whose clicks reached their threshold
+// with these redirects to their owners. The redirects are counted again if they fail to be stored.
+func (s *service) flushClicks(ctx context.Context) {
+	clicks := s.clicks.take()
+	if len(clicks) == 0 {
+		return
+	}
+
+	updated, err := s.Storage.AddClicks(ctx, clicks)
+	if err != nil {
+		s.logger.Error("cannot store clicks", zap.Error(err), zap.Int("urls", len(clicks)))
+		s.clicks.restore(clicks)
+
+		return
+	}
+
+	for _, r := range updated {
+		added := clicks[r.ShortURL]
+		if r.ClickThreshold <= 0 || r.Clicks < r.ClickThreshold || r.Clicks-added >= r.ClickThreshold {
+			continue
+		}
+
+		s.publishLink(r.UserID, webhook.EventLinkClickThreshold, r.ShortURL, models.LinkEvent{
+			OriginalURL:    r.OriginalURL,
+			Clicks:         r.Clicks,
+			ClickThreshold: r.ClickThreshold,
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/PrahaTurbo/url-shortener/internal/audit"
+	"github.com/PrahaTurbo/url-shortener/internal/auth"
+	"github.com/PrahaTurbo/url-shortener/internal/mocks"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/memory"
+)
+
+func TestService_SaveURL_linkOptions(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour).In(time.FixedZone("UTC+3", 3*60*60))
+	past := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name    string
+		opts    models.LinkOptions
+		want    *entity.URLRecord
+		wantErr error
+	}{
+		{
+			name: "should save expiry in UTC and click threshold",
+			opts: models.LinkOptions{ExpiresAt: &expiresAt, ClickThreshold: 100},
+			want: &entity.URLRecord{ShortURL: "FgAJzm", OriginalURL: "https://yandex.ru", UserID: "1", ClickThreshold: 100},
+		},
+		{
+			name:    "should reject expiry in the past",
+			opts:    models.LinkOptions{ExpiresAt: &past},
+			wantErr: ErrInvalidExpiry,
+		},
+		{
+			name:    "should reject negative click threshold",
+			opts:    models.LinkOptions{ClickThreshold: -1},
+			wantErr: ErrInvalidClickThreshold,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := setupService()
+
+			ctrl := gomock.NewController(t)
+			storage := mocks.NewMockRepository(ctrl)
+
+			if tt.want != nil {
+				storage.EXPECT().
+					CheckExistence(gomock.Any(), "FgAJzm", "1").
+					Return(errInternal)
+				storage.EXPECT().
+					SaveURL(gomock.Any(), gomock.Any()).
+					Do(func(_ context.Context, r entity.URLRecord) {
+						require.NotNil(t, r.ExpiresAt)
+						assert.Equal(t, time.UTC, r.ExpiresAt.Location())
+						assert.True(t, expiresAt.Equal(*r.ExpiresAt))
+
+						r.UUID, r.ExpiresAt = "", nil
+						assert.Equal(t, *tt.want, r)
+					}).
+					Return(nil)
+			}
+
+			service.Storage = storage
+
+			ctx := context.WithValue(context.Background(), auth.UserIDKey, "1")
+			_, err := service.SaveURL(ctx, "https://yandex.ru", tt.opts)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestService_SaveBatch_linkOptions(t *testing.T) {
+	service := setupService()
+
+	ctrl := gomock.NewController(t)
+	service.Storage = mocks.NewMockRepository(ctrl)
+
+	batch := []models.BatchRequest{
+		{CorrelationID: "1", OriginalURL: "https://ya.ru"},
+		{CorrelationID: "2", OriginalURL: "https://yandex.ru", LinkOptions: models.LinkOptions{ClickThreshold: -1}},
+	}
+
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "1")
+	_, err := service.SaveBatch(ctx, batch)
+	assert.ErrorIs(t, err, ErrInvalidClickThreshold)
+}
+
+func TestService_SaveURL_afterExpiry(t *testing.T) {
+	service := setupService()
+
+	repo, err := memory.NewInMemStorage("", service.logger)
+	require.NoError(t, err)
+
+	service.Storage = repo
+
+	past := time.Now().Add(-time.Minute)
+	shortURL := generateShortURL("https://ya.ru")
+	require.NoError(t, repo.SaveURL(context.Background(), entity.URLRecord{
+		UUID: "1", ShortURL: shortURL, OriginalURL: "https://ya.ru", UserID: "1", ExpiresAt: &past,
+	}))
+
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "1")
+	got, err := service.SaveURL(ctx, "https://ya.ru", models.LinkOptions{})
+	require.NoError(t, err, "an expired link must be shortened again rather than reported as existing")
+	assert.Equal(t, baseURL+"/"+shortURL, got)
+
+	originalURL, err := repo.GetURL(context.Background(), shortURL)
+	require.NoError(t, err)
+	assert.Equal(t, "https://ya.ru", originalURL)
+}
+
+func Test_service_expireLinks(t *testing.T) {
+	service := setupService()
+
+	ctrl := gomock.NewController(t)
+	storage := mocks.NewMockRepository(ctrl)
+	publisher := mocks.NewMockEventPublisher(ctrl)
+	recorder := mocks.NewMockAuditRecorder(ctrl)
+
+	expiresAt := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	gomock.InOrder(
+		storage.EXPECT().
+			ExpireURLs(gomock.Any(), gomock.Any()).
+			Return([]entity.URLRecord{
+				{ShortURL: "abc", OriginalURL: "https://abc.example", UserID: "alice", ExpiresAt: &expiresAt, DeletedFlag: true},
+				{ShortURL: "abc", OriginalURL: "https://abc.example", UserID: "bob", ExpiresAt: &expiresAt, DeletedFlag: true},
+			}, nil),
+		publisher.EXPECT().
+			Publish("alice", "link.expired", models.LinkEvent{ShortURL: baseURL + "/abc", OriginalURL: "https://abc.example", ExpiresAt: &expiresAt}),
+		publisher.EXPECT().
+			Publish("bob", "link.expired", models.LinkEvent{ShortURL: baseURL + "/abc", OriginalURL: "https://abc.example", ExpiresAt: &expiresAt}),
+		recorder.EXPECT().
+			RecordBatch(gomock.Any(), gomock.Len(2)).
+			Do(func(_ context.Context, entries []audit.Entry) {
+				for _, e := range entries {
+					assert.Equal(t, audit.ActionLinkExpire, e.Action)
+					assert.Equal(t, "abc", e.Target)
+					assert.False(t, e.Before.(entity.URLRecord).DeletedFlag)
+					assert.True(t, e.After.(entity.URLRecord).DeletedFlag)
+				}
+			}),
+	)
+
+	service.Storage = storage
+	service.publisher = publisher
+	service.audit = recorder
+
+	service.expireLinks(context.Background())
+}
+
+func Test_service_flushClicks(t *testing.T) {
+	service := setupService()
+
+	ctrl := gomock.NewController(t)
+	storage := mocks.NewMockRepository(ctrl)
+	publisher := mocks.NewMockEventPublisher(ctrl)
+
+	gomock.InOrder(
+		storage.EXPECT().GetURL(gomock.Any(), "abc").Return("https://abc.example", nil).Times(3),
+		storage.EXPECT().GetURL(gomock.Any(), "def").Return("", errInternal),
+		// Only the link whose clicks reached its threshold with these clicks is published.
+		storage.EXPECT().
+			AddClicks(gomock.Any(), map[string]int64{"abc": 3}).
+			Return([]entity.URLRecord{
+				{ShortURL: "abc", OriginalURL: "https://abc.example", UserID: "alice", Clicks: 11, ClickThreshold: 10},
+				{ShortURL: "abc", OriginalURL: "https://abc.example", UserID: "bob", Clicks: 20, ClickThreshold: 10},
+				{ShortURL: "abc", OriginalURL: "https://abc.example", UserID: "carol", Clicks: 12, ClickThreshold: 13},
+				{ShortURL: "abc", OriginalURL: "https://abc.example", UserID: "dave", Clicks: 3},
+			}, nil),
+		publisher.EXPECT().
+			Publish("alice", "link.click_threshold_reached", models.LinkEvent{
+				ShortURL:       baseURL + "/abc",
+				OriginalURL:    "https://abc.example",
+				Clicks:         11,
+				ClickThreshold: 10,
+			}),
+	)
+
+	service.Storage = storage
+	service.publisher = publisher
+
+	ctx := context.Background()
+	for _, shortURL := range []string{"abc", "abc", "abc", "def"} {
+		_, _ = service.GetURL(ctx, shortURL)
+	}
+
+	service.flushClicks(ctx)
+	service.flushClicks(ctx)
+}
+
+func Test_service_flushClicks_restoresOnError(t *testing.T) {
+	service := setupService()
+
+	ctrl := gomock.NewController(t)
+	storage := mocks.NewMockRepository(ctrl)
+
+	gomock.InOrder(
+		storage.EXPECT().AddClicks(gomock.Any(), map[string]int64{"abc": 2}).Return(nil, errInternal),
+		storage.EXPECT().AddClicks(gomock.Any(), map[string]int64{"abc": 3, "def": 1}).Return(nil, nil),
+	)
+
+	service.Storage = storage
+
+	service.clicks.add("abc")
+	service.clicks.add("abc")
+	service.flushClicks(context.Background())
+
+	service.clicks.add("abc")
+	service.clicks.add("def")
+	service.flushClicks(context.Background())
+}
+
+func TestService_MaintainLinks(t *testing.T) {
+	service := setupService()
+	service.expiryInterval = time.Millisecond
+	service.flushInterval = time.Hour
+
+	ctrl := gomock.NewController(t)
+	storage := mocks.NewMockRepository(ctrl)
+
+	expired := make(chan struct{})
+	storage.EXPECT().
+		ExpireURLs(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(context.Context, time.Time) ([]entity.URLRecord, error) {
+			select {
+			case expired <- struct{}{}:
+			default:
+			}
+
+			return nil, nil
+		}).
+		AnyTimes()
+	// The clicks counted before the context is done are stored on the way out.
+	storage.EXPECT().
+		AddClicks(gomock.Any(), map[string]int64{"abc": 1}).
+		Return(nil, nil)
+
+	service.Storage = storage
+	service.clicks.add("abc")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		service.MaintainLinks(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-expired:
+	case <-time.After(time.Second):
+		t.Fatal("links were not expired")
+	}
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("maintenance did not stop")
+	}
+}
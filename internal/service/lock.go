@@ -0,0 +1,45 @@
+package service
+
+import "sync"
+
+// userLocks holds a mutex per user, so that the operations of a user can be serialized
+// without blocking the ones of the other users.
+type userLocks struct {
+	mu    sync.Mutex
+	locks map[string]*userLock
+}
+
+type userLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+func newUserLocks() *userLocks {
+	return &userLocks{locks: make(map[string]*userLock)}
+}
+
+// lock locks the mutex of the user and returns the function that unlocks it. The mutex is dropped
+// once nobody holds or waits for it.
+func (l *userLocks) lock(userID string) func() {
+	l.mu.Lock()
+	ul, ok := l.locks[userID]
+	if !ok {
+		ul = &userLock{}
+		l.locks[userID] = ul
+	}
+	ul.refs++
+	l.mu.Unlock()
+
+	ul.mu.Lock()
+
+	return func() {
+		ul.mu.Unlock()
+
+		l.mu.Lock()
+		ul.refs--
+		if ul.refs == 0 {
+			delete(l.locks, userID)
+		}
+		l.mu.Unlock()
+	}
+}
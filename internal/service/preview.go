@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// PreviewFetcher is an interface for fetching Open Graph previews of destination pages.
+type PreviewFetcher interface {
+	Fetch(ctx context.Context, url string) (*entity.Preview, error)
+}
+
+type previewTask struct {
+	ShortURL    string
+	OriginalURL string
+}
+
+// enqueuePreview passes the URL to the preview worker. The task is dropped
+// if the queue is full, so creating URLs never waits on slow destinations.
+func (s *service) enqueuePreview(shortURL, originalURL string) {
+	if s.previewChan == nil {
+		return
+	}
+
+	select {
+	case s.previewChan <- previewTask{ShortURL: shortURL, OriginalURL: originalURL}:
+	default:
+		s.logger.Debug("preview queue is full, skipping", zap.String("short url", shortURL))
+	}
+}
+
+func (s *service) startPreviewWorker(workers int) {
+	for i := 0; i < workers; i++ {
+		go func() {
+			for task := range s.previewChan {
+				s.handlePreview(task)
+			}
+		}()
+	}
+}
+
+func (s *service) handlePreview(task previewTask) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	preview, err := s.fetcher.Fetch(ctx, task.OriginalURL)
+	if err != nil {
+		s.logger.Debug("cannot fetch preview", zap.Error(err), zap.String("url", task.OriginalURL))
+		return
+	}
+
+	if err := s.Storage.SavePreview(ctx, task.ShortURL, *preview); err != nil {
+		s.logger.Error("cannot save preview", zap.Error(err), zap.String("short url", task.ShortURL))
+	}
+}
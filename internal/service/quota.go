@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/PrahaTurbo/url-shortener/internal/audit"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// Names of the quotas reported by QuotaError.
+const (
+	QuotaLinks     = "links"
+	QuotaBatchSize = "batch_size"
+	QuotaURLLength = "url_length"
+)
+
+// QuotaError is returned when a request exceeds one of the quotas of the user.
+// It matches ErrQuotaExceeded with errors.Is.
+type QuotaError struct {
+	Quota     string
+	Limit     int
+	Requested int
+}
+
+// Error implements the error interface.
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s quota exceeded: requested %d, limit is %d", e.Quota, e.Requested, e.Limit)
+}
+
+// Is reports whether the target is ErrQuotaExceeded.
+func (e *QuotaError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// WithQuotas enables enforcement of the quotas. The defaults apply to every user
+// unless an administrator overrides them for the user.
+func WithQuotas(defaults models.Quota, repo storage.QuotaRepository) Option {
+	return func(s *service) {
+		s.defaultQuota = defaults
+		s.quotas = repo
+	}
+}
+
+// GetUserQuota retrieves the quota applied to the user, either the overridden or the default one.
+func (s *service) GetUserQuota(ctx context.Context, userID string) (*models.Quota, error) {
+	quota, err := s.quotaFor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &quota, nil
+}
+
+// SetUserQuota overrides the default quota for the user. The user in the context, an administrator,
+// is recorded as the actor.
+func (s *service) SetUserQuota(ctx context.Context, userID string, quota models.Quota) error {
+	if s.quotas == nil {
+		return ErrQuotasDisabled
+	}
+
+	if quota.MaxLinks < 0 || quota.MaxBatchSize < 0 || quota.MaxURLLength < 0 {
+		return ErrInvalidQuota
+	}
+
+	actorID, err := extractUserIDFromCtx(ctx)
+	if err != nil {
+		return err
+	}
+
+	before, err := s.auditedQuota(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	q := entity.Quota{
+		UserID:       userID,
+		MaxLinks:     quota.MaxLinks,
+		MaxBatchSize: quota.MaxBatchSize,
+		MaxURLLength: quota.MaxURLLength,
+	}
+
+	if err := s.quotas.SaveQuota(ctx, q); err != nil {
+		return err
+	}
+
+	s.record(ctx, audit.Entry{ActorID: actorID, Action: audit.ActionQuotaSet, Target: userID, Before: before, After: quota})
+
+	return nil
+}
+
+// ResetUserQuota removes the override of the quota for the user, so the default one applies again.
+// The user in the context is recorded as the actor.
+func (s *service) ResetUserQuota(ctx context.Context, userID string) error {
+	if s.quotas == nil {
+		return ErrQuotasDisabled
+	}
+
+	actorID, err := extractUserIDFromCtx(ctx)
+	if err != nil {
+		return err
+	}
+
+	before, err := s.auditedQuota(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.quotas.DeleteQuota(ctx, userID); err != nil {
+		return err
+	}
+
+	s.record(ctx, audit.Entry{ActorID: actorID, Action: audit.ActionQuotaReset, Target: userID, Before: before, After: s.defaultQuota})
+
+	return nil
+}
+
+func (s *service) quotaFor(ctx context.Context, userID string) (models.Quota, error) {
+	if s.quotas == nil {
+		return s.defaultQuota, nil
+	}
+
+	q, err := s.quotas.GetQuota(ctx, userID)
+	if err != nil {
+		return models.Quota{}, err
+	}
+
+	if q == nil {
+		return s.defaultQuota, nil
+	}
+
+	quota := models.Quota{
+		MaxLinks:     q.MaxLinks,
+		MaxBatchSize: q.MaxBatchSize,
+		MaxURLLength: q.MaxURLLength,
+	}
+
+	return quota, nil
+}
+
+// checkRequest checks the original URLs of a request against the quota.
+func checkRequest(quota models.Quota, urls []string) error {
+	if quota.MaxBatchSize > 0 && len(urls) > quota.MaxBatchSize {
+		return &QuotaError{Quota: QuotaBatchSize, Limit: quota.MaxBatchSize, Requested: len(urls)}
+	}
+
+	if quota.MaxURLLength > 0 {
+		for _, url := range urls {
+			if len(url) > quota.MaxURLLength {
+				return &QuotaError{Quota: QuotaURLLength, Limit: quota.MaxURLLength, Requested: len(url)}
+			}
+		}
+	}
+
+	return nil
+}
+
+// lockLinks serializes the requests of the user that check the links quota and save the new links, if the
+// quota limits the links, so that concurrent requests can't all pass the check and exceed the quota together.
+// The returned function must be called once the links are saved. The requests are serialized within
+// the instance, so the links created at the same time through other instances sharing the storage
+// may still exceed the quota.
+func (s *service) lockLinks(quota models.Quota, userID string) func() {
+	if quota.MaxLinks == 0 {
+		return func() {}
+	}
+
+	return s.linkLocks.lock(userID)
+}
+
+// checkLinks checks whether the user may add the number of new links without exceeding the quota.
+func (s *service) checkLinks(ctx context.Context, quota models.Quota, userID string, newLinks int) error {
+	if quota.MaxLinks == 0 || newLinks == 0 {
+		return nil
+	}
+
+	count, err := s.Storage.CountURLsByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if count+newLinks > quota.MaxLinks {
+		return &QuotaError{Quota: QuotaLinks, Limit: quota.MaxLinks, Requested: count + newLinks}
+	}
+
+	return nil
+}
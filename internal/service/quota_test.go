@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/PrahaTurbo/url-shortener/internal/audit"
+	"github.com/PrahaTurbo/url-shortener/internal/auth"
+	"github.com/PrahaTurbo/url-shortener/internal/mocks"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/memory"
+)
+
+func TestService_SaveURL_quotas(t *testing.T) {
+	defaults := models.Quota{MaxLinks: 2, MaxBatchSize: 2, MaxURLLength: 20}
+
+	tests := []struct {
+		name    string
+		url     string
+		prepare func(s *mocks.MockRepository, q *mocks.MockQuotaRepository)
+		want    *QuotaError
+	}{
+		{
+			name: "should save url within the default quota",
+			url:  "https://yandex.ru",
+			prepare: func(s *mocks.MockRepository, q *mocks.MockQuotaRepository) {
+				s.EXPECT().CheckExistence(gomock.Any(), "FgAJzm", "1").Return(errors.New("no url"))
+				q.EXPECT().GetQuota(gomock.Any(), "1").Return(nil, nil)
+				s.EXPECT().CountURLsByUserID(gomock.Any(), "1").Return(1, nil)
+				s.EXPECT().SaveURL(gomock.Any(), gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name: "should reject url exceeding the links quota",
+			url:  "https://yandex.ru",
+			prepare: func(s *mocks.MockRepository, q *mocks.MockQuotaRepository) {
+				s.EXPECT().CheckExistence(gomock.Any(), "FgAJzm", "1").Return(errors.New("no url"))
+				q.EXPECT().GetQuota(gomock.Any(), "1").Return(nil, nil)
+				s.EXPECT().CountURLsByUserID(gomock.Any(), "1").Return(2, nil)
+			},
+			want: &QuotaError{Quota: QuotaLinks, Limit: 2, Requested: 3},
+		},
+		{
+			name: "should reject too long url",
+			url:  "https://yandex.ru/" + strings.Repeat("a", 10),
+			prepare: func(s *mocks.MockRepository, q *mocks.MockQuotaRepository) {
+				s.EXPECT().CheckExistence(gomock.Any(), gomock.Any(), "1").Return(errors.New("no url"))
+				q.EXPECT().GetQuota(gomock.Any(), "1").Return(nil, nil)
+			},
+			want: &QuotaError{Quota: QuotaURLLength, Limit: 20, Requested: 28},
+		},
+		{
+			name: "should apply the quota overridden for the user",
+			url:  "https://yandex.ru",
+			prepare: func(s *mocks.MockRepository, q *mocks.MockQuotaRepository) {
+				s.EXPECT().CheckExistence(gomock.Any(), "FgAJzm", "1").Return(errors.New("no url"))
+				q.EXPECT().GetQuota(gomock.Any(), "1").Return(&entity.Quota{UserID: "1", MaxLinks: 0}, nil)
+				s.EXPECT().SaveURL(gomock.Any(), gomock.Any()).Return(nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			storage := mocks.NewMockRepository(ctrl)
+			quotas := mocks.NewMockQuotaRepository(ctrl)
+			ctx := context.WithValue(context.Background(), auth.UserIDKey, "1")
+
+			tt.prepare(storage, quotas)
+
+			service := setupService()
+			service.Storage = storage
+			WithQuotas(defaults, quotas)(&service)
+
+			_, err := service.SaveURL(ctx, tt.url, models.LinkOptions{})
+
+			if tt.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, ErrQuotaExceeded)
+
+			var qErr *QuotaError
+			require.ErrorAs(t, err, &qErr)
+			assert.Equal(t, tt.want, qErr)
+		})
+	}
+}
+
+func TestService_SaveBatch_quotas(t *testing.T) {
+	batch := []models.BatchRequest{
+		{CorrelationID: "1", OriginalURL: "https://yandex.ru"},
+		{CorrelationID: "2", OriginalURL: "https://ya.ru"},
+		{CorrelationID: "3", OriginalURL: "https://google.com"},
+	}
+
+	tests := []struct {
+		name    string
+		quota   models.Quota
+		prepare func(s *mocks.MockRepository)
+		want    *QuotaError
+	}{
+		{
+			name:    "should reject too large batch before touching storage",
+			quota:   models.Quota{MaxBatchSize: 2},
+			prepare: func(s *mocks.MockRepository) {},
+			want:    &QuotaError{Quota: QuotaBatchSize, Limit: 2, Requested: 3},
+		},
+		{
+			name:  "should count only new links against the links quota",
+			quota: models.Quota{MaxLinks: 3},
+			prepare: func(s *mocks.MockRepository) {
+				s.EXPECT().GetExistingURLs(gomock.Any(), gomock.Any(), "1").Return([]string{"FgAJzm"}, nil)
+				s.EXPECT().CountURLsByUserID(gomock.Any(), "1").Return(1, nil)
+				s.EXPECT().SaveURLBatch(gomock.Any(), gomock.Len(2)).Return(nil)
+			},
+		},
+		{
+			name:  "should reject batch exceeding the links quota",
+			quota: models.Quota{MaxLinks: 3},
+			prepare: func(s *mocks.MockRepository) {
+				s.EXPECT().GetExistingURLs(gomock.Any(), gomock.Any(), "1").Return(nil, nil)
+				s.EXPECT().CountURLsByUserID(gomock.Any(), "1").Return(1, nil)
+			},
+			want: &QuotaError{Quota: QuotaLinks, Limit: 3, Requested: 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			storage := mocks.NewMockRepository(ctrl)
+			ctx := context.WithValue(context.Background(), auth.UserIDKey, "1")
+
+			tt.prepare(storage)
+
+			service := setupService()
+			service.Storage = storage
+			service.defaultQuota = tt.quota
+
+			_, err := service.SaveBatch(ctx, batch)
+
+			if tt.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var qErr *QuotaError
+			require.ErrorAs(t, err, &qErr)
+			assert.Equal(t, tt.want, qErr)
+		})
+	}
+}
+
+func TestService_SetUserQuota(t *testing.T) {
+	tests := []struct {
+		name    string
+		quota   models.Quota
+		prepare func(q *mocks.MockQuotaRepository)
+		wantErr error
+	}{
+		{
+			name:  "should save the quota of the user",
+			quota: models.Quota{MaxLinks: 100},
+			prepare: func(q *mocks.MockQuotaRepository) {
+				q.EXPECT().SaveQuota(gomock.Any(), entity.Quota{UserID: "1", MaxLinks: 100}).Return(nil)
+			},
+		},
+		{
+			name:    "should reject negative limits",
+			quota:   models.Quota{MaxURLLength: -1},
+			prepare: func(q *mocks.MockQuotaRepository) {},
+			wantErr: ErrInvalidQuota,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			quotas := mocks.NewMockQuotaRepository(ctrl)
+
+			tt.prepare(quotas)
+
+			service := setupService()
+			WithQuotas(models.Quota{}, quotas)(&service)
+
+			ctx := context.WithValue(context.Background(), auth.UserIDKey, "admin")
+			err := service.SetUserQuota(ctx, "1", tt.quota)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+
+	t.Run("should record the administrator as the actor", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		quotas := mocks.NewMockQuotaRepository(ctrl)
+		recorder := mocks.NewMockAuditRecorder(ctrl)
+
+		quotas.EXPECT().GetQuota(gomock.Any(), "1").Return(nil, nil)
+		quotas.EXPECT().SaveQuota(gomock.Any(), entity.Quota{UserID: "1", MaxLinks: 100}).Return(nil)
+		recorder.EXPECT().Record(gomock.Any(), audit.Entry{
+			ActorID: "admin",
+			Action:  audit.ActionQuotaSet,
+			Target:  "1",
+			Before:  &models.Quota{},
+			After:   models.Quota{MaxLinks: 100},
+		})
+
+		service := setupService()
+		WithQuotas(models.Quota{}, quotas)(&service)
+		WithAuditLog(recorder)(&service)
+
+		ctx := context.WithValue(context.Background(), auth.UserIDKey, "admin")
+		require.NoError(t, service.SetUserQuota(ctx, "1", models.Quota{MaxLinks: 100}))
+	})
+
+	t.Run("should fail if quotas are disabled", func(t *testing.T) {
+		service := setupService()
+
+		err := service.SetUserQuota(context.Background(), "1", models.Quota{})
+		assert.ErrorIs(t, err, ErrQuotasDisabled)
+	})
+}
+
+func TestService_SaveURL_concurrentLinksQuota(t *testing.T) {
+	service := setupService()
+
+	repo, err := memory.NewInMemStorage("", service.logger)
+	require.NoError(t, err)
+
+	service.Storage = repo
+	WithQuotas(models.Quota{MaxLinks: 3}, nil)(&service)
+
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "1")
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		saved    int
+		exceeded int
+	)
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			_, err := service.SaveURL(ctx, fmt.Sprintf("https://example.com/%d", i), models.LinkOptions{})
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			switch {
+			case err == nil:
+				saved++
+			case errors.Is(err, ErrQuotaExceeded):
+				exceeded++
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, 3, saved)
+	assert.Equal(t, 17, exceeded)
+}
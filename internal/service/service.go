@@ -2,53 +2,95 @@ package service
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 
+	"github.com/PrahaTurbo/url-shortener/internal/audit"
 	"github.com/PrahaTurbo/url-shortener/internal/logger"
 	"github.com/PrahaTurbo/url-shortener/internal/models"
 	"github.com/PrahaTurbo/url-shortener/internal/storage"
 	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+	"github.com/PrahaTurbo/url-shortener/internal/webhook"
 )
 
 // Service is an interface for API that handle URL shortening and associated operations.
+// MaintainLinks runs the expiry of the links and the storing of their clicks until the context is done.
 type Service interface {
-	SaveURL(ctx context.Context, originalURL string) (string, error)
+	SaveURL(ctx context.Context, originalURL string, opts models.LinkOptions) (string, error)
 	SaveBatch(ctx context.Context, batch []models.BatchRequest) ([]models.BatchResponse, error)
 	GetURL(ctx context.Context, shortURL string) (string, error)
 	GetURLsByUserID(ctx context.Context) ([]models.UserURLsResponse, error)
+	Expand(ctx context.Context, shortURL string) (*models.ExpandResponse, error)
 	DeleteURLs(ctx context.Context, urls []string) error
 	GetStats(ctx context.Context) (*models.StatsResponse, error)
+	GetUserQuota(ctx context.Context, userID string) (*models.Quota, error)
+	SetUserQuota(ctx context.Context, userID string, quota models.Quota) error
+	ResetUserQuota(ctx context.Context, userID string) error
 	PingDB() error
+	MaintainLinks(ctx context.Context)
 }
 
 type service struct {
-	Storage   storage.Repository
-	logger    *logger.Logger
-	baseURL   string
-	delChan   chan models.URLDeletionTask
-	semaphore *semaphore
+	Storage        storage.Repository
+	logger         *logger.Logger
+	baseURL        string
+	delChan        chan models.URLDeletionTask
+	semaphore      *semaphore
+	fetcher        PreviewFetcher
+	previewChan    chan previewTask
+	publisher      EventPublisher
+	defaultQuota   models.Quota
+	quotas         storage.QuotaRepository
+	linkLocks      *userLocks
+	audit          audit.Recorder
+	clicks         clickCounter
+	expiryInterval time.Duration
+	flushInterval  time.Duration
+}
+
+// Option configures optional dependencies of the service.
+type Option func(s *service)
+
+// WithPreviewFetcher enables asynchronous fetching of Open Graph previews for newly created URLs.
+func WithPreviewFetcher(f PreviewFetcher) Option {
+	return func(s *service) {
+		s.fetcher = f
+	}
 }
 
 // NewService creates a new instance of the URL service with specified configurations.
-func NewService(baseURL string, storage storage.Repository, logger *logger.Logger) Service {
+func NewService(baseURL string, storage storage.Repository, logger *logger.Logger, opts ...Option) Service {
 	s := &service{
-		Storage:   storage,
-		logger:    logger,
-		baseURL:   baseURL,
-		delChan:   make(chan models.URLDeletionTask, 10),
-		semaphore: newSemaphore(5),
+		Storage:        storage,
+		logger:         logger,
+		baseURL:        baseURL,
+		delChan:        make(chan models.URLDeletionTask, 10),
+		semaphore:      newSemaphore(5),
+		linkLocks:      newUserLocks(),
+		expiryInterval: linkExpiryInterval,
+		flushInterval:  clickFlushInterval,
+	}
+
+	for _, opt := range opts {
+		opt(s)
 	}
 
 	go s.startURLDeletionWorker(time.Second*10, 100)
 
+	if s.fetcher != nil {
+		s.previewChan = make(chan previewTask, 100)
+		go s.startPreviewWorker(5)
+	}
+
 	return s
 }
 
-// SaveURL saves an original URL, provides a shortened version, and returns it.
-func (s *service) SaveURL(ctx context.Context, originalURL string) (string, error) {
+// SaveURL saves an original URL with the options of the link, provides a shortened version, and returns it.
+// The options are ignored if the user already has the URL.
+func (s *service) SaveURL(ctx context.Context, originalURL string, opts models.LinkOptions) (string, error) {
 	shortURL := generateShortURL(originalURL)
 
 	userID, err := extractUserIDFromCtx(ctx)
@@ -56,21 +98,46 @@ func (s *service) SaveURL(ctx context.Context, originalURL string) (string, erro
 		return "", err
 	}
 
+	if err = checkLinkOptions(opts, time.Now()); err != nil {
+		return "", err
+	}
+
 	if s.alreadyInStorage(ctx, shortURL, userID) {
 		return formURL(s.baseURL, shortURL), ErrAlready
 	}
 
-	r := entity.URLRecord{
-		UUID:        uuid.New().String(),
-		ShortURL:    shortURL,
-		OriginalURL: originalURL,
-		UserID:      userID,
+	quota, err := s.quotaFor(ctx, userID)
+	if err != nil {
+		return "", err
 	}
 
+	if err = checkRequest(quota, []string{originalURL}); err != nil {
+		return "", err
+	}
+
+	unlock := s.lockLinks(quota, userID)
+	defer unlock()
+
+	if err = s.checkLinks(ctx, quota, userID, 1); err != nil {
+		return "", err
+	}
+
+	r := newRecord(shortURL, originalURL, userID, opts)
+	r.UUID = uuid.New().String()
+
 	if err = s.Storage.SaveURL(ctx, r); err != nil {
+		if errors.Is(err, storage.ErrURLExists) {
+			// A concurrent request of the user saved the URL after the check above.
+			return formURL(s.baseURL, shortURL), ErrAlready
+		}
+
 		return "", err
 	}
 
+	s.enqueuePreview(shortURL, originalURL)
+	s.publish(userID, webhook.EventLinkCreated, shortURL, originalURL)
+	s.record(ctx, audit.Entry{ActorID: userID, Action: audit.ActionLinkCreate, Target: shortURL, After: r})
+
 	return formURL(s.baseURL, shortURL), nil
 }
 
@@ -84,40 +151,89 @@ func (s *service) SaveBatch(ctx context.Context, batch []models.BatchRequest) ([
 		return nil, err
 	}
 
+	quota, err := s.quotaFor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := make([]string, 0, len(batch))
+	for _, req := range batch {
+		urls = append(urls, req.OriginalURL)
+	}
+
+	if err = checkRequest(quota, urls); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	shortURLs := make([]string, 0, len(batch))
 	for _, req := range batch {
 		if req.OriginalURL == "" {
 			return nil, ErrNoOriginalURL
 		}
 
+		if err = checkLinkOptions(req.LinkOptions, now); err != nil {
+			return nil, err
+		}
+
 		shortURL := generateShortURL(req.OriginalURL)
+		shortURLs = append(shortURLs, shortURL)
 
 		var res models.BatchResponse
 		res.CorrelationID = req.CorrelationID
 		res.ShortURL = formURL(s.baseURL, shortURL)
 		response = append(response, res)
+	}
 
-		if s.alreadyInStorage(ctx, shortURL, userID) {
+	unlock := s.lockLinks(quota, userID)
+	defer unlock()
+
+	// The URLs the user already has are looked up for the whole batch at once.
+	existing, err := s.Storage.GetExistingURLs(ctx, shortURLs, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(batch))
+	for _, shortURL := range existing {
+		seen[shortURL] = true
+	}
+
+	for i, req := range batch {
+		shortURL := shortURLs[i]
+		if seen[shortURL] {
 			continue
 		}
 
-		r := &entity.URLRecord{
-			UUID:        uuid.New().String(),
-			ShortURL:    shortURL,
-			OriginalURL: req.OriginalURL,
-			UserID:      userID,
-		}
+		seen[shortURL] = true
 
-		records = append(records, r)
+		r := newRecord(shortURL, req.OriginalURL, userID, req.LinkOptions)
+		r.UUID = uuid.New().String()
+
+		records = append(records, &r)
+	}
+
+	if err = s.checkLinks(ctx, quota, userID, len(records)); err != nil {
+		return nil, err
 	}
 
 	if err := s.Storage.SaveURLBatch(ctx, records); err != nil {
 		return nil, err
 	}
 
+	entries := make([]audit.Entry, 0, len(records))
+	for _, r := range records {
+		s.enqueuePreview(r.ShortURL, r.OriginalURL)
+		s.publish(userID, webhook.EventLinkCreated, r.ShortURL, r.OriginalURL)
+		entries = append(entries, audit.Entry{ActorID: userID, Action: audit.ActionLinkCreate, Target: r.ShortURL, After: r})
+	}
+
+	s.recordBatch(ctx, entries)
+
 	return response, nil
 }
 
-// GetURL retrieves the original URL given the shortened one.
+// GetURL retrieves the original URL given the shortened one and counts a click of the link.
 // If the shortened URL is not registered in the service, the error will be returned.
 func (s *service) GetURL(ctx context.Context, shortURL string) (string, error) {
 	originalURL, err := s.Storage.GetURL(ctx, shortURL)
@@ -125,10 +241,13 @@ func (s *service) GetURL(ctx context.Context, shortURL string) (string, error) {
 		return "", err
 	}
 
+	s.clicks.add(shortURL)
+
 	return originalURL, nil
 }
 
-// GetURLsByUserID retrieves all the URLs associated with a specific user.
+// GetURLsByUserID retrieves all the live URLs associated with a specific user, skipping the deleted and expired ones.
+// It returns storage.ErrURLNotFound if the user has none.
 func (s *service) GetURLsByUserID(ctx context.Context) ([]models.UserURLsResponse, error) {
 	userID, err := extractUserIDFromCtx(ctx)
 	if err != nil {
@@ -142,18 +261,59 @@ func (s *service) GetURLsByUserID(ctx context.Context) ([]models.UserURLsRespons
 
 	var response []models.UserURLsResponse
 
+	now := time.Now()
 	for _, record := range records {
+		if record.Gone(now) {
+			continue
+		}
+
 		r := models.UserURLsResponse{
-			ShortURL:    formURL(s.baseURL, record.ShortURL),
-			OriginalURL: record.OriginalURL,
+			ShortURL:       formURL(s.baseURL, record.ShortURL),
+			OriginalURL:    record.OriginalURL,
+			Disabled:       record.Disabled,
+			WorkspaceID:    record.WorkspaceID,
+			ExpiresAt:      record.ExpiresAt,
+			Clicks:         record.Clicks,
+			ClickThreshold: record.ClickThreshold,
+		}
+
+		if record.Preview != nil {
+			r.Title = record.Preview.Title
+			r.Description = record.Preview.Description
+			r.Image = record.Preview.Image
 		}
 
 		response = append(response, r)
 	}
 
+	if len(response) == 0 {
+		return nil, storage.ErrURLNotFound
+	}
+
 	return response, nil
 }
 
+// Expand retrieves the original URL given the shortened one alongside the preview of its destination.
+func (s *service) Expand(ctx context.Context, shortURL string) (*models.ExpandResponse, error) {
+	record, err := s.Storage.GetURLRecord(ctx, shortURL)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &models.ExpandResponse{
+		ShortURL:    formURL(s.baseURL, record.ShortURL),
+		OriginalURL: record.OriginalURL,
+	}
+
+	if record.Preview != nil {
+		resp.Title = record.Preview.Title
+		resp.Description = record.Preview.Description
+		resp.Image = record.Preview.Image
+	}
+
+	return resp, nil
+}
+
 // DeleteURLs initiates the process of deleting a set of URLs, passes models.URLDeletionTask to
 // deletion channel.
 func (s *service) DeleteURLs(ctx context.Context, urls []string) error {
@@ -165,6 +325,7 @@ func (s *service) DeleteURLs(ctx context.Context, urls []string) error {
 	task := models.URLDeletionTask{
 		UserID: userID,
 		URLs:   urls,
+		Source: audit.SourceFromContext(ctx),
 	}
 
 	s.delChan <- task
@@ -189,6 +350,14 @@ func (s *service) GetStats(ctx context.Context) (*models.StatsResponse, error) {
 		Users: stats.Users,
 	}
 
+	if stats.Cache != nil {
+		resp.Cache = &models.CacheStatsResponse{
+			Hits:   stats.Cache.Hits,
+			Misses: stats.Cache.Misses,
+			Size:   stats.Cache.Size,
+		}
+	}
+
 	return resp, nil
 }
 
@@ -219,14 +388,19 @@ func (s *service) handleDeletion(tasks []models.URLDeletionTask) {
 	for _, task := range tasks {
 		s.semaphore.acquire()
 
-		go func(urls []string, user string) {
+		go func(urls []string, user string, src models.RequestSource) {
 			defer s.semaphore.release()
 
-			if err := s.Storage.DeleteURLBatch(urls, user); err != nil {
+			ctx := audit.WithSource(context.Background(), src)
+			deleted, err := s.Storage.DeleteURLBatch(urls, user)
+			if err != nil {
 				s.logger.Error("cannot delete batch urls", zap.Error(err), zap.String("user id", user))
+				return
 			}
 
-		}(task.URLs, task.UserID)
+			s.publishDeleted(deleted)
+			s.recordDeleted(ctx, user, deleted)
+		}(task.URLs, task.UserID, task.Source)
 	}
 }
 
@@ -11,11 +11,14 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 
+	"github.com/PrahaTurbo/url-shortener/internal/audit"
 	"github.com/PrahaTurbo/url-shortener/internal/auth"
 	"github.com/PrahaTurbo/url-shortener/internal/logger"
 	"github.com/PrahaTurbo/url-shortener/internal/mocks"
 	"github.com/PrahaTurbo/url-shortener/internal/models"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
 	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/memory"
 )
 
 type badContextKey string
@@ -27,8 +30,9 @@ func setupService() service {
 	log, _ := logger.Initialize("debug")
 
 	return service{
-		baseURL: baseURL,
-		logger:  log,
+		baseURL:   baseURL,
+		logger:    log,
+		linkLocks: newUserLocks(),
 	}
 }
 
@@ -117,7 +121,7 @@ func TestService_SaveURL(t *testing.T) {
 			tt.prepare(storage)
 			service.Storage = storage
 
-			shortURL, err := service.SaveURL(ctx, tt.url)
+			shortURL, err := service.SaveURL(ctx, tt.url, models.LinkOptions{})
 
 			if tt.want.err != nil {
 				assert.Equal(t, tt.want.err, err)
@@ -222,8 +226,8 @@ func TestService_SaveBatch(t *testing.T) {
 			batchReq: batch,
 			prepare: func(s *mocks.MockRepository) {
 				s.EXPECT().
-					CheckExistence(gomock.Any(), gomock.Any(), gomock.Any()).
-					Return(errors.New("no url")).AnyTimes()
+					GetExistingURLs(gomock.Any(), []string{"fpCk-c", "FgAJzm"}, "1").
+					Return(nil, nil)
 				s.EXPECT().
 					SaveURLBatch(gomock.Any(), gomock.Any()).
 					Return(nil)
@@ -246,11 +250,8 @@ func TestService_SaveBatch(t *testing.T) {
 			batchReq: batch,
 			prepare: func(s *mocks.MockRepository) {
 				s.EXPECT().
-					CheckExistence(gomock.Any(), gomock.Any(), gomock.Any()).
-					Return(errors.New("no url"))
-				s.EXPECT().
-					CheckExistence(gomock.Any(), gomock.Any(), gomock.Any()).
-					Return(nil)
+					GetExistingURLs(gomock.Any(), []string{"fpCk-c", "FgAJzm"}, "1").
+					Return([]string{"FgAJzm"}, nil)
 				s.EXPECT().
 					SaveURLBatch(gomock.Any(), gomock.Len(1)).
 					Return(nil)
@@ -273,8 +274,8 @@ func TestService_SaveBatch(t *testing.T) {
 			batchReq: batch,
 			prepare: func(s *mocks.MockRepository) {
 				s.EXPECT().
-					CheckExistence(gomock.Any(), gomock.Any(), gomock.Any()).
-					Return(errors.New("no url")).AnyTimes()
+					GetExistingURLs(gomock.Any(), gomock.Any(), "1").
+					Return(nil, nil)
 				s.EXPECT().
 					SaveURLBatch(gomock.Any(), gomock.Any()).
 					Return(errInternal)
@@ -283,6 +284,18 @@ func TestService_SaveBatch(t *testing.T) {
 				err: errInternal,
 			},
 		},
+		{
+			name:     "should fail if existing urls can't be looked up",
+			batchReq: batch,
+			prepare: func(s *mocks.MockRepository) {
+				s.EXPECT().
+					GetExistingURLs(gomock.Any(), gomock.Any(), "1").
+					Return(nil, errInternal)
+			},
+			want: want{
+				err: errInternal,
+			},
+		},
 		{
 			name:     "should return error if can't extract user id from context",
 			batchReq: batch,
@@ -409,6 +422,200 @@ func TestService_GetURLsByUserID(t *testing.T) {
 	}
 }
 
+func TestService_GetURLsByUserID_skipsGone(t *testing.T) {
+	service := setupService()
+
+	repo, err := memory.NewInMemStorage("", service.logger)
+	require.NoError(t, err)
+
+	service.Storage = repo
+
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "1")
+
+	kept, err := service.SaveURL(ctx, "https://ya.ru", models.LinkOptions{})
+	require.NoError(t, err)
+
+	_, err = service.SaveURL(ctx, "https://yandex.ru", models.LinkOptions{})
+	require.NoError(t, err)
+
+	past := time.Now().Add(-time.Minute)
+	require.NoError(t, repo.SaveURL(ctx, entity.URLRecord{
+		UUID: "expired", ShortURL: "expired", OriginalURL: "https://expired.example.com", UserID: "1", ExpiresAt: &past,
+	}))
+
+	_, err = repo.DeleteURLBatch([]string{generateShortURL("https://yandex.ru")}, "1")
+	require.NoError(t, err)
+
+	resp, err := service.GetURLsByUserID(ctx)
+	require.NoError(t, err)
+	require.Len(t, resp, 1, "the deleted and expired links must not be listed")
+	assert.Equal(t, kept, resp[0].ShortURL)
+
+	_, err = repo.DeleteURLBatch([]string{generateShortURL("https://ya.ru")}, "1")
+	require.NoError(t, err)
+
+	_, err = service.GetURLsByUserID(ctx)
+	assert.ErrorIs(t, err, storage.ErrURLNotFound, "a user left with no live links has none to list")
+}
+
+func TestService_Expand(t *testing.T) {
+	service := setupService()
+
+	type want struct {
+		resp *models.ExpandResponse
+		err  error
+	}
+
+	tests := []struct {
+		name     string
+		shortURL string
+		prepare  func(s *mocks.MockRepository)
+		want     want
+	}{
+		{
+			name:     "should expand url with preview successfully",
+			shortURL: "FgAJzm",
+			prepare: func(s *mocks.MockRepository) {
+				s.EXPECT().
+					GetURLRecord(gomock.Any(), "FgAJzm").
+					Return(&entity.URLRecord{
+						ShortURL:    "FgAJzm",
+						OriginalURL: "https://yandex.ru",
+						Preview: &entity.Preview{
+							Title:       "Yandex",
+							Description: "Search",
+							Image:       "https://yandex.ru/logo.png",
+						},
+					}, nil)
+			},
+			want: want{
+				resp: &models.ExpandResponse{
+					ShortURL:    baseURL + "/FgAJzm",
+					OriginalURL: "https://yandex.ru",
+					Title:       "Yandex",
+					Description: "Search",
+					Image:       "https://yandex.ru/logo.png",
+				},
+			},
+		},
+		{
+			name:     "should expand url without preview",
+			shortURL: "FgAJzm",
+			prepare: func(s *mocks.MockRepository) {
+				s.EXPECT().
+					GetURLRecord(gomock.Any(), "FgAJzm").
+					Return(&entity.URLRecord{
+						ShortURL:    "FgAJzm",
+						OriginalURL: "https://yandex.ru",
+					}, nil)
+			},
+			want: want{
+				resp: &models.ExpandResponse{
+					ShortURL:    baseURL + "/FgAJzm",
+					OriginalURL: "https://yandex.ru",
+				},
+			},
+		},
+		{
+			name:     "should fail if url not found",
+			shortURL: "abc",
+			prepare: func(s *mocks.MockRepository) {
+				s.EXPECT().
+					GetURLRecord(gomock.Any(), "abc").
+					Return(nil, errInternal)
+			},
+			want: want{
+				err: errInternal,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			storage := mocks.NewMockRepository(ctrl)
+
+			tt.prepare(storage)
+			service.Storage = storage
+
+			resp, err := service.Expand(context.Background(), tt.shortURL)
+
+			assert.Equal(t, tt.want.err, err)
+			assert.Equal(t, tt.want.resp, resp)
+		})
+	}
+}
+
+func Test_service_handlePreview(t *testing.T) {
+	service := setupService()
+
+	task := previewTask{ShortURL: "FgAJzm", OriginalURL: "https://yandex.ru"}
+	preview := &entity.Preview{Title: "Yandex"}
+
+	tests := []struct {
+		name    string
+		prepare func(s *mocks.MockRepository, f *mocks.MockPreviewFetcher)
+	}{
+		{
+			name: "should save fetched preview",
+			prepare: func(s *mocks.MockRepository, f *mocks.MockPreviewFetcher) {
+				f.EXPECT().
+					Fetch(gomock.Any(), "https://yandex.ru").
+					Return(preview, nil)
+				s.EXPECT().
+					SavePreview(gomock.Any(), "FgAJzm", *preview).
+					Return(nil)
+			},
+		},
+		{
+			name: "should not save preview if fetch fails",
+			prepare: func(s *mocks.MockRepository, f *mocks.MockPreviewFetcher) {
+				f.EXPECT().
+					Fetch(gomock.Any(), "https://yandex.ru").
+					Return(nil, errInternal)
+			},
+		},
+		{
+			name: "should log error if preview cannot be saved",
+			prepare: func(s *mocks.MockRepository, f *mocks.MockPreviewFetcher) {
+				f.EXPECT().
+					Fetch(gomock.Any(), "https://yandex.ru").
+					Return(preview, nil)
+				s.EXPECT().
+					SavePreview(gomock.Any(), "FgAJzm", *preview).
+					Return(errInternal)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			storage := mocks.NewMockRepository(ctrl)
+			fetcher := mocks.NewMockPreviewFetcher(ctrl)
+
+			tt.prepare(storage, fetcher)
+			service.Storage = storage
+			service.fetcher = fetcher
+
+			service.handlePreview(task)
+		})
+	}
+}
+
+func Test_service_enqueuePreview(t *testing.T) {
+	service := setupService()
+
+	service.enqueuePreview("FgAJzm", "https://yandex.ru")
+
+	service.previewChan = make(chan previewTask, 1)
+	service.enqueuePreview("FgAJzm", "https://yandex.ru")
+	service.enqueuePreview("fpCk-c", "https://ya.ru")
+
+	require.Len(t, service.previewChan, 1)
+	assert.Equal(t, previewTask{ShortURL: "FgAJzm", OriginalURL: "https://yandex.ru"}, <-service.previewChan)
+}
+
 func TestService_PingDB(t *testing.T) {
 	service := setupService()
 
@@ -575,7 +782,7 @@ func Test_service_handleDeletion(t *testing.T) {
 			prepare: func(s *mocks.MockRepository) {
 				s.EXPECT().
 					DeleteURLBatch(gomock.Any(), gomock.Any()).
-					Return(nil).
+					Return(nil, nil).
 					Times(2)
 			},
 		},
@@ -584,10 +791,10 @@ func Test_service_handleDeletion(t *testing.T) {
 			prepare: func(s *mocks.MockRepository) {
 				s.EXPECT().
 					DeleteURLBatch(gomock.Any(), gomock.Any()).
-					Return(nil)
+					Return(nil, nil)
 				s.EXPECT().
 					DeleteURLBatch(gomock.Any(), gomock.Any()).
-					Return(errInternal)
+					Return(nil, errInternal)
 			},
 		},
 	}
@@ -612,6 +819,124 @@ func Test_service_handleDeletion(t *testing.T) {
 	}
 }
 
+func Test_service_handleDeletion_publishesEvents(t *testing.T) {
+	service := setupService()
+
+	ctrl := gomock.NewController(t)
+	storage := mocks.NewMockRepository(ctrl)
+	publisher := mocks.NewMockEventPublisher(ctrl)
+
+	done := make(chan struct{})
+
+	// The link of the workspace is deleted by a member, so its owner is notified.
+	gomock.InOrder(
+		storage.EXPECT().
+			DeleteURLBatch([]string{"abc", "xyz"}, "testuser").
+			Return([]entity.URLRecord{
+				{ShortURL: "abc", OriginalURL: "https://abc.example", UserID: "testuser", DeletedFlag: true},
+				{ShortURL: "xyz", OriginalURL: "https://xyz.example", UserID: "owner", WorkspaceID: "ws", DeletedFlag: true},
+			}, nil),
+		publisher.EXPECT().
+			Publish("testuser", "link.deleted", models.LinkEvent{ShortURL: baseURL + "/abc", OriginalURL: "https://abc.example"}),
+		publisher.EXPECT().
+			Publish("owner", "link.deleted", models.LinkEvent{ShortURL: baseURL + "/xyz", OriginalURL: "https://xyz.example"}).
+			Do(func(string, string, models.LinkEvent) { close(done) }),
+	)
+
+	service.Storage = storage
+	service.publisher = publisher
+	service.semaphore = newSemaphore(5)
+
+	service.handleDeletion([]models.URLDeletionTask{{UserID: "testuser", URLs: []string{"abc", "xyz"}}})
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("deletion event was not published")
+	}
+}
+
+func TestService_SaveBatch_recordsAudit(t *testing.T) {
+	service := setupService()
+
+	ctrl := gomock.NewController(t)
+	storage := mocks.NewMockRepository(ctrl)
+	recorder := mocks.NewMockAuditRecorder(ctrl)
+
+	batch := []models.BatchRequest{
+		{CorrelationID: "1", OriginalURL: "https://ya.ru"},
+		{CorrelationID: "2", OriginalURL: "https://yandex.ru"},
+	}
+
+	storage.EXPECT().
+		GetExistingURLs(gomock.Any(), []string{"fpCk-c", "FgAJzm"}, "1").
+		Return(nil, nil)
+	storage.EXPECT().
+		SaveURLBatch(gomock.Any(), gomock.Len(2)).
+		Return(nil)
+	recorder.EXPECT().
+		RecordBatch(gomock.Any(), gomock.Len(2)).
+		Do(func(_ context.Context, entries []audit.Entry) {
+			for i, target := range []string{"fpCk-c", "FgAJzm"} {
+				assert.Equal(t, audit.ActionLinkCreate, entries[i].Action)
+				assert.Equal(t, "1", entries[i].ActorID)
+				assert.Equal(t, target, entries[i].Target)
+			}
+		})
+
+	service.Storage = storage
+	service.audit = recorder
+
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "1")
+	_, err := service.SaveBatch(ctx, batch)
+	require.NoError(t, err)
+}
+
+func Test_service_handleDeletion_recordsAudit(t *testing.T) {
+	service := setupService()
+
+	ctrl := gomock.NewController(t)
+	storage := mocks.NewMockRepository(ctrl)
+	recorder := mocks.NewMockAuditRecorder(ctrl)
+
+	done := make(chan struct{})
+
+	abc := entity.URLRecord{UUID: "1", ShortURL: "abc", OriginalURL: "https://abc.example", UserID: "testuser"}
+	deleted := abc
+	deleted.DeletedFlag = true
+	src := models.RequestSource{Transport: audit.TransportHTTP, ClientIP: "192.0.2.1", RequestID: "req-1"}
+
+	gomock.InOrder(
+		storage.EXPECT().
+			DeleteURLBatch([]string{"abc", "xyz"}, "testuser").
+			Return([]entity.URLRecord{deleted}, nil),
+		recorder.EXPECT().
+			RecordBatch(gomock.Any(), []audit.Entry{{
+				ActorID: "testuser",
+				Action:  audit.ActionLinkDelete,
+				Target:  "abc",
+				Before:  abc,
+				After:   deleted,
+			}}).
+			Do(func(ctx context.Context, _ []audit.Entry) {
+				assert.Equal(t, src, audit.SourceFromContext(ctx))
+				close(done)
+			}),
+	)
+
+	service.Storage = storage
+	service.audit = recorder
+	service.semaphore = newSemaphore(5)
+
+	service.handleDeletion([]models.URLDeletionTask{{UserID: "testuser", URLs: []string{"abc", "xyz"}, Source: src}})
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("deletion was not recorded")
+	}
+}
+
 func BenchmarkService_SaveBatch(b *testing.B) {
 	service := setupService()
 	ctrl := gomock.NewController(b)
@@ -619,8 +944,8 @@ func BenchmarkService_SaveBatch(b *testing.B) {
 	ctx := context.WithValue(context.Background(), auth.UserIDKey, "1")
 
 	storage.EXPECT().
-		CheckExistence(gomock.Any(), gomock.Any(), gomock.Any()).
-		Return(nil).AnyTimes()
+		GetExistingURLs(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(nil, nil).AnyTimes()
 	storage.EXPECT().
 		SaveURLBatch(gomock.Any(), gomock.Any()).
 		Return(nil).AnyTimes()
@@ -653,7 +978,7 @@ func BenchmarkService_DeleteURLs(b *testing.B) {
 
 	storage.EXPECT().
 		DeleteURLBatch(gomock.Any(), gomock.Any()).
-		Return(nil).AnyTimes()
+		Return(nil, nil).AnyTimes()
 
 	service := NewService(baseURL, storage, log)
 
@@ -12,9 +12,14 @@ import (
 
 // Error variables, used in the service
 var (
-	ErrExtractFromContext = errors.New("cannot extract userID from context")
-	ErrAlready            = errors.New("URL already in storage")
-	ErrNoOriginalURL      = errors.New("no url in original_url field")
+	ErrExtractFromContext    = errors.New("cannot extract userID from context")
+	ErrAlready               = errors.New("URL already in storage")
+	ErrNoOriginalURL         = errors.New("no url in original_url field")
+	ErrQuotaExceeded         = errors.New("quota exceeded")
+	ErrInvalidQuota          = errors.New("quota limits must not be negative")
+	ErrQuotasDisabled        = errors.New("quotas are not enabled")
+	ErrInvalidExpiry         = errors.New("link must expire in the future")
+	ErrInvalidClickThreshold = errors.New("click threshold must not be negative")
 )
 
 func extractUserIDFromCtx(ctx context.Context) (string, error) {
@@ -0,0 +1,107 @@
+package bolt
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// The API keys are stored by ID in the api_keys bucket, and the hashes index maps the hash of a key to its ID.
+var (
+	apiKeysBucket     = []byte("api_keys")
+	apiKeyHashesIndex = []byte("api_key_hashes_idx")
+)
+
+// APIKeyStorage is a struct that implements the storage.APIKeyRepository interface, using an embedded bbolt database.
+type APIKeyStorage struct {
+	kv
+}
+
+// NewAPIKeyStorage initializes a new APIKeyStorage instance and creates its buckets if they don't exist.
+func NewAPIKeyStorage(db *bbolt.DB) (storage.APIKeyRepository, error) {
+	s, err := newKV(db, apiKeysBucket, apiKeyHashesIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	return &APIKeyStorage{kv: s}, nil
+}
+
+// SaveAPIKey stores a new API key in the database.
+func (s *APIKeyStorage) SaveAPIKey(ctx context.Context, key entity.APIKey) error {
+	return s.update(ctx, func(tx *bbolt.Tx) error {
+		if err := putJSON(tx.Bucket(apiKeysBucket), []byte(key.ID), key); err != nil {
+			return err
+		}
+
+		return tx.Bucket(apiKeyHashesIndex).Put([]byte(key.Hash), []byte(key.ID))
+	})
+}
+
+// GetAPIKeyByHash retrieves the API key with the given hash from the database. It returns nil if there is no such key.
+func (s *APIKeyStorage) GetAPIKeyByHash(ctx context.Context, hash string) (*entity.APIKey, error) {
+	var key *entity.APIKey
+	err := s.view(ctx, func(tx *bbolt.Tx) error {
+		id := tx.Bucket(apiKeyHashesIndex).Get([]byte(hash))
+		if id == nil {
+			return nil
+		}
+
+		var k entity.APIKey
+		ok, err := getJSON(tx.Bucket(apiKeysBucket), id, &k)
+		if ok {
+			key = &k
+		}
+
+		return err
+	})
+
+	return key, err
+}
+
+// GetAPIKeysByUserID retrieves all the API keys of a specific user from the database,
+// including the revoked ones, oldest first.
+func (s *APIKeyStorage) GetAPIKeysByUserID(ctx context.Context, userID string) ([]entity.APIKey, error) {
+	var keys []entity.APIKey
+	err := s.view(ctx, func(tx *bbolt.Tx) error {
+		var err error
+		keys, err = scanJSON(tx.Bucket(apiKeysBucket), nil, func(k entity.APIKey) bool {
+			return k.UserID == userID
+		})
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortByCreation(keys, func(k entity.APIKey) time.Time { return k.CreatedAt })
+
+	return keys, nil
+}
+
+// RevokeAPIKey marks the API key of a specific user as revoked in the database.
+func (s *APIKeyStorage) RevokeAPIKey(ctx context.Context, id, userID string) error {
+	return s.update(ctx, func(tx *bbolt.Tx) error {
+		keys := tx.Bucket(apiKeysBucket)
+
+		var k entity.APIKey
+		ok, err := getJSON(keys, []byte(id), &k)
+		if err != nil {
+			return err
+		}
+
+		if !ok || k.UserID != userID {
+			return errors.New("api key not found")
+		}
+
+		k.Revoked = true
+
+		return putJSON(keys, []byte(id), k)
+	})
+}
@@ -0,0 +1,945 @@
+// Package bolt implements the URL repository and the repositories of the accounts, API keys, refresh tokens,
+// quotas, webhooks and workspaces on an embedded bbolt key-value store, for single-binary deployments
+// without a PostgreSQL database. The repositories may share one database, each with its own buckets.
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// The records are stored by UUID in the urls bucket. The index buckets map the short URL, the user
+// and the workspace of a record, followed by its sequence number, to its UUID, so that scanning
+// a prefix of an index returns the records in the order they were created. The expiry index maps
+// the expiry of the records that expire and aren't deleted yet, followed by the sequence number,
+// to the UUID, so that the expired records are the ones up to the current time.
+var (
+	urlsBucket      = []byte("urls")
+	shortURLsIndex  = []byte("short_urls_idx")
+	usersIndex      = []byte("users_idx")
+	workspacesIndex = []byte("workspaces_idx")
+	expiriesIndex   = []byte("expiries_idx")
+	allBuckets      = [][]byte{urlsBucket, shortURLsIndex, usersIndex, workspacesIndex, expiriesIndex}
+)
+
+// record is the stored form of a URL record. Seq orders the records by creation.
+type record struct {
+	entity.URLRecord
+	Seq uint64 `json:"seq"`
+}
+
+// BoltStorage is a struct that implements the storage.Repository interface, using an embedded bbolt database.
+// Every change is committed in a single transaction, so a batch of URLs is stored entirely or not at all.
+type BoltStorage struct {
+	kv
+	workspaces storage.WorkspaceRepository
+	logger     *logger.Logger
+}
+
+// Option configures optional features of the BoltStorage.
+type Option func(s *BoltStorage)
+
+// WithWorkspaces resolves the URLs a user can view and delete through the memberships
+// of the user in the workspaces of the repo.
+func WithWorkspaces(repo storage.WorkspaceRepository) Option {
+	return func(s *BoltStorage) {
+		s.workspaces = repo
+	}
+}
+
+// indexExpiries creates the expiry index of a database with records but without the index.
+func indexExpiries(db *bbolt.DB) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		urls := tx.Bucket(urlsBucket)
+		if urls == nil || tx.Bucket(expiriesIndex) != nil {
+			return nil
+		}
+
+		index, err := tx.CreateBucket(expiriesIndex)
+		if err != nil {
+			return err
+		}
+
+		return urls.ForEach(func(_, v []byte) error {
+			var r record
+			if err := json.Unmarshal(v, &r); err != nil {
+				return err
+			}
+
+			if key, ok := expiryKey(r); ok {
+				return index.Put(key, []byte(r.UUID))
+			}
+
+			return nil
+		})
+	})
+}
+
+// Open opens the bbolt database at the path, creating it if it doesn't exist. It fails if another process
+// holds the database open for a second.
+func Open(path string) (*bbolt.DB, error) {
+	return bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second})
+}
+
+// NewBoltStorage initializes a new BoltStorage instance with provided inputs and creates the buckets
+// of the records and their indexes if they don't exist. The expiry index of a database created
+// before it is built from the records.
+func NewBoltStorage(db *bbolt.DB, logger *logger.Logger, opts ...Option) (storage.Repository, error) {
+	if err := indexExpiries(db); err != nil {
+		return nil, err
+	}
+
+	store, err := newKV(db, allBuckets...)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &BoltStorage{
+		kv:     store,
+		logger: logger,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
+}
+
+// SaveURL stores a new URL record in the database.
+// The record is disabled if its shortened URL was disabled for the other users.
+func (s *BoltStorage) SaveURL(ctx context.Context, url entity.URLRecord) error {
+	return s.update(ctx, func(tx *bbolt.Tx) error {
+		return insert(tx, url)
+	})
+}
+
+// SaveURLBatch stores a batch of URL records in the database in a single transaction.
+func (s *BoltStorage) SaveURLBatch(ctx context.Context, urls []*entity.URLRecord) error {
+	return s.update(ctx, func(tx *bbolt.Tx) error {
+		for _, url := range urls {
+			if err := insert(tx, *url); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetURL retrieves the original URL from the database given its shortened version,
+// unless it has been deleted, has expired or has been disabled.
+func (s *BoltStorage) GetURL(ctx context.Context, shortURL string) (string, error) {
+	r, err := s.GetURLRecord(ctx, shortURL)
+	if err != nil {
+		return "", err
+	}
+
+	return r.OriginalURL, nil
+}
+
+// GetURLRecord retrieves the first URL record with the shortened URL that is neither deleted nor expired,
+// including its preview, from the database, unless there is no such record or the URL has been disabled.
+func (s *BoltStorage) GetURLRecord(ctx context.Context, shortURL string) (*entity.URLRecord, error) {
+	var r *record
+
+	now := time.Now()
+
+	err := s.view(ctx, func(tx *bbolt.Tx) error {
+		records, err := scanIndex(tx, shortURLsIndex, shortURL)
+		if err != nil {
+			return err
+		}
+
+		if len(records) == 0 {
+			return fmt.Errorf("%w: %s", storage.ErrURLNotFound, shortURL)
+		}
+
+		r = &records[0]
+		for i := range records {
+			if !records[i].Gone(now) {
+				r = &records[i]
+				break
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Gone(now) {
+		return nil, storage.ErrURLDeleted
+	}
+
+	if r.Disabled {
+		return nil, storage.ErrURLDisabled
+	}
+
+	return &r.URLRecord, nil
+}
+
+// GetURLsByUserID retrieves all the URL records of a specific user from the database,
+// followed by the records of the workspaces the user can view.
+func (s *BoltStorage) GetURLsByUserID(ctx context.Context, userID string) ([]entity.URLRecord, error) {
+	viewable, err := s.memberWorkspaces(ctx, userID, func(m entity.WorkspaceMember) bool { return m.CanView })
+	if err != nil {
+		return nil, err
+	}
+
+	var result []entity.URLRecord
+
+	err = s.view(ctx, func(tx *bbolt.Tx) error {
+		own, err := scanIndex(tx, usersIndex, userID)
+		if err != nil {
+			return err
+		}
+
+		var shared []record
+		for id := range viewable {
+			records, err := scanIndex(tx, workspacesIndex, id)
+			if err != nil {
+				return err
+			}
+
+			for _, r := range records {
+				if r.UserID != userID {
+					shared = append(shared, r)
+				}
+			}
+		}
+
+		sort.Slice(shared, func(i, j int) bool { return shared[i].Seq < shared[j].Seq })
+
+		for _, r := range append(own, shared...) {
+			result = append(result, r.URLRecord)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("%w: no short urls for id %s", storage.ErrURLNotFound, userID)
+	}
+
+	return result, nil
+}
+
+// CountURLsByUserID counts the URL records of a specific user that are not deleted.
+func (s *BoltStorage) CountURLsByUserID(ctx context.Context, userID string) (int, error) {
+	var count int
+
+	err := s.view(ctx, func(tx *bbolt.Tx) error {
+		records, err := scanIndex(tx, usersIndex, userID)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range records {
+			if !r.DeletedFlag {
+				count++
+			}
+		}
+
+		return nil
+	})
+
+	return count, err
+}
+
+// CheckExistence checks if a live shortened URL associated with a user exists in the database.
+// Deleted and expired records don't count, since saving the URL again replaces them.
+func (s *BoltStorage) CheckExistence(ctx context.Context, shortURL, userID string) error {
+	now := time.Now()
+
+	return s.view(ctx, func(tx *bbolt.Tx) error {
+		r, ok, err := userRecord(tx, shortURL, userID)
+		if err != nil {
+			return err
+		}
+
+		if !ok || r.Gone(now) {
+			return fmt.Errorf("%w: %s", storage.ErrURLNotFound, shortURL)
+		}
+
+		return nil
+	})
+}
+
+// GetExistingURLs returns the shortened URLs among the given ones the user has live records of in the database,
+// reading them in a single transaction. Deleted and expired records don't count.
+func (s *BoltStorage) GetExistingURLs(ctx context.Context, shortURLs []string, userID string) ([]string, error) {
+	now := time.Now()
+
+	var existing []string
+	err := s.view(ctx, func(tx *bbolt.Tx) error {
+		for _, shortURL := range shortURLs {
+			r, ok, err := userRecord(tx, shortURL, userID)
+			if err != nil {
+				return err
+			}
+
+			if ok && !r.Gone(now) {
+				existing = append(existing, shortURL)
+			}
+		}
+
+		return nil
+	})
+
+	return existing, err
+}
+
+// ReassignURLs moves the URL records of one user to another.
+// Records with a shortened URL the other user already has stay with the original user.
+func (s *BoltStorage) ReassignURLs(ctx context.Context, fromUserID, toUserID string) error {
+	return s.update(ctx, func(tx *bbolt.Tx) error {
+		owned, err := scanIndex(tx, usersIndex, toUserID)
+		if err != nil {
+			return err
+		}
+
+		ownedURLs := make(map[string]bool, len(owned))
+		for _, r := range owned {
+			ownedURLs[r.ShortURL] = true
+		}
+
+		records, err := scanIndex(tx, usersIndex, fromUserID)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range records {
+			if ownedURLs[r.ShortURL] {
+				continue
+			}
+
+			updated := r
+			updated.UserID = toUserID
+
+			if err := update(tx, r, updated); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// ReassignURL moves the record with the shortened URL from one user to another.
+// It returns storage.ErrURLNotFound if the first user has no such record.
+func (s *BoltStorage) ReassignURL(ctx context.Context, shortURL, fromUserID, toUserID string) error {
+	return s.update(ctx, func(tx *bbolt.Tx) error {
+		_, owned, err := userRecord(tx, shortURL, toUserID)
+		if err != nil {
+			return err
+		}
+
+		if owned {
+			return storage.ErrURLExists
+		}
+
+		r, ok, err := userRecord(tx, shortURL, fromUserID)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return storage.ErrURLNotFound
+		}
+
+		updated := r
+		updated.UserID = toUserID
+
+		return update(tx, r, updated)
+	})
+}
+
+// SetURLDisabled disables or enables every record with the shortened URL and returns the updated records.
+// It returns storage.ErrURLNotFound if there is no such record.
+func (s *BoltStorage) SetURLDisabled(ctx context.Context, shortURL string, disabled bool) ([]entity.URLRecord, error) {
+	return s.updateShortURL(ctx, shortURL, func(r *record) {
+		r.Disabled = disabled
+	})
+}
+
+// SetURLWorkspace moves the record of the user with the shortened URL to the workspace, or out of
+// any workspace if the workspaceID is empty. It returns storage.ErrURLNotFound if the user has no such record.
+func (s *BoltStorage) SetURLWorkspace(ctx context.Context, shortURL, userID, workspaceID string) error {
+	return s.update(ctx, func(tx *bbolt.Tx) error {
+		r, ok, err := userRecord(tx, shortURL, userID)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return storage.ErrURLNotFound
+		}
+
+		updated := r
+		updated.WorkspaceID = workspaceID
+
+		return update(tx, r, updated)
+	})
+}
+
+// SavePreview stores the preview for every record with the given shortened URL.
+// It returns storage.ErrURLNotFound if there is no such record.
+func (s *BoltStorage) SavePreview(ctx context.Context, shortURL string, preview entity.Preview) error {
+	_, err := s.updateShortURL(ctx, shortURL, func(r *record) {
+		p := preview
+		r.Preview = &p
+	})
+
+	return err
+}
+
+// DeleteURLBatch marks a set of URLs associated with a user, or with the workspaces the user
+// is allowed to delete from, as deleted. The records are kept with their DeletedFlag set.
+// It returns the records it marked.
+func (s *BoltStorage) DeleteURLBatch(urls []string, user string) ([]entity.URLRecord, error) {
+	deletable, err := s.memberWorkspaces(context.Background(), user, func(m entity.WorkspaceMember) bool { return m.CanDelete })
+	if err != nil {
+		return nil, err
+	}
+
+	var marked []entity.URLRecord
+
+	err = s.db.Update(func(tx *bbolt.Tx) error {
+		marked = nil
+
+		for _, shortURL := range urls {
+			records, err := scanIndex(tx, shortURLsIndex, shortURL)
+			if err != nil {
+				return err
+			}
+
+			for _, r := range records {
+				if r.DeletedFlag || (r.UserID != user && !deletable[r.WorkspaceID]) {
+					continue
+				}
+
+				updated := r
+				updated.DeletedFlag = true
+
+				if err := update(tx, r, updated); err != nil {
+					return err
+				}
+
+				marked = append(marked, updated.URLRecord)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return marked, nil
+}
+
+// ExpireURLs marks the URLs that expired at or before the time as deleted in a single transaction
+// and returns the records it marked. It seeks the expiry index up to the time, so the records that
+// don't expire, or expire later, aren't read.
+func (s *BoltStorage) ExpireURLs(ctx context.Context, now time.Time) ([]entity.URLRecord, error) {
+	var marked []entity.URLRecord
+
+	err := s.update(ctx, func(tx *bbolt.Tx) error {
+		marked = nil
+
+		urls := tx.Bucket(urlsBucket)
+		limit := expiryPrefix(now)
+
+		var expired []record
+		c := tx.Bucket(expiriesIndex).Cursor()
+		for k, id := c.First(); k != nil && bytes.Compare(k[:len(limit)], limit) <= 0; k, id = c.Next() {
+			data := urls.Get(id)
+			if data == nil {
+				return fmt.Errorf("index %s refers to missing url record %s", expiriesIndex, id)
+			}
+
+			var r record
+			if err := json.Unmarshal(data, &r); err != nil {
+				return err
+			}
+
+			if !r.DeletedFlag && r.Expired(now) {
+				expired = append(expired, r)
+			}
+		}
+
+		for _, r := range expired {
+			updated := r
+			updated.DeletedFlag = true
+
+			if err := update(tx, r, updated); err != nil {
+				return err
+			}
+
+			marked = append(marked, updated.URLRecord)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return marked, nil
+}
+
+// AddClicks adds the clicks of every shortened URL to its records that are not deleted in a single transaction
+// and returns the updated records.
+func (s *BoltStorage) AddClicks(ctx context.Context, clicks map[string]int64) ([]entity.URLRecord, error) {
+	var result []entity.URLRecord
+
+	err := s.update(ctx, func(tx *bbolt.Tx) error {
+		result = nil
+
+		for shortURL, n := range clicks {
+			records, err := scanIndex(tx, shortURLsIndex, shortURL)
+			if err != nil {
+				return err
+			}
+
+			for _, r := range records {
+				if r.DeletedFlag {
+					continue
+				}
+
+				updated := r
+				updated.Clicks += n
+
+				if err := put(tx, updated); err != nil {
+					return err
+				}
+
+				result = append(result, updated.URLRecord)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Ping checks that the database is open.
+func (s *BoltStorage) Ping() error {
+	return s.db.View(func(tx *bbolt.Tx) error {
+		return nil
+	})
+}
+
+// GetStats retrieves statistical data about the URLs and users that are not deleted.
+func (s *BoltStorage) GetStats(ctx context.Context) (*entity.Stats, error) {
+	var stats entity.Stats
+
+	err := s.view(ctx, func(tx *bbolt.Tx) error {
+		users := make(map[string]bool)
+
+		err := tx.Bucket(urlsBucket).ForEach(func(_, v []byte) error {
+			var r record
+			if err := json.Unmarshal(v, &r); err != nil {
+				return err
+			}
+
+			if !r.DeletedFlag {
+				stats.URLs++
+				users[r.UserID] = true
+			}
+
+			return nil
+		})
+
+		stats.Users = len(users)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+// GetUserIDs returns the users with URLs that are not deleted.
+func (s *BoltStorage) GetUserIDs(ctx context.Context) ([]string, error) {
+	var users []string
+
+	err := s.view(ctx, func(tx *bbolt.Tx) error {
+		seen := make(map[string]bool)
+
+		return tx.Bucket(urlsBucket).ForEach(func(_, v []byte) error {
+			var r record
+			if err := json.Unmarshal(v, &r); err != nil {
+				return err
+			}
+
+			if !r.DeletedFlag && !seen[r.UserID] {
+				seen[r.UserID] = true
+				users = append(users, r.UserID)
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// ScanURLs returns up to limit records, including the deleted ones, in the order of their UUIDs after the afterUUID.
+func (s *BoltStorage) ScanURLs(ctx context.Context, afterUUID string, limit int) ([]entity.URLRecord, error) {
+	var records []entity.URLRecord
+
+	err := s.view(ctx, func(tx *bbolt.Tx) error {
+		c := tx.Bucket(urlsBucket).Cursor()
+
+		k, v := c.Seek([]byte(afterUUID))
+		if k != nil && string(k) == afterUUID {
+			k, v = c.Next()
+		}
+
+		for ; k != nil && len(records) < limit; k, v = c.Next() {
+			var r record
+			if err := json.Unmarshal(v, &r); err != nil {
+				return err
+			}
+
+			records = append(records, r.URLRecord)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// ImportURLs stores the records as they are in a single transaction, replacing the records with the same UUIDs.
+// It returns storage.ErrURLExists if the user of a record has another record with its shortened URL.
+func (s *BoltStorage) ImportURLs(ctx context.Context, records []entity.URLRecord) error {
+	return s.update(ctx, func(tx *bbolt.Tx) error {
+		urls := tx.Bucket(urlsBucket)
+
+		for _, url := range records {
+			other, ok, err := userRecord(tx, url.ShortURL, url.UserID)
+			if err != nil {
+				return err
+			}
+
+			if ok && other.UUID != url.UUID {
+				return fmt.Errorf("%w: %s", storage.ErrURLExists, url.ShortURL)
+			}
+
+			data := urls.Get([]byte(url.UUID))
+			if data == nil {
+				seq, err := urls.NextSequence()
+				if err != nil {
+					return err
+				}
+
+				r := record{URLRecord: url, Seq: seq}
+				if err := put(tx, r); err != nil {
+					return err
+				}
+
+				if err := setIndexes(tx, r, func(b *bbolt.Bucket, key []byte) error {
+					return b.Put(key, []byte(r.UUID))
+				}); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			var old record
+			if err := json.Unmarshal(data, &old); err != nil {
+				return err
+			}
+
+			if err := update(tx, old, record{URLRecord: url, Seq: old.Seq}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// RemoveURLs removes the records with the UUIDs and their index entries in a single transaction.
+func (s *BoltStorage) RemoveURLs(ctx context.Context, uuids []string) error {
+	return s.update(ctx, func(tx *bbolt.Tx) error {
+		urls := tx.Bucket(urlsBucket)
+
+		for _, uuid := range uuids {
+			data := urls.Get([]byte(uuid))
+			if data == nil {
+				continue
+			}
+
+			var r record
+			if err := json.Unmarshal(data, &r); err != nil {
+				return err
+			}
+
+			if err := remove(tx, r); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// updateShortURL applies fn to every record with the shortened URL in a single transaction
+// and returns the updated records. It returns storage.ErrURLNotFound if there is no such record.
+func (s *BoltStorage) updateShortURL(ctx context.Context, shortURL string, fn func(r *record)) ([]entity.URLRecord, error) {
+	var result []entity.URLRecord
+
+	err := s.update(ctx, func(tx *bbolt.Tx) error {
+		result = nil
+
+		records, err := scanIndex(tx, shortURLsIndex, shortURL)
+		if err != nil {
+			return err
+		}
+
+		if len(records) == 0 {
+			return storage.ErrURLNotFound
+		}
+
+		for _, r := range records {
+			updated := r
+			fn(&updated)
+
+			if err := update(tx, r, updated); err != nil {
+				return err
+			}
+
+			result = append(result, updated.URLRecord)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// memberWorkspaces returns the set of the workspaces whose memberships of the user satisfy the allowed func.
+func (s *BoltStorage) memberWorkspaces(ctx context.Context, userID string, allowed func(entity.WorkspaceMember) bool) (map[string]bool, error) {
+	if s.workspaces == nil {
+		return nil, nil
+	}
+
+	memberships, err := s.workspaces.GetMembershipsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]bool, len(memberships))
+	for _, m := range memberships {
+		if allowed(m) {
+			ids[m.WorkspaceID] = true
+		}
+	}
+
+	return ids, nil
+}
+
+// insert stores a new record and its index entries.
+func insert(tx *bbolt.Tx, url entity.URLRecord) error {
+	urls := tx.Bucket(urlsBucket)
+	if urls.Get([]byte(url.UUID)) != nil {
+		return fmt.Errorf("%w: record %s", storage.ErrURLExists, url.UUID)
+	}
+
+	others, err := scanIndex(tx, shortURLsIndex, url.ShortURL)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, o := range others {
+		if o.UserID == url.UserID {
+			if !o.Gone(now) {
+				return fmt.Errorf("%w: %s", storage.ErrURLExists, url.ShortURL)
+			}
+
+			// The deleted or expired record of the user gives way to the new one.
+			if err := remove(tx, o); err != nil {
+				return err
+			}
+		}
+
+		if o.Disabled {
+			url.Disabled = true
+		}
+	}
+
+	seq, err := urls.NextSequence()
+	if err != nil {
+		return err
+	}
+
+	r := record{URLRecord: url, Seq: seq}
+	if err := put(tx, r); err != nil {
+		return err
+	}
+
+	return setIndexes(tx, r, func(b *bbolt.Bucket, key []byte) error {
+		return b.Put(key, []byte(r.UUID))
+	})
+}
+
+// remove deletes the record and its index entries.
+func remove(tx *bbolt.Tx, r record) error {
+	if err := setIndexes(tx, r, func(b *bbolt.Bucket, key []byte) error {
+		return b.Delete(key)
+	}); err != nil {
+		return err
+	}
+
+	return tx.Bucket(urlsBucket).Delete([]byte(r.UUID))
+}
+
+// update replaces the record and moves its index entries if the indexed fields changed.
+func update(tx *bbolt.Tx, old, updated record) error {
+	if err := setIndexes(tx, old, func(b *bbolt.Bucket, key []byte) error {
+		return b.Delete(key)
+	}); err != nil {
+		return err
+	}
+
+	if err := put(tx, updated); err != nil {
+		return err
+	}
+
+	return setIndexes(tx, updated, func(b *bbolt.Bucket, key []byte) error {
+		return b.Put(key, []byte(updated.UUID))
+	})
+}
+
+func put(tx *bbolt.Tx, r record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	return tx.Bucket(urlsBucket).Put([]byte(r.UUID), data)
+}
+
+// setIndexes applies fn to the index buckets with the keys of the record.
+func setIndexes(tx *bbolt.Tx, r record, fn func(b *bbolt.Bucket, key []byte) error) error {
+	if err := fn(tx.Bucket(shortURLsIndex), indexKey(r.ShortURL, r.Seq)); err != nil {
+		return err
+	}
+
+	if err := fn(tx.Bucket(usersIndex), indexKey(r.UserID, r.Seq)); err != nil {
+		return err
+	}
+
+	if r.WorkspaceID != "" {
+		if err := fn(tx.Bucket(workspacesIndex), indexKey(r.WorkspaceID, r.Seq)); err != nil {
+			return err
+		}
+	}
+
+	key, ok := expiryKey(r)
+	if !ok {
+		return nil
+	}
+
+	return fn(tx.Bucket(expiriesIndex), key)
+}
+
+// scanIndex returns the records indexed under the value in the index, in creation order.
+func scanIndex(tx *bbolt.Tx, index []byte, value string) ([]record, error) {
+	prefix := indexPrefix(value)
+	urls := tx.Bucket(urlsBucket)
+
+	var records []record
+
+	c := tx.Bucket(index).Cursor()
+	for k, id := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, id = c.Next() {
+		data := urls.Get(id)
+		if data == nil {
+			return nil, fmt.Errorf("index %s refers to missing url record %s", index, id)
+		}
+
+		var r record
+		if err := json.Unmarshal(data, &r); err != nil {
+			return nil, err
+		}
+
+		records = append(records, r)
+	}
+
+	return records, nil
+}
+
+// userRecord returns the record of the user with the shortened URL, if any.
+func userRecord(tx *bbolt.Tx, shortURL, userID string) (record, bool, error) {
+	records, err := scanIndex(tx, usersIndex, userID)
+	if err != nil {
+		return record{}, false, err
+	}
+
+	for _, r := range records {
+		if r.ShortURL == shortURL {
+			return r, true, nil
+		}
+	}
+
+	return record{}, false, nil
+}
+
+// indexPrefix terminates the value with a zero byte, so that the prefix of one value doesn't match a longer value.
+func indexPrefix(value string) []byte {
+	return append([]byte(value), 0)
+}
+
+func indexKey(value string, seq uint64) []byte {
+	return binary.BigEndian.AppendUint64(indexPrefix(value), seq)
+}
+
+// expiryKey returns the key of the record in the expiry index, unless it doesn't expire or is deleted.
+func expiryKey(r record) ([]byte, bool) {
+	if r.ExpiresAt == nil || r.DeletedFlag {
+		return nil, false
+	}
+
+	return binary.BigEndian.AppendUint64(expiryPrefix(*r.ExpiresAt), r.Seq), true
+}
+
+// expiryPrefix orders the times by their nanoseconds since the Unix epoch. The expiries are never before it.
+func expiryPrefix(t time.Time) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(t.UnixNano()))
+}
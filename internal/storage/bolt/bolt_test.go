@@ -0,0 +1,219 @@
+package bolt
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/memory"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/storagetest"
+)
+
+func setupStorage(t *testing.T, path string, opts ...Option) (storage.Repository, *bbolt.DB) {
+	log, _ := logger.Initialize("debug")
+
+	db, err := Open(path)
+	require.NoError(t, err)
+
+	s, err := NewBoltStorage(db, log, opts...)
+	require.NoError(t, err)
+
+	return s, db
+}
+
+func TestBoltStorage_conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Repository {
+		s, db := setupStorage(t, filepath.Join(t.TempDir(), "urls.bolt"))
+		t.Cleanup(func() { db.Close() })
+
+		return s
+	})
+}
+
+func TestBoltStorage_persists(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "urls.bolt")
+
+	s, db := setupStorage(t, path)
+
+	require.NoError(t, s.SaveURLBatch(ctx, []*entity.URLRecord{
+		{UUID: "1", ShortURL: "abc", OriginalURL: "https://a.example.com", UserID: "alice"},
+		{UUID: "2", ShortURL: "def", OriginalURL: "https://d.example.com", UserID: "alice"},
+	}))
+	_, err := s.DeleteURLBatch([]string{"def"}, "alice")
+	require.NoError(t, err)
+	require.NoError(t, s.SavePreview(ctx, "abc", entity.Preview{Title: "A"}))
+	require.NoError(t, db.Close())
+
+	// The records, their deletion and preview survive reopening the database.
+	s, db = setupStorage(t, path)
+	defer db.Close()
+
+	got, err := s.GetURL(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://a.example.com", got)
+
+	r, err := s.GetURLRecord(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, &entity.Preview{Title: "A"}, r.Preview)
+
+	_, err = s.GetURL(ctx, "def")
+	assert.ErrorIs(t, err, storage.ErrURLDeleted)
+
+	count, err := s.CountURLsByUserID(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	stats, err := s.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &entity.Stats{URLs: 1, Users: 1}, stats)
+}
+
+func TestBoltStorage_indexesExpiries(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "urls.bolt")
+
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+
+	s, db := setupStorage(t, path)
+	require.NoError(t, s.SaveURLBatch(ctx, []*entity.URLRecord{
+		{UUID: "1", ShortURL: "abc", UserID: "alice", ExpiresAt: &past},
+		{UUID: "2", ShortURL: "def", UserID: "alice", ExpiresAt: &future},
+		{UUID: "3", ShortURL: "ghi", UserID: "alice"},
+	}))
+
+	// A database created before the expiry index has the records without it.
+	require.NoError(t, db.Update(func(tx *bbolt.Tx) error {
+		return tx.DeleteBucket(expiriesIndex)
+	}))
+	require.NoError(t, db.Close())
+
+	s, db = setupStorage(t, path)
+	defer db.Close()
+
+	marked, err := s.ExpireURLs(ctx, time.Now())
+	require.NoError(t, err)
+	require.Len(t, marked, 1)
+	assert.Equal(t, "abc", marked[0].ShortURL)
+
+	// A marked record isn't marked again.
+	marked, err = s.ExpireURLs(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, marked)
+
+	marked, err = s.ExpireURLs(ctx, future)
+	require.NoError(t, err)
+	require.Len(t, marked, 1)
+	assert.Equal(t, "def", marked[0].ShortURL)
+}
+
+func TestBoltStorage_SaveURLBatch_isAtomic(t *testing.T) {
+	ctx := context.Background()
+	s, db := setupStorage(t, filepath.Join(t.TempDir(), "urls.bolt"))
+	defer db.Close()
+
+	require.NoError(t, s.SaveURL(ctx, entity.URLRecord{UUID: "1", ShortURL: "abc", UserID: "alice"}))
+
+	err := s.SaveURLBatch(ctx, []*entity.URLRecord{
+		{UUID: "2", ShortURL: "def", UserID: "alice"},
+		{UUID: "1", ShortURL: "ghi", UserID: "alice"},
+	})
+	assert.Error(t, err)
+
+	_, err = s.GetURL(ctx, "def")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func TestBoltStorage_workspaces(t *testing.T) {
+	ctx := context.Background()
+	workspaces := memory.NewWorkspaceStorage()
+
+	s, db := setupStorage(t, filepath.Join(t.TempDir(), "urls.bolt"), WithWorkspaces(workspaces))
+	defer db.Close()
+
+	require.NoError(t, workspaces.SaveWorkspace(ctx,
+		entity.Workspace{ID: "ws", Name: "team"},
+		entity.WorkspaceMember{WorkspaceID: "ws", UserID: "alice", CanView: true, CanEdit: true, CanDelete: true}))
+	require.NoError(t, workspaces.SaveMember(ctx, entity.WorkspaceMember{WorkspaceID: "ws", UserID: "bob", CanView: true}))
+
+	require.NoError(t, s.SaveURL(ctx, entity.URLRecord{UUID: "1", ShortURL: "abc", UserID: "alice"}))
+	require.NoError(t, s.SaveURL(ctx, entity.URLRecord{UUID: "2", ShortURL: "def", UserID: "bob"}))
+	require.NoError(t, s.SetURLWorkspace(ctx, "abc", "alice", "ws"))
+
+	records, err := s.GetURLsByUserID(ctx, "bob")
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "def", records[0].ShortURL)
+	assert.Equal(t, "abc", records[1].ShortURL)
+
+	// Bob can view but not delete the links of the workspace.
+	_, err = s.DeleteURLBatch([]string{"abc"}, "bob")
+	require.NoError(t, err)
+	_, err = s.GetURL(ctx, "abc")
+	require.NoError(t, err)
+
+	require.NoError(t, s.SetURLWorkspace(ctx, "abc", "alice", ""))
+
+	records, err = s.GetURLsByUserID(ctx, "bob")
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+}
+
+func TestBoltStorage_reassign(t *testing.T) {
+	ctx := context.Background()
+	s, db := setupStorage(t, filepath.Join(t.TempDir(), "urls.bolt"))
+	defer db.Close()
+
+	require.NoError(t, s.SaveURLBatch(ctx, []*entity.URLRecord{
+		{UUID: "1", ShortURL: "abc", UserID: "anon"},
+		{UUID: "2", ShortURL: "def", UserID: "anon"},
+		{UUID: "3", ShortURL: "def", UserID: "alice"},
+	}))
+
+	require.NoError(t, s.ReassignURLs(ctx, "anon", "alice"))
+
+	assert.NoError(t, s.CheckExistence(ctx, "abc", "alice"))
+	assert.NoError(t, s.CheckExistence(ctx, "def", "anon"))
+	assert.ErrorIs(t, s.CheckExistence(ctx, "abc", "anon"), storage.ErrURLNotFound)
+
+	assert.ErrorIs(t, s.ReassignURL(ctx, "abc", "anon", "bob"), storage.ErrURLNotFound)
+	require.NoError(t, s.ReassignURL(ctx, "abc", "alice", "bob"))
+	assert.NoError(t, s.CheckExistence(ctx, "abc", "bob"))
+}
+
+func TestBoltStorage_SetURLDisabled(t *testing.T) {
+	ctx := context.Background()
+	s, db := setupStorage(t, filepath.Join(t.TempDir(), "urls.bolt"))
+	defer db.Close()
+
+	_, err := s.SetURLDisabled(ctx, "abc", true)
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	require.NoError(t, s.SaveURL(ctx, entity.URLRecord{UUID: "1", ShortURL: "abc", UserID: "alice"}))
+	_, err = s.SetURLDisabled(ctx, "abc", true)
+	require.NoError(t, err)
+
+	// A new record of a disabled short URL is disabled too.
+	require.NoError(t, s.SaveURL(ctx, entity.URLRecord{UUID: "2", ShortURL: "abc", UserID: "bob"}))
+
+	records, err := s.GetURLsByUserID(ctx, "bob")
+	require.NoError(t, err)
+	assert.True(t, records[0].Disabled)
+
+	_, err = s.GetURL(ctx, "abc")
+	assert.ErrorIs(t, err, storage.ErrURLDisabled)
+
+	_, err = s.SetURLDisabled(ctx, "abc", false)
+	require.NoError(t, err)
+
+	_, err = s.GetURL(ctx, "abc")
+	assert.NoError(t, err)
+}
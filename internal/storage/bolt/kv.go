@@ -0,0 +1,106 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// kv runs the transactions of the repositories that keep their entities as JSON values in buckets.
+type kv struct {
+	db *bbolt.DB
+}
+
+// newKV creates the buckets if they don't exist.
+func newKV(db *bbolt.DB, buckets ...[]byte) (kv, error) {
+	err := db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range buckets {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return kv{db: db}, err
+}
+
+// view runs fn in a read-only transaction, unless the context is done.
+func (s kv) view(ctx context.Context, fn func(tx *bbolt.Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.db.View(fn)
+}
+
+// update runs fn in a read-write transaction, unless the context is done.
+func (s kv) update(ctx context.Context, fn func(tx *bbolt.Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.db.Update(fn)
+}
+
+// getJSON decodes the value of the key in the bucket into v. It returns false if there is no such key.
+func getJSON(b *bbolt.Bucket, key []byte, v interface{}) (bool, error) {
+	data := b.Get(key)
+	if data == nil {
+		return false, nil
+	}
+
+	return true, json.Unmarshal(data, v)
+}
+
+// putJSON stores v encoded as JSON under the key in the bucket.
+func putJSON(b *bbolt.Bucket, key []byte, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return b.Put(key, data)
+}
+
+// scanJSON decodes the values of the keys with the prefix in the bucket in the order of the keys,
+// and returns the ones the keep func accepts.
+func scanJSON[T any](b *bbolt.Bucket, prefix []byte, keep func(v T) bool) ([]T, error) {
+	var values []T
+
+	c := b.Cursor()
+	for k, data := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, data = c.Next() {
+		var v T
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+
+		if keep(v) {
+			values = append(values, v)
+		}
+	}
+
+	return values, nil
+}
+
+// all accepts every value scanned by scanJSON.
+func all[T any](T) bool {
+	return true
+}
+
+// sortByCreation sorts the values by the creation times returned by createdAt, oldest first.
+func sortByCreation[T any](values []T, createdAt func(v T) time.Time) {
+	sort.SliceStable(values, func(i, j int) bool {
+		return createdAt(values[i]).Before(createdAt(values[j]))
+	})
+}
+
+// pairKey joins the values with a zero byte, so that the pairs of a first value share its indexPrefix.
+func pairKey(first, second string) []byte {
+	return append(indexPrefix(first), second...)
+}
@@ -0,0 +1,58 @@
+package bolt
+
+import (
+	"context"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// The quotas are stored by the ID of their user in the quotas bucket.
+var quotasBucket = []byte("quotas")
+
+// QuotaStorage is a struct that implements the storage.QuotaRepository interface, using an embedded bbolt database.
+type QuotaStorage struct {
+	kv
+}
+
+// NewQuotaStorage initializes a new QuotaStorage instance and creates its bucket if it doesn't exist.
+func NewQuotaStorage(db *bbolt.DB) (storage.QuotaRepository, error) {
+	s, err := newKV(db, quotasBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	return &QuotaStorage{kv: s}, nil
+}
+
+// SaveQuota stores the quota of a user in the database, replacing the previous one.
+func (s *QuotaStorage) SaveQuota(ctx context.Context, quota entity.Quota) error {
+	return s.update(ctx, func(tx *bbolt.Tx) error {
+		return putJSON(tx.Bucket(quotasBucket), []byte(quota.UserID), quota)
+	})
+}
+
+// GetQuota retrieves the quota of a user from the database. It returns nil if the quota wasn't overridden for the user.
+func (s *QuotaStorage) GetQuota(ctx context.Context, userID string) (*entity.Quota, error) {
+	var quota *entity.Quota
+	err := s.view(ctx, func(tx *bbolt.Tx) error {
+		var q entity.Quota
+		ok, err := getJSON(tx.Bucket(quotasBucket), []byte(userID), &q)
+		if ok {
+			quota = &q
+		}
+
+		return err
+	})
+
+	return quota, err
+}
+
+// DeleteQuota removes the quota of a user from the database, so the default one applies again.
+func (s *QuotaStorage) DeleteQuota(ctx context.Context, userID string) error {
+	return s.update(ctx, func(tx *bbolt.Tx) error {
+		return tx.Bucket(quotasBucket).Delete([]byte(userID))
+	})
+}
@@ -0,0 +1,172 @@
+package bolt
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// The refresh tokens are stored by ID in the refresh_tokens bucket, and the hashes index maps the hash
+// of a token to its ID.
+var (
+	refreshTokensBucket     = []byte("refresh_tokens")
+	refreshTokenHashesIndex = []byte("refresh_token_hashes_idx")
+)
+
+// RefreshTokenStorage is a struct that implements the storage.RefreshTokenRepository interface,
+// using an embedded bbolt database.
+type RefreshTokenStorage struct {
+	kv
+}
+
+// NewRefreshTokenStorage initializes a new RefreshTokenStorage instance and creates its buckets if they don't exist.
+func NewRefreshTokenStorage(db *bbolt.DB) (storage.RefreshTokenRepository, error) {
+	s, err := newKV(db, refreshTokensBucket, refreshTokenHashesIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RefreshTokenStorage{kv: s}, nil
+}
+
+// SaveRefreshToken stores a new refresh token in the database. The expired tokens are dropped along the way.
+func (s *RefreshTokenStorage) SaveRefreshToken(ctx context.Context, token entity.RefreshToken) error {
+	return s.update(ctx, func(tx *bbolt.Tx) error {
+		tokens := tx.Bucket(refreshTokensBucket)
+		hashes := tx.Bucket(refreshTokenHashesIndex)
+
+		now := time.Now()
+		expired, err := scanJSON(tokens, nil, func(t entity.RefreshToken) bool {
+			return t.ExpiresAt.Before(now)
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, t := range expired {
+			if err := tokens.Delete([]byte(t.ID)); err != nil {
+				return err
+			}
+
+			if err := hashes.Delete([]byte(t.Hash)); err != nil {
+				return err
+			}
+		}
+
+		if err := putJSON(tokens, []byte(token.ID), token); err != nil {
+			return err
+		}
+
+		return hashes.Put([]byte(token.Hash), []byte(token.ID))
+	})
+}
+
+// GetRefreshTokenByHash retrieves the refresh token with the given hash from the database.
+// It returns nil if there is no such token.
+func (s *RefreshTokenStorage) GetRefreshTokenByHash(ctx context.Context, hash string) (*entity.RefreshToken, error) {
+	var token *entity.RefreshToken
+	err := s.view(ctx, func(tx *bbolt.Tx) error {
+		id := tx.Bucket(refreshTokenHashesIndex).Get([]byte(hash))
+		if id == nil {
+			return nil
+		}
+
+		var t entity.RefreshToken
+		ok, err := getJSON(tx.Bucket(refreshTokensBucket), id, &t)
+		if ok {
+			token = &t
+		}
+
+		return err
+	})
+
+	return token, err
+}
+
+// RevokeRefreshToken revokes the refresh token in the database, either because it was rotated
+// or because the user logged out.
+func (s *RefreshTokenStorage) RevokeRefreshToken(ctx context.Context, id string, rotated bool) error {
+	return s.update(ctx, func(tx *bbolt.Tx) error {
+		tokens := tx.Bucket(refreshTokensBucket)
+
+		var t entity.RefreshToken
+		ok, err := getJSON(tokens, []byte(id), &t)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return errors.New("refresh token not found")
+		}
+
+		if !t.RevokedAt.IsZero() {
+			return nil
+		}
+
+		t.RevokedAt = time.Now().UTC()
+		t.Rotated = rotated
+
+		return putJSON(tokens, []byte(id), t)
+	})
+}
+
+// RevokeUserRefreshTokens revokes all the refresh tokens of a specific user in the database.
+func (s *RefreshTokenStorage) RevokeUserRefreshTokens(ctx context.Context, userID string) error {
+	return s.update(ctx, func(tx *bbolt.Tx) error {
+		tokens := tx.Bucket(refreshTokensBucket)
+
+		active, err := scanJSON(tokens, nil, func(t entity.RefreshToken) bool {
+			return t.UserID == userID && t.RevokedAt.IsZero()
+		})
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		for _, t := range active {
+			t.RevokedAt = now
+			if err := putJSON(tokens, []byte(t.ID), t); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// DeleteRefreshTokens removes the refresh tokens that expired or were revoked before the time from the database.
+func (s *RefreshTokenStorage) DeleteRefreshTokens(ctx context.Context, before time.Time) (int, error) {
+	var deleted int
+	err := s.update(ctx, func(tx *bbolt.Tx) error {
+		tokens := tx.Bucket(refreshTokensBucket)
+		hashes := tx.Bucket(refreshTokenHashesIndex)
+
+		stale, err := scanJSON(tokens, nil, func(t entity.RefreshToken) bool {
+			return t.ExpiresAt.Before(before) || (!t.RevokedAt.IsZero() && t.RevokedAt.Before(before))
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, t := range stale {
+			if err := tokens.Delete([]byte(t.ID)); err != nil {
+				return err
+			}
+
+			if err := hashes.Delete([]byte(t.Hash)); err != nil {
+				return err
+			}
+		}
+
+		deleted = len(stale)
+
+		return nil
+	})
+
+	return deleted, err
+}
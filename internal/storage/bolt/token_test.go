@@ -0,0 +1,68 @@
+package bolt
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+func TestRefreshTokenStorage(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	db, err := Open(filepath.Join(t.TempDir(), "urls.bolt"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	s, err := NewRefreshTokenStorage(db)
+	require.NoError(t, err)
+
+	require.NoError(t, s.SaveRefreshToken(ctx, entity.RefreshToken{ID: "old", UserID: "alice", Hash: "h0", ExpiresAt: now.Add(-time.Minute)}))
+	require.NoError(t, s.SaveRefreshToken(ctx, entity.RefreshToken{ID: "1", UserID: "alice", Hash: "h1", ExpiresAt: now.Add(time.Hour)}))
+	require.NoError(t, s.SaveRefreshToken(ctx, entity.RefreshToken{ID: "2", UserID: "alice", Hash: "h2", ExpiresAt: now.Add(time.Hour)}))
+
+	expired, err := s.GetRefreshTokenByHash(ctx, "h0")
+	require.NoError(t, err)
+	assert.Nil(t, expired, "the expired tokens must be dropped")
+
+	require.NoError(t, s.RevokeRefreshToken(ctx, "1", true))
+	assert.Error(t, s.RevokeRefreshToken(ctx, "unknown", false))
+
+	token, err := s.GetRefreshTokenByHash(ctx, "h1")
+	require.NoError(t, err)
+	require.NotNil(t, token)
+	assert.True(t, token.Rotated)
+	assert.False(t, token.RevokedAt.IsZero())
+
+	require.NoError(t, s.RevokeUserRefreshTokens(ctx, "alice"))
+
+	token, err = s.GetRefreshTokenByHash(ctx, "h2")
+	require.NoError(t, err)
+	require.NotNil(t, token)
+	assert.False(t, token.Rotated)
+	assert.False(t, token.RevokedAt.IsZero())
+
+	require.NoError(t, s.SaveRefreshToken(ctx, entity.RefreshToken{ID: "3", UserID: "bob", Hash: "h3", ExpiresAt: now.Add(time.Hour)}))
+
+	deleted, err := s.DeleteRefreshTokens(ctx, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted, "the revoked tokens must be deleted")
+
+	token, err = s.GetRefreshTokenByHash(ctx, "h1")
+	require.NoError(t, err)
+	assert.Nil(t, token)
+
+	token, err = s.GetRefreshTokenByHash(ctx, "h3")
+	require.NoError(t, err)
+	assert.NotNil(t, token, "the active tokens must be kept")
+
+	deleted, err = s.DeleteRefreshTokens(ctx, now.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted, "the expired tokens must be deleted")
+}
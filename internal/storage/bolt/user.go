@@ -0,0 +1,109 @@
+package bolt
+
+import (
+	"context"
+	"errors"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// The accounts are stored by ID in the accounts bucket, and the usernames index maps a username to its account.
+var (
+	accountsBucket = []byte("accounts")
+	usernamesIndex = []byte("usernames_idx")
+)
+
+// UserStorage is a struct that implements the storage.UserRepository interface, using an embedded bbolt database.
+type UserStorage struct {
+	kv
+}
+
+// NewUserStorage initializes a new UserStorage instance and creates its buckets if they don't exist.
+func NewUserStorage(db *bbolt.DB) (storage.UserRepository, error) {
+	s, err := newKV(db, accountsBucket, usernamesIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserStorage{kv: s}, nil
+}
+
+// SaveUser stores a new account in the database. It returns storage.ErrUsernameTaken if the username is already taken.
+func (s *UserStorage) SaveUser(ctx context.Context, user entity.User) error {
+	return s.update(ctx, func(tx *bbolt.Tx) error {
+		usernames := tx.Bucket(usernamesIndex)
+		if usernames.Get([]byte(user.Username)) != nil {
+			return storage.ErrUsernameTaken
+		}
+
+		if err := putJSON(tx.Bucket(accountsBucket), []byte(user.ID), user); err != nil {
+			return err
+		}
+
+		return usernames.Put([]byte(user.Username), []byte(user.ID))
+	})
+}
+
+// GetUserByID retrieves the account with the given ID from the database. It returns nil if there is no such account.
+func (s *UserStorage) GetUserByID(ctx context.Context, id string) (*entity.User, error) {
+	var u *entity.User
+	err := s.view(ctx, func(tx *bbolt.Tx) error {
+		var err error
+		u, err = getUser(tx, []byte(id))
+
+		return err
+	})
+
+	return u, err
+}
+
+// GetUserByUsername retrieves the account with the given username from the database.
+// It returns nil if there is no such account.
+func (s *UserStorage) GetUserByUsername(ctx context.Context, username string) (*entity.User, error) {
+	var u *entity.User
+	err := s.view(ctx, func(tx *bbolt.Tx) error {
+		id := tx.Bucket(usernamesIndex).Get([]byte(username))
+		if id == nil {
+			return nil
+		}
+
+		var err error
+		u, err = getUser(tx, id)
+
+		return err
+	})
+
+	return u, err
+}
+
+// SetUserRole changes the role of the account with the given ID in the database. It fails if there is no such account.
+func (s *UserStorage) SetUserRole(ctx context.Context, id, role string) error {
+	return s.update(ctx, func(tx *bbolt.Tx) error {
+		u, err := getUser(tx, []byte(id))
+		if err != nil {
+			return err
+		}
+
+		if u == nil {
+			return errors.New("user not found")
+		}
+
+		u.Role = role
+
+		return putJSON(tx.Bucket(accountsBucket), []byte(id), u)
+	})
+}
+
+// getUser returns the account with the ID, or nil if there is no such account.
+func getUser(tx *bbolt.Tx, id []byte) (*entity.User, error) {
+	var u entity.User
+	ok, err := getJSON(tx.Bucket(accountsBucket), id, &u)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	return &u, nil
+}
@@ -0,0 +1,49 @@
+package bolt
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+func TestUserStorage(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "urls.bolt")
+
+	db, err := Open(path)
+	require.NoError(t, err)
+
+	s, err := NewUserStorage(db)
+	require.NoError(t, err)
+
+	require.NoError(t, s.SaveUser(ctx, entity.User{ID: "1", Username: "alice", CreatedAt: time.Now()}))
+	assert.ErrorIs(t, s.SaveUser(ctx, entity.User{ID: "2", Username: "alice", CreatedAt: time.Now()}), storage.ErrUsernameTaken)
+	require.NoError(t, s.SetUserRole(ctx, "1", "admin"))
+	assert.Error(t, s.SetUserRole(ctx, "2", "admin"))
+	require.NoError(t, db.Close())
+
+	// The accounts survive reopening the database.
+	db, err = Open(path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	s, err = NewUserStorage(db)
+	require.NoError(t, err)
+
+	u, err := s.GetUserByUsername(ctx, "alice")
+	require.NoError(t, err)
+	require.NotNil(t, u)
+	assert.Equal(t, "1", u.ID)
+	assert.Equal(t, "admin", u.Role)
+
+	u, err = s.GetUserByID(ctx, "2")
+	require.NoError(t, err)
+	assert.Nil(t, u)
+}
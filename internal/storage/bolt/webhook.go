@@ -0,0 +1,133 @@
+package bolt
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// The webhooks are stored by ID in the webhooks bucket. The deliveries bucket holds a bucket per webhook
+// with its delivery attempts keyed by their sequence number, so that they're kept in the order they were made.
+var (
+	webhooksBucket   = []byte("webhooks")
+	deliveriesBucket = []byte("webhook_deliveries")
+)
+
+// WebhookStorage is a struct that implements the storage.WebhookRepository interface, using an embedded bbolt database.
+type WebhookStorage struct {
+	kv
+}
+
+// NewWebhookStorage initializes a new WebhookStorage instance and creates its buckets if they don't exist.
+func NewWebhookStorage(db *bbolt.DB) (storage.WebhookRepository, error) {
+	s, err := newKV(db, webhooksBucket, deliveriesBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebhookStorage{kv: s}, nil
+}
+
+// SaveWebhook stores a new webhook in the database.
+func (s *WebhookStorage) SaveWebhook(ctx context.Context, webhook entity.Webhook) error {
+	return s.update(ctx, func(tx *bbolt.Tx) error {
+		return putJSON(tx.Bucket(webhooksBucket), []byte(webhook.ID), webhook)
+	})
+}
+
+// GetWebhooksByUserID retrieves all the webhooks registered by a specific user from the database, oldest first.
+func (s *WebhookStorage) GetWebhooksByUserID(ctx context.Context, userID string) ([]entity.Webhook, error) {
+	var webhooks []entity.Webhook
+	err := s.view(ctx, func(tx *bbolt.Tx) error {
+		var err error
+		webhooks, err = scanJSON(tx.Bucket(webhooksBucket), nil, func(w entity.Webhook) bool {
+			return w.UserID == userID
+		})
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortByCreation(webhooks, func(w entity.Webhook) time.Time { return w.CreatedAt })
+
+	return webhooks, nil
+}
+
+// DeleteWebhook removes the webhook of a specific user together with its delivery log from the database.
+func (s *WebhookStorage) DeleteWebhook(ctx context.Context, id, userID string) error {
+	return s.update(ctx, func(tx *bbolt.Tx) error {
+		webhooks := tx.Bucket(webhooksBucket)
+
+		var w entity.Webhook
+		ok, err := getJSON(webhooks, []byte(id), &w)
+		if err != nil {
+			return err
+		}
+
+		if !ok || w.UserID != userID {
+			return errors.New("webhook not found")
+		}
+
+		if err := webhooks.Delete([]byte(id)); err != nil {
+			return err
+		}
+
+		err = tx.Bucket(deliveriesBucket).DeleteBucket([]byte(id))
+		if errors.Is(err, bbolt.ErrBucketNotFound) {
+			return nil
+		}
+
+		return err
+	})
+}
+
+// SaveDelivery appends a delivery attempt to the log of its webhook in the database.
+func (s *WebhookStorage) SaveDelivery(ctx context.Context, delivery entity.WebhookDelivery) error {
+	return s.update(ctx, func(tx *bbolt.Tx) error {
+		log, err := tx.Bucket(deliveriesBucket).CreateBucketIfNotExists([]byte(delivery.WebhookID))
+		if err != nil {
+			return err
+		}
+
+		seq, err := log.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		return putJSON(log, binary.BigEndian.AppendUint64(nil, seq), delivery)
+	})
+}
+
+// GetDeliveries retrieves up to limit of the latest delivery attempts of a webhook from the database, newest first.
+func (s *WebhookStorage) GetDeliveries(ctx context.Context, webhookID string, limit int) ([]entity.WebhookDelivery, error) {
+	var deliveries []entity.WebhookDelivery
+	err := s.view(ctx, func(tx *bbolt.Tx) error {
+		log := tx.Bucket(deliveriesBucket).Bucket([]byte(webhookID))
+		if log == nil {
+			return nil
+		}
+
+		c := log.Cursor()
+		for k, data := c.Last(); k != nil && len(deliveries) < limit; k, data = c.Prev() {
+			var d entity.WebhookDelivery
+			if err := json.Unmarshal(data, &d); err != nil {
+				return err
+			}
+
+			deliveries = append(deliveries, d)
+		}
+
+		return nil
+	})
+
+	return deliveries, err
+}
@@ -0,0 +1,57 @@
+package bolt
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+func TestWebhookStorage(t *testing.T) {
+	ctx := context.Background()
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	db, err := Open(filepath.Join(t.TempDir(), "urls.bolt"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	s, err := NewWebhookStorage(db)
+	require.NoError(t, err)
+
+	require.NoError(t, s.SaveWebhook(ctx, entity.Webhook{ID: "2", UserID: "alice", URL: "https://b.example", CreatedAt: created.Add(time.Hour)}))
+	require.NoError(t, s.SaveWebhook(ctx, entity.Webhook{ID: "1", UserID: "alice", URL: "https://a.example", CreatedAt: created}))
+	require.NoError(t, s.SaveWebhook(ctx, entity.Webhook{ID: "3", UserID: "bob", URL: "https://c.example", CreatedAt: created}))
+
+	webhooks, err := s.GetWebhooksByUserID(ctx, "alice")
+	require.NoError(t, err)
+	require.Len(t, webhooks, 2)
+	assert.Equal(t, "1", webhooks[0].ID)
+	assert.Equal(t, "2", webhooks[1].ID)
+
+	for _, attempt := range []int{1, 2, 3} {
+		require.NoError(t, s.SaveDelivery(ctx, entity.WebhookDelivery{ID: "d", WebhookID: "1", Attempt: attempt}))
+	}
+
+	deliveries, err := s.GetDeliveries(ctx, "1", 2)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, 3, deliveries[0].Attempt)
+	assert.Equal(t, 2, deliveries[1].Attempt)
+
+	assert.Error(t, s.DeleteWebhook(ctx, "1", "bob"), "only the owner may delete a webhook")
+	require.NoError(t, s.DeleteWebhook(ctx, "1", "alice"))
+	require.NoError(t, s.DeleteWebhook(ctx, "2", "alice"), "a webhook without deliveries must be deleted")
+
+	deliveries, err = s.GetDeliveries(ctx, "1", 10)
+	require.NoError(t, err)
+	assert.Empty(t, deliveries)
+
+	webhooks, err = s.GetWebhooksByUserID(ctx, "alice")
+	require.NoError(t, err)
+	assert.Empty(t, webhooks)
+}
@@ -0,0 +1,178 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// The workspaces are stored by ID in the workspaces bucket and their members by the workspace and user IDs
+// in the workspace_members bucket. The memberships index maps the user and workspace IDs of a member back
+// to the member, so that the memberships of a user are found without scanning every workspace.
+var (
+	workspacesBucket       = []byte("workspaces")
+	workspaceMembersBucket = []byte("workspace_members")
+	membershipsIndex       = []byte("memberships_idx")
+)
+
+// WorkspaceStorage is a struct that implements the storage.WorkspaceRepository interface,
+// using an embedded bbolt database.
+type WorkspaceStorage struct {
+	kv
+}
+
+// NewWorkspaceStorage initializes a new WorkspaceStorage instance and creates its buckets if they don't exist.
+func NewWorkspaceStorage(db *bbolt.DB) (storage.WorkspaceRepository, error) {
+	s, err := newKV(db, workspacesBucket, workspaceMembersBucket, membershipsIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WorkspaceStorage{kv: s}, nil
+}
+
+// SaveWorkspace stores a new workspace with its first member in the database.
+func (s *WorkspaceStorage) SaveWorkspace(ctx context.Context, workspace entity.Workspace, owner entity.WorkspaceMember) error {
+	return s.update(ctx, func(tx *bbolt.Tx) error {
+		if err := putJSON(tx.Bucket(workspacesBucket), []byte(workspace.ID), workspace); err != nil {
+			return err
+		}
+
+		return putMember(tx, owner)
+	})
+}
+
+// GetWorkspace retrieves the workspace with the given ID from the database. It returns nil if there is no such workspace.
+func (s *WorkspaceStorage) GetWorkspace(ctx context.Context, id string) (*entity.Workspace, error) {
+	var workspace *entity.Workspace
+	err := s.view(ctx, func(tx *bbolt.Tx) error {
+		var w entity.Workspace
+		ok, err := getJSON(tx.Bucket(workspacesBucket), []byte(id), &w)
+		if ok {
+			workspace = &w
+		}
+
+		return err
+	})
+
+	return workspace, err
+}
+
+// SaveMember adds the member to the workspace in the database or replaces the permissions of the member.
+func (s *WorkspaceStorage) SaveMember(ctx context.Context, member entity.WorkspaceMember) error {
+	return s.update(ctx, func(tx *bbolt.Tx) error {
+		if tx.Bucket(workspacesBucket).Get([]byte(member.WorkspaceID)) == nil {
+			return errors.New("workspace not found")
+		}
+
+		var existing entity.WorkspaceMember
+		ok, err := getJSON(tx.Bucket(workspaceMembersBucket), pairKey(member.WorkspaceID, member.UserID), &existing)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			member.CreatedAt = existing.CreatedAt
+		}
+
+		return putMember(tx, member)
+	})
+}
+
+// GetMember retrieves the membership of the user in the workspace from the database.
+// It returns nil if the user is not a member.
+func (s *WorkspaceStorage) GetMember(ctx context.Context, workspaceID, userID string) (*entity.WorkspaceMember, error) {
+	var member *entity.WorkspaceMember
+	err := s.view(ctx, func(tx *bbolt.Tx) error {
+		var m entity.WorkspaceMember
+		ok, err := getJSON(tx.Bucket(workspaceMembersBucket), pairKey(workspaceID, userID), &m)
+		if ok {
+			member = &m
+		}
+
+		return err
+	})
+
+	return member, err
+}
+
+// GetMembers retrieves the members of the workspace from the database, oldest first.
+func (s *WorkspaceStorage) GetMembers(ctx context.Context, workspaceID string) ([]entity.WorkspaceMember, error) {
+	var members []entity.WorkspaceMember
+	err := s.view(ctx, func(tx *bbolt.Tx) error {
+		var err error
+		members, err = scanJSON(tx.Bucket(workspaceMembersBucket), indexPrefix(workspaceID), all[entity.WorkspaceMember])
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortByCreation(members, memberCreatedAt)
+
+	return members, nil
+}
+
+// GetMembershipsByUserID retrieves the memberships of the user in all the workspaces from the database, oldest first.
+func (s *WorkspaceStorage) GetMembershipsByUserID(ctx context.Context, userID string) ([]entity.WorkspaceMember, error) {
+	var memberships []entity.WorkspaceMember
+	err := s.view(ctx, func(tx *bbolt.Tx) error {
+		members := tx.Bucket(workspaceMembersBucket)
+		prefix := indexPrefix(userID)
+
+		c := tx.Bucket(membershipsIndex).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			var m entity.WorkspaceMember
+			ok, err := getJSON(members, pairKey(string(k[len(prefix):]), userID), &m)
+			if err != nil {
+				return err
+			}
+
+			if !ok {
+				return errors.New("memberships index refers to a missing member")
+			}
+
+			memberships = append(memberships, m)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortByCreation(memberships, memberCreatedAt)
+
+	return memberships, nil
+}
+
+// DeleteMember removes the user from the workspace in the database.
+func (s *WorkspaceStorage) DeleteMember(ctx context.Context, workspaceID, userID string) error {
+	return s.update(ctx, func(tx *bbolt.Tx) error {
+		if err := tx.Bucket(workspaceMembersBucket).Delete(pairKey(workspaceID, userID)); err != nil {
+			return err
+		}
+
+		return tx.Bucket(membershipsIndex).Delete(pairKey(userID, workspaceID))
+	})
+}
+
+// putMember stores the member and its entry in the memberships index.
+func putMember(tx *bbolt.Tx, m entity.WorkspaceMember) error {
+	if err := putJSON(tx.Bucket(workspaceMembersBucket), pairKey(m.WorkspaceID, m.UserID), m); err != nil {
+		return err
+	}
+
+	return tx.Bucket(membershipsIndex).Put(pairKey(m.UserID, m.WorkspaceID), []byte{})
+}
+
+func memberCreatedAt(m entity.WorkspaceMember) time.Time {
+	return m.CreatedAt
+}
@@ -0,0 +1,69 @@
+package bolt
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+func TestWorkspaceStorage(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "urls.bolt")
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	db, err := Open(path)
+	require.NoError(t, err)
+
+	s, err := NewWorkspaceStorage(db)
+	require.NoError(t, err)
+
+	owner := entity.WorkspaceMember{WorkspaceID: "ws", UserID: "alice", CanView: true, CanEdit: true, CanDelete: true, CreatedAt: created}
+	require.NoError(t, s.SaveWorkspace(ctx, entity.Workspace{ID: "ws", Name: "team", CreatedAt: created}, owner))
+	require.NoError(t, s.SaveWorkspace(ctx, entity.Workspace{ID: "other", Name: "other", CreatedAt: created},
+		entity.WorkspaceMember{WorkspaceID: "other", UserID: "bob", CanView: true, CreatedAt: created.Add(2 * time.Hour)}))
+	require.NoError(t, s.SaveMember(ctx, entity.WorkspaceMember{WorkspaceID: "ws", UserID: "bob", CanView: true, CreatedAt: created}))
+	require.NoError(t, s.SaveMember(ctx, entity.WorkspaceMember{WorkspaceID: "ws", UserID: "carol", CanView: true, CreatedAt: created}))
+	require.NoError(t, s.SaveMember(ctx, entity.WorkspaceMember{WorkspaceID: "ws", UserID: "bob", CanDelete: true, CreatedAt: created.Add(time.Hour)}))
+	require.NoError(t, s.DeleteMember(ctx, "ws", "carol"))
+	assert.Error(t, s.SaveMember(ctx, entity.WorkspaceMember{WorkspaceID: "unknown", UserID: "bob"}))
+	require.NoError(t, db.Close())
+
+	// The workspaces and their members survive reopening the database.
+	db, err = Open(path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	s, err = NewWorkspaceStorage(db)
+	require.NoError(t, err)
+
+	w, err := s.GetWorkspace(ctx, "ws")
+	require.NoError(t, err)
+	assert.Equal(t, "team", w.Name)
+
+	members, err := s.GetMembers(ctx, "ws")
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+	assert.Equal(t, "alice", members[0].UserID)
+
+	bob := entity.WorkspaceMember{WorkspaceID: "ws", UserID: "bob", CanDelete: true, CreatedAt: created}
+	assert.Equal(t, bob, members[1], "saving a member again must keep the time it joined")
+
+	memberships, err := s.GetMembershipsByUserID(ctx, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, []entity.WorkspaceMember{bob,
+		{WorkspaceID: "other", UserID: "bob", CanView: true, CreatedAt: created.Add(2 * time.Hour)}}, memberships)
+
+	carol, err := s.GetMember(ctx, "ws", "carol")
+	require.NoError(t, err)
+	assert.Nil(t, carol)
+
+	memberships, err = s.GetMembershipsByUserID(ctx, "carol")
+	require.NoError(t, err)
+	assert.Empty(t, memberships)
+}
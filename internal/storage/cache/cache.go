@@ -0,0 +1,320 @@
+// Package cache provides a read-through cache of redirects in front of a storage.Repository.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// Default limits of the cache.
+const (
+	DefaultSize        = 10000
+	DefaultTTL         = time.Minute
+	DefaultNegativeTTL = 10 * time.Second
+)
+
+// cachedErrors are the results of a lookup that are cached like an original URL: unknown, deleted
+// and disabled codes. Any other error is returned to the caller without being cached.
+var cachedErrors = []error{
+	storage.ErrURLNotFound,
+	storage.ErrURLDisabled,
+	storage.ErrURLDeleted,
+}
+
+// Option is a function that configures the cache.
+type Option func(*repository)
+
+// WithSize sets the maximum number of cached codes. The least recently used codes are evicted first.
+func WithSize(size int) Option {
+	return func(r *repository) {
+		r.size = size
+	}
+}
+
+// WithTTL sets the time the original URL of a code is cached for.
+func WithTTL(ttl time.Duration) Option {
+	return func(r *repository) {
+		r.ttl = ttl
+	}
+}
+
+// WithNegativeTTL sets the time an unknown, deleted or disabled code is cached for.
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(r *repository) {
+		r.negativeTTL = ttl
+	}
+}
+
+type entry struct {
+	shortURL    string
+	originalURL string
+	err         error
+	expiresAt   time.Time
+}
+
+type repository struct {
+	storage.Repository
+
+	size        int
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	mu         sync.Mutex
+	entries    map[string]*list.Element
+	lru        *list.List
+	generation uint64
+
+	group  singleflight.Group
+	hits   atomic.Int64
+	misses atomic.Int64
+
+	now func() time.Time
+}
+
+// NewRepository wraps the repository with a bounded cache of the original URLs of the codes, looked up
+// with GetURLRecord so that the entry of a code expires no later than the record it was read from.
+// Concurrent misses of the same code share a single lookup. The changes made through the returned
+// repository invalidate the codes they touch, while changes made by other instances are seen once
+// the cached entries expire. GetStats adds the hits and misses of the cache.
+func NewRepository(repo storage.Repository, opts ...Option) storage.Repository {
+	r := &repository{
+		Repository:  repo,
+		size:        DefaultSize,
+		ttl:         DefaultTTL,
+		negativeTTL: DefaultNegativeTTL,
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+		now:         time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// GetURL returns the original URL of the code from the cache, or looks it up in the repository on a miss.
+func (r *repository) GetURL(ctx context.Context, shortURL string) (string, error) {
+	if e, ok := r.get(shortURL); ok {
+		r.hits.Add(1)
+		return e.originalURL, e.err
+	}
+
+	r.misses.Add(1)
+
+	ch := r.group.DoChan(shortURL, func() (interface{}, error) {
+		return r.load(shortURL), nil
+	})
+
+	select {
+	case res := <-ch:
+		e := res.Val.(*entry)
+		return e.originalURL, e.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// load looks the code up in the repository and caches the result unless the code was invalidated meanwhile.
+// The lookup is shared by the callers waiting for the code, so it doesn't depend on the context of any of them.
+func (r *repository) load(shortURL string) *entry {
+	// A lookup that completed since the miss may have cached the code already.
+	if e, ok := r.get(shortURL); ok {
+		return e
+	}
+
+	r.mu.Lock()
+	generation := r.generation
+	r.mu.Unlock()
+
+	record, err := r.Repository.GetURLRecord(context.Background(), shortURL)
+	e := &entry{shortURL: shortURL, err: err}
+
+	switch {
+	case err == nil:
+		e.originalURL = record.OriginalURL
+		e.expiresAt = r.now().Add(r.ttl)
+
+		if record.ExpiresAt != nil && record.ExpiresAt.Before(e.expiresAt) {
+			e.expiresAt = *record.ExpiresAt
+		}
+	case isCached(err):
+		e.expiresAt = r.now().Add(r.negativeTTL)
+	default:
+		return e
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.generation == generation {
+		r.put(e)
+	}
+
+	return e
+}
+
+func (r *repository) get(shortURL string) (*entry, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	el, ok := r.entries[shortURL]
+	if !ok {
+		return nil, false
+	}
+
+	e := el.Value.(*entry)
+	if !r.now().Before(e.expiresAt) {
+		r.lru.Remove(el)
+		delete(r.entries, shortURL)
+
+		return nil, false
+	}
+
+	r.lru.MoveToFront(el)
+
+	return e, true
+}
+
+// put stores the entry as the most recently used one and evicts the least recently used entries
+// above the size. It must be called with the mutex held.
+func (r *repository) put(e *entry) {
+	if el, ok := r.entries[e.shortURL]; ok {
+		el.Value = e
+		r.lru.MoveToFront(el)
+
+		return
+	}
+
+	r.entries[e.shortURL] = r.lru.PushFront(e)
+
+	for r.lru.Len() > r.size {
+		oldest := r.lru.Back()
+		r.lru.Remove(oldest)
+		delete(r.entries, oldest.Value.(*entry).shortURL)
+	}
+}
+
+// invalidate removes the codes from the cache. It also makes the lookups in progress
+// skip caching their results, which may predate the change.
+func (r *repository) invalidate(shortURLs ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.generation++
+
+	for _, shortURL := range shortURLs {
+		if el, ok := r.entries[shortURL]; ok {
+			r.lru.Remove(el)
+			delete(r.entries, shortURL)
+		}
+	}
+}
+
+// SaveURL stores the URL record and invalidates its code, which may be cached as unknown.
+func (r *repository) SaveURL(ctx context.Context, url entity.URLRecord) error {
+	defer r.invalidate(url.ShortURL)
+
+	return r.Repository.SaveURL(ctx, url)
+}
+
+// SaveURLBatch stores the URL records and invalidates their codes, which may be cached as unknown.
+func (r *repository) SaveURLBatch(ctx context.Context, urls []*entity.URLRecord) error {
+	shortURLs := make([]string, 0, len(urls))
+	for _, url := range urls {
+		shortURLs = append(shortURLs, url.ShortURL)
+	}
+
+	defer r.invalidate(shortURLs...)
+
+	return r.Repository.SaveURLBatch(ctx, urls)
+}
+
+// ReassignURL moves the URL to another user and invalidates its code.
+func (r *repository) ReassignURL(ctx context.Context, shortURL, fromUserID, toUserID string) error {
+	defer r.invalidate(shortURL)
+
+	return r.Repository.ReassignURL(ctx, shortURL, fromUserID, toUserID)
+}
+
+// SetURLDisabled disables or enables the URL and invalidates its code.
+func (r *repository) SetURLDisabled(ctx context.Context, shortURL string, disabled bool) ([]entity.URLRecord, error) {
+	defer r.invalidate(shortURL)
+
+	return r.Repository.SetURLDisabled(ctx, shortURL, disabled)
+}
+
+// SetURLWorkspace moves the URL to a workspace and invalidates its code.
+func (r *repository) SetURLWorkspace(ctx context.Context, shortURL, userID, workspaceID string) error {
+	defer r.invalidate(shortURL)
+
+	return r.Repository.SetURLWorkspace(ctx, shortURL, userID, workspaceID)
+}
+
+// SavePreview stores the preview of the URL and invalidates its code.
+func (r *repository) SavePreview(ctx context.Context, shortURL string, preview entity.Preview) error {
+	defer r.invalidate(shortURL)
+
+	return r.Repository.SavePreview(ctx, shortURL, preview)
+}
+
+// DeleteURLBatch marks the URLs as deleted and invalidates their codes.
+func (r *repository) DeleteURLBatch(urls []string, user string) ([]entity.URLRecord, error) {
+	defer r.invalidate(urls...)
+
+	return r.Repository.DeleteURLBatch(urls, user)
+}
+
+// ExpireURLs marks the expired URLs as deleted and invalidates their codes.
+func (r *repository) ExpireURLs(ctx context.Context, now time.Time) ([]entity.URLRecord, error) {
+	expired, err := r.Repository.ExpireURLs(ctx, now)
+
+	shortURLs := make([]string, 0, len(expired))
+	for _, url := range expired {
+		shortURLs = append(shortURLs, url.ShortURL)
+	}
+
+	r.invalidate(shortURLs...)
+
+	return expired, err
+}
+
+// GetStats retrieves the stats of the repository and adds the stats of the cache.
+func (r *repository) GetStats(ctx context.Context) (*entity.Stats, error) {
+	stats, err := r.Repository.GetStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	size := r.lru.Len()
+	r.mu.Unlock()
+
+	stats.Cache = &entity.CacheStats{
+		Hits:   r.hits.Load(),
+		Misses: r.misses.Load(),
+		Size:   size,
+	}
+
+	return stats, nil
+}
+
+func isCached(err error) bool {
+	for _, target := range cachedErrors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
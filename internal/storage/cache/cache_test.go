@@ -0,0 +1,290 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/mocks"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/memory"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/storagetest"
+)
+
+func setupRepository(t *testing.T, opts ...Option) (*repository, *mocks.MockRepository, *time.Time) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockRepository(ctrl)
+
+	r := NewRepository(repo, opts...).(*repository)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	return r, repo, &now
+}
+
+func TestRepository_GetURL(t *testing.T) {
+	ctx := context.Background()
+	errInternal := errors.New("internal error")
+
+	tests := []struct {
+		name    string
+		prepare func(repo *mocks.MockRepository)
+		calls   int
+		want    string
+		wantErr error
+	}{
+		{
+			name: "should look up original url once",
+			prepare: func(repo *mocks.MockRepository) {
+				repo.EXPECT().GetURLRecord(gomock.Any(), "abc").Return(&entity.URLRecord{OriginalURL: "https://example.com"}, nil).Times(1)
+			},
+			calls: 3,
+			want:  "https://example.com",
+		},
+		{
+			name: "should cache unknown code",
+			prepare: func(repo *mocks.MockRepository) {
+				repo.EXPECT().GetURLRecord(gomock.Any(), "abc").Return(nil, storage.ErrURLNotFound).Times(1)
+			},
+			calls:   3,
+			wantErr: storage.ErrURLNotFound,
+		},
+		{
+			name: "should cache deleted code",
+			prepare: func(repo *mocks.MockRepository) {
+				repo.EXPECT().GetURLRecord(gomock.Any(), "abc").Return(nil, storage.ErrURLDeleted).Times(1)
+			},
+			calls:   2,
+			wantErr: storage.ErrURLDeleted,
+		},
+		{
+			name: "should not cache other errors",
+			prepare: func(repo *mocks.MockRepository) {
+				repo.EXPECT().GetURLRecord(gomock.Any(), "abc").Return(nil, errInternal).Times(2)
+			},
+			calls:   2,
+			wantErr: errInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, repo, _ := setupRepository(t)
+			tt.prepare(repo)
+
+			for i := 0; i < tt.calls; i++ {
+				got, err := r.GetURL(ctx, "abc")
+				assert.Equal(t, tt.wantErr, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRepository_GetURL_expires(t *testing.T) {
+	r, repo, now := setupRepository(t, WithTTL(time.Minute), WithNegativeTTL(time.Second))
+	ctx := context.Background()
+
+	gomock.InOrder(
+		repo.EXPECT().GetURLRecord(gomock.Any(), "abc").Return(nil, storage.ErrURLNotFound),
+		repo.EXPECT().GetURLRecord(gomock.Any(), "abc").Return(&entity.URLRecord{OriginalURL: "https://example.com"}, nil),
+		repo.EXPECT().GetURLRecord(gomock.Any(), "abc").Return(&entity.URLRecord{OriginalURL: "https://example.org"}, nil),
+	)
+
+	_, err := r.GetURL(ctx, "abc")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	*now = now.Add(time.Second)
+
+	got, err := r.GetURL(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", got)
+
+	*now = now.Add(59 * time.Second)
+
+	got, err = r.GetURL(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", got)
+
+	*now = now.Add(time.Second)
+
+	got, err = r.GetURL(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.org", got)
+}
+
+func TestRepository_GetURL_expiresWithRecord(t *testing.T) {
+	r, repo, now := setupRepository(t, WithTTL(time.Minute))
+	ctx := context.Background()
+
+	expiresAt := now.Add(10 * time.Second)
+
+	gomock.InOrder(
+		repo.EXPECT().GetURLRecord(gomock.Any(), "abc").Return(&entity.URLRecord{OriginalURL: "https://example.com", ExpiresAt: &expiresAt}, nil),
+		repo.EXPECT().GetURLRecord(gomock.Any(), "abc").Return(nil, storage.ErrURLDeleted),
+	)
+
+	got, err := r.GetURL(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", got)
+
+	*now = now.Add(9 * time.Second)
+
+	got, err = r.GetURL(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", got)
+
+	// The entry expires with the record instead of after the TTL.
+	*now = now.Add(time.Second)
+
+	_, err = r.GetURL(ctx, "abc")
+	assert.ErrorIs(t, err, storage.ErrURLDeleted)
+}
+
+func TestRepository_GetURL_evictsLeastRecentlyUsed(t *testing.T) {
+	r, repo, _ := setupRepository(t, WithSize(2))
+	ctx := context.Background()
+
+	repo.EXPECT().GetURLRecord(gomock.Any(), "a").Return(&entity.URLRecord{OriginalURL: "https://a.example.com"}, nil).Times(1)
+	repo.EXPECT().GetURLRecord(gomock.Any(), "b").Return(&entity.URLRecord{OriginalURL: "https://b.example.com"}, nil).Times(2)
+	repo.EXPECT().GetURLRecord(gomock.Any(), "c").Return(&entity.URLRecord{OriginalURL: "https://c.example.com"}, nil).Times(1)
+
+	for _, code := range []string{"a", "b", "a", "c", "a", "b"} {
+		_, err := r.GetURL(ctx, code)
+		require.NoError(t, err)
+	}
+}
+
+func TestRepository_GetURL_collapsesConcurrentMisses(t *testing.T) {
+	r, repo, _ := setupRepository(t)
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	repo.EXPECT().GetURLRecord(gomock.Any(), "abc").DoAndReturn(func(context.Context, string) (*entity.URLRecord, error) {
+		<-release
+		return &entity.URLRecord{OriginalURL: "https://example.com"}, nil
+	}).Times(1)
+
+	const callers = 10
+
+	var wg sync.WaitGroup
+	results := make(chan string, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			got, err := r.GetURL(ctx, "abc")
+			assert.NoError(t, err)
+			results <- got
+		}()
+	}
+
+	// Wait for every caller to miss before the lookup completes.
+	require.Eventually(t, func() bool { return r.misses.Load() == callers }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	for got := range results {
+		assert.Equal(t, "https://example.com", got)
+	}
+}
+
+func TestRepository_invalidates(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		change func(r *repository, repo *mocks.MockRepository) error
+	}{
+		{
+			name: "should invalidate deleted codes",
+			change: func(r *repository, repo *mocks.MockRepository) error {
+				repo.EXPECT().DeleteURLBatch([]string{"abc", "def"}, "user").Return(nil, nil)
+				_, err := r.DeleteURLBatch([]string{"abc", "def"}, "user")
+				return err
+			},
+		},
+		{
+			name: "should invalidate disabled code",
+			change: func(r *repository, repo *mocks.MockRepository) error {
+				repo.EXPECT().SetURLDisabled(gomock.Any(), "abc", true).Return(nil, nil)
+				_, err := r.SetURLDisabled(ctx, "abc", true)
+				return err
+			},
+		},
+		{
+			name: "should invalidate saved code",
+			change: func(r *repository, repo *mocks.MockRepository) error {
+				repo.EXPECT().SaveURL(gomock.Any(), entity.URLRecord{ShortURL: "abc"}).Return(nil)
+				return r.SaveURL(ctx, entity.URLRecord{ShortURL: "abc"})
+			},
+		},
+		{
+			name: "should invalidate saved batch codes",
+			change: func(r *repository, repo *mocks.MockRepository) error {
+				urls := []*entity.URLRecord{{ShortURL: "abc"}}
+				repo.EXPECT().SaveURLBatch(gomock.Any(), urls).Return(nil)
+				return r.SaveURLBatch(ctx, urls)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, repo, _ := setupRepository(t)
+
+			repo.EXPECT().GetURLRecord(gomock.Any(), "abc").Return(&entity.URLRecord{OriginalURL: "https://example.com"}, nil).Times(2)
+
+			_, err := r.GetURL(ctx, "abc")
+			require.NoError(t, err)
+
+			require.NoError(t, tt.change(r, repo))
+
+			_, err = r.GetURL(ctx, "abc")
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestRepository_GetStats(t *testing.T) {
+	r, repo, _ := setupRepository(t)
+	ctx := context.Background()
+
+	repo.EXPECT().GetURLRecord(gomock.Any(), "abc").Return(&entity.URLRecord{OriginalURL: "https://example.com"}, nil)
+	repo.EXPECT().GetStats(gomock.Any()).Return(&entity.Stats{URLs: 1, Users: 1}, nil)
+
+	for i := 0; i < 3; i++ {
+		_, err := r.GetURL(ctx, "abc")
+		require.NoError(t, err)
+	}
+
+	stats, err := r.GetStats(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, &entity.Stats{
+		URLs:  1,
+		Users: 1,
+		Cache: &entity.CacheStats{Hits: 2, Misses: 1, Size: 1},
+	}, stats)
+}
+
+func TestRepository_conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Repository {
+		log, _ := logger.Initialize("debug")
+		urls, err := memory.NewInMemStorage("", log)
+		require.NoError(t, err)
+
+		return NewRepository(urls)
+	})
+}
@@ -1,16 +1,180 @@
 package entity
 
-// URLRecord represents a URL stored in the database.
+import "time"
+
+// URLRecord represents a URL stored in the database. A URL with a WorkspaceID is shared with
+// the members of the workspace besides the user who created it. A URL with an ExpiresAt is deleted
+// once it expires, and a URL with a ClickThreshold notifies its owner once its Clicks reach it.
 type URLRecord struct {
-	UUID        string `json:"uuid"`
-	ShortURL    string `json:"short_url"`
-	OriginalURL string `json:"original_url"`
-	UserID      string `json:"user_id"`
-	DeletedFlag bool   `json:"is_deleted,omitempty"`
+	UUID           string     `json:"uuid"`
+	ShortURL       string     `json:"short_url"`
+	OriginalURL    string     `json:"original_url"`
+	UserID         string     `json:"user_id"`
+	DeletedFlag    bool       `json:"is_deleted,omitempty"`
+	Disabled       bool       `json:"is_disabled,omitempty"`
+	WorkspaceID    string     `json:"workspace_id,omitempty"`
+	Preview        *Preview   `json:"preview,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	Clicks         int64      `json:"clicks,omitempty"`
+	ClickThreshold int64      `json:"click_threshold,omitempty"`
+}
+
+// Expired reports whether the record expired at or before the time. An expired record is treated
+// as deleted even before it's marked as deleted.
+func (r URLRecord) Expired(now time.Time) bool {
+	return r.ExpiresAt != nil && !r.ExpiresAt.After(now)
+}
+
+// Gone reports whether the record is deleted or expired at the time.
+func (r URLRecord) Gone(now time.Time) bool {
+	return r.DeletedFlag || r.Expired(now)
+}
+
+// Preview represents Open Graph metadata extracted from the destination of a short URL.
+type Preview struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
 }
 
 // Stats represents statistical data about the URLs and Users.
+// Cache is set if the redirects are served through a cache.
 type Stats struct {
 	URLs  int
 	Users int
+	Cache *CacheStats
+}
+
+// CacheStats represents the hits and misses of the redirect cache and the number of cached codes.
+type CacheStats struct {
+	Hits   int64
+	Misses int64
+	Size   int
+}
+
+// Webhook represents an endpoint registered by a user to receive link lifecycle events.
+type Webhook struct {
+	ID        string
+	UserID    string
+	URL       string
+	Secret    string
+	Events    []string
+	CreatedAt time.Time
+}
+
+// WebhookDelivery represents a single attempt to deliver an event to a webhook.
+type WebhookDelivery struct {
+	ID         string
+	WebhookID  string
+	EventID    string
+	EventType  string
+	Attempt    int
+	StatusCode int
+	Error      string
+	Delivered  bool
+	CreatedAt  time.Time
+}
+
+// Quota represents the limits overridden for a specific user. A zero limit means no limit.
+type Quota struct {
+	UserID       string
+	MaxLinks     int
+	MaxBatchSize int
+	MaxURLLength int
+}
+
+// APIKey represents a key a user issued for programmatic clients. Only the hash of the key is stored.
+// A zero ExpiresAt means the key never expires.
+type APIKey struct {
+	ID        string
+	UserID    string
+	Name      string
+	Hash      string
+	Scopes    []string
+	ExpiresAt time.Time
+	Revoked   bool
+	CreatedAt time.Time
+}
+
+// User represents a registered account. Only the bcrypt hash of the password is stored.
+// An empty Role is the role of an ordinary user.
+type User struct {
+	ID           string
+	Username     string
+	PasswordHash string
+	Role         string
+	CreatedAt    time.Time
+}
+
+// RefreshToken represents a long-lived token a user exchanges for new access tokens.
+// Only the hash of the token is stored. A token is revoked when it's exchanged, which is
+// reported by Rotated, or when the user logs out.
+type RefreshToken struct {
+	ID        string
+	UserID    string
+	Hash      string
+	ExpiresAt time.Time
+	RevokedAt time.Time
+	Rotated   bool
+	CreatedAt time.Time
+}
+
+// Workspace represents a group of users sharing links.
+type Workspace struct {
+	ID        string
+	Name      string
+	CreatedAt time.Time
+}
+
+// WorkspaceMember represents the membership of a user in a workspace and what the user is allowed
+// to do with the links of the workspace.
+type WorkspaceMember struct {
+	WorkspaceID string
+	UserID      string
+	CanView     bool
+	CanEdit     bool
+	CanDelete   bool
+	CreatedAt   time.Time
+}
+
+// AuditEntry represents a change recorded in the audit log. Before and After hold the changed values as JSON
+// and are empty when there was nothing before or after the change, e.g. for created and deleted objects.
+type AuditEntry struct {
+	ID        string    `json:"id"`
+	ActorID   string    `json:"actor_id"`
+	Action    string    `json:"action"`
+	Target    string    `json:"target"`
+	Transport string    `json:"transport"`
+	ClientIP  string    `json:"client_ip"`
+	RequestID string    `json:"request_id"`
+	Before    string    `json:"before,omitempty"`
+	After     string    `json:"after,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuditFilter selects entries of the audit log. Empty fields match any entry.
+type AuditFilter struct {
+	ActorID   string
+	Action    string
+	Target    string
+	Transport string
+	Since     time.Time
+	Until     time.Time
+	Limit     int
+	Offset    int
+}
+
+// Match reports whether the entry satisfies the filter, ignoring the Limit and Offset.
+func (f AuditFilter) Match(e AuditEntry) bool {
+	switch {
+	case f.ActorID != "" && e.ActorID != f.ActorID,
+		f.Action != "" && e.Action != f.Action,
+		f.Target != "" && e.Target != f.Target,
+		f.Transport != "" && e.Transport != f.Transport,
+		!f.Since.IsZero() && e.CreatedAt.Before(f.Since),
+		!f.Until.IsZero() && !e.CreatedAt.Before(f.Until):
+		return false
+	}
+
+	return true
 }
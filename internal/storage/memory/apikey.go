@@ -0,0 +1,129 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// APIKeyStorage keeps the API keys of users in memory and, if it has a file, appends every change
+// of a key to the file, so the keys and their revocations survive a restart.
+type APIKeyStorage struct {
+	keys     map[string]entity.APIKey
+	filePath string
+	mu       sync.Mutex
+}
+
+// NewAPIKeyStorage initializes a new APIKeyStorage instance that keeps the keys in memory only.
+func NewAPIKeyStorage() storage.APIKeyRepository {
+	return &APIKeyStorage{
+		keys: make(map[string]entity.APIKey),
+	}
+}
+
+// NewFileAPIKeyStorage initializes a new APIKeyStorage instance persisted to the file at filePath
+// and restores the keys from the file if it exists. It fails if the file can't be read.
+func NewFileAPIKeyStorage(filePath string) (storage.APIKeyRepository, error) {
+	s := &APIKeyStorage{
+		keys:     make(map[string]entity.APIKey),
+		filePath: filePath,
+	}
+
+	err := replayJSON(filePath, func(dec *json.Decoder) error {
+		var k entity.APIKey
+		if err := dec.Decode(&k); err != nil {
+			return err
+		}
+
+		s.keys[k.ID] = k
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// SaveAPIKey stores a new API key.
+func (s *APIKeyStorage) SaveAPIKey(_ context.Context, key entity.APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.writeKey(key); err != nil {
+		return err
+	}
+
+	s.keys[key.ID] = key
+
+	return nil
+}
+
+// GetAPIKeyByHash retrieves the API key with the given hash. It returns nil if there is no such key.
+func (s *APIKeyStorage) GetAPIKeyByHash(_ context.Context, hash string) (*entity.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, k := range s.keys {
+		if k.Hash == hash {
+			return &k, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// GetAPIKeysByUserID retrieves all the API keys of a specific user, including the revoked ones, oldest first.
+func (s *APIKeyStorage) GetAPIKeysByUserID(_ context.Context, userID string) ([]entity.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var keys []entity.APIKey
+	for _, k := range s.keys {
+		if k.UserID == userID {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+
+	return keys, nil
+}
+
+// RevokeAPIKey marks the API key of a specific user as revoked.
+func (s *APIKeyStorage) RevokeAPIKey(_ context.Context, id, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.keys[id]
+	if !ok || k.UserID != userID {
+		return errors.New("api key not found")
+	}
+
+	k.Revoked = true
+
+	if err := s.writeKey(k); err != nil {
+		return err
+	}
+
+	s.keys[id] = k
+
+	return nil
+}
+
+// writeKey appends the key to the file, where its last entry replaces the previous ones on restore.
+func (s *APIKeyStorage) writeKey(k entity.APIKey) error {
+	if s.filePath == "" {
+		return nil
+	}
+
+	return appendJSON(s.filePath, k)
+}
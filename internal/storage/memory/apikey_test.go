@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+func TestFileAPIKeyStorage(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "db.json.keys")
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	s, err := NewFileAPIKeyStorage(path)
+	require.NoError(t, err)
+
+	first := entity.APIKey{ID: "1", UserID: "alice", Name: "ci", Hash: "h1", Scopes: []string{"urls:read"}, CreatedAt: created}
+	second := entity.APIKey{ID: "2", UserID: "alice", Name: "deploy", Hash: "h2", ExpiresAt: created.Add(time.Hour), CreatedAt: created.Add(time.Minute)}
+	require.NoError(t, s.SaveAPIKey(ctx, first))
+	require.NoError(t, s.SaveAPIKey(ctx, second))
+	require.NoError(t, s.RevokeAPIKey(ctx, "1", "alice"))
+	assert.Error(t, s.RevokeAPIKey(ctx, "2", "bob"))
+
+	restored, err := NewFileAPIKeyStorage(path)
+	require.NoError(t, err)
+
+	first.Revoked = true
+
+	keys, err := restored.GetAPIKeysByUserID(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, []entity.APIKey{first, second}, keys)
+
+	key, err := restored.GetAPIKeyByHash(ctx, "h2")
+	require.NoError(t, err)
+	assert.Equal(t, &second, key)
+
+	require.NoError(t, os.WriteFile(path, []byte("{\"ID\":"), 0600))
+
+	_, err = NewFileAPIKeyStorage(path)
+	assert.Error(t, err, "a damaged file should not be overwritten")
+}
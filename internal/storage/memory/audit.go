@@ -0,0 +1,134 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// AuditStorage keeps the audit log in memory and appends every entry to a file,
+// so the log survives a restart. The file is only ever appended to.
+type AuditStorage struct {
+	entries  []entity.AuditEntry
+	filePath string
+	logger   *logger.Logger
+	mu       sync.Mutex
+}
+
+// NewAuditStorage initializes a new AuditStorage instance and restores the entries from the file if it exists.
+// An empty filePath keeps the log in memory only.
+func NewAuditStorage(filePath string, logger *logger.Logger) storage.AuditRepository {
+	s := &AuditStorage{
+		filePath: filePath,
+		logger:   logger,
+	}
+
+	if err := s.restoreFromFile(); err != nil {
+		logger.Error("cannot restore audit log from file", zap.Error(err))
+	}
+
+	return s
+}
+
+// SaveAuditEntries appends the entries to the file in a single write and to the log in memory.
+// The entries are not added to the log if they cannot be written to the file.
+func (s *AuditStorage) SaveAuditEntries(_ context.Context, entries []entity.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.writeEntriesToFile(entries); err != nil {
+		return err
+	}
+
+	s.entries = append(s.entries, entries...)
+
+	return nil
+}
+
+// GetAuditEntries retrieves the entries matching the filter, newest first.
+func (s *AuditStorage) GetAuditEntries(_ context.Context, filter entity.AuditFilter) ([]entity.AuditEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var entries []entity.AuditEntry
+	skipped := 0
+	for i := len(s.entries) - 1; i >= 0 && len(entries) < filter.Limit; i-- {
+		if !filter.Match(s.entries[i]) {
+			continue
+		}
+
+		if skipped < filter.Offset {
+			skipped++
+			continue
+		}
+
+		entries = append(entries, s.entries[i])
+	}
+
+	return entries, nil
+}
+
+func (s *AuditStorage) restoreFromFile() error {
+	if s.filePath == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(s.filePath, os.O_RDONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			s.logger.Error("failed to close the file", zap.Error(err))
+		}
+	}()
+
+	dec := json.NewDecoder(f)
+	for dec.More() {
+		var e entity.AuditEntry
+		if err := dec.Decode(&e); err != nil {
+			return err
+		}
+
+		s.entries = append(s.entries, e)
+	}
+
+	return nil
+}
+
+func (s *AuditStorage) writeEntriesToFile(entries []entity.AuditEntry) error {
+	if s.filePath == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(s.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			s.logger.Error("failed to close the file", zap.Error(err))
+		}
+	}()
+
+	// The entries are encoded into a buffer first, so that they're appended with one write.
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	_, err = f.Write(buf.Bytes())
+
+	return err
+}
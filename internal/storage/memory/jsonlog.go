@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// appendJSON appends the value to the file as a line of JSON, creating the file if it doesn't exist.
+func appendJSON(path string, v interface{}) (err error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	return json.NewEncoder(f).Encode(v)
+}
+
+// replayJSON decodes the lines of JSON of the file in order and passes each of them to apply with the
+// decoder positioned on it. A missing file has no lines. An undecodable line fails the replay, so a
+// damaged file is repaired by hand instead of being overwritten by the next appends.
+func replayJSON(path string, apply func(dec *json.Decoder) error) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	for line := 1; dec.More(); line++ {
+		if err := apply(dec); err != nil {
+			return fmt.Errorf("%s: entry %d: %w", path, line, err)
+		}
+	}
+
+	return nil
+}
+
+// rewriteJSON replaces the file with the values, a line of JSON each. The values are written to a temporary
+// file that atomically replaces the file once it's synced, so a crash leaves either the old file or the new one.
+func rewriteJSON(path string, values []interface{}) error {
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	for _, v := range values {
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+	}
+
+	tmpPath := path + ".tmp"
+
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(buf.Bytes())
+	if err == nil {
+		err = tmp.Sync()
+	}
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return syncDir(filepath.Dir(path))
+}
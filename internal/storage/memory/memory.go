@@ -2,11 +2,12 @@ package memory
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"sync"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -15,193 +16,1278 @@ import (
 	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
 )
 
+// DefaultSyncInterval is the time between the syncs of the log with the SyncInterval policy.
+const DefaultSyncInterval = time.Second
+
+// ErrReadOnly is returned by the changes to an InMemStorage opened with WithReadOnly.
+var ErrReadOnly = errors.New("storage is read-only")
+
 // InMemStorage maintains an in-memory representation of URL shortening data.
+// Every change is appended to a log of operations in the storage file before it's applied,
+// so that the data survives a restart.
 type InMemStorage struct {
 	urls            map[string]string
+	active          map[string]int // The number of records of every shortened URL that aren't deleted.
 	users           map[string][]entity.URLRecord
+	previews        map[string]entity.Preview
+	disabled        map[string]bool
+	workspaces      storage.WorkspaceRepository
 	storageFilePath string
+	log             *wal
+	syncPolicy      SyncPolicy
+	syncInterval    time.Duration
+	compactInterval time.Duration
+	readOnly        bool
+	stop            chan struct{}
+	done            chan struct{}
 	logger          *logger.Logger
 	mu              sync.Mutex
 }
 
+// Option configures optional features of the InMemStorage.
+type Option func(s *InMemStorage)
+
+// WithWorkspaces resolves the URLs a user can view and delete through the memberships
+// of the user in the workspaces of the repo.
+func WithWorkspaces(repo storage.WorkspaceRepository) Option {
+	return func(s *InMemStorage) {
+		s.workspaces = repo
+	}
+}
+
+// WithSyncPolicy sets when the changes appended to the log are synced to the disk. The default is SyncAlways.
+func WithSyncPolicy(policy SyncPolicy) Option {
+	return func(s *InMemStorage) {
+		s.syncPolicy = policy
+	}
+}
+
+// WithCompactInterval periodically replaces the log with a snapshot of the records, if it changed since the
+// last compaction, so that the log doesn't grow with every update. Zero, the default, disables compaction.
+func WithCompactInterval(interval time.Duration) Option {
+	return func(s *InMemStorage) {
+		s.compactInterval = interval
+	}
+}
+
+// WithReadOnly restores the records from the file without ever writing to it, e.g. to copy them to another
+// storage backend: the file must exist, it's neither converted from the legacy format nor truncated after
+// a torn write, and the changes to the records fail with ErrReadOnly.
+func WithReadOnly() Option {
+	return func(s *InMemStorage) {
+		s.readOnly = true
+	}
+}
+
 // NewInMemStorage initializes a new InMemStorage instance with provided inputs
 // and restore previous URL shortening data from the file if it exists.
-func NewInMemStorage(filePath string, logger *logger.Logger) storage.Repository {
+// An empty filePath keeps the data in memory only.
+// It fails if the file can't be restored entirely, e.g. with ErrCorruptLog, or opened for appending.
+// A corrupt file is left as it is for a manual repair, since the changes appended to it after the corrupt
+// record would be lost on the next restore.
+func NewInMemStorage(filePath string, logger *logger.Logger, opts ...Option) (storage.Repository, error) {
 	s := &InMemStorage{
 		urls:            make(map[string]string),
+		active:          make(map[string]int),
 		users:           make(map[string][]entity.URLRecord),
+		previews:        make(map[string]entity.Preview),
+		disabled:        make(map[string]bool),
 		storageFilePath: filePath,
+		syncPolicy:      SyncAlways,
+		syncInterval:    DefaultSyncInterval,
 		logger:          logger,
 	}
 
-	if err := s.restoreFromFile(); err != nil {
-		logger.Error("cannot restore url records from file", zap.Error(err))
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.readOnly {
+		if _, err := os.Stat(filePath); err != nil {
+			return nil, fmt.Errorf("open storage file: %w", err)
+		}
+	}
+
+	legacy, err := s.restoreFromFile()
+	if err != nil {
+		return nil, fmt.Errorf("restore url records: %w", err)
+	}
+
+	if s.readOnly {
+		return s, nil
+	}
+
+	if err := s.openLog(); err != nil {
+		return nil, fmt.Errorf("open storage file: %w", err)
+	}
+
+	if legacy {
+		logger.Info("converting storage file to operation log")
+
+		if err := s.log.compact(s.snapshot()); err != nil {
+			logger.Error("cannot convert storage file", zap.Error(err))
+		}
+	}
+
+	if s.log != nil && (s.syncPolicy == SyncInterval || s.compactInterval > 0) {
+		s.stop = make(chan struct{})
+		s.done = make(chan struct{})
+
+		go s.maintainLog()
 	}
 
-	return s
+	return s, nil
 }
 
-// SaveURL stores a new URL record in InMemStorage and writes the record to
+// SaveURL stores a new URL record in InMemStorage and logs its creation to
 // the file if a filePath was specified during initialization.
-func (s *InMemStorage) SaveURL(_ context.Context, r entity.URLRecord) error {
+// The record is disabled if its shortened URL was disabled for the other users.
+// It returns storage.ErrURLExists if the user already has a record with the shortened URL.
+func (s *InMemStorage) SaveURL(ctx context.Context, r entity.URLRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.urls[r.ShortURL] = r.OriginalURL
-	s.users[r.UserID] = append(s.users[r.UserID], r)
+	return s.saveRecords([]entity.URLRecord{r})
+}
+
+// SaveURLBatch stores a batch of URL records in InMemStorage and logs their creation
+// as a single operation, so that either all or none of them are restored.
+// It returns storage.ErrURLExists and stores none of the records if the user of a record already
+// has a record with its shortened URL, including the earlier records of the batch.
+func (s *InMemStorage) SaveURLBatch(ctx context.Context, urls []*entity.URLRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := make([]entity.URLRecord, 0, len(urls))
+	for _, r := range urls {
+		records = append(records, *r)
+	}
 
-	if err := s.writeRecordToFile(r); err != nil {
+	return s.saveRecords(records)
+}
+
+// saveRecords logs the creation of the records and adds them. It must be called with the mutex held.
+func (s *InMemStorage) saveRecords(records []entity.URLRecord) error {
+	now := time.Now()
+
+	// The deleted and expired records of the users give way to the new ones.
+	var replaced []string
+
+	seen := make(map[[2]string]bool, len(records))
+	for i := range records {
+		key := [2]string{records[i].UserID, records[i].ShortURL}
+		if seen[key] {
+			return fmt.Errorf("%w: %s", storage.ErrURLExists, records[i].ShortURL)
+		}
+
+		if old, ok := s.ownRecord(records[i].UserID, records[i].ShortURL); ok {
+			if !old.Gone(now) {
+				return fmt.Errorf("%w: %s", storage.ErrURLExists, records[i].ShortURL)
+			}
+
+			replaced = append(replaced, old.UUID)
+		}
+
+		seen[key] = true
+		records[i].Disabled = s.disabled[records[i].ShortURL]
+	}
+
+	if err := s.appendOp(walOp{Op: opCreate, Records: records, UUIDs: replaced}); err != nil {
 		return err
 	}
 
+	if len(replaced) > 0 {
+		s.remove(replaced, s.owners())
+	}
+
+	for _, r := range records {
+		s.urls[r.ShortURL] = r.OriginalURL
+		s.active[r.ShortURL]++
+		s.users[r.UserID] = append(s.users[r.UserID], r)
+
+		if r.Disabled {
+			s.disabled[r.ShortURL] = true
+		}
+	}
+
 	return nil
 }
 
-// SaveURLBatch stores a batch of URL records in InMemStorage by iterating through the input
-// and calling SaveURL function for each record.
-func (s *InMemStorage) SaveURLBatch(ctx context.Context, urls []*entity.URLRecord) error {
-	for _, r := range urls {
-		if err := s.SaveURL(ctx, *r); err != nil {
-			return err
+// GetURL retrieves the original URL from InMemStorage given its shortened version,
+// unless all of its records have been deleted or have expired, or it has been disabled.
+func (s *InMemStorage) GetURL(ctx context.Context, shortURL string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.liveRecord(shortURL, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	return r.OriginalURL, nil
+}
+
+// GetURLRecord retrieves the first URL record with the shortened version that is neither deleted nor expired,
+// including its preview, from InMemStorage, unless there is no such record or the URL has been disabled.
+func (s *InMemStorage) GetURLRecord(ctx context.Context, shortURL string) (*entity.URLRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, err := s.liveRecord(shortURL, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return s.withPreview(*r), nil
+}
+
+// liveRecord returns the first record with the shortened URL that is neither deleted nor expired at the time.
+// The expired records count as deleted. It must be called with the mutex held.
+func (s *InMemStorage) liveRecord(shortURL string, now time.Time) (*entity.URLRecord, error) {
+	if err := s.checkAvailable(shortURL); err != nil {
+		return nil, err
+	}
+
+	for _, records := range s.users {
+		for i := range records {
+			if records[i].ShortURL == shortURL && !records[i].Gone(now) {
+				return &records[i], nil
+			}
 		}
 	}
 
+	return nil, storage.ErrURLDeleted
+}
+
+// checkAvailable returns an error if the shortened URL can't be followed. It must be called with the mutex held.
+func (s *InMemStorage) checkAvailable(shortURL string) error {
+	if _, ok := s.urls[shortURL]; !ok {
+		return fmt.Errorf("%w: %s", storage.ErrURLNotFound, shortURL)
+	}
+
+	if s.active[shortURL] == 0 {
+		return storage.ErrURLDeleted
+	}
+
+	if s.disabled[shortURL] {
+		return storage.ErrURLDisabled
+	}
+
 	return nil
 }
 
-// GetURL retrieves the original URL from InMemStorage given its shortened version.
-func (s *InMemStorage) GetURL(_ context.Context, shortURL string) (string, error) {
+// GetURLsByUserID retrieves all the URL records of a specific user from InMemStorage,
+// including the records of the workspaces the user can view.
+func (s *InMemStorage) GetURLsByUserID(ctx context.Context, userID string) ([]entity.URLRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	viewable, err := s.memberWorkspaces(ctx, userID, func(m entity.WorkspaceMember) bool { return m.CanView })
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getURLs(userID, viewable)
+}
+
+// GetURLsInWorkspaces retrieves all the URL records of a specific user from InMemStorage,
+// followed by the records of the workspaces with the IDs.
+func (s *InMemStorage) GetURLsInWorkspaces(ctx context.Context, userID string, workspaceIDs []string) ([]entity.URLRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return s.getURLs(userID, workspaceSet(workspaceIDs))
+}
+
+// getURLs retrieves the URL records of the user followed by the records of the viewable workspaces.
+func (s *InMemStorage) getURLs(userID string, viewable map[string]bool) ([]entity.URLRecord, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	originalURL, ok := s.urls[shortURL]
-	if !ok {
-		return "", fmt.Errorf("no url for id: %s", shortURL)
+	var result []entity.URLRecord
+	for _, r := range s.users[userID] {
+		result = append(result, *s.withPreview(r))
+	}
+
+	if len(viewable) > 0 {
+		owners := make([]string, 0, len(s.users))
+		for owner := range s.users {
+			if owner != userID {
+				owners = append(owners, owner)
+			}
+		}
+
+		sort.Strings(owners)
+
+		for _, owner := range owners {
+			for _, r := range s.users[owner] {
+				if viewable[r.WorkspaceID] {
+					result = append(result, *s.withPreview(r))
+				}
+			}
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("%w: no short urls for id %s", storage.ErrURLNotFound, userID)
 	}
 
-	return originalURL, nil
+	return result, nil
 }
 
-// GetURLsByUserID retrieves all the URL records of a specific user from InMemStorage.
-func (s *InMemStorage) GetURLsByUserID(_ context.Context, userID string) ([]entity.URLRecord, error) {
+// CountURLsByUserID counts the URL records of a specific user that are not deleted.
+func (s *InMemStorage) CountURLsByUserID(ctx context.Context, userID string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	records, ok := s.users[userID]
-	if !ok {
-		return nil, fmt.Errorf("no short urls for id %s", userID)
+	var count int
+	for _, r := range s.users[userID] {
+		if !r.DeletedFlag {
+			count++
+		}
 	}
 
-	return records, nil
+	return count, nil
 }
 
-// DeleteURLBatch marks a set of URLs associated with a user as deleted in InMemStorage
-// by iterating through the user’s URL records and setting DeletedFlag to true for
-// matching URLs.
-func (s *InMemStorage) DeleteURLBatch(urls []string, user string) error {
+// SavePreview attaches the preview to every record with the given shortened URL and logs
+// the update of the records to the file, so the preview survives a restart.
+func (s *InMemStorage) SavePreview(ctx context.Context, shortURL string, preview entity.Preview) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	records, ok := s.users[user]
-	if !ok {
-		return errors.New("user not found")
+	if _, ok := s.urls[shortURL]; !ok {
+		return fmt.Errorf("%w: %s", storage.ErrURLNotFound, shortURL)
 	}
 
-	for i := range records {
-		for _, url := range urls {
-			if url == records[i].ShortURL {
-				records[i].DeletedFlag = true
+	var updated []entity.URLRecord
+	for _, records := range s.users {
+		for _, r := range records {
+			if r.ShortURL == shortURL {
+				r.Preview = &preview
+				updated = append(updated, r)
 			}
 		}
 	}
 
+	if err := s.appendOp(walOp{Op: opUpdate, Records: updated}); err != nil {
+		return err
+	}
+
+	s.previews[shortURL] = preview
+
 	return nil
 }
 
-// CheckExistence checks if a shortened URL associated with a user exists in InMemStorage.
-func (s *InMemStorage) CheckExistence(_ context.Context, shortURL, userID string) error {
+// DeleteURLBatch marks a set of URLs associated with a user as deleted in InMemStorage
+// by iterating through the user’s URL records, and the records of the workspaces the user
+// is allowed to delete from, and setting DeletedFlag to true for matching URLs.
+// It returns the records it marked.
+func (s *InMemStorage) DeleteURLBatch(urls []string, user string) ([]entity.URLRecord, error) {
+	deletable, err := s.memberWorkspaces(context.Background(), user, func(m entity.WorkspaceMember) bool { return m.CanDelete })
+	if err != nil {
+		return nil, err
+	}
+
+	return s.deleteURLs(urls, user, deletable)
+}
+
+// DeleteURLBatchInWorkspaces marks a set of URLs associated with a user, or with the workspaces with the IDs,
+// as deleted in InMemStorage. It returns the records it marked.
+func (s *InMemStorage) DeleteURLBatchInWorkspaces(urls []string, user string, workspaceIDs []string) ([]entity.URLRecord, error) {
+	return s.deleteURLs(urls, user, workspaceSet(workspaceIDs))
+}
+
+// deleteURLs marks the URLs of the user and of the deletable workspaces as deleted and returns the records it marked.
+func (s *InMemStorage) deleteURLs(urls []string, user string, deletable map[string]bool) ([]entity.URLRecord, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	records, ok := s.users[userID]
-	if !ok {
-		return fmt.Errorf("user not found")
+	shortURLs := make(map[string]bool, len(urls))
+	for _, url := range urls {
+		shortURLs[url] = true
 	}
 
-	for _, r := range records {
-		if r.ShortURL == shortURL {
-			return nil
+	var deleted []*entity.URLRecord
+	var uuids []string
+	for owner, records := range s.users {
+		for i := range records {
+			if owner != user && !deletable[records[i].WorkspaceID] {
+				continue
+			}
+
+			if shortURLs[records[i].ShortURL] && !records[i].DeletedFlag {
+				deleted = append(deleted, &records[i])
+				uuids = append(uuids, records[i].UUID)
+			}
 		}
 	}
 
-	return fmt.Errorf("no urls for user")
-}
+	if len(deleted) == 0 {
+		return nil, nil
+	}
 
-// Ping returns an error as InMemStorage does not maintain connection to any external
-// SQL database.
-func (s *InMemStorage) Ping() error {
-	return errors.New("no connection to sql database")
+	if err := s.appendOp(walOp{Op: opDelete, UUIDs: uuids}); err != nil {
+		return nil, err
+	}
+
+	marked := make([]entity.URLRecord, 0, len(deleted))
+	for _, r := range deleted {
+		r.DeletedFlag = true
+		s.active[r.ShortURL]--
+		marked = append(marked, *s.withPreview(*r))
+	}
+
+	return marked, nil
 }
 
-// GetStats retrieves statistical data about the URLs and users.
-func (s *InMemStorage) GetStats(_ context.Context) (*entity.Stats, error) {
+// ExpireURLs marks the records that expired at or before the time as deleted, logs their deletion
+// to the file and returns the records it marked.
+func (s *InMemStorage) ExpireURLs(ctx context.Context, now time.Time) ([]entity.URLRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	stats := &entity.Stats{
-		URLs:  len(s.urls),
-		Users: len(s.users),
+	var expired []*entity.URLRecord
+	var uuids []string
+	for _, records := range s.users {
+		for i := range records {
+			r := &records[i]
+			if !r.DeletedFlag && r.Expired(now) {
+				expired = append(expired, r)
+				uuids = append(uuids, r.UUID)
+			}
+		}
+	}
+
+	if len(expired) == 0 {
+		return nil, nil
 	}
 
-	return stats, nil
+	if err := s.appendOp(walOp{Op: opDelete, UUIDs: uuids}); err != nil {
+		return nil, err
+	}
+
+	marked := make([]entity.URLRecord, 0, len(expired))
+	for _, r := range expired {
+		r.DeletedFlag = true
+		s.active[r.ShortURL]--
+		marked = append(marked, *s.withPreview(*r))
+	}
+
+	return marked, nil
 }
 
-func (s *InMemStorage) restoreFromFile() error {
+// AddClicks adds the clicks of every shortened URL to its records that are not deleted, logs the update
+// of the records to the file and returns the updated records.
+func (s *InMemStorage) AddClicks(ctx context.Context, clicks map[string]int64) ([]entity.URLRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	f, err := os.OpenFile(s.storageFilePath, os.O_RDONLY|os.O_CREATE, 0666)
-	if err != nil {
+	var targets []*entity.URLRecord
+	var updated []entity.URLRecord
+	for _, records := range s.users {
+		for i := range records {
+			n, ok := clicks[records[i].ShortURL]
+			if !ok || records[i].DeletedFlag {
+				continue
+			}
+
+			r := records[i]
+			r.Clicks += n
+
+			targets = append(targets, &records[i])
+			updated = append(updated, r)
+		}
+	}
+
+	if len(updated) == 0 {
+		return nil, nil
+	}
+
+	updated = s.withPreviews(updated)
+	if err := s.appendOp(walOp{Op: opUpdate, Records: updated}); err != nil {
+		return nil, err
+	}
+
+	for i, r := range targets {
+		r.Clicks = updated[i].Clicks
+	}
+
+	return updated, nil
+}
+
+// CheckExistence checks if a live shortened URL associated with a user exists in InMemStorage.
+// Deleted and expired records don't count, since saving the URL again replaces them.
+func (s *InMemStorage) CheckExistence(ctx context.Context, shortURL, userID string) error {
+	if err := ctx.Err(); err != nil {
 		return err
 	}
-	defer func() {
-		if err := f.Close(); err != nil {
-			s.logger.Error("failed to close the file", zap.Error(err))
-		}
-	}()
 
-	dec := json.NewDecoder(f)
-	for dec.More() {
-		var r entity.URLRecord
-		if err := dec.Decode(&r); err != nil {
-			return err
-		}
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-		s.urls[r.ShortURL] = r.OriginalURL
-		s.users[r.UserID] = append(s.users[r.UserID], r)
+	if r, ok := s.ownRecord(userID, shortURL); !ok || r.Gone(time.Now()) {
+		return fmt.Errorf("%w: %s", storage.ErrURLNotFound, shortURL)
 	}
 
 	return nil
 }
 
-func (s *InMemStorage) writeRecordToFile(r entity.URLRecord) error {
-	if s.storageFilePath == "" {
-		return nil
+// GetExistingURLs returns the shortened URLs among the given ones the user has live records of in InMemStorage.
+// Deleted and expired records don't count.
+func (s *InMemStorage) GetExistingURLs(ctx context.Context, shortURLs []string, userID string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	f, err := os.OpenFile(s.storageFilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
-	if err != nil {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	var existing []string
+	for _, shortURL := range shortURLs {
+		if r, ok := s.ownRecord(userID, shortURL); ok && !r.Gone(now) {
+			existing = append(existing, shortURL)
+		}
+	}
+
+	return existing, nil
+}
+
+// ReassignURLs moves the URL records of one user to another and logs the update of the moved records to the file.
+// Records with a shortened URL the other user already has stay with the original user.
+func (s *InMemStorage) ReassignURLs(ctx context.Context, fromUserID, toUserID string) error {
+	if err := ctx.Err(); err != nil {
 		return err
 	}
-	defer func() {
-		if err := f.Close(); err != nil {
-			s.logger.Error("failed to close the file", zap.Error(err))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	owned := make(map[string]bool)
+	for _, r := range s.users[toUserID] {
+		owned[r.ShortURL] = true
+	}
+
+	var kept, moved []entity.URLRecord
+	for _, r := range s.users[fromUserID] {
+		if owned[r.ShortURL] {
+			kept = append(kept, r)
+			continue
 		}
-	}()
 
-	if err := json.NewEncoder(f).Encode(r); err != nil {
+		r.UserID = toUserID
+		moved = append(moved, r)
+	}
+
+	if len(moved) == 0 {
+		return nil
+	}
+
+	if err := s.appendOp(walOp{Op: opUpdate, Records: s.withPreviews(moved)}); err != nil {
 		return err
 	}
 
+	s.users[toUserID] = append(s.users[toUserID], moved...)
+
+	if len(kept) == 0 {
+		delete(s.users, fromUserID)
+	} else {
+		s.users[fromUserID] = kept
+	}
+
 	return nil
 }
+
+// ReassignURL moves the record with the shortened URL from one user to another and logs the update
+// of the moved record to the file. It returns storage.ErrURLNotFound if the first user has no such record,
+// and storage.ErrURLExists if the other user already has one.
+func (s *InMemStorage) ReassignURL(ctx context.Context, shortURL, fromUserID, toUserID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.owns(toUserID, shortURL) {
+		return fmt.Errorf("%w: %s", storage.ErrURLExists, shortURL)
+	}
+
+	for _, r := range s.users[fromUserID] {
+		if r.ShortURL != shortURL {
+			continue
+		}
+
+		r.UserID = toUserID
+
+		if err := s.appendOp(walOp{Op: opUpdate, Records: s.withPreviews([]entity.URLRecord{r})}); err != nil {
+			return err
+		}
+
+		s.removeRecord(fromUserID, r.UUID)
+		s.users[toUserID] = append(s.users[toUserID], r)
+
+		return nil
+	}
+
+	return storage.ErrURLNotFound
+}
+
+// SetURLDisabled disables or enables every record with the shortened URL, logs the update
+// of the records to the file and returns the updated records. It returns storage.ErrURLNotFound
+// if there is no such record.
+func (s *InMemStorage) SetURLDisabled(ctx context.Context, shortURL string, disabled bool) ([]entity.URLRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.urls[shortURL]; !ok {
+		return nil, storage.ErrURLNotFound
+	}
+
+	var targets []*entity.URLRecord
+	var updated []entity.URLRecord
+	for _, records := range s.users {
+		for i := range records {
+			if records[i].ShortURL != shortURL {
+				continue
+			}
+
+			r := records[i]
+			r.Disabled = disabled
+
+			targets = append(targets, &records[i])
+			updated = append(updated, r)
+		}
+	}
+
+	updated = s.withPreviews(updated)
+	if err := s.appendOp(walOp{Op: opUpdate, Records: updated}); err != nil {
+		return nil, err
+	}
+
+	if disabled {
+		s.disabled[shortURL] = true
+	} else {
+		delete(s.disabled, shortURL)
+	}
+
+	for _, r := range targets {
+		r.Disabled = disabled
+	}
+
+	return updated, nil
+}
+
+// SetURLWorkspace moves the record of the user with the shortened URL to the workspace, or out of
+// any workspace if the workspaceID is empty, and logs the update of the record to the file.
+// It returns storage.ErrURLNotFound if the user has no such record.
+func (s *InMemStorage) SetURLWorkspace(ctx context.Context, shortURL, userID, workspaceID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := s.users[userID]
+	for i := range records {
+		if records[i].ShortURL != shortURL {
+			continue
+		}
+
+		r := records[i]
+		r.WorkspaceID = workspaceID
+
+		if err := s.appendOp(walOp{Op: opUpdate, Records: s.withPreviews([]entity.URLRecord{r})}); err != nil {
+			return err
+		}
+
+		records[i].WorkspaceID = workspaceID
+
+		return nil
+	}
+
+	return storage.ErrURLNotFound
+}
+
+// Ping returns an error as InMemStorage does not maintain connection to any external
+// SQL database.
+func (s *InMemStorage) Ping() error {
+	return errors.New("no connection to sql database")
+}
+
+// GetStats retrieves statistical data about the URL records and users that are not deleted.
+func (s *InMemStorage) GetStats(ctx context.Context) (*entity.Stats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := &entity.Stats{}
+	for _, records := range s.users {
+		var count int
+		for _, r := range records {
+			if !r.DeletedFlag {
+				count++
+			}
+		}
+
+		if count > 0 {
+			stats.URLs += count
+			stats.Users++
+		}
+	}
+
+	return stats, nil
+}
+
+// GetUserIDs returns the users with URLs that are not deleted.
+func (s *InMemStorage) GetUserIDs(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var users []string
+	for user, records := range s.users {
+		for _, r := range records {
+			if !r.DeletedFlag {
+				users = append(users, user)
+				break
+			}
+		}
+	}
+
+	return users, nil
+}
+
+// ScanURLs returns up to limit records, including the deleted ones, with their previews,
+// in the order of their UUIDs after the afterUUID.
+func (s *InMemStorage) ScanURLs(ctx context.Context, afterUUID string, limit int) ([]entity.URLRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var records []entity.URLRecord
+	for _, userRecords := range s.users {
+		for _, r := range userRecords {
+			if r.UUID > afterUUID {
+				records = append(records, r)
+			}
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool { return records[i].UUID < records[j].UUID })
+
+	if len(records) > limit {
+		records = records[:limit]
+	}
+
+	return s.withPreviews(records), nil
+}
+
+// ImportURLs stores the records as they are, replacing the records with the same UUIDs,
+// and logs their update to the file. It returns storage.ErrURLExists if the user of a record
+// has another record with its shortened URL.
+func (s *InMemStorage) ImportURLs(ctx context.Context, records []entity.URLRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[[2]string]bool, len(records))
+	for _, r := range records {
+		key := [2]string{r.UserID, r.ShortURL}
+		if seen[key] {
+			return fmt.Errorf("%w: %s", storage.ErrURLExists, r.ShortURL)
+		}
+
+		seen[key] = true
+
+		for _, o := range s.users[r.UserID] {
+			if o.ShortURL == r.ShortURL && o.UUID != r.UUID {
+				return fmt.Errorf("%w: %s", storage.ErrURLExists, r.ShortURL)
+			}
+		}
+	}
+
+	if err := s.appendOp(walOp{Op: opUpdate, Records: records}); err != nil {
+		return err
+	}
+
+	owners := s.owners()
+	for _, r := range records {
+		if old, ok := s.record(owners[r.UUID], r.UUID); ok && !old.DeletedFlag {
+			s.active[old.ShortURL]--
+		}
+
+		if !r.DeletedFlag {
+			s.active[r.ShortURL]++
+		}
+
+		s.restoreRecord(r, owners)
+	}
+
+	return nil
+}
+
+// RemoveURLs removes the records with the UUIDs for good and logs their removal to the file.
+func (s *InMemStorage) RemoveURLs(ctx context.Context, uuids []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	owners := s.owners()
+
+	var known []string
+	for _, uuid := range uuids {
+		if _, ok := owners[uuid]; ok {
+			known = append(known, uuid)
+		}
+	}
+
+	if len(known) == 0 {
+		return nil
+	}
+
+	if err := s.appendOp(walOp{Op: opRemove, UUIDs: known}); err != nil {
+		return err
+	}
+
+	s.remove(known, owners)
+
+	return nil
+}
+
+// Close stops the periodic sync and compaction of the log, syncs the pending changes and closes the log.
+func (s *InMemStorage) Close() error {
+	if s.stop != nil {
+		close(s.stop)
+		<-s.done
+		s.stop = nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.log == nil {
+		return nil
+	}
+
+	err := s.log.close()
+	s.log = nil
+
+	return err
+}
+
+// restoreFromFile replays the log of the storage file. A torn last operation, left by a crash
+// in the middle of a write, is dropped from the file, unless the storage is read-only.
+// It reports whether the file is a flat file of records of the previous versions.
+func (s *InMemStorage) restoreFromFile() (legacy bool, err error) {
+	if s.storageFilePath == "" {
+		return false, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// owners tracks the owner of every restored record, since the updates of a record
+	// may move it to another user.
+	owners := make(map[string]string)
+
+	size, legacy, err := replayWAL(s.storageFilePath, func(op walOp) {
+		s.apply(op, owners)
+	})
+
+	s.active = make(map[string]int)
+	for _, records := range s.users {
+		for _, r := range records {
+			if !r.DeletedFlag {
+				s.active[r.ShortURL]++
+			}
+		}
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	info, err := os.Stat(s.storageFilePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	switch {
+	case info.Size() == size:
+	case s.readOnly:
+		s.logger.Warn("ignoring torn write at the end of read-only storage file",
+			zap.Int64("offset", size), zap.Int64("bytes", info.Size()-size))
+	default:
+		s.logger.Warn("dropping torn write at the end of storage file",
+			zap.Int64("offset", size), zap.Int64("bytes", info.Size()-size))
+
+		if err := os.Truncate(s.storageFilePath, size); err != nil {
+			return false, err
+		}
+	}
+
+	return legacy, nil
+}
+
+// apply applies a logged operation to the records being restored.
+func (s *InMemStorage) apply(op walOp, owners map[string]string) {
+	switch op.Op {
+	case opCreate, opUpdate:
+		// A creation first removes the records it replaces.
+		s.remove(op.UUIDs, owners)
+
+		for _, r := range op.Records {
+			s.restoreRecord(r, owners)
+		}
+	case opDelete:
+		for _, uuid := range op.UUIDs {
+			records := s.users[owners[uuid]]
+			for i := range records {
+				if records[i].UUID == uuid {
+					records[i].DeletedFlag = true
+				}
+			}
+		}
+	case opRemove:
+		s.remove(op.UUIDs, owners)
+	}
+}
+
+// remove removes the records with the UUIDs, and forgets the shortened URLs left without records.
+func (s *InMemStorage) remove(uuids []string, owners map[string]string) {
+	for _, uuid := range uuids {
+		owner, ok := owners[uuid]
+		if !ok {
+			continue
+		}
+
+		r, _ := s.record(owner, uuid)
+		if !r.DeletedFlag {
+			s.active[r.ShortURL]--
+		}
+
+		s.removeRecord(owner, uuid)
+		delete(owners, uuid)
+
+		if !s.hasShortURL(r.ShortURL) {
+			delete(s.urls, r.ShortURL)
+			delete(s.active, r.ShortURL)
+			delete(s.previews, r.ShortURL)
+			delete(s.disabled, r.ShortURL)
+		}
+	}
+}
+
+func (s *InMemStorage) restoreRecord(r entity.URLRecord, owners map[string]string) {
+	if r.Preview != nil {
+		s.previews[r.ShortURL] = *r.Preview
+		r.Preview = nil
+	}
+
+	if r.Disabled {
+		s.disabled[r.ShortURL] = true
+	} else {
+		delete(s.disabled, r.ShortURL)
+	}
+
+	owner, restored := owners[r.UUID]
+	if restored && owner == r.UserID {
+		s.replaceRecord(r)
+		return
+	}
+
+	if restored {
+		s.removeRecord(owner, r.UUID)
+	}
+
+	owners[r.UUID] = r.UserID
+	s.urls[r.ShortURL] = r.OriginalURL
+	s.users[r.UserID] = append(s.users[r.UserID], r)
+}
+
+// openLog opens the storage file for appending.
+func (s *InMemStorage) openLog() error {
+	if s.storageFilePath == "" {
+		return nil
+	}
+
+	log, err := openWAL(s.storageFilePath, s.syncPolicy)
+	if err != nil {
+		return err
+	}
+
+	s.log = log
+
+	return nil
+}
+
+// maintainLog syncs the log with the SyncInterval policy and compacts it periodically until the storage is closed.
+func (s *InMemStorage) maintainLog() {
+	defer close(s.done)
+
+	var syncC, compactC <-chan time.Time
+
+	if s.syncPolicy == SyncInterval {
+		t := time.NewTicker(s.syncInterval)
+		defer t.Stop()
+		syncC = t.C
+	}
+
+	if s.compactInterval > 0 {
+		t := time.NewTicker(s.compactInterval)
+		defer t.Stop()
+		compactC = t.C
+	}
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-syncC:
+			s.mu.Lock()
+			if err := s.log.sync(); err != nil {
+				s.logger.Error("failed to sync storage file", zap.Error(err))
+			}
+			s.mu.Unlock()
+		case <-compactC:
+			if err := s.compact(); err != nil {
+				s.logger.Error("failed to compact storage file", zap.Error(err))
+			}
+		}
+	}
+}
+
+// compact replaces the log with a snapshot of the records if it changed since the last compaction.
+func (s *InMemStorage) compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.log == nil || s.log.appended == 0 {
+		return nil
+	}
+
+	return s.log.compact(s.snapshot())
+}
+
+// snapshot returns all records with their previews, ordered by user. It must be called with the mutex held.
+func (s *InMemStorage) snapshot() []entity.URLRecord {
+	users := make([]string, 0, len(s.users))
+	for user := range s.users {
+		users = append(users, user)
+	}
+
+	sort.Strings(users)
+
+	var records []entity.URLRecord
+	for _, user := range users {
+		records = append(records, s.withPreviews(s.users[user])...)
+	}
+
+	return records
+}
+
+// appendOp appends the operation to the log, if the records are persisted. It must be called with the mutex held.
+// It fails if the storage is read-only.
+func (s *InMemStorage) appendOp(op walOp) error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
+
+	if s.log == nil {
+		return nil
+	}
+
+	return s.log.append(op)
+}
+
+// replaceRecord overwrites a previously restored record with the same UUID.
+func (s *InMemStorage) replaceRecord(r entity.URLRecord) {
+	records := s.users[r.UserID]
+	for i := range records {
+		if records[i].UUID == r.UUID {
+			records[i] = r
+			return
+		}
+	}
+}
+
+// removeRecord removes the record with the UUID from the records of the user.
+func (s *InMemStorage) removeRecord(userID, uuid string) {
+	records := s.users[userID]
+	for i := range records {
+		if records[i].UUID == uuid {
+			s.users[userID] = append(records[:i], records[i+1:]...)
+			break
+		}
+	}
+
+	if len(s.users[userID]) == 0 {
+		delete(s.users, userID)
+	}
+}
+
+// owners returns the owners of the records by their UUIDs. It must be called with the mutex held.
+func (s *InMemStorage) owners() map[string]string {
+	owners := make(map[string]string)
+	for user, records := range s.users {
+		for _, r := range records {
+			owners[r.UUID] = user
+		}
+	}
+
+	return owners
+}
+
+// record returns the record of the user with the UUID. It must be called with the mutex held.
+func (s *InMemStorage) record(userID, uuid string) (entity.URLRecord, bool) {
+	for _, r := range s.users[userID] {
+		if r.UUID == uuid {
+			return r, true
+		}
+	}
+
+	return entity.URLRecord{}, false
+}
+
+// hasShortURL reports whether any user has a record with the shortened URL. It must be called with the mutex held.
+func (s *InMemStorage) hasShortURL(shortURL string) bool {
+	if s.active[shortURL] > 0 {
+		return true
+	}
+
+	for _, records := range s.users {
+		for _, r := range records {
+			if r.ShortURL == shortURL {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// owns reports whether the user has a record with the shortened URL. It must be called with the mutex held.
+func (s *InMemStorage) owns(userID, shortURL string) bool {
+	_, ok := s.ownRecord(userID, shortURL)
+	return ok
+}
+
+// ownRecord returns the record of the user with the shortened URL. It must be called with the mutex held.
+func (s *InMemStorage) ownRecord(userID, shortURL string) (entity.URLRecord, bool) {
+	for _, r := range s.users[userID] {
+		if r.ShortURL == shortURL {
+			return r, true
+		}
+	}
+
+	return entity.URLRecord{}, false
+}
+
+// memberWorkspaces returns the set of the workspaces whose memberships of the user satisfy the allowed func.
+func (s *InMemStorage) memberWorkspaces(ctx context.Context, userID string, allowed func(entity.WorkspaceMember) bool) (map[string]bool, error) {
+	if s.workspaces == nil {
+		return nil, nil
+	}
+
+	memberships, err := s.workspaces.GetMembershipsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]bool, len(memberships))
+	for _, m := range memberships {
+		if allowed(m) {
+			ids[m.WorkspaceID] = true
+		}
+	}
+
+	return ids, nil
+}
+
+// workspaceSet returns the set of the workspaces with the IDs.
+func workspaceSet(ids []string) map[string]bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+
+	return set
+}
+
+func (s *InMemStorage) withPreview(r entity.URLRecord) *entity.URLRecord {
+	if p, ok := s.previews[r.ShortURL]; ok {
+		r.Preview = &p
+	}
+
+	return &r
+}
+
+func (s *InMemStorage) withPreviews(records []entity.URLRecord) []entity.URLRecord {
+	result := make([]entity.URLRecord, 0, len(records))
+	for _, r := range records {
+		result = append(result, *s.withPreview(r))
+	}
+
+	return result
+}
@@ -0,0 +1,33 @@
+package memory
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/storagetest"
+)
+
+func TestInMemStorage_conformance(t *testing.T) {
+	tests := []struct {
+		name string
+		path func(t *testing.T) string
+	}{
+		{
+			name: "in memory",
+			path: func(*testing.T) string { return "" },
+		},
+		{
+			name: "with storage file",
+			path: func(t *testing.T) string { return filepath.Join(t.TempDir(), "db.log") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storagetest.Run(t, func(t *testing.T) storage.Repository {
+				return openStorage(t, tt.path(t))
+			})
+		})
+	}
+}
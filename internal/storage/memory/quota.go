@@ -0,0 +1,126 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// QuotaStorage keeps quotas overridden for specific users in memory and, if it has a file, appends every change
+// to the file, so the overrides survive a restart.
+type QuotaStorage struct {
+	quotas   map[string]entity.Quota
+	filePath string
+	mu       sync.Mutex
+}
+
+// Operations of the quota file.
+const (
+	quotaOpSave   = "save_quota"
+	quotaOpDelete = "delete_quota"
+)
+
+// quotaOp is a change appended to the quota file. The save_quota operation saves the Quota,
+// the delete_quota operation deletes the quota of the UserID.
+type quotaOp struct {
+	Op     string        `json:"op"`
+	Quota  *entity.Quota `json:"quota,omitempty"`
+	UserID string        `json:"user_id,omitempty"`
+}
+
+// NewQuotaStorage initializes a new QuotaStorage instance that keeps the quotas in memory only.
+func NewQuotaStorage() storage.QuotaRepository {
+	return &QuotaStorage{
+		quotas: make(map[string]entity.Quota),
+	}
+}
+
+// NewFileQuotaStorage initializes a new QuotaStorage instance persisted to the file at filePath
+// and restores the overridden quotas from the file if it exists. It fails if the file can't be read.
+func NewFileQuotaStorage(filePath string) (storage.QuotaRepository, error) {
+	s := &QuotaStorage{
+		quotas:   make(map[string]entity.Quota),
+		filePath: filePath,
+	}
+
+	err := replayJSON(filePath, func(dec *json.Decoder) error {
+		var op quotaOp
+		if err := dec.Decode(&op); err != nil {
+			return err
+		}
+
+		return s.apply(op)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// SaveQuota stores the quota of a user, replacing the previous one.
+func (s *QuotaStorage) SaveQuota(_ context.Context, quota entity.Quota) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.write(quotaOp{Op: quotaOpSave, Quota: &quota})
+}
+
+// GetQuota retrieves the quota of a user. It returns nil if the quota wasn't overridden for the user.
+func (s *QuotaStorage) GetQuota(_ context.Context, userID string) (*entity.Quota, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	quota, ok := s.quotas[userID]
+	if !ok {
+		return nil, nil
+	}
+
+	return &quota, nil
+}
+
+// DeleteQuota removes the quota of a user, so the default one applies again.
+func (s *QuotaStorage) DeleteQuota(_ context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.quotas[userID]; !ok {
+		return nil
+	}
+
+	return s.write(quotaOp{Op: quotaOpDelete, UserID: userID})
+}
+
+// write appends the change to the file, if there is one, and applies it.
+func (s *QuotaStorage) write(op quotaOp) error {
+	if s.filePath != "" {
+		if err := appendJSON(s.filePath, op); err != nil {
+			return err
+		}
+	}
+
+	return s.apply(op)
+}
+
+// apply applies the change to the quotas in memory.
+func (s *QuotaStorage) apply(op quotaOp) error {
+	switch op.Op {
+	case quotaOpSave:
+		if op.Quota == nil {
+			return errors.New("quota is missing")
+		}
+
+		s.quotas[op.Quota.UserID] = *op.Quota
+	case quotaOpDelete:
+		delete(s.quotas, op.UserID)
+	default:
+		return fmt.Errorf("unknown operation %q", op.Op)
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package memory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+func TestFileQuotaStorage(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "db.json.quotas")
+
+	s, err := NewFileQuotaStorage(path)
+	require.NoError(t, err)
+
+	require.NoError(t, s.SaveQuota(ctx, entity.Quota{UserID: "alice", MaxLinks: 10}))
+	require.NoError(t, s.SaveQuota(ctx, entity.Quota{UserID: "alice", MaxLinks: 20, MaxBatchSize: 5}))
+	require.NoError(t, s.SaveQuota(ctx, entity.Quota{UserID: "bob", MaxLinks: 1}))
+	require.NoError(t, s.DeleteQuota(ctx, "bob"))
+	require.NoError(t, s.DeleteQuota(ctx, "carol"))
+
+	restored, err := NewFileQuotaStorage(path)
+	require.NoError(t, err)
+
+	quota, err := restored.GetQuota(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, &entity.Quota{UserID: "alice", MaxLinks: 20, MaxBatchSize: 5}, quota)
+
+	quota, err = restored.GetQuota(ctx, "bob")
+	require.NoError(t, err)
+	assert.Nil(t, quota)
+
+	require.NoError(t, os.WriteFile(path, []byte("{\"op\":"), 0600))
+
+	_, err = NewFileQuotaStorage(path)
+	assert.Error(t, err, "a damaged file should not be overwritten")
+}
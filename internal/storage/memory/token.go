@@ -0,0 +1,199 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// RefreshTokenStorage keeps refresh tokens in memory and, if it has a file, appends every change
+// of a token to the file, so the sessions survive a restart.
+type RefreshTokenStorage struct {
+	tokens   map[string]entity.RefreshToken
+	filePath string
+	mu       sync.Mutex
+}
+
+// NewRefreshTokenStorage initializes a new RefreshTokenStorage instance that keeps the tokens in memory only.
+func NewRefreshTokenStorage() storage.RefreshTokenRepository {
+	return &RefreshTokenStorage{
+		tokens: make(map[string]entity.RefreshToken),
+	}
+}
+
+// NewFileRefreshTokenStorage initializes a new RefreshTokenStorage instance persisted to the file at filePath
+// and restores the tokens that haven't expired from the file if it exists. The file is then rewritten with
+// just these tokens, so it doesn't keep growing with every session. It fails if the file can't be read.
+func NewFileRefreshTokenStorage(filePath string) (storage.RefreshTokenRepository, error) {
+	s := &RefreshTokenStorage{
+		tokens:   make(map[string]entity.RefreshToken),
+		filePath: filePath,
+	}
+
+	var entries int
+
+	err := replayJSON(filePath, func(dec *json.Decoder) error {
+		var t entity.RefreshToken
+		if err := dec.Decode(&t); err != nil {
+			return err
+		}
+
+		s.tokens[t.ID] = t
+		entries++
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.dropExpired(time.Now())
+
+	if entries > len(s.tokens) {
+		tokens := make([]interface{}, 0, len(s.tokens))
+		for _, t := range s.tokens {
+			tokens = append(tokens, t)
+		}
+
+		if err := rewriteJSON(filePath, tokens); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// SaveRefreshToken stores a new refresh token. The expired tokens are dropped along the way.
+func (s *RefreshTokenStorage) SaveRefreshToken(_ context.Context, token entity.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.writeToken(token); err != nil {
+		return err
+	}
+
+	s.dropExpired(time.Now())
+	s.tokens[token.ID] = token
+
+	return nil
+}
+
+// GetRefreshTokenByHash retrieves the refresh token with the given hash. It returns nil if there is no such token.
+func (s *RefreshTokenStorage) GetRefreshTokenByHash(_ context.Context, hash string) (*entity.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.tokens {
+		if t.Hash == hash {
+			return &t, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// RevokeRefreshToken revokes the refresh token, either because it was rotated or because the user logged out.
+func (s *RefreshTokenStorage) RevokeRefreshToken(_ context.Context, id string, rotated bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tokens[id]
+	if !ok {
+		return errors.New("refresh token not found")
+	}
+
+	if t.RevokedAt.IsZero() {
+		t.RevokedAt = time.Now().UTC()
+		t.Rotated = rotated
+
+		if err := s.writeToken(t); err != nil {
+			return err
+		}
+
+		s.tokens[id] = t
+	}
+
+	return nil
+}
+
+// RevokeUserRefreshTokens revokes all the refresh tokens of a specific user.
+func (s *RefreshTokenStorage) RevokeUserRefreshTokens(_ context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	for id, t := range s.tokens {
+		if t.UserID == userID && t.RevokedAt.IsZero() {
+			t.RevokedAt = now
+
+			if err := s.writeToken(t); err != nil {
+				return err
+			}
+
+			s.tokens[id] = t
+		}
+	}
+
+	return nil
+}
+
+// DeleteRefreshTokens removes the tokens that expired or were revoked before the time. With a file,
+// the file is rewritten with the remaining tokens, so the removed ones aren't restored.
+func (s *RefreshTokenStorage) DeleteRefreshTokens(_ context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stale := make(map[string]bool)
+	for id, t := range s.tokens {
+		if t.ExpiresAt.Before(before) || (!t.RevokedAt.IsZero() && t.RevokedAt.Before(before)) {
+			stale[id] = true
+		}
+	}
+
+	if len(stale) == 0 {
+		return 0, nil
+	}
+
+	if s.filePath != "" {
+		tokens := make([]interface{}, 0, len(s.tokens)-len(stale))
+		for id, t := range s.tokens {
+			if !stale[id] {
+				tokens = append(tokens, t)
+			}
+		}
+
+		if err := rewriteJSON(s.filePath, tokens); err != nil {
+			return 0, err
+		}
+	}
+
+	for id := range stale {
+		delete(s.tokens, id)
+	}
+
+	return len(stale), nil
+}
+
+// dropExpired drops the tokens that expired before now from memory. Their entries in the file
+// are dropped the next time the file is restored.
+func (s *RefreshTokenStorage) dropExpired(now time.Time) {
+	for id, t := range s.tokens {
+		if t.ExpiresAt.Before(now) {
+			delete(s.tokens, id)
+		}
+	}
+}
+
+// writeToken appends the token to the file, where its last entry replaces the previous ones on restore.
+func (s *RefreshTokenStorage) writeToken(t entity.RefreshToken) error {
+	if s.filePath == "" {
+		return nil
+	}
+
+	return appendJSON(s.filePath, t)
+}
@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+func TestFileRefreshTokenStorage(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "db.json.tokens")
+	now := time.Now().UTC().Truncate(time.Second)
+
+	s, err := NewFileRefreshTokenStorage(path)
+	require.NoError(t, err)
+
+	active := entity.RefreshToken{ID: "1", UserID: "alice", Hash: "h1", ExpiresAt: now.Add(time.Hour), CreatedAt: now}
+	require.NoError(t, s.SaveRefreshToken(ctx, active))
+	require.NoError(t, s.SaveRefreshToken(ctx, entity.RefreshToken{ID: "2", UserID: "alice", Hash: "h2", ExpiresAt: now.Add(time.Hour), CreatedAt: now}))
+	require.NoError(t, s.SaveRefreshToken(ctx, entity.RefreshToken{ID: "3", UserID: "bob", Hash: "h3", ExpiresAt: now.Add(-time.Hour), CreatedAt: now}))
+	require.NoError(t, s.RevokeRefreshToken(ctx, "2", true))
+
+	restored, err := NewFileRefreshTokenStorage(path)
+	require.NoError(t, err)
+
+	token, err := restored.GetRefreshTokenByHash(ctx, "h1")
+	require.NoError(t, err)
+	assert.Equal(t, &active, token)
+
+	token, err = restored.GetRefreshTokenByHash(ctx, "h2")
+	require.NoError(t, err)
+	require.NotNil(t, token)
+	assert.True(t, token.Rotated)
+	assert.False(t, token.RevokedAt.IsZero())
+
+	token, err = restored.GetRefreshTokenByHash(ctx, "h3")
+	require.NoError(t, err)
+	assert.Nil(t, token, "an expired token should not be restored")
+
+	assert.Equal(t, 2, countLines(t, path), "the file should be rewritten with the restored tokens")
+
+	require.NoError(t, os.WriteFile(path, []byte("{\"ID\":"), 0600))
+
+	_, err = NewFileRefreshTokenStorage(path)
+	assert.Error(t, err, "a damaged file should not be overwritten")
+}
+
+func TestRefreshTokenStorage_DeleteRefreshTokens(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "db.json.tokens")
+	now := time.Now()
+
+	s, err := NewFileRefreshTokenStorage(path)
+	require.NoError(t, err)
+
+	require.NoError(t, s.SaveRefreshToken(ctx, entity.RefreshToken{ID: "1", UserID: "alice", Hash: "h1", ExpiresAt: now.Add(time.Hour)}))
+	require.NoError(t, s.SaveRefreshToken(ctx, entity.RefreshToken{ID: "2", UserID: "alice", Hash: "h2", ExpiresAt: now.Add(2 * time.Hour)}))
+	require.NoError(t, s.SaveRefreshToken(ctx, entity.RefreshToken{ID: "3", UserID: "bob", Hash: "h3", ExpiresAt: now.Add(2 * time.Hour)}))
+	require.NoError(t, s.RevokeRefreshToken(ctx, "3", false))
+
+	deleted, err := s.DeleteRefreshTokens(ctx, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted, "the revoked token should be deleted")
+
+	deleted, err = s.DeleteRefreshTokens(ctx, now.Add(90*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted, "the expired token should be deleted")
+
+	restored, err := NewFileRefreshTokenStorage(path)
+	require.NoError(t, err)
+
+	for hash, kept := range map[string]bool{"h1": false, "h2": true, "h3": false} {
+		token, err := restored.GetRefreshTokenByHash(ctx, hash)
+		require.NoError(t, err)
+		assert.Equal(t, kept, token != nil, hash)
+	}
+
+	assert.Equal(t, 1, countLines(t, path))
+}
+
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var lines int
+	for sc := bufio.NewScanner(f); sc.Scan(); {
+		lines++
+	}
+
+	return lines
+}
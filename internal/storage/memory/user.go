@@ -0,0 +1,127 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// UserStorage keeps registered accounts in memory and, if it has a file, appends every change
+// of an account to the file, so the accounts survive a restart like the URLs they own.
+type UserStorage struct {
+	users    map[string]entity.User
+	filePath string
+	mu       sync.Mutex
+}
+
+// NewUserStorage initializes a new UserStorage instance that keeps the accounts in memory only.
+func NewUserStorage() storage.UserRepository {
+	return &UserStorage{
+		users: make(map[string]entity.User),
+	}
+}
+
+// NewFileUserStorage initializes a new UserStorage instance persisted to the file at filePath
+// and restores the accounts from the file if it exists. It fails if the file can't be read.
+func NewFileUserStorage(filePath string) (storage.UserRepository, error) {
+	s := &UserStorage{
+		users:    make(map[string]entity.User),
+		filePath: filePath,
+	}
+
+	err := replayJSON(filePath, func(dec *json.Decoder) error {
+		var u entity.User
+		if err := dec.Decode(&u); err != nil {
+			return err
+		}
+
+		s.users[u.ID] = u
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// SaveUser stores a new account. It returns storage.ErrUsernameTaken if the username is already taken.
+func (s *UserStorage) SaveUser(_ context.Context, user entity.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Username == user.Username {
+			return storage.ErrUsernameTaken
+		}
+	}
+
+	if err := s.writeUser(user); err != nil {
+		return err
+	}
+
+	s.users[user.ID] = user
+
+	return nil
+}
+
+// GetUserByID retrieves the account with the given ID. It returns nil if there is no such account.
+func (s *UserStorage) GetUserByID(_ context.Context, id string) (*entity.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return nil, nil
+	}
+
+	return &u, nil
+}
+
+// GetUserByUsername retrieves the account with the given username. It returns nil if there is no such account.
+func (s *UserStorage) GetUserByUsername(_ context.Context, username string) (*entity.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Username == username {
+			return &u, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// SetUserRole changes the role of the account with the given ID. It fails if there is no such account.
+func (s *UserStorage) SetUserRole(_ context.Context, id, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return errors.New("user not found")
+	}
+
+	u.Role = role
+	if err := s.writeUser(u); err != nil {
+		return err
+	}
+
+	s.users[id] = u
+
+	return nil
+}
+
+// writeUser appends the account to the file, where its last entry replaces the previous ones on restore.
+func (s *UserStorage) writeUser(u entity.User) error {
+	if s.filePath == "" {
+		return nil
+	}
+
+	return appendJSON(s.filePath, u)
+}
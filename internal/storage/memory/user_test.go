@@ -0,0 +1,43 @@
+package memory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+func TestFileUserStorage(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "db.json.users")
+
+	s, err := NewFileUserStorage(path)
+	require.NoError(t, err)
+
+	require.NoError(t, s.SaveUser(ctx, entity.User{ID: "1", Username: "alice"}))
+	require.NoError(t, s.SaveUser(ctx, entity.User{ID: "2", Username: "bob"}))
+	require.NoError(t, s.SetUserRole(ctx, "1", "admin"))
+	assert.ErrorIs(t, s.SaveUser(ctx, entity.User{ID: "3", Username: "alice"}), storage.ErrUsernameTaken)
+
+	restored, err := NewFileUserStorage(path)
+	require.NoError(t, err)
+
+	u, err := restored.GetUserByUsername(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, &entity.User{ID: "1", Username: "alice", Role: "admin"}, u)
+
+	u, err = restored.GetUserByID(ctx, "2")
+	require.NoError(t, err)
+	assert.Equal(t, "bob", u.Username)
+
+	require.NoError(t, os.WriteFile(path, []byte("{\"id\":"), 0600))
+
+	_, err = NewFileUserStorage(path)
+	assert.Error(t, err, "a damaged file should not be overwritten")
+}
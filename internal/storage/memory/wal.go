@@ -0,0 +1,306 @@
+package memory
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// SyncPolicy defines when the appended operations are flushed from the OS to the disk.
+type SyncPolicy string
+
+// Sync policies of the log.
+const (
+	SyncAlways   SyncPolicy = "always"   // Every write is synced before it returns, so no acknowledged change is lost.
+	SyncInterval SyncPolicy = "interval" // Writes are synced periodically, so a crash loses at most the last interval.
+	SyncNever    SyncPolicy = "never"    // Writes are left to the OS, which flushes them at its own pace.
+)
+
+// Types of the logged operations.
+const (
+	opCreate = "create" // Adds the records, replacing the records with the UUIDs.
+	opUpdate = "update" // Replaces the records with the same UUIDs, moving them to another user if it changed.
+	opDelete = "delete" // Marks the records with the UUIDs as deleted.
+	opRemove = "remove" // Removes the records with the UUIDs.
+)
+
+// ErrCorruptLog is thrown when a record in the middle of the log fails its checksum. Only the last record
+// of the log may be torn, by a crash in the middle of a write, and is dropped on restore.
+var ErrCorruptLog = errors.New("storage log is corrupt")
+
+var checksumTable = crc32.MakeTable(crc32.Castagnoli)
+
+// walOp is an operation on the URL records, written to the log as a line of the CRC-32C checksum
+// of the JSON-encoded operation in hex, a space and the JSON-encoded operation.
+type walOp struct {
+	Op      string             `json:"op"`
+	Records []entity.URLRecord `json:"records,omitempty"`
+	UUIDs   []string           `json:"uuids,omitempty"`
+}
+
+// wal is the append-only log of the operations on the URL records of InMemStorage.
+type wal struct {
+	path     string
+	f        *os.File
+	policy   SyncPolicy
+	dirty    bool  // Whether there are writes that are not synced yet.
+	appended int   // The number of operations appended since the last compaction.
+	size     int64 // The size of the log, up to the end of the last complete operation.
+	failed   error // Why the log can't be appended to, if a failed write couldn't be undone.
+}
+
+func openWAL(path string, policy SyncPolicy) (*wal, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &wal{path: path, f: f, policy: policy, size: info.Size()}, nil
+}
+
+// append writes the operation to the log with a single write, and syncs it if the policy is SyncAlways.
+// A failed write is truncated away, so that the next operation isn't appended after a broken line.
+// If it can't be, the log rejects the appends until it's compacted.
+func (w *wal) append(op walOp) error {
+	if w.failed != nil {
+		return w.failed
+	}
+
+	line, err := encodeOp(op)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.f.Write(line); err != nil {
+		if truncErr := w.f.Truncate(w.size); truncErr != nil {
+			w.failed = fmt.Errorf("storage log is unusable after a failed write: %w", truncErr)
+		}
+
+		return err
+	}
+
+	w.size += int64(len(line))
+	w.appended++
+	w.dirty = true
+
+	if w.policy == SyncAlways {
+		return w.sync()
+	}
+
+	return nil
+}
+
+// sync flushes the writes to the disk, if there are any.
+func (w *wal) sync() error {
+	if !w.dirty {
+		return nil
+	}
+
+	if err := w.f.Sync(); err != nil {
+		return err
+	}
+
+	w.dirty = false
+
+	return nil
+}
+
+// compact replaces the log with a snapshot of the records, one create operation per record.
+// The snapshot is written to a temporary file that atomically replaces the log once it's synced,
+// so a crash during compaction leaves either the old log or the snapshot.
+func (w *wal) compact(records []entity.URLRecord) error {
+	tmpPath := w.path + ".tmp"
+
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+
+	if err := writeSnapshot(tmp, records); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, w.path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := syncDir(filepath.Dir(w.path)); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(w.path, os.O_APPEND|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	old := w.f
+	w.f = f
+	w.dirty = false
+	w.appended = 0
+	w.size = info.Size()
+	w.failed = nil
+
+	return old.Close()
+}
+
+// close syncs the pending writes and closes the log.
+func (w *wal) close() error {
+	if err := w.sync(); err != nil {
+		w.f.Close()
+		return err
+	}
+
+	return w.f.Close()
+}
+
+func writeSnapshot(f *os.File, records []entity.URLRecord) error {
+	var buf bytes.Buffer
+	for _, r := range records {
+		line, err := encodeOp(walOp{Op: opCreate, Records: []entity.URLRecord{r}})
+		if err != nil {
+			return err
+		}
+
+		buf.Write(line)
+	}
+
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
+
+func encodeOp(op walOp) ([]byte, error) {
+	payload, err := json.Marshal(op)
+	if err != nil {
+		return nil, err
+	}
+
+	line := make([]byte, 0, len(payload)+10)
+	line = append(line, fmt.Sprintf("%08x ", crc32.Checksum(payload, checksumTable))...)
+	line = append(line, payload...)
+
+	return append(line, '\n'), nil
+}
+
+// replayWAL reads the operations of the log in order and passes them to apply. It returns the size
+// of the intact part of the log, which is less than the size of the file if the last record is torn.
+// Lines of the legacy format, which held a JSON-encoded URL record per line, are passed as update
+// operations and reported by legacy.
+func replayWAL(path string, apply func(op walOp)) (size int64, legacy bool, err error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	var offset int
+	for offset < len(data) {
+		end := bytes.IndexByte(data[offset:], '\n')
+		if end < 0 {
+			// The last write didn't complete.
+			break
+		}
+
+		line := data[offset : offset+end]
+		next := offset + end + 1
+
+		if len(bytes.TrimSpace(line)) == 0 {
+			offset = next
+			continue
+		}
+
+		if line[0] == '{' {
+			var r entity.URLRecord
+			if err := json.Unmarshal(line, &r); err != nil {
+				return 0, false, fmt.Errorf("%w: invalid record at offset %d: %v", ErrCorruptLog, offset, err)
+			}
+
+			apply(walOp{Op: opUpdate, Records: []entity.URLRecord{r}})
+			legacy = true
+			offset = next
+
+			continue
+		}
+
+		op, err := decodeOp(line)
+		if err != nil {
+			if next == len(data) {
+				// The last record is torn, e.g. its data didn't reach the disk before a crash.
+				break
+			}
+
+			return 0, false, fmt.Errorf("%w: record at offset %d: %v", ErrCorruptLog, offset, err)
+		}
+
+		apply(op)
+		offset = next
+	}
+
+	return int64(offset), legacy, nil
+}
+
+func decodeOp(line []byte) (walOp, error) {
+	var op walOp
+
+	if len(line) < 10 || line[8] != ' ' {
+		return op, errors.New("malformed record")
+	}
+
+	checksum, err := strconv.ParseUint(string(line[:8]), 16, 32)
+	if err != nil {
+		return op, errors.New("malformed checksum")
+	}
+
+	payload := line[9:]
+	if crc32.Checksum(payload, checksumTable) != uint32(checksum) {
+		return op, errors.New("checksum mismatch")
+	}
+
+	if err := json.Unmarshal(payload, &op); err != nil {
+		return op, err
+	}
+
+	return op, nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
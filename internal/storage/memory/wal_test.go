@@ -0,0 +1,315 @@
+package memory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+func openStorage(t *testing.T, path string, opts ...Option) *InMemStorage {
+	log, _ := logger.Initialize("debug")
+
+	repo, err := NewInMemStorage(path, log, opts...)
+	require.NoError(t, err)
+
+	s := repo.(*InMemStorage)
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+func reopenStorage(t *testing.T, s *InMemStorage, opts ...Option) *InMemStorage {
+	require.NoError(t, s.Close())
+
+	return openStorage(t, s.storageFilePath, opts...)
+}
+
+func TestInMemStorage_restoresLog(t *testing.T) {
+	ctx := context.Background()
+	s := openStorage(t, filepath.Join(t.TempDir(), "db.log"))
+
+	require.NoError(t, s.SaveURLBatch(ctx, []*entity.URLRecord{
+		{UUID: "1", ShortURL: "abc", OriginalURL: "https://a.example.com", UserID: "alice"},
+		{UUID: "2", ShortURL: "def", OriginalURL: "https://d.example.com", UserID: "alice"},
+		{UUID: "3", ShortURL: "ghi", OriginalURL: "https://g.example.com", UserID: "anon"},
+	}))
+	_, err := s.DeleteURLBatch([]string{"def"}, "alice")
+	require.NoError(t, err)
+	require.NoError(t, s.SavePreview(ctx, "abc", entity.Preview{Title: "A"}))
+	_, err = s.SetURLDisabled(ctx, "abc", true)
+	require.NoError(t, err)
+	require.NoError(t, s.ReassignURLs(ctx, "anon", "alice"))
+	require.NoError(t, s.SetURLWorkspace(ctx, "ghi", "alice", "ws"))
+
+	want, err := s.GetURLsByUserID(ctx, "alice")
+	require.NoError(t, err)
+
+	s = reopenStorage(t, s)
+
+	got, err := s.GetURLsByUserID(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	assert.True(t, got[1].DeletedFlag, "deletion must survive a restart")
+
+	_, err = s.GetURL(ctx, "def")
+	assert.ErrorIs(t, err, storage.ErrURLDeleted)
+	assert.True(t, got[0].Disabled)
+	assert.Equal(t, "ws", got[2].WorkspaceID)
+
+	count, err := s.CountURLsByUserID(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestInMemStorage_restoresImportAndRemoval(t *testing.T) {
+	ctx := context.Background()
+	s := openStorage(t, filepath.Join(t.TempDir(), "db.log"))
+
+	require.NoError(t, s.SaveURL(ctx, entity.URLRecord{UUID: "1", ShortURL: "abc", OriginalURL: "https://a.example.com", UserID: "alice"}))
+	require.NoError(t, s.ImportURLs(ctx, []entity.URLRecord{
+		{UUID: "1", ShortURL: "abc", OriginalURL: "https://b.example.com", UserID: "alice"},
+		{UUID: "2", ShortURL: "def", OriginalURL: "https://d.example.com", UserID: "bob", DeletedFlag: true},
+	}))
+	require.NoError(t, s.RemoveURLs(ctx, []string{"1"}))
+
+	s = reopenStorage(t, s)
+
+	_, err := s.GetURL(ctx, "abc")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound, "removal must survive a restart")
+
+	_, err = s.GetURL(ctx, "def")
+	assert.ErrorIs(t, err, storage.ErrURLDeleted)
+
+	records, err := s.ScanURLs(ctx, "", 10)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "bob", records[0].UserID)
+}
+
+func TestInMemStorage_dropsTornWrite(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "db.log")
+	s := openStorage(t, path)
+
+	require.NoError(t, s.SaveURL(ctx, entity.URLRecord{UUID: "1", ShortURL: "abc", OriginalURL: "https://a.example.com", UserID: "alice"}))
+	require.NoError(t, s.SaveURL(ctx, entity.URLRecord{UUID: "2", ShortURL: "def", OriginalURL: "https://d.example.com", UserID: "alice"}))
+	require.NoError(t, s.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		torn []byte
+	}{
+		{
+			name: "should drop incomplete last line",
+			torn: data[:len(data)-5],
+		},
+		{
+			name: "should drop last line with bad checksum",
+			torn: append(append([]byte{}, data[:len(data)-3]...), 'x', '}', '\n'),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, os.WriteFile(path, tt.torn, 0666))
+
+			s := openStorage(t, path)
+
+			_, err := s.GetURL(ctx, "abc")
+			require.NoError(t, err)
+
+			_, err = s.GetURL(ctx, "def")
+			assert.Error(t, err)
+
+			// The torn write is cut off, so the next operations are appended after the intact ones.
+			require.NoError(t, s.SaveURL(ctx, entity.URLRecord{UUID: "3", ShortURL: "ghi", OriginalURL: "https://g.example.com", UserID: "alice"}))
+
+			s = reopenStorage(t, s)
+
+			_, err = s.GetURL(ctx, "ghi")
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestWAL_append_failedWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.log")
+
+	w, err := openWAL(path, SyncAlways)
+	require.NoError(t, err)
+	t.Cleanup(func() { w.close() })
+
+	require.NoError(t, w.append(walOp{Op: opCreate, Records: []entity.URLRecord{{UUID: "1", ShortURL: "abc", UserID: "alice"}}}))
+
+	// A read-only handle fails both the write and its truncation.
+	writable := w.f
+	w.f, err = os.Open(path)
+	require.NoError(t, err)
+
+	assert.Error(t, w.append(walOp{Op: opDelete, UUIDs: []string{"1"}}))
+
+	w.f.Close()
+	w.f = writable
+
+	// The log may end with a broken line, so nothing is appended after it until it's compacted.
+	assert.Error(t, w.append(walOp{Op: opDelete, UUIDs: []string{"1"}}))
+
+	require.NoError(t, w.compact([]entity.URLRecord{{UUID: "1", ShortURL: "abc", UserID: "alice"}}))
+	require.NoError(t, w.append(walOp{Op: opDelete, UUIDs: []string{"1"}}))
+
+	var ops []string
+	_, _, err = replayWAL(path, func(op walOp) { ops = append(ops, op.Op) })
+	require.NoError(t, err)
+	assert.Equal(t, []string{opCreate, opDelete}, ops)
+}
+
+func TestReplayWAL_corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.log")
+
+	first, err := encodeOp(walOp{Op: opCreate, Records: []entity.URLRecord{{UUID: "1"}}})
+	require.NoError(t, err)
+	second, err := encodeOp(walOp{Op: opDelete, UUIDs: []string{"1"}})
+	require.NoError(t, err)
+
+	corrupt := []byte(strings.Replace(string(first), `"1"`, `"2"`, 1))
+	require.NoError(t, os.WriteFile(path, append(corrupt, second...), 0666))
+
+	_, _, err = replayWAL(path, func(walOp) {})
+	assert.ErrorIs(t, err, ErrCorruptLog)
+}
+
+func TestNewInMemStorage_corruptLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.log")
+
+	first, err := encodeOp(walOp{Op: opCreate, Records: []entity.URLRecord{{UUID: "1", ShortURL: "abc", UserID: "alice"}}})
+	require.NoError(t, err)
+	second, err := encodeOp(walOp{Op: opDelete, UUIDs: []string{"1"}})
+	require.NoError(t, err)
+
+	corrupt := append([]byte(strings.Replace(string(first), `"abc"`, `"xyz"`, 1)), second...)
+	require.NoError(t, os.WriteFile(path, corrupt, 0666))
+
+	log, _ := logger.Initialize("debug")
+
+	_, err = NewInMemStorage(path, log)
+	assert.ErrorIs(t, err, ErrCorruptLog)
+
+	// The log is left as it is for a manual repair.
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, corrupt, data)
+}
+
+func TestInMemStorage_compact(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "db.log")
+	s := openStorage(t, path)
+
+	require.NoError(t, s.SaveURL(ctx, entity.URLRecord{UUID: "1", ShortURL: "abc", OriginalURL: "https://a.example.com", UserID: "alice"}))
+	for i := 0; i <= 10; i++ {
+		_, err := s.SetURLDisabled(ctx, "abc", i%2 == 0)
+		require.NoError(t, err)
+	}
+	_, err := s.DeleteURLBatch([]string{"abc"}, "alice")
+	require.NoError(t, err)
+
+	before, err := os.Stat(path)
+	require.NoError(t, err)
+
+	require.NoError(t, s.compact())
+
+	after, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Less(t, after.Size(), before.Size())
+
+	// The compacted log is appended to.
+	require.NoError(t, s.SaveURL(ctx, entity.URLRecord{UUID: "2", ShortURL: "def", OriginalURL: "https://d.example.com", UserID: "bob"}))
+
+	s = reopenStorage(t, s)
+
+	records, err := s.GetURLsByUserID(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, []entity.URLRecord{
+		{UUID: "1", ShortURL: "abc", OriginalURL: "https://a.example.com", UserID: "alice", DeletedFlag: true, Disabled: true},
+	}, records)
+
+	_, err = s.GetURL(ctx, "def")
+	assert.NoError(t, err)
+}
+
+func TestInMemStorage_convertsLegacyFile(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "db.json")
+
+	legacy := `{"uuid":"1","short_url":"abc","original_url":"https://a.example.com","user_id":"alice"}
+{"uuid":"1","short_url":"abc","original_url":"https://a.example.com","user_id":"alice","preview":{"title":"A"}}
+`
+	require.NoError(t, os.WriteFile(path, []byte(legacy), 0666))
+
+	s := openStorage(t, path)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotEqual(t, byte('{'), data[0], "storage file must be converted to the log")
+
+	s = reopenStorage(t, s)
+
+	r, err := s.GetURLRecord(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, &entity.Preview{Title: "A"}, r.Preview)
+}
+
+func TestNewInMemStorage_readOnly(t *testing.T) {
+	ctx := context.Background()
+	log, _ := logger.Initialize("debug")
+	dir := t.TempDir()
+
+	legacy := filepath.Join(dir, "db.json")
+	require.NoError(t, os.WriteFile(legacy,
+		[]byte(`{"uuid":"1","short_url":"abc","original_url":"https://a.example.com","user_id":"alice"}`+"\n"), 0666))
+
+	op, err := encodeOp(walOp{Op: opCreate, Records: []entity.URLRecord{{UUID: "1", ShortURL: "abc", OriginalURL: "https://a.example.com", UserID: "alice"}}})
+	require.NoError(t, err)
+	torn := filepath.Join(dir, "db.log")
+	require.NoError(t, os.WriteFile(torn, append(op, op[:len(op)-5]...), 0666))
+
+	for _, path := range []string{legacy, torn} {
+		before, err := os.ReadFile(path)
+		require.NoError(t, err)
+
+		s := openStorage(t, path, WithReadOnly())
+
+		_, err = s.GetURL(ctx, "abc")
+		assert.NoError(t, err, path)
+
+		err = s.SaveURL(ctx, entity.URLRecord{UUID: "2", ShortURL: "def", OriginalURL: "https://d.example.com", UserID: "alice"})
+		assert.ErrorIs(t, err, ErrReadOnly)
+
+		_, err = s.GetURL(ctx, "def")
+		assert.ErrorIs(t, err, storage.ErrURLNotFound, "a refused change must not be applied")
+
+		require.NoError(t, s.Close())
+
+		// Neither the legacy file is converted nor the torn write cut off.
+		after, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, before, after, path)
+	}
+
+	_, err = NewInMemStorage(filepath.Join(dir, "missing.log"), log, WithReadOnly())
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.NoFileExists(t, filepath.Join(dir, "missing.log"))
+}
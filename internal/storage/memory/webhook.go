@@ -0,0 +1,167 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// WebhookStorage keeps webhooks and their delivery log in memory and, if it has a file, appends every change
+// to the file, so the webhooks survive a restart like the links whose events they receive.
+type WebhookStorage struct {
+	webhooks   map[string]entity.Webhook
+	deliveries map[string][]entity.WebhookDelivery
+	filePath   string
+	mu         sync.Mutex
+}
+
+// Operations of the webhook file.
+const (
+	webhookOpSave         = "save_webhook"
+	webhookOpDelete       = "delete_webhook"
+	webhookOpSaveDelivery = "save_delivery"
+)
+
+// webhookOp is a change appended to the webhook file. The save_webhook and delete_webhook operations
+// save or delete the Webhook, the save_delivery operation appends the Delivery to the log of its webhook.
+type webhookOp struct {
+	Op       string                  `json:"op"`
+	Webhook  *entity.Webhook         `json:"webhook,omitempty"`
+	Delivery *entity.WebhookDelivery `json:"delivery,omitempty"`
+}
+
+// NewWebhookStorage initializes a new WebhookStorage instance that keeps the webhooks in memory only.
+func NewWebhookStorage() storage.WebhookRepository {
+	return &WebhookStorage{
+		webhooks:   make(map[string]entity.Webhook),
+		deliveries: make(map[string][]entity.WebhookDelivery),
+	}
+}
+
+// NewFileWebhookStorage initializes a new WebhookStorage instance persisted to the file at filePath
+// and restores the webhooks and their delivery logs from the file if it exists. It fails if the file can't be read.
+func NewFileWebhookStorage(filePath string) (storage.WebhookRepository, error) {
+	s := &WebhookStorage{
+		webhooks:   make(map[string]entity.Webhook),
+		deliveries: make(map[string][]entity.WebhookDelivery),
+		filePath:   filePath,
+	}
+
+	err := replayJSON(filePath, func(dec *json.Decoder) error {
+		var op webhookOp
+		if err := dec.Decode(&op); err != nil {
+			return err
+		}
+
+		return s.apply(op)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// SaveWebhook stores a new webhook.
+func (s *WebhookStorage) SaveWebhook(_ context.Context, webhook entity.Webhook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.write(webhookOp{Op: webhookOpSave, Webhook: &webhook})
+}
+
+// GetWebhooksByUserID retrieves all the webhooks registered by a specific user.
+func (s *WebhookStorage) GetWebhooksByUserID(_ context.Context, userID string) ([]entity.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var webhooks []entity.Webhook
+	for _, w := range s.webhooks {
+		if w.UserID == userID {
+			webhooks = append(webhooks, w)
+		}
+	}
+
+	return webhooks, nil
+}
+
+// DeleteWebhook removes the webhook of a specific user together with its delivery log.
+func (s *WebhookStorage) DeleteWebhook(_ context.Context, id, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.webhooks[id]
+	if !ok || w.UserID != userID {
+		return errors.New("webhook not found")
+	}
+
+	return s.write(webhookOp{Op: webhookOpDelete, Webhook: &w})
+}
+
+// SaveDelivery appends a delivery attempt to the log of its webhook.
+func (s *WebhookStorage) SaveDelivery(_ context.Context, delivery entity.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.write(webhookOp{Op: webhookOpSaveDelivery, Delivery: &delivery})
+}
+
+// GetDeliveries retrieves up to limit of the latest delivery attempts of a webhook, newest first.
+func (s *WebhookStorage) GetDeliveries(_ context.Context, webhookID string, limit int) ([]entity.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log := s.deliveries[webhookID]
+
+	var deliveries []entity.WebhookDelivery
+	for i := len(log) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		deliveries = append(deliveries, log[i])
+	}
+
+	return deliveries, nil
+}
+
+// write appends the change to the file, if there is one, and applies it.
+func (s *WebhookStorage) write(op webhookOp) error {
+	if s.filePath != "" {
+		if err := appendJSON(s.filePath, op); err != nil {
+			return err
+		}
+	}
+
+	return s.apply(op)
+}
+
+// apply applies the change to the webhooks in memory.
+func (s *WebhookStorage) apply(op webhookOp) error {
+	switch op.Op {
+	case webhookOpSave:
+		if op.Webhook == nil {
+			return errors.New("webhook is missing")
+		}
+
+		s.webhooks[op.Webhook.ID] = *op.Webhook
+	case webhookOpDelete:
+		if op.Webhook == nil {
+			return errors.New("webhook is missing")
+		}
+
+		delete(s.webhooks, op.Webhook.ID)
+		delete(s.deliveries, op.Webhook.ID)
+	case webhookOpSaveDelivery:
+		if op.Delivery == nil {
+			return errors.New("delivery is missing")
+		}
+
+		s.deliveries[op.Delivery.WebhookID] = append(s.deliveries[op.Delivery.WebhookID], *op.Delivery)
+	default:
+		return fmt.Errorf("unknown operation %q", op.Op)
+	}
+
+	return nil
+}
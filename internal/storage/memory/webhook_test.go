@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+func TestFileWebhookStorage(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "db.json.webhooks")
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	s, err := NewFileWebhookStorage(path)
+	require.NoError(t, err)
+
+	hook := entity.Webhook{ID: "1", UserID: "alice", URL: "https://hooks.example.com", Secret: "s", Events: []string{"link.created"}, CreatedAt: created}
+	require.NoError(t, s.SaveWebhook(ctx, hook))
+	require.NoError(t, s.SaveWebhook(ctx, entity.Webhook{ID: "2", UserID: "alice", URL: "https://other.example.com"}))
+	require.NoError(t, s.SaveDelivery(ctx, entity.WebhookDelivery{ID: "d1", WebhookID: "1", Attempt: 1, StatusCode: 500, CreatedAt: created}))
+	require.NoError(t, s.SaveDelivery(ctx, entity.WebhookDelivery{ID: "d2", WebhookID: "1", Attempt: 2, Delivered: true, CreatedAt: created}))
+	require.NoError(t, s.SaveDelivery(ctx, entity.WebhookDelivery{ID: "d3", WebhookID: "2", Attempt: 1, CreatedAt: created}))
+	require.NoError(t, s.DeleteWebhook(ctx, "2", "alice"))
+	assert.Error(t, s.DeleteWebhook(ctx, "1", "bob"))
+
+	restored, err := NewFileWebhookStorage(path)
+	require.NoError(t, err)
+
+	webhooks, err := restored.GetWebhooksByUserID(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, []entity.Webhook{hook}, webhooks)
+
+	deliveries, err := restored.GetDeliveries(ctx, "1", 10)
+	require.NoError(t, err)
+
+	want, err := s.GetDeliveries(ctx, "1", 10)
+	require.NoError(t, err)
+	assert.Equal(t, want, deliveries)
+	require.Len(t, deliveries, 2)
+
+	deliveries, err = restored.GetDeliveries(ctx, "2", 10)
+	require.NoError(t, err)
+	assert.Empty(t, deliveries)
+
+	require.NoError(t, os.WriteFile(path, []byte("{\"op\":"), 0600))
+
+	_, err = NewFileWebhookStorage(path)
+	assert.Error(t, err, "a damaged file should not be overwritten")
+}
@@ -0,0 +1,218 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// WorkspaceStorage keeps workspaces and their members in memory and, if it has a file, appends every change
+// to the file, so the workspaces survive a restart like the URLs moved to them.
+type WorkspaceStorage struct {
+	workspaces map[string]entity.Workspace
+	members    map[string]map[string]entity.WorkspaceMember
+	filePath   string
+	mu         sync.Mutex
+}
+
+// Operations of the workspace file.
+const (
+	workspaceOpSave         = "save_workspace"
+	workspaceOpSaveMember   = "save_member"
+	workspaceOpDeleteMember = "delete_member"
+)
+
+// workspaceOp is a change appended to the workspace file. The save_workspace operation saves the Workspace
+// with the Member as its owner, the other operations save or delete the Member.
+type workspaceOp struct {
+	Op        string                 `json:"op"`
+	Workspace *entity.Workspace      `json:"workspace,omitempty"`
+	Member    entity.WorkspaceMember `json:"member"`
+}
+
+// NewWorkspaceStorage initializes a new WorkspaceStorage instance that keeps the workspaces in memory only.
+func NewWorkspaceStorage() storage.WorkspaceRepository {
+	return &WorkspaceStorage{
+		workspaces: make(map[string]entity.Workspace),
+		members:    make(map[string]map[string]entity.WorkspaceMember),
+	}
+}
+
+// NewFileWorkspaceStorage initializes a new WorkspaceStorage instance persisted to the file at filePath
+// and restores the workspaces from the file if it exists. It fails if the file can't be read.
+func NewFileWorkspaceStorage(filePath string) (storage.WorkspaceRepository, error) {
+	s := &WorkspaceStorage{
+		workspaces: make(map[string]entity.Workspace),
+		members:    make(map[string]map[string]entity.WorkspaceMember),
+		filePath:   filePath,
+	}
+
+	err := replayJSON(filePath, func(dec *json.Decoder) error {
+		var op workspaceOp
+		if err := dec.Decode(&op); err != nil {
+			return err
+		}
+
+		return s.apply(op)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// SaveWorkspace stores a new workspace with its first member.
+func (s *WorkspaceStorage) SaveWorkspace(_ context.Context, workspace entity.Workspace, owner entity.WorkspaceMember) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.write(workspaceOp{Op: workspaceOpSave, Workspace: &workspace, Member: owner})
+}
+
+// GetWorkspace retrieves the workspace with the given ID. It returns nil if there is no such workspace.
+func (s *WorkspaceStorage) GetWorkspace(_ context.Context, id string) (*entity.Workspace, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.workspaces[id]
+	if !ok {
+		return nil, nil
+	}
+
+	return &w, nil
+}
+
+// SaveMember adds the member to the workspace or replaces the permissions of the member.
+func (s *WorkspaceStorage) SaveMember(_ context.Context, member entity.WorkspaceMember) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.members[member.WorkspaceID]; !ok {
+		return errors.New("workspace not found")
+	}
+
+	return s.write(workspaceOp{Op: workspaceOpSaveMember, Member: member})
+}
+
+// GetMember retrieves the membership of the user in the workspace. It returns nil if the user is not a member.
+func (s *WorkspaceStorage) GetMember(_ context.Context, workspaceID, userID string) (*entity.WorkspaceMember, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.members[workspaceID][userID]
+	if !ok {
+		return nil, nil
+	}
+
+	return &m, nil
+}
+
+// GetMembers retrieves the members of the workspace, oldest first.
+func (s *WorkspaceStorage) GetMembers(_ context.Context, workspaceID string) ([]entity.WorkspaceMember, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	members := make([]entity.WorkspaceMember, 0, len(s.members[workspaceID]))
+	for _, m := range s.members[workspaceID] {
+		members = append(members, m)
+	}
+
+	sortMembers(members)
+
+	return members, nil
+}
+
+// GetMembershipsByUserID retrieves the memberships of the user in all the workspaces, oldest first.
+func (s *WorkspaceStorage) GetMembershipsByUserID(_ context.Context, userID string) ([]entity.WorkspaceMember, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var memberships []entity.WorkspaceMember
+	for _, members := range s.members {
+		if m, ok := members[userID]; ok {
+			memberships = append(memberships, m)
+		}
+	}
+
+	sortMembers(memberships)
+
+	return memberships, nil
+}
+
+// DeleteMember removes the user from the workspace.
+func (s *WorkspaceStorage) DeleteMember(_ context.Context, workspaceID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.write(workspaceOp{
+		Op:     workspaceOpDeleteMember,
+		Member: entity.WorkspaceMember{WorkspaceID: workspaceID, UserID: userID},
+	})
+}
+
+// write appends the change to the file, if there is one, and applies it.
+func (s *WorkspaceStorage) write(op workspaceOp) error {
+	if s.filePath != "" {
+		if err := appendJSON(s.filePath, op); err != nil {
+			return err
+		}
+	}
+
+	return s.apply(op)
+}
+
+// apply applies the change to the workspaces in memory.
+func (s *WorkspaceStorage) apply(op workspaceOp) error {
+	m := op.Member
+
+	switch op.Op {
+	case workspaceOpSave:
+		if op.Workspace == nil {
+			return errors.New("workspace is missing")
+		}
+
+		s.workspaces[op.Workspace.ID] = *op.Workspace
+		s.members[op.Workspace.ID] = map[string]entity.WorkspaceMember{m.UserID: m}
+	case workspaceOpSaveMember:
+		members, ok := s.members[m.WorkspaceID]
+		if !ok {
+			return errors.New("workspace not found")
+		}
+
+		if existing, ok := members[m.UserID]; ok {
+			m.CreatedAt = existing.CreatedAt
+		}
+
+		members[m.UserID] = m
+	case workspaceOpDeleteMember:
+		delete(s.members[m.WorkspaceID], m.UserID)
+	default:
+		return fmt.Errorf("unknown operation %q", op.Op)
+	}
+
+	return nil
+}
+
+// sortMembers orders the members oldest first, and the ones created at the same time by workspace and user,
+// so that the order doesn't depend on the iteration of the maps.
+func sortMembers(members []entity.WorkspaceMember) {
+	sort.Slice(members, func(i, j int) bool {
+		a, b := members[i], members[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+
+		if a.WorkspaceID != b.WorkspaceID {
+			return a.WorkspaceID < b.WorkspaceID
+		}
+
+		return a.UserID < b.UserID
+	})
+}
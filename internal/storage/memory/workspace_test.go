@@ -0,0 +1,49 @@
+package memory
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+func TestFileWorkspaceStorage(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "db.json.workspaces")
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	s, err := NewFileWorkspaceStorage(path)
+	require.NoError(t, err)
+
+	owner := entity.WorkspaceMember{WorkspaceID: "ws", UserID: "alice", CanView: true, CanEdit: true, CanDelete: true, CreatedAt: created}
+	require.NoError(t, s.SaveWorkspace(ctx, entity.Workspace{ID: "ws", Name: "team", CreatedAt: created}, owner))
+	require.NoError(t, s.SaveMember(ctx, entity.WorkspaceMember{WorkspaceID: "ws", UserID: "bob", CanView: true, CreatedAt: created}))
+	require.NoError(t, s.SaveMember(ctx, entity.WorkspaceMember{WorkspaceID: "ws", UserID: "carol", CanView: true, CreatedAt: created}))
+	require.NoError(t, s.SaveMember(ctx, entity.WorkspaceMember{WorkspaceID: "ws", UserID: "bob", CanDelete: true, CreatedAt: created.Add(time.Hour)}))
+	require.NoError(t, s.DeleteMember(ctx, "ws", "carol"))
+	assert.Error(t, s.SaveMember(ctx, entity.WorkspaceMember{WorkspaceID: "unknown", UserID: "bob"}))
+
+	restored, err := NewFileWorkspaceStorage(path)
+	require.NoError(t, err)
+
+	w, err := restored.GetWorkspace(ctx, "ws")
+	require.NoError(t, err)
+	assert.Equal(t, "team", w.Name)
+
+	members, err := restored.GetMembers(ctx, "ws")
+	require.NoError(t, err)
+
+	want, err := s.GetMembers(ctx, "ws")
+	require.NoError(t, err)
+	assert.Equal(t, want, members)
+	require.Len(t, members, 2)
+
+	bob, err := restored.GetMember(ctx, "ws", "bob")
+	require.NoError(t, err)
+	assert.Equal(t, &entity.WorkspaceMember{WorkspaceID: "ws", UserID: "bob", CanDelete: true, CreatedAt: created}, bob)
+}
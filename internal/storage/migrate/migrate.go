@@ -0,0 +1,433 @@
+// Package migrate applies and reverts versioned SQL migrations and records the applied versions
+// in the 'schema_migrations' table of the database.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+)
+
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// ErrUnknownVersion is thrown when migrating to a version that no migration has.
+var ErrUnknownVersion = errors.New("unknown migration version")
+
+// Migration is a versioned change of the database schema with the scripts that apply and revert it.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status describes a migration and when it was applied. AppliedAt is zero if the migration is pending.
+type Status struct {
+	Migration
+	AppliedAt time.Time
+}
+
+// Applied reports whether the migration has been applied.
+func (s Status) Applied() bool {
+	return !s.AppliedAt.IsZero()
+}
+
+// Locker takes and releases a lock on the connection that keeps concurrent migrators from changing the schema
+// at the same time.
+type Locker interface {
+	Lock(ctx context.Context, conn *sql.Conn) error
+	Unlock(ctx context.Context, conn *sql.Conn) error
+}
+
+// Option configures optional features of the Migrator.
+type Option func(m *Migrator)
+
+// WithLocker holds the lock of the locker while the migrator reads or changes the schema.
+func WithLocker(l Locker) Option {
+	return func(m *Migrator) {
+		m.locker = l
+	}
+}
+
+// Migrator applies and reverts the migrations of a database and records the applied versions
+// in the 'schema_migrations' table. Every migration runs in its own transaction.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	locker     Locker
+	logger     *logger.Logger
+}
+
+// New initializes a new Migrator with the migrations in the root directory of fsys.
+func New(db *sql.DB, fsys fs.FS, logger *logger.Logger, opts ...Option) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Migrator{
+		db:         db,
+		migrations: migrations,
+		logger:     logger,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m, nil
+}
+
+// Status returns every migration with the time it was applied, in version order.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		statuses = make([]Status, 0, len(m.migrations))
+		for _, mg := range m.migrations {
+			statuses = append(statuses, Status{Migration: mg, AppliedAt: applied[mg.Version]})
+		}
+
+		return nil
+	})
+
+	return statuses, err
+}
+
+// Up applies all pending migrations in version order and returns the number of applied migrations.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	if len(m.migrations) == 0 {
+		return 0, nil
+	}
+
+	return m.To(ctx, m.migrations[len(m.migrations)-1].Version)
+}
+
+// Down reverts the given number of the latest applied migrations and returns the number of reverted migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	var n int
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && n < steps; i-- {
+			mg := m.migrations[i]
+			if applied[mg.Version].IsZero() {
+				continue
+			}
+
+			if err := m.revert(ctx, conn, mg); err != nil {
+				return err
+			}
+			n++
+		}
+
+		return nil
+	})
+
+	return n, err
+}
+
+// To applies the pending migrations up to the version and reverts the applied migrations above it,
+// so that the schema matches the version. Version 0 reverts every migration.
+// It returns the number of applied or reverted migrations.
+func (m *Migrator) To(ctx context.Context, version int64) (int, error) {
+	if version != 0 && !m.known(version) {
+		return 0, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	var n int
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mg := m.migrations[i]
+			if mg.Version <= version || applied[mg.Version].IsZero() {
+				continue
+			}
+
+			if err := m.revert(ctx, conn, mg); err != nil {
+				return err
+			}
+			n++
+		}
+
+		for _, mg := range m.migrations {
+			if mg.Version > version || !applied[mg.Version].IsZero() {
+				continue
+			}
+
+			if err := m.apply(ctx, conn, mg); err != nil {
+				return err
+			}
+			n++
+		}
+
+		return nil
+	})
+
+	return n, err
+}
+
+func (m *Migrator) known(version int64) bool {
+	for _, mg := range m.migrations {
+		if mg.Version == version {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, mg Migration) error {
+	err := m.inTx(ctx, conn, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, mg.Up); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, mg.Version, mg.Name)
+
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("apply migration %d_%s: %w", mg.Version, mg.Name, err)
+	}
+
+	m.logger.Info("applied migration", zap.Int64("version", mg.Version), zap.String("name", mg.Name))
+
+	return nil
+}
+
+func (m *Migrator) revert(ctx context.Context, conn *sql.Conn, mg Migration) error {
+	err := m.inTx(ctx, conn, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, mg.Down); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, mg.Version)
+
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("revert migration %d_%s: %w", mg.Version, mg.Name, err)
+	}
+
+	m.logger.Info("reverted migration", zap.Int64("version", mg.Version), zap.String("name", mg.Name))
+
+	return nil
+}
+
+// withLock runs fn on a dedicated connection holding the lock of the locker, if any,
+// after creating the 'schema_migrations' table if it doesn't exist.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.locker != nil {
+		if err := m.locker.Lock(ctx, conn); err != nil {
+			return fmt.Errorf("acquire migration lock: %w", err)
+		}
+
+		defer func() {
+			if err := m.locker.Unlock(context.Background(), conn); err != nil {
+				m.logger.Error("cannot release migration lock", zap.Error(err))
+			}
+		}()
+	}
+
+	query := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP)`
+
+	if _, err := conn.ExecContext(ctx, query); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+
+		applied[version] = appliedAt
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return applied, nil
+}
+
+func (m *Migrator) inTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			m.logger.Error("failed to rollback queries", zap.Error(err))
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Load reads the migrations from the root directory of fsys in version order. Every migration must have
+// an up and a down script named '<version>_<name>.up.sql' and '<version>_<name>.down.sql'.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, e := range entries {
+		match := migrationName.FindStringSubmatch(e.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", e.Name())
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %q", e.Name())
+		}
+
+		script, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mg, ok := byVersion[version]
+		if !ok {
+			mg = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = mg
+		}
+
+		if mg.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has different names %q and %q", version, mg.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			mg.Up = string(script)
+		} else {
+			mg.Down = string(script)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mg := range byVersion {
+		if mg.Up == "" || mg.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down scripts", mg.Version, mg.Name)
+		}
+
+		migrations = append(migrations, *mg)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Overlay returns a file system of the files of the layers. A file of a layer hides the file with
+// the same name in the layers before it, so that the migrations of a database dialect can share
+// the portable scripts and replace the ones that aren't.
+func Overlay(layers ...fs.FS) fs.FS {
+	return overlay(layers)
+}
+
+type overlay []fs.FS
+
+// Open opens the file of the last layer that has it.
+func (o overlay) Open(name string) (fs.File, error) {
+	for i := len(o) - 1; i >= 0; i-- {
+		f, err := o[i].Open(name)
+		if !errors.Is(err, fs.ErrNotExist) {
+			return f, err
+		}
+	}
+
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir lists the files the directory has in any of the layers, sorted by name.
+func (o overlay) ReadDir(name string) ([]fs.DirEntry, error) {
+	byName := make(map[string]fs.DirEntry)
+	found := false
+
+	for _, layer := range o {
+		entries, err := fs.ReadDir(layer, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		found = true
+		for _, e := range entries {
+			byName[e.Name()] = e
+		}
+	}
+
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(byName))
+	for _, e := range byName {
+		entries = append(entries, e)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	return entries, nil
+}
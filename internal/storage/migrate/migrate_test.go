@@ -0,0 +1,167 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+)
+
+func TestLoad(t *testing.T) {
+	file := func(s string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(s)}
+	}
+
+	tests := []struct {
+		name     string
+		files    fstest.MapFS
+		versions []int64
+		wantErr  bool
+	}{
+		{
+			name: "should sort migrations by version",
+			files: fstest.MapFS{
+				"0010_b.up.sql":   file("b up"),
+				"0010_b.down.sql": file("b down"),
+				"0002_a.up.sql":   file("a up"),
+				"0002_a.down.sql": file("a down"),
+			},
+			versions: []int64{2, 10},
+		},
+		{
+			name: "should reject missing down script",
+			files: fstest.MapFS{
+				"0001_a.up.sql": file("a up"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "should reject different names of one version",
+			files: fstest.MapFS{
+				"0001_a.up.sql":   file("a up"),
+				"0001_b.down.sql": file("b down"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "should reject invalid file name",
+			files: fstest.MapFS{
+				"a.up.sql": file("a up"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "should reject zero version",
+			files: fstest.MapFS{
+				"0000_a.up.sql":   file("a up"),
+				"0000_a.down.sql": file("a down"),
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := Load(tt.files)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+
+			versions := make([]int64, 0, len(migrations))
+			for _, mg := range migrations {
+				versions = append(versions, mg.Version)
+			}
+
+			assert.Equal(t, tt.versions, versions)
+		})
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	shared := fstest.MapFS{
+		"0001_a.up.sql":   {Data: []byte("shared a up")},
+		"0001_a.down.sql": {Data: []byte("shared a down")},
+	}
+	dialect := fstest.MapFS{
+		"0001_a.up.sql":   {Data: []byte("dialect a up")},
+		"0002_b.up.sql":   {Data: []byte("dialect b up")},
+		"0002_b.down.sql": {Data: []byte("dialect b down")},
+	}
+
+	migrations, err := Load(Overlay(shared, dialect))
+	require.NoError(t, err)
+
+	assert.Equal(t, []Migration{
+		{Version: 1, Name: "a", Up: "dialect a up", Down: "shared a down"},
+		{Version: 2, Name: "b", Up: "dialect b up", Down: "dialect b down"},
+	}, migrations)
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	log, _ := logger.Initialize("debug")
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "migrate.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	files := fstest.MapFS{
+		"0001_a.up.sql":   {Data: []byte(`CREATE TABLE a (id INTEGER)`)},
+		"0001_a.down.sql": {Data: []byte(`DROP TABLE a`)},
+		"0002_b.up.sql":   {Data: []byte(`CREATE TABLE b (id INTEGER)`)},
+		"0002_b.down.sql": {Data: []byte(`DROP TABLE b`)},
+		"0003_c.up.sql":   {Data: []byte(`CREATE TABLE c (id INTEGER)`)},
+		"0003_c.down.sql": {Data: []byte(`DROP TABLE c`)},
+	}
+
+	m, err := New(db, files, log)
+	require.NoError(t, err)
+
+	applied := func() []bool {
+		statuses, err := m.Status(ctx)
+		require.NoError(t, err)
+
+		got := make([]bool, 0, len(statuses))
+		for _, s := range statuses {
+			got = append(got, s.Applied())
+		}
+
+		return got
+	}
+
+	n, err := m.To(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []bool{true, true, false}, applied())
+
+	n, err = m.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []bool{true, true, true}, applied())
+
+	n, err = m.Down(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []bool{true, false, false}, applied())
+
+	_, err = db.ExecContext(ctx, `SELECT id FROM b`)
+	assert.Error(t, err, "reverted migration must drop its table")
+
+	_, err = m.To(ctx, 4)
+	assert.ErrorIs(t, err, ErrUnknownVersion)
+
+	n, err = m.To(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []bool{false, false, false}, applied())
+}
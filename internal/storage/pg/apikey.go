@@ -0,0 +1,150 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// APIKeyStorage is a struct that implements the storage.APIKeyRepository interface, using Postgresql as a storage backend.
+type APIKeyStorage struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+// NewAPIKeyStorage initializes a new APIKeyStorage instance with provided inputs.
+func NewAPIKeyStorage(db *sql.DB, logger *logger.Logger) storage.APIKeyRepository {
+	return &APIKeyStorage{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SaveAPIKey stores a new API key in the SQL database.
+func (s *APIKeyStorage) SaveAPIKey(ctx context.Context, key entity.APIKey) error {
+	query := `
+		INSERT INTO api_keys (id, user_id, name, key_hash, scopes, expires_at, revoked, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	var expiresAt sql.NullTime
+	if !key.ExpiresAt.IsZero() {
+		expiresAt = sql.NullTime{Time: key.ExpiresAt, Valid: true}
+	}
+
+	_, err := s.db.ExecContext(ctx, query,
+		key.ID, key.UserID, key.Name, key.Hash, strings.Join(key.Scopes, ","), expiresAt, key.Revoked, key.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetAPIKeyByHash retrieves the API key with the given hash from the SQL database.
+// It returns nil if there is no such key.
+func (s *APIKeyStorage) GetAPIKeyByHash(ctx context.Context, hash string) (*entity.APIKey, error) {
+	query := `
+		SELECT id, user_id, name, key_hash, scopes, expires_at, revoked, created_at
+		FROM api_keys
+		WHERE key_hash = $1`
+
+	row := s.db.QueryRowContext(ctx, query, hash)
+
+	key, err := scanAPIKey(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// GetAPIKeysByUserID retrieves all the API keys of a specific user, including the revoked ones, from the SQL database.
+func (s *APIKeyStorage) GetAPIKeysByUserID(ctx context.Context, userID string) ([]entity.APIKey, error) {
+	query := `
+		SELECT id, user_id, name, key_hash, scopes, expires_at, revoked, created_at
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at`
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			s.logger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	var keys []entity.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, *key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey marks the API key of a specific user as revoked in the SQL database.
+func (s *APIKeyStorage) RevokeAPIKey(ctx context.Context, id, userID string) error {
+	query := `
+		UPDATE api_keys
+		SET revoked = true
+		WHERE id = $1 AND user_id = $2`
+
+	res, err := s.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return errors.New("api key not found")
+	}
+
+	return nil
+}
+
+func scanAPIKey(row scanner) (*entity.APIKey, error) {
+	var k entity.APIKey
+	var scopes string
+	var expiresAt sql.NullTime
+
+	err := row.Scan(&k.ID, &k.UserID, &k.Name, &k.Hash, &scopes, &expiresAt, &k.Revoked, &k.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if scopes != "" {
+		k.Scopes = strings.Split(scopes, ",")
+	}
+
+	if expiresAt.Valid {
+		k.ExpiresAt = expiresAt.Time
+	}
+
+	return &k, nil
+}
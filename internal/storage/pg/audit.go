@@ -0,0 +1,124 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// AuditStorage is a struct that implements the storage.AuditRepository interface, using Postgresql as a storage backend.
+type AuditStorage struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+// NewAuditStorage initializes a new AuditStorage instance with provided inputs.
+func NewAuditStorage(db *sql.DB, logger *logger.Logger) storage.AuditRepository {
+	return &AuditStorage{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// auditChunkSize limits the entries inserted by a single statement, so that its bound parameters stay
+// well below the limits of Postgresql and SQLite.
+const auditChunkSize = 500
+
+// auditColumns is the number of the columns an entry is inserted with.
+const auditColumns = 10
+
+// SaveAuditEntries stores the entries in the SQL database with multi-row inserts in a single transaction.
+func (s *AuditStorage) SaveAuditEntries(ctx context.Context, entries []entity.AuditEntry) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			s.logger.Error("failed to rollback queries", zap.Error(err))
+		}
+	}()
+
+	for start := 0; start < len(entries); start += auditChunkSize {
+		end := start + auditChunkSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+
+		values := make([]string, 0, end-start)
+		args := make([]interface{}, 0, (end-start)*auditColumns)
+		for _, e := range entries[start:end] {
+			values = append(values, "("+placeholders(len(args)+1, auditColumns)+")")
+			args = append(args, e.ID, e.ActorID, e.Action, e.Target, e.Transport, e.ClientIP, e.RequestID,
+				e.Before, e.After, e.CreatedAt)
+		}
+
+		query := `
+		INSERT INTO audit_log (id, actor_id, action, target, transport, client_ip, request_id,
+			before_value, after_value, created_at)
+		VALUES ` + strings.Join(values, ", ")
+
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetAuditEntries retrieves the entries matching the filter from the SQL database, newest first.
+func (s *AuditStorage) GetAuditEntries(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditEntry, error) {
+	query := `
+		SELECT id, actor_id, action, target, transport, client_ip, request_id,
+			COALESCE(before_value, ''), COALESCE(after_value, ''), created_at
+		FROM audit_log
+		WHERE ($1 = '' OR actor_id = $1)
+			AND ($2 = '' OR action = $2)
+			AND ($3 = '' OR target = $3)
+			AND ($4 = '' OR transport = $4)
+			AND (CAST($5 AS TIMESTAMP) IS NULL OR created_at >= $5)
+			AND (CAST($6 AS TIMESTAMP) IS NULL OR created_at < $6)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $7 OFFSET $8`
+
+	rows, err := s.db.QueryContext(ctx, query, filter.ActorID, filter.Action, filter.Target, filter.Transport,
+		nullTime(filter.Since), nullTime(filter.Until), filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			s.logger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	var entries []entity.AuditEntry
+	for rows.Next() {
+		var e entity.AuditEntry
+		err := rows.Scan(&e.ID, &e.ActorID, &e.Action, &e.Target, &e.Transport, &e.ClientIP, &e.RequestID,
+			&e.Before, &e.After, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
@@ -0,0 +1,62 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"time"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/migrate"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/schema"
+)
+
+// migrationLockKey is the key of the advisory lock held while migrations run, so that concurrent instances
+// apply them one at a time.
+const migrationLockKey = 7354021884
+
+// migrationTimeout limits the time Migrate waits for the lock and applies the pending migrations at startup.
+const migrationTimeout = time.Minute
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// advisoryLock is a session-level PostgreSQL advisory lock on the migrations.
+type advisoryLock struct{}
+
+func (advisoryLock) Lock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey)
+	return err
+}
+
+// Unlock releases the lock. The lock is released with the session anyway, so the migrator only logs a failure.
+func (advisoryLock) Unlock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, migrationLockKey)
+	return err
+}
+
+// NewMigrator initializes a new migrate.Migrator with the portable and the PostgreSQL migrations embedded in the binary.
+// It holds an advisory lock while it changes the schema.
+func NewMigrator(db *sql.DB, logger *logger.Logger) (*migrate.Migrator, error) {
+	files, err := schema.Migrations(migrationFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.New(db, files, logger, migrate.WithLocker(advisoryLock{}))
+}
+
+// Migrate applies all pending migrations to the PostgreSQL database.
+func Migrate(db *sql.DB, logger *logger.Logger) error {
+	ctx, cancel := context.WithTimeout(context.Background(), migrationTimeout)
+	defer cancel()
+
+	m, err := NewMigrator(db, logger)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.Up(ctx)
+
+	return err
+}
@@ -0,0 +1,24 @@
+package pg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage/migrate"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/schema"
+)
+
+func TestMigrations(t *testing.T) {
+	files, err := schema.Migrations(migrationFiles)
+	require.NoError(t, err)
+
+	migrations, err := migrate.Load(files)
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, mg := range migrations {
+		assert.Equal(t, int64(i+1), mg.Version, "migration versions must be consecutive")
+	}
+}
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS short_urls (
+    id UUID UNIQUE,
+    user_id UUID,
+    short_url VARCHAR,
+    original_url VARCHAR,
+    is_deleted BOOLEAN DEFAULT false,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
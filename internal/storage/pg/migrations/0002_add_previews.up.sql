@@ -0,0 +1,4 @@
+ALTER TABLE short_urls
+    ADD COLUMN IF NOT EXISTS title VARCHAR,
+    ADD COLUMN IF NOT EXISTS description VARCHAR,
+    ADD COLUMN IF NOT EXISTS image_url VARCHAR;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+    id UUID PRIMARY KEY,
+    user_id UUID,
+    url VARCHAR,
+    secret VARCHAR,
+    events VARCHAR,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id UUID PRIMARY KEY,
+    webhook_id UUID REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_id UUID,
+    event_type VARCHAR,
+    attempt INT,
+    status_code INT,
+    error VARCHAR,
+    delivered BOOLEAN,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
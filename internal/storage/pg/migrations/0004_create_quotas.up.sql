@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS quotas (
+    user_id UUID PRIMARY KEY,
+    max_links INT,
+    max_batch_size INT,
+    max_url_length INT
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY,
+    user_id UUID,
+    name VARCHAR,
+    key_hash VARCHAR UNIQUE,
+    scopes VARCHAR,
+    expires_at TIMESTAMP,
+    revoked BOOLEAN DEFAULT false,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,5 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role VARCHAR DEFAULT 'user';
+
+ALTER TABLE short_urls
+    ADD COLUMN IF NOT EXISTS is_disabled BOOLEAN DEFAULT false;
@@ -0,0 +1,18 @@
+ALTER TABLE short_urls
+    ADD COLUMN IF NOT EXISTS workspace_id UUID;
+
+CREATE TABLE IF NOT EXISTS workspaces (
+    id UUID PRIMARY KEY,
+    name VARCHAR,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS workspace_members (
+    workspace_id UUID REFERENCES workspaces (id) ON DELETE CASCADE,
+    user_id UUID,
+    can_view BOOLEAN DEFAULT false,
+    can_edit BOOLEAN DEFAULT false,
+    can_delete BOOLEAN DEFAULT false,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (workspace_id, user_id)
+);
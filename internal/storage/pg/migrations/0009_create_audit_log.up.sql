@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id UUID PRIMARY KEY,
+    actor_id VARCHAR,
+    action VARCHAR,
+    target VARCHAR,
+    transport VARCHAR,
+    client_ip VARCHAR,
+    request_id VARCHAR,
+    before_value VARCHAR,
+    after_value VARCHAR,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_created_at_idx ON audit_log (created_at);
+
+-- The audit log is append-only: updates and deletions are ignored.
+CREATE OR REPLACE RULE audit_log_no_update AS ON UPDATE TO audit_log DO INSTEAD NOTHING;
+CREATE OR REPLACE RULE audit_log_no_delete AS ON DELETE TO audit_log DO INSTEAD NOTHING;
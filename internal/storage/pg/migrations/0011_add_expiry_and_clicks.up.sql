@@ -0,0 +1,6 @@
+ALTER TABLE short_urls
+    ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS clicks BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS click_threshold BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS short_urls_expires_at_idx ON short_urls (expires_at) WHERE is_deleted = false;
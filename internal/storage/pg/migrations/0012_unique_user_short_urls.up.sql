@@ -0,0 +1,14 @@
+-- A user has at most one record of a short URL. Earlier versions could store duplicates
+-- under concurrent requests; the first record that isn't deleted is kept in short_urls,
+-- and the others are moved to short_urls_duplicates for review rather than deleted.
+CREATE TABLE IF NOT EXISTS short_urls_duplicates (LIKE short_urls INCLUDING DEFAULTS);
+INSERT INTO short_urls_duplicates
+SELECT * FROM short_urls WHERE id IN (
+    SELECT id FROM (
+        SELECT id, ROW_NUMBER() OVER (PARTITION BY user_id, short_url ORDER BY is_deleted, created_at, id) AS n
+        FROM short_urls
+    ) ranked
+    WHERE n > 1
+);
+DELETE FROM short_urls WHERE id IN (SELECT id FROM short_urls_duplicates);
+CREATE UNIQUE INDEX IF NOT EXISTS short_urls_user_id_short_url_idx ON short_urls (user_id, short_url);
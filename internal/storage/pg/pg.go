@@ -8,6 +8,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"go.uber.org/zap"
 
 	"github.com/PrahaTurbo/url-shortener/internal/logger"
@@ -15,10 +17,8 @@ import (
 	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
 )
 
-// ErrURLDeleted is thrown when the URL being accessed has been deleted.
-var ErrURLDeleted = errors.New("url was deleted")
-
-// SQLStorage is a struct that implements the storage.Repository interface, using Postgresql as a storage backend.
+// SQLStorage is a struct that implements the storage.Repository interface, using a SQL database as a storage backend.
+// Its queries are portable between the PostgreSQL and SQLite schemas, and are bounded by the context of the caller.
 type SQLStorage struct {
 	db     *sql.DB
 	logger *logger.Logger
@@ -35,42 +35,31 @@ func NewSQLStorage(db *sql.DB, logger *logger.Logger) storage.Repository {
 }
 
 // SaveURL stores a new URL record in the SQL database.
+// It returns storage.ErrURLExists if the user already has a URL with the shortened URL.
 func (s *SQLStorage) SaveURL(ctx context.Context, url entity.URLRecord) error {
-	timeoutCtx, cancel := context.WithTimeout(ctx, time.Second*3)
-	defer cancel()
-
-	query := `
-		INSERT INTO short_urls (id, user_id, short_url, original_url)
-		VALUES ($1, $2, $3, $4)`
-
-	_, err := s.db.ExecContext(timeoutCtx, query, url.UUID, url.UserID, url.ShortURL, url.OriginalURL)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return s.SaveURLBatch(ctx, []*entity.URLRecord{&url})
 }
 
 // SaveURLBatch stores a batch of URL records in the SQL database in a single transaction.
+// It returns storage.ErrURLExists and stores none of the records if the user of a record already has
+// a live URL with its shortened URL, including the earlier records of the batch. A deleted or expired
+// record of the user is replaced.
 func (s *SQLStorage) SaveURLBatch(ctx context.Context, urls []*entity.URLRecord) error {
-	timeoutCtx, cancel := context.WithTimeout(ctx, time.Second*3)
-	defer cancel()
-
-	tx, err := s.db.Begin()
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer func() {
-		if err = tx.Rollback(); err != nil {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
 			s.logger.Error("failed to rollback queries", zap.Error(err))
 		}
 	}()
 
 	query := `
-		INSERT INTO short_urls (id, user_id, short_url, original_url)
-		VALUES ($1, $2, $3, $4)`
+		INSERT INTO short_urls (id, user_id, short_url, original_url, expires_at, click_threshold)
+		VALUES ($1, $2, $3, $4, $5, $6)`
 
-	stmt, err := tx.PrepareContext(timeoutCtx, query)
+	stmt, err := tx.PrepareContext(ctx, query)
 	if err != nil {
 		return err
 	}
@@ -80,130 +69,391 @@ func (s *SQLStorage) SaveURLBatch(ctx context.Context, urls []*entity.URLRecord)
 		}
 	}()
 
+	now := time.Now().UTC()
 	for _, url := range urls {
-		_, err := stmt.ExecContext(timeoutCtx, url.UUID, url.UserID, url.ShortURL, url.OriginalURL)
-		if err != nil {
+		if err := removeGone(ctx, tx, url.ShortURL, url.UserID, now); err != nil {
+			return err
+		}
+
+		if err := checkNotExists(ctx, tx, url.ShortURL, url.UserID); err != nil {
 			return err
 		}
+
+		_, err := stmt.ExecContext(ctx, url.UUID, url.UserID, url.ShortURL, url.OriginalURL, utc(url.ExpiresAt), url.ClickThreshold)
+		if err != nil {
+			return exists(err)
+		}
 	}
 
 	return tx.Commit()
 }
 
-// GetURL retrieves the original URL from the SQL database given its shortened version,
-// unless it has been deleted.
+// GetURL retrieves the original URL of the first record with the shortened version that is neither deleted
+// nor expired from the SQL database, unless all of them have been deleted or have expired, or the URL has been disabled.
 func (s *SQLStorage) GetURL(ctx context.Context, shortURL string) (string, error) {
-	timeoutCtx, cancel := context.WithTimeout(ctx, time.Second*3)
-	defer cancel()
-
 	query := `
-		SELECT original_url, is_deleted
+		SELECT original_url, ` + goneColumn + `, COALESCE(is_disabled, false)
 		FROM short_urls
-		WHERE short_url = $1`
+		WHERE short_url = $1
+		ORDER BY 2, created_at
+		LIMIT 1`
 
-	row := s.db.QueryRowContext(timeoutCtx, query, shortURL)
+	row := s.db.QueryRowContext(ctx, query, shortURL, time.Now().UTC())
 
 	var originalURL string
-	var isDeleted bool
-	if err := row.Scan(&originalURL, &isDeleted); err != nil {
-		return "", err
+	var isGone, isDisabled bool
+	if err := row.Scan(&originalURL, &isGone, &isDisabled); err != nil {
+		return "", notFound(err, shortURL)
 	}
 
 	if err := row.Err(); err != nil {
 		return "", err
 	}
 
-	if isDeleted {
-		return "", ErrURLDeleted
+	if isGone {
+		return "", storage.ErrURLDeleted
+	}
+
+	if isDisabled {
+		return "", storage.ErrURLDisabled
 	}
 
 	return originalURL, nil
 }
 
-// GetURLsByUserID retrieves all the URL records of a specific user from the SQL database.
+// GetURLRecord retrieves the first URL record with the shortened version that is neither deleted nor expired,
+// including its preview, from the SQL database, unless there is no such record or the URL has been disabled.
+func (s *SQLStorage) GetURLRecord(ctx context.Context, shortURL string) (*entity.URLRecord, error) {
+	query := `
+		SELECT ` + returningColumns + `
+		FROM short_urls
+		WHERE short_url = $1
+		ORDER BY ` + goneColumn + `, created_at
+		LIMIT 1`
+
+	now := time.Now()
+	row := s.db.QueryRowContext(ctx, query, shortURL, now.UTC())
+
+	r, err := scanRecord(row)
+	if err != nil {
+		return nil, notFound(err, shortURL)
+	}
+
+	if r.DeletedFlag || r.Expired(now) {
+		return nil, storage.ErrURLDeleted
+	}
+
+	if r.Disabled {
+		return nil, storage.ErrURLDisabled
+	}
+
+	return r, nil
+}
+
+// GetURLsByUserID retrieves all the URL records of a specific user from the SQL database,
+// including the records of the workspaces the user can view.
 func (s *SQLStorage) GetURLsByUserID(ctx context.Context, userID string) ([]entity.URLRecord, error) {
-	timeoutCtx, cancel := context.WithTimeout(ctx, time.Second*3)
-	defer cancel()
+	return s.getURLs(ctx, userID, `workspace_id IN (
+			SELECT workspace_id FROM workspace_members WHERE user_id = $1 AND can_view)`, userID)
+}
+
+// GetURLsInWorkspaces retrieves all the URL records of a specific user from the SQL database,
+// followed by the records of the workspaces with the IDs.
+func (s *SQLStorage) GetURLsInWorkspaces(ctx context.Context, userID string, workspaceIDs []string) ([]entity.URLRecord, error) {
+	args := []interface{}{userID}
+	for _, id := range workspaceIDs {
+		args = append(args, id)
+	}
 
+	return s.getURLs(ctx, userID, inWorkspaces(2, len(workspaceIDs)), args...)
+}
+
+// getURLs retrieves the URL records of the user and the ones matching the workspace condition, the own
+// records of the user first. The user is the first argument of the query.
+func (s *SQLStorage) getURLs(ctx context.Context, userID, inWorkspace string, args ...interface{}) ([]entity.URLRecord, error) {
 	query := `
-		SELECT id, user_id, short_url, original_url 
+		SELECT ` + returningColumns + `
 		FROM short_urls
-		WHERE user_id = $1`
+		WHERE user_id = $1 OR ` + inWorkspace + `
+		ORDER BY user_id <> $1, created_at`
 
-	rows, err := s.db.QueryContext(timeoutCtx, query, userID)
+	records, err := s.queryRecords(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		if err := rows.Close(); err != nil {
-			s.logger.Error("failed to close rows", zap.Error(err))
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: no short urls for id %s", storage.ErrURLNotFound, userID)
+	}
+
+	return records, nil
+}
+
+// CountURLsByUserID counts the URLs of a specific user that are not deleted in the SQL database.
+func (s *SQLStorage) CountURLsByUserID(ctx context.Context, userID string) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM short_urls
+		WHERE user_id = $1 AND is_deleted = false`
+
+	row := s.db.QueryRowContext(ctx, query, userID)
+
+	var count int
+	if err := row.Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// CheckExistence checks if a live shortened URL associated with a user exists in the SQL database.
+// Deleted and expired records don't count, since saving the URL again replaces them.
+func (s *SQLStorage) CheckExistence(ctx context.Context, shortURL, userID string) error {
+	query := `
+		SELECT id 
+		FROM short_urls
+		WHERE user_id = $1 AND NOT ` + goneColumn + ` AND short_url = $3`
+
+	row := s.db.QueryRowContext(ctx, query, userID, time.Now().UTC(), shortURL)
+
+	var id string
+	if err := row.Scan(&id); err != nil {
+		return notFound(err, shortURL)
+	}
+
+	if err := row.Err(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// existenceChunkSize limits the shortened URLs GetExistingURLs looks up with a single query, so that
+// its bound parameters stay well below the limits of Postgresql and SQLite.
+const existenceChunkSize = 1000
+
+// GetExistingURLs returns the shortened URLs among the given ones the user has live records of in the SQL database.
+// Deleted and expired records don't count. It looks them up with a query per thousand URLs.
+func (s *SQLStorage) GetExistingURLs(ctx context.Context, shortURLs []string, userID string) ([]string, error) {
+	now := time.Now().UTC()
+
+	var existing []string
+	for start := 0; start < len(shortURLs); start += existenceChunkSize {
+		end := start + existenceChunkSize
+		if end > len(shortURLs) {
+			end = len(shortURLs)
 		}
-	}()
 
-	var records []entity.URLRecord
-	for rows.Next() {
-		var r entity.URLRecord
-		if err := rows.Scan(&r.UUID, &r.UserID, &r.ShortURL, &r.OriginalURL); err != nil {
+		args := make([]interface{}, 0, end-start+2)
+		args = append(args, userID, now)
+		for _, shortURL := range shortURLs[start:end] {
+			args = append(args, shortURL)
+		}
+
+		query := `
+		SELECT DISTINCT short_url
+		FROM short_urls
+		WHERE user_id = $1 AND NOT ` + goneColumn + ` AND short_url IN (` + placeholders(3, end-start) + `)`
+
+		found, err := s.queryStrings(ctx, query, args...)
+		if err != nil {
 			return nil, err
 		}
 
-		records = append(records, r)
+		existing = append(existing, found...)
 	}
 
-	if err := rows.Err(); err != nil {
+	return existing, nil
+}
+
+// ReassignURLs moves the URLs of one user to another in the SQL database.
+// URLs with a shortened URL the other user already has stay with the original user.
+func (s *SQLStorage) ReassignURLs(ctx context.Context, fromUserID, toUserID string) error {
+	query := `
+		UPDATE short_urls
+		SET user_id = $2
+		WHERE user_id = $1 AND short_url NOT IN (
+			SELECT short_url FROM short_urls WHERE user_id = $2)`
+
+	if _, err := s.db.ExecContext(ctx, query, fromUserID, toUserID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ReassignURL moves the URL with the shortened URL from one user to another in the SQL database.
+// It returns storage.ErrURLNotFound if the first user has no such URL,
+// and storage.ErrURLExists if the other user already has one.
+func (s *SQLStorage) ReassignURL(ctx context.Context, shortURL, fromUserID, toUserID string) error {
+	if err := checkNotExists(ctx, s.db, shortURL, toUserID); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE short_urls
+		SET user_id = $3
+		WHERE short_url = $1 AND user_id = $2`
+
+	return s.execAffecting(ctx, query, shortURL, fromUserID, toUserID)
+}
+
+// SetURLDisabled disables or enables every record with the shortened URL in the SQL database
+// and returns the updated records. It returns storage.ErrURLNotFound if there is no such URL.
+func (s *SQLStorage) SetURLDisabled(ctx context.Context, shortURL string, disabled bool) ([]entity.URLRecord, error) {
+	query := `
+		UPDATE short_urls
+		SET is_disabled = $2
+		WHERE short_url = $1
+		RETURNING ` + returningColumns
+
+	records, err := s.queryRecords(ctx, query, shortURL, disabled)
+	if err != nil {
 		return nil, err
 	}
 
 	if len(records) == 0 {
-		return nil, fmt.Errorf("no short urls for id %s", userID)
+		return nil, storage.ErrURLNotFound
 	}
 
 	return records, nil
 }
 
-// CheckExistence checks if a shortened URL associated with a user exists in the SQL database.
-func (s *SQLStorage) CheckExistence(ctx context.Context, shortURL, userID string) error {
-	timeoutCtx, cancel := context.WithTimeout(ctx, time.Second*3)
-	defer cancel()
-
+// SetURLWorkspace moves the URL of the user with the shortened URL to the workspace in the SQL database,
+// or out of any workspace if the workspaceID is empty. It returns storage.ErrURLNotFound if the user has no such URL.
+func (s *SQLStorage) SetURLWorkspace(ctx context.Context, shortURL, userID, workspaceID string) error {
 	query := `
-		SELECT id 
-		FROM short_urls
-		WHERE user_id = $1 AND short_url = $2`
+		UPDATE short_urls
+		SET workspace_id = $3
+		WHERE short_url = $1 AND user_id = $2`
 
-	row := s.db.QueryRowContext(timeoutCtx, query, userID, shortURL)
+	workspace := sql.NullString{String: workspaceID, Valid: workspaceID != ""}
 
-	var id string
-	if err := row.Scan(&id); err != nil {
+	return s.execAffecting(ctx, query, shortURL, userID, workspace)
+}
+
+// execAffecting executes the query and returns storage.ErrURLNotFound if it affected no rows.
+func (s *SQLStorage) execAffecting(ctx context.Context, query string, args ...interface{}) error {
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
 		return err
 	}
 
-	if err := row.Err(); err != nil {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+// SavePreview stores the preview for every record with the given shortened URL in the SQL database.
+func (s *SQLStorage) SavePreview(ctx context.Context, shortURL string, preview entity.Preview) error {
+	query := `
+		UPDATE short_urls
+		SET title = $2, description = $3, image_url = $4
+		WHERE short_url = $1`
+
+	_, err := s.db.ExecContext(ctx, query, shortURL, preview.Title, preview.Description, preview.Image)
+	if err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// DeleteURLBatch marks a set of URLs associated with a user as deleted in SQL database
-// by setting 'is_deleted' field to true for matching URLs.
-func (s *SQLStorage) DeleteURLBatch(urls []string, user string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+// DeleteURLBatch marks a set of URLs associated with a user, or with the workspaces the user
+// is allowed to delete from, as deleted in SQL database by setting 'is_deleted' field to true for matching URLs.
+// It returns the records it marked.
+func (s *SQLStorage) DeleteURLBatch(urls []string, user string) ([]entity.URLRecord, error) {
+	return s.deleteURLs(urls, user, `workspace_id IN (
+			SELECT workspace_id FROM workspace_members WHERE user_id = $1 AND can_delete)`)
+}
+
+// DeleteURLBatchInWorkspaces marks a set of URLs associated with a user, or with the workspaces with the IDs,
+// as deleted in SQL database. It returns the records it marked.
+func (s *SQLStorage) DeleteURLBatchInWorkspaces(urls []string, user string, workspaceIDs []string) ([]entity.URLRecord, error) {
+	args := make([]interface{}, 0, len(workspaceIDs))
+	for _, id := range workspaceIDs {
+		args = append(args, id)
+	}
+
+	return s.deleteURLs(urls, user, inWorkspaces(2, len(workspaceIDs)), args...)
+}
+
+// deleteURLs marks the URLs of the user and the ones matching the workspace condition as deleted.
+// The user is the first argument of the query, followed by the workspaceArgs and the URLs.
+func (s *SQLStorage) deleteURLs(urls []string, user, inWorkspace string, workspaceArgs ...interface{}) ([]entity.URLRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultBatchTimeout)
 	defer cancel()
 
-	urlsString := "{" + strings.Join(urls, ",") + "}"
+	if len(urls) == 0 {
+		return nil, nil
+	}
+
+	args := make([]interface{}, 0, len(urls)+len(workspaceArgs)+1)
+	args = append(args, user)
+	args = append(args, workspaceArgs...)
+	first := len(args) + 1
+	for _, url := range urls {
+		args = append(args, url)
+	}
 
 	query := `
 		UPDATE short_urls 
 		SET is_deleted = true 
-		WHERE short_url = ANY($1::text[]) AND user_id = $2::uuid`
+		WHERE short_url IN (` + placeholders(first, len(urls)) + `) AND is_deleted = false AND (user_id = $1 OR ` + inWorkspace + `)
+		RETURNING ` + returningColumns
 
-	_, err := s.db.ExecContext(ctx, query, urlsString, user)
+	return s.queryRecords(ctx, query, args...)
+}
+
+// ExpireURLs marks the URLs that expired at or before the time as deleted in the SQL database
+// and returns the records it marked.
+func (s *SQLStorage) ExpireURLs(ctx context.Context, now time.Time) ([]entity.URLRecord, error) {
+	query := `
+		UPDATE short_urls
+		SET is_deleted = true
+		WHERE is_deleted = false AND expires_at <= $1
+		RETURNING ` + returningColumns
+
+	return s.queryRecords(ctx, query, now.UTC())
+}
+
+// AddClicks adds the clicks of every shortened URL to its records that are not deleted in the SQL database
+// in a single transaction, and returns the updated records.
+func (s *SQLStorage) AddClicks(ctx context.Context, clicks map[string]int64) ([]entity.URLRecord, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			s.logger.Error("failed to rollback queries", zap.Error(err))
+		}
+	}()
+
+	query := `
+		UPDATE short_urls
+		SET clicks = clicks + $2
+		WHERE short_url = $1 AND is_deleted = false
+		RETURNING ` + returningColumns
+
+	var updated []entity.URLRecord
+	for shortURL, n := range clicks {
+		records, err := s.queryRecordsIn(ctx, tx, query, shortURL, n)
+		if err != nil {
+			return nil, err
+		}
+
+		updated = append(updated, records...)
 	}
 
-	return nil
+	return updated, tx.Commit()
 }
 
 // Ping pings the database to check if it's alive.
@@ -213,15 +463,12 @@ func (s *SQLStorage) Ping() error {
 
 // GetStats retrieves statistical data about the URLs and users in the SQL database.
 func (s *SQLStorage) GetStats(ctx context.Context) (*entity.Stats, error) {
-	timeoutCtx, cancel := context.WithTimeout(ctx, time.Second*3)
-	defer cancel()
-
 	query := `
 		SELECT 
   (SELECT COUNT(DISTINCT user_id) FROM short_urls WHERE is_deleted = false) AS unique_users,
   (SELECT COUNT(short_url) FROM short_urls WHERE is_deleted = false) AS short_urls`
 
-	row := s.db.QueryRowContext(timeoutCtx, query)
+	row := s.db.QueryRowContext(ctx, query)
 
 	var stats entity.Stats
 	if err := row.Scan(&stats.Users, &stats.URLs); err != nil {
@@ -235,38 +482,314 @@ func (s *SQLStorage) GetStats(ctx context.Context) (*entity.Stats, error) {
 	return &stats, nil
 }
 
-// OpenDB opens a SQL database connection given a DSN string.
-func OpenDB(dsn string) (*sql.DB, error) {
-	db, err := sql.Open("pgx", dsn)
+// GetUserIDs returns the users with URLs that are not deleted in the SQL database.
+func (s *SQLStorage) GetUserIDs(ctx context.Context) ([]string, error) {
+	query := `
+		SELECT DISTINCT user_id
+		FROM short_urls
+		WHERE is_deleted = false`
+
+	return s.queryStrings(ctx, query)
+}
+
+// ScanURLs returns up to limit URL records, including the deleted ones, in the order of their UUIDs
+// after the afterUUID from the SQL database.
+func (s *SQLStorage) ScanURLs(ctx context.Context, afterUUID string, limit int) ([]entity.URLRecord, error) {
+	query := `SELECT ` + returningColumns + ` FROM short_urls ORDER BY id LIMIT $1`
+	args := []interface{}{limit}
+
+	if afterUUID != "" {
+		query = `SELECT ` + returningColumns + ` FROM short_urls WHERE id > $2 ORDER BY id LIMIT $1`
+		args = append(args, afterUUID)
+	}
+
+	return s.queryRecords(ctx, query, args...)
+}
+
+// ImportURLs stores the URL records as they are in the SQL database in a single transaction, replacing
+// the records with the same UUIDs. It returns storage.ErrURLExists and stores none of the records
+// if the user of a record has another record with its shortened URL.
+func (s *SQLStorage) ImportURLs(ctx context.Context, records []entity.URLRecord) error {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			s.logger.Error("failed to rollback queries", zap.Error(err))
+		}
+	}()
 
-	if err = db.Ping(); err != nil {
-		return nil, err
+	query := `
+		INSERT INTO short_urls (id, user_id, short_url, original_url, is_deleted, is_disabled, workspace_id, title, description, image_url,
+			expires_at, clicks, click_threshold)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (id) DO UPDATE
+		SET user_id = excluded.user_id, short_url = excluded.short_url, original_url = excluded.original_url,
+			is_deleted = excluded.is_deleted, is_disabled = excluded.is_disabled, workspace_id = excluded.workspace_id,
+			title = excluded.title, description = excluded.description, image_url = excluded.image_url,
+			expires_at = excluded.expires_at, clicks = excluded.clicks, click_threshold = excluded.click_threshold`
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return err
 	}
+	defer func() {
+		if err := stmt.Close(); err != nil {
+			s.logger.Error("failed to close statement", zap.Error(err))
+		}
+	}()
 
-	return db, nil
+	for _, r := range records {
+		if err := checkNotExistsOther(ctx, tx, r); err != nil {
+			return err
+		}
+
+		var p entity.Preview
+		if r.Preview != nil {
+			p = *r.Preview
+		}
+
+		workspace := sql.NullString{String: r.WorkspaceID, Valid: r.WorkspaceID != ""}
+
+		_, err := stmt.ExecContext(ctx, r.UUID, r.UserID, r.ShortURL, r.OriginalURL, r.DeletedFlag, r.Disabled,
+			workspace, p.Title, p.Description, p.Image, utc(r.ExpiresAt), r.Clicks, r.ClickThreshold)
+		if err != nil {
+			return exists(err)
+		}
+	}
+
+	return tx.Commit()
 }
 
-// CreateTable creates a 'short_urls' table in the SQL database if it doesn't exist.
-func CreateTable(db *sql.DB) error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
-	defer cancel()
+// RemoveURLs deletes the URL records with the UUIDs from the SQL database for good.
+func (s *SQLStorage) RemoveURLs(ctx context.Context, uuids []string) error {
+	if len(uuids) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, 0, len(uuids))
+	for _, uuid := range uuids {
+		args = append(args, uuid)
+	}
+
+	query := `DELETE FROM short_urls WHERE id IN (` + placeholders(1, len(uuids)) + `)`
+
+	_, err := s.db.ExecContext(ctx, query, args...)
+
+	return err
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
 
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// checkNotExists returns storage.ErrURLExists if the user has a URL with the shortened URL.
+func checkNotExists(ctx context.Context, db queryRower, shortURL, userID string) error {
 	query := `
-		CREATE TABLE IF NOT EXISTS short_urls (
-			id UUID UNIQUE,
-			user_id UUID,
-			short_url VARCHAR,
-  			original_url VARCHAR,
-  			is_deleted BOOLEAN DEFAULT false,
-  			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP)`
-
-	_, err := db.ExecContext(ctx, query)
-	if err != nil {
+		SELECT COUNT(*)
+		FROM short_urls
+		WHERE user_id = $1 AND short_url = $2`
+
+	var count int
+	if err := db.QueryRowContext(ctx, query, userID, shortURL).Scan(&count); err != nil {
 		return err
 	}
 
+	if count > 0 {
+		return fmt.Errorf("%w: %s", storage.ErrURLExists, shortURL)
+	}
+
 	return nil
 }
+
+// removeGone removes the deleted or expired record of the user with the shortened URL, so that a new record
+// can replace it.
+func removeGone(ctx context.Context, tx *sql.Tx, shortURL, userID string, now time.Time) error {
+	query := `
+		DELETE FROM short_urls
+		WHERE user_id = $1 AND ` + goneColumn + ` AND short_url = $3`
+
+	_, err := tx.ExecContext(ctx, query, userID, now, shortURL)
+
+	return err
+}
+
+// checkNotExistsOther returns storage.ErrURLExists if the user of the record has another record
+// with its shortened URL.
+func checkNotExistsOther(ctx context.Context, db queryRower, r entity.URLRecord) error {
+	query := `
+		SELECT COUNT(*)
+		FROM short_urls
+		WHERE user_id = $1 AND short_url = $2 AND id <> $3`
+
+	var count int
+	if err := db.QueryRowContext(ctx, query, r.UserID, r.ShortURL, r.UUID).Scan(&count); err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return fmt.Errorf("%w: %s", storage.ErrURLExists, r.ShortURL)
+	}
+
+	return nil
+}
+
+// placeholders returns n comma-separated placeholders of bound parameters, numbered from the first.
+func placeholders(first, n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "$%d", first+i)
+	}
+
+	return b.String()
+}
+
+// inWorkspaces returns the condition matching the records of the n workspaces whose IDs are the arguments
+// starting at first. It matches nothing if there are none.
+func inWorkspaces(first, n int) string {
+	if n == 0 {
+		return "false"
+	}
+
+	return "workspace_id IN (" + placeholders(first, n) + ")"
+}
+
+// uniqueViolation is the SQLSTATE Postgresql reports an insert that violates a unique index with.
+const uniqueViolation = "23505"
+
+// exists replaces the error of a concurrent insert of a record the user already has with storage.ErrURLExists.
+func exists(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return fmt.Errorf("%w: %s", storage.ErrURLExists, pgErr.Detail)
+	}
+
+	return err
+}
+
+// notFound replaces the error of a query that returned no rows with storage.ErrURLNotFound.
+func notFound(err error, shortURL string) error {
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: %s", storage.ErrURLNotFound, shortURL)
+	}
+
+	return err
+}
+
+// utc returns the time in UTC, since the timestamps are stored without their time zone.
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	u := t.UTC()
+
+	return &u
+}
+
+// goneColumn is true for the records that are deleted or expired at the time of the second argument,
+// so that ordering by it resolves a shortened URL to a live record first.
+const goneColumn = `(is_deleted OR COALESCE(expires_at <= $2, false))`
+
+// returningColumns are the columns of the records returned by the queries and updates, in the order of scanRecord.
+const returningColumns = `id, user_id, short_url, original_url, is_deleted, COALESCE(is_disabled, false),
+			COALESCE(CAST(workspace_id AS TEXT), ''), COALESCE(title, ''), COALESCE(description, ''), COALESCE(image_url, ''),
+			expires_at, clicks, click_threshold`
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// queryRecords runs the query and scans the records it returns.
+func (s *SQLStorage) queryRecords(ctx context.Context, query string, args ...interface{}) ([]entity.URLRecord, error) {
+	return s.queryRecordsIn(ctx, s.db, query, args...)
+}
+
+// queryRecordsIn runs the query on the database or in the transaction and scans the records it returns.
+func (s *SQLStorage) queryRecordsIn(ctx context.Context, db querier, query string, args ...interface{}) ([]entity.URLRecord, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			s.logger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	var records []entity.URLRecord
+	for rows.Next() {
+		r, err := scanRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, *r)
+	}
+
+	return records, rows.Err()
+}
+
+// queryStrings runs the query and scans the single text column of the rows it returns.
+func (s *SQLStorage) queryStrings(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			s.logger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+	}
+
+	return values, rows.Err()
+}
+
+func scanRecord(row scanner) (*entity.URLRecord, error) {
+	var r entity.URLRecord
+	var p entity.Preview
+
+	err := row.Scan(&r.UUID, &r.UserID, &r.ShortURL, &r.OriginalURL, &r.DeletedFlag, &r.Disabled, &r.WorkspaceID, &p.Title, &p.Description, &p.Image,
+		&r.ExpiresAt, &r.Clicks, &r.ClickThreshold)
+	if err != nil {
+		return nil, err
+	}
+
+	if p != (entity.Preview{}) {
+		r.Preview = &p
+	}
+
+	return &r, nil
+}
+
+// OpenDB opens a SQL database connection given a DSN string.
+func OpenDB(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = db.Ping(); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
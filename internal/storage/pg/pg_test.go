@@ -0,0 +1,30 @@
+package pg
+
+import (
+	"path/filepath"
+	"testing"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/sqlite"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/storagetest"
+)
+
+// TestSQLStorage_conformance runs the suite against SQLStorage on an SQLite database standing in for
+// Postgresql, so that the SQL of the storage is checked without a database server.
+func TestSQLStorage_conformance(t *testing.T) {
+	log, _ := logger.Initialize("debug")
+
+	storagetest.Run(t, func(t *testing.T) storage.Repository {
+		db, err := sqlite.OpenDB(sqlite.Scheme + filepath.Join(t.TempDir(), "urls.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+
+		require.NoError(t, sqlite.Migrate(db, log))
+
+		return NewSQLStorage(db, log)
+	})
+}
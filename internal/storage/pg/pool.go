@@ -0,0 +1,691 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// DefaultBatchTimeout is the time a batch of URLs is allowed to be stored in, if the PoolConfig doesn't set it.
+const DefaultBatchTimeout = time.Minute
+
+// DefaultQueryTimeout is the time a single query is allowed to run for, if the PoolConfig doesn't set it.
+const DefaultQueryTimeout = 3 * time.Second
+
+// pingTimeout limits the time the database is given to answer a ping, which has no context of a caller.
+const pingTimeout = 3 * time.Second
+
+// PoolConfig holds the sizing of the connection pool and the timeouts of single queries and batch inserts.
+// Zero values keep the defaults of pgxpool, DefaultQueryTimeout and DefaultBatchTimeout.
+type PoolConfig struct {
+	MaxConns     int32
+	MinConns     int32
+	QueryTimeout time.Duration
+	BatchTimeout time.Duration
+}
+
+// PoolStorage is a struct that implements the storage.Repository interface, using a native pgx connection pool
+// to a Postgresql database. It stores batches of URLs with the COPY protocol. The queries are bounded
+// by the context of the caller, and by the query timeout, or the batch timeout for the batches, too.
+type PoolStorage struct {
+	pool         *pgxpool.Pool
+	queryTimeout time.Duration
+	batchTimeout time.Duration
+	logger       *logger.Logger
+}
+
+// NewPoolStorage initializes a new PoolStorage instance with provided inputs.
+func NewPoolStorage(pool *pgxpool.Pool, cfg PoolConfig, logger *logger.Logger) storage.Repository {
+	s := &PoolStorage{
+		pool:         pool,
+		queryTimeout: cfg.QueryTimeout,
+		batchTimeout: cfg.BatchTimeout,
+		logger:       logger,
+	}
+
+	if s.queryTimeout <= 0 {
+		s.queryTimeout = DefaultQueryTimeout
+	}
+
+	if s.batchTimeout <= 0 {
+		s.batchTimeout = DefaultBatchTimeout
+	}
+
+	return s
+}
+
+// OpenPool opens a pgx connection pool sized by the config given a DSN string, and pings the database.
+func OpenPool(dsn string, cfg PoolConfig) (*pgxpool.Pool, error) {
+	pool, err := NewPool(dsn, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return pool, nil
+}
+
+// NewPool creates a pgx connection pool sized by the config given a DSN string. Unlike OpenPool,
+// it doesn't connect to the database, so the pool of a database that is down is created as well.
+func NewPool(dsn string, cfg PoolConfig) (*pgxpool.Pool, error) {
+	poolCfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.MaxConns > 0 {
+		poolCfg.MaxConns = cfg.MaxConns
+	}
+
+	if cfg.MinConns > 0 {
+		poolCfg.MinConns = cfg.MinConns
+	}
+
+	if poolCfg.MinConns > poolCfg.MaxConns {
+		return nil, fmt.Errorf("min connections %d exceed max connections %d", poolCfg.MinConns, poolCfg.MaxConns)
+	}
+
+	return pgxpool.NewWithConfig(context.Background(), poolCfg)
+}
+
+// SaveURL stores a new URL record in the database, replacing a deleted or expired record of the user
+// with the shortened URL. It returns storage.ErrURLExists if the user already has a live URL with the shortened URL.
+func (s *PoolStorage) SaveURL(ctx context.Context, url entity.URLRecord) error {
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	remove := `
+		DELETE FROM short_urls
+		WHERE user_id = $1 AND ` + goneColumn + ` AND short_url = $3`
+
+	query := `
+		INSERT INTO short_urls (id, user_id, short_url, original_url, expires_at, click_threshold)
+		SELECT $1::uuid, $2::uuid, $3::varchar, $4::varchar, $5::timestamp, $6::bigint
+		WHERE NOT EXISTS (
+			SELECT 1 FROM short_urls WHERE user_id = $2::uuid AND short_url = $3::varchar)`
+
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, remove, url.UserID, time.Now().UTC(), url.ShortURL); err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(ctx, query, url.UUID, url.UserID, url.ShortURL, url.OriginalURL, utc(url.ExpiresAt), url.ClickThreshold)
+		if err != nil {
+			return exists(err)
+		}
+
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("%w: %s", storage.ErrURLExists, url.ShortURL)
+		}
+
+		return nil
+	})
+}
+
+// SaveURLBatch stores a batch of URL records in the database with a single COPY, so that either all or none
+// of them are stored. It is limited by the batch timeout rather than the timeout of single queries.
+// It returns storage.ErrURLExists if the user of a record already has a live URL with its shortened URL,
+// including the earlier records of the batch. The deleted and expired records of the users are replaced.
+func (s *PoolStorage) SaveURLBatch(ctx context.Context, urls []*entity.URLRecord) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, s.batchTimeout)
+	defer cancel()
+
+	rows := make([][]interface{}, 0, len(urls))
+	userIDs := make([]uuid.UUID, 0, len(urls))
+	shortURLs := make([]string, 0, len(urls))
+	seen := make(map[[2]string]struct{}, len(urls))
+	for _, url := range urls {
+		key := [2]string{url.UserID, url.ShortURL}
+		if _, ok := seen[key]; ok {
+			return fmt.Errorf("%w: %s", storage.ErrURLExists, url.ShortURL)
+		}
+		seen[key] = struct{}{}
+
+		id, err := uuid.Parse(url.UUID)
+		if err != nil {
+			return fmt.Errorf("invalid id of url %s: %w", url.ShortURL, err)
+		}
+
+		userID, err := uuid.Parse(url.UserID)
+		if err != nil {
+			return fmt.Errorf("invalid user id of url %s: %w", url.ShortURL, err)
+		}
+
+		rows = append(rows, []interface{}{id, userID, url.ShortURL, url.OriginalURL, utc(url.ExpiresAt), url.ClickThreshold})
+		userIDs = append(userIDs, userID)
+		shortURLs = append(shortURLs, url.ShortURL)
+	}
+
+	remove := `
+		DELETE FROM short_urls s
+		USING unnest($1::uuid[], $3::varchar[]) AS b(user_id, short_url)
+		WHERE s.user_id = b.user_id AND s.short_url = b.short_url AND ` + goneColumn
+
+	query := `
+		SELECT s.short_url
+		FROM short_urls s
+		JOIN unnest($1::uuid[], $2::varchar[]) AS b(user_id, short_url)
+			ON s.user_id = b.user_id AND s.short_url = b.short_url
+		LIMIT 1`
+
+	return pgx.BeginFunc(timeoutCtx, s.pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(timeoutCtx, remove, userIDs, time.Now().UTC(), shortURLs); err != nil {
+			return err
+		}
+
+		var existing string
+		err := tx.QueryRow(timeoutCtx, query, userIDs, shortURLs).Scan(&existing)
+		if err == nil {
+			return fmt.Errorf("%w: %s", storage.ErrURLExists, existing)
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+
+		_, err = tx.CopyFrom(
+			timeoutCtx,
+			pgx.Identifier{"short_urls"},
+			[]string{"id", "user_id", "short_url", "original_url", "expires_at", "click_threshold"},
+			pgx.CopyFromRows(rows),
+		)
+
+		return exists(err)
+	})
+}
+
+// GetURL retrieves the original URL of the first record with the shortened version that is neither deleted
+// nor expired from the database, unless all of them have been deleted or have expired, or the URL has been disabled.
+func (s *PoolStorage) GetURL(ctx context.Context, shortURL string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT original_url, ` + goneColumn + `, COALESCE(is_disabled, false)
+		FROM short_urls
+		WHERE short_url = $1
+		ORDER BY 2, created_at
+		LIMIT 1`
+
+	var originalURL string
+	var isGone, isDisabled bool
+	if err := s.pool.QueryRow(ctx, query, shortURL, time.Now().UTC()).Scan(&originalURL, &isGone, &isDisabled); err != nil {
+		return "", notFound(err, shortURL)
+	}
+
+	if isGone {
+		return "", storage.ErrURLDeleted
+	}
+
+	if isDisabled {
+		return "", storage.ErrURLDisabled
+	}
+
+	return originalURL, nil
+}
+
+// GetURLRecord retrieves the first URL record with the shortened version that is neither deleted nor expired,
+// including its preview, from the database, unless there is no such record or the URL has been disabled.
+func (s *PoolStorage) GetURLRecord(ctx context.Context, shortURL string) (*entity.URLRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT ` + returningColumns + `
+		FROM short_urls
+		WHERE short_url = $1
+		ORDER BY ` + goneColumn + `, created_at
+		LIMIT 1`
+
+	now := time.Now()
+	r, err := scanRecord(s.pool.QueryRow(ctx, query, shortURL, now.UTC()))
+	if err != nil {
+		return nil, notFound(err, shortURL)
+	}
+
+	if r.DeletedFlag || r.Expired(now) {
+		return nil, storage.ErrURLDeleted
+	}
+
+	if r.Disabled {
+		return nil, storage.ErrURLDisabled
+	}
+
+	return r, nil
+}
+
+// GetURLsByUserID retrieves all the URL records of a specific user from the database,
+// including the records of the workspaces the user can view.
+func (s *PoolStorage) GetURLsByUserID(ctx context.Context, userID string) ([]entity.URLRecord, error) {
+	return s.getURLs(ctx, userID, `workspace_id IN (
+			SELECT workspace_id FROM workspace_members WHERE user_id = $1 AND can_view)`, userID)
+}
+
+// GetURLsInWorkspaces retrieves all the URL records of a specific user from the database, followed by
+// the records of the workspaces with the IDs. The IDs are passed as a native uuid array.
+func (s *PoolStorage) GetURLsInWorkspaces(ctx context.Context, userID string, workspaceIDs []string) ([]entity.URLRecord, error) {
+	return s.getURLs(ctx, userID, `workspace_id = ANY($2::uuid[])`, userID, workspaceIDs)
+}
+
+// getURLs retrieves the URL records of the user and the ones matching the workspace condition, the own
+// records of the user first. The user is the first argument of the query.
+func (s *PoolStorage) getURLs(ctx context.Context, userID, inWorkspace string, args ...interface{}) ([]entity.URLRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT ` + returningColumns + `
+		FROM short_urls
+		WHERE user_id = $1 OR ` + inWorkspace + `
+		ORDER BY user_id <> $1, created_at`
+
+	records, err := s.queryRecords(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: no short urls for id %s", storage.ErrURLNotFound, userID)
+	}
+
+	return records, nil
+}
+
+// CountURLsByUserID counts the URLs of a specific user that are not deleted in the database.
+func (s *PoolStorage) CountURLsByUserID(ctx context.Context, userID string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT COUNT(*)
+		FROM short_urls
+		WHERE user_id = $1 AND is_deleted = false`
+
+	var count int
+	if err := s.pool.QueryRow(ctx, query, userID).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// CheckExistence checks if a live shortened URL associated with a user exists in the database.
+// Deleted and expired records don't count, since saving the URL again replaces them.
+func (s *PoolStorage) CheckExistence(ctx context.Context, shortURL, userID string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT id
+		FROM short_urls
+		WHERE user_id = $1 AND NOT ` + goneColumn + ` AND short_url = $3
+		LIMIT 1`
+
+	var id string
+	if err := s.pool.QueryRow(ctx, query, userID, time.Now().UTC(), shortURL).Scan(&id); err != nil {
+		return notFound(err, shortURL)
+	}
+
+	return nil
+}
+
+// GetExistingURLs returns the shortened URLs among the given ones the user has live records of in the database
+// with a single query. Deleted and expired records don't count. The URLs are passed as a native text array.
+func (s *PoolStorage) GetExistingURLs(ctx context.Context, shortURLs []string, userID string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT DISTINCT short_url
+		FROM short_urls
+		WHERE user_id = $1 AND NOT ` + goneColumn + ` AND short_url = ANY($3::varchar[])`
+
+	rows, err := s.pool.Query(ctx, query, userID, time.Now().UTC(), shortURLs)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// ReassignURLs moves the URLs of one user to another in the database.
+// URLs with a shortened URL the other user already has stay with the original user.
+func (s *PoolStorage) ReassignURLs(ctx context.Context, fromUserID, toUserID string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE short_urls
+		SET user_id = $2
+		WHERE user_id = $1 AND short_url NOT IN (
+			SELECT short_url FROM short_urls WHERE user_id = $2)`
+
+	_, err := s.pool.Exec(ctx, query, fromUserID, toUserID)
+
+	return err
+}
+
+// ReassignURL moves the URL with the shortened URL from one user to another in the database.
+// It returns storage.ErrURLNotFound if the first user has no such URL,
+// and storage.ErrURLExists if the other user already has one.
+func (s *PoolStorage) ReassignURL(ctx context.Context, shortURL, fromUserID, toUserID string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	if err := s.CheckExistence(ctx, shortURL, toUserID); err == nil {
+		return fmt.Errorf("%w: %s", storage.ErrURLExists, shortURL)
+	} else if !errors.Is(err, storage.ErrURLNotFound) {
+		return err
+	}
+
+	query := `
+		UPDATE short_urls
+		SET user_id = $3
+		WHERE short_url = $1 AND user_id = $2`
+
+	return s.execAffecting(ctx, query, shortURL, fromUserID, toUserID)
+}
+
+// SetURLDisabled disables or enables every record with the shortened URL in the database
+// and returns the updated records. It returns storage.ErrURLNotFound if there is no such URL.
+func (s *PoolStorage) SetURLDisabled(ctx context.Context, shortURL string, disabled bool) ([]entity.URLRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE short_urls
+		SET is_disabled = $2
+		WHERE short_url = $1
+		RETURNING ` + returningColumns
+
+	records, err := s.queryRecords(ctx, query, shortURL, disabled)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, storage.ErrURLNotFound
+	}
+
+	return records, nil
+}
+
+// SetURLWorkspace moves the URL of the user with the shortened URL to the workspace in the database,
+// or out of any workspace if the workspaceID is empty. It returns storage.ErrURLNotFound if the user has no such URL.
+func (s *PoolStorage) SetURLWorkspace(ctx context.Context, shortURL, userID, workspaceID string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE short_urls
+		SET workspace_id = NULLIF($3, '')::uuid
+		WHERE short_url = $1 AND user_id = $2`
+
+	return s.execAffecting(ctx, query, shortURL, userID, workspaceID)
+}
+
+// execAffecting executes the query and returns storage.ErrURLNotFound if it affected no rows.
+func (s *PoolStorage) execAffecting(ctx context.Context, query string, args ...interface{}) error {
+	tag, err := s.pool.Exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrURLNotFound
+	}
+
+	return nil
+}
+
+// queryRecords runs the query and scans the records it returns.
+func (s *PoolStorage) queryRecords(ctx context.Context, query string, args ...interface{}) ([]entity.URLRecord, error) {
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []entity.URLRecord
+	for rows.Next() {
+		r, err := scanRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, *r)
+	}
+
+	return records, rows.Err()
+}
+
+// SavePreview stores the preview for every record with the given shortened URL in the database.
+func (s *PoolStorage) SavePreview(ctx context.Context, shortURL string, preview entity.Preview) error {
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE short_urls
+		SET title = $2, description = $3, image_url = $4
+		WHERE short_url = $1`
+
+	_, err := s.pool.Exec(ctx, query, shortURL, preview.Title, preview.Description, preview.Image)
+
+	return err
+}
+
+// DeleteURLBatch marks a set of URLs associated with a user, or with the workspaces the user
+// is allowed to delete from, as deleted in the database. The URLs are passed as a native text array.
+// It returns the records it marked.
+func (s *PoolStorage) DeleteURLBatch(urls []string, user string) ([]entity.URLRecord, error) {
+	return s.deleteURLs(`workspace_id IN (
+			SELECT workspace_id FROM workspace_members WHERE user_id = $2::uuid AND can_delete)`, urls, user)
+}
+
+// DeleteURLBatchInWorkspaces marks a set of URLs associated with a user, or with the workspaces with the IDs,
+// as deleted in the database. The URLs and the IDs are passed as native arrays. It returns the records it marked.
+func (s *PoolStorage) DeleteURLBatchInWorkspaces(urls []string, user string, workspaceIDs []string) ([]entity.URLRecord, error) {
+	return s.deleteURLs(`workspace_id = ANY($3::uuid[])`, urls, user, workspaceIDs)
+}
+
+// deleteURLs marks the URLs of the user and the ones matching the workspace condition as deleted.
+// The URLs and the user are the first arguments of the query.
+func (s *PoolStorage) deleteURLs(inWorkspace string, args ...interface{}) ([]entity.URLRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.batchTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE short_urls
+		SET is_deleted = true
+		WHERE short_url = ANY($1) AND is_deleted = false AND (user_id = $2::uuid OR ` + inWorkspace + `)
+		RETURNING ` + returningColumns
+
+	return s.queryRecords(ctx, query, args...)
+}
+
+// ExpireURLs marks the URLs that expired at or before the time as deleted in the database
+// and returns the records it marked. Like a batch, it is limited by the batch timeout.
+func (s *PoolStorage) ExpireURLs(ctx context.Context, now time.Time) ([]entity.URLRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.batchTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE short_urls
+		SET is_deleted = true
+		WHERE is_deleted = false AND expires_at <= $1
+		RETURNING ` + returningColumns
+
+	return s.queryRecords(ctx, query, now.UTC())
+}
+
+// AddClicks adds the clicks of every shortened URL to its records that are not deleted in the database
+// with a single query, and returns the updated records. The URLs and the clicks are passed as native arrays.
+// Like a batch, it is limited by the batch timeout.
+func (s *PoolStorage) AddClicks(ctx context.Context, clicks map[string]int64) ([]entity.URLRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.batchTimeout)
+	defer cancel()
+
+	shortURLs := make([]string, 0, len(clicks))
+	counts := make([]int64, 0, len(clicks))
+	for shortURL, n := range clicks {
+		shortURLs = append(shortURLs, shortURL)
+		counts = append(counts, n)
+	}
+
+	query := `
+		UPDATE short_urls s
+		SET clicks = s.clicks + c.n
+		FROM unnest($1::varchar[], $2::bigint[]) AS c(code, n)
+		WHERE s.short_url = c.code AND s.is_deleted = false
+		RETURNING ` + returningColumns
+
+	return s.queryRecords(ctx, query, shortURLs, counts)
+}
+
+// Ping pings the database to check if it's alive.
+func (s *PoolStorage) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+
+	return s.pool.Ping(ctx)
+}
+
+// ReplicationLag returns how far the database lags behind its primary, if it's a replica. A replica
+// that has replayed everything it received doesn't lag, even if the primary had no writes for a while.
+// Other databases don't lag.
+func (s *PoolStorage) ReplicationLag(ctx context.Context) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT COALESCE(
+			CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+			ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()) END, 0)`
+
+	var seconds float64
+	if err := s.pool.QueryRow(ctx, query).Scan(&seconds); err != nil {
+		return 0, err
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// GetStats retrieves statistical data about the URLs and users in the database.
+func (s *PoolStorage) GetStats(ctx context.Context) (*entity.Stats, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT
+			(SELECT COUNT(DISTINCT user_id) FROM short_urls WHERE is_deleted = false) AS unique_users,
+			(SELECT COUNT(short_url) FROM short_urls WHERE is_deleted = false) AS short_urls`
+
+	var stats entity.Stats
+	if err := s.pool.QueryRow(ctx, query).Scan(&stats.Users, &stats.URLs); err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+// GetUserIDs returns the users with URLs that are not deleted in the database.
+func (s *PoolStorage) GetUserIDs(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, `SELECT DISTINCT user_id::text FROM short_urls WHERE is_deleted = false`)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// ScanURLs returns up to limit URL records, including the deleted ones, in the order of their UUIDs
+// after the afterUUID from the database.
+func (s *PoolStorage) ScanURLs(ctx context.Context, afterUUID string, limit int) ([]entity.URLRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.queryTimeout)
+	defer cancel()
+
+	query := `SELECT ` + returningColumns + ` FROM short_urls ORDER BY id LIMIT $1`
+	args := []interface{}{limit}
+
+	if afterUUID != "" {
+		query = `SELECT ` + returningColumns + ` FROM short_urls WHERE id > $2::uuid ORDER BY id LIMIT $1`
+		args = append(args, afterUUID)
+	}
+
+	return s.queryRecords(ctx, query, args...)
+}
+
+// ImportURLs stores the URL records as they are in the database in a single transaction, replacing
+// the records with the same UUIDs. It is limited by the batch timeout. It returns storage.ErrURLExists
+// and stores none of the records if the user of a record has another record with its shortened URL.
+func (s *PoolStorage) ImportURLs(ctx context.Context, records []entity.URLRecord) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, s.batchTimeout)
+	defer cancel()
+
+	query := `
+		INSERT INTO short_urls (id, user_id, short_url, original_url, is_deleted, is_disabled, workspace_id, title, description, image_url,
+			expires_at, clicks, click_threshold)
+		SELECT $1::uuid, $2::uuid, $3::varchar, $4::varchar, $5, $6, $7::uuid, $8, $9, $10, $11::timestamp, $12::bigint, $13::bigint
+		WHERE NOT EXISTS (
+			SELECT 1 FROM short_urls WHERE user_id = $2::uuid AND short_url = $3::varchar AND id <> $1::uuid)
+		ON CONFLICT (id) DO UPDATE
+		SET user_id = excluded.user_id, short_url = excluded.short_url, original_url = excluded.original_url,
+			is_deleted = excluded.is_deleted, is_disabled = excluded.is_disabled, workspace_id = excluded.workspace_id,
+			title = excluded.title, description = excluded.description, image_url = excluded.image_url,
+			expires_at = excluded.expires_at, clicks = excluded.clicks, click_threshold = excluded.click_threshold`
+
+	return pgx.BeginFunc(timeoutCtx, s.pool, func(tx pgx.Tx) error {
+		for _, r := range records {
+			var p entity.Preview
+			if r.Preview != nil {
+				p = *r.Preview
+			}
+
+			var workspace *string
+			if r.WorkspaceID != "" {
+				workspace = &r.WorkspaceID
+			}
+
+			tag, err := tx.Exec(timeoutCtx, query, r.UUID, r.UserID, r.ShortURL, r.OriginalURL, r.DeletedFlag, r.Disabled,
+				workspace, p.Title, p.Description, p.Image, utc(r.ExpiresAt), r.Clicks, r.ClickThreshold)
+			if err != nil {
+				return exists(err)
+			}
+
+			if tag.RowsAffected() == 0 {
+				return fmt.Errorf("%w: %s", storage.ErrURLExists, r.ShortURL)
+			}
+		}
+
+		return nil
+	})
+}
+
+// RemoveURLs deletes the URL records with the UUIDs from the database for good.
+// The UUIDs are passed as a native uuid array.
+func (s *PoolStorage) RemoveURLs(ctx context.Context, uuids []string) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, s.batchTimeout)
+	defer cancel()
+
+	_, err := s.pool.Exec(timeoutCtx, `DELETE FROM short_urls WHERE id = ANY($1::uuid[])`, uuids)
+
+	return err
+}
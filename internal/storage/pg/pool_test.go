@@ -0,0 +1,144 @@
+package pg
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// unreachableDSN points at a port nothing listens on, so that the tests fail rather than hang
+// if they reach the database.
+const unreachableDSN = "postgres://shortener@127.0.0.1:1/shortener?connect_timeout=1"
+
+func TestNewPool(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     PoolConfig
+		wantMax int32
+		wantMin int32
+		wantErr bool
+	}{
+		{
+			name:    "should size the pool by the config",
+			cfg:     PoolConfig{MaxConns: 8, MinConns: 2},
+			wantMax: 8,
+			wantMin: 2,
+		},
+		{
+			name:    "should keep the defaults of pgxpool for zero values",
+			cfg:     PoolConfig{},
+			wantMax: defaultMaxConns(),
+			wantMin: 0,
+		},
+		{
+			name:    "should reject more min connections than max connections",
+			cfg:     PoolConfig{MaxConns: 2, MinConns: 3},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool, err := NewPool(unreachableDSN, tt.cfg)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err, "the pool must be created without connecting")
+			t.Cleanup(pool.Close)
+
+			assert.Equal(t, tt.wantMax, pool.Config().MaxConns)
+			assert.Equal(t, tt.wantMin, pool.Config().MinConns)
+		})
+	}
+
+	_, err := NewPool("not a dsn", PoolConfig{})
+	assert.Error(t, err)
+}
+
+// defaultMaxConns is the size pgxpool gives a pool by default.
+func defaultMaxConns() int32 {
+	if n := int32(runtime.NumCPU()); n > 4 {
+		return n
+	}
+
+	return 4
+}
+
+func TestNewPoolStorage_batchTimeout(t *testing.T) {
+	log, _ := logger.Initialize("debug")
+
+	pool, err := NewPool(unreachableDSN, PoolConfig{})
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	s := NewPoolStorage(pool, PoolConfig{}, log).(*PoolStorage)
+	assert.Equal(t, DefaultBatchTimeout, s.batchTimeout)
+
+	s = NewPoolStorage(pool, PoolConfig{BatchTimeout: time.Second}, log).(*PoolStorage)
+	assert.Equal(t, time.Second, s.batchTimeout)
+}
+
+func TestNewPoolStorage_queryTimeout(t *testing.T) {
+	log, _ := logger.Initialize("debug")
+
+	pool, err := NewPool(unreachableDSN, PoolConfig{})
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	s := NewPoolStorage(pool, PoolConfig{}, log).(*PoolStorage)
+	assert.Equal(t, DefaultQueryTimeout, s.queryTimeout)
+
+	s = NewPoolStorage(pool, PoolConfig{QueryTimeout: time.Second}, log).(*PoolStorage)
+	assert.Equal(t, time.Second, s.queryTimeout)
+}
+
+// TestPoolStorage_SaveURLBatch_validation checks the batches rejected before they reach the database.
+func TestPoolStorage_SaveURLBatch_validation(t *testing.T) {
+	log, _ := logger.Initialize("debug")
+
+	pool, err := NewPool(unreachableDSN, PoolConfig{})
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	s := NewPoolStorage(pool, PoolConfig{BatchTimeout: time.Second}, log)
+
+	user := uuid.New().String()
+	record := func(shortURL, id, userID string) *entity.URLRecord {
+		return &entity.URLRecord{UUID: id, UserID: userID, ShortURL: shortURL, OriginalURL: "https://" + shortURL + ".example"}
+	}
+
+	t.Run("should reject a shortened URL repeated for the user", func(t *testing.T) {
+		err := s.SaveURLBatch(context.Background(), []*entity.URLRecord{
+			record("abc", uuid.New().String(), user),
+			record("abc", uuid.New().String(), user),
+		})
+		assert.ErrorIs(t, err, storage.ErrURLExists)
+	})
+
+	t.Run("should reject an invalid id", func(t *testing.T) {
+		err := s.SaveURLBatch(context.Background(), []*entity.URLRecord{record("abc", "1", user)})
+		assert.ErrorContains(t, err, "invalid id of url abc")
+	})
+
+	t.Run("should reject an invalid user id", func(t *testing.T) {
+		err := s.SaveURLBatch(context.Background(), []*entity.URLRecord{record("abc", uuid.New().String(), "alice")})
+		assert.ErrorContains(t, err, "invalid user id of url abc")
+	})
+
+	t.Run("should fail if the database is down", func(t *testing.T) {
+		err := s.SaveURLBatch(context.Background(), []*entity.URLRecord{record("abc", uuid.New().String(), user)})
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, storage.ErrURLExists)
+	})
+}
@@ -0,0 +1,63 @@
+//go:build postgres
+
+// The tests of this file need a Postgresql server, so they only run with the postgres build tag:
+//
+//	TEST_DATABASE_DSN=postgres://... go test -tags postgres ./internal/storage/pg/
+
+package pg
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/storagetest"
+)
+
+// testDSNEnv names the environment variable with the DSN of a Postgresql database the tests may wipe.
+const testDSNEnv = "TEST_DATABASE_DSN"
+
+// TestPostgres_conformance runs the suite against SQLStorage and PoolStorage on the Postgresql database
+// given by TEST_DATABASE_DSN. It fails rather than skips if it isn't set, so that a run with the tag
+// can't pass without a database.
+func TestPostgres_conformance(t *testing.T) {
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Fatalf("%s is not set", testDSNEnv)
+	}
+
+	log, _ := logger.Initialize("debug")
+
+	db, err := OpenDB(dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	require.NoError(t, Migrate(db, log))
+
+	pool, err := OpenPool(dsn, PoolConfig{})
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	truncate := func(t *testing.T) {
+		_, err := db.ExecContext(context.Background(), "TRUNCATE short_urls")
+		require.NoError(t, err)
+	}
+
+	t.Run("SQLStorage", func(t *testing.T) {
+		storagetest.Run(t, func(t *testing.T) storage.Repository {
+			truncate(t)
+			return NewSQLStorage(db, log)
+		})
+	})
+
+	t.Run("PoolStorage", func(t *testing.T) {
+		storagetest.Run(t, func(t *testing.T) storage.Repository {
+			truncate(t)
+			return NewPoolStorage(pool, PoolConfig{}, log)
+		})
+	})
+}
@@ -0,0 +1,77 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// QuotaStorage is a struct that implements the storage.QuotaRepository interface, using Postgresql as a storage backend.
+type QuotaStorage struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+// NewQuotaStorage initializes a new QuotaStorage instance with provided inputs.
+func NewQuotaStorage(db *sql.DB, logger *logger.Logger) storage.QuotaRepository {
+	return &QuotaStorage{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SaveQuota stores the quota of a user in the SQL database, replacing the previous one.
+func (s *QuotaStorage) SaveQuota(ctx context.Context, quota entity.Quota) error {
+	query := `
+		INSERT INTO quotas (user_id, max_links, max_batch_size, max_url_length)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE
+		SET max_links = EXCLUDED.max_links,
+			max_batch_size = EXCLUDED.max_batch_size,
+			max_url_length = EXCLUDED.max_url_length`
+
+	_, err := s.db.ExecContext(ctx, query, quota.UserID, quota.MaxLinks, quota.MaxBatchSize, quota.MaxURLLength)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetQuota retrieves the quota of a user from the SQL database.
+// It returns nil if the quota wasn't overridden for the user.
+func (s *QuotaStorage) GetQuota(ctx context.Context, userID string) (*entity.Quota, error) {
+	query := `
+		SELECT user_id, max_links, max_batch_size, max_url_length
+		FROM quotas
+		WHERE user_id = $1`
+
+	row := s.db.QueryRowContext(ctx, query, userID)
+
+	var q entity.Quota
+	err := row.Scan(&q.UserID, &q.MaxLinks, &q.MaxBatchSize, &q.MaxURLLength)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &q, nil
+}
+
+// DeleteQuota removes the quota of a user from the SQL database, so the default one applies again.
+func (s *QuotaStorage) DeleteQuota(ctx context.Context, userID string) error {
+	query := `DELETE FROM quotas WHERE user_id = $1`
+
+	if _, err := s.db.ExecContext(ctx, query, userID); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// RefreshTokenStorage is a struct that implements the storage.RefreshTokenRepository interface,
+// using Postgresql as a storage backend.
+type RefreshTokenStorage struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+// NewRefreshTokenStorage initializes a new RefreshTokenStorage instance with provided inputs.
+func NewRefreshTokenStorage(db *sql.DB, logger *logger.Logger) storage.RefreshTokenRepository {
+	return &RefreshTokenStorage{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SaveRefreshToken stores a new refresh token in the SQL database.
+func (s *RefreshTokenStorage) SaveRefreshToken(ctx context.Context, token entity.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (id, user_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := s.db.ExecContext(ctx, query, token.ID, token.UserID, token.Hash, token.ExpiresAt, token.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetRefreshTokenByHash retrieves the refresh token with the given hash from the SQL database.
+// It returns nil if there is no such token.
+func (s *RefreshTokenStorage) GetRefreshTokenByHash(ctx context.Context, hash string) (*entity.RefreshToken, error) {
+	query := `
+		SELECT id, user_id, token_hash, expires_at, revoked_at, rotated, created_at
+		FROM refresh_tokens
+		WHERE token_hash = $1`
+
+	row := s.db.QueryRowContext(ctx, query, hash)
+
+	var t entity.RefreshToken
+	var revokedAt sql.NullTime
+
+	err := row.Scan(&t.ID, &t.UserID, &t.Hash, &t.ExpiresAt, &revokedAt, &t.Rotated, &t.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if revokedAt.Valid {
+		t.RevokedAt = revokedAt.Time
+	}
+
+	return &t, nil
+}
+
+// RevokeRefreshToken revokes the refresh token in the SQL database, either because it was rotated
+// or because the user logged out.
+func (s *RefreshTokenStorage) RevokeRefreshToken(ctx context.Context, id string, rotated bool) error {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = $2, rotated = $3
+		WHERE id = $1 AND revoked_at IS NULL`
+
+	if _, err := s.db.ExecContext(ctx, query, id, time.Now().UTC(), rotated); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RevokeUserRefreshTokens revokes all the refresh tokens of a specific user in the SQL database.
+func (s *RefreshTokenStorage) RevokeUserRefreshTokens(ctx context.Context, userID string) error {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = $2
+		WHERE user_id = $1 AND revoked_at IS NULL`
+
+	if _, err := s.db.ExecContext(ctx, query, userID, time.Now().UTC()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteRefreshTokens removes the refresh tokens that expired or were revoked before the time from the SQL database.
+func (s *RefreshTokenStorage) DeleteRefreshTokens(ctx context.Context, before time.Time) (int, error) {
+	query := `
+		DELETE FROM refresh_tokens
+		WHERE expires_at < $1 OR revoked_at < $1`
+
+	res, err := s.db.ExecContext(ctx, query, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(deleted), nil
+}
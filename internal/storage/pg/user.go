@@ -0,0 +1,110 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// UserStorage is a struct that implements the storage.UserRepository interface, using Postgresql as a storage backend.
+type UserStorage struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+// NewUserStorage initializes a new UserStorage instance with provided inputs.
+func NewUserStorage(db *sql.DB, logger *logger.Logger) storage.UserRepository {
+	return &UserStorage{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SaveUser stores a new account in the SQL database.
+// It returns storage.ErrUsernameTaken if the username is already taken, even by a concurrent registration.
+func (s *UserStorage) SaveUser(ctx context.Context, user entity.User) error {
+	query := `
+		INSERT INTO users (id, username, password_hash, role, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (username) DO NOTHING`
+
+	res, err := s.db.ExecContext(ctx, query, user.ID, user.Username, user.PasswordHash, user.Role, user.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return storage.ErrUsernameTaken
+	}
+
+	return nil
+}
+
+// GetUserByID retrieves the account with the given ID from the SQL database.
+// It returns nil if there is no such account.
+func (s *UserStorage) GetUserByID(ctx context.Context, id string) (*entity.User, error) {
+	query := `
+		SELECT id, username, password_hash, COALESCE(role, ''), created_at
+		FROM users
+		WHERE id = $1`
+
+	return s.getUser(ctx, query, id)
+}
+
+// GetUserByUsername retrieves the account with the given username from the SQL database.
+// It returns nil if there is no such account.
+func (s *UserStorage) GetUserByUsername(ctx context.Context, username string) (*entity.User, error) {
+	query := `
+		SELECT id, username, password_hash, COALESCE(role, ''), created_at
+		FROM users
+		WHERE username = $1`
+
+	return s.getUser(ctx, query, username)
+}
+
+// SetUserRole changes the role of the account with the given ID in the SQL database.
+// It fails if there is no such account.
+func (s *UserStorage) SetUserRole(ctx context.Context, id, role string) error {
+	query := `UPDATE users SET role = $2 WHERE id = $1`
+
+	res, err := s.db.ExecContext(ctx, query, id, role)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return errors.New("user not found")
+	}
+
+	return nil
+}
+
+func (s *UserStorage) getUser(ctx context.Context, query string, arg string) (*entity.User, error) {
+	row := s.db.QueryRowContext(ctx, query, arg)
+
+	var u entity.User
+	err := row.Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Role, &u.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &u, nil
+}
@@ -0,0 +1,155 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// WebhookStorage is a struct that implements the storage.WebhookRepository interface, using Postgresql as a storage backend.
+type WebhookStorage struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+// NewWebhookStorage initializes a new WebhookStorage instance with provided inputs.
+func NewWebhookStorage(db *sql.DB, logger *logger.Logger) storage.WebhookRepository {
+	return &WebhookStorage{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SaveWebhook stores a new webhook in the SQL database.
+func (s *WebhookStorage) SaveWebhook(ctx context.Context, webhook entity.Webhook) error {
+	query := `
+		INSERT INTO webhooks (id, user_id, url, secret, events, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := s.db.ExecContext(ctx, query,
+		webhook.ID, webhook.UserID, webhook.URL, webhook.Secret, strings.Join(webhook.Events, ","), webhook.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetWebhooksByUserID retrieves all the webhooks registered by a specific user from the SQL database.
+func (s *WebhookStorage) GetWebhooksByUserID(ctx context.Context, userID string) ([]entity.Webhook, error) {
+	query := `
+		SELECT id, user_id, url, secret, events, created_at
+		FROM webhooks
+		WHERE user_id = $1
+		ORDER BY created_at`
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			s.logger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	var webhooks []entity.Webhook
+	for rows.Next() {
+		var w entity.Webhook
+		var events string
+		if err := rows.Scan(&w.ID, &w.UserID, &w.URL, &w.Secret, &events, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		w.Events = strings.Split(events, ",")
+		webhooks = append(webhooks, w)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// DeleteWebhook removes the webhook of a specific user together with its delivery log from the SQL database.
+func (s *WebhookStorage) DeleteWebhook(ctx context.Context, id, userID string) error {
+	query := `
+		DELETE FROM webhooks
+		WHERE id = $1 AND user_id = $2`
+
+	res, err := s.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return errors.New("webhook not found")
+	}
+
+	return nil
+}
+
+// SaveDelivery stores a delivery attempt in the SQL database.
+func (s *WebhookStorage) SaveDelivery(ctx context.Context, d entity.WebhookDelivery) error {
+	query := `
+		INSERT INTO webhook_deliveries (id, webhook_id, event_id, event_type, attempt, status_code, error, delivered, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	_, err := s.db.ExecContext(ctx, query,
+		d.ID, d.WebhookID, d.EventID, d.EventType, d.Attempt, d.StatusCode, d.Error, d.Delivered, d.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetDeliveries retrieves up to limit of the latest delivery attempts of a webhook from the SQL database, newest first.
+func (s *WebhookStorage) GetDeliveries(ctx context.Context, webhookID string, limit int) ([]entity.WebhookDelivery, error) {
+	query := `
+		SELECT id, webhook_id, event_id, event_type, attempt, status_code, error, delivered, created_at
+		FROM webhook_deliveries
+		WHERE webhook_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2`
+
+	rows, err := s.db.QueryContext(ctx, query, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			s.logger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	var deliveries []entity.WebhookDelivery
+	for rows.Next() {
+		var d entity.WebhookDelivery
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Attempt, &d.StatusCode, &d.Error, &d.Delivered, &d.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
@@ -0,0 +1,194 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"go.uber.org/zap"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// WorkspaceStorage is a struct that implements the storage.WorkspaceRepository interface, using Postgresql as a storage backend.
+type WorkspaceStorage struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+// NewWorkspaceStorage initializes a new WorkspaceStorage instance with provided inputs.
+func NewWorkspaceStorage(db *sql.DB, logger *logger.Logger) storage.WorkspaceRepository {
+	return &WorkspaceStorage{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SaveWorkspace stores a new workspace with its first member in the SQL database in a single transaction.
+func (s *WorkspaceStorage) SaveWorkspace(ctx context.Context, workspace entity.Workspace, owner entity.WorkspaceMember) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			s.logger.Error("failed to rollback queries", zap.Error(err))
+		}
+	}()
+
+	query := `
+		INSERT INTO workspaces (id, name, created_at)
+		VALUES ($1, $2, $3)`
+
+	if _, err := tx.ExecContext(ctx, query, workspace.ID, workspace.Name, workspace.CreatedAt); err != nil {
+		return err
+	}
+
+	if err := saveMember(ctx, tx, owner); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetWorkspace retrieves the workspace with the given ID from the SQL database.
+// It returns nil if there is no such workspace.
+func (s *WorkspaceStorage) GetWorkspace(ctx context.Context, id string) (*entity.Workspace, error) {
+	query := `
+		SELECT id, name, created_at
+		FROM workspaces
+		WHERE id = $1`
+
+	row := s.db.QueryRowContext(ctx, query, id)
+
+	var w entity.Workspace
+	err := row.Scan(&w.ID, &w.Name, &w.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &w, nil
+}
+
+// SaveMember adds the member to the workspace in the SQL database or replaces the permissions of the member.
+func (s *WorkspaceStorage) SaveMember(ctx context.Context, member entity.WorkspaceMember) error {
+	return saveMember(ctx, s.db, member)
+}
+
+// GetMember retrieves the membership of the user in the workspace from the SQL database.
+// It returns nil if the user is not a member.
+func (s *WorkspaceStorage) GetMember(ctx context.Context, workspaceID, userID string) (*entity.WorkspaceMember, error) {
+	query := `
+		SELECT workspace_id, user_id, can_view, can_edit, can_delete, created_at
+		FROM workspace_members
+		WHERE workspace_id = $1 AND user_id = $2`
+
+	row := s.db.QueryRowContext(ctx, query, workspaceID, userID)
+
+	m, err := scanMember(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// GetMembers retrieves the members of the workspace from the SQL database, oldest first.
+func (s *WorkspaceStorage) GetMembers(ctx context.Context, workspaceID string) ([]entity.WorkspaceMember, error) {
+	query := `
+		SELECT workspace_id, user_id, can_view, can_edit, can_delete, created_at
+		FROM workspace_members
+		WHERE workspace_id = $1
+		ORDER BY created_at`
+
+	return s.getMembers(ctx, query, workspaceID)
+}
+
+// GetMembershipsByUserID retrieves the memberships of the user in all the workspaces from the SQL database, oldest first.
+func (s *WorkspaceStorage) GetMembershipsByUserID(ctx context.Context, userID string) ([]entity.WorkspaceMember, error) {
+	query := `
+		SELECT workspace_id, user_id, can_view, can_edit, can_delete, created_at
+		FROM workspace_members
+		WHERE user_id = $1
+		ORDER BY created_at`
+
+	return s.getMembers(ctx, query, userID)
+}
+
+// DeleteMember removes the user from the workspace in the SQL database.
+func (s *WorkspaceStorage) DeleteMember(ctx context.Context, workspaceID, userID string) error {
+	query := `DELETE FROM workspace_members WHERE workspace_id = $1 AND user_id = $2`
+
+	if _, err := s.db.ExecContext(ctx, query, workspaceID, userID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *WorkspaceStorage) getMembers(ctx context.Context, query string, arg string) ([]entity.WorkspaceMember, error) {
+	rows, err := s.db.QueryContext(ctx, query, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			s.logger.Error("failed to close rows", zap.Error(err))
+		}
+	}()
+
+	var members []entity.WorkspaceMember
+	for rows.Next() {
+		m, err := scanMember(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		members = append(members, *m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func saveMember(ctx context.Context, db execer, m entity.WorkspaceMember) error {
+	query := `
+		INSERT INTO workspace_members (workspace_id, user_id, can_view, can_edit, can_delete, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (workspace_id, user_id) DO UPDATE
+		SET can_view = EXCLUDED.can_view,
+			can_edit = EXCLUDED.can_edit,
+			can_delete = EXCLUDED.can_delete`
+
+	_, err := db.ExecContext(ctx, query, m.WorkspaceID, m.UserID, m.CanView, m.CanEdit, m.CanDelete, m.CreatedAt)
+
+	return err
+}
+
+func scanMember(row scanner) (*entity.WorkspaceMember, error) {
+	var m entity.WorkspaceMember
+
+	err := row.Scan(&m.WorkspaceID, &m.UserID, &m.CanView, &m.CanEdit, &m.CanDelete, &m.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
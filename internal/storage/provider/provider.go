@@ -1,32 +1,550 @@
 package provider
 
 import (
-	"log"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/stdlib"
+	"go.etcd.io/bbolt"
 
 	"github.com/PrahaTurbo/url-shortener/internal/logger"
 	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/bolt"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/cache"
 	"github.com/PrahaTurbo/url-shortener/internal/storage/memory"
 	"github.com/PrahaTurbo/url-shortener/internal/storage/pg"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/replica"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/shard"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/sqlite"
+)
+
+// Storage backends of the URL repository.
+const (
+	BackendMemory   = "memory"
+	BackendPostgres = "postgres"
+	BackendBolt     = "bolt"
+	BackendSQLite   = "sqlite"
+)
+
+// Storage groups the repositories that share the same storage backend.
+// Close must be called on shutdown to flush the pending changes.
+type Storage struct {
+	URLs       storage.Repository
+	Webhooks   storage.WebhookRepository
+	Quotas     storage.QuotaRepository
+	APIKeys    storage.APIKeyRepository
+	Users      storage.UserRepository
+	Tokens     storage.RefreshTokenRepository
+	Workspaces storage.WorkspaceRepository
+	Audit      storage.AuditRepository
+
+	closers []io.Closer
+}
+
+// Close releases the resources of the repositories, e.g. syncs and closes their files.
+func (s *Storage) Close() error {
+	return closers(s.closers).Close()
+}
+
+// closers closes all of its closers in order, even if some of them fail.
+type closers []io.Closer
+
+func (c closers) Close() error {
+	var errs []string
+	for _, closer := range c {
+		if err := closer.Close(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("close storage: %s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// Config holds the parameters of the storage backends.
+type Config struct {
+	Backend       string            // The storage backend. Empty selects it by the DSN, and memory if there is none.
+	DSN           string            // The PostgreSQL data source name, or the sqlite:// path of a SQLite database.
+	FilePath      string            // The file the in-memory URL repository is persisted to, and the prefix of the files of the accounts, workspaces, webhooks, quotas, API keys and refresh tokens.
+	FileSync      memory.SyncPolicy // When the changes to the file are synced to the disk.
+	FileCompact   time.Duration     // The time between the compactions of the file. Zero disables compaction.
+	AuditFilePath string            // The file the in-memory audit log is appended to. Empty keeps the log in memory.
+	BoltFilePath  string            // The file of the bbolt database of the bolt backend.
+	Pool          pg.PoolConfig     // The sizing of the PostgreSQL connection pool and the timeout of batch inserts.
+	ReplicaDSNs   []string          // The PostgreSQL data source names of the read replicas of the DSN database.
+	ReplicaCheck  time.Duration     // The time between the health checks of the replicas. Zero uses the default.
+	ReplicaMaxLag time.Duration     // The replication lag past which a replica is unhealthy. Zero disables the check.
+	ShardDSNs     []string          // The data source names of the databases the URLs are sharded over besides the DSN one.
+	Cache         CacheConfig       // The redirect cache in front of the SQL URL repositories.
+}
+
+// CacheConfig holds the limits of the redirect cache. A zero Size disables the cache.
+type CacheConfig struct {
+	Size        int
+	TTL         time.Duration
+	NegativeTTL time.Duration
+}
+
+// NewStorage creates new storage repositories for the backend selected by the config.
+//
+// The memory backend returns in-memory repositories, the URL repository logs its changes to the file
+// at FilePath for persistence and the audit log is appended to the file at AuditFilePath.
+// With a FilePath, the accounts the URLs are merged into, the workspaces they are moved to, the webhooks
+// with their deliveries, the quotas overridden for users, the API keys and the refresh tokens are persisted
+// to the FilePath.users, FilePath.workspaces, FilePath.webhooks, FilePath.quotas, FilePath.keys
+// and FilePath.tokens files alongside. It fails if any of the files is corrupt,
+// rather than append to a file whose changes wouldn't be restored.
+//
+// The postgres backend opens a pgx connection pool to a PostgreSQL database shared by all repositories,
+// and returns a URL repository using the pool natively and SQL-based repositories for the rest.
+// The URL repository is wrapped with the redirect cache, if enabled.
+// It also applies the pending schema migrations to the PostgreSQL database.
+// With ReplicaDSNs, the URL repository reads the redirects, the URLs of users and the stats from the
+// healthy replicas, each with a pool sized like the one of the primary database. The reads may be stale
+// by up to ReplicaMaxLag, or by any lag if it's zero.
+// With ShardDSNs, the URLs are sharded over the DSN database and the ShardDSNs databases in this order,
+// each migrated and with a pool sized like the one of the DSN database. The workspaces and their members
+// are kept in the DSN database only, and the shards are given the workspaces of a user resolved from it.
+//
+// The sqlite backend opens the SQLite database at the path of the DSN and returns the same SQL-based
+// repositories as the postgres backend, wrapped with the redirect cache likewise.
+// It applies the pending schema migrations to the SQLite database, and shards the URLs likewise.
+//
+// The bolt backend stores the URLs, the accounts, the API keys, the refresh tokens, the quotas, the webhooks
+// and the workspaces in the embedded bbolt database at BoltFilePath, and keeps the audit log like the memory backend.
+func NewStorage(cfg Config, logger *logger.Logger) (*Storage, error) {
+	backend := cfg.Backend
+	if backend == "" {
+		switch {
+		case sqlite.IsDSN(cfg.DSN):
+			backend = BackendSQLite
+		case cfg.DSN != "":
+			backend = BackendPostgres
+		default:
+			backend = BackendMemory
+		}
+	}
+
+	if len(cfg.ReplicaDSNs) > 0 && backend != BackendPostgres {
+		return nil, fmt.Errorf("read replicas require the %s storage backend", BackendPostgres)
+	}
+
+	if len(cfg.ShardDSNs) > 0 {
+		if backend != BackendPostgres && backend != BackendSQLite {
+			return nil, fmt.Errorf("shards require the %s or %s storage backend", BackendPostgres, BackendSQLite)
+		}
+
+		if len(cfg.ReplicaDSNs) > 0 {
+			return nil, fmt.Errorf("read replicas can't be combined with shards")
+		}
+	}
+
+	switch backend {
+	case BackendMemory:
+		return newMemoryStorage(cfg, logger)
+	case BackendPostgres:
+		return newPostgresStorage(cfg, logger)
+	case BackendSQLite:
+		return newSQLiteStorage(cfg, logger)
+	case BackendBolt:
+		return newBoltStorage(cfg, logger)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}
+
+// Suffixes appended to the FilePath of the memory backend to get the files of the accounts, the workspaces,
+// the webhooks, the quota overrides, the API keys and the refresh tokens.
+const (
+	usersFileSuffix      = ".users"
+	workspacesFileSuffix = ".workspaces"
+	webhooksFileSuffix   = ".webhooks"
+	quotasFileSuffix     = ".quotas"
+	apiKeysFileSuffix    = ".keys"
+	tokensFileSuffix     = ".tokens"
 )
 
-// NewStorage creates a new storage repository based on provided parameters.
-// If a data source name (dsn) is not provided, it returns an in-memory storage that uses a file for persistence.
-// If a dsn is provided, it opens a connection to a PostgreSQL database and returns a SQL-based storage repository.
-// It also executes a method to create the necessary table in the PostgreSQL database if it does not already exist.
-func NewStorage(dsn string, filePath string, logger *logger.Logger) (storage.Repository, error) {
-	if dsn == "" {
-		return memory.NewInMemStorage(filePath, logger), nil
+func newMemoryStorage(cfg Config, logger *logger.Logger) (*Storage, error) {
+	s := newMemoryRepositories(cfg, logger)
+
+	if cfg.FilePath != "" {
+		users, err := memory.NewFileUserStorage(cfg.FilePath + usersFileSuffix)
+		if err != nil {
+			return nil, fmt.Errorf("restore accounts: %w", err)
+		}
+
+		workspaces, err := memory.NewFileWorkspaceStorage(cfg.FilePath + workspacesFileSuffix)
+		if err != nil {
+			return nil, fmt.Errorf("restore workspaces: %w", err)
+		}
+
+		webhooks, err := memory.NewFileWebhookStorage(cfg.FilePath + webhooksFileSuffix)
+		if err != nil {
+			return nil, fmt.Errorf("restore webhooks: %w", err)
+		}
+
+		quotas, err := memory.NewFileQuotaStorage(cfg.FilePath + quotasFileSuffix)
+		if err != nil {
+			return nil, fmt.Errorf("restore quotas: %w", err)
+		}
+
+		apiKeys, err := memory.NewFileAPIKeyStorage(cfg.FilePath + apiKeysFileSuffix)
+		if err != nil {
+			return nil, fmt.Errorf("restore api keys: %w", err)
+		}
+
+		tokens, err := memory.NewFileRefreshTokenStorage(cfg.FilePath + tokensFileSuffix)
+		if err != nil {
+			return nil, fmt.Errorf("restore refresh tokens: %w", err)
+		}
+
+		s.Users = users
+		s.Workspaces = workspaces
+		s.Webhooks = webhooks
+		s.Quotas = quotas
+		s.APIKeys = apiKeys
+		s.Tokens = tokens
+	}
+
+	urls, err := memory.NewInMemStorage(cfg.FilePath, logger,
+		memory.WithWorkspaces(s.Workspaces),
+		memory.WithSyncPolicy(cfg.FileSync),
+		memory.WithCompactInterval(cfg.FileCompact),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	s.URLs = urls
+
+	if c, ok := s.URLs.(io.Closer); ok {
+		s.closers = append(s.closers, c)
+	}
+
+	return s, nil
+}
+
+// newMemoryRepositories returns the in-memory repositories of everything but the URLs.
+func newMemoryRepositories(cfg Config, logger *logger.Logger) *Storage {
+	return &Storage{
+		Webhooks:   memory.NewWebhookStorage(),
+		Quotas:     memory.NewQuotaStorage(),
+		APIKeys:    memory.NewAPIKeyStorage(),
+		Users:      memory.NewUserStorage(),
+		Tokens:     memory.NewRefreshTokenStorage(),
+		Workspaces: memory.NewWorkspaceStorage(),
+		Audit:      memory.NewAuditStorage(cfg.AuditFilePath, logger),
+	}
+}
+
+func newPostgresStorage(cfg Config, logger *logger.Logger) (*Storage, error) {
+	if cfg.DSN == "" {
+		return nil, fmt.Errorf("storage backend %s requires a database dsn", BackendPostgres)
+	}
+
+	pool, err := pg.OpenPool(cfg.DSN, cfg.Pool)
+	if err != nil {
+		return nil, err
+	}
+
+	db := stdlib.OpenDBFromPool(pool)
+
+	if err := pg.Migrate(db, logger); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	s := newSQLRepositories(db, logger)
+	s.closers = append(s.closers, closeFunc(pool.Close))
+
+	urls, err := s.withShards(pg.NewPoolStorage(pool, cfg.Pool, logger), cfg, logger)
+	if err != nil {
+		s.Close()
+		return nil, err
 	}
 
-	db, err := pg.OpenDB(dsn)
+	if len(cfg.ReplicaDSNs) > 0 {
+		replicas := make([]storage.Repository, 0, len(cfg.ReplicaDSNs))
+		for i, dsn := range cfg.ReplicaDSNs {
+			replicaPool, err := pg.NewPool(dsn, cfg.Pool)
+			if err != nil {
+				s.Close()
+				return nil, fmt.Errorf("replica %d: %w", i, err)
+			}
+
+			s.closers = append(s.closers, closeFunc(replicaPool.Close))
+			replicas = append(replicas, pg.NewPoolStorage(replicaPool, cfg.Pool, logger))
+		}
+
+		var opts []replica.Option
+		if cfg.ReplicaCheck > 0 {
+			opts = append(opts, replica.WithCheckInterval(cfg.ReplicaCheck))
+		}
+		if cfg.ReplicaMaxLag > 0 {
+			opts = append(opts, replica.WithMaxLag(cfg.ReplicaMaxLag))
+		}
+
+		urls = replica.NewRepository(urls, replicas, logger, opts...)
+
+		// The health checks stop before the pools of the replicas are closed.
+		s.closers = append([]io.Closer{urls.(io.Closer)}, s.closers...)
+	}
+
+	s.URLs = withCache(urls, cfg.Cache)
+
+	return s, nil
+}
+
+// closeFunc adapts a func without a result to io.Closer.
+type closeFunc func()
+
+func (f closeFunc) Close() error {
+	f()
+	return nil
+}
+
+func newSQLiteStorage(cfg Config, logger *logger.Logger) (*Storage, error) {
+	if !sqlite.IsDSN(cfg.DSN) {
+		return nil, fmt.Errorf("storage backend %s requires a %s dsn", BackendSQLite, sqlite.Scheme)
+	}
+
+	db, err := sqlite.OpenDB(cfg.DSN)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+
+	if err := sqlite.Migrate(db, logger); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	s := newSQLRepositories(db, logger)
+	s.closers = append(s.closers, db)
+
+	urls, err := s.withShards(pg.NewSQLStorage(db, logger), cfg, logger)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	s.URLs = withCache(urls, cfg.Cache)
+
+	return s, nil
+}
+
+// withShards spreads the URLs over the URL repository of the DSN database and the ones of the ShardDSNs
+// databases, if there are any, resolving the workspaces of the users through the workspace repository
+// of the storage, and registers the closers of the shards.
+func (s *Storage) withShards(urls storage.Repository, cfg Config, logger *logger.Logger) (storage.Repository, error) {
+	if len(cfg.ShardDSNs) == 0 {
+		return urls, nil
+	}
+
+	shards := []storage.Repository{urls}
+	for i, dsn := range cfg.ShardDSNs {
+		urls, closer, err := openURLs(dsn, cfg.Pool, logger)
+		if err != nil {
+			return nil, fmt.Errorf("shard %d: %w", i+1, err)
+		}
+
+		s.closers = append(s.closers, closer)
+		shards = append(shards, urls)
+	}
+
+	return shard.NewRepository(shards, shard.WithWorkspaces(s.Workspaces)), nil
+}
+
+// OpenShards opens the URL repositories of the shards of the config, the one of the DSN database followed by
+// the ones of the ShardDSNs databases, for the tools that work on every shard, e.g. to rebalance them.
+// It applies the pending schema migrations to the databases. The returned closer closes them.
+func OpenShards(cfg Config, logger *logger.Logger) ([]storage.Repository, io.Closer, error) {
+	var (
+		shards []storage.Repository
+		opened closers
+	)
+
+	for i, dsn := range append([]string{cfg.DSN}, cfg.ShardDSNs...) {
+		urls, closer, err := openURLs(dsn, cfg.Pool, logger)
+		if err != nil {
+			opened.Close()
+			return nil, nil, fmt.Errorf("shard %d: %w", i, err)
+		}
+
+		opened = append(opened, closer)
+		shards = append(shards, urls)
+	}
+
+	return shards, opened, nil
+}
+
+// Schemes of the locations of the URL repositories of the backends without a DSN.
+const (
+	FileScheme = "file://"
+	BoltScheme = "bolt://"
+)
+
+// OpenURLs opens the URL repository at the location, for the tools that work on the URLs of any backend,
+// e.g. to migrate them. A file:// location is the file of the memory backend, a bolt:// location the database
+// file of the bolt backend, and any other location a DSN of the postgres or sqlite backend, whose pending
+// schema migrations are applied. The returned closer closes the repository.
+func OpenURLs(location string, cfg Config, logger *logger.Logger) (storage.Repository, io.Closer, error) {
+	switch {
+	case strings.HasPrefix(location, FileScheme):
+		urls, err := memory.NewInMemStorage(strings.TrimPrefix(location, FileScheme), logger)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return urls, urls.(io.Closer), nil
+	case strings.HasPrefix(location, BoltScheme):
+		db, err := bolt.Open(strings.TrimPrefix(location, BoltScheme))
+		if err != nil {
+			return nil, nil, fmt.Errorf("open bolt database: %w", err)
+		}
+
+		urls, err := bolt.NewBoltStorage(db, logger)
+		if err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+
+		return urls, db, nil
+	default:
+		return openURLs(location, cfg.Pool, logger)
+	}
+}
+
+// OpenSourceURLs opens the URL repository at the location like OpenURLs, for the tools that only read its URLs,
+// e.g. to migrate them from it. The file of a file:// location must exist and is opened read-only: it's left
+// exactly as it is, and the repository fails to open if the file can't be restored entirely.
+func OpenSourceURLs(location string, cfg Config, logger *logger.Logger) (storage.Repository, io.Closer, error) {
+	if !strings.HasPrefix(location, FileScheme) {
+		return OpenURLs(location, cfg, logger)
+	}
+
+	urls, err := memory.NewInMemStorage(strings.TrimPrefix(location, FileScheme), logger, memory.WithReadOnly())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return urls, urls.(io.Closer), nil
+}
+
+// openURLs opens the URL repository of the database of the DSN, a PostgreSQL or a sqlite:// one,
+// and applies the pending schema migrations to it. The returned closer closes the database.
+func openURLs(dsn string, poolCfg pg.PoolConfig, logger *logger.Logger) (storage.Repository, io.Closer, error) {
+	if sqlite.IsDSN(dsn) {
+		db, err := sqlite.OpenDB(dsn)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if err := sqlite.Migrate(db, logger); err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+
+		return pg.NewSQLStorage(db, logger), db, nil
+	}
+
+	pool, err := pg.OpenPool(dsn, poolCfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	db := stdlib.OpenDBFromPool(pool)
+	defer db.Close()
+
+	if err := pg.Migrate(db, logger); err != nil {
+		pool.Close()
+		return nil, nil, err
+	}
+
+	return pg.NewPoolStorage(pool, poolCfg, logger), closeFunc(pool.Close), nil
+}
+
+// newSQLRepositories returns the SQL-based repositories of everything but the URLs.
+func newSQLRepositories(db *sql.DB, logger *logger.Logger) *Storage {
+	return &Storage{
+		Webhooks:   pg.NewWebhookStorage(db, logger),
+		Quotas:     pg.NewQuotaStorage(db, logger),
+		APIKeys:    pg.NewAPIKeyStorage(db, logger),
+		Users:      pg.NewUserStorage(db, logger),
+		Tokens:     pg.NewRefreshTokenStorage(db, logger),
+		Workspaces: pg.NewWorkspaceStorage(db, logger),
+		Audit:      pg.NewAuditStorage(db, logger),
+	}
+}
+
+// withCache wraps the URL repository with the redirect cache, unless it is disabled.
+func withCache(urls storage.Repository, cfg CacheConfig) storage.Repository {
+	if cfg.Size <= 0 {
+		return urls
+	}
+
+	return cache.NewRepository(urls,
+		cache.WithSize(cfg.Size),
+		cache.WithTTL(cfg.TTL),
+		cache.WithNegativeTTL(cfg.NegativeTTL),
+	)
+}
+
+func newBoltStorage(cfg Config, logger *logger.Logger) (*Storage, error) {
+	db, err := bolt.Open(cfg.BoltFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("open bolt database: %w", err)
+	}
+
+	s, err := newBoltRepositories(db, cfg, logger)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	s.closers = append(s.closers, db)
+
+	return s, nil
+}
+
+// newBoltRepositories returns the repositories stored in the bbolt database, all but the audit log,
+// which is kept like the one of the memory backend.
+func newBoltRepositories(db *bbolt.DB, cfg Config, logger *logger.Logger) (*Storage, error) {
+	s := &Storage{Audit: memory.NewAuditStorage(cfg.AuditFilePath, logger)}
+
+	var err error
+	if s.Webhooks, err = bolt.NewWebhookStorage(db); err != nil {
+		return nil, err
+	}
+
+	if s.Quotas, err = bolt.NewQuotaStorage(db); err != nil {
+		return nil, err
+	}
+
+	if s.APIKeys, err = bolt.NewAPIKeyStorage(db); err != nil {
+		return nil, err
+	}
+
+	if s.Users, err = bolt.NewUserStorage(db); err != nil {
+		return nil, err
+	}
+
+	if s.Tokens, err = bolt.NewRefreshTokenStorage(db); err != nil {
+		return nil, err
+	}
+
+	if s.Workspaces, err = bolt.NewWorkspaceStorage(db); err != nil {
+		return nil, err
 	}
 
-	store := pg.NewSQLStorage(db, logger)
-	if err := pg.CreateTable(db); err != nil {
+	if s.URLs, err = bolt.NewBoltStorage(db, logger, bolt.WithWorkspaces(s.Workspaces)); err != nil {
 		return nil, err
 	}
 
-	return store, nil
+	return s, nil
 }
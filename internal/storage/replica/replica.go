@@ -0,0 +1,264 @@
+// Package replica provides a URL repository that routes the reads of redirects and listings to read replicas.
+package replica
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// DefaultCheckInterval is the time between the health checks of the replicas.
+const DefaultCheckInterval = 5 * time.Second
+
+// Option is a function that configures the repository.
+type Option func(*repository)
+
+// WithCheckInterval sets the time between the health checks of the replicas.
+func WithCheckInterval(interval time.Duration) Option {
+	return func(r *repository) {
+		r.checkInterval = interval
+	}
+}
+
+// WithMaxLag marks the replicas that implement LagReporter unhealthy while they lag behind the primary
+// by more than maxLag, so that the reads they serve are at most about maxLag old. Zero disables the check.
+func WithMaxLag(maxLag time.Duration) Option {
+	return func(r *repository) {
+		r.maxLag = maxLag
+	}
+}
+
+// LagReporter is implemented by the replicas that can tell how far behind the primary they are.
+type LagReporter interface {
+	ReplicationLag(ctx context.Context) (time.Duration, error)
+}
+
+// repository is a storage.Repository that writes to the primary repository and reads the original URLs,
+// the URLs of users and the stats from healthy replicas in turn, so that the redirects don't compete
+// with the writes. Every other method, including CheckExistence, uses the primary, so it sees the writes
+// made through it at once.
+//
+// A replica is healthy while it answers its periodic pings and, with WithMaxLag, doesn't lag behind
+// the primary by more than the max lag. A read that fails on a replica for any reason but the outcomes
+// of storage.Repository marks it unhealthy until the next successful ping and is retried on the primary,
+// as are the reads when no replica is healthy. Reads that find no URL, or find it deleted or disabled,
+// on a replica are retried on the primary too, since the replica may not have caught up with the URL.
+//
+// The reads served by a replica may still be stale: a URL deleted or disabled on the primary is
+// redirected to, and a listing or the stats miss the latest changes, until the replica catches up.
+type repository struct {
+	storage.Repository
+
+	replicas      []storage.Repository
+	healthy       []atomic.Bool
+	next          atomic.Uint32
+	checkInterval time.Duration
+	maxLag        time.Duration
+
+	stop chan struct{}
+	done chan struct{}
+
+	logger *logger.Logger
+}
+
+// NewRepository returns a repository that writes to the primary and reads from the replicas.
+// The replicas are considered unhealthy until their first ping, which is sent at once.
+// The returned repository implements io.Closer, which must be called to stop the health checks.
+func NewRepository(primary storage.Repository, replicas []storage.Repository, logger *logger.Logger, opts ...Option) storage.Repository {
+	r := &repository{
+		Repository:    primary,
+		replicas:      replicas,
+		healthy:       make([]atomic.Bool, len(replicas)),
+		checkInterval: DefaultCheckInterval,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+		logger:        logger,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	go r.checkHealth()
+
+	return r
+}
+
+// GetURL retrieves the original URL from a healthy replica, or from the primary.
+func (r *repository) GetURL(ctx context.Context, shortURL string) (string, error) {
+	var originalURL string
+
+	err := r.read(ctx, func(repo storage.Repository) error {
+		var err error
+		originalURL, err = repo.GetURL(ctx, shortURL)
+
+		return err
+	})
+
+	return originalURL, err
+}
+
+// GetURLRecord retrieves the URL record from a healthy replica, or from the primary.
+func (r *repository) GetURLRecord(ctx context.Context, shortURL string) (*entity.URLRecord, error) {
+	var record *entity.URLRecord
+
+	err := r.read(ctx, func(repo storage.Repository) error {
+		var err error
+		record, err = repo.GetURLRecord(ctx, shortURL)
+
+		return err
+	})
+
+	return record, err
+}
+
+// GetURLsByUserID retrieves the URL records of the user from a healthy replica, or from the primary.
+func (r *repository) GetURLsByUserID(ctx context.Context, userID string) ([]entity.URLRecord, error) {
+	var records []entity.URLRecord
+
+	err := r.read(ctx, func(repo storage.Repository) error {
+		var err error
+		records, err = repo.GetURLsByUserID(ctx, userID)
+
+		return err
+	})
+
+	return records, err
+}
+
+// GetStats retrieves the stats from a healthy replica, or from the primary.
+func (r *repository) GetStats(ctx context.Context) (*entity.Stats, error) {
+	var stats *entity.Stats
+
+	err := r.read(ctx, func(repo storage.Repository) error {
+		var err error
+		stats, err = repo.GetStats(ctx)
+
+		return err
+	})
+
+	return stats, err
+}
+
+// Close stops the health checks of the replicas.
+func (r *repository) Close() error {
+	close(r.stop)
+	<-r.done
+
+	return nil
+}
+
+// read runs fn against the next healthy replica, and against the primary if there is none
+// or the replica can't answer.
+func (r *repository) read(ctx context.Context, fn func(repo storage.Repository) error) error {
+	i, ok := r.pick()
+	if !ok {
+		return fn(r.Repository)
+	}
+
+	err := fn(r.replicas[i])
+	switch {
+	case err == nil, ctx.Err() != nil:
+		return err
+	case errors.Is(err, storage.ErrURLNotFound), errors.Is(err, storage.ErrURLDeleted), errors.Is(err, storage.ErrURLDisabled):
+		return fn(r.Repository)
+	}
+
+	r.logger.Warn("replica read failed, failing over to primary", zap.Int("replica", i), zap.Error(err))
+	r.healthy[i].Store(false)
+
+	return fn(r.Repository)
+}
+
+// pick returns the index of the next healthy replica in turn.
+func (r *repository) pick() (int, bool) {
+	healthy := make([]int, 0, len(r.replicas))
+	for i := range r.replicas {
+		if r.healthy[i].Load() {
+			healthy = append(healthy, i)
+		}
+	}
+
+	if len(healthy) == 0 {
+		return 0, false
+	}
+
+	return healthy[r.next.Add(1)%uint32(len(healthy))], true
+}
+
+// checkHealth pings the replicas periodically until the repository is closed.
+func (r *repository) checkHealth() {
+	defer close(r.done)
+
+	r.ping()
+
+	t := time.NewTicker(r.checkInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-t.C:
+			r.ping()
+		}
+	}
+}
+
+// check pings the replica and, with a max lag, checks that it doesn't lag behind the primary by more.
+func (r *repository) check(replica storage.Repository) error {
+	if err := replica.Ping(); err != nil {
+		return err
+	}
+
+	lr, ok := replica.(LagReporter)
+	if r.maxLag <= 0 || !ok {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.checkInterval)
+	defer cancel()
+
+	lag, err := lr.ReplicationLag(ctx)
+	if err != nil {
+		return err
+	}
+
+	if lag > r.maxLag {
+		return fmt.Errorf("replication lag of %s exceeds %s", lag, r.maxLag)
+	}
+
+	return nil
+}
+
+// ping checks every replica at once and records whether it's healthy.
+func (r *repository) ping() {
+	var wg sync.WaitGroup
+
+	for i := range r.replicas {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			err := r.check(r.replicas[i])
+			if healthy := err == nil; r.healthy[i].Swap(healthy) != healthy {
+				if healthy {
+					r.logger.Info("replica is healthy", zap.Int("replica", i))
+				} else {
+					r.logger.Warn("replica is unhealthy", zap.Int("replica", i), zap.Error(err))
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+}
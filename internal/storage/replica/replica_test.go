@@ -0,0 +1,189 @@
+package replica
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/mocks"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// setupRepository returns a repository with the replicas whose pings succeed marked healthy.
+func setupRepository(t *testing.T, healthy ...bool) (*repository, *mocks.MockRepository, []*mocks.MockRepository) {
+	ctrl := gomock.NewController(t)
+	log, _ := logger.Initialize("debug")
+
+	primary := mocks.NewMockRepository(ctrl)
+
+	var replicas []*mocks.MockRepository
+	var repos []storage.Repository
+	for _, h := range healthy {
+		replica := mocks.NewMockRepository(ctrl)
+
+		var err error
+		if !h {
+			err = errors.New("connection refused")
+		}
+
+		replica.EXPECT().Ping().Return(err).MinTimes(1)
+
+		replicas = append(replicas, replica)
+		repos = append(repos, replica)
+	}
+
+	r := NewRepository(primary, repos, log, WithCheckInterval(time.Hour)).(*repository)
+	t.Cleanup(func() { r.Close() })
+
+	// Wait for the first health check.
+	require.Eventually(t, func() bool {
+		for i, h := range healthy {
+			if r.healthy[i].Load() != h {
+				return false
+			}
+		}
+
+		return true
+	}, time.Second, time.Millisecond)
+
+	return r, primary, replicas
+}
+
+func TestRepository_GetURL(t *testing.T) {
+	ctx := context.Background()
+	errConn := errors.New("connection reset")
+
+	tests := []struct {
+		name        string
+		prepare     func(primary, replica *mocks.MockRepository)
+		want        string
+		wantErr     error
+		wantHealthy bool
+	}{
+		{
+			name: "should read from replica",
+			prepare: func(primary, replica *mocks.MockRepository) {
+				replica.EXPECT().GetURL(gomock.Any(), "abc").Return("https://a.example.com", nil)
+			},
+			want:        "https://a.example.com",
+			wantHealthy: true,
+		},
+		{
+			name: "should retry deletion on primary",
+			prepare: func(primary, replica *mocks.MockRepository) {
+				replica.EXPECT().GetURL(gomock.Any(), "abc").Return("", storage.ErrURLDeleted)
+				primary.EXPECT().GetURL(gomock.Any(), "abc").Return("", storage.ErrURLDeleted)
+			},
+			wantErr:     storage.ErrURLDeleted,
+			wantHealthy: true,
+		},
+		{
+			name: "should retry stale disabling on primary",
+			prepare: func(primary, replica *mocks.MockRepository) {
+				replica.EXPECT().GetURL(gomock.Any(), "abc").Return("", storage.ErrURLDisabled)
+				primary.EXPECT().GetURL(gomock.Any(), "abc").Return("https://a.example.com", nil)
+			},
+			want:        "https://a.example.com",
+			wantHealthy: true,
+		},
+		{
+			name: "should retry unknown url on primary",
+			prepare: func(primary, replica *mocks.MockRepository) {
+				replica.EXPECT().GetURL(gomock.Any(), "abc").Return("", storage.ErrURLNotFound)
+				primary.EXPECT().GetURL(gomock.Any(), "abc").Return("https://a.example.com", nil)
+			},
+			want:        "https://a.example.com",
+			wantHealthy: true,
+		},
+		{
+			name: "should fail over to primary",
+			prepare: func(primary, replica *mocks.MockRepository) {
+				replica.EXPECT().GetURL(gomock.Any(), "abc").Return("", errConn)
+				primary.EXPECT().GetURL(gomock.Any(), "abc").Return("https://a.example.com", nil)
+			},
+			want:        "https://a.example.com",
+			wantHealthy: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, primary, replicas := setupRepository(t, true)
+			tt.prepare(primary, replicas[0])
+
+			got, err := r.GetURL(ctx, "abc")
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantHealthy, r.healthy[0].Load())
+		})
+	}
+}
+
+func TestRepository_routesReads(t *testing.T) {
+	ctx := context.Background()
+	r, primary, replicas := setupRepository(t, true, false, true)
+
+	// The healthy replicas take turns, and the unhealthy one is skipped.
+	replicas[0].EXPECT().GetStats(gomock.Any()).Return(&entity.Stats{}, nil).Times(2)
+	replicas[2].EXPECT().GetStats(gomock.Any()).Return(&entity.Stats{}, nil).Times(2)
+
+	for i := 0; i < 4; i++ {
+		_, err := r.GetStats(ctx)
+		require.NoError(t, err)
+	}
+
+	// The writes and the existence checks use the primary.
+	record := entity.URLRecord{UUID: "1", ShortURL: "abc", UserID: "alice"}
+	primary.EXPECT().SaveURL(gomock.Any(), record).Return(nil)
+	primary.EXPECT().CheckExistence(gomock.Any(), "abc", "alice").Return(nil)
+
+	require.NoError(t, r.SaveURL(ctx, record))
+	require.NoError(t, r.CheckExistence(ctx, "abc", "alice"))
+}
+
+func TestRepository_withoutHealthyReplicas(t *testing.T) {
+	ctx := context.Background()
+	r, primary, _ := setupRepository(t, false)
+
+	primary.EXPECT().GetURLsByUserID(gomock.Any(), "alice").Return([]entity.URLRecord{{ShortURL: "abc"}}, nil)
+
+	records, err := r.GetURLsByUserID(ctx, "alice")
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+}
+
+type laggingReplica struct {
+	*mocks.MockRepository
+	lag time.Duration
+}
+
+func (r laggingReplica) ReplicationLag(context.Context) (time.Duration, error) {
+	return r.lag, nil
+}
+
+func TestRepository_maxLag(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	log, _ := logger.Initialize("debug")
+
+	var repos []storage.Repository
+	for _, lag := range []time.Duration{time.Second, time.Minute} {
+		replica := mocks.NewMockRepository(ctrl)
+		replica.EXPECT().Ping().Return(nil).MinTimes(1)
+
+		repos = append(repos, laggingReplica{MockRepository: replica, lag: lag})
+	}
+
+	r := NewRepository(mocks.NewMockRepository(ctrl), repos, log,
+		WithCheckInterval(time.Hour), WithMaxLag(10*time.Second)).(*repository)
+	t.Cleanup(func() { r.Close() })
+
+	require.Eventually(t, func() bool { return r.healthy[0].Load() }, time.Second, time.Millisecond)
+	assert.False(t, r.healthy[1].Load())
+}
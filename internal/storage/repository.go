@@ -2,18 +2,152 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
 )
 
+// Error variables, used in the repositories. Every URL repository returns them, possibly wrapped,
+// so that callers can tell the outcomes apart with errors.Is regardless of the storage backend.
+var (
+	ErrURLNotFound = errors.New("url not found")
+	ErrURLDeleted  = errors.New("url was deleted")
+	ErrURLDisabled = errors.New("url was disabled")
+	ErrURLExists   = errors.New("url already exists")
+)
+
+// ErrUsernameTaken is returned by the user repositories when saving an account with the username of another one.
+var ErrUsernameTaken = errors.New("username is already taken")
+
 // Repository is an interface that defines operations to interact with the storage system.
+// The URLs of a user include the URLs of the workspaces the user can view, and the user can delete
+// the URLs of the workspaces the user is allowed to delete from.
+//
+// A user has at most one record of a shortened URL: saving another one returns ErrURLExists, unless the record
+// is deleted or expired, in which case the new one replaces it.
+// GetURL and GetURLRecord resolve a shortened URL to its first record that is neither deleted nor expired,
+// and return ErrURLDeleted if all of its records are deleted or expired, ErrURLDisabled if it's disabled
+// and ErrURLNotFound if there are no records. GetExistingURLs returns the shortened URLs among the given ones the user
+// has live records of, like CheckExistence does for a single one. GetURLsByUserID includes the deleted
+// records, flagged, and the expired ones, for the moderators, so that listings of the live URLs must skip them
+// with URLRecord.Gone. It returns ErrURLNotFound if the user has none. GetStats counts the records and users that aren't deleted.
+// SetURLDisabled returns the records it updated and DeleteURLBatch the records it marked as deleted,
+// so that callers can tell whom a change concerns without reading the records again.
+// ExpireURLs marks the records that expired at or before the time as deleted and returns them.
+// AddClicks adds the clicks of every shortened URL to its records that aren't deleted and returns the updated records.
 type Repository interface {
 	SaveURL(ctx context.Context, url entity.URLRecord) error
 	SaveURLBatch(ctx context.Context, urls []*entity.URLRecord) error
 	GetURL(ctx context.Context, shortURL string) (string, error)
+	GetURLRecord(ctx context.Context, shortURL string) (*entity.URLRecord, error)
 	GetURLsByUserID(ctx context.Context, userID string) ([]entity.URLRecord, error)
+	CountURLsByUserID(ctx context.Context, userID string) (int, error)
 	CheckExistence(ctx context.Context, shortURL, userID string) error
-	DeleteURLBatch(urls []string, user string) error
+	GetExistingURLs(ctx context.Context, shortURLs []string, userID string) ([]string, error)
+	ReassignURLs(ctx context.Context, fromUserID, toUserID string) error
+	ReassignURL(ctx context.Context, shortURL, fromUserID, toUserID string) error
+	SetURLDisabled(ctx context.Context, shortURL string, disabled bool) ([]entity.URLRecord, error)
+	SetURLWorkspace(ctx context.Context, shortURL, userID, workspaceID string) error
+	SavePreview(ctx context.Context, shortURL string, preview entity.Preview) error
+	DeleteURLBatch(urls []string, user string) ([]entity.URLRecord, error)
+	ExpireURLs(ctx context.Context, now time.Time) ([]entity.URLRecord, error)
+	AddClicks(ctx context.Context, clicks map[string]int64) ([]entity.URLRecord, error)
 	GetStats(ctx context.Context) (*entity.Stats, error)
 	Ping() error
 }
+
+// URLStore is implemented by the URL repositories whose records can be copied to another repository
+// as they are, with their UUIDs, owners, flags, workspaces and previews, e.g. to rebalance shards
+// or to migrate to another storage backend.
+type URLStore interface {
+	// ScanURLs returns up to limit records, including the deleted ones, in the order of their UUIDs,
+	// starting after the record with the afterUUID, or from the first record if it's empty.
+	ScanURLs(ctx context.Context, afterUUID string, limit int) ([]entity.URLRecord, error)
+	// ImportURLs stores the records as they are, replacing the records with the same UUIDs. It returns
+	// ErrURLExists if the user of a record has another record with its shortened URL.
+	ImportURLs(ctx context.Context, records []entity.URLRecord) error
+	// RemoveURLs removes the records with the UUIDs for good. Unknown UUIDs are ignored.
+	RemoveURLs(ctx context.Context, uuids []string) error
+}
+
+// UserLister is implemented by the URL repositories that list the users of their URLs, so that the stats
+// of several repositories count the users with URLs in more than one of them once.
+type UserLister interface {
+	// GetUserIDs returns the users with URLs that are not deleted.
+	GetUserIDs(ctx context.Context) ([]string, error)
+}
+
+// WorkspaceURLStore is implemented by the URL repositories that can be given the workspaces of a user instead
+// of resolving them from the memberships in their own database, so that a repository spreading the URLs over
+// several databases resolves the memberships once, from the workspace repository that holds them.
+type WorkspaceURLStore interface {
+	// GetURLsInWorkspaces retrieves the URL records of the user followed by the records of the workspaces
+	// with the IDs, like GetURLsByUserID.
+	GetURLsInWorkspaces(ctx context.Context, userID string, workspaceIDs []string) ([]entity.URLRecord, error)
+	// DeleteURLBatchInWorkspaces marks the URLs of the user and of the workspaces with the IDs as deleted,
+	// like DeleteURLBatch.
+	DeleteURLBatchInWorkspaces(urls []string, user string, workspaceIDs []string) ([]entity.URLRecord, error)
+}
+
+// WebhookRepository is an interface that defines operations to store webhooks and their delivery log.
+type WebhookRepository interface {
+	SaveWebhook(ctx context.Context, webhook entity.Webhook) error
+	GetWebhooksByUserID(ctx context.Context, userID string) ([]entity.Webhook, error)
+	DeleteWebhook(ctx context.Context, id, userID string) error
+	SaveDelivery(ctx context.Context, delivery entity.WebhookDelivery) error
+	GetDeliveries(ctx context.Context, webhookID string, limit int) ([]entity.WebhookDelivery, error)
+}
+
+// QuotaRepository is an interface that defines operations to store quotas overridden for specific users.
+type QuotaRepository interface {
+	SaveQuota(ctx context.Context, quota entity.Quota) error
+	GetQuota(ctx context.Context, userID string) (*entity.Quota, error)
+	DeleteQuota(ctx context.Context, userID string) error
+}
+
+// APIKeyRepository is an interface that defines operations to store the API keys of users.
+type APIKeyRepository interface {
+	SaveAPIKey(ctx context.Context, key entity.APIKey) error
+	GetAPIKeyByHash(ctx context.Context, hash string) (*entity.APIKey, error)
+	GetAPIKeysByUserID(ctx context.Context, userID string) ([]entity.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id, userID string) error
+}
+
+// UserRepository is an interface that defines operations to store registered accounts.
+type UserRepository interface {
+	SaveUser(ctx context.Context, user entity.User) error
+	GetUserByID(ctx context.Context, id string) (*entity.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*entity.User, error)
+	SetUserRole(ctx context.Context, id, role string) error
+}
+
+// RefreshTokenRepository is an interface that defines operations to store refresh tokens and revoke them.
+// DeleteRefreshTokens removes the tokens that expired or were revoked before the time and returns how many it removed.
+type RefreshTokenRepository interface {
+	SaveRefreshToken(ctx context.Context, token entity.RefreshToken) error
+	GetRefreshTokenByHash(ctx context.Context, hash string) (*entity.RefreshToken, error)
+	RevokeRefreshToken(ctx context.Context, id string, rotated bool) error
+	RevokeUserRefreshTokens(ctx context.Context, userID string) error
+	DeleteRefreshTokens(ctx context.Context, before time.Time) (int, error)
+}
+
+// AuditRepository is an interface that defines operations on the append-only log of the changes made to
+// the links, accounts and settings. Entries are never updated or removed.
+// SaveAuditEntries stores the entries at once, either all of them or none.
+// GetAuditEntries returns the entries matching the filter, newest first.
+type AuditRepository interface {
+	SaveAuditEntries(ctx context.Context, entries []entity.AuditEntry) error
+	GetAuditEntries(ctx context.Context, filter entity.AuditFilter) ([]entity.AuditEntry, error)
+}
+
+// WorkspaceRepository is an interface that defines operations to store workspaces and their members.
+type WorkspaceRepository interface {
+	SaveWorkspace(ctx context.Context, workspace entity.Workspace, owner entity.WorkspaceMember) error
+	GetWorkspace(ctx context.Context, id string) (*entity.Workspace, error)
+	SaveMember(ctx context.Context, member entity.WorkspaceMember) error
+	GetMember(ctx context.Context, workspaceID, userID string) (*entity.WorkspaceMember, error)
+	GetMembers(ctx context.Context, workspaceID string) ([]entity.WorkspaceMember, error)
+	GetMembershipsByUserID(ctx context.Context, userID string) ([]entity.WorkspaceMember, error)
+	DeleteMember(ctx context.Context, workspaceID, userID string) error
+}
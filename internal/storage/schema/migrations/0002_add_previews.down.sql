@@ -0,0 +1,3 @@
+ALTER TABLE short_urls DROP COLUMN title;
+ALTER TABLE short_urls DROP COLUMN description;
+ALTER TABLE short_urls DROP COLUMN image_url;
@@ -0,0 +1,3 @@
+ALTER TABLE short_urls DROP COLUMN is_disabled;
+
+ALTER TABLE users DROP COLUMN role;
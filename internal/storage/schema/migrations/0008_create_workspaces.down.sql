@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS workspace_members;
+DROP TABLE IF EXISTS workspaces;
+
+ALTER TABLE short_urls DROP COLUMN workspace_id;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS workspace_members_user_id_idx;
+DROP INDEX IF EXISTS short_urls_workspace_id_idx;
+DROP INDEX IF EXISTS short_urls_user_id_idx;
+DROP INDEX IF EXISTS short_urls_short_url_idx;
@@ -0,0 +1,4 @@
+CREATE INDEX IF NOT EXISTS short_urls_short_url_idx ON short_urls (short_url);
+CREATE INDEX IF NOT EXISTS short_urls_user_id_idx ON short_urls (user_id);
+CREATE INDEX IF NOT EXISTS short_urls_workspace_id_idx ON short_urls (workspace_id);
+CREATE INDEX IF NOT EXISTS workspace_members_user_id_idx ON workspace_members (user_id);
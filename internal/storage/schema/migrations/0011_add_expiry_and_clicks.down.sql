@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS short_urls_expires_at_idx;
+
+ALTER TABLE short_urls DROP COLUMN click_threshold;
+ALTER TABLE short_urls DROP COLUMN clicks;
+ALTER TABLE short_urls DROP COLUMN expires_at;
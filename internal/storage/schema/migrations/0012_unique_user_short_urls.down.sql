@@ -0,0 +1,4 @@
+-- The duplicates set aside by the up migration are restored.
+DROP INDEX IF EXISTS short_urls_user_id_short_url_idx;
+INSERT INTO short_urls SELECT * FROM short_urls_duplicates;
+DROP TABLE IF EXISTS short_urls_duplicates;
@@ -0,0 +1,29 @@
+// Package schema embeds the migrations of the SQL schema that are portable between the PostgreSQL
+// and SQLite databases. The migrations of each dialect overlay them with the scripts that aren't.
+package schema
+
+import (
+	"embed"
+	"io/fs"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage/migrate"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migrations returns the portable migration scripts overlaid with the scripts of a dialect,
+// which are in the migrations directory of dialectFiles.
+func Migrations(dialectFiles fs.FS) (fs.FS, error) {
+	shared, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	dialect, err := fs.Sub(dialectFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.Overlay(shared, dialect), nil
+}
@@ -0,0 +1,136 @@
+package shard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// DefaultBatchSize is the number of records Rebalance reads from a shard at once, if it isn't set.
+const DefaultBatchSize = 1000
+
+// RebalanceResult counts the records Rebalance went through.
+type RebalanceResult struct {
+	Scanned   int // The records read from the shards.
+	Moved     int // The records moved to the shards of their short codes.
+	Conflicts int // The records left in place, since the user already has the code on the shard of the code.
+}
+
+// Rebalance moves every record that is not stored on the shard the ring assigns its short code to,
+// e.g. after shards were appended, to that shard. The shards must implement storage.URLStore.
+//
+// The records are read from every shard in batches of batchSize records. The misplaced records of a batch
+// are imported to their shards before they are removed from the one they were read from, so a record
+// is never lost, and an interrupted rebalance is completed by running it again. A record whose user
+// created the same code on the new shard in the meantime is left in place and counted as a conflict.
+func Rebalance(ctx context.Context, shards []storage.Repository, batchSize int, logger *logger.Logger) (RebalanceResult, error) {
+	var result RebalanceResult
+
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	stores := make([]storage.URLStore, 0, len(shards))
+	for i, repo := range shards {
+		store, ok := repo.(storage.URLStore)
+		if !ok {
+			return result, fmt.Errorf("shard %d: %w", i, errNoURLStore)
+		}
+
+		stores = append(stores, store)
+	}
+
+	ring := newRing(len(shards))
+
+	for i, src := range stores {
+		var after string
+		for {
+			records, err := src.ScanURLs(ctx, after, batchSize)
+			if err != nil {
+				return result, fmt.Errorf("scan shard %d: %w", i, err)
+			}
+
+			if len(records) == 0 {
+				break
+			}
+
+			after = records[len(records)-1].UUID
+			result.Scanned += len(records)
+
+			moves := make(map[int][]entity.URLRecord)
+			for _, r := range records {
+				if j := ring.shard(r.ShortURL); j != i {
+					moves[j] = append(moves[j], r)
+				}
+			}
+
+			var moved []string
+			for j, batch := range moves {
+				imported, err := importRecords(ctx, stores[j], batch, logger)
+				if err != nil {
+					return result, fmt.Errorf("import to shard %d: %w", j, err)
+				}
+
+				result.Conflicts += len(batch) - len(imported)
+				moved = append(moved, imported...)
+			}
+
+			if len(moved) == 0 {
+				continue
+			}
+
+			if err := src.RemoveURLs(ctx, moved); err != nil {
+				return result, fmt.Errorf("remove from shard %d: %w", i, err)
+			}
+
+			result.Moved += len(moved)
+
+			logger.Info("moved records to their shards", zap.Int("shard", i), zap.Int("records", len(moved)))
+		}
+	}
+
+	return result, nil
+}
+
+// importRecords imports the records to the store and returns the UUIDs of the imported ones.
+// If the batch conflicts with the records of the store, the records are imported one by one
+// and the conflicting ones are skipped.
+func importRecords(ctx context.Context, store storage.URLStore, records []entity.URLRecord, logger *logger.Logger) ([]string, error) {
+	uuids := make([]string, 0, len(records))
+
+	err := store.ImportURLs(ctx, records)
+	switch {
+	case err == nil:
+		for _, r := range records {
+			uuids = append(uuids, r.UUID)
+		}
+
+		return uuids, nil
+	case !errors.Is(err, storage.ErrURLExists):
+		return nil, err
+	}
+
+	for _, r := range records {
+		err := store.ImportURLs(ctx, []entity.URLRecord{r})
+		if errors.Is(err, storage.ErrURLExists) {
+			logger.Warn("record conflicts with its shard, left in place",
+				zap.String("uuid", r.UUID), zap.String("short_url", r.ShortURL), zap.String("user_id", r.UserID))
+
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		uuids = append(uuids, r.UUID)
+	}
+
+	return uuids, nil
+}
@@ -0,0 +1,65 @@
+package shard
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"sort"
+	"strconv"
+)
+
+// VirtualNodes is the number of points of every shard on the ring. More points spread the codes
+// more evenly over the shards.
+const VirtualNodes = 160
+
+// ring is a consistent hash ring that assigns the short codes to the shards. The points of a shard
+// depend only on its index, so appending a shard moves only the codes it takes over from the others,
+// about 1/n of them, while reordering or removing shards moves most codes.
+type ring struct {
+	points []uint32
+	shards map[uint32]int
+}
+
+// newRing returns the ring of n shards.
+func newRing(n int) *ring {
+	r := &ring{
+		points: make([]uint32, 0, n*VirtualNodes),
+		shards: make(map[uint32]int, n*VirtualNodes),
+	}
+
+	for i := 0; i < n; i++ {
+		for v := 0; v < VirtualNodes; v++ {
+			point := hash("shard-" + strconv.Itoa(i) + "-" + strconv.Itoa(v))
+			if _, ok := r.shards[point]; ok {
+				continue
+			}
+
+			r.points = append(r.points, point)
+			r.shards[point] = i
+		}
+	}
+
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+
+	return r
+}
+
+// shard returns the index of the shard the code is assigned to, the one of the first point
+// on the ring at or after the hash of the code.
+func (r *ring) shard(code string) int {
+	h := hash(code)
+
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+
+	return r.shards[r.points[i]]
+}
+
+// hash maps the key to the ring. It uses MD5 for its even spread, not for security: the simpler hashes
+// cluster the points of similar keys, like the ones of the shards.
+func hash(key string) uint32 {
+	sum := md5.Sum([]byte(key))
+
+	return binary.BigEndian.Uint32(sum[:4])
+}
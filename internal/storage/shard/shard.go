@@ -0,0 +1,517 @@
+// Package shard provides a URL repository that spreads the records over several repositories by their short codes.
+package shard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+var (
+	errNoURLStore          = errors.New("repository doesn't implement storage.URLStore")
+	errNoUserLister        = errors.New("repository doesn't implement storage.UserLister")
+	errNoWorkspaceURLStore = errors.New("repository doesn't implement storage.WorkspaceURLStore")
+)
+
+// repository is a storage.Repository that stores every record on the shard the consistent hash ring
+// assigns its short code to, so that all the records of a code, of any user, are on the same shard.
+// The methods of a code go to its shard only, the ones of a user go to every shard at once and
+// their results are merged.
+//
+// The shards are independent, so a batch is stored on each shard entirely or not at all, but it can
+// be stored on some shards and not on others. The stats count every user once if all the shards implement
+// storage.UserLister, and once per shard with their URLs otherwise. The memberships of a user are resolved
+// once, through the workspace repository, and the shards are given the workspaces they allow, so they must
+// implement storage.WorkspaceURLStore. Without a workspace repository, the user has no workspace URLs.
+//
+// The repository implements storage.URLStore and storage.UserLister if all its shards do.
+type repository struct {
+	shards     []storage.Repository
+	ring       *ring
+	workspaces storage.WorkspaceRepository
+}
+
+// Option configures the repository.
+type Option func(r *repository)
+
+// WithWorkspaces resolves the URLs a user can view and delete through the memberships
+// of the user in the workspaces of the repo.
+func WithWorkspaces(repo storage.WorkspaceRepository) Option {
+	return func(r *repository) {
+		r.workspaces = repo
+	}
+}
+
+// NewRepository returns a repository that spreads the records over the shards. The order of the shards
+// decides which codes they store: new shards must be appended, and the records they take over moved
+// to them with Rebalance. Until then, the codes of the moved records aren't found.
+func NewRepository(shards []storage.Repository, opts ...Option) storage.Repository {
+	r := &repository{
+		shards: shards,
+		ring:   newRing(len(shards)),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// SaveURL stores a new URL record on the shard of its shortened URL.
+func (r *repository) SaveURL(ctx context.Context, url entity.URLRecord) error {
+	return r.shardOf(url.ShortURL).SaveURL(ctx, url)
+}
+
+// SaveURLBatch stores the URL records of the batch on their shards at once, in a batch per shard.
+func (r *repository) SaveURLBatch(ctx context.Context, urls []*entity.URLRecord) error {
+	batches := make([][]*entity.URLRecord, len(r.shards))
+	for _, url := range urls {
+		i := r.ring.shard(url.ShortURL)
+		batches[i] = append(batches[i], url)
+	}
+
+	return r.each(ctx, func(ctx context.Context, i int, repo storage.Repository) error {
+		if len(batches[i]) == 0 {
+			return nil
+		}
+
+		return repo.SaveURLBatch(ctx, batches[i])
+	})
+}
+
+// GetURL retrieves the original URL from the shard of the shortened URL.
+func (r *repository) GetURL(ctx context.Context, shortURL string) (string, error) {
+	return r.shardOf(shortURL).GetURL(ctx, shortURL)
+}
+
+// GetURLRecord retrieves the URL record from the shard of the shortened URL.
+func (r *repository) GetURLRecord(ctx context.Context, shortURL string) (*entity.URLRecord, error) {
+	return r.shardOf(shortURL).GetURLRecord(ctx, shortURL)
+}
+
+// GetURLsByUserID retrieves the URL records of the user from every shard, the own records of the user first.
+// It returns storage.ErrURLNotFound if no shard has any.
+func (r *repository) GetURLsByUserID(ctx context.Context, userID string) ([]entity.URLRecord, error) {
+	viewable, err := r.memberWorkspaces(ctx, userID, func(m entity.WorkspaceMember) bool { return m.CanView })
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([][]entity.URLRecord, len(r.shards))
+
+	err = r.eachWorkspaceStore(ctx, func(ctx context.Context, i int, store storage.WorkspaceURLStore) error {
+		records, err := store.GetURLsInWorkspaces(ctx, userID, viewable)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			return nil
+		}
+
+		results[i] = records
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var records []entity.URLRecord
+	for _, result := range results {
+		records = append(records, result...)
+	}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: no short urls for id %s", storage.ErrURLNotFound, userID)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].UserID == userID && records[j].UserID != userID
+	})
+
+	return records, nil
+}
+
+// CountURLsByUserID sums the URLs of the user that are not deleted on every shard.
+func (r *repository) CountURLsByUserID(ctx context.Context, userID string) (int, error) {
+	counts := make([]int, len(r.shards))
+
+	err := r.each(ctx, func(ctx context.Context, i int, repo storage.Repository) error {
+		var err error
+		counts[i], err = repo.CountURLsByUserID(ctx, userID)
+
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	for _, c := range counts {
+		count += c
+	}
+
+	return count, nil
+}
+
+// CheckExistence checks if the user has the shortened URL on its shard.
+func (r *repository) CheckExistence(ctx context.Context, shortURL, userID string) error {
+	return r.shardOf(shortURL).CheckExistence(ctx, shortURL, userID)
+}
+
+// GetExistingURLs looks up the shortened URLs the user has on their shards, with a lookup per shard.
+func (r *repository) GetExistingURLs(ctx context.Context, shortURLs []string, userID string) ([]string, error) {
+	batches := make([][]string, len(r.shards))
+	for _, shortURL := range shortURLs {
+		i := r.ring.shard(shortURL)
+		batches[i] = append(batches[i], shortURL)
+	}
+
+	results := make([][]string, len(r.shards))
+
+	err := r.each(ctx, func(ctx context.Context, i int, repo storage.Repository) error {
+		if len(batches[i]) == 0 {
+			return nil
+		}
+
+		var err error
+		results[i], err = repo.GetExistingURLs(ctx, batches[i], userID)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var existing []string
+	for _, result := range results {
+		existing = append(existing, result...)
+	}
+
+	return existing, nil
+}
+
+// ReassignURLs moves the URLs of one user to another on every shard.
+func (r *repository) ReassignURLs(ctx context.Context, fromUserID, toUserID string) error {
+	return r.each(ctx, func(ctx context.Context, _ int, repo storage.Repository) error {
+		return repo.ReassignURLs(ctx, fromUserID, toUserID)
+	})
+}
+
+// ReassignURL moves the URL with the shortened URL from one user to another on its shard.
+func (r *repository) ReassignURL(ctx context.Context, shortURL, fromUserID, toUserID string) error {
+	return r.shardOf(shortURL).ReassignURL(ctx, shortURL, fromUserID, toUserID)
+}
+
+// SetURLDisabled disables or enables the shortened URL on its shard.
+func (r *repository) SetURLDisabled(ctx context.Context, shortURL string, disabled bool) ([]entity.URLRecord, error) {
+	return r.shardOf(shortURL).SetURLDisabled(ctx, shortURL, disabled)
+}
+
+// SetURLWorkspace moves the URL of the user with the shortened URL to the workspace on its shard.
+func (r *repository) SetURLWorkspace(ctx context.Context, shortURL, userID, workspaceID string) error {
+	return r.shardOf(shortURL).SetURLWorkspace(ctx, shortURL, userID, workspaceID)
+}
+
+// SavePreview stores the preview of the shortened URL on its shard.
+func (r *repository) SavePreview(ctx context.Context, shortURL string, preview entity.Preview) error {
+	return r.shardOf(shortURL).SavePreview(ctx, shortURL, preview)
+}
+
+// DeleteURLBatch marks the URLs of the user as deleted on their shards at once, in a batch per shard,
+// and returns the records marked on every shard. On failure, the records marked on the other shards
+// are not returned.
+func (r *repository) DeleteURLBatch(urls []string, user string) ([]entity.URLRecord, error) {
+	deletable, err := r.memberWorkspaces(context.Background(), user, func(m entity.WorkspaceMember) bool { return m.CanDelete })
+	if err != nil {
+		return nil, err
+	}
+
+	batches := make([][]string, len(r.shards))
+	for _, url := range urls {
+		i := r.ring.shard(url)
+		batches[i] = append(batches[i], url)
+	}
+
+	results := make([][]entity.URLRecord, len(r.shards))
+
+	err = r.eachWorkspaceStore(context.Background(), func(_ context.Context, i int, store storage.WorkspaceURLStore) error {
+		if len(batches[i]) == 0 {
+			return nil
+		}
+
+		var err error
+		results[i], err = store.DeleteURLBatchInWorkspaces(batches[i], user, deletable)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var marked []entity.URLRecord
+	for _, result := range results {
+		marked = append(marked, result...)
+	}
+
+	return marked, nil
+}
+
+// ExpireURLs marks the expired URLs as deleted on every shard at once and returns the records marked
+// on every shard. On failure, the records marked on the other shards are not returned.
+func (r *repository) ExpireURLs(ctx context.Context, now time.Time) ([]entity.URLRecord, error) {
+	results := make([][]entity.URLRecord, len(r.shards))
+
+	err := r.each(ctx, func(ctx context.Context, i int, repo storage.Repository) error {
+		var err error
+		results[i], err = repo.ExpireURLs(ctx, now)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var marked []entity.URLRecord
+	for _, result := range results {
+		marked = append(marked, result...)
+	}
+
+	return marked, nil
+}
+
+// AddClicks adds the clicks of the URLs on their shards at once, in a batch per shard, and returns
+// the records updated on every shard. On failure, the records updated on the other shards are not returned.
+func (r *repository) AddClicks(ctx context.Context, clicks map[string]int64) ([]entity.URLRecord, error) {
+	batches := make([]map[string]int64, len(r.shards))
+	for shortURL, n := range clicks {
+		i := r.ring.shard(shortURL)
+		if batches[i] == nil {
+			batches[i] = make(map[string]int64)
+		}
+
+		batches[i][shortURL] = n
+	}
+
+	results := make([][]entity.URLRecord, len(r.shards))
+
+	err := r.each(ctx, func(ctx context.Context, i int, repo storage.Repository) error {
+		if len(batches[i]) == 0 {
+			return nil
+		}
+
+		var err error
+		results[i], err = repo.AddClicks(ctx, batches[i])
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var updated []entity.URLRecord
+	for _, result := range results {
+		updated = append(updated, result...)
+	}
+
+	return updated, nil
+}
+
+// Ping pings every shard and fails if any of them is down.
+func (r *repository) Ping() error {
+	return r.each(context.Background(), func(_ context.Context, _ int, repo storage.Repository) error {
+		return repo.Ping()
+	})
+}
+
+// GetStats sums the stats of every shard, counting the users once.
+func (r *repository) GetStats(ctx context.Context) (*entity.Stats, error) {
+	results := make([]*entity.Stats, len(r.shards))
+
+	err := r.each(ctx, func(ctx context.Context, i int, repo storage.Repository) error {
+		var err error
+		results[i], err = repo.GetStats(ctx)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var stats entity.Stats
+	for _, s := range results {
+		stats.URLs += s.URLs
+		stats.Users += s.Users
+	}
+
+	users, err := r.GetUserIDs(ctx)
+	switch {
+	case errors.Is(err, errNoUserLister):
+	case err != nil:
+		return nil, err
+	default:
+		stats.Users = len(users)
+	}
+
+	return &stats, nil
+}
+
+// GetUserIDs returns the users with URLs that are not deleted on any shard, each once.
+func (r *repository) GetUserIDs(ctx context.Context) ([]string, error) {
+	results := make([][]string, len(r.shards))
+
+	err := r.each(ctx, func(ctx context.Context, i int, repo storage.Repository) error {
+		lister, ok := repo.(storage.UserLister)
+		if !ok {
+			return errNoUserLister
+		}
+
+		var err error
+		results[i], err = lister.GetUserIDs(ctx)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+
+	var users []string
+	for _, result := range results {
+		for _, user := range result {
+			if !seen[user] {
+				seen[user] = true
+				users = append(users, user)
+			}
+		}
+	}
+
+	return users, nil
+}
+
+// ScanURLs returns up to limit records of every shard, including the deleted ones, in the order of their UUIDs
+// after the afterUUID.
+func (r *repository) ScanURLs(ctx context.Context, afterUUID string, limit int) ([]entity.URLRecord, error) {
+	results := make([][]entity.URLRecord, len(r.shards))
+
+	err := r.eachStore(ctx, func(ctx context.Context, i int, store storage.URLStore) error {
+		var err error
+		results[i], err = store.ScanURLs(ctx, afterUUID, limit)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var records []entity.URLRecord
+	for _, result := range results {
+		records = append(records, result...)
+	}
+
+	sort.Slice(records, func(i, j int) bool { return records[i].UUID < records[j].UUID })
+
+	if len(records) > limit {
+		records = records[:limit]
+	}
+
+	return records, nil
+}
+
+// ImportURLs stores the records as they are on their shards at once, in a batch per shard.
+func (r *repository) ImportURLs(ctx context.Context, records []entity.URLRecord) error {
+	batches := make([][]entity.URLRecord, len(r.shards))
+	for _, rec := range records {
+		i := r.ring.shard(rec.ShortURL)
+		batches[i] = append(batches[i], rec)
+	}
+
+	return r.eachStore(ctx, func(ctx context.Context, i int, store storage.URLStore) error {
+		if len(batches[i]) == 0 {
+			return nil
+		}
+
+		return store.ImportURLs(ctx, batches[i])
+	})
+}
+
+// RemoveURLs removes the records with the UUIDs from every shard.
+func (r *repository) RemoveURLs(ctx context.Context, uuids []string) error {
+	return r.eachStore(ctx, func(ctx context.Context, _ int, store storage.URLStore) error {
+		return store.RemoveURLs(ctx, uuids)
+	})
+}
+
+// memberWorkspaces returns the workspaces whose memberships of the user satisfy the allowed func.
+func (r *repository) memberWorkspaces(ctx context.Context, userID string, allowed func(entity.WorkspaceMember) bool) ([]string, error) {
+	if r.workspaces == nil {
+		return nil, nil
+	}
+
+	memberships, err := r.workspaces.GetMembershipsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, m := range memberships {
+		if allowed(m) {
+			ids = append(ids, m.WorkspaceID)
+		}
+	}
+
+	return ids, nil
+}
+
+// shardOf returns the shard of the shortened URL.
+func (r *repository) shardOf(shortURL string) storage.Repository {
+	return r.shards[r.ring.shard(shortURL)]
+}
+
+// each runs fn against every shard at once and returns the first error, annotated with the index of its shard.
+func (r *repository) each(ctx context.Context, fn func(ctx context.Context, i int, repo storage.Repository) error) error {
+	g, ctx := errgroup.WithContext(ctx)
+
+	for i, repo := range r.shards {
+		i, repo := i, repo
+		g.Go(func() error {
+			if err := fn(ctx, i, repo); err != nil {
+				return fmt.Errorf("shard %d: %w", i, err)
+			}
+
+			return nil
+		})
+	}
+
+	return g.Wait()
+}
+
+// eachStore runs fn against the storage.URLStore of every shard at once, like each.
+func (r *repository) eachStore(ctx context.Context, fn func(ctx context.Context, i int, store storage.URLStore) error) error {
+	return r.each(ctx, func(ctx context.Context, i int, repo storage.Repository) error {
+		store, ok := repo.(storage.URLStore)
+		if !ok {
+			return errNoURLStore
+		}
+
+		return fn(ctx, i, store)
+	})
+}
+
+// eachWorkspaceStore runs fn against the storage.WorkspaceURLStore of every shard at once, like each.
+func (r *repository) eachWorkspaceStore(ctx context.Context, fn func(ctx context.Context, i int, store storage.WorkspaceURLStore) error) error {
+	return r.each(ctx, func(ctx context.Context, i int, repo storage.Repository) error {
+		store, ok := repo.(storage.WorkspaceURLStore)
+		if !ok {
+			return errNoWorkspaceURLStore
+		}
+
+		return fn(ctx, i, store)
+	})
+}
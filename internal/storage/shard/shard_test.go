@@ -0,0 +1,202 @@
+package shard
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/memory"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/pg"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/sqlite"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/storagetest"
+)
+
+func newShards(t *testing.T, n int) []storage.Repository {
+	log, _ := logger.Initialize("debug")
+
+	shards := make([]storage.Repository, 0, n)
+	for i := 0; i < n; i++ {
+		urls, err := memory.NewInMemStorage("", log)
+		require.NoError(t, err)
+
+		shards = append(shards, urls)
+	}
+
+	return shards
+}
+
+func TestRepository_conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Repository {
+		return NewRepository(newShards(t, 3))
+	})
+}
+
+func TestRing_distribution(t *testing.T) {
+	r := newRing(4)
+
+	counts := make([]int, 4)
+	for i := 0; i < 10000; i++ {
+		counts[r.shard(fmt.Sprintf("code%d", i))]++
+	}
+
+	for i, count := range counts {
+		assert.InDelta(t, 2500, count, 500, "shard %d", i)
+	}
+}
+
+func TestRing_appendMovesCodesToNewShard(t *testing.T) {
+	before, after := newRing(3), newRing(4)
+
+	var moved int
+	for i := 0; i < 10000; i++ {
+		code := fmt.Sprintf("code%d", i)
+		if from, to := before.shard(code), after.shard(code); from != to {
+			assert.Equal(t, 3, to, "a code may only move to the new shard")
+			moved++
+		}
+	}
+
+	assert.InDelta(t, 2500, moved, 500)
+}
+
+func TestRepository_fansOutUserQueries(t *testing.T) {
+	ctx := context.Background()
+	shards := newShards(t, 3)
+	repo := NewRepository(shards)
+
+	var urls []*entity.URLRecord
+	for i := 0; i < 30; i++ {
+		code := fmt.Sprintf("code%d", i)
+		urls = append(urls, &entity.URLRecord{UUID: code, ShortURL: code, OriginalURL: "https://example.com/" + code, UserID: "alice"})
+	}
+	require.NoError(t, repo.SaveURLBatch(ctx, urls))
+
+	// The records are spread over every shard.
+	for i, s := range shards {
+		count, err := s.CountURLsByUserID(ctx, "alice")
+		require.NoError(t, err)
+		assert.NotZero(t, count, "shard %d", i)
+	}
+
+	records, err := repo.GetURLsByUserID(ctx, "alice")
+	require.NoError(t, err)
+	assert.Len(t, records, 30)
+
+	_, err = repo.DeleteURLBatch([]string{"code1", "code2", "code3"}, "alice")
+	require.NoError(t, err)
+
+	count, err := repo.CountURLsByUserID(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, 27, count)
+
+	stats, err := repo.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 27, stats.URLs)
+}
+
+func TestRepository_workspacesOfDSNDatabase(t *testing.T) {
+	ctx := context.Background()
+	log, _ := logger.Initialize("debug")
+
+	dbs := make([]*sql.DB, 0, 3)
+	shards := make([]storage.Repository, 0, 3)
+	for i := 0; i < 3; i++ {
+		db, err := sqlite.OpenDB(sqlite.Scheme + filepath.Join(t.TempDir(), "urls.db"))
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		require.NoError(t, sqlite.Migrate(db, log))
+
+		dbs = append(dbs, db)
+		shards = append(shards, pg.NewSQLStorage(db, log))
+	}
+
+	// The memberships are kept in the first database only, like in the DSN database of the provider.
+	workspaces := pg.NewWorkspaceStorage(dbs[0], log)
+	require.NoError(t, workspaces.SaveWorkspace(ctx,
+		entity.Workspace{ID: "ws", Name: "team"},
+		entity.WorkspaceMember{WorkspaceID: "ws", UserID: "bob", CanView: true, CanDelete: true}))
+
+	repo := NewRepository(shards, WithWorkspaces(workspaces))
+
+	var codes []string
+	for i := 0; i < 30; i++ {
+		code := fmt.Sprintf("code%d", i)
+		codes = append(codes, code)
+		require.NoError(t, repo.SaveURL(ctx, entity.URLRecord{UUID: code, ShortURL: code, OriginalURL: "https://example.com/" + code, UserID: "alice"}))
+		require.NoError(t, repo.SetURLWorkspace(ctx, code, "alice", "ws"))
+	}
+
+	records, err := repo.GetURLsByUserID(ctx, "bob")
+	require.NoError(t, err)
+	assert.Len(t, records, 30, "the workspace URLs of every shard must be listed")
+
+	deleted, err := repo.DeleteURLBatch(codes, "bob")
+	require.NoError(t, err)
+	assert.Len(t, deleted, 30, "the workspace URLs of every shard must be deleted")
+
+	_, err = repo.GetURLsByUserID(ctx, "carol")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func TestRebalance(t *testing.T) {
+	ctx := context.Background()
+	log, _ := logger.Initialize("debug")
+
+	shards := newShards(t, 2)
+	old := NewRepository(shards)
+
+	var codes []string
+	for i := 0; i < 100; i++ {
+		code := fmt.Sprintf("code%d", i)
+		codes = append(codes, code)
+		require.NoError(t, old.SaveURL(ctx, entity.URLRecord{
+			UUID: fmt.Sprintf("%03d", i), ShortURL: code, OriginalURL: "https://example.com/" + code, UserID: "alice",
+		}))
+	}
+	_, err := old.DeleteURLBatch([]string{"code0"}, "alice")
+	require.NoError(t, err)
+
+	shards = append(shards, newShards(t, 1)...)
+	repo := NewRepository(shards)
+
+	// A user creates a code on the new shard before its old record is moved.
+	var conflict string
+	for _, code := range codes[1:] {
+		if newRing(3).shard(code) == 2 {
+			conflict = code
+			break
+		}
+	}
+	require.NoError(t, shards[2].SaveURL(ctx, entity.URLRecord{UUID: "new", ShortURL: conflict, OriginalURL: "https://new.example.com", UserID: "alice"}))
+
+	result, err := Rebalance(ctx, shards, 7, log)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, result.Scanned, 100, "the records moved to a shard are read again")
+	assert.NotZero(t, result.Moved)
+	assert.Equal(t, 1, result.Conflicts)
+
+	for _, code := range codes[1:] {
+		_, err := repo.GetURL(ctx, code)
+		assert.NoError(t, err, code)
+	}
+
+	_, err = repo.GetURL(ctx, "code0")
+	assert.ErrorIs(t, err, storage.ErrURLDeleted, "the deletion must be moved with the record")
+
+	records, err := repo.(storage.URLStore).ScanURLs(ctx, "", 1000)
+	require.NoError(t, err)
+	assert.Len(t, records, 101)
+
+	// Nothing is left to move but the conflict.
+	result, err = Rebalance(ctx, shards, 7, log)
+	require.NoError(t, err)
+	assert.Equal(t, RebalanceResult{Scanned: 101, Conflicts: 1}, result)
+}
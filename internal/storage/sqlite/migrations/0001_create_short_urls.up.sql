@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS short_urls (
+    id TEXT UNIQUE,
+    user_id TEXT,
+    short_url TEXT,
+    original_url TEXT,
+    is_deleted BOOLEAN DEFAULT false,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,3 @@
+ALTER TABLE short_urls ADD COLUMN title TEXT;
+ALTER TABLE short_urls ADD COLUMN description TEXT;
+ALTER TABLE short_urls ADD COLUMN image_url TEXT;
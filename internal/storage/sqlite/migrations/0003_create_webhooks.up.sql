@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+    id TEXT PRIMARY KEY,
+    user_id TEXT,
+    url TEXT,
+    secret TEXT,
+    events TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id TEXT PRIMARY KEY,
+    webhook_id TEXT REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_id TEXT,
+    event_type TEXT,
+    attempt INTEGER,
+    status_code INTEGER,
+    error TEXT,
+    delivered BOOLEAN,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
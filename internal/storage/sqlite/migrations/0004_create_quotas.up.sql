@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS quotas (
+    user_id TEXT PRIMARY KEY,
+    max_links INTEGER,
+    max_batch_size INTEGER,
+    max_url_length INTEGER
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id TEXT PRIMARY KEY,
+    user_id TEXT,
+    name TEXT,
+    key_hash TEXT UNIQUE,
+    scopes TEXT,
+    expires_at TIMESTAMP,
+    revoked BOOLEAN DEFAULT false,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
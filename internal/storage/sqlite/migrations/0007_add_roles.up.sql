@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN role TEXT DEFAULT 'user';
+
+ALTER TABLE short_urls ADD COLUMN is_disabled BOOLEAN DEFAULT false;
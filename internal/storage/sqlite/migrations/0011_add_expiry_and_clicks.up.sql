@@ -0,0 +1,5 @@
+ALTER TABLE short_urls ADD COLUMN expires_at TIMESTAMP;
+ALTER TABLE short_urls ADD COLUMN clicks INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE short_urls ADD COLUMN click_threshold INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS short_urls_expires_at_idx ON short_urls (expires_at) WHERE is_deleted = false;
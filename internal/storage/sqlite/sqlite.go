@@ -0,0 +1,93 @@
+// Package sqlite opens SQLite databases with the pure-Go driver and migrates them to the schema
+// of the SQL-based repositories of the pg package, which run on SQLite unchanged.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"strings"
+	"time"
+
+	// The pure-Go SQLite driver registered as "sqlite".
+	_ "modernc.org/sqlite"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/migrate"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/schema"
+)
+
+// Scheme is the prefix of the DSNs of SQLite databases, followed by the path of the database file,
+// e.g. 'sqlite:///var/lib/shortener/urls.db' or 'sqlite://urls.db' for a path relative to the working directory.
+const Scheme = "sqlite://"
+
+// migrationTimeout limits the time Migrate applies the pending migrations at startup.
+const migrationTimeout = time.Minute
+
+// pragmas enforce the foreign keys, wait for locks held by other connections instead of failing,
+// let readers run concurrently with a writer and store the times in a sortable format.
+// Transactions take the write lock upfront, so that they don't deadlock when upgrading a read lock.
+const pragmas = "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)" +
+	"&_time_format=sqlite&_txlock=immediate"
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// IsDSN reports whether the DSN refers to a SQLite database.
+func IsDSN(dsn string) bool {
+	return strings.HasPrefix(dsn, Scheme)
+}
+
+// OpenDB opens the SQLite database of the DSN, creating the file if it doesn't exist.
+func OpenDB(dsn string) (*sql.DB, error) {
+	if !IsDSN(dsn) {
+		return nil, fmt.Errorf("sqlite dsn must start with %s", Scheme)
+	}
+
+	path := strings.TrimPrefix(dsn, Scheme)
+	if path == "" {
+		return nil, fmt.Errorf("sqlite dsn must have a database path")
+	}
+
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+
+	db, err := sql.Open("sqlite", "file:"+path+separator+pragmas)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = db.Ping(); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// NewMigrator initializes a new migrate.Migrator with the portable and the SQLite migrations embedded in the binary.
+func NewMigrator(db *sql.DB, logger *logger.Logger) (*migrate.Migrator, error) {
+	files, err := schema.Migrations(migrationFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.New(db, files, logger)
+}
+
+// Migrate applies all pending migrations to the SQLite database.
+func Migrate(db *sql.DB, logger *logger.Logger) error {
+	ctx, cancel := context.WithTimeout(context.Background(), migrationTimeout)
+	defer cancel()
+
+	m, err := NewMigrator(db, logger)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.Up(ctx)
+
+	return err
+}
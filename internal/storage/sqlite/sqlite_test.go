@@ -0,0 +1,250 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/pg"
+)
+
+func setupDB(t *testing.T) (*sql.DB, *logger.Logger) {
+	log, _ := logger.Initialize("debug")
+
+	db, err := OpenDB(Scheme + filepath.Join(t.TempDir(), "urls.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	require.NoError(t, Migrate(db, log))
+
+	return db, log
+}
+
+func TestIsDSN(t *testing.T) {
+	assert.True(t, IsDSN("sqlite:///var/lib/urls.db"))
+	assert.False(t, IsDSN("postgres://localhost/urls"))
+}
+
+func TestMigrations_revert(t *testing.T) {
+	ctx := context.Background()
+	db, log := setupDB(t)
+
+	m, err := NewMigrator(db, log)
+	require.NoError(t, err)
+
+	n, err := m.To(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 12, n)
+
+	n, err = m.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 12, n)
+}
+
+func TestMigrations_keepDuplicates(t *testing.T) {
+	ctx := context.Background()
+	db, log := setupDB(t)
+
+	m, err := NewMigrator(db, log)
+	require.NoError(t, err)
+
+	_, err = m.To(ctx, 11)
+	require.NoError(t, err)
+
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO short_urls (id, user_id, short_url, original_url, is_deleted, created_at) VALUES
+			('1', 'alice', 'abc', 'https://a.example', true, $1),
+			('2', 'alice', 'abc', 'https://a.example', false, $2),
+			('3', 'alice', 'abc', 'https://a.example', false, $3),
+			('4', 'bob', 'abc', 'https://a.example', false, $1)`,
+		created, created.Add(time.Hour), created.Add(2*time.Hour))
+	require.NoError(t, err)
+
+	ids := func(table string) []string {
+		rows, err := db.QueryContext(ctx, `SELECT id FROM `+table+` ORDER BY id`)
+		require.NoError(t, err)
+		defer rows.Close()
+
+		var ids []string
+		for rows.Next() {
+			var id string
+			require.NoError(t, rows.Scan(&id))
+			ids = append(ids, id)
+		}
+		require.NoError(t, rows.Err())
+
+		return ids
+	}
+
+	_, err = m.To(ctx, 12)
+	require.NoError(t, err)
+
+	// The first record that isn't deleted is kept, the others are set aside.
+	assert.Equal(t, []string{"2", "4"}, ids("short_urls"))
+	assert.Equal(t, []string{"1", "3"}, ids("short_urls_duplicates"))
+
+	_, err = m.To(ctx, 11)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"1", "2", "3", "4"}, ids("short_urls"))
+}
+
+func TestSQLStorage(t *testing.T) {
+	ctx := context.Background()
+	db, log := setupDB(t)
+
+	s := pg.NewSQLStorage(db, log)
+	workspaces := pg.NewWorkspaceStorage(db, log)
+
+	require.NoError(t, s.SaveURL(ctx, entity.URLRecord{UUID: "1", ShortURL: "abc", OriginalURL: "https://a.example.com", UserID: "alice"}))
+	require.NoError(t, s.SaveURLBatch(ctx, []*entity.URLRecord{
+		{UUID: "2", ShortURL: "def", OriginalURL: "https://d.example.com", UserID: "alice"},
+		{UUID: "3", ShortURL: "ghi", OriginalURL: "https://g.example.com", UserID: "bob"},
+	}))
+
+	got, err := s.GetURL(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://a.example.com", got)
+
+	_, err = s.GetURL(ctx, "xyz")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	require.NoError(t, s.SavePreview(ctx, "abc", entity.Preview{Title: "A"}))
+
+	r, err := s.GetURLRecord(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, &entity.Preview{Title: "A"}, r.Preview)
+
+	require.NoError(t, workspaces.SaveWorkspace(ctx,
+		entity.Workspace{ID: "ws", Name: "team"},
+		entity.WorkspaceMember{WorkspaceID: "ws", UserID: "bob", CanView: true, CanDelete: true}))
+	require.NoError(t, s.SetURLWorkspace(ctx, "def", "alice", "ws"))
+
+	records, err := s.GetURLsByUserID(ctx, "bob")
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "ghi", records[0].ShortURL)
+	assert.Equal(t, "ws", records[1].WorkspaceID)
+
+	// Bob deletes his own link and the link of the workspace, but not the other links of Alice.
+	_, err = s.DeleteURLBatch([]string{"abc", "def", "ghi"}, "bob")
+	require.NoError(t, err)
+
+	_, err = s.GetURL(ctx, "def")
+	assert.ErrorIs(t, err, storage.ErrURLDeleted)
+
+	_, err = s.SetURLDisabled(ctx, "abc", true)
+	require.NoError(t, err)
+
+	_, err = s.GetURL(ctx, "abc")
+	assert.ErrorIs(t, err, storage.ErrURLDisabled)
+
+	assert.ErrorIs(t, s.ReassignURL(ctx, "abc", "bob", "carol"), storage.ErrURLNotFound)
+
+	count, err := s.CountURLsByUserID(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	stats, err := s.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &entity.Stats{URLs: 1, Users: 1}, stats)
+}
+
+func TestAuditStorage(t *testing.T) {
+	ctx := context.Background()
+	db, log := setupDB(t)
+
+	s := pg.NewAuditStorage(db, log)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, s.SaveAuditEntries(ctx, []entity.AuditEntry{
+		{ID: "1", ActorID: "alice", Action: "create", CreatedAt: now},
+		{ID: "2", ActorID: "bob", Action: "delete", CreatedAt: now.Add(time.Hour)},
+	}))
+
+	entries, err := s.GetAuditEntries(ctx, entity.AuditFilter{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "2", entries[0].ID)
+	assert.True(t, now.Equal(entries[1].CreatedAt))
+
+	entries, err = s.GetAuditEntries(ctx, entity.AuditFilter{Since: now.Add(time.Minute), Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "bob", entries[0].ActorID)
+
+	// The audit log is append-only.
+	_, err = db.ExecContext(ctx, `DELETE FROM audit_log`)
+	require.NoError(t, err)
+
+	entries, err = s.GetAuditEntries(ctx, entity.AuditFilter{Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	// Large batches are inserted in chunks, either all of them or none.
+	batch := make([]entity.AuditEntry, 0, 1200)
+	for i := 0; i < cap(batch); i++ {
+		batch = append(batch, entity.AuditEntry{ID: fmt.Sprintf("carol-%d", i), ActorID: "carol", Action: "create", CreatedAt: now})
+	}
+
+	failing := append(append([]entity.AuditEntry(nil), batch...), entity.AuditEntry{ID: "1", ActorID: "carol", CreatedAt: now})
+	require.Error(t, s.SaveAuditEntries(ctx, failing))
+
+	entries, err = s.GetAuditEntries(ctx, entity.AuditFilter{ActorID: "carol", Limit: 2000})
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	require.NoError(t, s.SaveAuditEntries(ctx, batch))
+
+	entries, err = s.GetAuditEntries(ctx, entity.AuditFilter{ActorID: "carol", Limit: 2000})
+	require.NoError(t, err)
+	assert.Len(t, entries, len(batch))
+}
+
+func TestUserStorage(t *testing.T) {
+	ctx := context.Background()
+	db, log := setupDB(t)
+
+	s := pg.NewUserStorage(db, log)
+
+	require.NoError(t, s.SaveUser(ctx, entity.User{ID: "1", Username: "alice", CreatedAt: time.Now()}))
+	assert.ErrorIs(t, s.SaveUser(ctx, entity.User{ID: "2", Username: "alice", CreatedAt: time.Now()}), storage.ErrUsernameTaken)
+
+	u, err := s.GetUserByUsername(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, "1", u.ID)
+}
+
+func TestRefreshTokenStorage(t *testing.T) {
+	ctx := context.Background()
+	db, log := setupDB(t)
+
+	s := pg.NewRefreshTokenStorage(db, log)
+
+	now := time.Now().UTC()
+	require.NoError(t, s.SaveRefreshToken(ctx, entity.RefreshToken{ID: "1", UserID: "alice", Hash: "h1", ExpiresAt: now.Add(time.Hour), CreatedAt: now}))
+	require.NoError(t, s.SaveRefreshToken(ctx, entity.RefreshToken{ID: "2", UserID: "alice", Hash: "h2", ExpiresAt: now.Add(-time.Hour), CreatedAt: now}))
+	require.NoError(t, s.SaveRefreshToken(ctx, entity.RefreshToken{ID: "3", UserID: "bob", Hash: "h3", ExpiresAt: now.Add(time.Hour), CreatedAt: now}))
+	require.NoError(t, s.RevokeRefreshToken(ctx, "1", true))
+
+	deleted, err := s.DeleteRefreshTokens(ctx, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+
+	for hash, kept := range map[string]bool{"h1": false, "h2": false, "h3": true} {
+		token, err := s.GetRefreshTokenByHash(ctx, hash)
+		require.NoError(t, err)
+		assert.Equal(t, kept, token != nil, hash)
+	}
+}
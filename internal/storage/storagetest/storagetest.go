@@ -0,0 +1,809 @@
+// Package storagetest provides a behavioral test suite that every storage.Repository must pass,
+// so that the storage backends stay interchangeable. A backend runs it from its tests with a Factory:
+//
+//	func TestStorage_conformance(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) storage.Repository {
+//			return NewStorage(...)
+//		})
+//	}
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// Factory returns a new empty repository for a test. It should register the cleanup of the repository with t.
+type Factory func(t *testing.T) storage.Repository
+
+// Users of the suite. The IDs are UUIDs, since the SQL backends store them as such.
+var (
+	alice = "00000000-0000-0000-0000-00000000000a"
+	bob   = "00000000-0000-0000-0000-00000000000b"
+	carol = "00000000-0000-0000-0000-00000000000c"
+)
+
+// Run runs the suite against the repositories returned by newRepo, a new one for every test.
+func Run(t *testing.T, newRepo Factory) {
+	t.Run("Save", func(t *testing.T) { testSave(t, newRepo) })
+	t.Run("Batch", func(t *testing.T) { testBatch(t, newRepo) })
+	t.Run("Exists", func(t *testing.T) { testExists(t, newRepo) })
+	t.Run("Listing", func(t *testing.T) { testListing(t, newRepo) })
+	t.Run("NotFound", func(t *testing.T) { testNotFound(t, newRepo) })
+	t.Run("Deletion", func(t *testing.T) { testDeletion(t, newRepo) })
+	t.Run("AsyncDeletion", func(t *testing.T) { testAsyncDeletion(t, newRepo) })
+	t.Run("Stats", func(t *testing.T) { testStats(t, newRepo) })
+	t.Run("Concurrency", func(t *testing.T) { testConcurrency(t, newRepo) })
+	t.Run("ContextCancellation", func(t *testing.T) { testContextCancellation(t, newRepo) })
+	t.Run("Store", func(t *testing.T) { testStore(t, newRepo) })
+	t.Run("Expiry", func(t *testing.T) { testExpiry(t, newRepo) })
+	t.Run("ExpiredReads", func(t *testing.T) { testExpiredReads(t, newRepo) })
+	t.Run("Recreate", func(t *testing.T) { testRecreate(t, newRepo) })
+	t.Run("Clicks", func(t *testing.T) { testClicks(t, newRepo) })
+}
+
+func newRecord(shortURL, userID string) entity.URLRecord {
+	return entity.URLRecord{
+		UUID:        uuid.New().String(),
+		ShortURL:    shortURL,
+		OriginalURL: "https://" + shortURL + ".example.com",
+		UserID:      userID,
+	}
+}
+
+func testSave(t *testing.T, newRepo Factory) {
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	want := newRecord("abc", alice)
+	require.NoError(t, repo.SaveURL(ctx, want))
+
+	got, err := repo.GetURL(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, want.OriginalURL, got)
+
+	r, err := repo.GetURLRecord(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, want.UUID, r.UUID)
+	assert.Equal(t, want.UserID, r.UserID)
+	assert.Equal(t, want.ShortURL, r.ShortURL)
+	assert.Equal(t, want.OriginalURL, r.OriginalURL)
+	assert.False(t, r.DeletedFlag)
+	assert.False(t, r.Disabled)
+
+	assert.NoError(t, repo.CheckExistence(ctx, "abc", alice))
+
+	count, err := repo.CountURLsByUserID(ctx, alice)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func testBatch(t *testing.T, newRepo Factory) {
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	require.NoError(t, repo.SaveURLBatch(ctx, nil), "an empty batch must be accepted")
+
+	batch := []entity.URLRecord{newRecord("abc", alice), newRecord("def", alice), newRecord("abc", bob)}
+	require.NoError(t, repo.SaveURLBatch(ctx, pointers(batch)))
+
+	for _, want := range batch {
+		assert.NoError(t, repo.CheckExistence(ctx, want.ShortURL, want.UserID), want.ShortURL)
+
+		got, err := repo.GetURL(ctx, want.ShortURL)
+		require.NoError(t, err)
+		assert.Equal(t, want.OriginalURL, got)
+	}
+
+	count, err := repo.CountURLsByUserID(ctx, alice)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	existing, err := repo.GetExistingURLs(ctx, []string{"abc", "def", "xyz"}, alice)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"abc", "def"}, existing)
+
+	_, err = repo.DeleteURLBatch([]string{"def"}, alice)
+	require.NoError(t, err)
+
+	existing, err = repo.GetExistingURLs(ctx, []string{"def"}, bob)
+	require.NoError(t, err)
+	assert.Empty(t, existing, "the records of another user must not count")
+
+	existing, err = repo.GetExistingURLs(ctx, []string{"def"}, alice)
+	require.NoError(t, err)
+	assert.Empty(t, existing, "the deleted records must not count")
+}
+
+func testListing(t *testing.T, newRepo Factory) {
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	require.NoError(t, repo.SaveURLBatch(ctx, pointers([]entity.URLRecord{
+		newRecord("abc", alice),
+		newRecord("def", alice),
+		newRecord("ghi", bob),
+	})))
+
+	tests := []struct {
+		name   string
+		userID string
+		want   []string
+	}{
+		{
+			name:   "should list URLs of first user",
+			userID: alice,
+			want:   []string{"abc", "def"},
+		},
+		{
+			name:   "should list URLs of second user",
+			userID: bob,
+			want:   []string{"ghi"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := repo.GetURLsByUserID(ctx, tt.userID)
+			require.NoError(t, err)
+
+			got := make([]string, 0, len(records))
+			for _, r := range records {
+				assert.Equal(t, tt.userID, r.UserID)
+				got = append(got, r.ShortURL)
+			}
+
+			assert.ElementsMatch(t, tt.want, got)
+		})
+	}
+}
+
+func testDeletion(t *testing.T, newRepo Factory) {
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	require.NoError(t, repo.SaveURL(ctx, newRecord("abc", alice)))
+	require.NoError(t, repo.SaveURL(ctx, newRecord("abc", bob)))
+	require.NoError(t, repo.SaveURL(ctx, newRecord("def", alice)))
+
+	marked, err := repo.DeleteURLBatch([]string{"abc", "def"}, alice)
+	require.NoError(t, err)
+	require.Len(t, marked, 2)
+
+	for _, r := range marked {
+		assert.Equal(t, alice, r.UserID, r.ShortURL)
+		assert.True(t, r.DeletedFlag, r.ShortURL)
+	}
+
+	// The short URL of bob isn't deleted, so it still resolves.
+	got, err := repo.GetURL(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://abc.example.com", got)
+
+	r, err := repo.GetURLRecord(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, bob, r.UserID)
+	assert.False(t, r.DeletedFlag)
+
+	_, err = repo.GetURL(ctx, "def")
+	assert.ErrorIs(t, err, storage.ErrURLDeleted)
+
+	_, err = repo.GetURLRecord(ctx, "def")
+	assert.ErrorIs(t, err, storage.ErrURLDeleted)
+
+	_, err = repo.DeleteURLBatch([]string{"abc"}, bob)
+	require.NoError(t, err)
+
+	_, err = repo.GetURL(ctx, "abc")
+	assert.ErrorIs(t, err, storage.ErrURLDeleted)
+
+	// The deleted records are still listed, flagged, for the moderators. Listings of the live URLs skip them.
+	records, err := repo.GetURLsByUserID(ctx, alice)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+
+	for _, r := range records {
+		assert.True(t, r.DeletedFlag, r.ShortURL)
+	}
+
+	count, err := repo.CountURLsByUserID(ctx, alice)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	// Deleting a record twice changes nothing.
+	marked, err = repo.DeleteURLBatch([]string{"def"}, alice)
+	require.NoError(t, err)
+	assert.Empty(t, marked)
+
+	_, err = repo.GetURL(ctx, "def")
+	assert.ErrorIs(t, err, storage.ErrURLDeleted)
+
+	// Only the owner deletes a record.
+	require.NoError(t, repo.SaveURL(ctx, newRecord("ghi", alice)))
+	marked, err = repo.DeleteURLBatch([]string{"ghi"}, carol)
+	require.NoError(t, err)
+	assert.Empty(t, marked)
+
+	_, err = repo.GetURL(ctx, "ghi")
+	assert.NoError(t, err)
+}
+
+// testAsyncDeletion deletes the records the way the deletion worker of the service does, from another
+// goroutine and without a request context, and checks that every read sees the deletion once it returns.
+func testAsyncDeletion(t *testing.T, newRepo Factory) {
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	require.NoError(t, repo.SaveURLBatch(ctx, pointers([]entity.URLRecord{
+		newRecord("abc", alice),
+		newRecord("def", alice),
+	})))
+
+	done := make(chan error)
+	go func() {
+		_, err := repo.DeleteURLBatch([]string{"abc"}, alice)
+		done <- err
+	}()
+
+	// Reads while the deletion is in flight see the record either way.
+	if _, err := repo.GetURL(ctx, "abc"); err != nil {
+		assert.ErrorIs(t, err, storage.ErrURLDeleted)
+	}
+
+	require.NoError(t, <-done)
+
+	_, err := repo.GetURL(ctx, "abc")
+	assert.ErrorIs(t, err, storage.ErrURLDeleted)
+
+	_, err = repo.GetURLRecord(ctx, "abc")
+	assert.ErrorIs(t, err, storage.ErrURLDeleted)
+
+	records, err := repo.GetURLsByUserID(ctx, alice)
+	require.NoError(t, err)
+
+	for _, r := range records {
+		assert.Equal(t, r.ShortURL == "abc", r.DeletedFlag, r.ShortURL)
+	}
+
+	count, err := repo.CountURLsByUserID(ctx, alice)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	assertStats(t, repo, 1, 1)
+
+	_, err = repo.GetURL(ctx, "def")
+	assert.NoError(t, err)
+}
+
+func testNotFound(t *testing.T, newRepo Factory) {
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	require.NoError(t, repo.SaveURL(ctx, newRecord("abc", alice)))
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{
+			name: "GetURL",
+			call: func() error {
+				_, err := repo.GetURL(ctx, "xyz")
+				return err
+			},
+		},
+		{
+			name: "GetURLRecord",
+			call: func() error {
+				_, err := repo.GetURLRecord(ctx, "xyz")
+				return err
+			},
+		},
+		{
+			name: "GetURLsByUserID",
+			call: func() error {
+				_, err := repo.GetURLsByUserID(ctx, bob)
+				return err
+			},
+		},
+		{
+			name: "CheckExistence of another user",
+			call: func() error {
+				return repo.CheckExistence(ctx, "abc", bob)
+			},
+		},
+		{
+			name: "CheckExistence of unknown URL",
+			call: func() error {
+				return repo.CheckExistence(ctx, "xyz", alice)
+			},
+		},
+		{
+			name: "ReassignURL",
+			call: func() error {
+				return repo.ReassignURL(ctx, "abc", bob, carol)
+			},
+		},
+		{
+			name: "SetURLDisabled",
+			call: func() error {
+				_, err := repo.SetURLDisabled(ctx, "xyz", true)
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, tt.call(), storage.ErrURLNotFound)
+		})
+	}
+
+	assert.NoError(t, repo.CheckExistence(ctx, "abc", alice))
+}
+
+func testExists(t *testing.T, newRepo Factory) {
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	require.NoError(t, repo.SaveURL(ctx, newRecord("abc", alice)))
+
+	err := repo.SaveURL(ctx, newRecord("abc", alice))
+	assert.ErrorIs(t, err, storage.ErrURLExists)
+
+	// Another user may have the same short URL.
+	require.NoError(t, repo.SaveURL(ctx, newRecord("abc", bob)))
+
+	assert.ErrorIs(t, repo.ReassignURL(ctx, "abc", alice, bob), storage.ErrURLExists)
+
+	tests := []struct {
+		name  string
+		batch []entity.URLRecord
+	}{
+		{
+			name:  "should reject URL the user already has",
+			batch: []entity.URLRecord{newRecord("def", alice), newRecord("abc", alice)},
+		},
+		{
+			name:  "should reject URL repeated in the batch",
+			batch: []entity.URLRecord{newRecord("def", alice), newRecord("def", alice)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, repo.SaveURLBatch(ctx, pointers(tt.batch)), storage.ErrURLExists)
+
+			// None of the batch is stored.
+			_, err := repo.GetURL(ctx, "def")
+			assert.ErrorIs(t, err, storage.ErrURLNotFound)
+		})
+	}
+}
+
+func testStats(t *testing.T, newRepo Factory) {
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	assertStats(t, repo, 0, 0)
+
+	require.NoError(t, repo.SaveURL(ctx, newRecord("abc", alice)))
+	require.NoError(t, repo.SaveURL(ctx, newRecord("def", alice)))
+	require.NoError(t, repo.SaveURL(ctx, newRecord("abc", bob)))
+
+	assertStats(t, repo, 3, 2)
+
+	// Deleted records aren't counted, nor the users left without records.
+	_, err := repo.DeleteURLBatch([]string{"abc"}, alice)
+	require.NoError(t, err)
+	_, err = repo.DeleteURLBatch([]string{"abc"}, bob)
+	require.NoError(t, err)
+
+	assertStats(t, repo, 1, 1)
+
+	if lister, ok := repo.(storage.UserLister); ok {
+		users, err := lister.GetUserIDs(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{alice}, users)
+	}
+}
+
+// testConcurrency saves distinct records and the same record from many goroutines at once.
+// Every distinct record is stored, and the same record only once.
+func testConcurrency(t *testing.T, newRepo Factory) {
+	const workers = 8
+
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	var wg sync.WaitGroup
+	errs := make([]error, workers)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			if err := repo.SaveURL(ctx, newRecord(fmt.Sprintf("url%d", i), bob)); err != nil {
+				t.Errorf("save url%d: %v", i, err)
+			}
+
+			errs[i] = repo.SaveURL(ctx, newRecord("abc", alice))
+
+			if _, err := repo.GetURL(ctx, fmt.Sprintf("url%d", i)); err != nil {
+				t.Errorf("get url%d: %v", i, err)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	var saved int
+	for _, err := range errs {
+		if err == nil {
+			saved++
+			continue
+		}
+
+		assert.ErrorIs(t, err, storage.ErrURLExists)
+	}
+
+	assert.Equal(t, 1, saved, "the same record must be saved once")
+
+	assertStats(t, repo, workers+1, 2)
+}
+
+func testContextCancellation(t *testing.T, newRepo Factory) {
+	repo := newRepo(t)
+
+	require.NoError(t, repo.SaveURL(context.Background(), newRecord("abc", alice)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{
+			name: "SaveURL",
+			call: func() error {
+				return repo.SaveURL(ctx, newRecord("def", alice))
+			},
+		},
+		{
+			name: "SaveURLBatch",
+			call: func() error {
+				return repo.SaveURLBatch(ctx, pointers([]entity.URLRecord{newRecord("def", alice)}))
+			},
+		},
+		{
+			name: "GetURL",
+			call: func() error {
+				_, err := repo.GetURL(ctx, "abc")
+				return err
+			},
+		},
+		{
+			name: "GetURLRecord",
+			call: func() error {
+				_, err := repo.GetURLRecord(ctx, "abc")
+				return err
+			},
+		},
+		{
+			name: "GetURLsByUserID",
+			call: func() error {
+				_, err := repo.GetURLsByUserID(ctx, alice)
+				return err
+			},
+		},
+		{
+			name: "CheckExistence",
+			call: func() error {
+				return repo.CheckExistence(ctx, "abc", alice)
+			},
+		},
+		{
+			name: "GetExistingURLs",
+			call: func() error {
+				_, err := repo.GetExistingURLs(ctx, []string{"abc"}, alice)
+				return err
+			},
+		},
+		{
+			name: "SetURLDisabled",
+			call: func() error {
+				_, err := repo.SetURLDisabled(ctx, "abc", true)
+				return err
+			},
+		},
+		{
+			name: "GetStats",
+			call: func() error {
+				_, err := repo.GetStats(ctx)
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, tt.call(), context.Canceled)
+		})
+	}
+
+	// The canceled calls changed nothing.
+	_, err := repo.GetURL(context.Background(), "def")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	_, err = repo.GetURL(context.Background(), "abc")
+	assert.NoError(t, err)
+}
+
+// testStore checks the storage.URLStore of the backends that implement it: the records are scanned in pages
+// and imported as they are, deletion state and preview included, and removed for good.
+func testStore(t *testing.T, newRepo Factory) {
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	store, ok := repo.(storage.URLStore)
+	if !ok {
+		t.Skip("the repository doesn't implement storage.URLStore")
+	}
+
+	records := []entity.URLRecord{
+		{UUID: "00000000-0000-0000-0000-000000000003", ShortURL: "ghi", OriginalURL: "https://ghi.example.com", UserID: bob},
+		{UUID: "00000000-0000-0000-0000-000000000001", ShortURL: "abc", OriginalURL: "https://abc.example.com", UserID: alice,
+			Preview: &entity.Preview{Title: "ABC"}},
+		{UUID: "00000000-0000-0000-0000-000000000002", ShortURL: "def", OriginalURL: "https://def.example.com", UserID: alice,
+			DeletedFlag: true},
+	}
+	require.NoError(t, store.ImportURLs(ctx, records))
+
+	_, err := repo.GetURL(ctx, "def")
+	assert.ErrorIs(t, err, storage.ErrURLDeleted)
+
+	r, err := repo.GetURLRecord(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, records[1].UUID, r.UUID)
+	assert.Equal(t, records[1].Preview, r.Preview)
+
+	// The pages follow each other in the order of the UUIDs, deleted records included.
+	var scanned []entity.URLRecord
+	var after string
+	for {
+		page, err := store.ScanURLs(ctx, after, 2)
+		require.NoError(t, err)
+
+		if len(page) == 0 {
+			break
+		}
+
+		scanned = append(scanned, page...)
+		after = page[len(page)-1].UUID
+	}
+
+	require.Len(t, scanned, 3)
+	assert.Equal(t, []string{"abc", "def", "ghi"}, []string{scanned[0].ShortURL, scanned[1].ShortURL, scanned[2].ShortURL})
+	assert.True(t, scanned[1].DeletedFlag)
+	assert.Equal(t, records[0].UserID, scanned[2].UserID)
+
+	// Importing a record again replaces it.
+	restored := records[2]
+	restored.DeletedFlag = false
+	require.NoError(t, store.ImportURLs(ctx, []entity.URLRecord{restored}))
+
+	got, err := repo.GetURL(ctx, "def")
+	require.NoError(t, err)
+	assert.Equal(t, restored.OriginalURL, got)
+
+	// A user still has one record of a short URL.
+	err = store.ImportURLs(ctx, []entity.URLRecord{newRecord("abc", alice)})
+	assert.ErrorIs(t, err, storage.ErrURLExists)
+
+	require.NoError(t, store.RemoveURLs(ctx, []string{records[1].UUID, uuid.New().String()}))
+
+	_, err = repo.GetURL(ctx, "abc")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+
+	scanned, err = store.ScanURLs(ctx, "", 10)
+	require.NoError(t, err)
+	assert.Len(t, scanned, 2)
+
+	assertStats(t, repo, 2, 2)
+}
+
+func testExpiry(t *testing.T, newRepo Factory) {
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	now := time.Date(2030, time.January, 1, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Minute), now.Add(time.Hour)
+
+	expired := newRecord("abc", alice)
+	expired.ExpiresAt = &past
+
+	expiredBatch := newRecord("abc", bob)
+	expiredBatch.ExpiresAt = &now
+
+	live := newRecord("def", alice)
+	live.ExpiresAt = &future
+
+	require.NoError(t, repo.SaveURL(ctx, expired))
+	require.NoError(t, repo.SaveURLBatch(ctx, pointers([]entity.URLRecord{expiredBatch, live, newRecord("ghi", carol)})))
+
+	r, err := repo.GetURLRecord(ctx, "def")
+	require.NoError(t, err)
+	require.NotNil(t, r.ExpiresAt)
+	assert.True(t, future.Equal(*r.ExpiresAt), "the expiry must be stored")
+
+	marked, err := repo.ExpireURLs(ctx, now)
+	require.NoError(t, err)
+	require.Len(t, marked, 2)
+
+	users := make([]string, 0, len(marked))
+	for _, r := range marked {
+		assert.Equal(t, "abc", r.ShortURL)
+		assert.True(t, r.DeletedFlag)
+		users = append(users, r.UserID)
+	}
+	assert.ElementsMatch(t, []string{alice, bob}, users)
+
+	_, err = repo.GetURL(ctx, "abc")
+	assert.ErrorIs(t, err, storage.ErrURLDeleted)
+
+	for _, shortURL := range []string{"def", "ghi"} {
+		_, err = repo.GetURL(ctx, shortURL)
+		assert.NoError(t, err, shortURL)
+	}
+
+	marked, err = repo.ExpireURLs(ctx, now)
+	require.NoError(t, err)
+	assert.Empty(t, marked, "the deleted records must not expire again")
+
+	assertStats(t, repo, 2, 2)
+}
+
+// testExpiredReads checks that the records read as deleted once they expire, before ExpireURLs marks them.
+func testExpiredReads(t *testing.T, newRepo Factory) {
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+
+	expired := newRecord("abc", alice)
+	expired.ExpiresAt = &past
+
+	live := newRecord("abc", bob)
+	live.ExpiresAt = &future
+	live.OriginalURL = "https://bob.example.com"
+
+	require.NoError(t, repo.SaveURL(ctx, expired))
+
+	_, err := repo.GetURL(ctx, "abc")
+	assert.ErrorIs(t, err, storage.ErrURLDeleted)
+
+	_, err = repo.GetURLRecord(ctx, "abc")
+	assert.ErrorIs(t, err, storage.ErrURLDeleted)
+
+	// Another record of the short URL that hasn't expired still resolves it.
+	require.NoError(t, repo.SaveURL(ctx, live))
+
+	got, err := repo.GetURL(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, live.OriginalURL, got)
+
+	r, err := repo.GetURLRecord(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, bob, r.UserID)
+}
+
+func testRecreate(t *testing.T, newRepo Factory) {
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	past := time.Now().Add(-time.Hour)
+
+	expired := newRecord("abc", alice)
+	expired.ExpiresAt = &past
+	require.NoError(t, repo.SaveURL(ctx, expired))
+
+	assert.ErrorIs(t, repo.CheckExistence(ctx, "abc", alice), storage.ErrURLNotFound, "an expired record must not count")
+
+	again := newRecord("abc", alice)
+	again.OriginalURL = "https://again.example.com"
+	require.NoError(t, repo.SaveURL(ctx, again), "an expired record must be replaced")
+
+	r, err := repo.GetURLRecord(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, again.UUID, r.UUID)
+	assert.Equal(t, again.OriginalURL, r.OriginalURL)
+
+	require.NoError(t, repo.SaveURL(ctx, newRecord("def", alice)))
+	_, err = repo.DeleteURLBatch([]string{"def"}, alice)
+	require.NoError(t, err)
+
+	batch := []entity.URLRecord{newRecord("def", alice), newRecord("ghi", alice)}
+	batch[0].OriginalURL = "https://again.example.com/def"
+	require.NoError(t, repo.SaveURLBatch(ctx, pointers(batch)), "a deleted record must be replaced")
+
+	got, err := repo.GetURL(ctx, "def")
+	require.NoError(t, err)
+	assert.Equal(t, batch[0].OriginalURL, got)
+
+	count, err := repo.CountURLsByUserID(ctx, alice)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count, "the replaced records must be gone")
+}
+
+func testClicks(t *testing.T, newRepo Factory) {
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	owned := newRecord("abc", alice)
+	owned.ClickThreshold = 10
+
+	require.NoError(t, repo.SaveURL(ctx, owned))
+	require.NoError(t, repo.SaveURLBatch(ctx, pointers([]entity.URLRecord{newRecord("abc", bob), newRecord("def", bob)})))
+
+	_, err := repo.DeleteURLBatch([]string{"def"}, bob)
+	require.NoError(t, err)
+
+	updated, err := repo.AddClicks(ctx, map[string]int64{"abc": 3, "def": 5, "xyz": 1})
+	require.NoError(t, err)
+	require.Len(t, updated, 2, "the deleted and unknown URLs must not count clicks")
+
+	updated, err = repo.AddClicks(ctx, map[string]int64{"abc": 4})
+	require.NoError(t, err)
+	require.Len(t, updated, 2)
+
+	for _, r := range updated {
+		assert.Equal(t, int64(7), r.Clicks, r.UserID)
+
+		if r.UserID == alice {
+			assert.Equal(t, int64(10), r.ClickThreshold)
+		}
+	}
+
+	records, err := repo.GetURLsByUserID(ctx, bob)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+
+	for _, r := range records {
+		if r.ShortURL == "def" {
+			assert.Zero(t, r.Clicks)
+		} else {
+			assert.Equal(t, int64(7), r.Clicks)
+		}
+	}
+}
+
+// assertStats checks the counts of the stats. The other stats, like the ones of a cache, depend on the backend.
+func assertStats(t *testing.T, repo storage.Repository, urls, users int) {
+	t.Helper()
+
+	stats, err := repo.GetStats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, urls, stats.URLs, "urls")
+	assert.Equal(t, users, stats.Users, "users")
+}
+
+func pointers(records []entity.URLRecord) []*entity.URLRecord {
+	result := make([]*entity.URLRecord, 0, len(records))
+	for i := range records {
+		result = append(result, &records[i])
+	}
+
+	return result
+}
@@ -0,0 +1,140 @@
+// Package transfer copies the URL records from one storage backend to another as they are,
+// with their UUIDs, owners and deletion state, e.g. to move from the file storage to a database.
+package transfer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+)
+
+// DefaultBatchSize is the number of records copied at once, if it isn't set.
+const DefaultBatchSize = 1000
+
+// Checkpoint is the progress of a copy. The records are copied in the order of their UUIDs,
+// so a copy resumes after the last record it copied.
+type Checkpoint struct {
+	Source    string `json:"source"`     // The source of the copy, without credentials.
+	Target    string `json:"target"`     // The target of the copy, without credentials.
+	AfterUUID string `json:"after_uuid"` // The UUID of the last copied record.
+	Copied    int    `json:"copied"`     // The number of copied records.
+}
+
+// LoadCheckpoint reads the checkpoint from the file at the path. It returns false if there is no such file.
+func LoadCheckpoint(path string) (Checkpoint, bool, error) {
+	var cp Checkpoint
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, false, nil
+	}
+	if err != nil {
+		return cp, false, err
+	}
+
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return cp, false, fmt.Errorf("invalid checkpoint %s: %w", path, err)
+	}
+
+	return cp, true, nil
+}
+
+// SaveCheckpoint writes the checkpoint to the file at the path. The file is replaced at once,
+// so an interrupted save leaves the previous checkpoint.
+func SaveCheckpoint(path string, cp Checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+// Copy imports the records of the source into the target in batches of batchSize records, in the order
+// of their UUIDs after the AfterUUID of the checkpoint, and calls save with the progress after every batch.
+// It returns the checkpoint of the last batch, which is also the one of a failed copy to resume from.
+//
+// The target replaces the records it already has with the same UUIDs, so the batch a copy was interrupted
+// in is copied again without harm. The records created in the source after the copy started may be missed,
+// so the source should not be written to meanwhile.
+func Copy(ctx context.Context, source, target storage.URLStore, batchSize int, cp Checkpoint, save func(Checkpoint) error) (Checkpoint, error) {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	for {
+		records, err := source.ScanURLs(ctx, cp.AfterUUID, batchSize)
+		if err != nil {
+			return cp, fmt.Errorf("read source: %w", err)
+		}
+
+		if len(records) == 0 {
+			return cp, nil
+		}
+
+		if err := target.ImportURLs(ctx, records); err != nil {
+			return cp, fmt.Errorf("write target: %w", err)
+		}
+
+		cp.AfterUUID = records[len(records)-1].UUID
+		cp.Copied += len(records)
+
+		if err := save(cp); err != nil {
+			return cp, fmt.Errorf("save checkpoint: %w", err)
+		}
+	}
+}
+
+// Counts counts the records of a store.
+type Counts struct {
+	Records int // All the records, including the deleted ones.
+	Deleted int // The deleted records.
+	Users   int // The users with records, including the deleted ones.
+}
+
+// Count reads every record of the store in batches of batchSize records and counts them.
+func Count(ctx context.Context, store storage.URLStore, batchSize int) (Counts, error) {
+	var counts Counts
+
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	users := make(map[string]bool)
+
+	var after string
+	for {
+		records, err := store.ScanURLs(ctx, after, batchSize)
+		if err != nil {
+			return counts, err
+		}
+
+		if len(records) == 0 {
+			break
+		}
+
+		for _, r := range records {
+			counts.Records++
+			if r.DeletedFlag {
+				counts.Deleted++
+			}
+
+			users[r.UserID] = true
+		}
+
+		after = records[len(records)-1].UUID
+	}
+
+	counts.Users = len(users)
+
+	return counts, nil
+}
@@ -0,0 +1,121 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/memory"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/pg"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/sqlite"
+)
+
+func setup(t *testing.T) (storage.Repository, storage.Repository) {
+	log, _ := logger.Initialize("debug")
+
+	source, err := memory.NewInMemStorage(filepath.Join(t.TempDir(), "db.log"), log)
+	require.NoError(t, err)
+	t.Cleanup(func() { source.(*memory.InMemStorage).Close() })
+
+	db, err := sqlite.OpenDB(sqlite.Scheme + filepath.Join(t.TempDir(), "urls.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, sqlite.Migrate(db, log))
+
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		require.NoError(t, source.SaveURL(ctx, entity.URLRecord{
+			UUID:        fmt.Sprintf("00000000-0000-0000-0000-%012d", i),
+			ShortURL:    fmt.Sprintf("code%d", i),
+			OriginalURL: fmt.Sprintf("https://example.com/%d", i),
+			UserID:      fmt.Sprintf("00000000-0000-0000-0000-00000000000%d", i%3),
+		}))
+	}
+
+	_, err = source.DeleteURLBatch([]string{"code0", "code3"}, "00000000-0000-0000-0000-000000000000")
+	require.NoError(t, err)
+	_, err = source.SetURLDisabled(ctx, "code1", true)
+	require.NoError(t, err)
+	require.NoError(t, source.SavePreview(ctx, "code2", entity.Preview{Title: "Two"}))
+
+	return source, pg.NewSQLStorage(db, log)
+}
+
+func TestCopy(t *testing.T) {
+	ctx := context.Background()
+	source, target := setup(t)
+
+	var saved []Checkpoint
+	cp, err := Copy(ctx, source.(storage.URLStore), target.(storage.URLStore), 4, Checkpoint{}, func(cp Checkpoint) error {
+		saved = append(saved, cp)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 10, cp.Copied)
+	assert.Len(t, saved, 3)
+
+	want, err := Count(ctx, source.(storage.URLStore), 3)
+	require.NoError(t, err)
+	assert.Equal(t, Counts{Records: 10, Deleted: 2, Users: 3}, want)
+
+	got, err := Count(ctx, target.(storage.URLStore), 3)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	_, err = target.GetURL(ctx, "code0")
+	assert.ErrorIs(t, err, storage.ErrURLDeleted)
+
+	_, err = target.GetURL(ctx, "code1")
+	assert.ErrorIs(t, err, storage.ErrURLDisabled)
+
+	r, err := target.GetURLRecord(ctx, "code2")
+	require.NoError(t, err)
+	assert.Equal(t, "00000000-0000-0000-0000-000000000002", r.UUID)
+	assert.Equal(t, "00000000-0000-0000-0000-000000000002", r.UserID)
+	assert.Equal(t, &entity.Preview{Title: "Two"}, r.Preview)
+}
+
+func TestCopy_resumes(t *testing.T) {
+	ctx := context.Background()
+	source, target := setup(t)
+
+	errInterrupted := errors.New("interrupted")
+
+	cp, err := Copy(ctx, source.(storage.URLStore), target.(storage.URLStore), 4, Checkpoint{}, func(cp Checkpoint) error {
+		if cp.Copied > 4 {
+			return errInterrupted
+		}
+
+		return nil
+	})
+	require.ErrorIs(t, err, errInterrupted)
+	assert.Equal(t, 8, cp.Copied, "the checkpoint of the interrupted batch is returned")
+
+	// The checkpoint of the last saved batch survives the restart.
+	path := filepath.Join(t.TempDir(), "checkpoint")
+	require.NoError(t, SaveCheckpoint(path, Checkpoint{AfterUUID: "00000000-0000-0000-0000-000000000003", Copied: 4}))
+
+	cp, ok, err := LoadCheckpoint(path)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	cp, err = Copy(ctx, source.(storage.URLStore), target.(storage.URLStore), 4, cp, func(Checkpoint) error { return nil })
+	require.NoError(t, err)
+	assert.Equal(t, 10, cp.Copied)
+
+	counts, err := Count(ctx, target.(storage.URLStore), 0)
+	require.NoError(t, err)
+	assert.Equal(t, 10, counts.Records)
+
+	_, ok, err = LoadCheckpoint(filepath.Join(t.TempDir(), "missing"))
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
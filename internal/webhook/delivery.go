@@ -0,0 +1,217 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// Headers set on every webhook request.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderEventID   = "X-Webhook-ID"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Event is the JSON body sent to webhook endpoints.
+type Event struct {
+	ID        string           `json:"id"`
+	Type      string           `json:"type"`
+	CreatedAt time.Time        `json:"created_at"`
+	Data      models.LinkEvent `json:"data"`
+}
+
+type pendingEvent struct {
+	userID string
+	event  Event
+}
+
+type delivery struct {
+	webhook entity.Webhook
+	event   Event
+	body    []byte
+	attempt int
+}
+
+// Sign computes the signature of the webhook request body sent at the given unix timestamp.
+// Receivers verify the X-Webhook-Signature header, which has the form "t=<timestamp>,v1=<signature>",
+// by computing the HMAC-SHA256 of "<timestamp>.<body>" with the webhook secret.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Publish queues the event for delivery to every webhook of the user subscribed to it.
+// It never blocks: the event is dropped if the queue is full.
+func (s *service) Publish(userID, eventType string, link models.LinkEvent) {
+	e := pendingEvent{
+		userID: userID,
+		event: Event{
+			ID:        uuid.New().String(),
+			Type:      eventType,
+			CreatedAt: time.Now().UTC(),
+			Data:      link,
+		},
+	}
+
+	select {
+	case s.events <- e:
+	default:
+		s.logger.Error("webhook event queue is full, dropping event",
+			zap.String("user id", userID), zap.String("event", eventType))
+	}
+}
+
+func (s *service) startEventWorker() {
+	for e := range s.events {
+		s.fanOut(e)
+	}
+}
+
+func (s *service) fanOut(e pendingEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	webhooks, err := s.repo.GetWebhooksByUserID(ctx, e.userID)
+	if err != nil {
+		s.logger.Error("cannot get webhooks", zap.Error(err), zap.String("user id", e.userID))
+		return
+	}
+
+	body, err := json.Marshal(e.event)
+	if err != nil {
+		s.logger.Error("cannot marshal webhook event", zap.Error(err))
+		return
+	}
+
+	for _, w := range webhooks {
+		if !subscribed(w, e.event.Type) {
+			continue
+		}
+
+		s.deliveries <- delivery{webhook: w, event: e.event, body: body, attempt: 1}
+	}
+}
+
+func (s *service) startDeliveryWorker(workers int) {
+	for i := 0; i < workers; i++ {
+		go func() {
+			for d := range s.deliveries {
+				s.deliver(d)
+			}
+		}()
+	}
+}
+
+// deliver sends the event to the webhook and records the attempt in the delivery log.
+// Failed attempts are retried with exponential backoff until maxAttempts is reached.
+func (s *service) deliver(d delivery) {
+	statusCode, err := s.send(d)
+
+	record := entity.WebhookDelivery{
+		ID:         uuid.New().String(),
+		WebhookID:  d.webhook.ID,
+		EventID:    d.event.ID,
+		EventType:  d.event.Type,
+		Attempt:    d.attempt,
+		StatusCode: statusCode,
+		Delivered:  err == nil,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	if err := s.repo.SaveDelivery(ctx, record); err != nil {
+		s.logger.Error("cannot save webhook delivery", zap.Error(err), zap.String("webhook id", d.webhook.ID))
+	}
+
+	if err == nil || d.attempt >= s.maxAttempts {
+		return
+	}
+
+	next := d
+	next.attempt++
+
+	time.AfterFunc(s.backoff(d.attempt), func() {
+		s.deliveries <- next
+	})
+}
+
+func (s *service) send(d delivery) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.client.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.webhook.URL, bytes.NewReader(d.body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, d.event.Type)
+	req.Header.Set(HeaderEventID, d.event.ID)
+	req.Header.Set(HeaderSignature, fmt.Sprintf("t=%d,v1=%s", timestamp, Sign(d.webhook.Secret, timestamp, d.body)))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			s.logger.Debug("failed to close webhook response body", zap.Error(err))
+		}
+	}()
+
+	if _, err := io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16)); err != nil {
+		s.logger.Debug("failed to read webhook response body", zap.Error(err))
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+func (s *service) backoff(attempt int) time.Duration {
+	d := s.baseBackoff << (attempt - 1)
+	if d <= 0 || d > s.maxBackoff {
+		return s.maxBackoff
+	}
+
+	return d
+}
+
+func subscribed(w entity.Webhook, eventType string) bool {
+	for _, e := range w.Events {
+		if e == eventType {
+			return true
+		}
+	}
+
+	return false
+}
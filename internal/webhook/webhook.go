@@ -0,0 +1,289 @@
+// Package webhook lets users register endpoints that receive signed JSON events
+// about the lifecycle of their links.
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/PrahaTurbo/url-shortener/internal/audit"
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	"github.com/PrahaTurbo/url-shortener/internal/netguard"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+)
+
+// Link lifecycle events a webhook can subscribe to. A link is updated when it's disabled or enabled,
+// reassigned to another user or moved to a workspace. A link expires when it's deleted at its expiry,
+// and reaches its click threshold when its counted clicks first reach the threshold it was created with.
+const (
+	EventLinkCreated        = "link.created"
+	EventLinkUpdated        = "link.updated"
+	EventLinkDeleted        = "link.deleted"
+	EventLinkExpired        = "link.expired"
+	EventLinkClickThreshold = "link.click_threshold_reached"
+)
+
+// Error variables, used in the webhook service.
+var (
+	ErrInvalidURL    = errors.New("webhook url must be an absolute http or https url")
+	ErrInvalidEvents = errors.New("webhook must subscribe to at least one known event")
+	ErrNotFound      = errors.New("webhook not found")
+)
+
+var knownEvents = map[string]bool{
+	EventLinkCreated:        true,
+	EventLinkUpdated:        true,
+	EventLinkDeleted:        true,
+	EventLinkExpired:        true,
+	EventLinkClickThreshold: true,
+}
+
+// Publisher is an interface for publishing link events to the webhooks of a user.
+type Publisher interface {
+	Publish(userID, eventType string, link models.LinkEvent)
+}
+
+// Service is an interface for managing webhooks and publishing link events to them.
+type Service interface {
+	Publisher
+	Register(ctx context.Context, userID string, req models.WebhookRequest) (*models.WebhookResponse, error)
+	List(ctx context.Context, userID string) ([]models.WebhookResponse, error)
+	Delete(ctx context.Context, userID, id string) error
+	Deliveries(ctx context.Context, userID, id string) ([]models.WebhookDeliveryResponse, error)
+}
+
+type service struct {
+	repo        storage.WebhookRepository
+	logger      *logger.Logger
+	client      *http.Client
+	events      chan pendingEvent
+	deliveries  chan delivery
+	maxAttempts int
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+	audit       audit.Recorder
+}
+
+// Option configures optional dependencies of the webhook service.
+type Option func(s *service)
+
+// WithAuditLog enables recording of registered and deleted webhooks in the audit log.
+func WithAuditLog(r audit.Recorder) Option {
+	return func(s *service) {
+		s.audit = r
+	}
+}
+
+// NewService creates a new instance of the webhook service and starts the delivery workers.
+// Unless allowPrivate is set, webhooks cannot deliver to loopback and private network addresses.
+func NewService(repo storage.WebhookRepository, logger *logger.Logger, allowPrivate bool, opts ...Option) Service {
+	allow := netguard.IsPublicIP
+	if allowPrivate {
+		allow = func(net.IP) bool { return true }
+	}
+
+	s := &service{
+		repo:   repo,
+		logger: logger,
+		client: &http.Client{
+			Timeout:   time.Second * 10,
+			Transport: netguard.NewTransport(time.Second*10, allow),
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		events:      make(chan pendingEvent, 100),
+		deliveries:  make(chan delivery, 100),
+		maxAttempts: 5,
+		baseBackoff: time.Second,
+		maxBackoff:  time.Minute,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	go s.startEventWorker()
+	go s.startDeliveryWorker(5)
+
+	return s
+}
+
+// Register validates and stores a new webhook for the user. The response includes
+// the generated secret used to sign the events, which is never returned again.
+func (s *service) Register(ctx context.Context, userID string, req models.WebhookRequest) (*models.WebhookResponse, error) {
+	if err := validateURL(req.URL); err != nil {
+		return nil, err
+	}
+
+	events, err := normalizeEvents(req.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	w := entity.Webhook{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		URL:       req.URL,
+		Secret:    secret,
+		Events:    events,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if err := s.repo.SaveWebhook(ctx, w); err != nil {
+		return nil, err
+	}
+
+	resp := toResponse(w)
+	s.record(ctx, audit.Entry{ActorID: userID, Action: audit.ActionWebhookCreate, Target: w.ID, After: resp})
+
+	resp.Secret = w.Secret
+
+	return &resp, nil
+}
+
+// List retrieves all the webhooks registered by the user.
+func (s *service) List(ctx context.Context, userID string) ([]models.WebhookResponse, error) {
+	webhooks, err := s.repo.GetWebhooksByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]models.WebhookResponse, 0, len(webhooks))
+	for _, w := range webhooks {
+		resp = append(resp, toResponse(w))
+	}
+
+	return resp, nil
+}
+
+// Delete removes the webhook of the user.
+func (s *service) Delete(ctx context.Context, userID, id string) error {
+	w, err := s.findWebhook(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteWebhook(ctx, id, userID); err != nil {
+		return err
+	}
+
+	s.record(ctx, audit.Entry{ActorID: userID, Action: audit.ActionWebhookDelete, Target: id, Before: toResponse(*w)})
+
+	return nil
+}
+
+// Deliveries retrieves the latest delivery attempts of the user's webhook.
+func (s *service) Deliveries(ctx context.Context, userID, id string) ([]models.WebhookDeliveryResponse, error) {
+	if _, err := s.findWebhook(ctx, userID, id); err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.repo.GetDeliveries(ctx, id, 100)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]models.WebhookDeliveryResponse, 0, len(deliveries))
+	for _, d := range deliveries {
+		resp = append(resp, models.WebhookDeliveryResponse{
+			ID:         d.ID,
+			EventID:    d.EventID,
+			EventType:  d.EventType,
+			Attempt:    d.Attempt,
+			StatusCode: d.StatusCode,
+			Error:      d.Error,
+			Delivered:  d.Delivered,
+			CreatedAt:  d.CreatedAt,
+		})
+	}
+
+	return resp, nil
+}
+
+func (s *service) record(ctx context.Context, e audit.Entry) {
+	if s.audit == nil {
+		return
+	}
+
+	s.audit.Record(ctx, e)
+}
+
+func (s *service) findWebhook(ctx context.Context, userID, id string) (*entity.Webhook, error) {
+	webhooks, err := s.repo.GetWebhooksByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range webhooks {
+		if webhooks[i].ID == id {
+			return &webhooks[i], nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func validateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return ErrInvalidURL
+	}
+
+	return nil
+}
+
+func normalizeEvents(events []string) ([]string, error) {
+	seen := make(map[string]bool, len(events))
+	result := make([]string, 0, len(events))
+
+	for _, e := range events {
+		if !knownEvents[e] {
+			return nil, ErrInvalidEvents
+		}
+
+		if !seen[e] {
+			seen[e] = true
+			result = append(result, e)
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, ErrInvalidEvents
+	}
+
+	return result, nil
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+func toResponse(w entity.Webhook) models.WebhookResponse {
+	return models.WebhookResponse{
+		ID:        w.ID,
+		URL:       w.URL,
+		Events:    w.Events,
+		CreatedAt: w.CreatedAt,
+	}
+}
@@ -0,0 +1,191 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/memory"
+)
+
+func setupService() *service {
+	log, _ := logger.Initialize("debug")
+
+	s := NewService(memory.NewWebhookStorage(), log, true).(*service)
+	s.baseBackoff = time.Millisecond
+	s.maxBackoff = time.Millisecond * 10
+	s.maxAttempts = 3
+
+	return s
+}
+
+func TestService_Register(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     models.WebhookRequest
+		events  []string
+		wantErr error
+	}{
+		{
+			name:   "should register webhook successfully",
+			req:    models.WebhookRequest{URL: "https://cms.example.com/hooks", Events: []string{EventLinkCreated, EventLinkDeleted, EventLinkCreated}},
+			events: []string{EventLinkCreated, EventLinkDeleted},
+		},
+		{
+			name:   "should register webhook for expiry and click threshold",
+			req:    models.WebhookRequest{URL: "https://cms.example.com/hooks", Events: []string{EventLinkExpired, EventLinkClickThreshold}},
+			events: []string{EventLinkExpired, EventLinkClickThreshold},
+		},
+		{
+			name:    "should reject relative url",
+			req:     models.WebhookRequest{URL: "/hooks", Events: []string{EventLinkCreated}},
+			wantErr: ErrInvalidURL,
+		},
+		{
+			name:    "should reject unsupported scheme",
+			req:     models.WebhookRequest{URL: "ftp://cms.example.com", Events: []string{EventLinkCreated}},
+			wantErr: ErrInvalidURL,
+		},
+		{
+			name:    "should reject unknown event",
+			req:     models.WebhookRequest{URL: "https://cms.example.com/hooks", Events: []string{"link.visited"}},
+			wantErr: ErrInvalidEvents,
+		},
+		{
+			name:    "should reject empty events",
+			req:     models.WebhookRequest{URL: "https://cms.example.com/hooks"},
+			wantErr: ErrInvalidEvents,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := setupService()
+
+			resp, err := s.Register(context.Background(), "user", tt.req)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.events, resp.Events)
+			assert.True(t, strings.HasPrefix(resp.Secret, "whsec_"))
+
+			list, err := s.List(context.Background(), "user")
+			require.NoError(t, err)
+			require.Len(t, list, 1)
+			assert.Empty(t, list[0].Secret)
+		})
+	}
+}
+
+func TestService_Delete(t *testing.T) {
+	s := setupService()
+	ctx := context.Background()
+
+	resp, err := s.Register(ctx, "owner", models.WebhookRequest{URL: "https://cms.example.com", Events: []string{EventLinkCreated}})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, s.Delete(ctx, "stranger", resp.ID), ErrNotFound)
+	assert.NoError(t, s.Delete(ctx, "owner", resp.ID))
+	assert.ErrorIs(t, s.Delete(ctx, "owner", resp.ID), ErrNotFound)
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+
+	sig := Sign("secret", 1700000000, body)
+
+	assert.Len(t, sig, 64)
+	assert.Equal(t, sig, Sign("secret", 1700000000, body))
+	assert.NotEqual(t, sig, Sign("other", 1700000000, body))
+	assert.NotEqual(t, sig, Sign("secret", 1700000001, body))
+}
+
+func TestService_Publish(t *testing.T) {
+	var mu sync.Mutex
+	var calls int
+	received := make(chan *http.Request, 10)
+	bodies := make(chan []byte, 10)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		n := calls
+		mu.Unlock()
+
+		if n == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer srv.Close()
+
+	s := setupService()
+	ctx := context.Background()
+
+	hook, err := s.Register(ctx, "user", models.WebhookRequest{URL: srv.URL, Events: []string{EventLinkDeleted}})
+	require.NoError(t, err)
+
+	s.Publish("user", EventLinkCreated, models.LinkEvent{ShortURL: "http://localhost/ignored"})
+	s.Publish("user", EventLinkDeleted, models.LinkEvent{ShortURL: "http://localhost/abc"})
+
+	select {
+	case r := <-received:
+		body := <-bodies
+
+		var e Event
+		require.NoError(t, json.Unmarshal(body, &e))
+		assert.Equal(t, EventLinkDeleted, e.Type)
+		assert.Equal(t, "http://localhost/abc", e.Data.ShortURL)
+		assert.Equal(t, EventLinkDeleted, r.Header.Get(HeaderEvent))
+
+		var ts int64
+		var sig string
+		_, err := fmt.Sscanf(strings.Replace(r.Header.Get(HeaderSignature), ",v1=", " ", 1), "t=%d %s", &ts, &sig)
+		require.NoError(t, err)
+		assert.Equal(t, Sign(hook.Secret, ts, body), sig)
+	case <-time.After(time.Second * 5):
+		t.Fatal("webhook was not delivered")
+	}
+
+	require.Eventually(t, func() bool {
+		deliveries, err := s.Deliveries(ctx, "user", hook.ID)
+		return err == nil && len(deliveries) == 2
+	}, time.Second*5, time.Millisecond*10)
+
+	deliveries, err := s.Deliveries(ctx, "user", hook.ID)
+	require.NoError(t, err)
+
+	assert.True(t, deliveries[0].Delivered)
+	assert.Equal(t, 2, deliveries[0].Attempt)
+	assert.False(t, deliveries[1].Delivered)
+	assert.Equal(t, http.StatusServiceUnavailable, deliveries[1].StatusCode)
+}
+
+func TestService_backoff(t *testing.T) {
+	s := &service{baseBackoff: time.Second, maxBackoff: time.Second * 5}
+
+	assert.Equal(t, time.Second, s.backoff(1))
+	assert.Equal(t, time.Second*2, s.backoff(2))
+	assert.Equal(t, time.Second*4, s.backoff(3))
+	assert.Equal(t, time.Second*5, s.backoff(4))
+	assert.Equal(t, time.Second*5, s.backoff(100))
+}
@@ -0,0 +1,446 @@
+// Package workspace lets users share links in workspaces. Every member of a workspace has its own
+// permissions on the links of the workspace, so the links stay manageable when the user who created
+// them leaves: view lists them among the links of the member, edit adds the links the member created
+// to the workspace, and delete deletes any of them. A link is only ever moved by the user who created it,
+// even with the edit permission. The members with all the permissions manage the members.
+package workspace
+
+import (
+	"context"
+	"errors"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/PrahaTurbo/url-shortener/internal/audit"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+	"github.com/PrahaTurbo/url-shortener/internal/webhook"
+)
+
+// Permissions a member of a workspace can have. See the package doc for what they allow.
+const (
+	PermissionView   = "view"
+	PermissionEdit   = "edit"
+	PermissionDelete = "delete"
+)
+
+const maxNameLength = 100
+
+// Error variables, used in the workspace service.
+var (
+	ErrInvalidName        = errors.New("workspace name must be from 1 to 100 characters long")
+	ErrInvalidPermissions = errors.New("permissions must be a non-empty list of view, edit and delete")
+	ErrNotFound           = errors.New("workspace not found")
+	ErrForbidden          = errors.New("member lacks the permission")
+	ErrLastManager        = errors.New("workspace must keep a member with all the permissions")
+	ErrURLNotFound        = errors.New("url not found")
+)
+
+// Service is an interface for managing workspaces, their members and the links shared in them.
+type Service interface {
+	Create(ctx context.Context, userID string, req models.WorkspaceRequest) (*models.WorkspaceResponse, error)
+	List(ctx context.Context, userID string) ([]models.WorkspaceResponse, error)
+	Members(ctx context.Context, userID, id string) ([]models.WorkspaceMemberResponse, error)
+	SetMember(ctx context.Context, userID, id, memberID string, req models.WorkspaceMemberRequest) error
+	RemoveMember(ctx context.Context, userID, id, memberID string) error
+	AddURLs(ctx context.Context, userID, id string, urls []string) error
+}
+
+type service struct {
+	repo      storage.WorkspaceRepository
+	urls      storage.Repository
+	audit     audit.Recorder
+	publisher webhook.Publisher
+	baseURL   string
+	now       func() time.Time
+}
+
+// Option configures optional dependencies of the workspace service.
+type Option func(s *service)
+
+// WithAuditLog enables recording of created workspaces, membership changes and links moved to workspaces
+// in the audit log.
+func WithAuditLog(r audit.Recorder) Option {
+	return func(s *service) {
+		s.audit = r
+	}
+}
+
+// WithEventPublisher enables publishing of the links moved to workspaces. The baseURL is the prefix
+// of the published short URLs.
+func WithEventPublisher(p webhook.Publisher, baseURL string) Option {
+	return func(s *service) {
+		s.publisher = p
+		s.baseURL = baseURL
+	}
+}
+
+// NewService creates a new instance of the workspace service.
+func NewService(repo storage.WorkspaceRepository, urls storage.Repository, opts ...Option) Service {
+	s := &service{
+		repo: repo,
+		urls: urls,
+		now:  time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Create creates a workspace with the user as its member with all the permissions.
+func (s *service) Create(ctx context.Context, userID string, req models.WorkspaceRequest) (*models.WorkspaceResponse, error) {
+	if n := utf8.RuneCountInString(req.Name); n == 0 || n > maxNameLength {
+		return nil, ErrInvalidName
+	}
+
+	now := s.now().UTC()
+
+	w := entity.Workspace{
+		ID:        uuid.New().String(),
+		Name:      req.Name,
+		CreatedAt: now,
+	}
+
+	owner := entity.WorkspaceMember{
+		WorkspaceID: w.ID,
+		UserID:      userID,
+		CanView:     true,
+		CanEdit:     true,
+		CanDelete:   true,
+		CreatedAt:   now,
+	}
+
+	if err := s.repo.SaveWorkspace(ctx, w, owner); err != nil {
+		return nil, err
+	}
+
+	resp := &models.WorkspaceResponse{
+		ID:          w.ID,
+		Name:        w.Name,
+		Permissions: permissions(owner),
+		CreatedAt:   w.CreatedAt,
+	}
+
+	s.record(ctx, audit.Entry{ActorID: userID, Action: audit.ActionWorkspaceCreate, Target: w.ID, After: resp})
+
+	return resp, nil
+}
+
+// List returns the workspaces the user is a member of with the permissions of the user in them.
+func (s *service) List(ctx context.Context, userID string) ([]models.WorkspaceResponse, error) {
+	memberships, err := s.repo.GetMembershipsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]models.WorkspaceResponse, 0, len(memberships))
+	for _, m := range memberships {
+		w, err := s.repo.GetWorkspace(ctx, m.WorkspaceID)
+		if err != nil {
+			return nil, err
+		}
+
+		if w == nil {
+			continue
+		}
+
+		resp = append(resp, models.WorkspaceResponse{
+			ID:          w.ID,
+			Name:        w.Name,
+			Permissions: permissions(m),
+			CreatedAt:   w.CreatedAt,
+		})
+	}
+
+	return resp, nil
+}
+
+// Members returns the members of the workspace. The user needs the view permission.
+func (s *service) Members(ctx context.Context, userID, id string) ([]models.WorkspaceMemberResponse, error) {
+	if err := s.authorize(ctx, userID, id, isViewer); err != nil {
+		return nil, err
+	}
+
+	members, err := s.repo.GetMembers(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]models.WorkspaceMemberResponse, 0, len(members))
+	for _, m := range members {
+		resp = append(resp, models.WorkspaceMemberResponse{
+			UserID:      m.UserID,
+			Permissions: permissions(m),
+			CreatedAt:   m.CreatedAt,
+		})
+	}
+
+	return resp, nil
+}
+
+// SetMember adds the member to the workspace or changes the permissions of the member.
+// The user needs all the permissions.
+func (s *service) SetMember(ctx context.Context, userID, id, memberID string, req models.WorkspaceMemberRequest) error {
+	m, err := parsePermissions(req.Permissions)
+	if err != nil {
+		return err
+	}
+
+	if err := s.authorize(ctx, userID, id, isManager); err != nil {
+		return err
+	}
+
+	if !isManager(m) {
+		if err := s.checkNotLastManager(ctx, id, memberID); err != nil {
+			return err
+		}
+	}
+
+	before, err := s.repo.GetMember(ctx, id, memberID)
+	if err != nil {
+		return err
+	}
+
+	m.WorkspaceID = id
+	m.UserID = memberID
+	m.CreatedAt = s.now().UTC()
+
+	if err := s.repo.SaveMember(ctx, m); err != nil {
+		return err
+	}
+
+	s.record(ctx, audit.Entry{
+		ActorID: userID,
+		Action:  audit.ActionWorkspaceMemberSet,
+		Target:  id,
+		Before:  memberValue(before),
+		After:   memberValue(&m),
+	})
+
+	return nil
+}
+
+// RemoveMember removes the member from the workspace. The user needs all the permissions,
+// unless the user leaves the workspace.
+func (s *service) RemoveMember(ctx context.Context, userID, id, memberID string) error {
+	allowed := isManager
+	if userID == memberID {
+		allowed = func(entity.WorkspaceMember) bool { return true }
+	}
+
+	if err := s.authorize(ctx, userID, id, allowed); err != nil {
+		return err
+	}
+
+	if err := s.checkNotLastManager(ctx, id, memberID); err != nil {
+		return err
+	}
+
+	before, err := s.repo.GetMember(ctx, id, memberID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteMember(ctx, id, memberID); err != nil {
+		return err
+	}
+
+	s.record(ctx, audit.Entry{
+		ActorID: userID,
+		Action:  audit.ActionWorkspaceMemberRemove,
+		Target:  id,
+		Before:  memberValue(before),
+	})
+
+	return nil
+}
+
+// AddURLs moves the links the user created to the workspace. The user needs the edit permission,
+// which doesn't allow moving the links other users created.
+func (s *service) AddURLs(ctx context.Context, userID, id string, urls []string) error {
+	if err := s.authorize(ctx, userID, id, isEditor); err != nil {
+		return err
+	}
+
+	before := s.ownRecords(ctx, userID)
+
+	for _, url := range urls {
+		err := s.urls.SetURLWorkspace(ctx, url, userID, id)
+		if errors.Is(err, storage.ErrURLNotFound) {
+			return ErrURLNotFound
+		}
+
+		if err != nil {
+			return err
+		}
+
+		s.record(ctx, audit.Entry{
+			ActorID: userID,
+			Action:  audit.ActionLinkMove,
+			Target:  url,
+			Before:  before[url],
+			After:   map[string]string{"workspace_id": id},
+		})
+
+		s.publishUpdated(userID, models.LinkEvent{ShortURL: s.baseURL + "/" + url, WorkspaceID: id})
+	}
+
+	return nil
+}
+
+// authorize checks that the membership of the user in the workspace satisfies the allowed func.
+// Workspaces the user is not a member of are reported as not found.
+func (s *service) authorize(ctx context.Context, userID, id string, allowed func(entity.WorkspaceMember) bool) error {
+	m, err := s.repo.GetMember(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+
+	if m == nil {
+		return ErrNotFound
+	}
+
+	if !allowed(*m) {
+		return ErrForbidden
+	}
+
+	return nil
+}
+
+// checkNotLastManager fails if the member is the only member of the workspace with all the permissions.
+func (s *service) checkNotLastManager(ctx context.Context, id, memberID string) error {
+	members, err := s.repo.GetMembers(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	var managers int
+	var memberIsManager bool
+	for _, m := range members {
+		if !isManager(m) {
+			continue
+		}
+
+		managers++
+
+		if m.UserID == memberID {
+			memberIsManager = true
+		}
+	}
+
+	if memberIsManager && managers == 1 {
+		return ErrLastManager
+	}
+
+	return nil
+}
+
+func (s *service) record(ctx context.Context, e audit.Entry) {
+	if s.audit == nil {
+		return
+	}
+
+	s.audit.Record(ctx, e)
+}
+
+func (s *service) publishUpdated(userID string, link models.LinkEvent) {
+	if s.publisher == nil {
+		return
+	}
+
+	s.publisher.Publish(userID, webhook.EventLinkUpdated, link)
+}
+
+// ownRecords returns the workspace of every link the user created, by short URL, to record the links
+// moved to another workspace. It skips the lookup if the audit log is disabled.
+func (s *service) ownRecords(ctx context.Context, userID string) map[string]map[string]string {
+	if s.audit == nil {
+		return nil
+	}
+
+	records, err := s.urls.GetURLsByUserID(ctx, userID)
+	if err != nil {
+		return nil
+	}
+
+	own := make(map[string]map[string]string, len(records))
+	for _, r := range records {
+		if r.UserID == userID {
+			own[r.ShortURL] = map[string]string{"workspace_id": r.WorkspaceID}
+		}
+	}
+
+	return own
+}
+
+// memberValue is the value of the membership recorded in the audit log.
+func memberValue(m *entity.WorkspaceMember) *models.WorkspaceMemberResponse {
+	if m == nil {
+		return nil
+	}
+
+	return &models.WorkspaceMemberResponse{
+		UserID:      m.UserID,
+		Permissions: permissions(*m),
+		CreatedAt:   m.CreatedAt,
+	}
+}
+
+func isViewer(m entity.WorkspaceMember) bool {
+	return m.CanView
+}
+
+func isEditor(m entity.WorkspaceMember) bool {
+	return m.CanEdit
+}
+
+func isManager(m entity.WorkspaceMember) bool {
+	return m.CanView && m.CanEdit && m.CanDelete
+}
+
+func parsePermissions(perms []string) (entity.WorkspaceMember, error) {
+	var m entity.WorkspaceMember
+
+	if len(perms) == 0 {
+		return m, ErrInvalidPermissions
+	}
+
+	for _, p := range perms {
+		switch p {
+		case PermissionView:
+			m.CanView = true
+		case PermissionEdit:
+			m.CanEdit = true
+		case PermissionDelete:
+			m.CanDelete = true
+		default:
+			return m, ErrInvalidPermissions
+		}
+	}
+
+	return m, nil
+}
+
+func permissions(m entity.WorkspaceMember) []string {
+	perms := make([]string, 0, 3)
+
+	if m.CanView {
+		perms = append(perms, PermissionView)
+	}
+
+	if m.CanEdit {
+		perms = append(perms, PermissionEdit)
+	}
+
+	if m.CanDelete {
+		perms = append(perms, PermissionDelete)
+	}
+
+	return perms
+}
@@ -0,0 +1,192 @@
+package workspace
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/PrahaTurbo/url-shortener/internal/logger"
+	"github.com/PrahaTurbo/url-shortener/internal/mocks"
+	"github.com/PrahaTurbo/url-shortener/internal/models"
+	"github.com/PrahaTurbo/url-shortener/internal/storage"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/entity"
+	"github.com/PrahaTurbo/url-shortener/internal/storage/memory"
+	"github.com/PrahaTurbo/url-shortener/internal/webhook"
+)
+
+func setupService(t *testing.T, opts ...Option) (Service, storage.Repository) {
+	log, _ := logger.Initialize("debug")
+	workspaces := memory.NewWorkspaceStorage()
+	urls, err := memory.NewInMemStorage(filepath.Join(t.TempDir(), "db.json"), log, memory.WithWorkspaces(workspaces))
+	require.NoError(t, err)
+
+	return NewService(workspaces, urls, opts...), urls
+}
+
+func saveURL(t *testing.T, urls storage.Repository, userID, shortURL string) {
+	err := urls.SaveURL(context.Background(), entity.URLRecord{
+		UUID:        userID + shortURL,
+		ShortURL:    shortURL,
+		OriginalURL: "https://" + shortURL + ".example",
+		UserID:      userID,
+	})
+	require.NoError(t, err)
+}
+
+func TestService_Create(t *testing.T) {
+	s, _ := setupService(t)
+	ctx := context.Background()
+
+	_, err := s.Create(ctx, "alice", models.WorkspaceRequest{})
+	assert.ErrorIs(t, err, ErrInvalidName)
+
+	w, err := s.Create(ctx, "alice", models.WorkspaceRequest{Name: "team"})
+	require.NoError(t, err)
+	assert.Equal(t, "team", w.Name)
+	assert.Equal(t, []string{PermissionView, PermissionEdit, PermissionDelete}, w.Permissions)
+
+	list, err := s.List(ctx, "alice")
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, w.ID, list[0].ID)
+
+	list, err = s.List(ctx, "bob")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func TestService_SetMember(t *testing.T) {
+	s, _ := setupService(t)
+	ctx := context.Background()
+
+	w, err := s.Create(ctx, "alice", models.WorkspaceRequest{Name: "team"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		userID   string
+		id       string
+		memberID string
+		perms    []string
+		wantErr  error
+	}{
+		{
+			name:     "should add member",
+			userID:   "alice",
+			id:       w.ID,
+			memberID: "bob",
+			perms:    []string{PermissionView},
+		},
+		{
+			name:     "should reject unknown permission",
+			userID:   "alice",
+			id:       w.ID,
+			memberID: "bob",
+			perms:    []string{"own"},
+			wantErr:  ErrInvalidPermissions,
+		},
+		{
+			name:     "should forbid member without all the permissions",
+			userID:   "bob",
+			id:       w.ID,
+			memberID: "carol",
+			perms:    []string{PermissionView},
+			wantErr:  ErrForbidden,
+		},
+		{
+			name:     "should hide workspace from non-members",
+			userID:   "carol",
+			id:       w.ID,
+			memberID: "carol",
+			perms:    []string{PermissionView},
+			wantErr:  ErrNotFound,
+		},
+		{
+			name:     "should keep the last member with all the permissions",
+			userID:   "alice",
+			id:       w.ID,
+			memberID: "alice",
+			perms:    []string{PermissionView},
+			wantErr:  ErrLastManager,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.SetMember(ctx, tt.userID, tt.id, tt.memberID, models.WorkspaceMemberRequest{Permissions: tt.perms})
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+
+	members, err := s.Members(ctx, "bob", w.ID)
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+	assert.Equal(t, "alice", members[0].UserID)
+	assert.Equal(t, []string{PermissionView}, members[1].Permissions)
+}
+
+func TestService_RemoveMember(t *testing.T) {
+	s, _ := setupService(t)
+	ctx := context.Background()
+
+	w, err := s.Create(ctx, "alice", models.WorkspaceRequest{Name: "team"})
+	require.NoError(t, err)
+	require.NoError(t, s.SetMember(ctx, "alice", w.ID, "bob", models.WorkspaceMemberRequest{Permissions: []string{PermissionView}}))
+	require.NoError(t, s.SetMember(ctx, "alice", w.ID, "carol", models.WorkspaceMemberRequest{Permissions: []string{PermissionView}}))
+
+	assert.ErrorIs(t, s.RemoveMember(ctx, "bob", w.ID, "carol"), ErrForbidden)
+	assert.ErrorIs(t, s.RemoveMember(ctx, "alice", w.ID, "alice"), ErrLastManager)
+	assert.NoError(t, s.RemoveMember(ctx, "bob", w.ID, "bob"))
+	assert.NoError(t, s.RemoveMember(ctx, "alice", w.ID, "carol"))
+
+	members, err := s.Members(ctx, "alice", w.ID)
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	assert.Equal(t, "alice", members[0].UserID)
+}
+
+func TestService_AddURLs(t *testing.T) {
+	publisher := mocks.NewMockWebhookPublisher(gomock.NewController(t))
+	s, urls := setupService(t, WithEventPublisher(publisher, "http://localhost:8080"))
+	ctx := context.Background()
+
+	saveURL(t, urls, "alice", "abc")
+	saveURL(t, urls, "alice", "def")
+	saveURL(t, urls, "bob", "xyz")
+
+	w, err := s.Create(ctx, "alice", models.WorkspaceRequest{Name: "team"})
+	require.NoError(t, err)
+	require.NoError(t, s.SetMember(ctx, "alice", w.ID, "bob", models.WorkspaceMemberRequest{Permissions: []string{PermissionView}}))
+	require.NoError(t, s.SetMember(ctx, "alice", w.ID, "carol", models.WorkspaceMemberRequest{Permissions: []string{PermissionView, PermissionDelete}}))
+
+	assert.ErrorIs(t, s.AddURLs(ctx, "bob", w.ID, []string{"xyz"}), ErrForbidden)
+	assert.ErrorIs(t, s.AddURLs(ctx, "alice", w.ID, []string{"xyz"}), ErrURLNotFound)
+
+	publisher.EXPECT().Publish("alice", webhook.EventLinkUpdated, models.LinkEvent{ShortURL: "http://localhost:8080/abc", WorkspaceID: w.ID})
+	require.NoError(t, s.AddURLs(ctx, "alice", w.ID, []string{"abc"}))
+
+	records, err := urls.GetURLsByUserID(ctx, "bob")
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "xyz", records[0].ShortURL)
+	assert.Equal(t, "abc", records[1].ShortURL)
+	assert.Equal(t, w.ID, records[1].WorkspaceID)
+
+	_, err = urls.DeleteURLBatch([]string{"abc", "def"}, "bob")
+	require.NoError(t, err)
+	records, err = urls.GetURLsByUserID(ctx, "alice")
+	require.NoError(t, err)
+	assert.False(t, records[0].DeletedFlag)
+
+	_, err = urls.DeleteURLBatch([]string{"abc", "def"}, "carol")
+	require.NoError(t, err)
+	records, err = urls.GetURLsByUserID(ctx, "alice")
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.True(t, records[0].DeletedFlag)
+	assert.False(t, records[1].DeletedFlag)
+}